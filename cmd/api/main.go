@@ -12,11 +12,13 @@ import (
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/analytics"
 	"github.com/abdul-hamid-achik/file.cheap/internal/api"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/auth"
 	"github.com/abdul-hamid-achik/file.cheap/internal/billing"
 	"github.com/abdul-hamid-achik/file.cheap/internal/config"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/email"
+	"github.com/abdul-hamid-achik/file.cheap/internal/events"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
@@ -24,6 +26,7 @@ import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
 	"github.com/abdul-hamid-achik/file.cheap/internal/tracing"
 	"github.com/abdul-hamid-achik/file.cheap/internal/web"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
 	"github.com/abdul-hamid-achik/job-queue/pkg/broker"
 	"github.com/abdul-hamid-achik/job-queue/pkg/job"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -100,21 +103,27 @@ func run() error {
 
 	log.Info("connecting to object storage")
 	storageCfg := &storage.Config{
-		Endpoint:  cfg.MinIOEndpoint,
-		AccessKey: cfg.MinIOAccessKey,
-		SecretKey: cfg.MinIOSecretKey,
-		Bucket:    cfg.MinIOBucket,
-		UseSSL:    cfg.MinIOUseSSL,
-		Region:    cfg.MinIORegion,
+		Backend:    cfg.StorageBackend,
+		Endpoint:   cfg.MinIOEndpoint,
+		AccessKey:  cfg.MinIOAccessKey,
+		SecretKey:  cfg.MinIOSecretKey,
+		Bucket:     cfg.MinIOBucket,
+		UseSSL:     cfg.MinIOUseSSL,
+		Region:     cfg.MinIORegion,
+		Path:       cfg.StoragePath,
+		BaseURL:    cfg.BaseURL,
+		SigningKey: cfg.StorageSigningKey,
 	}
-	store, err := storage.NewMinIOStorage(storageCfg)
+	store, err := storage.New(storageCfg)
 	if err != nil {
 		return fmt.Errorf("failed to create storage: %w", err)
 	}
-	if err := store.EnsureBucket(ctx); err != nil {
-		return fmt.Errorf("failed to ensure bucket: %w", err)
+	if minioStore, ok := store.(*storage.MinIOStorage); ok {
+		if err := minioStore.EnsureBucket(ctx); err != nil {
+			return fmt.Errorf("failed to ensure bucket: %w", err)
+		}
 	}
-	log.Info("object storage connected")
+	log.Info("object storage connected", "backend", storageCfg.Backend)
 
 	log.Info("connecting to redis")
 	redisOpt, err := redis.ParseURL(cfg.RedisURL)
@@ -167,35 +176,52 @@ func run() error {
 	registry.Register("metadata", imgproc.NewMetadataProcessor(nil))
 	registry.Register("optimize", imgproc.NewOptimizeProcessor(nil))
 	registry.Register("convert", imgproc.NewConvertProcessor(nil))
+	avifEnabled := imgproc.AVIFAvailable()
+	if !avifEnabled {
+		log.Warn("avif encoding unavailable (avifenc not found); avif requests will be rejected")
+	}
 
 	poolStats := analytics.NewPoolStatsFunc(func() analytics.PoolStats { return pool.Stat() })
 	analyticsService := analytics.NewService(queries, redisClient)
 	analyticsService.SetPoolStats(poolStats)
 
+	uploadSessions := api.NewRedisUploadSessionStore(redisClient)
+	eventBus := events.NewRedisBus(redisClient)
+	webhookDispatcher := webhook.NewDispatcher(queries, &brokerAdapter{broker: b})
+
 	apiCfg := &api.Config{
-		Storage:          instrumentedStore,
-		Broker:           &brokerAdapter{broker: b},
-		Queries:          queries,
-		MaxUploadSize:    cfg.MaxUploadSize,
-		JWTSecret:        cfg.JWTSecret,
-		BaseURL:          cfg.BaseURL,
-		Registry:         registry,
-		Pool:             pool,
-		RedisClient:      redisClient,
-		AnalyticsService: analyticsService,
+		Storage:           instrumentedStore,
+		Broker:            &brokerAdapter{broker: b},
+		Queries:           queries,
+		MaxUploadSize:     cfg.MaxUploadSize,
+		JWTSecret:         cfg.JWTSecret,
+		BaseURL:           cfg.BaseURL,
+		Registry:          registry,
+		Pool:              pool,
+		RedisClient:       redisClient,
+		AnalyticsService:  analyticsService,
+		UploadSessions:    uploadSessions,
+		AVIFEnabled:       avifEnabled,
+		Events:            eventBus,
+		WebhookDispatcher: webhookDispatcher,
 	}
 	apiRouter := api.NewRouter(apiCfg)
 	mux.Handle("/v1/", apiRouter)
 	mux.Handle("/health", apiRouter)
 	mux.Handle("/health/", apiRouter)
 	mux.Handle("/cdn/", apiRouter)
+	if fsStore, ok := store.(*storage.FilesystemStorage); ok {
+		mux.Handle("GET "+storage.FilesystemDownloadPath, fsStore)
+		mux.Handle("PUT "+storage.FilesystemDownloadPath, fsStore)
+	}
 
 	webCfg := &web.Config{
-		Storage: instrumentedStore,
-		Queries: queries,
-		Broker:  &brokerAdapter{broker: b},
-		BaseURL: cfg.BaseURL,
-		Secure:  cfg.Secure,
+		Storage:           instrumentedStore,
+		Queries:           queries,
+		Broker:            &brokerAdapter{broker: b},
+		BaseURL:           cfg.BaseURL,
+		Secure:            cfg.Secure,
+		WebhookDispatcher: webhookDispatcher,
 	}
 
 	var billingHandlers *web.BillingHandlers
@@ -233,7 +259,11 @@ func run() error {
 	webRouter := web.NewRouter(webCfg, sessionManager, authService, oauthService, emailService, billingHandlers, analyticsHandlers, adminHandlers, enterpriseHandlers)
 	mux.Handle("/", webRouter)
 
-	handler := api.SecurityHeaders(metrics.HTTPMetricsMiddleware(web.Recovery(web.RequestID(web.RequestLogger(mux)))))
+	// Every request carries the audit logger so API, web and CDN handlers can
+	// record audit entries.
+	auditLogger := audit.NewLogger(queries)
+
+	handler := api.SecurityHeaders(metrics.HTTPMetricsMiddleware(web.Recovery(web.RequestID(web.RequestLogger(audit.Middleware(auditLogger)(mux))))))
 	if cfg.TracingEnabled {
 		handler = tracing.HTTPMiddleware("api")(handler)
 	}
@@ -268,8 +298,13 @@ func run() error {
 	}()
 
 	// Start chunked upload session cleanup goroutine
-	uploadSessionStore := api.GetSessionStore()
-	go uploadSessionStore.StartCleanup(ctx, store)
+	cleanupCtx, stopCleanup := context.WithCancel(ctx)
+	defer stopCleanup()
+	go api.RunUploadSessionCleanup(cleanupCtx, uploadSessions, store)
+
+	// Fan worker job events out to this replica's SSE clients
+	api.StartSSEHub()
+	go api.RunSSEEventBridge(cleanupCtx, eventBus)
 
 	// Start session cleanup goroutine
 	go func() {
@@ -301,7 +336,7 @@ func run() error {
 		log.Info("shutdown signal received", "signal", sig)
 
 		// Stop background cleanup goroutines
-		uploadSessionStore.StopCleanup()
+		stopCleanup()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
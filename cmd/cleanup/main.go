@@ -51,18 +51,22 @@ func run() error {
 
 	log.Info("connecting to object storage")
 	storageCfg := &storage.Config{
-		Endpoint:  cfg.MinIOEndpoint,
-		AccessKey: cfg.MinIOAccessKey,
-		SecretKey: cfg.MinIOSecretKey,
-		Bucket:    cfg.MinIOBucket,
-		UseSSL:    cfg.MinIOUseSSL,
-		Region:    cfg.MinIORegion,
+		Backend:    cfg.StorageBackend,
+		Endpoint:   cfg.MinIOEndpoint,
+		AccessKey:  cfg.MinIOAccessKey,
+		SecretKey:  cfg.MinIOSecretKey,
+		Bucket:     cfg.MinIOBucket,
+		UseSSL:     cfg.MinIOUseSSL,
+		Region:     cfg.MinIORegion,
+		Path:       cfg.StoragePath,
+		BaseURL:    cfg.BaseURL,
+		SigningKey: cfg.StorageSigningKey,
 	}
-	store, err := storage.NewMinIOStorage(storageCfg)
+	store, err := storage.New(storageCfg)
 	if err != nil {
 		return fmt.Errorf("failed to create storage: %w", err)
 	}
-	log.Info("object storage connected")
+	log.Info("object storage connected", "backend", storageCfg.Backend)
 
 	queries := db.New(pool)
 
@@ -12,6 +12,8 @@ import (
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/config"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/email"
+	"github.com/abdul-hamid-achik/file.cheap/internal/events"
 	"github.com/abdul-hamid-achik/file.cheap/internal/health"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
@@ -20,8 +22,10 @@ import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor/pdf"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
 	"github.com/abdul-hamid-achik/file.cheap/internal/tracing"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
 	fpworker "github.com/abdul-hamid-achik/file.cheap/internal/worker"
 	"github.com/abdul-hamid-achik/job-queue/pkg/broker"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
 	"github.com/abdul-hamid-achik/job-queue/pkg/middleware"
 	"github.com/abdul-hamid-achik/job-queue/pkg/worker"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -30,6 +34,21 @@ import (
 	"github.com/rs/zerolog"
 )
 
+type brokerAdapter struct {
+	broker *broker.RedisStreamsBroker
+}
+
+func (a *brokerAdapter) Enqueue(jobType string, payload interface{}) (string, error) {
+	j, err := job.New(jobType, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to create job: %w", err)
+	}
+	if err := a.broker.Enqueue(context.Background(), j); err != nil {
+		return "", err
+	}
+	return j.ID, nil
+}
+
 func main() {
 	if err := run(); err != nil {
 		slog.Error("fatal error", "error", err)
@@ -83,18 +102,22 @@ func run() error {
 
 	log.Info("connecting to object storage")
 	storageCfg := &storage.Config{
-		Endpoint:  cfg.MinIOEndpoint,
-		AccessKey: cfg.MinIOAccessKey,
-		SecretKey: cfg.MinIOSecretKey,
-		Bucket:    cfg.MinIOBucket,
-		UseSSL:    cfg.MinIOUseSSL,
-		Region:    cfg.MinIORegion,
-	}
-	store, err := storage.NewMinIOStorage(storageCfg)
+		Backend:    cfg.StorageBackend,
+		Endpoint:   cfg.MinIOEndpoint,
+		AccessKey:  cfg.MinIOAccessKey,
+		SecretKey:  cfg.MinIOSecretKey,
+		Bucket:     cfg.MinIOBucket,
+		UseSSL:     cfg.MinIOUseSSL,
+		Region:     cfg.MinIORegion,
+		Path:       cfg.StoragePath,
+		BaseURL:    cfg.BaseURL,
+		SigningKey: cfg.StorageSigningKey,
+	}
+	store, err := storage.New(storageCfg)
 	if err != nil {
 		return fmt.Errorf("failed to create storage: %w", err)
 	}
-	log.Info("object storage connected")
+	log.Info("object storage connected", "backend", storageCfg.Backend)
 
 	log.Info("connecting to redis")
 	redisOpt, err := redis.ParseURL(cfg.RedisURL)
@@ -130,15 +153,37 @@ func run() error {
 	procRegistry.Register("metadata", image.NewMetadataProcessor(processor.DefaultConfig()))
 	procRegistry.Register("optimize", image.NewOptimizeProcessor(processor.DefaultConfig()))
 	procRegistry.Register("convert", image.NewConvertProcessor(processor.DefaultConfig()))
+	if !image.AVIFAvailable() {
+		log.Warn("avif encoding unavailable (avifenc not found)")
+	}
 
 	registerVideoProcessors(procRegistry, log)
 
 	log.Info("processor registry ready", "count", len(procRegistry.List()))
 
 	deps := &fpworker.Dependencies{
-		Storage:  instrumentedStore,
-		Registry: procRegistry,
-		Queries:  queries,
+		Storage:           instrumentedStore,
+		Registry:          procRegistry,
+		Queries:           queries,
+		WebhookDispatcher: webhook.NewDispatcher(queries, &brokerAdapter{broker: b}),
+		Events:            events.NewRedisBus(redisClient),
+	}
+
+	emailService := email.NewService(email.Config{
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUsername: cfg.SMTPUsername,
+		SMTPPassword: cfg.SMTPPassword,
+		FromAddress:  cfg.SMTPFromAddress,
+		FromName:     cfg.SMTPFromName,
+		BaseURL:      cfg.BaseURL,
+	})
+
+	// Circuit state lives in Postgres so every worker replica shares it.
+	webhookDeps := &fpworker.WebhookDependencies{
+		Queries:        queries,
+		CircuitBreaker: webhook.NewCircuitBreaker(queries),
+		Notifier:       emailService,
 	}
 
 	log.Info("registering job handlers")
@@ -151,12 +196,22 @@ func run() error {
 	_ = registry.Register("metadata", fpworker.MetadataHandler(deps))
 	_ = registry.Register("optimize", fpworker.OptimizeHandler(deps))
 	_ = registry.Register("convert", fpworker.ConvertHandler(deps))
+	_ = registry.Register("transform", fpworker.TransformHandler(deps))
+	_ = registry.Register("zip_download", fpworker.ZipDownloadHandler(deps))
+	_ = registry.Register("webhook_delivery", fpworker.WebhookDeliveryHandler(webhookDeps))
+	_ = registry.Register("webhook_dlq_replay", fpworker.WebhookDLQReplayHandler(&fpworker.WebhookDLQReplayDependencies{
+		Queries: queries,
+		Broker:  &brokerAdapter{broker: b},
+	}))
 
 	registerVideoHandlers(registry, deps)
 
 	log.Info("handlers registered", "count", len(registry.Types()))
 
+	// Batch tracking sits outermost so it sees panics and timeouts as the
+	// job's final error.
 	registry.Use(
+		fpworker.BatchTrackingMiddleware(deps),
 		middleware.RecoveryMiddleware(zerologger),
 		middleware.LoggingMiddleware(zerologger),
 		middleware.TimeoutMiddleware(cfg.JobTimeout),
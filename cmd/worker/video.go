@@ -30,4 +30,8 @@ func registerVideoProcessors(procRegistry *processor.Registry, log *slog.Logger)
 func registerVideoHandlers(registry *jobqueueworker.Registry, deps *fpworker.Dependencies) {
 	_ = registry.Register("video_thumbnail", fpworker.VideoThumbnailHandler(deps))
 	_ = registry.Register("video_transcode", fpworker.VideoTranscodeHandler(deps))
+	_ = registry.Register("video_storyboard", fpworker.VideoStoryboardHandler(deps))
+	_ = registry.Register("video_preview", fpworker.VideoPreviewHandler(deps))
+	_ = registry.Register("video_hls", fpworker.VideoHLSHandler(deps))
+	_ = registry.Register("video_dash", fpworker.VideoDASHHandler(deps))
 }
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 1000
+)
+
+var errInvalidAuditCursor = errors.New("invalid cursor")
+
+type AuditQuerier interface {
+	GetUserRole(ctx context.Context, id pgtype.UUID) (db.UserRole, error)
+	ListAuditLogs(ctx context.Context, arg db.ListAuditLogsParams) ([]db.AuditLog, error)
+}
+
+type AuditConfig struct {
+	Queries AuditQuerier
+}
+
+type AuditLogResponse struct {
+	ID           string          `json:"id"`
+	UserID       string          `json:"user_id,omitempty"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id,omitempty"`
+	IPAddress    string          `json:"ip_address,omitempty"`
+	UserAgent    string          `json:"user_agent,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+type AuditLogListResponse struct {
+	Entries    []AuditLogResponse `json:"entries"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	HasMore    bool               `json:"has_more"`
+}
+
+// encodeAuditCursor returns an opaque cursor positioned after entry. Entries
+// are ordered newest first by (created_at, id), so the pair is enough to
+// resume without skipping rows that share a timestamp.
+func encodeAuditCursor(entry db.AuditLog) string {
+	raw := entry.CreatedAt.Time.UTC().Format(time.RFC3339Nano) + "," + uuidFromPgtype(entry.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAuditCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidAuditCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return time.Time{}, uuid.Nil, errInvalidAuditCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidAuditCursor
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidAuditCursor
+	}
+	return t, u, nil
+}
+
+// parseAuditFilters builds the query parameters for ListAuditLogs from the
+// request, without the user scope or limit.
+func parseAuditFilters(r *http.Request) (db.ListAuditLogsParams, *apperror.Error) {
+	q := r.URL.Query()
+	var params db.ListAuditLogsParams
+
+	if action := q.Get("action"); action != "" {
+		if !audit.Action(action).Valid() {
+			return params, apperror.WrapWithMessage(nil, "invalid_action", "Unknown audit action: "+action, http.StatusBadRequest)
+		}
+		params.Action = &action
+	}
+
+	if resourceType := q.Get("resource_type"); resourceType != "" {
+		params.ResourceType = &resourceType
+	}
+
+	if resourceID := q.Get("resource_id"); resourceID != "" {
+		id, err := uuid.Parse(resourceID)
+		if err != nil {
+			return params, apperror.WrapWithMessage(err, "invalid_resource_id", "Invalid resource_id parameter", http.StatusBadRequest)
+		}
+		params.ResourceID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	for _, bound := range []struct {
+		name string
+		dest *pgtype.Timestamptz
+	}{
+		{"from", &params.Since},
+		{"to", &params.Until},
+	} {
+		value := q.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return params, apperror.WrapWithMessage(err, "invalid_"+bound.name,
+				fmt.Sprintf("Invalid %s parameter, expected RFC 3339", bound.name), http.StatusBadRequest)
+		}
+		*bound.dest = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		t, id, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return params, apperror.WrapWithMessage(err, "invalid_cursor", "Invalid cursor parameter", http.StatusBadRequest)
+		}
+		params.CursorTime = pgtype.Timestamptz{Time: t, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	return params, nil
+}
+
+// ListAuditLogsHandler returns audit log entries newest first. Users see
+// their own entries; admins see everyone's and may narrow to one user with
+// user_id. format=csv streams the same page as a CSV export.
+func ListAuditLogsHandler(cfg *AuditConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_format", "format must be one of: json, csv", http.StatusBadRequest))
+			return
+		}
+
+		limit := defaultAuditLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			l, err := strconv.Atoi(limitStr)
+			if err != nil || l < 1 || l > maxAuditLimit {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_limit",
+					fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit), http.StatusBadRequest))
+				return
+			}
+			limit = l
+		}
+
+		params, appErr := parseAuditFilters(r)
+		if appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
+
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+		role, err := cfg.Queries.GetUserRole(r.Context(), pgUserID)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		params.UserID = pgUserID
+		if filter := r.URL.Query().Get("user_id"); filter != "" {
+			filterID, err := uuid.Parse(filter)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_user_id", "Invalid user_id parameter", http.StatusBadRequest))
+				return
+			}
+			if filterID != userID && role != db.UserRoleAdmin {
+				apperror.WriteJSON(w, r, apperror.ErrForbidden)
+				return
+			}
+			params.UserID = pgtype.UUID{Bytes: filterID, Valid: true}
+		} else if role == db.UserRoleAdmin {
+			params.UserID = pgtype.UUID{}
+		}
+
+		// Fetch one extra row to learn whether another page exists.
+		params.Limit = int32(limit + 1)
+		entries, err := cfg.Queries.ListAuditLogs(r.Context(), params)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		hasMore := len(entries) > limit
+		if hasMore {
+			entries = entries[:limit]
+		}
+		var nextCursor string
+		if hasMore {
+			nextCursor = encodeAuditCursor(entries[len(entries)-1])
+			w.Header().Set("X-Next-Cursor", nextCursor)
+		}
+
+		if format == "csv" {
+			writeAuditCSV(w, entries)
+			return
+		}
+
+		resp := AuditLogListResponse{
+			Entries:    make([]AuditLogResponse, len(entries)),
+			NextCursor: nextCursor,
+			HasMore:    hasMore,
+		}
+		for i, entry := range entries {
+			resp.Entries[i] = auditLogToResponse(entry)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func auditLogToResponse(entry db.AuditLog) AuditLogResponse {
+	resp := AuditLogResponse{
+		ID:           uuidFromPgtype(entry.ID),
+		UserID:       uuidFromPgtype(entry.UserID),
+		Action:       string(entry.Action),
+		ResourceType: entry.ResourceType,
+		ResourceID:   uuidFromPgtype(entry.ResourceID),
+		CreatedAt:    entry.CreatedAt.Time.UTC().Format(time.RFC3339Nano),
+	}
+	if entry.IpAddress != nil {
+		resp.IPAddress = entry.IpAddress.String()
+	}
+	if entry.UserAgent != nil {
+		resp.UserAgent = *entry.UserAgent
+	}
+	if len(entry.Metadata) > 0 {
+		resp.Metadata = json.RawMessage(entry.Metadata)
+	}
+	return resp
+}
+
+var auditCSVHeader = []string{
+	"id", "created_at", "user_id", "action", "resource_type", "resource_id", "ip_address", "user_agent", "metadata",
+}
+
+func writeAuditCSV(w http.ResponseWriter, entries []db.AuditLog) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.csv"`, time.Now().UTC().Format("20060102-150405")))
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(auditCSVHeader)
+	for _, entry := range entries {
+		resp := auditLogToResponse(entry)
+		_ = cw.Write([]string{
+			resp.ID,
+			resp.CreatedAt,
+			resp.UserID,
+			resp.Action,
+			resp.ResourceType,
+			resp.ResourceID,
+			resp.IPAddress,
+			resp.UserAgent,
+			string(resp.Metadata),
+		})
+	}
+	cw.Flush()
+}
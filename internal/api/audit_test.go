@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
+	"github.com/abdul-hamid-achik/file.cheap/internal/auth"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func seedAuditLogs(q *MockQuerier, userID uuid.UUID, base time.Time, actions ...audit.Action) {
+	for i, action := range actions {
+		q.AddAuditLog(db.AuditLog{
+			ID:           pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			UserID:       pgtype.UUID{Bytes: userID, Valid: true},
+			Action:       db.AuditAction(action),
+			ResourceType: audit.ResourceFile,
+			ResourceID:   pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Metadata:     []byte(`{"n":1}`),
+			CreatedAt:    pgtype.Timestamptz{Time: base.Add(time.Duration(i) * time.Minute), Valid: true},
+		})
+	}
+}
+
+func getAuditLogs(t *testing.T, q *MockQuerier, userID uuid.UUID, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/v1/audit"+query, nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+	rec := httptest.NewRecorder()
+	ListAuditLogsHandler(&AuditConfig{Queries: q}).ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeAuditList(t *testing.T, rec *httptest.ResponseRecorder) AuditLogListResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp AuditLogListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp
+}
+
+func TestListAuditLogsHandler_Pagination(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedAuditLogs(q, userID, base,
+		audit.ActionFileUpload, audit.ActionFileDownload, audit.ActionFileShare,
+		audit.ActionShareAccess, audit.ActionFileDelete)
+	seedAuditLogs(q, uuid.New(), base, audit.ActionFileUpload)
+
+	var got []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		query := "?limit=2"
+		if cursor != "" {
+			query += "&cursor=" + cursor
+		}
+		resp := decodeAuditList(t, getAuditLogs(t, q, userID, query))
+		for _, e := range resp.Entries {
+			if e.UserID != userID.String() {
+				t.Fatalf("entry for user %s leaked into %s's log", e.UserID, userID)
+			}
+			got = append(got, e.Action)
+		}
+		if !resp.HasMore {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	want := []string{"file.delete", "share.access", "file.share", "file.download", "file.upload"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+}
+
+func TestListAuditLogsHandler_Filters(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedAuditLogs(q, userID, base,
+		audit.ActionFileUpload, audit.ActionFileDownload, audit.ActionFileDownload, audit.ActionFileDelete)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"action", "?action=file.download", 2},
+		{"resource type", "?resource_type=file", 4},
+		{"other resource type", "?resource_type=webhook", 0},
+		{"from", "?from=2026-01-01T12:02:00Z", 2},
+		{"to", "?to=2026-01-01T12:02:00Z", 2},
+		{"range and action", "?from=2026-01-01T12:01:00Z&to=2026-01-01T12:02:00Z&action=file.download", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := decodeAuditList(t, getAuditLogs(t, q, userID, tt.query))
+			if len(resp.Entries) != tt.want {
+				t.Errorf("got %d entries, want %d", len(resp.Entries), tt.want)
+			}
+		})
+	}
+}
+
+func TestListAuditLogsHandler_InvalidParams(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+
+	for _, query := range []string{
+		"?action=file.explode",
+		"?resource_id=nope",
+		"?from=yesterday",
+		"?cursor=%21%21",
+		"?limit=0",
+		"?limit=5000",
+		"?format=xml",
+		"?user_id=nope",
+	} {
+		t.Run(query, func(t *testing.T) {
+			rec := getAuditLogs(t, q, userID, query)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400; body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestListAuditLogsHandler_Scope(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	otherID := uuid.New()
+	base := time.Now().Add(-time.Hour)
+	seedAuditLogs(q, userID, base, audit.ActionFileUpload)
+	seedAuditLogs(q, otherID, base, audit.ActionFileUpload, audit.ActionFileDelete)
+
+	rec := getAuditLogs(t, q, userID, "?user_id="+otherID.String())
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin reading another user: status = %d, want 403", rec.Code)
+	}
+
+	q.UserRole = db.UserRoleAdmin
+	if resp := decodeAuditList(t, getAuditLogs(t, q, userID, "")); len(resp.Entries) != 3 {
+		t.Errorf("admin sees %d entries, want 3", len(resp.Entries))
+	}
+	if resp := decodeAuditList(t, getAuditLogs(t, q, userID, "?user_id="+otherID.String())); len(resp.Entries) != 2 {
+		t.Errorf("admin filtered to other user sees %d entries, want 2", len(resp.Entries))
+	}
+}
+
+func TestListAuditLogsHandler_Permission(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	seedAuditLogs(q, userID, time.Now().Add(-time.Hour), audit.ActionFileUpload)
+	handler := withPerm(auth.PermAuditRead, ListAuditLogsHandler(&AuditConfig{Queries: q}))
+
+	for _, tt := range []struct {
+		perms      []string
+		wantStatus int
+	}{
+		{[]string{auth.PermFilesRead, auth.PermFilesWrite}, http.StatusForbidden},
+		{[]string{auth.PermAuditRead}, http.StatusOK},
+	} {
+		ctx := context.WithValue(context.Background(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, PermissionsKey, tt.perms)
+		req := httptest.NewRequest(http.MethodGet, "/v1/audit", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("token with %v: status = %d, want %d", tt.perms, rec.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestListAuditLogsHandler_CSV(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	seedAuditLogs(q, userID, time.Now().Add(-time.Hour), audit.ActionFileUpload, audit.ActionFileDelete)
+
+	rec := getAuditLogs(t, q, userID, "?format=csv&limit=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("Content-Disposition = %q, want attachment", rec.Header().Get("Content-Disposition"))
+	}
+	if rec.Header().Get("X-Next-Cursor") == "" {
+		t.Error("X-Next-Cursor missing on a partial export")
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d rows, want header and one entry", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(auditCSVHeader, ",") {
+		t.Errorf("header = %v", records[0])
+	}
+	if records[1][3] != string(audit.ActionFileDelete) || records[1][8] != `{"n":1}` {
+		t.Errorf("row = %v", records[1])
+	}
+}
+
+func TestAuditHooks_Shares(t *testing.T) {
+	q, s, registry := setupCDNTestDeps(t)
+	userID := uuid.New()
+	fileID := uuid.New()
+	q.AddFile(createTestFileWithID(fileID, userID, "photo.jpg"))
+	cfg := &CDNConfig{Storage: s, Queries: q, Registry: registry}
+
+	ctx := audit.WithLogger(context.Background(), audit.NewLogger(q))
+	ctx = context.WithValue(ctx, UserIDKey, userID)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files/"+fileID.String()+"/share", nil).WithContext(ctx)
+	req.SetPathValue("id", fileID.String())
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	rec := httptest.NewRecorder()
+	CreateShareHandler(cfg, "https://file.cheap").ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create share status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	q.AddShareByToken("tok", createTestShareByToken(fileID, userID, "tok", "uploads/photo.jpg", "image/jpeg", "photo.jpg", nil, nil))
+	s.PresignedURLFn = func(key string, expiry int) (string, error) { return "https://cdn.example.com/" + key, nil }
+	req = httptest.NewRequest(http.MethodGet, "/cdn/tok/_/photo.jpg", nil).WithContext(audit.WithLogger(context.Background(), audit.NewLogger(q)))
+	req.SetPathValue("token", "tok")
+	req.SetPathValue("transforms", "_")
+	req.SetPathValue("filename", "photo.jpg")
+	rec = httptest.NewRecorder()
+	CDNHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("cdn status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	// The share access is written in the background
+	var logs []db.AuditLog
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if logs = q.AuditLogs(); len(logs) >= 2 {
+			break
+		}
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(logs))
+	}
+	if logs[0].Action != db.AuditAction(audit.ActionFileShare) || uuid.UUID(logs[0].ResourceID.Bytes) != fileID {
+		t.Errorf("first entry = %s on %s, want file.share on the file", logs[0].Action, uuidFromPgtype(logs[0].ResourceID))
+	}
+	if logs[0].IpAddress == nil || logs[0].IpAddress.String() != "203.0.113.7" {
+		t.Errorf("ip = %v, want the first forwarded address", logs[0].IpAddress)
+	}
+	if logs[1].Action != db.AuditAction(audit.ActionShareAccess) || uuid.UUID(logs[1].UserID.Bytes) != userID {
+		t.Errorf("second entry = %s for %s, want share.access for the owner", logs[1].Action, uuidFromPgtype(logs[1].UserID))
+	}
+}
+
+func TestAuditHooks_NoLogger(t *testing.T) {
+	q, s, registry := setupCDNTestDeps(t)
+	userID := uuid.New()
+	fileID := uuid.New()
+	q.AddFile(createTestFileWithID(fileID, userID, "photo.jpg"))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files/"+fileID.String()+"/share", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+	req.SetPathValue("id", fileID.String())
+	rec := httptest.NewRecorder()
+	CreateShareHandler(&CDNConfig{Storage: s, Queries: q, Registry: registry}, "").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if n := len(q.AuditLogs()); n != 0 {
+		t.Errorf("got %d audit entries without a logger, want 0", n)
+	}
+}
@@ -14,10 +14,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/abdul-hamid-achik/file.cheap/internal/streaming"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"golang.org/x/crypto/bcrypt"
@@ -61,12 +66,63 @@ type CDNQuerier interface {
 	CreateFileShare(ctx context.Context, arg db.CreateFileShareParams) (db.FileShare, error)
 	ListFileSharesByFile(ctx context.Context, fileID pgtype.UUID) ([]db.FileShare, error)
 	DeleteFileShare(ctx context.Context, arg db.DeleteFileShareParams) error
+	GetTransformPresetByName(ctx context.Context, arg db.GetTransformPresetByNameParams) (db.TransformPreset, error)
+	GetVariant(ctx context.Context, arg db.GetVariantParams) (db.FileVariant, error)
 }
 
 type CDNConfig struct {
 	Storage  storage.Storage
 	Queries  CDNQuerier
 	Registry *processor.Registry
+	// AVIFEnabled lets f_auto negotiate AVIF output. Without it f_avif is
+	// rejected.
+	AVIFEnabled bool
+	// WebhookDispatcher, when set, announces share.created and share.accessed.
+	WebhookDispatcher *webhook.Dispatcher
+	// accesses de-duplicates share.accessed events; nil uses a process-wide
+	// tracker.
+	accesses *shareAccessTracker
+}
+
+func (c *CDNConfig) shareAccesses() *shareAccessTracker {
+	if c.accesses != nil {
+		return c.accesses
+	}
+	return defaultShareAccesses
+}
+
+// recordShareAccess counts a validated request for a share, and audits and
+// announces the first one from each client per shareAccessWindow. It must
+// only be called once the request has passed every check, so rejected
+// requests are never reported.
+func (c *CDNConfig) recordShareAccess(r *http.Request, share db.GetFileShareByTokenRow, transforms string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = c.Queries.IncrementShareAccessCount(ctx, share.ID)
+	}()
+
+	if !c.shareAccesses().first(uuidFromPgtype(share.ID), shareClient(r), time.Now()) {
+		return
+	}
+
+	// Share accesses are attributed to the owner of the shared file.
+	audit.RecordAsync(r, audit.Entry{
+		UserID:       uuid.UUID(share.UserID.Bytes),
+		Action:       audit.ActionShareAccess,
+		ResourceType: audit.ResourceShare,
+		ResourceID:   uuid.UUID(share.ID.Bytes),
+		Metadata: map[string]any{
+			"file_id":    uuidFromPgtype(share.FileID),
+			"transforms": transforms,
+		},
+	})
+	if c.WebhookDispatcher != nil {
+		event, err := webhook.NewShareAccessedEvent(uuidFromPgtype(share.ID), uuidFromPgtype(share.FileID), transforms)
+		if err == nil {
+			dispatchWebhook(r.Context(), c.WebhookDispatcher, uuid.UUID(share.UserID.Bytes), event)
+		}
+	}
 }
 
 func GenerateShareToken() (string, error) {
@@ -98,56 +154,29 @@ func CDNHandler(cfg *CDNConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.FromContext(r.Context())
 
-		token := r.PathValue("token")
 		transforms := r.PathValue("transforms")
 		filename := r.PathValue("filename")
 
-		if token == "" {
-			http.Error(w, `{"error":{"code":"bad_request","message":"missing share token"}}`, http.StatusBadRequest)
+		share, ok := authorizeShare(w, r, cfg)
+		if !ok {
 			return
 		}
 
-		share, err := cfg.Queries.GetFileShareByToken(r.Context(), token)
+		// preset_<name> refers to a preset of the file's owner.
+		expanded, err := expandPresetTransforms(r.Context(), cfg.Queries, share.UserID, transforms)
 		if err != nil {
-			log.Debug("share not found", "token", token, "error", err)
-			http.Error(w, `{"error":{"code":"not_found","message":"share not found or expired"}}`, http.StatusNotFound)
+			http.Error(w, fmt.Sprintf(`{"error":{"code":"bad_request","message":"%s"}}`, err.Error()), http.StatusBadRequest)
 			return
 		}
 
-		if share.PasswordHash != nil && *share.PasswordHash != "" {
-			password := r.Header.Get("X-Share-Password")
-			if password == "" {
-				http.Error(w, `{"error":{"code":"password_required","message":"This share is password protected"}}`, http.StatusUnauthorized)
-				return
-			}
-			if err := bcrypt.CompareHashAndPassword([]byte(*share.PasswordHash), []byte(password)); err != nil {
-				http.Error(w, `{"error":{"code":"invalid_password","message":"Invalid password"}}`, http.StatusUnauthorized)
-				return
-			}
-		}
-
-		if share.MaxDownloads != nil {
-			limitReached, err := cfg.Queries.IsShareDownloadLimitReached(r.Context(), share.ID)
-			if err == nil && limitReached {
-				http.Error(w, `{"error":{"code":"download_limit_reached","message":"Download limit reached for this share"}}`, http.StatusForbidden)
-				return
-			}
-		}
-
-		go func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			_ = cfg.Queries.IncrementShareAccessCount(ctx, share.ID)
-		}()
-
-		opts, err := ParseTransforms(transforms)
+		pipeline, err := ParsePipeline(expanded)
 		if err != nil {
 			log.Debug("invalid transforms", "transforms", transforms, "error", err)
 			http.Error(w, fmt.Sprintf(`{"error":{"code":"bad_request","message":"%s"}}`, err.Error()), http.StatusBadRequest)
 			return
 		}
 
-		if err := ValidateTransforms(opts); err != nil {
+		if err := ValidatePipeline(pipeline); err != nil {
 			http.Error(w, fmt.Sprintf(`{"error":{"code":"bad_request","message":"%s"}}`, err.Error()), http.StatusBadRequest)
 			return
 		}
@@ -159,17 +188,29 @@ func CDNHandler(cfg *CDNConfig) http.HandlerFunc {
 			}
 		}
 
-		if !opts.RequiresProcessing() {
+		if !cfg.AVIFEnabled && pipeline.HasFormat("avif") {
+			http.Error(w, `{"error":{"code":"format_unavailable","message":"AVIF output is not available on this server"}}`, http.StatusBadRequest)
+			return
+		}
+
+		if pipeline.HasAutoFormat() {
+			pipeline.ResolveAutoFormat(NegotiateFormat(r.Header.Get("Accept"), share.ContentType, cfg.AVIFEnabled))
+			w.Header().Set("Vary", "Accept")
+		}
+
+		cfg.recordShareAccess(r, share, transforms)
+
+		if !pipeline.RequiresProcessing() {
 			go func() {
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
 				_ = cfg.Queries.IncrementShareDownloadCount(ctx, share.ID)
 			}()
-			serveOriginal(w, r, cfg, share.StorageKey, share.ContentType, filename)
+			serveOriginal(w, r, cfg, share.StorageKey, share.ContentType, filename, share.SizeBytes, share.UpdatedAt.Time)
 			return
 		}
 
-		cacheKey := opts.CacheKey()
+		cacheKey := pipeline.CacheKey()
 		fileID := share.FileID
 
 		cached, err := cfg.Queries.GetTransformCache(r.Context(), db.GetTransformCacheParams{
@@ -189,7 +230,7 @@ func CDNHandler(cfg *CDNConfig) http.HandlerFunc {
 
 			// Generate ETag from cache key and cache entry timestamp
 			etag := generateETag(cacheKey, cached.CreatedAt.Time)
-			serveCached(w, r, cfg, cached.StorageKey, cached.ContentType, filename, etag)
+			serveCached(w, r, cfg, cached.StorageKey, cached.ContentType, filename, etag, cached.SizeBytes)
 			return
 		}
 
@@ -200,7 +241,7 @@ func CDNHandler(cfg *CDNConfig) http.HandlerFunc {
 
 		shouldCache := requestCount >= cacheThreshold
 
-		result, err := processTransform(r.Context(), cfg, share.StorageKey, share.ContentType, opts)
+		result, err := processTransform(r.Context(), cfg, share.StorageKey, share.ContentType, pipeline)
 		if err != nil {
 			log.Error("transform failed", "error", err)
 			http.Error(w, `{"error":{"code":"processing_error","message":"failed to process image"}}`, http.StatusInternalServerError)
@@ -223,6 +264,89 @@ func CDNHandler(cfg *CDNConfig) http.HandlerFunc {
 	}
 }
 
+// authorizeShare resolves the share named by the token path value and checks
+// its password and download limit, writing the error response if access is
+// denied.
+func authorizeShare(w http.ResponseWriter, r *http.Request, cfg *CDNConfig) (db.GetFileShareByTokenRow, bool) {
+	token := r.PathValue("token")
+	if token == "" {
+		http.Error(w, `{"error":{"code":"bad_request","message":"missing share token"}}`, http.StatusBadRequest)
+		return db.GetFileShareByTokenRow{}, false
+	}
+
+	share, err := cfg.Queries.GetFileShareByToken(r.Context(), token)
+	if err != nil {
+		logger.FromContext(r.Context()).Debug("share not found", "token", token, "error", err)
+		http.Error(w, `{"error":{"code":"not_found","message":"share not found or expired"}}`, http.StatusNotFound)
+		return share, false
+	}
+
+	if share.PasswordHash != nil && *share.PasswordHash != "" {
+		password := r.Header.Get("X-Share-Password")
+		if password == "" {
+			http.Error(w, `{"error":{"code":"password_required","message":"This share is password protected"}}`, http.StatusUnauthorized)
+			return share, false
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(*share.PasswordHash), []byte(password)); err != nil {
+			http.Error(w, `{"error":{"code":"invalid_password","message":"Invalid password"}}`, http.StatusUnauthorized)
+			return share, false
+		}
+	}
+
+	if share.MaxDownloads != nil {
+		limitReached, err := cfg.Queries.IsShareDownloadLimitReached(r.Context(), share.ID)
+		if err == nil && limitReached {
+			http.Error(w, `{"error":{"code":"download_limit_reached","message":"Download limit reached for this share"}}`, http.StatusForbidden)
+			return share, false
+		}
+	}
+
+	return share, true
+}
+
+// ShareHLSHandler streams the HLS package of a shared video. Playlists are
+// rewritten so segments are fetched through the share as well; successfully
+// opening the master playlist counts as one access and one download, and is
+// what gets audited and announced to webhooks.
+func ShareHLSHandler(cfg *CDNConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		share, ok := authorizeShare(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		rendition, name := r.PathValue("rendition"), r.PathValue("segment")
+		base := "/cdn/" + r.PathValue("token") + "/hls"
+		if rendition != "" || name != video.HLSMasterPlaylist {
+			streaming.ServeHLS(w, r, cfg.Queries, cfg.Storage, share.FileID, rendition, name, base)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		streaming.ServeHLS(sw, r, cfg.Queries, cfg.Storage, share.FileID, rendition, name, base)
+		if sw.status >= http.StatusBadRequest {
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = cfg.Queries.IncrementShareDownloadCount(ctx, share.ID)
+		}()
+		cfg.recordShareAccess(r, share, "hls")
+	}
+}
+
+// statusWriter remembers the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
 func isTransformAllowed(requested string, allowed []string) bool {
 	if requested == "" || requested == "_" || requested == "original" {
 		return true
@@ -235,25 +359,36 @@ func isTransformAllowed(requested string, allowed []string) bool {
 	return false
 }
 
-func serveOriginal(w http.ResponseWriter, r *http.Request, cfg *CDNConfig, storageKey, contentType, filename string) {
+func serveOriginal(w http.ResponseWriter, r *http.Request, cfg *CDNConfig, storageKey, contentType, filename string, size int64, modified time.Time) {
+	w.Header().Set("Cache-Control", getCacheControl(contentType))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	if serveRangeRequest(w, r, cfg.Storage, storageKey, contentType, size, objectETag(storageKey, size, modified)) {
+		return
+	}
+
 	url, err := cfg.Storage.GetPresignedURL(r.Context(), storageKey, 3600)
 	if err != nil {
 		http.Error(w, `{"error":{"code":"internal","message":"failed to generate URL"}}`, http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Cache-Control", getCacheControl(contentType))
-	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	w.Header().Set("Accept-Ranges", "bytes")
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func serveCached(w http.ResponseWriter, r *http.Request, cfg *CDNConfig, storageKey, contentType, filename, etag string) {
+func serveCached(w http.ResponseWriter, r *http.Request, cfg *CDNConfig, storageKey, contentType, filename, etag string, size int64) {
 	// Check for conditional request
 	if etag != "" && checkETag(r, etag) {
 		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
+	w.Header().Set("Cache-Control", getCacheControl(contentType))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	if serveRangeRequest(w, r, cfg.Storage, storageKey, contentType, size, etag) {
+		return
+	}
+
 	url, err := cfg.Storage.GetPresignedURL(r.Context(), storageKey, 3600)
 	if err != nil {
 		http.Error(w, `{"error":{"code":"internal","message":"failed to generate URL"}}`, http.StatusInternalServerError)
@@ -263,29 +398,18 @@ func serveCached(w http.ResponseWriter, r *http.Request, cfg *CDNConfig, storage
 	if etag != "" {
 		w.Header().Set("ETag", etag)
 	}
-	w.Header().Set("Cache-Control", getCacheControl(contentType))
-	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	w.Header().Set("Accept-Ranges", "bytes")
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func processTransform(ctx context.Context, cfg *CDNConfig, storageKey, contentType string, opts *TransformOptions) (*processor.Result, error) {
+func processTransform(ctx context.Context, cfg *CDNConfig, storageKey, contentType string, pipeline *TransformPipeline) (*processor.Result, error) {
 	reader, err := cfg.Storage.Download(ctx, storageKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
 	defer func() { _ = reader.Close() }()
 
-	procName := opts.ProcessorNameForContentType(contentType)
-	if procName == "" {
-		procName = "resize"
-	}
-
-	proc, ok := cfg.Registry.Get(procName)
-	if !ok {
-		return nil, fmt.Errorf("processor not found: %s", procName)
-	}
-
-	result, err := proc.Process(ctx, opts.ToProcessorOptions(), reader)
+	result, err := cfg.Registry.RunPipeline(ctx, pipeline.ProcessorSteps(contentType), reader)
 	if err != nil {
 		return nil, fmt.Errorf("processing failed: %w", err)
 	}
@@ -301,7 +425,7 @@ func cacheResult(ctx context.Context, cfg *CDNConfig, fileID pgtype.UUID, cacheK
 	}
 
 	fileUUID, _ := uuid.FromBytes(fileID.Bytes[:])
-	storageKey := fmt.Sprintf("cache/%s/%s/%s", fileUUID.String(), cacheKey, result.Filename)
+	storageKey := worker.TransformCacheKey(fileUUID.String(), cacheKey, result.Filename)
 
 	uploadCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -432,8 +556,31 @@ func CreateShareHandler(cfg *CDNConfig, baseURL string) http.HandlerFunc {
 			return
 		}
 
+		audit.Record(r, audit.Entry{
+			UserID:       userID,
+			Action:       audit.ActionFileShare,
+			ResourceType: audit.ResourceFile,
+			ResourceID:   fileID,
+			Metadata: map[string]any{
+				"share_id":     uuidFromPgtype(share.ID),
+				"has_password": passwordHash != nil,
+				"expires":      expiresAt.Valid,
+			},
+		})
+
 		shareURL := fmt.Sprintf("%s/cdn/%s/_/%s", baseURL, token, file.Filename)
 
+		if cfg.WebhookDispatcher != nil {
+			var eventExpiresAt *time.Time
+			if expiresAt.Valid {
+				eventExpiresAt = &expiresAt.Time
+			}
+			event, err := webhook.NewShareCreatedEvent(uuidFromPgtype(share.ID), fileIDStr, shareURL, eventExpiresAt)
+			if err == nil {
+				dispatchWebhook(r.Context(), cfg.WebhookDispatcher, userID, event)
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		_, _ = fmt.Fprintf(w, `{"id":"%s","token":"%s","share_url":"%s"`, uuidFromPgtype(share.ID), token, shareURL)
@@ -528,6 +675,12 @@ func DeleteShareHandler(cfg *CDNConfig) http.HandlerFunc {
 			return
 		}
 
+		audit.Record(r, audit.Entry{
+			UserID:       userID,
+			Action:       audit.ActionShareDelete,
+			ResourceType: audit.ResourceShare,
+			ResourceID:   shareID,
+		})
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
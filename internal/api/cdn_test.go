@@ -186,6 +186,46 @@ func TestCDNHandler_TokenValidation(t *testing.T) {
 	}
 }
 
+func TestCDNHandler_RangeOriginal(t *testing.T) {
+	queries, store, registry := setupCDNTestDeps(t)
+	content := "0123456789"
+
+	share := createTestShareByToken(
+		uuid.New(), uuid.New(), "range-token",
+		"uploads/clip.mp4", "video/mp4", "clip.mp4",
+		nil, nil,
+	)
+	share.SizeBytes = int64(len(content))
+	queries.AddShareByToken("range-token", share)
+	if err := store.Upload(context.Background(), share.StorageKey, strings.NewReader(content), share.ContentType, share.SizeBytes); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	handler := CDNHandler(&CDNConfig{Storage: store, Queries: queries, Registry: registry})
+
+	req := httptest.NewRequest("GET", "/cdn/range-token/_/clip.mp4", nil)
+	req.SetPathValue("token", "range-token")
+	req.SetPathValue("transforms", "_")
+	req.SetPathValue("filename", "clip.mp4")
+	req.Header.Set("Range", "bytes=2-4")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusPartialContent, rec.Body.String())
+	}
+	if rec.Body.String() != "234" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "234")
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 2-4/10" {
+		t.Errorf("Content-Range = %q, want %q", got, "bytes 2-4/10")
+	}
+	if got := rec.Header().Get("Content-Type"); got != "video/mp4" {
+		t.Errorf("Content-Type = %q, want video/mp4", got)
+	}
+}
+
 func TestCDNHandler_TransformParsing(t *testing.T) {
 	fileID := uuid.New()
 	userID := uuid.New()
@@ -1106,3 +1146,128 @@ func TestDeleteShareHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestCDNHandler_Pipeline(t *testing.T) {
+	fileID := uuid.New()
+	userID := uuid.New()
+	queries, store, registry := setupCDNTestDeps(t)
+
+	queries.AddShareByToken("chain-token", createTestShareByToken(fileID, userID, "chain-token",
+		"uploads/test.jpg", "image/jpeg", "test.jpg", nil, nil))
+	_ = store.MemoryStorage.Upload(context.Background(), "uploads/test.jpg",
+		bytes.NewReader([]byte("original")), "image/jpeg", 8)
+
+	var calls []string
+	for _, name := range []string{"resize", "watermark", "webp"} {
+		registry.Register(name, &MockProcessor{
+			name:  name,
+			types: []string{"image/jpeg"},
+			processFunc: func(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
+				data, _ := io.ReadAll(input)
+				calls = append(calls, name)
+				out := append(data, []byte(">"+name)...)
+				return &processor.Result{Data: bytes.NewReader(out), ContentType: "image/webp", Filename: "out.webp"}, nil
+			},
+		})
+	}
+
+	handler := CDNHandler(&CDNConfig{Storage: store, Queries: queries, Registry: registry})
+	req := httptest.NewRequest("GET", "/cdn/chain-token/w_800,wm_foo,f_webp/test.jpg", nil)
+	req.SetPathValue("token", "chain-token")
+	req.SetPathValue("transforms", "w_800,wm_foo,f_webp")
+	req.SetPathValue("filename", "test.jpg")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if want := "original>resize>watermark>webp"; rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if strings.Join(calls, ",") != "resize,watermark,webp" {
+		t.Errorf("processors ran in order %v", calls)
+	}
+}
+
+func TestCDNHandler_AutoFormat(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		avif            bool
+		wantProcessor   string
+		wantContentType string
+	}{
+		{name: "avif client", accept: "image/avif,image/webp,*/*", avif: true, wantProcessor: "convert", wantContentType: "image/avif"},
+		{name: "avif without encoder", accept: "image/avif,image/webp,*/*", avif: false, wantProcessor: "webp", wantContentType: "image/webp"},
+		{name: "legacy client", accept: "*/*", avif: true, wantProcessor: "convert", wantContentType: "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileID := uuid.New()
+			userID := uuid.New()
+			queries, store, registry := setupCDNTestDeps(t)
+
+			queries.AddShareByToken("auto-token", createTestShareByToken(fileID, userID, "auto-token",
+				"uploads/test.jpg", "image/jpeg", "test.jpg", nil, nil))
+			_ = store.MemoryStorage.Upload(context.Background(), "uploads/test.jpg",
+				bytes.NewReader([]byte("original")), "image/jpeg", 8)
+
+			var ran string
+			var gotFormat string
+			for _, name := range []string{"webp", "convert"} {
+				registry.Register(name, &MockProcessor{
+					name:  name,
+					types: []string{"image/jpeg"},
+					processFunc: func(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
+						ran, gotFormat = name, opts.Format
+						return &processor.Result{Data: bytes.NewReader([]byte("out")), ContentType: "image/" + opts.Format}, nil
+					},
+				})
+			}
+
+			handler := CDNHandler(&CDNConfig{Storage: store, Queries: queries, Registry: registry, AVIFEnabled: tt.avif})
+			req := httptest.NewRequest("GET", "/cdn/auto-token/f_auto/test.jpg", nil)
+			req.Header.Set("Accept", tt.accept)
+			req.SetPathValue("token", "auto-token")
+			req.SetPathValue("transforms", "f_auto")
+			req.SetPathValue("filename", "test.jpg")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if ran != tt.wantProcessor {
+				t.Errorf("processor = %q, want %q", ran, tt.wantProcessor)
+			}
+			if got := "image/" + gotFormat; got != tt.wantContentType {
+				t.Errorf("negotiated %q, want %q", got, tt.wantContentType)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept", got)
+			}
+		})
+	}
+}
+
+func TestCDNHandler_AVIFUnavailable(t *testing.T) {
+	fileID := uuid.New()
+	userID := uuid.New()
+	queries, store, registry := setupCDNTestDeps(t)
+	queries.AddShareByToken("avif-token", createTestShareByToken(fileID, userID, "avif-token",
+		"uploads/test.jpg", "image/jpeg", "test.jpg", nil, nil))
+
+	handler := CDNHandler(&CDNConfig{Storage: store, Queries: queries, Registry: registry})
+	req := httptest.NewRequest("GET", "/cdn/avif-token/w_400,f_avif/test.jpg", nil)
+	req.SetPathValue("token", "avif-token")
+	req.SetPathValue("transforms", "w_400,f_avif")
+	req.SetPathValue("filename", "test.jpg")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "format_unavailable") {
+		t.Errorf("status = %d, want 400 format_unavailable; body = %s", rec.Code, rec.Body.String())
+	}
+}
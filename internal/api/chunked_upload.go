@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -15,15 +16,21 @@ import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
 	"github.com/abdul-hamid-achik/file.cheap/internal/billing"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/filetype"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
-	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/abdul-hamid-achik/file.cheap/internal/uploadrules"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// chunkAssembleLockTTL bounds how long the request that delivered the last
+// chunk holds its claim on the upload while assembling it.
+const chunkAssembleLockTTL = 10 * time.Minute
+
 // ChunkedUploadConfig holds configuration for chunked uploads
 type ChunkedUploadConfig struct {
 	Storage       storage.Storage
@@ -31,6 +38,17 @@ type ChunkedUploadConfig struct {
 	Broker        Broker
 	MaxUploadSize int64
 	ChunkSize     int64 // Default chunk size (5MB minimum for S3)
+	// Sessions holds upload state; nil uses a process-local store.
+	Sessions UploadSessionStore
+	// WebhookDispatcher announces completed presigned uploads.
+	WebhookDispatcher *webhook.Dispatcher
+}
+
+func (c *ChunkedUploadConfig) sessions() UploadSessionStore {
+	if c.Sessions != nil {
+		return c.Sessions
+	}
+	return defaultSessionStore
 }
 
 // uploadSession tracks an in-progress chunked upload
@@ -41,98 +59,29 @@ type uploadSession struct {
 	ContentType  string
 	TotalSize    int64
 	ChunksTotal  int
-	ChunksLoaded map[int]bool
+	ChunksLoaded map[int]bool `json:"-"`
 	StorageKey   string
 	CreatedAt    time.Time
-	mu           sync.Mutex
-}
-
-// uploadSessionStore stores active upload sessions (in-memory for single instance)
-// For production, use Redis
-type uploadSessionStore struct {
-	sessions map[string]*uploadSession
-	mu       sync.RWMutex
-	done     chan struct{}
-}
-
-var sessionStore = &uploadSessionStore{
-	sessions: make(map[string]*uploadSession),
-	done:     make(chan struct{}),
-}
-
-// StartCleanup starts a background goroutine to clean up expired upload sessions
-func (s *uploadSessionStore) StartCleanup(ctx context.Context, storageClient storage.Storage) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ticker.C:
-			s.cleanupExpired(ctx, storageClient)
-		case <-s.done:
-			return
-		case <-ctx.Done():
-			return
-		}
-	}
-}
-
-// StopCleanup stops the cleanup goroutine
-func (s *uploadSessionStore) StopCleanup() {
-	select {
-	case <-s.done:
-		// already closed
-	default:
-		close(s.done)
-	}
-}
-
-// cleanupExpired removes sessions older than 1 hour and their orphaned chunks
-func (s *uploadSessionStore) cleanupExpired(ctx context.Context, storageClient storage.Storage) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	cutoff := time.Now().Add(-1 * time.Hour)
-	for id, session := range s.sessions {
-		if session.CreatedAt.Before(cutoff) {
-			// Delete orphaned chunks from storage
-			for i := 0; i < session.ChunksTotal; i++ {
-				chunkKey := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
-				_ = storageClient.Delete(ctx, chunkKey)
-			}
-			delete(s.sessions, id)
-		}
-	}
-}
-
-// GetSessionStore returns the global session store for cleanup initialization
-func GetSessionStore() *uploadSessionStore {
-	return sessionStore
-}
-
-func (s *uploadSessionStore) Get(id string) (*uploadSession, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	session, ok := s.sessions[id]
-	return session, ok
-}
-
-func (s *uploadSessionStore) Set(session *uploadSession) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.sessions[session.ID] = session
-}
-
-func (s *uploadSessionStore) Delete(id string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.sessions, id)
+	// Offset and FileID track tus uploads, whose chunk count is not known
+	// until the final PATCH arrives.
+	Offset int64
+	FileID string
+	// Direct marks presigned uploads, whose body is written straight to
+	// StorageKey by the client rather than in chunks.
+	Direct bool
+	// FolderID and Tags file the finished upload before upload rules run.
+	FolderID string
+	Tags     []string
+	mu       sync.Mutex
 }
 
 // InitUploadRequest is the request to start a chunked upload
 type InitUploadRequest struct {
-	Filename    string `json:"filename"`
-	ContentType string `json:"content_type"`
-	TotalSize   int64  `json:"total_size"`
+	Filename    string   `json:"filename"`
+	ContentType string   `json:"content_type"`
+	TotalSize   int64    `json:"total_size"`
+	FolderID    string   `json:"folder_id,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 // InitUploadResponse is returned when starting a chunked upload
@@ -174,32 +123,15 @@ func InitChunkedUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 			return
 		}
 
-		billingInfo := GetBilling(r.Context())
-		if billingInfo != nil {
-			if billingInfo.FilesLimit >= 0 && billingInfo.FilesCount >= int64(billingInfo.FilesLimit) {
-				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "file_limit_reached", "File limit reached", http.StatusForbidden))
-				return
-			}
-
-			maxSize := billingInfo.MaxFileSize
-			limits := billing.GetTierLimits(billingInfo.Tier)
-
-			if video.IsVideoType(req.ContentType) {
-				maxSize = limits.MaxVideoSize
-
-				// Check video storage quota
-				pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
-				videoUsageBytes, err := cfg.Queries.GetUserVideoStorageUsage(r.Context(), pgUserID)
-				if err == nil && videoUsageBytes+req.TotalSize > limits.VideoStorageBytes {
-					apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "video_storage_quota_exceeded", "Video storage quota exceeded, please upgrade or delete old videos", http.StatusForbidden))
-					return
-				}
-			}
+		if appErr := checkUploadQuota(r.Context(), cfg, userID, req.ContentType, req.TotalSize); appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
 
-			if req.TotalSize > maxSize {
-				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "file_too_large", fmt.Sprintf("File too large, max size: %d MB", maxSize/(1024*1024)), http.StatusForbidden))
-				return
-			}
+		placement, appErr := cfg.parsePlacement(r.Context(), userID, req.FolderID, req.Tags)
+		if appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
 		}
 
 		chunkSize := cfg.ChunkSize
@@ -221,9 +153,15 @@ func InitChunkedUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 			ChunksLoaded: make(map[int]bool),
 			StorageKey:   storageKey,
 			CreatedAt:    time.Now(),
+			FolderID:     req.FolderID,
+			Tags:         placement.Tags,
 		}
 
-		sessionStore.Set(session)
+		if err := cfg.sessions().Set(r.Context(), session); err != nil {
+			log.Error("failed to save upload session", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
 
 		log.Info("chunked upload initiated",
 			"upload_id", uploadID,
@@ -241,6 +179,38 @@ func InitChunkedUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 	}
 }
 
+// checkUploadQuota enforces the tier file count, file size and video storage
+// limits for an upload of totalSize bytes announced before any data arrives.
+func checkUploadQuota(ctx context.Context, cfg *ChunkedUploadConfig, userID uuid.UUID, contentType string, totalSize int64) *apperror.Error {
+	billingInfo := GetBilling(ctx)
+	if billingInfo == nil {
+		return nil
+	}
+
+	if billingInfo.FilesLimit >= 0 && billingInfo.FilesCount >= int64(billingInfo.FilesLimit) {
+		return apperror.WrapWithMessage(nil, "file_limit_reached", "File limit reached", http.StatusForbidden)
+	}
+
+	maxSize := billingInfo.MaxFileSize
+	limits := billing.GetTierLimits(billingInfo.Tier)
+
+	if video.IsVideoType(contentType) {
+		maxSize = limits.MaxVideoSize
+
+		// Check video storage quota
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+		videoUsageBytes, err := cfg.Queries.GetUserVideoStorageUsage(ctx, pgUserID)
+		if err == nil && videoUsageBytes+totalSize > limits.VideoStorageBytes {
+			return apperror.WrapWithMessage(nil, "video_storage_quota_exceeded", "Video storage quota exceeded, please upgrade or delete old videos", http.StatusForbidden)
+		}
+	}
+
+	if totalSize > maxSize {
+		return apperror.WrapWithMessage(nil, "file_too_large", fmt.Sprintf("File too large, max size: %d MB", maxSize/(1024*1024)), http.StatusForbidden)
+	}
+	return nil
+}
+
 // UploadChunkHandler handles uploading a single chunk
 func UploadChunkHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -260,9 +230,9 @@ func UploadChunkHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 			return
 		}
 
-		session, ok := sessionStore.Get(uploadID)
-		if !ok {
-			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "session_not_found", "Upload session not found", http.StatusNotFound))
+		session, err := cfg.sessions().Get(r.Context(), uploadID)
+		if err != nil {
+			writeSessionLookupError(w, r, err)
 			return
 		}
 
@@ -290,11 +260,13 @@ func UploadChunkHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 			return
 		}
 
-		session.mu.Lock()
-		session.ChunksLoaded[chunkIndex] = true
-		chunksLoaded := len(session.ChunksLoaded)
+		chunksLoaded, err := cfg.sessions().MarkChunkLoaded(r.Context(), uploadID, chunkIndex)
+		if err != nil {
+			log.Error("failed to record chunk", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
 		complete := chunksLoaded == session.ChunksTotal
-		session.mu.Unlock()
 
 		log.Debug("chunk uploaded",
 			"upload_id", uploadID,
@@ -312,14 +284,42 @@ func UploadChunkHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 		}
 
 		if complete {
+			// A retried final chunk also sees every chunk loaded, so only the
+			// request holding the claim assembles. Reloading the session under
+			// the claim turns a retry after assembly into a 404.
+			unlock, err := cfg.sessions().Lock(r.Context(), uploadID, chunkAssembleLockTTL)
+			if err != nil {
+				if errors.Is(err, errUploadSessionLocked) {
+					apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "upload_locked", "This upload is already being assembled", http.StatusLocked))
+					return
+				}
+				log.Error("failed to lock upload session", "error", err)
+				apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+				return
+			}
+			defer unlock()
+
+			session, err = cfg.sessions().Get(r.Context(), uploadID)
+			if err != nil {
+				writeSessionLookupError(w, r, err)
+				return
+			}
+
 			fileID, err := assembleChunks(r.Context(), cfg, session, log)
 			if err != nil {
+				var appErr *apperror.Error
+				if errors.As(err, &appErr) {
+					_ = cfg.sessions().Delete(r.Context(), uploadID)
+					apperror.WriteJSON(w, r, appErr)
+					return
+				}
 				log.Error("failed to assemble chunks", "error", err)
 				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "assemble_failed", "Failed to assemble file", http.StatusInternalServerError))
 				return
 			}
 			response.FileID = fileID
-			sessionStore.Delete(uploadID)
+			_ = cfg.sessions().Delete(r.Context(), uploadID)
+			recordUpload(r, session.UserID, fileID, session.Filename, session.ContentType, session.TotalSize, "chunked")
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -353,26 +353,35 @@ func assembleChunks(ctx context.Context, cfg *ChunkedUploadConfig, session *uplo
 		}
 	}()
 
+	// Verify the declared type against the first chunk's magic bytes
+	detectedType, body, err := filetype.Sniff(pr)
+	if err != nil {
+		_ = pr.CloseWithError(err)
+		return "", fmt.Errorf("failed to read file header: %w", err)
+	}
+	contentType, err := filetype.Resolve(session.ContentType, detectedType)
+	if err != nil {
+		_ = pr.CloseWithError(err)
+		deleteChunks(ctx, cfg.Storage, session)
+		log.Warn("chunked upload content rejected", "declared_type", session.ContentType, "detected_type", detectedType)
+		return "", contentMismatchError(err)
+	}
+
 	// Upload from pipe reader (streams directly to storage)
-	if err := cfg.Storage.Upload(ctx, session.StorageKey, pr, session.ContentType, session.TotalSize); err != nil {
+	hashingBody := storage.NewHashingReader(body)
+	if err := cfg.Storage.Upload(ctx, session.StorageKey, hashingBody, contentType, session.TotalSize); err != nil {
 		metrics.RecordFileUpload("error", 0, time.Since(startTime).Seconds())
 		return "", fmt.Errorf("failed to upload combined file: %w", err)
 	}
 
+	contentHash := hashingBody.Sum()
+
 	// Clean up chunks after successful assembly
-	for i := 0; i < session.ChunksTotal; i++ {
-		chunkKey := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
-		_ = cfg.Storage.Delete(ctx, chunkKey)
-	}
+	deleteChunks(ctx, cfg.Storage, session)
 
 	if cfg.Queries != nil {
 		pgUserID := pgtype.UUID{Bytes: session.UserID, Valid: true}
 
-		contentType := session.ContentType
-		if contentType == "" {
-			contentType = "application/octet-stream"
-		}
-
 		dbFile, err := cfg.Queries.CreateFile(ctx, db.CreateFileParams{
 			UserID:      pgUserID,
 			Filename:    session.Filename,
@@ -380,6 +389,7 @@ func assembleChunks(ctx context.Context, cfg *ChunkedUploadConfig, session *uplo
 			SizeBytes:   session.TotalSize,
 			StorageKey:  session.StorageKey,
 			Status:      db.FileStatusPending,
+			ContentHash: &contentHash,
 		})
 		if err != nil {
 			metrics.RecordFileUpload("error", 0, time.Since(startTime).Seconds())
@@ -390,36 +400,13 @@ func assembleChunks(ctx context.Context, cfg *ChunkedUploadConfig, session *uplo
 		fileIDStr := uuidFromPgtype(dbFile.ID)
 		log.Info("file record created from chunked upload", "file_id", fileIDStr, "filename", session.Filename)
 
+		placement := session.placement()
+		placement.apply(ctx, cfg.Queries, session.UserID, dbFile.ID, log)
+
 		if cfg.Broker != nil {
 			var fileUUID uuid.UUID
 			copy(fileUUID[:], dbFile.ID.Bytes[:])
-
-			switch {
-			case strings.HasPrefix(contentType, "image/"):
-				payload := worker.NewThumbnailPayload(fileUUID)
-				if jobID, err := worker.EnqueueWithTracking(ctx, cfg.Queries, cfg.Broker, &payload, db.JobTypeThumbnail); err != nil {
-					log.Error("failed to enqueue thumbnail job", "error", err)
-				} else {
-					metrics.RecordJobEnqueued("thumbnail")
-					log.Info("thumbnail job enqueued", "job_id", jobID)
-				}
-			case contentType == "application/pdf":
-				payload := worker.NewPDFThumbnailPayload(fileUUID)
-				if jobID, err := worker.EnqueueWithTracking(ctx, cfg.Queries, cfg.Broker, &payload, db.JobTypePdfThumbnail); err != nil {
-					log.Error("failed to enqueue pdf_thumbnail job", "error", err)
-				} else {
-					metrics.RecordJobEnqueued("pdf_thumbnail")
-					log.Info("pdf_thumbnail job enqueued", "job_id", jobID)
-				}
-			case video.IsVideoType(contentType):
-				payload := worker.NewVideoThumbnailPayload(fileUUID)
-				if jobID, err := worker.EnqueueWithTracking(ctx, cfg.Queries, cfg.Broker, &payload, db.JobTypeVideoThumbnail); err != nil {
-					log.Error("failed to enqueue video_thumbnail job", "error", err)
-				} else {
-					metrics.RecordJobEnqueued("video_thumbnail")
-					log.Info("video_thumbnail job enqueued", "job_id", jobID)
-				}
-			}
+			enqueueProcessingJobs(ctx, cfg.Queries, cfg.Broker, session.UserID, placement.ruleFile(fileUUID, session.Filename, contentType), log)
 		}
 
 		return fileIDStr, nil
@@ -428,6 +415,54 @@ func assembleChunks(ctx context.Context, cfg *ChunkedUploadConfig, session *uplo
 	return session.ID, nil
 }
 
+// enqueueProcessingJobs schedules the processing every new upload receives:
+// the default job for its content type plus the jobs added by the user's
+// upload rules. Failures are logged, not returned, so an upload is never
+// rejected because a job could not be queued.
+func enqueueProcessingJobs(ctx context.Context, queries Querier, broker Broker, userID uuid.UUID, file uploadrules.File, log *slog.Logger) {
+	var tier db.SubscriptionTier
+	if billingInfo := GetBilling(ctx); billingInfo != nil {
+		tier = billingInfo.Tier
+	}
+	uploadrules.Enqueue(ctx, queries, broker, userID, file, tier, log)
+}
+
+// parsePlacement validates the folder and tags sent when an upload session
+// starts. Without a database there is nothing to file the upload under.
+func (c *ChunkedUploadConfig) parsePlacement(ctx context.Context, userID uuid.UUID, folderID string, tags []string) (uploadPlacement, *apperror.Error) {
+	if c.Queries == nil {
+		return uploadPlacement{}, nil
+	}
+	return parseUploadPlacement(ctx, c.Queries, userID, folderID, tags)
+}
+
+// placement returns the folder and tags recorded when the session started.
+func (s *uploadSession) placement() uploadPlacement {
+	p := uploadPlacement{Tags: s.Tags}
+	if id, err := uuid.Parse(s.FolderID); err == nil {
+		p.FolderID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+	return p
+}
+
+// writeSessionLookupError maps a session store lookup failure to a response
+func writeSessionLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUploadSessionNotFound) {
+		apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "session_not_found", "Upload session not found", http.StatusNotFound))
+		return
+	}
+	logger.FromContext(r.Context()).Error("failed to load upload session", "error", err)
+	apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+}
+
+// deleteChunks removes every chunk object belonging to a session
+func deleteChunks(ctx context.Context, storageClient storage.Storage, session *uploadSession) {
+	for i := 0; i < session.ChunksTotal; i++ {
+		chunkKey := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
+		_ = storageClient.Delete(ctx, chunkKey)
+	}
+}
+
 // GetUploadStatusHandler returns the status of an upload session
 func GetUploadStatusHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -438,9 +473,9 @@ func GetUploadStatusHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 		}
 
 		uploadID := r.PathValue("uploadId")
-		session, ok := sessionStore.Get(uploadID)
-		if !ok {
-			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "session_not_found", "Upload session not found", http.StatusNotFound))
+		session, err := cfg.sessions().Get(r.Context(), uploadID)
+		if err != nil {
+			writeSessionLookupError(w, r, err)
 			return
 		}
 
@@ -477,9 +512,9 @@ func CancelUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 		}
 
 		uploadID := r.PathValue("uploadId")
-		session, ok := sessionStore.Get(uploadID)
-		if !ok {
-			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "session_not_found", "Upload session not found", http.StatusNotFound))
+		session, err := cfg.sessions().Get(r.Context(), uploadID)
+		if err != nil {
+			writeSessionLookupError(w, r, err)
 			return
 		}
 
@@ -488,12 +523,11 @@ func CancelUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
 			return
 		}
 
-		for i := 0; i < session.ChunksTotal; i++ {
-			chunkKey := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
-			_ = cfg.Storage.Delete(r.Context(), chunkKey)
-		}
+		deleteChunks(r.Context(), cfg.Storage, session)
 
-		sessionStore.Delete(uploadID)
+		if err := cfg.sessions().Delete(r.Context(), uploadID); err != nil {
+			log.Error("failed to delete upload session", "error", err)
+		}
 
 		log.Info("chunked upload cancelled", "upload_id", uploadID)
 
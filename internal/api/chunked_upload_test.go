@@ -3,20 +3,27 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func TestUploadSessionStore(t *testing.T) {
-	store := &uploadSessionStore{
-		sessions: make(map[string]*uploadSession),
-	}
+	store := newMemoryUploadSessionStore()
+	ctx := context.Background()
 
 	t.Run("Set and Get", func(t *testing.T) {
 		session := &uploadSession{
@@ -29,11 +36,11 @@ func TestUploadSessionStore(t *testing.T) {
 			ChunksLoaded: make(map[int]bool),
 		}
 
-		store.Set(session)
+		_ = store.Set(ctx, session)
 
-		got, ok := store.Get("test-session-1")
-		if !ok {
-			t.Fatal("Get() returned false, want true")
+		got, err := store.Get(ctx, "test-session-1")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
 		}
 		if got.ID != session.ID {
 			t.Errorf("Got session ID = %q, want %q", got.ID, session.ID)
@@ -44,9 +51,9 @@ func TestUploadSessionStore(t *testing.T) {
 	})
 
 	t.Run("Get non-existent", func(t *testing.T) {
-		_, ok := store.Get("non-existent")
-		if ok {
-			t.Error("Get() returned true for non-existent session")
+		_, err := store.Get(ctx, "non-existent")
+		if !errors.Is(err, errUploadSessionNotFound) {
+			t.Errorf("Get() error = %v, want errUploadSessionNotFound", err)
 		}
 	})
 
@@ -56,13 +63,13 @@ func TestUploadSessionStore(t *testing.T) {
 			UserID:       uuid.New(),
 			ChunksLoaded: make(map[int]bool),
 		}
-		store.Set(session)
+		_ = store.Set(ctx, session)
 
-		store.Delete("test-session-2")
+		_ = store.Delete(ctx, "test-session-2")
 
-		_, ok := store.Get("test-session-2")
-		if ok {
-			t.Error("Get() returned true after Delete()")
+		_, err := store.Get(ctx, "test-session-2")
+		if !errors.Is(err, errUploadSessionNotFound) {
+			t.Errorf("Get() after Delete() error = %v, want errUploadSessionNotFound", err)
 		}
 	})
 
@@ -80,9 +87,9 @@ func TestUploadSessionStore(t *testing.T) {
 					UserID:       uuid.New(),
 					ChunksLoaded: make(map[int]bool),
 				}
-				store.Set(session)
-				store.Get(id)
-				store.Delete(id)
+				_ = store.Set(ctx, session)
+				_, _ = store.Get(ctx, id)
+				_ = store.Delete(ctx, id)
 			}(i)
 		}
 
@@ -536,9 +543,8 @@ func TestChunkSizeCalculation(t *testing.T) {
 }
 
 func TestSessionOwnershipVerification(t *testing.T) {
-	store := &uploadSessionStore{
-		sessions: make(map[string]*uploadSession),
-	}
+	store := newMemoryUploadSessionStore()
+	ctx := context.Background()
 
 	ownerID := uuid.New()
 	otherID := uuid.New()
@@ -549,12 +555,12 @@ func TestSessionOwnershipVerification(t *testing.T) {
 		Filename:     "test.mp4",
 		ChunksLoaded: make(map[int]bool),
 	}
-	store.Set(session)
+	_ = store.Set(ctx, session)
 
 	t.Run("owner can access", func(t *testing.T) {
-		got, ok := store.Get("test-session")
-		if !ok {
-			t.Fatal("Get() returned false")
+		got, err := store.Get(ctx, "test-session")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
 		}
 		if got.UserID != ownerID {
 			t.Error("Wrong owner")
@@ -562,12 +568,191 @@ func TestSessionOwnershipVerification(t *testing.T) {
 	})
 
 	t.Run("non-owner check", func(t *testing.T) {
-		got, ok := store.Get("test-session")
-		if !ok {
-			t.Fatal("Get() returned false")
+		got, err := store.Get(ctx, "test-session")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
 		}
 		if got.UserID == otherID {
 			t.Error("Session should not belong to other user")
 		}
 	})
 }
+
+func TestAssembleChunks_ContentValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		chunks      []string
+		wantErr     bool
+		wantType    string
+	}{
+		{
+			name:        "matching mp4",
+			contentType: "video/mp4",
+			chunks:      []string{"\x00\x00\x00\x18ftypisom", "\x00\x00\x02\x00moov"},
+			wantType:    "video/mp4",
+		},
+		{
+			name:        "octet-stream resolved from content",
+			contentType: "application/octet-stream",
+			chunks:      []string{"\x89PNG\r\n\x1a\n", "rest"},
+			wantType:    "image/png",
+		},
+		{
+			name:        "html labelled as video",
+			contentType: "video/mp4",
+			chunks:      []string{"<!DOCTYPE html><html>", "</html>"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMockStorage()
+			session := &uploadSession{
+				ID:          "test-session",
+				UserID:      uuid.New(),
+				Filename:    "upload.bin",
+				ContentType: tt.contentType,
+				ChunksTotal: len(tt.chunks),
+				StorageKey:  "uploads/test/upload.bin",
+			}
+			for i, chunk := range tt.chunks {
+				session.TotalSize += int64(len(chunk))
+				key := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
+				_ = store.Upload(context.Background(), key, strings.NewReader(chunk), "application/octet-stream", int64(len(chunk)))
+			}
+
+			cfg := &ChunkedUploadConfig{Storage: store}
+			_, err := assembleChunks(context.Background(), cfg, session, slog.Default())
+
+			if tt.wantErr {
+				var appErr *apperror.Error
+				if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusBadRequest {
+					t.Fatalf("assembleChunks() error = %v, want 400 apperror", err)
+				}
+				if store.Count() != 0 {
+					t.Errorf("storage has %d objects after rejection, want 0", store.Count())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("assembleChunks() error = %v", err)
+			}
+			got, ok := store.GetContentType(session.StorageKey)
+			if !ok {
+				t.Fatal("assembled file not stored")
+			}
+			if got != tt.wantType {
+				t.Errorf("content type = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestAssembleChunks_RecordsContentHash(t *testing.T) {
+	store := NewMockStorage()
+	queries := NewMockQuerier()
+	chunks := []string{"\x89PNG\r\n\x1a\n", "rest of the image"}
+	session := &uploadSession{
+		ID:          "hash-session",
+		UserID:      uuid.New(),
+		Filename:    "image.png",
+		ContentType: "image/png",
+		ChunksTotal: len(chunks),
+		StorageKey:  "uploads/test/image.png",
+	}
+	for i, chunk := range chunks {
+		session.TotalSize += int64(len(chunk))
+		key := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
+		_ = store.Upload(context.Background(), key, strings.NewReader(chunk), "application/octet-stream", int64(len(chunk)))
+	}
+
+	cfg := &ChunkedUploadConfig{Storage: store, Queries: queries}
+	fileID, err := assembleChunks(context.Background(), cfg, session, slog.Default())
+	if err != nil {
+		t.Fatalf("assembleChunks() error = %v", err)
+	}
+
+	// Chunked and tus uploads record the hash so later uploads can be
+	// deduplicated against them.
+	file, err := queries.GetFile(context.Background(), pgtype.UUID{Bytes: uuid.MustParse(fileID), Valid: true})
+	if err != nil {
+		t.Fatalf("file not created: %v", err)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(chunks, "")))
+	if file.ContentHash == nil || *file.ContentHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("content hash = %v, want the SHA-256 of the assembled file", file.ContentHash)
+	}
+}
+
+func TestUploadChunkHandler_FinalChunkClaim(t *testing.T) {
+	queries, store, broker, cfg := setupTestDeps(t)
+	sessions := newMemoryUploadSessionStore()
+	router := NewRouter(&Config{
+		Storage:        store,
+		Queries:        queries,
+		Broker:         broker,
+		MaxUploadSize:  cfg.MaxUploadSize,
+		JWTSecret:      cfg.JWTSecret,
+		UploadSessions: sessions,
+	})
+	userID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	content := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 24)
+	_ = sessions.Set(context.Background(), &uploadSession{
+		ID:          "final-chunk",
+		UserID:      userID,
+		Filename:    "photo.png",
+		ContentType: "image/png",
+		TotalSize:   int64(len(content)),
+		ChunksTotal: 1,
+		StorageKey:  "uploads/" + userID.String() + "/final-chunk/photo.png",
+		CreatedAt:   time.Now(),
+	})
+
+	put := func() int {
+		req := httptest.NewRequest(http.MethodPut, "/v1/upload/chunked/final-chunk?chunk=0", strings.NewReader(content))
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Another request is assembling the upload
+	unlock, err := sessions.Lock(context.Background(), "final-chunk", time.Minute)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if code := put(); code != http.StatusLocked {
+		t.Errorf("final chunk while claimed status = %d, want %d", code, http.StatusLocked)
+	}
+	unlock()
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = put()
+		}()
+	}
+	wg.Wait()
+
+	assembled := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			assembled++
+		case http.StatusLocked, http.StatusNotFound:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if assembled != 1 {
+		t.Errorf("%d final chunks assembled the upload, want 1", assembled)
+	}
+	if n, _ := queries.CountFilesByUser(context.Background(), pgtype.UUID{Bytes: userID, Valid: true}); n != 1 {
+		t.Errorf("created %d files, want 1", n)
+	}
+}
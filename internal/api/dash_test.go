@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+)
+
+func TestVideoDASHHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		tier       db.SubscriptionTier
+		body       string
+		wantStatus int
+		want       []int
+	}{
+		{"defaults", db.SubscriptionTierPro, `{}`, http.StatusAccepted, []int{360, 720}},
+		{"capped by the plan", db.SubscriptionTierPro, `{"resolutions": [480, 2160]}`, http.StatusAccepted, []int{480}},
+		{"nothing left", db.SubscriptionTierPro, `{"resolutions": [2160]}`, http.StatusBadRequest, nil},
+		{"free tier", db.SubscriptionTierFree, `{}`, http.StatusForbidden, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			fileID := uuid.New()
+			queries, storage, broker, cfg := setupTestDeps(t)
+			queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+			queries.BillingTier = tt.tier
+			router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+			req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/dash", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.want == nil {
+				if broker.HasJob("video_dash") {
+					t.Error("no job should be enqueued")
+				}
+				return
+			}
+			var payload *worker.VideoDASHPayload
+			for _, j := range broker.jobs {
+				if p, ok := j.Payload.(*worker.VideoDASHPayload); ok {
+					payload = p
+				}
+			}
+			if payload == nil {
+				t.Fatal("expected a video_dash job")
+			}
+			if len(payload.Resolutions) != len(tt.want) {
+				t.Fatalf("resolutions = %v, want %v", payload.Resolutions, tt.want)
+			}
+			for i := range tt.want {
+				if payload.Resolutions[i] != tt.want[i] {
+					t.Errorf("resolutions = %v, want %v", payload.Resolutions, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDASHStreamHandler(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+
+	key := "processed/" + fileID.String() + "/dash_manifest/manifest.mpd"
+	mpd := `<MPD><Period><AdaptationSet><Representation id="0"><SegmentTemplate initialization="init-$RepresentationID$.m4s" media="chunk-$RepresentationID$-$Number%05d$.m4s"/></Representation></AdaptationSet></Period></MPD>`
+	if err := storage.Upload(context.Background(), key, strings.NewReader(mpd), "application/dash+xml", int64(len(mpd))); err != nil {
+		t.Fatal(err)
+	}
+	v := createTestVariant(fileID, "dash_manifest")
+	v.StorageKey = key
+	queries.AddVariant(v)
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	base := "/v1/files/" + fileID.String() + "/dash/"
+	rec := serveStream(t, router, userID, base+"manifest.mpd")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("manifest: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/dash+xml" {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `initialization="`+base+`init-$RepresentationID$.m4s"`) {
+		t.Errorf("segment URLs were not rewritten:\n%s", rec.Body.String())
+	}
+
+	rec = serveStream(t, router, userID, base+"init-0.m4s")
+	if rec.Code != http.StatusTemporaryRedirect || !strings.Contains(rec.Header().Get("Location"), "dash_manifest/init-0.m4s") {
+		t.Errorf("segment: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if rec := serveStream(t, router, uuid.New(), base+"manifest.mpd"); rec.Code != http.StatusNotFound {
+		t.Errorf("other user: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
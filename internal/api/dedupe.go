@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DedupeMode controls how an upload whose content hash matches one of the
+// user's existing files is handled.
+type DedupeMode string
+
+const (
+	// DedupeOff stores every upload as a separate object (default).
+	DedupeOff DedupeMode = ""
+	// DedupeExisting returns the existing file instead of creating a new one.
+	DedupeExisting DedupeMode = "existing"
+	// DedupeLink creates a new file row that shares the existing stored object.
+	DedupeLink DedupeMode = "link"
+)
+
+// ParseDedupeMode parses the dedupe upload parameter. "true" is accepted as
+// an alias for "existing".
+func ParseDedupeMode(s string) (DedupeMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", "off":
+		return DedupeOff, nil
+	case "true", "existing":
+		return DedupeExisting, nil
+	case "link":
+		return DedupeLink, nil
+	default:
+		return DedupeOff, fmt.Errorf("invalid dedupe mode %q", s)
+	}
+}
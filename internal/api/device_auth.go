@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
 	"github.com/google/uuid"
@@ -260,7 +261,7 @@ func DeviceApproveHandler(cfg *DeviceAuthConfig) http.HandlerFunc {
 
 		if cfg.Queries != nil {
 			pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
-			_, err := cfg.Queries.CreateAPIToken(r.Context(), db.CreateAPITokenParams{
+			token, err := cfg.Queries.CreateAPIToken(r.Context(), db.CreateAPITokenParams{
 				UserID:    pgUserID,
 				Name:      "fc CLI",
 				TokenHash: tokenHashStr,
@@ -270,6 +271,13 @@ func DeviceApproveHandler(cfg *DeviceAuthConfig) http.HandlerFunc {
 				apperror.WriteJSON(w, r, apperror.ErrInternal)
 				return
 			}
+			audit.Record(r, audit.Entry{
+				UserID:       userID,
+				Action:       audit.ActionAPITokenCreate,
+				ResourceType: audit.ResourceAPIToken,
+				ResourceID:   uuid.UUID(token.ID.Bytes),
+				Metadata:     map[string]any{"name": token.Name, "source": "device_auth"},
+			})
 		}
 
 		deviceAuthStore.Approve(code.DeviceCode, userID, apiKey)
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testMediaPlaylist = "#EXTM3U\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:6.000000,\nsegment_000.ts\n#EXTINF:4.000000,\nsegment_001.ts\n#EXT-X-ENDLIST\n"
+
+// addTestHLSPackage stores a two-rendition HLS package the way the worker
+// lays it out and registers its variants.
+func addTestHLSPackage(t *testing.T, queries *MockQuerier, store *MockStorage, fileID uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	prefix := "processed/" + fileID.String() + "/"
+
+	master := video.BuildMasterPlaylist([]video.HLSRendition{
+		{Name: "360p", Width: 640, Height: 360, Bandwidth: 900000, Codecs: "avc1.4d401e,mp4a.40.2"},
+		{Name: "720p", Width: 1280, Height: 720, Bandwidth: 3200000, Codecs: "avc1.4d401f,mp4a.40.2"},
+	})
+	objects := map[string]string{
+		prefix + "hls_master/master.m3u8": string(master),
+		prefix + "hls_360p/playlist.m3u8": testMediaPlaylist,
+		prefix + "hls_720p/playlist.m3u8": testMediaPlaylist,
+	}
+	for key, body := range objects {
+		if err := store.Upload(ctx, key, strings.NewReader(body), "application/x-mpegURL", int64(len(body))); err != nil {
+			t.Fatalf("failed to store %s: %v", key, err)
+		}
+	}
+
+	for _, name := range []string{"hls_master", "hls_360p", "hls_720p"} {
+		v := createTestVariant(fileID, name)
+		v.ContentType = "application/x-mpegURL"
+		v.StorageKey = prefix + name + "/playlist.m3u8"
+		if name == "hls_master" {
+			v.StorageKey = prefix + name + "/master.m3u8"
+		}
+		queries.AddVariant(v)
+	}
+}
+
+func serveStream(t *testing.T, router http.Handler, userID uuid.UUID, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHLSStreamHandler_RenditionPlaylist(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+	addTestHLSPackage(t, queries, storage, fileID)
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	rec := serveStream(t, router, userID, "/v1/files/"+fileID.String()+"/hls/360p/playlist.m3u8")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	base := "/v1/files/" + fileID.String() + "/hls/360p/"
+	if !strings.Contains(body, "\n"+base+"segment_000.ts\n") || !strings.Contains(body, "\n"+base+"segment_001.ts\n") {
+		t.Errorf("segment URIs were not rewritten:\n%s", body)
+	}
+	if !strings.Contains(body, "#EXTINF:6.000000,") || !strings.HasSuffix(body, "#EXT-X-ENDLIST\n") {
+		t.Errorf("playlist tags should be kept:\n%s", body)
+	}
+}
+
+func TestHLSStreamHandler_NotFound(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+	addTestHLSPackage(t, queries, storage, fileID)
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	for _, path := range []string{
+		"/hls/1080p/playlist.m3u8", // not packaged
+		"/hls/4k/playlist.m3u8",    // not a rendition
+		"/hls/missing.m3u8",        // not stored
+	} {
+		rec := serveStream(t, router, userID, "/v1/files/"+fileID.String()+path)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestHLSStreamHandler_SingleRenditionPackage(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+
+	// Packages from before renditions were split keep a media playlist and
+	// its segments directly under hls_master.
+	key := "processed/" + fileID.String() + "/hls_master/playlist.m3u8"
+	_ = storage.Upload(context.Background(), key, strings.NewReader(testMediaPlaylist), "application/x-mpegURL", int64(len(testMediaPlaylist)))
+	v := createTestVariant(fileID, "hls_master")
+	v.StorageKey = key
+	queries.AddVariant(v)
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	rec := serveStream(t, router, userID, "/v1/files/"+fileID.String()+"/hls/master.m3u8")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	segment := "/v1/files/" + fileID.String() + "/hls/segment_000.ts"
+	if !strings.Contains(rec.Body.String(), segment) {
+		t.Fatalf("playlist does not reference %s:\n%s", segment, rec.Body.String())
+	}
+
+	rec = serveStream(t, router, userID, segment)
+	if rec.Code != http.StatusTemporaryRedirect || !strings.Contains(rec.Header().Get("Location"), "hls_master/segment_000.ts") {
+		t.Errorf("segment: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestShareHLSHandler(t *testing.T) {
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	addTestHLSPackage(t, queries, storage, fileID)
+
+	share := createTestShareByToken(fileID, uuid.New(), "hls-token", "uploads/clip.mp4", "video/mp4", "clip.mp4", nil, nil)
+	queries.AddShareByToken("hls-token", share)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	locked := createTestShareByToken(fileID, uuid.New(), "locked-token", "uploads/clip.mp4", "video/mp4", "clip.mp4", nil, nil)
+	passwordHash := string(hash)
+	locked.PasswordHash = &passwordHash
+	queries.AddShareByToken("locked-token", locked)
+
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/cdn/hls-token/hls/master.m3u8")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("master: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "\n/cdn/hls-token/hls/720p/playlist.m3u8\n") {
+		t.Errorf("master playlist should stay on the share:\n%s", rec.Body.String())
+	}
+
+	rec = get("/cdn/hls-token/hls/720p/playlist.m3u8")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "\n/cdn/hls-token/hls/720p/segment_000.ts\n") {
+		t.Errorf("rendition: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = get("/cdn/hls-token/hls/720p/segment_000.ts")
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("segment: status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+	}
+
+	if rec := get("/cdn/unknown/hls/master.m3u8"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown share: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := get("/cdn/locked-token/hls/master.m3u8"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("locked share: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := get("/cdn/locked-token/hls/master.m3u8", "X-Share-Password", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("unlocked share: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestVideoHLSHandler_Resolutions(t *testing.T) {
+	tests := []struct {
+		name       string
+		tier       db.SubscriptionTier
+		body       string
+		wantStatus int
+		want       []int
+	}{
+		{"filtered to the ladder", db.SubscriptionTierPro, `{"resolutions": [1080, 240, 360, 360]}`, http.StatusAccepted, []int{360, 1080}},
+		{"capped by the plan", db.SubscriptionTierPro, `{"resolutions": [360, 2160]}`, http.StatusAccepted, []int{360}},
+		{"nothing left", db.SubscriptionTierPro, `{"resolutions": [2160]}`, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			fileID := uuid.New()
+			queries, storage, broker, cfg := setupTestDeps(t)
+			queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+			queries.BillingTier = tt.tier
+			router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+			req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/hls", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.want == nil {
+				return
+			}
+			var payload *worker.VideoHLSPayload
+			for _, j := range broker.jobs {
+				if p, ok := j.Payload.(*worker.VideoHLSPayload); ok {
+					payload = p
+				}
+			}
+			if payload == nil {
+				t.Fatal("expected a video_hls job")
+			}
+			if len(payload.Resolutions) != len(tt.want) {
+				t.Fatalf("resolutions = %v, want %v", payload.Resolutions, tt.want)
+			}
+			for i := range tt.want {
+				if payload.Resolutions[i] != tt.want[i] {
+					t.Errorf("resolutions = %v, want %v", payload.Resolutions, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	imgproc "github.com/abdul-hamid-achik/file.cheap/internal/processor/image"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type MetadataQuerier interface {
+	GetFile(ctx context.Context, id pgtype.UUID) (db.File, error)
+	ReplaceFileContent(ctx context.Context, arg db.ReplaceFileContentParams) (db.File, error)
+	UpsertFileMetadata(ctx context.Context, arg db.UpsertFileMetadataParams) (db.FileMetadatum, error)
+	CountStorageKeyReferences(ctx context.Context, arg db.CountStorageKeyReferencesParams) (int64, error)
+}
+
+type MetadataConfig struct {
+	Storage storage.Storage
+	Queries MetadataQuerier
+}
+
+// FileMetadataGPS is a capture location in decimal degrees.
+type FileMetadataGPS struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
+// FileMetadataResponse is the image metadata returned with a file.
+type FileMetadataResponse struct {
+	Width       int32            `json:"width"`
+	Height      int32            `json:"height"`
+	Format      string           `json:"format"`
+	Orientation int32            `json:"orientation"`
+	CameraMake  *string          `json:"camera_make,omitempty"`
+	CameraModel *string          `json:"camera_model,omitempty"`
+	LensModel   *string          `json:"lens_model,omitempty"`
+	CapturedAt  *time.Time       `json:"captured_at,omitempty"`
+	GPS         *FileMetadataGPS `json:"gps,omitempty"`
+	ICCProfile  *string          `json:"icc_profile,omitempty"`
+	Caption     *string          `json:"caption,omitempty"`
+	Keywords    []string         `json:"keywords"`
+}
+
+func fileMetadataToResponse(m db.FileMetadatum) FileMetadataResponse {
+	resp := FileMetadataResponse{
+		Width:       m.Width,
+		Height:      m.Height,
+		Format:      m.Format,
+		Orientation: m.Orientation,
+		CameraMake:  m.CameraMake,
+		CameraModel: m.CameraModel,
+		LensModel:   m.LensModel,
+		ICCProfile:  m.IccProfile,
+		Caption:     m.Caption,
+		Keywords:    m.Keywords,
+	}
+	if resp.Keywords == nil {
+		resp.Keywords = []string{}
+	}
+	if m.CapturedAt.Valid {
+		t := m.CapturedAt.Time.UTC()
+		resp.CapturedAt = &t
+	}
+	if m.GpsLatitude != nil && m.GpsLongitude != nil {
+		resp.GPS = &FileMetadataGPS{Latitude: *m.GpsLatitude, Longitude: *m.GpsLongitude, Altitude: m.GpsAltitude}
+	}
+	return resp
+}
+
+type StripMetadataResponse struct {
+	FileID       string               `json:"file_id"`
+	Stripped     bool                 `json:"stripped"`
+	SizeBytes    int64                `json:"size_bytes"`
+	BytesRemoved int64                `json:"bytes_removed"`
+	Metadata     FileMetadataResponse `json:"metadata"`
+}
+
+// StripMetadataHandler removes GPS, camera details, captions and other
+// EXIF/IPTC/XMP metadata from an image's original without re-encoding it.
+// The stripped copy is stored under a new key so deduplicated files that
+// shared the old object are unaffected, and the stored metadata is refreshed
+// to match.
+func StripMetadataHandler(cfg *MetadataConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		fileID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_file_id", "Invalid file ID format", http.StatusBadRequest))
+			return
+		}
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil || file.UserID != (pgtype.UUID{Bytes: userID, Valid: true}) || file.DeletedAt.Valid {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+		if file.StorageKey == "" {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "original_deleted",
+				"The original file is no longer stored", http.StatusConflict))
+			return
+		}
+
+		switch file.ContentType {
+		case "image/jpeg", "image/png", "image/webp":
+		default:
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "unsupported_type",
+				"Metadata can only be stripped from JPEG, PNG and WebP images", http.StatusBadRequest))
+			return
+		}
+
+		reader, err := cfg.Storage.Download(r.Context(), file.StorageKey)
+		if err != nil {
+			log.Error("failed to download original", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+		original, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			log.Error("failed to read original", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		stripped, err := imgproc.StripMetadata(original)
+		if err != nil {
+			if errors.Is(err, processor.ErrUnsupportedType) || errors.Is(err, processor.ErrCorruptedFile) {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_image",
+					"The image could not be read", http.StatusUnprocessableEntity))
+				return
+			}
+			log.Error("failed to strip metadata", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		changed := !bytes.Equal(stripped, original)
+		if changed {
+			file, err = replaceOriginal(r.Context(), cfg, file, userID, stripped)
+			if err != nil {
+				log.Error("failed to store stripped original", "error", err)
+				apperror.WriteJSON(w, r, apperror.ErrInternal)
+				return
+			}
+		}
+
+		meta, err := imgproc.ExtractMetadata(stripped)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_image",
+				"The image could not be read", http.StatusUnprocessableEntity))
+			return
+		}
+		row, err := cfg.Queries.UpsertFileMetadata(r.Context(), worker.FileMetadataParams(fileID, meta))
+		if err != nil {
+			log.Error("failed to save metadata", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		log.Info("metadata stripped", "file_id", fileID.String(), "changed", changed, "bytes_removed", len(original)-len(stripped))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(StripMetadataResponse{
+			FileID:       fileID.String(),
+			Stripped:     changed,
+			SizeBytes:    file.SizeBytes,
+			BytesRemoved: int64(len(original) - len(stripped)),
+			Metadata:     fileMetadataToResponse(row),
+		})
+	}
+}
+
+// replaceOriginal uploads data as the file's new original and releases the
+// old object unless another file still uses it.
+func replaceOriginal(ctx context.Context, cfg *MetadataConfig, file db.File, userID uuid.UUID, data []byte) (db.File, error) {
+	key := fmt.Sprintf("uploads/%s/%s/stripped/%s", userID.String(), uuidFromPgtype(file.ID), SanitizeFilename(file.Filename))
+
+	body := storage.NewHashingReader(bytes.NewReader(data))
+	if err := cfg.Storage.Upload(ctx, key, body, file.ContentType, int64(len(data))); err != nil {
+		return file, fmt.Errorf("failed to upload: %w", err)
+	}
+	hash := body.Sum()
+
+	oldKey := file.StorageKey
+	updated, err := cfg.Queries.ReplaceFileContent(ctx, db.ReplaceFileContentParams{
+		ID:          file.ID,
+		StorageKey:  key,
+		SizeBytes:   int64(len(data)),
+		ContentHash: &hash,
+	})
+	if err != nil {
+		return file, fmt.Errorf("failed to update file: %w", err)
+	}
+
+	if oldKey != key {
+		if _, err := worker.ReleaseStorageObject(ctx, cfg.Queries, cfg.Storage, file.ID, oldKey); err != nil {
+			logger.FromContext(ctx).Warn("failed to delete previous original", "storage_key", oldKey, "error", err)
+		}
+	}
+	return updated, nil
+}
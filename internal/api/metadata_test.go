@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// createTaggedPNG returns a PNG with an EXIF camera make of "LG" and an
+// author text chunk.
+func createTaggedPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 8))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+
+	chunk := func(typ string, data []byte) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		out = append(append(out, typ...), data...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	}
+	// A big-endian TIFF with a single inline Make tag.
+	exif := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x0F, 0, 2, 0, 0, 0, 3, 'L', 'G', 0, 0, 0, 0, 0, 0}
+
+	data := buf.Bytes()
+	out := append([]byte{}, data[:33]...)
+	out = append(out, chunk("eXIf", exif)...)
+	out = append(out, chunk("tEXt", []byte("Author\x00Jane Doe"))...)
+	return append(out, data[33:]...)
+}
+
+func serveStrip(ctx context.Context, cfg *MetadataConfig, fileID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/v1/files/"+fileID+"/strip", nil)
+	req.SetPathValue("id", fileID)
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	StripMetadataHandler(cfg).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStripMetadataHandler(t *testing.T) {
+	userID := uuid.New()
+	queries := NewMockQuerier()
+	store := NewMockStorage()
+
+	original := createTaggedPNG(t)
+	file := createTestFile(userID, "photo.png")
+	file.ContentType = "image/png"
+	queries.AddFile(file)
+	// A deduplicated upload shares the same object.
+	duplicate := createTestFile(userID, "copy.png")
+	duplicate.StorageKey = file.StorageKey
+	queries.AddFile(duplicate)
+	if err := store.Upload(context.Background(), file.StorageKey, bytes.NewReader(original), "image/png", int64(len(original))); err != nil {
+		t.Fatalf("failed to store image: %v", err)
+	}
+
+	cfg := &MetadataConfig{Storage: store, Queries: queries}
+	ctx := context.WithValue(context.Background(), UserIDKey, userID)
+
+	rec := serveStrip(ctx, cfg, uuidFromPgtype(file.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp StripMetadataResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Stripped || resp.BytesRemoved <= 0 || resp.SizeBytes != int64(len(original))-resp.BytesRemoved {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.Metadata.CameraMake != nil || resp.Metadata.Width != 16 || resp.Metadata.Format != "png" {
+		t.Errorf("metadata = %+v, want dimensions without camera details", resp.Metadata)
+	}
+
+	updated, _ := queries.GetFile(context.Background(), file.ID)
+	if updated.StorageKey == file.StorageKey || !strings.Contains(updated.StorageKey, "/stripped/") {
+		t.Fatalf("storage key = %q, want a new stripped key", updated.StorageKey)
+	}
+	if updated.ContentHash == nil {
+		t.Error("content hash should be updated")
+	}
+
+	reader, err := store.Download(context.Background(), updated.StorageKey)
+	if err != nil {
+		t.Fatalf("stripped object missing: %v", err)
+	}
+	stripped, _ := io.ReadAll(reader)
+	if bytes.Contains(stripped, []byte("Jane Doe")) || bytes.Contains(stripped, []byte("LG")) {
+		t.Error("stripped object still carries metadata")
+	}
+	if _, err := store.Download(context.Background(), file.StorageKey); err != nil {
+		t.Error("object shared with a duplicate should be kept")
+	}
+
+	if _, err := queries.GetFileMetadata(context.Background(), file.ID); err != nil {
+		t.Errorf("metadata should be saved: %v", err)
+	}
+
+	// Stripping again finds nothing to remove.
+	rec = serveStrip(ctx, cfg, uuidFromPgtype(file.ID))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"stripped":false`) {
+		t.Errorf("second strip: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStripMetadataHandler_Errors(t *testing.T) {
+	userID := uuid.New()
+	queries := NewMockQuerier()
+	store := NewMockStorage()
+
+	photo := createTestFile(userID, "photo.jpg")
+	queries.AddFile(photo)
+	pdf := createTestFile(userID, "doc.pdf")
+	pdf.ContentType = "application/pdf"
+	queries.AddFile(pdf)
+	purged := createTestFile(userID, "old.jpg")
+	purged.StorageKey = ""
+	queries.AddFile(purged)
+	broken := createTestFile(userID, "broken.jpg")
+	queries.AddFile(broken)
+	_ = store.Upload(context.Background(), broken.StorageKey, strings.NewReader("not an image"), "image/jpeg", 12)
+
+	cfg := &MetadataConfig{Storage: store, Queries: queries}
+	userCtx := context.WithValue(context.Background(), UserIDKey, userID)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		fileID     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid id", userCtx, "nope", http.StatusBadRequest, "invalid_file_id"},
+		{"other user", context.WithValue(context.Background(), UserIDKey, uuid.New()), uuidFromPgtype(photo.ID), http.StatusNotFound, "not_found"},
+		{"not an image", userCtx, uuidFromPgtype(pdf.ID), http.StatusBadRequest, "unsupported_type"},
+		{"original deleted", userCtx, uuidFromPgtype(purged.ID), http.StatusConflict, "original_deleted"},
+		{"corrupted image", userCtx, uuidFromPgtype(broken.ID), http.StatusUnprocessableEntity, "invalid_image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveStrip(tt.ctx, cfg, tt.fileID)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("body = %s, want code %s", rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGetFileHandler_IncludesMetadata(t *testing.T) {
+	userID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	file := createTestFile(userID, "photo.jpg")
+	queries.AddFile(file)
+
+	lat, lon := 48.8584, 2.2945
+	cameraMake := "Apple"
+	_, _ = queries.UpsertFileMetadata(context.Background(), db.UpsertFileMetadataParams{
+		FileID:       file.ID,
+		Width:        3024,
+		Height:       4032,
+		Format:       "jpeg",
+		CameraMake:   &cameraMake,
+		CapturedAt:   pgtype.Timestamptz{Time: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), Valid: true},
+		GpsLatitude:  &lat,
+		GpsLongitude: &lon,
+		Orientation:  6,
+		Keywords:     []string{"paris"},
+	})
+
+	router := NewRouter(&Config{
+		Storage:       storage,
+		Queries:       queries,
+		MaxUploadSize: cfg.MaxUploadSize,
+		JWTSecret:     cfg.JWTSecret,
+	})
+
+	req := httptest.NewRequest("GET", "/v1/files/"+uuidFromPgtype(file.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Metadata *FileMetadataResponse `json:"metadata"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	m := resp.Metadata
+	if m == nil {
+		t.Fatal("expected metadata in the file response")
+	}
+	if m.Width != 3024 || m.Orientation != 6 || m.CameraMake == nil || *m.CameraMake != "Apple" {
+		t.Errorf("metadata = %+v", m)
+	}
+	if m.GPS == nil || m.GPS.Latitude != lat || m.GPS.Longitude != lon {
+		t.Errorf("gps = %+v", m.GPS)
+	}
+	if m.CapturedAt == nil || m.CapturedAt.Year() != 2024 || len(m.Keywords) != 1 {
+		t.Errorf("captured_at = %v, keywords = %v", m.CapturedAt, m.Keywords)
+	}
+}
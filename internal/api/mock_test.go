@@ -1,9 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -12,6 +14,8 @@ import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -77,6 +81,14 @@ type MockQuerier struct {
 	caches        map[string]db.TransformCache
 	requestCounts map[string]int32
 
+	webhooks    map[string]db.Webhook
+	dlqReplays  map[string]db.WebhookDlqReplay
+	presets     map[string]db.TransformPreset
+	uploadRules map[string]db.UploadRule
+	metadata    map[string]db.FileMetadatum
+
+	ReplayableDLQCount int64
+
 	GetFileErr        error
 	ListFilesErr      error
 	CreateFileErr     error
@@ -93,6 +105,11 @@ type MockQuerier struct {
 	CreateTransformCacheErr error
 
 	BillingTier db.SubscriptionTier
+	UserRole    db.UserRole
+	// TransformationsUsed is reported as the user's transformation count.
+	TransformationsUsed int32
+
+	auditLogs []db.AuditLog
 }
 
 func NewMockQuerier() *MockQuerier {
@@ -103,6 +120,11 @@ func NewMockQuerier() *MockQuerier {
 		sharesByToken: make(map[string]db.GetFileShareByTokenRow),
 		caches:        make(map[string]db.TransformCache),
 		requestCounts: make(map[string]int32),
+		webhooks:      make(map[string]db.Webhook),
+		dlqReplays:    make(map[string]db.WebhookDlqReplay),
+		presets:       make(map[string]db.TransformPreset),
+		uploadRules:   make(map[string]db.UploadRule),
+		metadata:      make(map[string]db.FileMetadatum),
 		BillingTier:   db.SubscriptionTierPro, // Default to Pro for existing tests
 	}
 }
@@ -273,11 +295,36 @@ func (m *MockQuerier) CreateFile(ctx context.Context, arg db.CreateFileParams) (
 		Status:      arg.Status,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
+		ContentHash: arg.ContentHash,
 	}
 	m.files[id.String()] = f
 	return f, nil
 }
 
+func (m *MockQuerier) GetFileByContentHash(ctx context.Context, arg db.GetFileByContentHashParams) (db.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *db.File
+	userKey := uuidToString(arg.UserID)
+	for _, f := range m.files {
+		if uuidToString(f.UserID) != userKey || f.DeletedAt.Valid || f.StorageKey == "" {
+			continue
+		}
+		if f.ContentHash == nil || arg.ContentHash == nil || *f.ContentHash != *arg.ContentHash {
+			continue
+		}
+		if found == nil || f.CreatedAt.Time.Before(found.CreatedAt.Time) {
+			f := f
+			found = &f
+		}
+	}
+	if found == nil {
+		return db.File{}, pgx.ErrNoRows
+	}
+	return *found, nil
+}
+
 func (m *MockQuerier) SoftDeleteFile(ctx context.Context, id pgtype.UUID) error {
 	if m.SoftDeleteFileErr != nil {
 		return m.SoftDeleteFileErr
@@ -579,7 +626,7 @@ func (m *MockQuerier) GetUserTransformationUsage(ctx context.Context, id pgtype.
 	}
 
 	return db.GetUserTransformationUsageRow{
-		TransformationsCount: 0,
+		TransformationsCount: m.TransformationsUsed,
 		TransformationsLimit: limit,
 	}, nil
 }
@@ -699,10 +746,23 @@ func (m *MockQuerier) CreateWebhook(ctx context.Context, arg db.CreateWebhookPar
 		Secret: arg.Secret,
 		Events: arg.Events,
 		Active: true,
+		Format: arg.Format,
 	}, nil
 }
 
+func (m *MockQuerier) AddWebhook(wh db.Webhook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.webhooks[uuidToString(wh.ID)] = wh
+}
+
 func (m *MockQuerier) GetWebhook(ctx context.Context, arg db.GetWebhookParams) (db.Webhook, error) {
+	m.mu.RLock()
+	wh, ok := m.webhooks[uuidToString(arg.ID)]
+	m.mu.RUnlock()
+	if ok {
+		return wh, nil
+	}
 	return db.Webhook{
 		ID:     arg.ID,
 		UserID: arg.UserID,
@@ -719,12 +779,30 @@ func (m *MockQuerier) CountWebhooksByUser(ctx context.Context, userID pgtype.UUI
 }
 
 func (m *MockQuerier) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	format := "native"
+	if arg.Format != nil {
+		format = *arg.Format
+	}
 	return db.Webhook{
 		ID:     arg.ID,
 		UserID: arg.UserID,
 		Url:    arg.Url,
 		Events: arg.Events,
 		Active: arg.Active,
+		Format: format,
+	}, nil
+}
+
+func (m *MockQuerier) RotateWebhookSecret(ctx context.Context, arg db.RotateWebhookSecretParams) (db.Webhook, error) {
+	previous := "whsec_previous"
+	return db.Webhook{
+		ID:                      arg.ID,
+		UserID:                  arg.UserID,
+		Secret:                  arg.Secret,
+		Active:                  true,
+		Format:                  "native",
+		PreviousSecret:          &previous,
+		PreviousSecretExpiresAt: arg.PreviousSecretExpiresAt,
 	}, nil
 }
 
@@ -787,9 +865,79 @@ func (m *MockQuerier) CountJobsByUser(ctx context.Context, arg db.CountJobsByUse
 }
 
 func (m *MockQuerier) GetUserRole(ctx context.Context, id pgtype.UUID) (db.UserRole, error) {
+	if m.UserRole != "" {
+		return m.UserRole, nil
+	}
 	return db.UserRoleUser, nil
 }
 
+func (m *MockQuerier) CreateAuditLog(ctx context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := db.AuditLog{
+		ID:           pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:       arg.UserID,
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   arg.ResourceID,
+		IpAddress:    arg.IpAddress,
+		UserAgent:    arg.UserAgent,
+		Metadata:     arg.Metadata,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.auditLogs = append(m.auditLogs, entry)
+	return entry, nil
+}
+
+func (m *MockQuerier) AddAuditLog(entry db.AuditLog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auditLogs = append(m.auditLogs, entry)
+}
+
+func (m *MockQuerier) AuditLogs() []db.AuditLog {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]db.AuditLog(nil), m.auditLogs...)
+}
+
+func (m *MockQuerier) ListAuditLogs(ctx context.Context, arg db.ListAuditLogsParams) ([]db.AuditLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []db.AuditLog
+	for _, e := range m.auditLogs {
+		switch {
+		case arg.UserID.Valid && e.UserID.Bytes != arg.UserID.Bytes:
+		case arg.Action != nil && string(e.Action) != *arg.Action:
+		case arg.ResourceType != nil && e.ResourceType != *arg.ResourceType:
+		case arg.ResourceID.Valid && e.ResourceID.Bytes != arg.ResourceID.Bytes:
+		case arg.Since.Valid && e.CreatedAt.Time.Before(arg.Since.Time):
+		case arg.Until.Valid && !e.CreatedAt.Time.Before(arg.Until.Time):
+		case arg.CursorTime.Valid && !auditLogBefore(e, arg.CursorTime.Time, uuid.UUID(arg.CursorID.Bytes)):
+		default:
+			result = append(result, e)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return auditLogBefore(result[j], result[i].CreatedAt.Time, uuid.UUID(result[i].ID.Bytes))
+	})
+	if len(result) > int(arg.Limit) {
+		result = result[:arg.Limit]
+	}
+	return result, nil
+}
+
+// auditLogBefore reports whether e sorts after (t, id) in newest-first order.
+func auditLogBefore(e db.AuditLog, t time.Time, id uuid.UUID) bool {
+	if !e.CreatedAt.Time.Equal(t) {
+		return e.CreatedAt.Time.Before(t)
+	}
+	return bytes.Compare(e.ID.Bytes[:], id[:]) < 0
+}
+
 func (m *MockQuerier) GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error) {
 	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
 	return db.User{
@@ -945,6 +1093,304 @@ func (m *MockQuerier) DeleteWebhookDLQEntry(ctx context.Context, id pgtype.UUID)
 	return nil
 }
 
+func (m *MockQuerier) CountReplayableWebhookDLQ(ctx context.Context, arg db.CountReplayableWebhookDLQParams) (int64, error) {
+	return m.ReplayableDLQCount, nil
+}
+
+func (m *MockQuerier) CreateWebhookDLQReplay(ctx context.Context, arg db.CreateWebhookDLQReplayParams) (db.WebhookDlqReplay, error) {
+	replay := db.WebhookDlqReplay{
+		ID:            pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:        arg.UserID,
+		WebhookID:     arg.WebhookID,
+		EventType:     arg.EventType,
+		CreatedAfter:  arg.CreatedAfter,
+		CreatedBefore: arg.CreatedBefore,
+		RatePerSecond: arg.RatePerSecond,
+		Status:        arg.Status,
+		TotalEntries:  arg.TotalEntries,
+		CreatedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.mu.Lock()
+	m.dlqReplays[uuidToString(replay.ID)] = replay
+	m.mu.Unlock()
+	return replay, nil
+}
+
+func (m *MockQuerier) GetWebhookDLQReplay(ctx context.Context, arg db.GetWebhookDLQReplayParams) (db.WebhookDlqReplay, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	replay, ok := m.dlqReplays[uuidToString(arg.ID)]
+	if !ok || replay.UserID != arg.UserID {
+		return db.WebhookDlqReplay{}, pgx.ErrNoRows
+	}
+	return replay, nil
+}
+
+func (m *MockQuerier) CompleteWebhookDLQReplay(ctx context.Context, arg db.CompleteWebhookDLQReplayParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if replay, ok := m.dlqReplays[uuidToString(arg.ID)]; ok {
+		replay.Status = arg.Status
+		replay.ErrorMessage = arg.ErrorMessage
+		m.dlqReplays[uuidToString(arg.ID)] = replay
+	}
+	return nil
+}
+
+func (m *MockQuerier) AddPreset(p db.TransformPreset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.presets[uuidToString(p.ID)] = p
+}
+
+func (m *MockQuerier) CreateTransformPreset(ctx context.Context, arg db.CreateTransformPresetParams) (db.TransformPreset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.presets {
+		if p.UserID == arg.UserID && p.Name == arg.Name {
+			return db.TransformPreset{}, &pgconn.PgError{Code: "23505"}
+		}
+	}
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	preset := db.TransformPreset{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		Width:     arg.Width,
+		Height:    arg.Height,
+		Fit:       arg.Fit,
+		Position:  arg.Position,
+		Format:    arg.Format,
+		Quality:   arg.Quality,
+		Watermark: arg.Watermark,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.presets[uuidToString(preset.ID)] = preset
+	return preset, nil
+}
+
+func (m *MockQuerier) GetTransformPreset(ctx context.Context, arg db.GetTransformPresetParams) (db.TransformPreset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.presets[uuidToString(arg.ID)]
+	if !ok || p.UserID != arg.UserID {
+		return db.TransformPreset{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+func (m *MockQuerier) GetTransformPresetByName(ctx context.Context, arg db.GetTransformPresetByNameParams) (db.TransformPreset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.presets {
+		if p.UserID == arg.UserID && p.Name == arg.Name {
+			return p, nil
+		}
+	}
+	return db.TransformPreset{}, pgx.ErrNoRows
+}
+
+func (m *MockQuerier) ListTransformPresetsByUser(ctx context.Context, userID pgtype.UUID) ([]db.TransformPreset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []db.TransformPreset
+	for _, p := range m.presets {
+		if p.UserID == userID {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (m *MockQuerier) UpdateTransformPreset(ctx context.Context, arg db.UpdateTransformPresetParams) (db.TransformPreset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.presets[uuidToString(arg.ID)]
+	if !ok || p.UserID != arg.UserID {
+		return db.TransformPreset{}, pgx.ErrNoRows
+	}
+	p.Name = arg.Name
+	p.Width = arg.Width
+	p.Height = arg.Height
+	p.Fit = arg.Fit
+	p.Position = arg.Position
+	p.Format = arg.Format
+	p.Quality = arg.Quality
+	p.Watermark = arg.Watermark
+	p.UpdatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	m.presets[uuidToString(arg.ID)] = p
+	return p, nil
+}
+
+func (m *MockQuerier) DeleteTransformPreset(ctx context.Context, arg db.DeleteTransformPresetParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.presets[uuidToString(arg.ID)]; ok && p.UserID == arg.UserID {
+		delete(m.presets, uuidToString(arg.ID))
+	}
+	return nil
+}
+
+func (m *MockQuerier) AddUploadRule(r db.UploadRule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploadRules[uuidToString(r.ID)] = r
+}
+
+func (m *MockQuerier) CreateUploadRule(ctx context.Context, arg db.CreateUploadRuleParams) (db.UploadRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	rule := db.UploadRule{
+		ID:              pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:          arg.UserID,
+		Name:            arg.Name,
+		ContentType:     arg.ContentType,
+		FolderID:        arg.FolderID,
+		Tag:             arg.Tag,
+		FilenamePattern: arg.FilenamePattern,
+		Jobs:            arg.Jobs,
+		Enabled:         arg.Enabled,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	m.uploadRules[uuidToString(rule.ID)] = rule
+	return rule, nil
+}
+
+func (m *MockQuerier) GetUploadRule(ctx context.Context, arg db.GetUploadRuleParams) (db.UploadRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.uploadRules[uuidToString(arg.ID)]
+	if !ok || r.UserID != arg.UserID {
+		return db.UploadRule{}, pgx.ErrNoRows
+	}
+	return r, nil
+}
+
+func (m *MockQuerier) listUploadRules(userID pgtype.UUID, enabledOnly bool) []db.UploadRule {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []db.UploadRule
+	for _, r := range m.uploadRules {
+		if r.UserID == userID && (r.Enabled || !enabledOnly) {
+			result = append(result, r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Time.Before(result[j].CreatedAt.Time) })
+	return result
+}
+
+func (m *MockQuerier) ListUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error) {
+	return m.listUploadRules(userID, false), nil
+}
+
+func (m *MockQuerier) ListEnabledUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error) {
+	return m.listUploadRules(userID, true), nil
+}
+
+func (m *MockQuerier) UpdateUploadRule(ctx context.Context, arg db.UpdateUploadRuleParams) (db.UploadRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.uploadRules[uuidToString(arg.ID)]
+	if !ok || r.UserID != arg.UserID {
+		return db.UploadRule{}, pgx.ErrNoRows
+	}
+	r.Name = arg.Name
+	r.ContentType = arg.ContentType
+	r.FolderID = arg.FolderID
+	r.Tag = arg.Tag
+	r.FilenamePattern = arg.FilenamePattern
+	r.Jobs = arg.Jobs
+	r.Enabled = arg.Enabled
+	r.UpdatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	m.uploadRules[uuidToString(arg.ID)] = r
+	return r, nil
+}
+
+func (m *MockQuerier) DeleteUploadRule(ctx context.Context, arg db.DeleteUploadRuleParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.uploadRules[uuidToString(arg.ID)]; ok && r.UserID == arg.UserID {
+		delete(m.uploadRules, uuidToString(arg.ID))
+	}
+	return nil
+}
+
+func (m *MockQuerier) GetFileMetadata(ctx context.Context, fileID pgtype.UUID) (db.FileMetadatum, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	meta, ok := m.metadata[uuidToString(fileID)]
+	if !ok {
+		return db.FileMetadatum{}, pgx.ErrNoRows
+	}
+	return meta, nil
+}
+
+func (m *MockQuerier) UpsertFileMetadata(ctx context.Context, arg db.UpsertFileMetadataParams) (db.FileMetadatum, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	meta := db.FileMetadatum{
+		FileID:       arg.FileID,
+		Width:        arg.Width,
+		Height:       arg.Height,
+		Format:       arg.Format,
+		CameraMake:   arg.CameraMake,
+		CameraModel:  arg.CameraModel,
+		LensModel:    arg.LensModel,
+		CapturedAt:   arg.CapturedAt,
+		GpsLatitude:  arg.GpsLatitude,
+		GpsLongitude: arg.GpsLongitude,
+		GpsAltitude:  arg.GpsAltitude,
+		Orientation:  arg.Orientation,
+		IccProfile:   arg.IccProfile,
+		Caption:      arg.Caption,
+		Keywords:     arg.Keywords,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Blurhash:     arg.Blurhash,
+		Lqip:         arg.Lqip,
+	}
+	if existing, ok := m.metadata[uuidToString(arg.FileID)]; ok {
+		meta.CreatedAt = existing.CreatedAt
+		if meta.Blurhash == nil {
+			meta.Blurhash, meta.Lqip = existing.Blurhash, existing.Lqip
+		}
+	}
+	m.metadata[uuidToString(arg.FileID)] = meta
+	return meta, nil
+}
+
+func (m *MockQuerier) ReplaceFileContent(ctx context.Context, arg db.ReplaceFileContentParams) (db.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[uuidToString(arg.ID)]
+	if !ok || f.DeletedAt.Valid {
+		return db.File{}, pgx.ErrNoRows
+	}
+	f.StorageKey = arg.StorageKey
+	f.SizeBytes = arg.SizeBytes
+	f.ContentHash = arg.ContentHash
+	f.UpdatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	m.files[uuidToString(arg.ID)] = f
+	return f, nil
+}
+
+func (m *MockQuerier) CountStorageKeyReferences(ctx context.Context, arg db.CountStorageKeyReferencesParams) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var count int64
+	for _, f := range m.files {
+		if f.StorageKey == arg.StorageKey && f.ID != arg.ID {
+			count++
+		}
+	}
+	return count, nil
+}
+
 var _ Querier = (*MockQuerier)(nil)
 
 type MockStorage struct {
@@ -975,6 +1421,13 @@ func (m *MockStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 	return m.MemoryStorage.Download(ctx, key)
 }
 
+func (m *MockStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if m.DownloadErr != nil {
+		return nil, m.DownloadErr
+	}
+	return m.MemoryStorage.DownloadRange(ctx, key, offset, length)
+}
+
 func (m *MockStorage) Delete(ctx context.Context, key string) error {
 	if m.DeleteErr != nil {
 		return m.DeleteErr
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/presets"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type PresetQuerier interface {
+	CreateTransformPreset(ctx context.Context, arg db.CreateTransformPresetParams) (db.TransformPreset, error)
+	GetTransformPreset(ctx context.Context, arg db.GetTransformPresetParams) (db.TransformPreset, error)
+	ListTransformPresetsByUser(ctx context.Context, userID pgtype.UUID) ([]db.TransformPreset, error)
+	UpdateTransformPreset(ctx context.Context, arg db.UpdateTransformPresetParams) (db.TransformPreset, error)
+	DeleteTransformPreset(ctx context.Context, arg db.DeleteTransformPresetParams) error
+}
+
+// presetLookup resolves a user's preset by name when it is applied.
+type presetLookup interface {
+	GetTransformPresetByName(ctx context.Context, arg db.GetTransformPresetByNameParams) (db.TransformPreset, error)
+}
+
+type PresetConfig struct {
+	Queries PresetQuerier
+}
+
+// PresetRequest defines a named transform preset. Omitted options are left to
+// the processor defaults.
+type PresetRequest struct {
+	Name      string `json:"name"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Fit       string `json:"fit,omitempty"`
+	Position  string `json:"position,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Quality   int    `json:"quality,omitempty"`
+	Watermark string `json:"watermark,omitempty"`
+}
+
+type PresetResponse struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Width     *int32  `json:"width,omitempty"`
+	Height    *int32  `json:"height,omitempty"`
+	Fit       *string `json:"fit,omitempty"`
+	Position  *string `json:"position,omitempty"`
+	Format    *string `json:"format,omitempty"`
+	Quality   *int32  `json:"quality,omitempty"`
+	Watermark *string `json:"watermark,omitempty"`
+	// Transforms is the preset in the CDN transform grammar.
+	Transforms string `json:"transforms"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+var presetNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// isBuiltinPreset reports whether name is one of the presets every account
+// gets; user presets cannot shadow them.
+func isBuiltinPreset(name string) bool {
+	if _, ok := presets.Get(name); ok {
+		return true
+	}
+	return name == "avif"
+}
+
+// presetParams validates a preset request and converts it to its stored form.
+func presetParams(req *PresetRequest) (db.CreateTransformPresetParams, error) {
+	var params db.CreateTransformPresetParams
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if !presetNamePattern.MatchString(req.Name) {
+		return params, errors.New("name must be 1-64 lowercase letters, digits, - or _")
+	}
+	if isBuiltinPreset(req.Name) {
+		return params, fmt.Errorf("%q is a built-in preset name", req.Name)
+	}
+	if strings.ContainsAny(req.Watermark, ",/") {
+		return params, errors.New("watermark cannot contain , or /")
+	}
+	if strings.EqualFold(req.Format, formatAuto) {
+		return params, errors.New("format auto is not supported in presets")
+	}
+
+	params.Name = req.Name
+	if req.Width != 0 {
+		v := int32(req.Width)
+		params.Width = &v
+	}
+	if req.Height != 0 {
+		v := int32(req.Height)
+		params.Height = &v
+	}
+	if req.Quality != 0 {
+		v := int32(req.Quality)
+		params.Quality = &v
+	}
+	if req.Fit != "" {
+		v := strings.ToLower(req.Fit)
+		params.Fit = &v
+	}
+	if req.Position != "" {
+		v := strings.ToLower(req.Position)
+		params.Position = &v
+	}
+	if req.Format != "" {
+		v := strings.ToLower(req.Format)
+		params.Format = &v
+	}
+	if req.Watermark != "" {
+		params.Watermark = &req.Watermark
+	}
+
+	pipeline, err := ParsePipeline(presetTransforms(db.TransformPreset{
+		Width:     params.Width,
+		Height:    params.Height,
+		Fit:       params.Fit,
+		Position:  params.Position,
+		Format:    params.Format,
+		Quality:   params.Quality,
+		Watermark: params.Watermark,
+	}))
+	if err != nil {
+		return params, err
+	}
+	if !pipeline.RequiresProcessing() {
+		return params, errors.New("preset must set at least one transform option")
+	}
+	return params, ValidatePipeline(pipeline)
+}
+
+// presetTransforms renders a preset in the CDN transform grammar: resize,
+// then watermark, then format conversion. Position applies to the resize, or
+// to the watermark when there is no resize, and quality to the final step.
+func presetTransforms(p db.TransformPreset) string {
+	var parts []string
+	geometry := p.Width != nil || p.Height != nil || p.Fit != nil
+
+	if p.Width != nil {
+		parts = append(parts, fmt.Sprintf("w_%d", *p.Width))
+	}
+	if p.Height != nil {
+		parts = append(parts, fmt.Sprintf("h_%d", *p.Height))
+	}
+	if p.Fit != nil {
+		parts = append(parts, "c_"+*p.Fit)
+	}
+	if geometry && p.Position != nil {
+		parts = append(parts, "pos_"+*p.Position)
+	}
+	if p.Watermark != nil {
+		parts = append(parts, "wm_"+*p.Watermark)
+	}
+	if !geometry && p.Position != nil {
+		parts = append(parts, "pos_"+*p.Position)
+	}
+	if p.Format != nil {
+		parts = append(parts, "f_"+*p.Format)
+	}
+	if p.Quality != nil {
+		parts = append(parts, fmt.Sprintf("q_%d", *p.Quality))
+	}
+	return strings.Join(parts, ",")
+}
+
+// resolveUserPresets splits preset names into built-in names and the transform
+// strings of the user's own presets. Names that match neither are kept with
+// the built-in names so callers report them as before.
+func resolveUserPresets(ctx context.Context, q presetLookup, userID pgtype.UUID, names []string) (builtin, transforms []string) {
+	for _, name := range names {
+		if !isBuiltinPreset(name) {
+			preset, err := q.GetTransformPresetByName(ctx, db.GetTransformPresetByNameParams{UserID: userID, Name: name})
+			if err == nil {
+				transforms = append(transforms, presetTransforms(preset))
+				continue
+			}
+		}
+		builtin = append(builtin, name)
+	}
+	return builtin, transforms
+}
+
+// expandPresetTransforms replaces preset_<name> parameters in a CDN transform
+// string with the named preset of the file owner.
+func expandPresetTransforms(ctx context.Context, q presetLookup, userID pgtype.UUID, transforms string) (string, error) {
+	if !strings.Contains(transforms, "preset_") {
+		return transforms, nil
+	}
+
+	parts := strings.Split(transforms, ",")
+	for i, part := range parts {
+		name, ok := strings.CutPrefix(strings.TrimSpace(part), "preset_")
+		if !ok {
+			continue
+		}
+		preset, err := q.GetTransformPresetByName(ctx, db.GetTransformPresetByNameParams{UserID: userID, Name: name})
+		if err != nil {
+			return "", fmt.Errorf("unknown preset: %s", name)
+		}
+		parts[i] = presetTransforms(preset)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func ListPresetsHandler(cfg *PresetConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		rows, err := cfg.Queries.ListTransformPresetsByUser(r.Context(), pgtype.UUID{Bytes: userID, Valid: true})
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		results := make([]PresetResponse, len(rows))
+		for i, p := range rows {
+			results[i] = presetToResponse(p)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"presets": results,
+		})
+	}
+}
+
+func CreatePresetHandler(cfg *PresetConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		var req PresetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid JSON request body", http.StatusBadRequest))
+			return
+		}
+
+		params, err := presetParams(&req)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_preset", err.Error(), http.StatusBadRequest))
+			return
+		}
+		params.UserID = pgtype.UUID{Bytes: userID, Valid: true}
+
+		preset, err := cfg.Queries.CreateTransformPreset(r.Context(), params)
+		if err != nil {
+			if isUniqueViolation(err) {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "preset_exists", "A preset with this name already exists", http.StatusConflict))
+				return
+			}
+			log.Error("failed to create preset", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(presetToResponse(preset))
+	}
+}
+
+func GetPresetHandler(cfg *PresetConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		presetID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_preset_id", "Invalid preset ID format", http.StatusBadRequest))
+			return
+		}
+
+		preset, err := cfg.Queries.GetTransformPreset(r.Context(), db.GetTransformPresetParams{
+			ID:     pgtype.UUID{Bytes: presetID, Valid: true},
+			UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		})
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(presetToResponse(preset))
+	}
+}
+
+func UpdatePresetHandler(cfg *PresetConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		presetID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_preset_id", "Invalid preset ID format", http.StatusBadRequest))
+			return
+		}
+
+		var req PresetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid JSON request body", http.StatusBadRequest))
+			return
+		}
+
+		params, err := presetParams(&req)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_preset", err.Error(), http.StatusBadRequest))
+			return
+		}
+
+		preset, err := cfg.Queries.UpdateTransformPreset(r.Context(), db.UpdateTransformPresetParams{
+			ID:        pgtype.UUID{Bytes: presetID, Valid: true},
+			UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+			Name:      params.Name,
+			Width:     params.Width,
+			Height:    params.Height,
+			Fit:       params.Fit,
+			Position:  params.Position,
+			Format:    params.Format,
+			Quality:   params.Quality,
+			Watermark: params.Watermark,
+		})
+		if err != nil {
+			if isUniqueViolation(err) {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "preset_exists", "A preset with this name already exists", http.StatusConflict))
+				return
+			}
+			log.Debug("failed to update preset", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(presetToResponse(preset))
+	}
+}
+
+func DeletePresetHandler(cfg *PresetConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		presetID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_preset_id", "Invalid preset ID format", http.StatusBadRequest))
+			return
+		}
+
+		if err := cfg.Queries.DeleteTransformPreset(r.Context(), db.DeleteTransformPresetParams{
+			ID:     pgtype.UUID{Bytes: presetID, Valid: true},
+			UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		}); err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func presetToResponse(p db.TransformPreset) PresetResponse {
+	return PresetResponse{
+		ID:         uuidFromPgtype(p.ID),
+		Name:       p.Name,
+		Width:      p.Width,
+		Height:     p.Height,
+		Fit:        p.Fit,
+		Position:   p.Position,
+		Format:     p.Format,
+		Quality:    p.Quality,
+		Watermark:  p.Watermark,
+		Transforms: presetTransforms(p),
+		CreatedAt:  p.CreatedAt.Time.Format(time.RFC3339),
+		UpdatedAt:  p.UpdatedAt.Time.Format(time.RFC3339),
+	}
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint error.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,343 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func createTestPreset(userID uuid.UUID, name string) db.TransformPreset {
+	width := int32(1200)
+	fit := "cover"
+	format := "webp"
+	quality := int32(80)
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return db.TransformPreset{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		Name:      name,
+		Width:     &width,
+		Fit:       &fit,
+		Format:    &format,
+		Quality:   &quality,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestPresetTransforms(t *testing.T) {
+	w, h, q := int32(800), int32(600), int32(75)
+	fit, pos, format, wm := "cover", "top", "webp", "acme"
+
+	tests := []struct {
+		name   string
+		preset db.TransformPreset
+		want   string
+	}{
+		{
+			name:   "resize and convert",
+			preset: db.TransformPreset{Width: &w, Height: &h, Fit: &fit, Format: &format, Quality: &q},
+			want:   "w_800,h_600,c_cover,f_webp,q_75",
+		},
+		{
+			name:   "position applies to resize",
+			preset: db.TransformPreset{Width: &w, Position: &pos, Watermark: &wm},
+			want:   "w_800,pos_top,wm_acme",
+		},
+		{
+			name:   "position applies to watermark",
+			preset: db.TransformPreset{Watermark: &wm, Position: &pos},
+			want:   "wm_acme,pos_top",
+		},
+		{
+			name:   "quality only",
+			preset: db.TransformPreset{Quality: &q},
+			want:   "q_75",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := presetTransforms(tt.preset); got != tt.want {
+				t.Errorf("presetTransforms() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePresetHandler(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "valid preset",
+			body:       `{"name": "Hero", "width": 1600, "height": 900, "fit": "cover", "format": "webp", "quality": 80}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "duplicate name",
+			body:       `{"name": "existing", "width": 100}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   "preset_exists",
+		},
+		{
+			name:       "built-in name",
+			body:       `{"name": "thumbnail", "width": 100}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_preset",
+		},
+		{
+			name:       "invalid name",
+			body:       `{"name": "my preset", "width": 100}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_preset",
+		},
+		{
+			name:       "no options",
+			body:       `{"name": "empty"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_preset",
+		},
+		{
+			name:       "invalid fit",
+			body:       `{"name": "bad", "width": 100, "fit": "stretch"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_preset",
+		},
+		{
+			name:       "auto format",
+			body:       `{"name": "auto", "format": "auto"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_preset",
+		},
+		{
+			name:       "watermark with separator",
+			body:       `{"name": "wm", "watermark": "a,b"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_preset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := NewMockQuerier()
+			queries.AddPreset(createTestPreset(userID, "existing"))
+			handler := CreatePresetHandler(&PresetConfig{Queries: queries})
+
+			req := httptest.NewRequest("POST", "/v1/presets", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("body = %s, want code %s", rec.Body.String(), tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusCreated {
+				var resp PresetResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Name != "hero" {
+					t.Errorf("name = %q, want hero", resp.Name)
+				}
+				if resp.Transforms != "w_1600,h_900,c_cover,f_webp,q_80" {
+					t.Errorf("transforms = %q", resp.Transforms)
+				}
+			}
+		})
+	}
+}
+
+func TestPresetHandlers_OwnerScoped(t *testing.T) {
+	ownerID := uuid.New()
+	otherID := uuid.New()
+	queries := NewMockQuerier()
+	preset := createTestPreset(ownerID, "hero")
+	queries.AddPreset(preset)
+	cfg := &PresetConfig{Queries: queries}
+
+	req := httptest.NewRequest("GET", "/v1/presets/"+uuidFromPgtype(preset.ID), nil)
+	req.SetPathValue("id", uuidFromPgtype(preset.ID))
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, otherID))
+	rec := httptest.NewRecorder()
+	GetPresetHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get by other user status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	req = httptest.NewRequest("PUT", "/v1/presets/"+uuidFromPgtype(preset.ID), strings.NewReader(`{"name": "hero", "width": 640}`))
+	req.SetPathValue("id", uuidFromPgtype(preset.ID))
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, ownerID))
+	rec = httptest.NewRecorder()
+	UpdatePresetHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp PresetResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Transforms != "w_640" {
+		t.Errorf("transforms after update = %q, want w_640", resp.Transforms)
+	}
+
+	req = httptest.NewRequest("DELETE", "/v1/presets/"+uuidFromPgtype(preset.ID), nil)
+	req.SetPathValue("id", uuidFromPgtype(preset.ID))
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, ownerID))
+	rec = httptest.NewRecorder()
+	DeletePresetHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/presets", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, ownerID))
+	rec = httptest.NewRecorder()
+	ListPresetsHandler(cfg).ServeHTTP(rec, req)
+	var list struct {
+		Presets []PresetResponse `json:"presets"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(list.Presets) != 0 {
+		t.Errorf("listed %d presets after delete, want 0", len(list.Presets))
+	}
+}
+
+func TestExpandPresetTransforms(t *testing.T) {
+	userID := uuid.New()
+	pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+	queries := NewMockQuerier()
+	queries.AddPreset(createTestPreset(userID, "hero"))
+
+	got, err := expandPresetTransforms(context.Background(), queries, pgUserID, "preset_hero,wm_acme")
+	if err != nil {
+		t.Fatalf("expandPresetTransforms() error = %v", err)
+	}
+	if want := "w_1200,c_cover,f_webp,q_80,wm_acme"; got != want {
+		t.Errorf("expandPresetTransforms() = %q, want %q", got, want)
+	}
+
+	if _, err := expandPresetTransforms(context.Background(), queries, pgUserID, "preset_missing"); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+
+	otherUser := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	if _, err := expandPresetTransforms(context.Background(), queries, otherUser, "preset_hero"); err == nil {
+		t.Error("expected error for another user's preset")
+	}
+}
+
+func TestTransformHandler_UserPresetEnqueuesPipeline(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, broker, cfg := setupTestDeps(t)
+	queries.AddFile(createTestFileWithID(fileID, userID, "test.jpg"))
+	queries.AddPreset(createTestPreset(userID, "hero"))
+
+	router := NewRouter(&Config{
+		Storage:       storage,
+		Queries:       queries,
+		Broker:        broker,
+		MaxUploadSize: cfg.MaxUploadSize,
+		JWTSecret:     cfg.JWTSecret,
+	})
+
+	req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/transform", strings.NewReader(`{"presets": ["hero", "thumbnail"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, 1*time.Hour))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	if !broker.HasJob("thumbnail") {
+		t.Error("expected a thumbnail job for the built-in preset")
+	}
+
+	var payload *worker.TransformPayload
+	for _, j := range broker.jobs {
+		if p, ok := j.Payload.(*worker.TransformPayload); ok && j.Type == string(db.JobTypeTransform) {
+			payload = p
+		}
+	}
+	if payload == nil {
+		t.Fatal("expected a transform job for the user preset")
+	}
+	if payload.Transforms != "w_1200,c_cover,f_webp,q_80" {
+		t.Errorf("transforms = %q", payload.Transforms)
+	}
+}
+
+func TestCDNHandler_UserPreset(t *testing.T) {
+	fileID := uuid.New()
+	userID := uuid.New()
+	queries, store, registry := setupCDNTestDeps(t)
+
+	queries.AddShareByToken("preset-token", createTestShareByToken(fileID, userID, "preset-token",
+		"uploads/test.jpg", "image/jpeg", "test.jpg", nil, nil))
+	queries.AddPreset(createTestPreset(userID, "hero"))
+	_ = store.MemoryStorage.Upload(context.Background(), "uploads/test.jpg",
+		bytes.NewReader([]byte("original")), "image/jpeg", 8)
+
+	var widths []int
+	for _, name := range []string{"resize", "webp"} {
+		registry.Register(name, &MockProcessor{
+			name:  name,
+			types: []string{"image/jpeg"},
+			processFunc: func(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
+				data, _ := io.ReadAll(input)
+				if name == "resize" {
+					widths = append(widths, opts.Width)
+				}
+				return &processor.Result{Data: bytes.NewReader(data), ContentType: "image/webp", Filename: "out.webp"}, nil
+			},
+		})
+	}
+
+	handler := CDNHandler(&CDNConfig{Storage: store, Queries: queries, Registry: registry})
+
+	for _, tt := range []struct {
+		preset     string
+		wantStatus int
+	}{
+		{"preset_hero", http.StatusOK},
+		{"preset_missing", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest("GET", "/cdn/preset-token/"+tt.preset+"/test.jpg", nil)
+		req.SetPathValue("token", "preset-token")
+		req.SetPathValue("transforms", tt.preset)
+		req.SetPathValue("filename", "test.jpg")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d; body = %s", tt.preset, rec.Code, tt.wantStatus, rec.Body.String())
+		}
+	}
+	if len(widths) != 1 || widths[0] != 1200 {
+		t.Errorf("resize widths = %v, want [1200]", widths)
+	}
+}
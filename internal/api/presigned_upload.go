@@ -0,0 +1,295 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/filetype"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// presignedUploadExpiry is how long a presigned upload URL accepts the PUT.
+const presignedUploadExpiry = 15 * time.Minute
+
+// presignedCompleteLockTTL bounds how long a completing request holds its
+// claim on an upload, long enough to hash a maximum size object.
+const presignedCompleteLockTTL = 10 * time.Minute
+
+// PresignedUploadResponse tells the client where to PUT the file body
+type PresignedUploadResponse struct {
+	UploadID  string            `json:"upload_id"`
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// InitPresignedUploadHandler reserves a storage key and returns a presigned
+// PUT URL so the file body goes straight to storage instead of through the
+// API. The upload is finished with CompletePresignedUploadHandler.
+func InitPresignedUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		var req InitUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid request body", http.StatusBadRequest))
+			return
+		}
+
+		if req.Filename == "" || req.TotalSize <= 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "missing_required_fields", "Filename and total_size are required", http.StatusBadRequest))
+			return
+		}
+		if cfg.MaxUploadSize > 0 && req.TotalSize > cfg.MaxUploadSize {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "file_too_large",
+				fmt.Sprintf("File too large, max size: %d MB", cfg.MaxUploadSize/(1024*1024)), http.StatusRequestEntityTooLarge))
+			return
+		}
+
+		contentType := req.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		if !IsAllowedMIMEType(contentType) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_file_type",
+				"This file type is not allowed", http.StatusBadRequest))
+			return
+		}
+		if IsBlockedExtension(req.Filename) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "blocked_file_type",
+				"This file type is not allowed for security reasons", http.StatusBadRequest))
+			return
+		}
+		filename := SanitizeFilename(req.Filename)
+
+		if appErr := checkUploadQuota(r.Context(), cfg, userID, contentType, req.TotalSize); appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
+
+		placement, appErr := cfg.parsePlacement(r.Context(), userID, req.FolderID, req.Tags)
+		if appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
+
+		uploadID := uuid.New().String()
+		storageKey := fmt.Sprintf("uploads/%s/%s/%s", userID.String(), uploadID, filename)
+
+		uploadURL, err := cfg.Storage.GetPresignedUploadURL(r.Context(), storageKey, contentType, int(presignedUploadExpiry.Seconds()))
+		if err != nil {
+			log.Error("failed to presign upload", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		session := &uploadSession{
+			ID:           uploadID,
+			UserID:       userID,
+			Filename:     filename,
+			ContentType:  contentType,
+			TotalSize:    req.TotalSize,
+			ChunksLoaded: make(map[int]bool),
+			StorageKey:   storageKey,
+			CreatedAt:    time.Now(),
+			Direct:       true,
+			FolderID:     req.FolderID,
+			Tags:         placement.Tags,
+		}
+		if err := cfg.sessions().Set(r.Context(), session); err != nil {
+			log.Error("failed to save upload session", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		log.Info("presigned upload initiated", "upload_id", uploadID, "filename", filename, "total_size", req.TotalSize)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(PresignedUploadResponse{
+			UploadID:  uploadID,
+			UploadURL: uploadURL,
+			Method:    http.MethodPut,
+			Headers:   map[string]string{"Content-Type": contentType},
+			ExpiresAt: session.CreatedAt.Add(presignedUploadExpiry).UTC(),
+		})
+	}
+}
+
+// CompletePresignedUploadHandler verifies the object written through the
+// presigned URL and turns it into a file, running the same quota checks,
+// processing jobs and events as a regular upload. The upload is claimed
+// before anything is written so concurrent completes create a single file.
+func CompletePresignedUploadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		uploadID := r.PathValue("uploadId")
+		session, err := cfg.sessions().Get(r.Context(), uploadID)
+		if err != nil {
+			writeSessionLookupError(w, r, err)
+			return
+		}
+		if session.UserID != userID || !session.Direct {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "session_not_found", "Upload session not found", http.StatusNotFound))
+			return
+		}
+
+		log = log.With("upload_id", uploadID)
+
+		unlock, err := cfg.sessions().Lock(r.Context(), uploadID, presignedCompleteLockTTL)
+		if err != nil {
+			if errors.Is(err, errUploadSessionLocked) {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "upload_locked", "This upload is already being completed", http.StatusLocked))
+				return
+			}
+			log.Error("failed to lock upload session", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+		defer unlock()
+
+		// A complete that finished while this one waited has deleted the
+		// session, so reloading it under the lock turns the repeat into a 404.
+		session, err = cfg.sessions().Get(r.Context(), uploadID)
+		if err != nil {
+			writeSessionLookupError(w, r, err)
+			return
+		}
+
+		info, err := cfg.Storage.Stat(r.Context(), session.StorageKey)
+		if errors.Is(err, storage.ErrNotFound) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "upload_not_found",
+				"The file has not been uploaded to the presigned URL yet", http.StatusConflict))
+			return
+		}
+		if err != nil {
+			log.Error("failed to stat uploaded object", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		// Anything that fails from here on leaves an object that will never
+		// become a file, so it is removed along with the session.
+		reject := func(appErr *apperror.Error) {
+			if err := cfg.Storage.Delete(r.Context(), session.StorageKey); err != nil {
+				log.Warn("failed to delete rejected upload", "storage_key", session.StorageKey, "error", err)
+			}
+			if err := cfg.sessions().Delete(r.Context(), uploadID); err != nil {
+				log.Error("failed to delete upload session", "error", err)
+			}
+			apperror.WriteJSON(w, r, appErr)
+		}
+
+		if info.Size != session.TotalSize {
+			log.Warn("presigned upload size mismatch", "expected", session.TotalSize, "actual", info.Size)
+			reject(apperror.WrapWithMessage(nil, "size_mismatch",
+				fmt.Sprintf("Uploaded %d bytes, expected %d", info.Size, session.TotalSize), http.StatusBadRequest))
+			return
+		}
+
+		if appErr := checkUploadQuota(r.Context(), cfg, userID, session.ContentType, info.Size); appErr != nil {
+			reject(appErr)
+			return
+		}
+
+		// Verify the declared type against the object's magic bytes, then
+		// read the rest of it for the content hash.
+		object, err := cfg.Storage.Download(r.Context(), session.StorageKey)
+		if err != nil {
+			log.Error("failed to read uploaded object", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+		defer func() { _ = object.Close() }()
+		hashingBody := storage.NewHashingReader(object)
+		detectedType, body, err := filetype.Sniff(hashingBody)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+		contentType, err := filetype.Resolve(session.ContentType, detectedType)
+		if err != nil {
+			log.Warn("presigned upload content rejected", "declared_type", session.ContentType, "detected_type", detectedType)
+			reject(contentMismatchError(err))
+			return
+		}
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			log.Error("failed to hash uploaded object", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+		contentHash := hashingBody.Sum()
+
+		fileID := session.ID
+		if cfg.Queries != nil {
+			dbFile, err := cfg.Queries.CreateFile(r.Context(), db.CreateFileParams{
+				UserID:      pgtype.UUID{Bytes: userID, Valid: true},
+				Filename:    session.Filename,
+				ContentType: contentType,
+				SizeBytes:   info.Size,
+				StorageKey:  session.StorageKey,
+				Status:      db.FileStatusPending,
+				ContentHash: &contentHash,
+			})
+			if err != nil {
+				log.Error("failed to create file record", "error", err)
+				apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+				return
+			}
+			fileID = uuidFromPgtype(dbFile.ID)
+
+			placement := session.placement()
+			placement.apply(r.Context(), cfg.Queries, userID, dbFile.ID, log)
+
+			if cfg.Broker != nil {
+				var fileUUID uuid.UUID
+				copy(fileUUID[:], dbFile.ID.Bytes[:])
+				enqueueProcessingJobs(r.Context(), cfg.Queries, cfg.Broker, userID, placement.ruleFile(fileUUID, session.Filename, contentType), log)
+			}
+		}
+		metrics.RecordFileUpload("success", info.Size, time.Since(session.CreatedAt).Seconds())
+
+		if err := cfg.sessions().Delete(r.Context(), uploadID); err != nil {
+			log.Error("failed to delete upload session", "error", err)
+		}
+
+		log.Info("presigned upload completed", "file_id", fileID, "filename", session.Filename, "size", info.Size)
+		recordUpload(r, userID, fileID, session.Filename, contentType, info.Size, "presigned")
+
+		if event, err := webhook.NewFileUploadedEvent(fileID, session.Filename, contentType, info.Size); err == nil {
+			dispatchWebhook(r.Context(), cfg.WebhookDispatcher, userID, event)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":       fileID,
+			"filename": session.Filename,
+			"status":   string(db.FileStatusPending),
+		})
+	}
+}
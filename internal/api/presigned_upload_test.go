@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func presignedRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"), time.Hour))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func initPresignedUpload(t *testing.T, router http.Handler, body string) PresignedUploadResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, presignedRequest(t, http.MethodPost, "/v1/upload/presigned", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("init status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp PresignedUploadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode init response: %v", err)
+	}
+	return resp
+}
+
+func TestPresignedUploadFlow(t *testing.T) {
+	queries, store, broker, cfg := setupTestDeps(t)
+	dispatcher, deliveries := newRecordingDispatcher()
+	router := NewRouter(&Config{
+		Storage:           store,
+		Queries:           queries,
+		Broker:            broker,
+		MaxUploadSize:     cfg.MaxUploadSize,
+		JWTSecret:         cfg.JWTSecret,
+		UploadSessions:    newMemoryUploadSessionStore(),
+		WebhookDispatcher: dispatcher,
+	})
+	content := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 24)
+
+	resp := initPresignedUpload(t, router, `{"filename":"photo.png","content_type":"image/png","total_size":32}`)
+	if resp.UploadURL == "" || resp.Method != http.MethodPut {
+		t.Fatalf("init response = %+v", resp)
+	}
+	if resp.Headers["Content-Type"] != "image/png" {
+		t.Errorf("Content-Type header = %q, want image/png", resp.Headers["Content-Type"])
+	}
+
+	complete := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, presignedRequest(t, http.MethodPost, "/v1/upload/presigned/"+resp.UploadID+"/complete", ""))
+		return rec
+	}
+
+	if rec := complete(); rec.Code != http.StatusConflict {
+		t.Fatalf("complete before upload status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	storageKey := "uploads/550e8400-e29b-41d4-a716-446655440000/" + resp.UploadID + "/photo.png"
+	if err := store.Upload(context.Background(), storageKey, strings.NewReader(content), "image/png", 32); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	rec := complete()
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("complete status = %d, want %d; body = %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	var result map[string]any
+	_ = json.NewDecoder(rec.Body).Decode(&result)
+	if result["id"] == "" || result["filename"] != "photo.png" {
+		t.Errorf("complete response = %v", result)
+	}
+	if !broker.HasJob("thumbnail") {
+		t.Error("expected thumbnail job to be enqueued")
+	}
+	fileID, _ := result["id"].(string)
+	event := waitForDelivery(t, deliveries, webhook.EventFileUploaded)
+	var data webhook.FileUploadedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data.FileID != fileID || data.ContentType != "image/png" || data.SizeBytes != 32 {
+		t.Errorf("file.uploaded data = %+v", data)
+	}
+	file, err := queries.GetFile(context.Background(), pgtype.UUID{Bytes: uuid.MustParse(fileID), Valid: true})
+	if err != nil {
+		t.Fatalf("file not created: %v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	if file.ContentHash == nil || *file.ContentHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("content hash = %v, want the SHA-256 of the object", file.ContentHash)
+	}
+
+	if rec := complete(); rec.Code != http.StatusNotFound {
+		t.Errorf("second complete status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestPresignedUploadComplete_Concurrent(t *testing.T) {
+	queries, store, broker, cfg := setupTestDeps(t)
+	sessions := newMemoryUploadSessionStore()
+	router := NewRouter(&Config{
+		Storage:        store,
+		Queries:        queries,
+		Broker:         broker,
+		MaxUploadSize:  cfg.MaxUploadSize,
+		JWTSecret:      cfg.JWTSecret,
+		UploadSessions: sessions,
+	})
+	content := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 24)
+
+	resp := initPresignedUpload(t, router, `{"filename":"photo.png","content_type":"image/png","total_size":32}`)
+	storageKey := "uploads/550e8400-e29b-41d4-a716-446655440000/" + resp.UploadID + "/photo.png"
+	_ = store.Upload(context.Background(), storageKey, strings.NewReader(content), "image/png", 32)
+
+	complete := func() int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, presignedRequest(t, http.MethodPost, "/v1/upload/presigned/"+resp.UploadID+"/complete", ""))
+		return rec.Code
+	}
+
+	// Another complete holds the claim
+	unlock, err := sessions.Lock(context.Background(), resp.UploadID, time.Minute)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if code := complete(); code != http.StatusLocked {
+		t.Errorf("complete while claimed status = %d, want %d", code, http.StatusLocked)
+	}
+	unlock()
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = complete()
+		}()
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusAccepted:
+			accepted++
+		case http.StatusLocked, http.StatusNotFound:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("%d completes accepted, want 1", accepted)
+	}
+	userID := pgtype.UUID{Bytes: uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"), Valid: true}
+	if n, _ := queries.CountFilesByUser(context.Background(), userID); n != 1 {
+		t.Errorf("created %d files, want 1", n)
+	}
+}
+
+func TestPresignedUploadComplete_Rejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"size mismatch", "\x89PNG\r\n\x1a\nshort", http.StatusBadRequest, "size_mismatch"},
+		{"content mismatch", "MZ" + strings.Repeat("\x00", 30), http.StatusBadRequest, "file_content"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, store, broker, cfg := setupTestDeps(t)
+			router := NewRouter(&Config{
+				Storage:        store,
+				Queries:        queries,
+				Broker:         broker,
+				MaxUploadSize:  cfg.MaxUploadSize,
+				JWTSecret:      cfg.JWTSecret,
+				UploadSessions: newMemoryUploadSessionStore(),
+			})
+
+			resp := initPresignedUpload(t, router, `{"filename":"photo.png","content_type":"image/png","total_size":32}`)
+			storageKey := "uploads/550e8400-e29b-41d4-a716-446655440000/" + resp.UploadID + "/photo.png"
+			_ = store.Upload(context.Background(), storageKey, strings.NewReader(tt.body), "image/png", int64(len(tt.body)))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, presignedRequest(t, http.MethodPost, "/v1/upload/presigned/"+resp.UploadID+"/complete", ""))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("body = %s, want error code %q", rec.Body.String(), tt.wantCode)
+			}
+			if ok, _ := store.Exists(context.Background(), storageKey); ok {
+				t.Error("rejected upload was not deleted from storage")
+			}
+		})
+	}
+}
+
+func TestInitPresignedUpload_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"missing size", `{"filename":"a.png","content_type":"image/png"}`, http.StatusBadRequest},
+		{"blocked extension", `{"filename":"run.exe","content_type":"image/png","total_size":10}`, http.StatusBadRequest},
+		{"too large", `{"filename":"a.png","content_type":"image/png","total_size":10000000000}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, store, broker, cfg := setupTestDeps(t)
+			router := NewRouter(&Config{
+				Storage:       store,
+				Queries:       queries,
+				Broker:        broker,
+				MaxUploadSize: cfg.MaxUploadSize,
+				JWTSecret:     cfg.JWTSecret,
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, presignedRequest(t, http.MethodPost, "/v1/upload/presigned", tt.body))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
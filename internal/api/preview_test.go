@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+)
+
+func TestVideoPreviewHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		tier       db.SubscriptionTier
+		body       string
+		wantStatus int
+		want       worker.VideoPreviewPayload
+	}{
+		{"defaults", db.SubscriptionTierPro, `{}`, http.StatusAccepted,
+			worker.VideoPreviewPayload{Formats: []string{"mp4"}, Width: 320, Segments: 3, SegmentDuration: 2, MaxResolution: 1080}},
+		{"animated range", db.SubscriptionTierEnterprise, `{"formats": ["webp", "gif", "webp"], "width": 480, "segments": 4, "segment_duration": 1.5, "start": 5, "end": 25}`, http.StatusAccepted,
+			worker.VideoPreviewPayload{Formats: []string{"webp", "gif"}, Width: 480, Segments: 4, SegmentDuration: 1.5, Start: 5, End: 25, MaxResolution: 2160}},
+		{"unknown format", db.SubscriptionTierPro, `{"formats": ["webm"]}`, http.StatusBadRequest, worker.VideoPreviewPayload{}},
+		{"width too large", db.SubscriptionTierPro, `{"width": 4096}`, http.StatusBadRequest, worker.VideoPreviewPayload{}},
+		{"too many segments", db.SubscriptionTierPro, `{"segments": 50}`, http.StatusBadRequest, worker.VideoPreviewPayload{}},
+		{"end before start", db.SubscriptionTierPro, `{"start": 10, "end": 5}`, http.StatusBadRequest, worker.VideoPreviewPayload{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			fileID := uuid.New()
+			queries, storage, broker, cfg := setupTestDeps(t)
+			queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+			queries.BillingTier = tt.tier
+			router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+			req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/preview", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				if broker.HasJob("video_preview") {
+					t.Error("no job should be enqueued")
+				}
+				return
+			}
+			var payload *worker.VideoPreviewPayload
+			for _, j := range broker.jobs {
+				if p, ok := j.Payload.(*worker.VideoPreviewPayload); ok {
+					payload = p
+				}
+			}
+			if payload == nil {
+				t.Fatal("expected a video_preview job")
+			}
+			got := *payload
+			got.JobID, got.FileID = tt.want.JobID, tt.want.FileID
+			if !slices.Equal(got.Formats, tt.want.Formats) || got.Width != tt.want.Width || got.Segments != tt.want.Segments ||
+				got.SegmentDuration != tt.want.SegmentDuration || got.Start != tt.want.Start || got.End != tt.want.End ||
+				got.MaxResolution != tt.want.MaxResolution {
+				t.Errorf("payload = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVideoPreviewHandler_TransformationLimit(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, broker, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+	queries.TransformationsUsed = 10000
+	router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/preview", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "transformation_limit_reached") {
+		t.Fatalf("status = %d, body = %s; want 403 transformation_limit_reached", rec.Code, rec.Body.String())
+	}
+	if broker.HasJob("video_preview") {
+		t.Error("no job should be enqueued")
+	}
+}
+
+func TestVideoPreviewHandler_NotAVideo(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, broker, cfg := setupTestDeps(t)
+	queries.AddFile(createTestFileWithID(fileID, userID, "photo.jpg"))
+	router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/preview", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDownloadHandler_PreviewFormats(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+	for _, variant := range []struct {
+		variantType db.VariantType
+		contentType string
+		filename    string
+	}{
+		{db.VariantTypeVideoPreviewWebp, "image/webp", "preview.webp"},
+		{db.VariantTypeVideoPreviewGif, "image/gif", "preview.gif"},
+	} {
+		v := createTestVariant(fileID, string(variant.variantType))
+		v.ContentType = variant.contentType
+		v.StorageKey = "processed/" + fileID.String() + "/" + string(variant.variantType) + "/" + variant.filename
+		queries.AddVariant(v)
+	}
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	for variant, wantFile := range map[string]string{
+		"video_preview_webp": "preview.webp",
+		"video_preview_gif":  "preview.gif",
+	} {
+		req := httptest.NewRequest("GET", "/v1/files/"+fileID.String()+"/download?variant="+variant, nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("%s: status = %d, want %d", variant, rec.Code, http.StatusTemporaryRedirect)
+		}
+		if location := rec.Header().Get("Location"); !strings.Contains(location, "/"+variant+"/"+wantFile) {
+			t.Errorf("%s: Location = %q, want the %s preview", variant, location, wantFile)
+		}
+	}
+}
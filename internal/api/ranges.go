@@ -0,0 +1,159 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+)
+
+var errRangeUnsatisfiable = errors.New("range not satisfiable")
+
+// byteRange is a resolved range within an object, in bytes.
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// parseRange resolves a Range header against an object of the given size.
+// Only single ranges are honoured; a nil range with a nil error means the
+// header should be ignored and the full object served.
+func parseRange(header string, size int64) (*byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+
+	if first == "" {
+		// Suffix range: the final N bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return nil, nil
+		}
+		if n == 0 || size == 0 {
+			return nil, errRangeUnsatisfiable
+		}
+		n = min(n, size)
+		return &byteRange{start: size - n, length: n}, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+	if start >= size {
+		return nil, errRangeUnsatisfiable
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return nil, nil
+		}
+		end = min(end, size-1)
+	}
+
+	return &byteRange{start: start, length: end - start + 1}, nil
+}
+
+// ifRangeMatches reports whether the If-Range precondition, if any, allows a
+// partial response. Only strong entity tags are compared; dates never match
+// because the handlers do not send Last-Modified.
+func ifRangeMatches(r *http.Request, etag string) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	return etag != "" && !strings.HasPrefix(ifRange, "W/") && ifRange == etag
+}
+
+// objectETag returns a strong validator for an object. Variant keys are
+// rewritten in place when a variant is regenerated, so the size and the time
+// the object's row was last written are part of the tag along with the key.
+func objectETag(storageKey string, size int64, modified time.Time) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00%d\x00%d", storageKey, size, modified.UnixNano())
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)[:8])
+}
+
+// isFirstRange reports whether r reads an object from its start: no usable
+// Range header, or a range beginning at byte 0. Handlers use it to count one
+// download per transfer rather than one per chunk.
+func isFirstRange(r *http.Request) bool {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(r.Header.Get("Range")), "bytes=")
+	if !ok {
+		return true
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(spec), "-")
+	if first == "" {
+		// Suffix ranges read the tail of the object.
+		return false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return err != nil || start == 0
+}
+
+// serveRangeRequest answers a request carrying a Range header directly from
+// storage with 206 or 416. It returns false when the request has no usable
+// range and the caller should serve the full object instead.
+func serveRangeRequest(w http.ResponseWriter, r *http.Request, store storage.Storage, storageKey, contentType string, size int64, etag string) bool {
+	header := r.Header.Get("Range")
+	if header == "" || !ifRangeMatches(r, etag) {
+		return false
+	}
+
+	rng, err := parseRange(header, size)
+	if errors.Is(err, errRangeUnsatisfiable) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "requested range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+		return true
+	}
+	if rng == nil {
+		return false
+	}
+
+	reader, err := store.DownloadRange(r.Context(), storageKey, rng.start, rng.length)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return true
+		}
+		logger.FromContext(r.Context()).Error("range download failed", "key", storageKey, "error", err)
+		http.Error(w, "download failed", http.StatusInternalServerError)
+		return true
+	}
+	defer func() { _ = reader.Close() }()
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Range", rng.contentRange(size))
+	w.Header().Set("Content-Length", strconv.FormatInt(rng.length, 10))
+	w.WriteHeader(http.StatusPartialContent)
+
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, reader)
+	}
+	return true
+}
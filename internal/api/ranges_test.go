@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		size    int64
+		want    *byteRange
+		wantErr error
+	}{
+		{"first bytes", "bytes=0-99", 1000, &byteRange{0, 100}, nil},
+		{"open ended", "bytes=500-", 1000, &byteRange{500, 500}, nil},
+		{"end clamped to size", "bytes=900-5000", 1000, &byteRange{900, 100}, nil},
+		{"suffix", "bytes=-200", 1000, &byteRange{800, 200}, nil},
+		{"suffix larger than object", "bytes=-5000", 1000, &byteRange{0, 1000}, nil},
+		{"start beyond end", "bytes=1000-", 1000, nil, errRangeUnsatisfiable},
+		{"zero suffix", "bytes=-0", 1000, nil, errRangeUnsatisfiable},
+		{"multiple ranges ignored", "bytes=0-1,5-6", 1000, nil, nil},
+		{"other unit ignored", "items=0-1", 1000, nil, nil},
+		{"reversed range ignored", "bytes=50-10", 1000, nil, nil},
+		{"malformed ignored", "bytes=abc", 1000, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRange(tt.header, tt.size)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseRange() error = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseRange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectETag(t *testing.T) {
+	const key = "variants/file/preview.mp4"
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := objectETag(key, 100, modified)
+
+	if got := objectETag(key, 100, modified); got != etag {
+		t.Errorf("objectETag() = %s, want stable %s", got, etag)
+	}
+	if objectETag(key, 101, modified) == etag {
+		t.Error("ETag should change when the size changes")
+	}
+	if objectETag(key, 100, modified.Add(time.Second)) == etag {
+		t.Error("ETag should change when the object is rewritten")
+	}
+	if objectETag("variants/file/other.mp4", 100, modified) == etag {
+		t.Error("ETag should differ between keys")
+	}
+}
+
+func TestIsFirstRange(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", true},
+		{"bytes=0-", true},
+		{"bytes=0-99", true},
+		{"bytes=100-199", false},
+		{"bytes=-200", false},
+		{"items=5-6", true},
+		{"bytes=abc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Range", tt.header)
+			}
+			if got := isFirstRange(r); got != tt.want {
+				t.Errorf("isFirstRange(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/presets"
+	imgproc "github.com/abdul-hamid-achik/file.cheap/internal/processor/image"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// responsiveSizes are the responsive variants from narrowest to widest.
+var responsiveSizes = []string{"sm", "md", "lg", "xl"}
+
+const (
+	// responsiveURLExpiry is how long signed variant URLs stay valid.
+	responsiveURLExpiry = 3600
+	// responsiveFallbackWidth caps the width of the <img> src used by
+	// browsers that ignore srcset.
+	responsiveFallbackWidth = 1024
+)
+
+type ResponsiveQuerier interface {
+	GetFile(ctx context.Context, id pgtype.UUID) (db.File, error)
+	ListVariantsByFile(ctx context.Context, fileID pgtype.UUID) ([]db.FileVariant, error)
+	GetFileMetadata(ctx context.Context, fileID pgtype.UUID) (db.FileMetadatum, error)
+	ListFileSharesByFile(ctx context.Context, fileID pgtype.UUID) ([]db.FileShare, error)
+	CreateFileShare(ctx context.Context, arg db.CreateFileShareParams) (db.FileShare, error)
+}
+
+type ResponsiveConfig struct {
+	Storage storage.Storage
+	Queries ResponsiveQuerier
+	BaseURL string
+	// AVIFEnabled adds an AVIF <source> to the manifest.
+	AVIFEnabled bool
+}
+
+// ResponsiveVariant is one width in the srcset. Ready variants already exist
+// and use a signed storage URL; on-demand ones are generated by the CDN on
+// first request.
+type ResponsiveVariant struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+	Status string `json:"status"`
+}
+
+type ResponsiveSource struct {
+	Type   string `json:"type"`
+	Srcset string `json:"srcset"`
+}
+
+type ResponsiveResponse struct {
+	FileID      string               `json:"file_id"`
+	Width       int                  `json:"width"`
+	Height      int                  `json:"height"`
+	AspectRatio float64              `json:"aspect_ratio"`
+	Srcset      string               `json:"srcset"`
+	Sizes       string               `json:"sizes"`
+	Sources     []ResponsiveSource   `json:"sources"`
+	Picture     string               `json:"picture"`
+	Placeholder *imgproc.Placeholder `json:"placeholder,omitempty"`
+	Variants    []ResponsiveVariant  `json:"variants"`
+	ShareToken  string               `json:"share_token"`
+}
+
+// ResponsiveImageHandler returns everything a frontend needs to render an
+// image responsively: a srcset over the sm-xl variants narrower than the
+// original, WebP and AVIF sources, a ready-made <picture> element and a
+// blurhash/LQIP placeholder. Dimensions and the placeholder come from the
+// metadata job, so the original is never downloaded here. Missing variants
+// point at the CDN, which generates them on first request, so the file needs
+// a public share; one is only created when the caller passes
+// create_share=true.
+func ResponsiveImageHandler(cfg *ResponsiveConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		fileID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_file_id", "Invalid file ID format", http.StatusBadRequest))
+			return
+		}
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil || file.UserID != (pgtype.UUID{Bytes: userID, Valid: true}) || file.DeletedAt.Valid {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if !strings.HasPrefix(file.ContentType, "image/") {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "not_an_image",
+				"Responsive images are only available for image files", http.StatusBadRequest))
+			return
+		}
+
+		variants, err := cfg.Queries.ListVariantsByFile(r.Context(), pgFileID)
+		if err != nil {
+			log.Error("failed to list variants", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+		byType := make(map[db.VariantType]db.FileVariant, len(variants))
+		for _, v := range variants {
+			byType[v.VariantType] = v
+		}
+
+		meta, err := cfg.Queries.GetFileMetadata(r.Context(), pgFileID)
+		hasMeta := err == nil
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Error("failed to get file metadata", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		width, height, exact, ok := responsiveDimensions(meta, hasMeta, byType)
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "metadata_pending",
+				"The image dimensions are not known yet; retry once the file's metadata job has finished", http.StatusConflict))
+			return
+		}
+
+		createShare := r.URL.Query().Get("create_share") == "true"
+		share, appErr := responsiveShare(r, cfg.Queries, userID, pgFileID, createShare)
+		if appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
+		cdnURL := func(transforms string) string {
+			return fmt.Sprintf("%s/cdn/%s/%s/%s", cfg.BaseURL, share.Token, transforms, url.PathEscape(file.Filename))
+		}
+		signedURL := func(key, transforms string) string {
+			u, err := cfg.Storage.GetPresignedURL(r.Context(), key, responsiveURLExpiry)
+			if err != nil {
+				log.Warn("failed to sign variant url, using cdn", "error", err)
+				return cdnURL(transforms)
+			}
+			return u
+		}
+
+		var entries []ResponsiveVariant
+		for _, name := range responsiveSizes {
+			target := presets.Responsive[name].Width
+			if v, ok := byType[db.VariantType(name)]; ok && !exact && v.Width != nil {
+				// Without the original's size, a variant's own width is the
+				// only one known to be right.
+				target = int(*v.Width)
+			}
+			// Only widths below the original's are listed. When its size is
+			// unknown the widest variant stands in for it and is listed too.
+			if target > width || (target == width && exact) {
+				continue
+			}
+			entry := ResponsiveVariant{
+				Name:   name,
+				Width:  target,
+				Height: int(math.Round(float64(target) * float64(height) / float64(width))),
+				Status: "on_demand",
+			}
+			transforms := fmt.Sprintf("w_%d", target)
+			if v, ok := byType[db.VariantType(name)]; ok {
+				if v.Height != nil {
+					entry.Height = int(*v.Height)
+				}
+				entry.URL = signedURL(v.StorageKey, transforms)
+				entry.Status = "ready"
+			} else {
+				entry.URL = cdnURL(transforms)
+			}
+			entries = append(entries, entry)
+		}
+		if exact {
+			entries = append(entries, ResponsiveVariant{
+				Name:   "original",
+				Width:  width,
+				Height: height,
+				URL:    signedURL(file.StorageKey, "_"),
+				Status: "ready",
+			})
+		}
+
+		sizes := r.URL.Query().Get("sizes")
+		if sizes == "" {
+			sizes = "100vw"
+		}
+
+		resp := ResponsiveResponse{
+			FileID:      fileID.String(),
+			Width:       width,
+			Height:      height,
+			AspectRatio: math.Round(float64(width)/float64(height)*10000) / 10000,
+			Srcset:      buildSrcset(entries, func(e ResponsiveVariant) string { return e.URL }),
+			Sizes:       sizes,
+			Variants:    entries,
+			ShareToken:  share.Token,
+		}
+
+		formats := []string{"webp"}
+		if cfg.AVIFEnabled {
+			formats = []string{"avif", "webp"}
+		}
+		for _, format := range formats {
+			resp.Sources = append(resp.Sources, ResponsiveSource{
+				Type: "image/" + format,
+				Srcset: buildSrcset(entries, func(e ResponsiveVariant) string {
+					if e.Name == "original" {
+						return cdnURL("f_" + format)
+					}
+					return cdnURL(fmt.Sprintf("w_%d,f_%s", e.Width, format))
+				}),
+			})
+		}
+
+		resp.Picture = pictureSnippet(resp, fallbackEntry(entries), r.URL.Query().Get("alt"))
+
+		if hasMeta && meta.Blurhash != nil && meta.Lqip != nil {
+			resp.Placeholder = &imgproc.Placeholder{Blurhash: *meta.Blurhash, LQIP: *meta.Lqip}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// responsiveDimensions returns the displayed size of the original as
+// recorded by the metadata job, falling back to the dimensions recorded on
+// the widest responsive variant. exact reports whether the size is the
+// original's own.
+func responsiveDimensions(meta db.FileMetadatum, hasMeta bool, variants map[db.VariantType]db.FileVariant) (width, height int, exact, ok bool) {
+	if hasMeta && meta.Width > 0 && meta.Height > 0 {
+		return int(meta.Width), int(meta.Height), true, true
+	}
+	for _, name := range slices.Backward(responsiveSizes) {
+		if v, ok := variants[db.VariantType(name)]; ok && v.Width != nil && v.Height != nil && *v.Height > 0 {
+			return int(*v.Width), int(*v.Height), false, true
+		}
+	}
+	return 0, 0, false, false
+}
+
+// responsiveShare finds a share whose CDN URLs work for anyone: no password,
+// download limit, expiry or transform restrictions. Without one it creates
+// one when create is set, which needs the shares:write permission.
+func responsiveShare(r *http.Request, q ResponsiveQuerier, userID uuid.UUID, fileID pgtype.UUID, create bool) (db.FileShare, *apperror.Error) {
+	shares, err := q.ListFileSharesByFile(r.Context(), fileID)
+	if err != nil {
+		return db.FileShare{}, apperror.ErrInternal
+	}
+	for _, s := range shares {
+		if (s.PasswordHash == nil || *s.PasswordHash == "") && s.MaxDownloads == nil && !s.ExpiresAt.Valid &&
+			(len(s.AllowedTransforms) == 0 || slices.Contains(s.AllowedTransforms, "*")) {
+			return s, nil
+		}
+	}
+
+	if !create {
+		return db.FileShare{}, apperror.WrapWithMessage(nil, "share_required",
+			"This file has no public share for CDN URLs; pass create_share=true to create one", http.StatusConflict)
+	}
+	if !HasPermission(r.Context(), "shares:write") {
+		return db.FileShare{}, apperror.WrapWithMessage(nil, "share_required",
+			"This file has no public share for CDN URLs and creating one requires the shares:write permission", http.StatusForbidden)
+	}
+
+	token, err := GenerateShareToken()
+	if err != nil {
+		return db.FileShare{}, apperror.ErrInternal
+	}
+	share, err := q.CreateFileShare(r.Context(), db.CreateFileShareParams{FileID: fileID, Token: token})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to create share", "error", err)
+		return db.FileShare{}, apperror.ErrInternal
+	}
+
+	audit.Record(r, audit.Entry{
+		UserID:       userID,
+		Action:       audit.ActionFileShare,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   uuid.UUID(fileID.Bytes),
+		Metadata: map[string]any{
+			"share_id": uuidFromPgtype(share.ID),
+			"source":   "responsive",
+		},
+	})
+	return share, nil
+}
+
+func buildSrcset(entries []ResponsiveVariant, urlFor func(ResponsiveVariant) string) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s %dw", urlFor(e), e.Width)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fallbackEntry picks the widest entry up to responsiveFallbackWidth.
+func fallbackEntry(entries []ResponsiveVariant) ResponsiveVariant {
+	fallback := entries[0]
+	for _, e := range entries {
+		if e.Width <= responsiveFallbackWidth {
+			fallback = e
+		}
+	}
+	return fallback
+}
+
+func pictureSnippet(resp ResponsiveResponse, fallback ResponsiveVariant, alt string) string {
+	var sb strings.Builder
+	sb.WriteString("<picture>\n")
+	for _, s := range resp.Sources {
+		fmt.Fprintf(&sb, "  <source type=\"%s\" srcset=\"%s\" sizes=\"%s\">\n",
+			s.Type, html.EscapeString(s.Srcset), html.EscapeString(resp.Sizes))
+	}
+	fmt.Fprintf(&sb, "  <img src=\"%s\" srcset=\"%s\" sizes=\"%s\" width=\"%d\" height=\"%d\" alt=\"%s\" loading=\"lazy\" decoding=\"async\">\n",
+		html.EscapeString(fallback.URL), html.EscapeString(resp.Srcset), html.EscapeString(resp.Sizes),
+		resp.Width, resp.Height, html.EscapeString(alt))
+	sb.WriteString("</picture>")
+	return sb.String()
+}
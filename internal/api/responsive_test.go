@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// setupResponsiveTest records metadata for a 1600x900 JPEG of userID and
+// returns a config that serves it. The original itself is not stored, so
+// the handler has to work from the recorded metadata alone.
+func setupResponsiveTest(t *testing.T, userID uuid.UUID) (*ResponsiveConfig, *MockQuerier, db.File) {
+	t.Helper()
+
+	queries := NewMockQuerier()
+	store := NewMockStorage()
+	file := createTestFile(userID, "photo.jpg")
+	queries.AddFile(file)
+
+	blurhash, lqip := "LEHV6nWB2yk8pyo0adR*.7kCMdnj", "data:image/jpeg;base64,/9j/"
+	if _, err := queries.UpsertFileMetadata(context.Background(), db.UpsertFileMetadataParams{
+		FileID:   file.ID,
+		Width:    1600,
+		Height:   900,
+		Format:   "jpeg",
+		Blurhash: &blurhash,
+		Lqip:     &lqip,
+	}); err != nil {
+		t.Fatalf("failed to store metadata: %v", err)
+	}
+
+	return &ResponsiveConfig{Storage: store, Queries: queries, BaseURL: "https://file.cheap"}, queries, file
+}
+
+func serveResponsive(ctx context.Context, cfg *ResponsiveConfig, fileID, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/v1/files/"+fileID+"/responsive"+query, nil)
+	req.SetPathValue("id", fileID)
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	ResponsiveImageHandler(cfg).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestResponsiveImageHandler(t *testing.T) {
+	userID := uuid.New()
+	cfg, queries, file := setupResponsiveTest(t, userID)
+	fileID := uuid.UUID(file.ID.Bytes)
+
+	md := createTestVariant(fileID, "md")
+	w, h := int32(1024), int32(576)
+	md.Width, md.Height = &w, &h
+	queries.AddVariant(md)
+	if err := cfg.Storage.Upload(context.Background(), md.StorageKey, strings.NewReader("variant"), "image/jpeg", 7); err != nil {
+		t.Fatalf("failed to store variant: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), UserIDKey, userID)
+	rec := serveResponsive(ctx, cfg, fileID.String(), "?sizes=(max-width:+800px)+100vw,+800px&alt=A+photo&create_share=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp ResponsiveResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Width != 1600 || resp.Height != 900 || resp.AspectRatio != 1.7778 {
+		t.Errorf("dimensions = %dx%d (%v), want 1600x900 (1.7778)", resp.Width, resp.Height, resp.AspectRatio)
+	}
+
+	// lg and xl are wider than the original and left out.
+	want := []struct {
+		name, status string
+		width        int
+	}{
+		{"sm", "on_demand", 640},
+		{"md", "ready", 1024},
+		{"original", "ready", 1600},
+	}
+	if len(resp.Variants) != len(want) {
+		t.Fatalf("variants = %+v, want %d", resp.Variants, len(want))
+	}
+	for i, v := range want {
+		got := resp.Variants[i]
+		if got.Name != v.name || got.Status != v.status || got.Width != v.width {
+			t.Errorf("variant %d = %+v, want %s %s %dw", i, got, v.name, v.status, v.width)
+		}
+	}
+	if got := resp.Variants[0]; got.Height != 360 || !strings.HasPrefix(got.URL, "https://file.cheap/cdn/"+resp.ShareToken+"/w_640/") {
+		t.Errorf("on-demand sm = %+v, want a 640x360 CDN URL", got)
+	}
+	if got := resp.Variants[1]; !strings.HasPrefix(got.URL, "https://storage.example.com/"+md.StorageKey) {
+		t.Errorf("ready md url = %q, want a signed storage URL", got.URL)
+	}
+
+	if !strings.Contains(resp.Srcset, " 640w, ") || !strings.HasSuffix(resp.Srcset, " 1600w") {
+		t.Errorf("srcset = %q", resp.Srcset)
+	}
+	if len(resp.Sources) != 1 || resp.Sources[0].Type != "image/webp" || !strings.Contains(resp.Sources[0].Srcset, "/w_640,f_webp/photo.jpg 640w") {
+		t.Errorf("sources = %+v, want a webp source", resp.Sources)
+	}
+	if resp.Sizes != "(max-width: 800px) 100vw, 800px" {
+		t.Errorf("sizes = %q", resp.Sizes)
+	}
+
+	for _, part := range []string{"<picture>", `<source type="image/webp"`, `width="1600" height="900"`, `alt="A photo"`, "</picture>"} {
+		if !strings.Contains(resp.Picture, part) {
+			t.Errorf("picture missing %q:\n%s", part, resp.Picture)
+		}
+	}
+
+	if resp.Placeholder == nil || resp.Placeholder.Blurhash != "LEHV6nWB2yk8pyo0adR*.7kCMdnj" || !strings.HasPrefix(resp.Placeholder.LQIP, "data:image/jpeg;base64,") {
+		t.Errorf("placeholder = %+v", resp.Placeholder)
+	}
+
+	shares, _ := queries.ListFileSharesByFile(context.Background(), file.ID)
+	if len(shares) != 1 || shares[0].Token != resp.ShareToken {
+		t.Errorf("expected a share to be created for the CDN URLs, got %d", len(shares))
+	}
+}
+
+func TestResponsiveImageHandler_ReusesPublicShare(t *testing.T) {
+	userID := uuid.New()
+	cfg, queries, file := setupResponsiveTest(t, userID)
+	cfg.AVIFEnabled = true
+
+	hash := "secret"
+	queries.AddShare(db.FileShare{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, FileID: file.ID, Token: "locked", PasswordHash: &hash})
+	queries.AddShare(db.FileShare{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, FileID: file.ID, Token: "public"})
+
+	ctx := context.WithValue(context.Background(), UserIDKey, userID)
+	rec := serveResponsive(ctx, cfg, uuidFromPgtype(file.ID), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp ResponsiveResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ShareToken != "public" {
+		t.Errorf("share token = %q, want the existing public share", resp.ShareToken)
+	}
+	if len(resp.Sources) != 2 || resp.Sources[0].Type != "image/avif" {
+		t.Errorf("sources = %+v, want avif then webp", resp.Sources)
+	}
+
+	shares, _ := queries.ListFileSharesByFile(context.Background(), file.ID)
+	if len(shares) != 2 {
+		t.Errorf("shares = %d, want no new share", len(shares))
+	}
+}
+
+func TestResponsiveImageHandler_Errors(t *testing.T) {
+	userID := uuid.New()
+	cfg, queries, file := setupResponsiveTest(t, userID)
+
+	pdf := createTestFile(userID, "doc.pdf")
+	pdf.ContentType = "application/pdf"
+	queries.AddFile(pdf)
+	pending := createTestFile(userID, "new.jpg")
+	queries.AddFile(pending)
+
+	userCtx := context.WithValue(context.Background(), UserIDKey, userID)
+	readOnlyCtx := context.WithValue(userCtx, PermissionsKey, []string{"files:read"})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		fileID     string
+		query      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid id", userCtx, "nope", "", http.StatusBadRequest, "invalid_file_id"},
+		{"other user", context.WithValue(context.Background(), UserIDKey, uuid.New()), uuidFromPgtype(file.ID), "", http.StatusNotFound, "not_found"},
+		{"not an image", userCtx, uuidFromPgtype(pdf.ID), "", http.StatusBadRequest, "not_an_image"},
+		{"no share", userCtx, uuidFromPgtype(file.ID), "", http.StatusConflict, "share_required"},
+		{"cannot create share", readOnlyCtx, uuidFromPgtype(file.ID), "?create_share=true", http.StatusForbidden, "share_required"},
+		{"no metadata", userCtx, uuidFromPgtype(pending.ID), "?create_share=true", http.StatusConflict, "metadata_pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveResponsive(tt.ctx, cfg, tt.fileID, tt.query)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("body = %s, want code %s", rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestResponsiveImageHandler_NoShareCreatedByDefault(t *testing.T) {
+	userID := uuid.New()
+	cfg, queries, file := setupResponsiveTest(t, userID)
+
+	ctx := context.WithValue(context.Background(), UserIDKey, userID)
+	if rec := serveResponsive(ctx, cfg, uuidFromPgtype(file.ID), ""); rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	if shares, _ := queries.ListFileSharesByFile(context.Background(), file.ID); len(shares) != 0 {
+		t.Errorf("shares = %d, want none without create_share", len(shares))
+	}
+}
+
+func TestResponsiveImageHandler_VariantDimensions(t *testing.T) {
+	userID := uuid.New()
+	cfg, queries, _ := setupResponsiveTest(t, userID)
+
+	// A file whose metadata job has not run yet takes its size from the
+	// widest responsive variant and has no placeholder. The original's own
+	// width is unknown, so it stays out of the srcset.
+	file := createTestFile(userID, "new.jpg")
+	queries.AddFile(file)
+	fileID := uuid.UUID(file.ID.Bytes)
+	lg := createTestVariant(fileID, "lg")
+	w, h := int32(1280), int32(960)
+	lg.Width, lg.Height = &w, &h
+	queries.AddVariant(lg)
+	queries.AddShare(db.FileShare{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, FileID: file.ID, Token: "public"})
+
+	ctx := context.WithValue(context.Background(), UserIDKey, userID)
+	rec := serveResponsive(ctx, cfg, fileID.String(), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp ResponsiveResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Width != 1280 || resp.Height != 960 || resp.Placeholder != nil {
+		t.Errorf("got %dx%d, placeholder %+v; want 1280x960 and no placeholder", resp.Width, resp.Height, resp.Placeholder)
+	}
+	last := resp.Variants[len(resp.Variants)-1]
+	if last.Name != "lg" || last.Width != 1280 || last.Status != "ready" {
+		t.Errorf("widest entry = %+v, want the ready lg variant at 1280w", last)
+	}
+	for _, v := range resp.Variants {
+		if v.Name == "original" {
+			t.Errorf("original listed as %dw although its size is unknown", v.Width)
+		}
+	}
+	if !strings.HasSuffix(resp.Srcset, " 1280w") || strings.Count(resp.Srcset, " 1280w") != 1 {
+		t.Errorf("srcset = %q, want lg as the single 1280w entry", resp.Srcset)
+	}
+}
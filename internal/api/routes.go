@@ -3,25 +3,31 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/analytics"
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/billing"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/events"
+	"github.com/abdul-hamid-achik/file.cheap/internal/filetype"
 	"github.com/abdul-hamid-achik/file.cheap/internal/health"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/abdul-hamid-achik/file.cheap/internal/streaming"
 	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
 	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
@@ -34,6 +40,7 @@ type Querier interface {
 	ListFilesByUserWithCount(ctx context.Context, arg db.ListFilesByUserWithCountParams) ([]db.ListFilesByUserWithCountRow, error)
 	CountFilesByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
 	CreateFile(ctx context.Context, arg db.CreateFileParams) (db.File, error)
+	GetFileByContentHash(ctx context.Context, arg db.GetFileByContentHashParams) (db.File, error)
 	SoftDeleteFile(ctx context.Context, id pgtype.UUID) error
 	ListVariantsByFile(ctx context.Context, fileID pgtype.UUID) ([]db.FileVariant, error)
 	GetVariant(ctx context.Context, arg db.GetVariantParams) (db.FileVariant, error)
@@ -67,6 +74,7 @@ type Querier interface {
 	ListWebhooksByUser(ctx context.Context, arg db.ListWebhooksByUserParams) ([]db.Webhook, error)
 	CountWebhooksByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
 	UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error)
+	RotateWebhookSecret(ctx context.Context, arg db.RotateWebhookSecretParams) (db.Webhook, error)
 	DeleteWebhook(ctx context.Context, arg db.DeleteWebhookParams) error
 	ListDeliveriesByWebhook(ctx context.Context, arg db.ListDeliveriesByWebhookParams) ([]db.WebhookDelivery, error)
 	CountDeliveriesByWebhook(ctx context.Context, webhookID pgtype.UUID) (int64, error)
@@ -115,6 +123,33 @@ type Querier interface {
 	CountWebhookDLQByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
 	MarkWebhookDLQRetried(ctx context.Context, id pgtype.UUID) error
 	DeleteWebhookDLQEntry(ctx context.Context, id pgtype.UUID) error
+	CountReplayableWebhookDLQ(ctx context.Context, arg db.CountReplayableWebhookDLQParams) (int64, error)
+	CreateWebhookDLQReplay(ctx context.Context, arg db.CreateWebhookDLQReplayParams) (db.WebhookDlqReplay, error)
+	GetWebhookDLQReplay(ctx context.Context, arg db.GetWebhookDLQReplayParams) (db.WebhookDlqReplay, error)
+	CompleteWebhookDLQReplay(ctx context.Context, arg db.CompleteWebhookDLQReplayParams) error
+	// Audit log
+	ListAuditLogs(ctx context.Context, arg db.ListAuditLogsParams) ([]db.AuditLog, error)
+	// Transform presets
+	CreateTransformPreset(ctx context.Context, arg db.CreateTransformPresetParams) (db.TransformPreset, error)
+	GetTransformPreset(ctx context.Context, arg db.GetTransformPresetParams) (db.TransformPreset, error)
+	GetTransformPresetByName(ctx context.Context, arg db.GetTransformPresetByNameParams) (db.TransformPreset, error)
+	ListTransformPresetsByUser(ctx context.Context, userID pgtype.UUID) ([]db.TransformPreset, error)
+	UpdateTransformPreset(ctx context.Context, arg db.UpdateTransformPresetParams) (db.TransformPreset, error)
+	DeleteTransformPreset(ctx context.Context, arg db.DeleteTransformPresetParams) error
+
+	// Upload rules
+	CreateUploadRule(ctx context.Context, arg db.CreateUploadRuleParams) (db.UploadRule, error)
+	GetUploadRule(ctx context.Context, arg db.GetUploadRuleParams) (db.UploadRule, error)
+	ListUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error)
+	ListEnabledUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error)
+	UpdateUploadRule(ctx context.Context, arg db.UpdateUploadRuleParams) (db.UploadRule, error)
+	DeleteUploadRule(ctx context.Context, arg db.DeleteUploadRuleParams) error
+
+	// Image metadata
+	GetFileMetadata(ctx context.Context, fileID pgtype.UUID) (db.FileMetadatum, error)
+	UpsertFileMetadata(ctx context.Context, arg db.UpsertFileMetadataParams) (db.FileMetadatum, error)
+	ReplaceFileContent(ctx context.Context, arg db.ReplaceFileContentParams) (db.File, error)
+	CountStorageKeyReferences(ctx context.Context, arg db.CountStorageKeyReferencesParams) (int64, error)
 }
 
 type Broker interface {
@@ -135,6 +170,13 @@ type Config struct {
 	Pool              *pgxpool.Pool
 	RedisClient       *redis.Client
 	AnalyticsService  *analytics.Service
+	// UploadSessions stores chunked upload state; nil keeps it in memory.
+	UploadSessions UploadSessionStore
+	// AVIFEnabled lets CDN f_auto requests negotiate AVIF output. Without it
+	// requests for AVIF output are rejected with format_unavailable.
+	AVIFEnabled bool
+	// Events carries worker job events to SSE clients; nil disables replay.
+	Events events.Bus
 }
 
 // withPerm wraps a handler with a permission check
@@ -166,21 +208,39 @@ func NewRouter(cfg *Config) http.Handler {
 		Broker:        cfg.Broker,
 		MaxUploadSize: cfg.MaxUploadSize,
 		ChunkSize:     5 * 1024 * 1024, // 5MB default chunk size
+		Sessions:      cfg.UploadSessions,
+
+		WebhookDispatcher: cfg.WebhookDispatcher,
 	}
 	apiMux.HandleFunc("POST /v1/upload/chunked", withPerm("files:write", InitChunkedUploadHandler(chunkedCfg)))
 	apiMux.HandleFunc("PUT /v1/upload/chunked/{uploadId}", withPerm("files:write", UploadChunkHandler(chunkedCfg)))
 	apiMux.HandleFunc("GET /v1/upload/chunked/{uploadId}", withPerm("files:read", GetUploadStatusHandler(chunkedCfg)))
 	apiMux.HandleFunc("DELETE /v1/upload/chunked/{uploadId}", withPerm("files:write", CancelUploadHandler(chunkedCfg)))
+	apiMux.HandleFunc("POST /v1/upload/presigned", withPerm("files:write", InitPresignedUploadHandler(chunkedCfg)))
+	apiMux.HandleFunc("POST /v1/upload/presigned/{uploadId}/complete", withPerm("files:write", CompletePresignedUploadHandler(chunkedCfg)))
 
 	apiMux.HandleFunc("GET /v1/files", withPerm("files:read", listFilesHandler(cfg)))
 	apiMux.HandleFunc("GET /v1/files/{id}", withPerm("files:read", getFileHandler(cfg)))
 	apiMux.HandleFunc("GET /v1/files/{id}/download", withPerm("files:read", downloadHandler(cfg)))
 	apiMux.HandleFunc("DELETE /v1/files/{id}", withPerm("files:delete", deleteHandler(cfg)))
 
+	responsiveCfg := &ResponsiveConfig{
+		Storage:     cfg.Storage,
+		Queries:     cfg.Queries,
+		BaseURL:     cfg.BaseURL,
+		AVIFEnabled: cfg.AVIFEnabled,
+	}
+	apiMux.HandleFunc("GET /v1/files/{id}/responsive", withPerm("files:read", ResponsiveImageHandler(responsiveCfg)))
+
+	metadataCfg := &MetadataConfig{Storage: cfg.Storage, Queries: cfg.Queries}
+	apiMux.HandleFunc("POST /v1/files/{id}/strip", withPerm("files:write", StripMetadataHandler(metadataCfg)))
+
 	cdnCfg := &CDNConfig{
-		Storage:  cfg.Storage,
-		Queries:  cfg.Queries,
-		Registry: cfg.Registry,
+		Storage:           cfg.Storage,
+		Queries:           cfg.Queries,
+		Registry:          cfg.Registry,
+		AVIFEnabled:       cfg.AVIFEnabled,
+		WebhookDispatcher: cfg.WebhookDispatcher,
 	}
 	apiMux.HandleFunc("POST /v1/files/{id}/share", withPerm("shares:write", CreateShareHandler(cdnCfg, cfg.BaseURL)))
 	apiMux.HandleFunc("GET /v1/files/{id}/shares", withPerm("shares:read", ListSharesHandler(cdnCfg)))
@@ -190,14 +250,20 @@ func NewRouter(cfg *Config) http.Handler {
 	apiMux.HandleFunc("POST /v1/files/{id}/video/transcode", withPerm("transform", videoTranscodeHandler(cfg)))
 	apiMux.HandleFunc("POST /v1/files/{id}/video/hls", withPerm("transform", videoHLSHandler(cfg)))
 	apiMux.HandleFunc("GET /v1/files/{id}/hls/{segment}", withPerm("files:read", hlsStreamHandler(cfg)))
+	apiMux.HandleFunc("GET /v1/files/{id}/hls/{rendition}/{segment}", withPerm("files:read", hlsStreamHandler(cfg)))
+	apiMux.HandleFunc("POST /v1/files/{id}/video/dash", withPerm("transform", videoDASHHandler(cfg)))
+	apiMux.HandleFunc("GET /v1/files/{id}/dash/{segment}", withPerm("files:read", dashStreamHandler(cfg)))
+	apiMux.HandleFunc("POST /v1/files/{id}/video/storyboard", withPerm("transform", videoStoryboardHandler(cfg)))
+	apiMux.HandleFunc("GET /v1/files/{id}/storyboard/{name}", withPerm("files:read", storyboardStreamHandler(cfg)))
+	apiMux.HandleFunc("POST /v1/files/{id}/video/preview", withPerm("transform", videoPreviewHandler(cfg)))
 
 	apiMux.HandleFunc("POST /v1/batch/transform", withPerm("transform", batchTransformHandler(cfg)))
 	apiMux.HandleFunc("GET /v1/batch/{id}", withPerm("files:read", getBatchHandler(cfg)))
 
-	sseCfg := &SSEConfig{Queries: cfg.Queries}
+	sseCfg := &SSEConfig{Queries: cfg.Queries, Events: cfg.Events}
 	apiMux.HandleFunc("GET /v1/files/{id}/status", FileStatusHandler(sseCfg))
 	apiMux.HandleFunc("GET /v1/files/{id}/events", FileStatusSSEHandler(sseCfg))
-	apiMux.HandleFunc("GET /v1/upload/progress", UploadProgressSSEHandler())
+	apiMux.HandleFunc("GET /v1/upload/progress", UploadProgressSSEHandler(cfg.UploadSessions))
 
 	deviceAuthCfg := &DeviceAuthConfig{
 		Queries: cfg.Queries,
@@ -219,6 +285,22 @@ func NewRouter(cfg *Config) http.Handler {
 	apiMux.HandleFunc("DELETE /v1/webhooks/{id}", withPerm("webhooks:write", DeleteWebhookHandler(webhookCfg)))
 	apiMux.HandleFunc("GET /v1/webhooks/{id}/deliveries", withPerm("webhooks:read", ListDeliveriesHandler(webhookCfg)))
 	apiMux.HandleFunc("POST /v1/webhooks/{id}/test", withPerm("webhooks:write", TestWebhookHandler(webhookCfg)))
+	apiMux.HandleFunc("POST /v1/webhooks/{id}/rotate-secret", withPerm("webhooks:write", RotateWebhookSecretHandler(webhookCfg)))
+
+	presetCfg := &PresetConfig{Queries: cfg.Queries}
+	apiMux.HandleFunc("GET /v1/presets", withPerm("files:read", ListPresetsHandler(presetCfg)))
+	apiMux.HandleFunc("POST /v1/presets", withPerm("transform", CreatePresetHandler(presetCfg)))
+	apiMux.HandleFunc("GET /v1/presets/{id}", withPerm("files:read", GetPresetHandler(presetCfg)))
+	apiMux.HandleFunc("PUT /v1/presets/{id}", withPerm("transform", UpdatePresetHandler(presetCfg)))
+	apiMux.HandleFunc("DELETE /v1/presets/{id}", withPerm("transform", DeletePresetHandler(presetCfg)))
+
+	uploadRuleCfg := &UploadRuleConfig{Queries: cfg.Queries}
+	apiMux.HandleFunc("GET /v1/upload-rules", withPerm("files:read", ListUploadRulesHandler(uploadRuleCfg)))
+	apiMux.HandleFunc("POST /v1/upload-rules", withPerm("transform", CreateUploadRuleHandler(uploadRuleCfg)))
+	apiMux.HandleFunc("POST /v1/upload-rules/test", withPerm("files:read", TestUploadRulesHandler(uploadRuleCfg)))
+	apiMux.HandleFunc("GET /v1/upload-rules/{id}", withPerm("files:read", GetUploadRuleHandler(uploadRuleCfg)))
+	apiMux.HandleFunc("PUT /v1/upload-rules/{id}", withPerm("transform", UpdateUploadRuleHandler(uploadRuleCfg)))
+	apiMux.HandleFunc("DELETE /v1/upload-rules/{id}", withPerm("transform", DeleteUploadRuleHandler(uploadRuleCfg)))
 
 	jobCfg := &JobConfig{Queries: cfg.Queries}
 	apiMux.HandleFunc("GET /v1/jobs", withPerm("files:read", ListJobsHandler(jobCfg)))
@@ -234,6 +316,9 @@ func NewRouter(cfg *Config) http.Handler {
 	apiMux.HandleFunc("DELETE /v1/folders/{id}", withPerm("files:delete", DeleteFolderHandler(foldersCfg)))
 	apiMux.HandleFunc("POST /v1/files/{id}/move", withPerm("files:write", MoveFileToFolderHandler(foldersCfg)))
 
+	auditCfg := &AuditConfig{Queries: cfg.Queries}
+	apiMux.HandleFunc("GET /v1/audit", withPerm("audit:read", ListAuditLogsHandler(auditCfg)))
+
 	userCfg := &UserConfig{Queries: cfg.Queries}
 	apiMux.HandleFunc("GET /v1/me", GetCurrentUserHandler(userCfg))
 	apiMux.HandleFunc("GET /v1/me/usage", GetUsageHandler(userCfg))
@@ -259,6 +344,8 @@ func NewRouter(cfg *Config) http.Handler {
 
 	// Webhook DLQ endpoints
 	apiMux.HandleFunc("GET /v1/webhooks/dlq", withPerm("webhooks:read", ListWebhookDLQHandler(webhookCfg)))
+	apiMux.HandleFunc("POST /v1/webhooks/dlq/replay", withPerm("webhooks:write", ReplayWebhookDLQHandler(webhookCfg)))
+	apiMux.HandleFunc("GET /v1/webhooks/dlq/replay/{id}", withPerm("webhooks:read", GetWebhookDLQReplayHandler(webhookCfg)))
 	apiMux.HandleFunc("POST /v1/webhooks/dlq/{id}/retry", withPerm("webhooks:write", RetryWebhookDLQHandler(webhookCfg)))
 	apiMux.HandleFunc("DELETE /v1/webhooks/dlq/{id}", withPerm("webhooks:write", DeleteWebhookDLQEntryHandler(webhookCfg)))
 
@@ -292,7 +379,20 @@ func NewRouter(cfg *Config) http.Handler {
 	handler := RateLimit(limiter)(CORS(DualAuthMiddleware(cfg.JWTSecret, cfg.Queries)(BillingMiddleware(cfg.Queries)(apiMux))))
 	mux.Handle("/v1/", handler)
 
+	// tus resumable uploads share the chunked upload sessions and storage but
+	// need their own CORS and OPTIONS handling, so they bypass the CORS above.
+	tusMux := http.NewServeMux()
+	tusMux.HandleFunc("POST /v1/tus", withPerm("files:write", TusCreateHandler(chunkedCfg)))
+	tusMux.HandleFunc("HEAD /v1/tus/{uploadId}", withPerm("files:read", TusHeadHandler(chunkedCfg)))
+	tusMux.HandleFunc("PATCH /v1/tus/{uploadId}", withPerm("files:write", TusPatchHandler(chunkedCfg)))
+	tusMux.HandleFunc("DELETE /v1/tus/{uploadId}", withPerm("files:write", TusDeleteHandler(chunkedCfg)))
+	tusHandler := RateLimit(limiter)(TusMiddleware(cfg.MaxUploadSize)(DualAuthMiddleware(cfg.JWTSecret, cfg.Queries)(BillingMiddleware(cfg.Queries)(tusMux))))
+	mux.Handle(tusBasePath, tusHandler)
+	mux.Handle(tusBasePath+"/", tusHandler)
+
 	mux.HandleFunc("GET /cdn/{token}/{transforms}/{filename}", CDNHandler(cdnCfg))
+	mux.HandleFunc("GET /cdn/{token}/hls/{segment}", ShareHLSHandler(cdnCfg))
+	mux.HandleFunc("GET /cdn/{token}/hls/{rendition}/{segment}", ShareHLSHandler(cdnCfg))
 
 	return mux
 }
@@ -362,26 +462,91 @@ func uploadHandler(cfg *Config) http.HandlerFunc {
 			return
 		}
 
+		// Verify the declared type against the file's magic bytes
+		detectedType, body, err := filetype.Sniff(file)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+		contentType, err = filetype.Resolve(contentType, detectedType)
+		if err != nil {
+			log.Warn("upload content rejected", "declared_type", header.Header.Get("Content-Type"), "detected_type", detectedType)
+			apperror.WriteJSON(w, r, contentMismatchError(err))
+			return
+		}
+
 		// Sanitize filename to prevent path traversal
 		sanitizedFilename := SanitizeFilename(header.Filename)
 
 		fileID := uuid.New()
 		storageKey := fmt.Sprintf("uploads/%s/%s/%s", userID.String(), fileID.String(), sanitizedFilename)
 
+		dedupe, err := ParseDedupeMode(r.FormValue("dedupe"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_dedupe_mode",
+				"dedupe must be one of: false, existing, link", http.StatusBadRequest))
+			return
+		}
+
+		var placement uploadPlacement
+		if cfg.Queries != nil {
+			var appErr *apperror.Error
+			placement, appErr = parseUploadPlacement(r.Context(), cfg.Queries, userID, r.FormValue("folder_id"), splitTags(r.FormValue("tags")))
+			if appErr != nil {
+				apperror.WriteJSON(w, r, appErr)
+				return
+			}
+		}
+
 		log.Info("uploading file", "filename", sanitizedFilename, "original_filename", header.Filename, "size", header.Size, "content_type", contentType)
 
+		hashingBody := storage.NewHashingReader(body)
 		uploadStart := time.Now()
-		if err := cfg.Storage.Upload(r.Context(), storageKey, file, contentType, header.Size); err != nil {
+		if err := cfg.Storage.Upload(r.Context(), storageKey, hashingBody, contentType, header.Size); err != nil {
 			metrics.RecordFileUpload("error", 0, 0)
 			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
 			return
 		}
 		metrics.RecordFileUpload("success", header.Size, time.Since(uploadStart).Seconds())
+		contentHash := hashingBody.Sum()
 
 		if cfg.Queries != nil {
 			var pgUserID pgtype.UUID
 			_ = pgUserID.Scan(userID)
 
+			var duplicateOf string
+			if dedupe != DedupeOff {
+				existing, err := cfg.Queries.GetFileByContentHash(r.Context(), db.GetFileByContentHashParams{
+					UserID:      pgUserID,
+					ContentHash: &contentHash,
+				})
+				switch {
+				case err == nil:
+					// The same bytes are already stored for this user, so the
+					// copy just written is redundant.
+					if err := cfg.Storage.Delete(r.Context(), storageKey); err != nil {
+						log.Warn("failed to delete duplicate upload", "storage_key", storageKey, "error", err)
+					}
+					duplicateOf = uuidFromPgtype(existing.ID)
+					log.Info("duplicate upload detected", "existing_file_id", duplicateOf, "mode", string(dedupe))
+
+					if dedupe == DedupeExisting {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusOK)
+						_ = json.NewEncoder(w).Encode(map[string]any{
+							"id":        duplicateOf,
+							"filename":  existing.Filename,
+							"status":    string(existing.Status),
+							"duplicate": true,
+						})
+						return
+					}
+					storageKey = existing.StorageKey
+				case !errors.Is(err, pgx.ErrNoRows):
+					log.Warn("failed to look up file by content hash", "error", err)
+				}
+			}
+
 			dbFile, err := cfg.Queries.CreateFile(r.Context(), db.CreateFileParams{
 				UserID:      pgUserID,
 				Filename:    sanitizedFilename,
@@ -389,6 +554,7 @@ func uploadHandler(cfg *Config) http.HandlerFunc {
 				SizeBytes:   header.Size,
 				StorageKey:  storageKey,
 				Status:      db.FileStatusPending,
+				ContentHash: &contentHash,
 			})
 			if err != nil {
 				apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
@@ -398,62 +564,35 @@ func uploadHandler(cfg *Config) http.HandlerFunc {
 			fileIDStr := uuidFromPgtype(dbFile.ID)
 			log.Info("file created", "file_id", fileIDStr)
 
+			recordUpload(r, userID, fileIDStr, dbFile.Filename, dbFile.ContentType, dbFile.SizeBytes, "multipart")
+			placement.apply(r.Context(), cfg.Queries, userID, dbFile.ID, log)
+
 			if cfg.Broker != nil {
 				var fileUUID uuid.UUID
 				copy(fileUUID[:], dbFile.ID.Bytes[:])
-
-				switch {
-				case strings.HasPrefix(contentType, "image/"):
-					payload := worker.NewThumbnailPayload(fileUUID)
-					jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeThumbnail)
-					if err != nil {
-						log.Error("failed to enqueue thumbnail job", "error", err)
-					} else {
-						metrics.RecordJobEnqueued("thumbnail")
-						log.Info("thumbnail job enqueued", "job_id", jobID)
-					}
-				case contentType == "application/pdf":
-					payload := worker.NewPDFThumbnailPayload(fileUUID)
-					jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypePdfThumbnail)
-					if err != nil {
-						log.Error("failed to enqueue pdf_thumbnail job", "error", err)
-					} else {
-						metrics.RecordJobEnqueued("pdf_thumbnail")
-						log.Info("pdf_thumbnail job enqueued", "job_id", jobID)
-					}
-				case video.IsVideoType(contentType):
-					payload := worker.NewVideoThumbnailPayload(fileUUID)
-					jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeVideoThumbnail)
-					if err != nil {
-						log.Error("failed to enqueue video_thumbnail job", "error", err)
-					} else {
-						metrics.RecordJobEnqueued("video_thumbnail")
-						log.Info("video_thumbnail job enqueued", "job_id", jobID)
-					}
-				default:
-					log.Debug("no automatic processing for content type", "content_type", contentType)
-				}
+				enqueueProcessingJobs(r.Context(), cfg.Queries, cfg.Broker, userID, placement.ruleFile(fileUUID, dbFile.Filename, contentType), log)
 			}
 
 			// Dispatch file.uploaded webhook event
 			if cfg.WebhookDispatcher != nil {
 				event, err := webhook.NewFileUploadedEvent(fileIDStr, dbFile.Filename, dbFile.ContentType, dbFile.SizeBytes)
 				if err == nil {
-					go func() {
-						if err := cfg.WebhookDispatcher.Dispatch(context.Background(), userID, event); err != nil {
-							log.Debug("webhook dispatch failed", "error", err)
-						}
-					}()
+					dispatchWebhook(r.Context(), cfg.WebhookDispatcher, userID, event)
 				}
 			}
 
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusAccepted)
-			_ = json.NewEncoder(w).Encode(map[string]any{
+			response := map[string]any{
 				"id":       fileIDStr,
 				"filename": dbFile.Filename,
 				"status":   string(dbFile.Status),
-			})
+			}
+			if duplicateOf != "" {
+				response["duplicate_of"] = duplicateOf
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(response)
 			return
 		}
 
@@ -467,6 +606,24 @@ func uploadHandler(cfg *Config) http.HandlerFunc {
 	}
 }
 
+// recordUpload writes the file.upload audit entry for a completed upload.
+// method names the upload protocol so the entries can be told apart.
+func recordUpload(r *http.Request, userID uuid.UUID, fileID, filename, contentType string, size int64, method string) {
+	id, _ := uuid.Parse(fileID)
+	audit.Record(r, audit.Entry{
+		UserID:       userID,
+		Action:       audit.ActionFileUpload,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   id,
+		Metadata: map[string]any{
+			"filename":     filename,
+			"content_type": contentType,
+			"size_bytes":   size,
+			"method":       method,
+		},
+	})
+}
+
 func listFilesHandler(cfg *Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := GetUserID(r.Context())
@@ -662,6 +819,10 @@ func getFileHandler(cfg *Config) http.HandlerFunc {
 			response["variants"] = variantsList
 		}
 
+		if meta, err := cfg.Queries.GetFileMetadata(r.Context(), pgFileID); err == nil {
+			response["metadata"] = fileMetadataToResponse(meta)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(response)
 	}
@@ -702,6 +863,9 @@ func downloadHandler(cfg *Config) http.HandlerFunc {
 		}
 
 		storageKey := file.StorageKey
+		contentType := file.ContentType
+		size := file.SizeBytes
+		modified := file.UpdatedAt.Time
 		variantType := r.URL.Query().Get("variant")
 		if variantType != "" {
 			// Direct lookup instead of fetching all variants
@@ -714,6 +878,31 @@ func downloadHandler(cfg *Config) http.HandlerFunc {
 				return
 			}
 			storageKey = variant.StorageKey
+			contentType = variant.ContentType
+			size = variant.SizeBytes
+			modified = variant.CreatedAt.Time
+		}
+
+		// Only the first range of a download is audited so resumed and
+		// seeking clients don't produce an entry per chunk.
+		if isFirstRange(r) {
+			metadata := map[string]any{"filename": file.Filename}
+			if variantType != "" {
+				metadata["variant"] = variantType
+			}
+			audit.Record(r, audit.Entry{
+				UserID:       userID,
+				Action:       audit.ActionFileDownload,
+				ResourceType: audit.ResourceFile,
+				ResourceID:   fileID,
+				Metadata:     metadata,
+			})
+		}
+
+		// Ranged requests are served directly so players and resuming
+		// clients get a 206 without depending on the storage backend.
+		if serveRangeRequest(w, r, cfg.Storage, storageKey, contentType, size, objectETag(storageKey, size, modified)) {
+			return
 		}
 
 		url, err := cfg.Storage.GetPresignedURL(r.Context(), storageKey, 3600)
@@ -722,6 +911,7 @@ func downloadHandler(cfg *Config) http.HandlerFunc {
 			return
 		}
 
+		w.Header().Set("Accept-Ranges", "bytes")
 		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 	}
 }
@@ -780,6 +970,19 @@ func deleteHandler(cfg *Config) http.HandlerFunc {
 
 		metrics.RecordFileDeletion("success")
 		log.Info("file deleted")
+		audit.Record(r, audit.Entry{
+			UserID:       userID,
+			Action:       audit.ActionFileDelete,
+			ResourceType: audit.ResourceFile,
+			ResourceID:   fileID,
+			Metadata:     map[string]any{"filename": file.Filename},
+		})
+		if cfg.WebhookDispatcher != nil {
+			event, err := webhook.NewFileDeletedEvent(fileIDStr, file.Filename)
+			if err == nil {
+				dispatchWebhook(r.Context(), cfg.WebhookDispatcher, userID, event)
+			}
+		}
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -839,11 +1042,36 @@ func uuidFromPgtype(id pgtype.UUID) string {
 	return u.String()
 }
 
+// avifRequested reports whether any preset or pipeline produces AVIF, which
+// needs avifenc on the worker hosts.
+func avifRequested(presets []string, pipelines []*TransformPipeline) bool {
+	if slices.Contains(presets, "avif") {
+		return true
+	}
+	for _, pipeline := range pipelines {
+		if pipeline.HasFormat("avif") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAVIFUnavailable rejects a request for AVIF output when avifenc is
+// not installed, rather than queueing jobs that are bound to fail.
+func writeAVIFUnavailable(w http.ResponseWriter, r *http.Request) {
+	apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "format_unavailable",
+		"AVIF output is not available on this server", http.StatusBadRequest))
+}
+
 type TransformRequest struct {
 	Presets   []string `json:"presets"`
 	WebP      bool     `json:"webp"`
 	Quality   int      `json:"quality"`
 	Watermark string   `json:"watermark"`
+	// Pipelines are transform strings in the CDN grammar, such as
+	// "w_800,wm_foo,f_webp". Each runs as one job whose result warms the CDN
+	// transform cache.
+	Pipelines []string `json:"pipelines"`
 }
 
 type TransformResponse struct {
@@ -901,12 +1129,48 @@ func transformHandler(cfg *Config) http.HandlerFunc {
 			return
 		}
 
-		if len(req.Presets) == 0 && !req.WebP && req.Watermark == "" {
+		// The user's own presets run as pipelines.
+		var presetPipelines []string
+		req.Presets, presetPipelines = resolveUserPresets(r.Context(), cfg.Queries, pgUserID, req.Presets)
+		req.Pipelines = append(req.Pipelines, presetPipelines...)
+
+		if len(req.Presets) == 0 && !req.WebP && req.Watermark == "" && len(req.Pipelines) == 0 {
 			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "no_transformations", "At least one transformation is required", http.StatusBadRequest))
 			return
 		}
 
-		jobCount := len(req.Presets)
+		pipelines := make([]*TransformPipeline, len(req.Pipelines))
+		pipelineWatermark := false
+		for i, transforms := range req.Pipelines {
+			pipeline, err := ParsePipeline(transforms)
+			if err == nil {
+				err = ValidatePipeline(pipeline)
+			}
+			if err == nil && !pipeline.RequiresProcessing() {
+				err = errors.New("pipeline has no steps")
+			}
+			if err == nil && pipeline.HasAutoFormat() {
+				err = errors.New("f_auto is only supported on CDN requests")
+			}
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_pipeline",
+					fmt.Sprintf("Invalid pipeline %q: %v", transforms, err), http.StatusBadRequest))
+				return
+			}
+			for _, step := range pipeline.Steps {
+				if step.Processor == "watermark" {
+					pipelineWatermark = true
+				}
+			}
+			pipelines[i] = pipeline
+		}
+
+		if !cfg.AVIFEnabled && avifRequested(req.Presets, pipelines) {
+			writeAVIFUnavailable(w, r)
+			return
+		}
+
+		jobCount := len(req.Presets) + len(pipelines)
 		if req.WebP {
 			jobCount++
 		}
@@ -936,7 +1200,7 @@ func transformHandler(cfg *Config) http.HandlerFunc {
 				}
 			}
 
-			if req.Watermark != "" && !billing.CanUseFeature(billingInfo.Tier, "watermark") {
+			if (req.Watermark != "" || pipelineWatermark) && !billing.CanUseFeature(billingInfo.Tier, "watermark") {
 				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "feature_not_available",
 					"Custom watermarks are not available on your plan. Upgrade to Pro for access.",
 					http.StatusForbidden))
@@ -972,6 +1236,10 @@ func transformHandler(cfg *Config) http.HandlerFunc {
 				p := worker.NewSocialPayload(fileID, preset)
 				payload = &p
 				dbJobType = db.JobTypeResize
+			case "avif":
+				p := worker.NewConvertPayload(fileID, "avif", quality)
+				payload = &p
+				dbJobType = db.JobTypeConvert
 			default:
 				log.Warn("unknown preset requested", "preset", preset)
 				continue
@@ -1019,12 +1287,27 @@ func transformHandler(cfg *Config) http.HandlerFunc {
 			}
 		}
 
+		for i, pipeline := range pipelines {
+			payload := worker.NewTransformPayload(fileID, req.Pipelines[i], pipeline.CacheKey(), pipeline.ProcessorSteps(file.ContentType))
+			jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeTransform)
+			if err != nil {
+				log.Error("failed to enqueue transform job", "pipeline", req.Pipelines[i], "error", err)
+				continue
+			}
+			metrics.RecordJobEnqueued(string(db.JobTypeTransform))
+			jobIDs = append(jobIDs, jobID)
+			if err := cfg.Queries.IncrementTransformationCount(r.Context(), pgUserID); err != nil {
+				log.Error("failed to increment transformation count", "error", err)
+			}
+		}
+
 		if len(jobIDs) == 0 {
 			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "no_jobs_created", "Failed to create any transformation jobs", http.StatusInternalServerError))
 			return
 		}
 
 		log.Info("transform jobs created", "job_count", len(jobIDs))
+		checkTransformQuota(r.Context(), cfg, userID, len(jobIDs))
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted)
@@ -1088,12 +1371,41 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 			return
 		}
 
-		if len(req.Presets) == 0 && !req.WebP && req.Watermark == "" {
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+
+		// The user's own presets run as pipelines on every file.
+		requestedPresets := req.Presets
+		var presetTransforms []string
+		req.Presets, presetTransforms = resolveUserPresets(r.Context(), cfg.Queries, pgUserID, req.Presets)
+
+		if len(req.Presets) == 0 && len(presetTransforms) == 0 && !req.WebP && req.Watermark == "" {
 			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "no_transformations", "At least one transformation is required", http.StatusBadRequest))
 			return
 		}
 
-		jobsPerFile := len(req.Presets)
+		presetPipelines := make([]*TransformPipeline, len(presetTransforms))
+		presetWatermark := false
+		for i, transforms := range presetTransforms {
+			pipeline, err := ParsePipeline(transforms)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_preset",
+					fmt.Sprintf("Invalid preset %q: %v", transforms, err), http.StatusBadRequest))
+				return
+			}
+			for _, step := range pipeline.Steps {
+				if step.Processor == "watermark" {
+					presetWatermark = true
+				}
+			}
+			presetPipelines[i] = pipeline
+		}
+
+		if !cfg.AVIFEnabled && avifRequested(req.Presets, presetPipelines) {
+			writeAVIFUnavailable(w, r)
+			return
+		}
+
+		jobsPerFile := len(req.Presets) + len(presetPipelines)
 		if req.WebP {
 			jobsPerFile++
 		}
@@ -1102,7 +1414,6 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 		}
 		totalJobs := jobsPerFile * len(req.FileIDs)
 
-		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
 		billingInfo := GetBilling(r.Context())
 
 		if billingInfo != nil {
@@ -1126,7 +1437,7 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 				}
 			}
 
-			if req.Watermark != "" && !billing.CanUseFeature(billingInfo.Tier, "watermark") {
+			if (req.Watermark != "" || presetWatermark) && !billing.CanUseFeature(billingInfo.Tier, "watermark") {
 				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "feature_not_available",
 					"Custom watermarks are not available on your plan. Upgrade to Pro for access.",
 					http.StatusForbidden))
@@ -1155,12 +1466,14 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 
 		// Validate ownership and collect valid file IDs
 		var validFileIDs []uuid.UUID
+		contentTypes := make(map[uuid.UUID]string, len(files))
 		for _, file := range files {
 			if uuidFromPgtype(file.UserID) != userID.String() {
 				continue
 			}
 			fileID, _ := uuid.FromBytes(file.ID.Bytes[:])
 			validFileIDs = append(validFileIDs, fileID)
+			contentTypes[fileID] = file.ContentType
 		}
 
 		if len(validFileIDs) == 0 {
@@ -1181,7 +1494,7 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 		batch, err := cfg.Queries.CreateBatchOperation(r.Context(), db.CreateBatchOperationParams{
 			UserID:     pgUserID,
 			TotalFiles: int32(len(validFileIDs)),
-			Presets:    req.Presets,
+			Presets:    requestedPresets,
 			Webp:       req.WebP,
 			Quality:    int32(quality),
 			Watermark:  watermark,
@@ -1218,6 +1531,10 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 					p := worker.NewSocialPayload(fileID, preset)
 					payload = &p
 					dbJobType = db.JobTypeResize
+				case "avif":
+					p := worker.NewConvertPayload(fileID, "avif", quality)
+					payload = &p
+					dbJobType = db.JobTypeConvert
 				default:
 					continue
 				}
@@ -1263,6 +1580,20 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 				}
 			}
 
+			for i, pipeline := range presetPipelines {
+				payload := worker.NewTransformPayload(fileID, presetTransforms[i], pipeline.CacheKey(), pipeline.ProcessorSteps(contentTypes[fileID]))
+				jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeTransform)
+				if err != nil {
+					log.Error("failed to enqueue transform job", "file_id", fileID, "pipeline", presetTransforms[i], "error", err)
+					continue
+				}
+				metrics.RecordJobEnqueued(string(db.JobTypeTransform))
+				jobIDs = append(jobIDs, jobID)
+				if err := cfg.Queries.IncrementTransformationCount(r.Context(), pgUserID); err != nil {
+					log.Error("failed to increment transformation count", "error", err)
+				}
+			}
+
 			if len(jobIDs) > 0 {
 				pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
 				_, err := cfg.Queries.CreateBatchItem(r.Context(), db.CreateBatchItemParams{
@@ -1278,6 +1609,7 @@ func batchTransformHandler(cfg *Config) http.HandlerFunc {
 		}
 
 		log.Info("batch transform created", "total_files", len(validFileIDs), "total_jobs", totalJobsCreated)
+		checkTransformQuota(r.Context(), cfg, userID, totalJobsCreated)
 
 		statusURL := fmt.Sprintf("/v1/batch/%s", batchIDStr)
 		if cfg.BaseURL != "" {
@@ -1623,6 +1955,7 @@ func videoHLSHandler(cfg *Config) http.HandlerFunc {
 			return
 		}
 
+		maxResolution := 0
 		billingInfo := GetBilling(r.Context())
 		if billingInfo != nil {
 			limits := billing.GetTierLimits(billingInfo.Tier)
@@ -1632,6 +1965,7 @@ func videoHLSHandler(cfg *Config) http.HandlerFunc {
 					http.StatusForbidden))
 				return
 			}
+			maxResolution = limits.MaxVideoResolution
 		}
 
 		var req VideoHLSRequest
@@ -1644,6 +1978,15 @@ func videoHLSHandler(cfg *Config) http.HandlerFunc {
 		if len(req.Resolutions) == 0 {
 			req.Resolutions = []int{360, 720}
 		}
+		// Each resolution becomes its own rendition; drop those that are not
+		// on the ladder or exceed the plan's maximum.
+		req.Resolutions = video.SelectHLSRenditions(req.Resolutions, 0, maxResolution)
+		if len(req.Resolutions) == 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_resolutions",
+				"Resolutions must be 360, 480, 720 or 1080 and within your plan's maximum video resolution.",
+				http.StatusBadRequest))
+			return
+		}
 
 		if cfg.Broker == nil {
 			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "service_unavailable", "Job queue is not available", http.StatusServiceUnavailable))
@@ -1652,6 +1995,7 @@ func videoHLSHandler(cfg *Config) http.HandlerFunc {
 
 		payload := worker.NewVideoHLSPayload(fileID, req.Resolutions)
 		payload.SegmentDuration = req.SegmentDuration
+		payload.MaxResolution = maxResolution
 		jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeVideoHls)
 		if err != nil {
 			log.Error("failed to enqueue video HLS job", "error", err)
@@ -1675,6 +2019,397 @@ func videoHLSHandler(cfg *Config) http.HandlerFunc {
 	}
 }
 
+type VideoDASHRequest struct {
+	SegmentDuration int   `json:"segment_duration"`
+	Resolutions     []int `json:"resolutions"`
+}
+
+func videoDASHHandler(cfg *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		fileIDStr := r.PathValue("id")
+		fileID, err := uuid.Parse(fileIDStr)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_file_id", "Invalid file ID format", http.StatusBadRequest))
+			return
+		}
+
+		log = log.With("user_id", userID.String(), "file_id", fileIDStr)
+
+		if cfg.Queries == nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if uuidFromPgtype(file.UserID) != userID.String() || file.DeletedAt.Valid {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if !video.IsVideoType(file.ContentType) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "not_a_video",
+				"This file is not a video.", http.StatusBadRequest))
+			return
+		}
+
+		maxResolution := 0
+		billingInfo := GetBilling(r.Context())
+		if billingInfo != nil {
+			limits := billing.GetTierLimits(billingInfo.Tier)
+			if !limits.AdaptiveBitrate {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "feature_not_available",
+					"DASH streaming requires Pro tier. Upgrade to enable adaptive bitrate streaming.",
+					http.StatusForbidden))
+				return
+			}
+			maxResolution = limits.MaxVideoResolution
+		}
+
+		var req VideoDASHRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			req = VideoDASHRequest{}
+		}
+		if req.SegmentDuration <= 0 {
+			req.SegmentDuration = 10
+		}
+		if len(req.Resolutions) == 0 {
+			req.Resolutions = []int{360, 720}
+		}
+		// DASH representations follow the HLS ladder and plan limits.
+		req.Resolutions = video.SelectHLSRenditions(req.Resolutions, 0, maxResolution)
+		if len(req.Resolutions) == 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_resolutions",
+				"Resolutions must be 360, 480, 720 or 1080 and within your plan's maximum video resolution.",
+				http.StatusBadRequest))
+			return
+		}
+
+		if cfg.Broker == nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "service_unavailable", "Job queue is not available", http.StatusServiceUnavailable))
+			return
+		}
+
+		payload := worker.NewVideoDASHPayload(fileID, req.Resolutions)
+		payload.SegmentDuration = req.SegmentDuration
+		payload.MaxResolution = maxResolution
+		jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeVideoDash)
+		if err != nil {
+			log.Error("failed to enqueue video DASH job", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+		metrics.RecordJobEnqueued("video_dash")
+
+		if err := cfg.Queries.IncrementTransformationCount(r.Context(), pgUserID); err != nil {
+			log.Error("failed to increment transformation count", "error", err)
+		}
+
+		log.Info("video DASH job created", "job_id", jobID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(VideoTranscodeResponse{
+			FileID: fileIDStr,
+			Jobs:   []string{jobID},
+		})
+	}
+}
+
+type VideoStoryboardRequest struct {
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+}
+
+func videoStoryboardHandler(cfg *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		fileIDStr := r.PathValue("id")
+		fileID, err := uuid.Parse(fileIDStr)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_file_id", "Invalid file ID format", http.StatusBadRequest))
+			return
+		}
+
+		log = log.With("user_id", userID.String(), "file_id", fileIDStr)
+
+		if cfg.Queries == nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if uuidFromPgtype(file.UserID) != userID.String() || file.DeletedAt.Valid {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if !video.IsVideoType(file.ContentType) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "not_a_video",
+				"This file is not a video.", http.StatusBadRequest))
+			return
+		}
+
+		billingInfo := GetBilling(r.Context())
+		if billingInfo != nil {
+			if !billing.CanUseFeature(billingInfo.Tier, "video_storyboard") {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "feature_not_available",
+					"Storyboards require Pro tier. Upgrade to enable video storyboards.",
+					http.StatusForbidden))
+				return
+			}
+
+			usage, err := cfg.Queries.GetUserTransformationUsage(r.Context(), pgUserID)
+			if err == nil {
+				remaining := int(usage.TransformationsLimit) - int(usage.TransformationsCount)
+				if usage.TransformationsLimit != -1 && remaining < 1 {
+					apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "transformation_limit_reached",
+						fmt.Sprintf("Not enough transformations remaining. Need 1, have %d.", remaining),
+						http.StatusForbidden))
+					return
+				}
+			}
+		}
+
+		var req VideoStoryboardRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			req = VideoStoryboardRequest{}
+		}
+
+		payload := worker.NewVideoStoryboardPayload(fileID)
+		if req.Columns != 0 {
+			payload.Columns = req.Columns
+		}
+		if req.Rows != 0 {
+			payload.Rows = req.Rows
+		}
+		if req.Width != 0 {
+			payload.Width = req.Width
+		}
+		if req.Height != 0 {
+			payload.Height = req.Height
+		}
+		if payload.Columns < 1 || payload.Columns > 20 || payload.Rows < 1 || payload.Rows > 20 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_grid",
+				"Columns and rows must be between 1 and 20.", http.StatusBadRequest))
+			return
+		}
+		if payload.Width < 32 || payload.Width > 480 || payload.Height < 18 || payload.Height > 480 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_dimensions",
+				"Thumbnail width must be between 32 and 480 and height between 18 and 480.", http.StatusBadRequest))
+			return
+		}
+
+		if cfg.Broker == nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "service_unavailable", "Job queue is not available", http.StatusServiceUnavailable))
+			return
+		}
+
+		jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeVideoStoryboard)
+		if err != nil {
+			log.Error("failed to enqueue video storyboard job", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+		metrics.RecordJobEnqueued("video_storyboard")
+
+		if err := cfg.Queries.IncrementTransformationCount(r.Context(), pgUserID); err != nil {
+			log.Error("failed to increment transformation count", "error", err)
+		}
+
+		log.Info("video storyboard job created", "job_id", jobID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(VideoTranscodeResponse{
+			FileID: fileIDStr,
+			Jobs:   []string{jobID},
+		})
+	}
+}
+
+type VideoPreviewRequest struct {
+	Formats         []string `json:"formats"`
+	Width           int      `json:"width"`
+	Segments        int      `json:"segments"`
+	SegmentDuration float64  `json:"segment_duration"`
+	Start           float64  `json:"start"`
+	End             float64  `json:"end"`
+}
+
+func videoPreviewHandler(cfg *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		fileIDStr := r.PathValue("id")
+		fileID, err := uuid.Parse(fileIDStr)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_file_id", "Invalid file ID format", http.StatusBadRequest))
+			return
+		}
+
+		log = log.With("user_id", userID.String(), "file_id", fileIDStr)
+
+		if cfg.Queries == nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if uuidFromPgtype(file.UserID) != userID.String() || file.DeletedAt.Valid {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		if !video.IsVideoType(file.ContentType) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "not_a_video",
+				"This file is not a video.", http.StatusBadRequest))
+			return
+		}
+
+		var req VideoPreviewRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			req = VideoPreviewRequest{}
+		}
+
+		// The plan's resolution caps the preview height in the worker, so
+		// portrait videos shrink to fit instead of being refused.
+		maxResolution := 0
+		billingInfo := GetBilling(r.Context())
+		if billingInfo != nil {
+			maxResolution = billing.GetTierLimits(billingInfo.Tier).MaxVideoResolution
+
+			usage, err := cfg.Queries.GetUserTransformationUsage(r.Context(), pgUserID)
+			if err == nil {
+				remaining := int(usage.TransformationsLimit) - int(usage.TransformationsCount)
+				if usage.TransformationsLimit != -1 && remaining < 1 {
+					apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "transformation_limit_reached",
+						fmt.Sprintf("Not enough transformations remaining. Need 1, have %d.", remaining),
+						http.StatusForbidden))
+					return
+				}
+			}
+		}
+
+		payload := worker.NewVideoPreviewPayload(fileID, maxResolution)
+		if len(req.Formats) > 0 {
+			payload.Formats = payload.Formats[:0]
+			for _, format := range req.Formats {
+				if !video.IsPreviewFormat(format) {
+					apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_format",
+						"Invalid format. Supported formats: mp4, webp, gif", http.StatusBadRequest))
+					return
+				}
+				if !slices.Contains(payload.Formats, format) {
+					payload.Formats = append(payload.Formats, format)
+				}
+			}
+		}
+		if req.Width != 0 {
+			payload.Width = req.Width
+		}
+		if req.Segments != 0 {
+			payload.Segments = req.Segments
+		}
+		if req.SegmentDuration != 0 {
+			payload.SegmentDuration = req.SegmentDuration
+		}
+		payload.Start = req.Start
+		payload.End = req.End
+
+		if payload.Width < 64 || payload.Width > 1280 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_dimensions",
+				"Preview width must be between 64 and 1280.", http.StatusBadRequest))
+			return
+		}
+		if payload.Segments < 1 || payload.Segments > 10 || payload.SegmentDuration < 0.5 || payload.SegmentDuration > 10 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_segments",
+				"Segments must be between 1 and 10 and segment_duration between 0.5 and 10 seconds.", http.StatusBadRequest))
+			return
+		}
+		if payload.Start < 0 || payload.End < 0 || (payload.End > 0 && payload.End <= payload.Start) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_range",
+				"Start must not be negative and end, when set, must be after start.", http.StatusBadRequest))
+			return
+		}
+
+		if cfg.Broker == nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "service_unavailable", "Job queue is not available", http.StatusServiceUnavailable))
+			return
+		}
+
+		jobID, err := worker.EnqueueWithTracking(r.Context(), cfg.Queries, cfg.Broker, &payload, db.JobTypeVideoPreview)
+		if err != nil {
+			log.Error("failed to enqueue video preview job", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+		metrics.RecordJobEnqueued("video_preview")
+
+		if err := cfg.Queries.IncrementTransformationCount(r.Context(), pgUserID); err != nil {
+			log.Error("failed to increment transformation count", "error", err)
+		}
+
+		log.Info("video preview job created", "job_id", jobID, "formats", payload.Formats)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(VideoTranscodeResponse{
+			FileID: fileIDStr,
+			Jobs:   []string{jobID},
+		})
+	}
+}
+
+// hlsStreamHandler serves the master playlist, rendition playlists and
+// segments of a file's HLS package, rewriting playlist URIs so players keep
+// fetching through this authenticated endpoint.
 func hlsStreamHandler(cfg *Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := GetUserID(r.Context())
@@ -1709,19 +2444,94 @@ func hlsStreamHandler(cfg *Config) http.HandlerFunc {
 			return
 		}
 
-		if uuidFromPgtype(file.UserID) != userID.String() {
+		if uuidFromPgtype(file.UserID) != userID.String() || file.DeletedAt.Valid {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
 
-		storageKey := fmt.Sprintf("variants/%s/hls_master/%s", fileIDStr, segment)
+		base := "/v1/files/" + fileIDStr + "/hls"
+		streaming.ServeHLS(w, r, cfg.Queries, cfg.Storage, pgFileID, r.PathValue("rendition"), segment, base)
+	}
+}
 
-		url, err := cfg.Storage.GetPresignedURL(r.Context(), storageKey, 3600)
+// dashStreamHandler serves the MPD and segments of a file's DASH package,
+// rewriting segment URLs in the manifest so players keep fetching through
+// this authenticated endpoint.
+func dashStreamHandler(cfg *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		fileIDStr := r.PathValue("id")
+		fileID, err := uuid.Parse(fileIDStr)
 		if err != nil {
+			http.Error(w, "invalid file ID", http.StatusBadRequest)
+			return
+		}
+
+		if cfg.Queries == nil {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
 
-		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		if uuidFromPgtype(file.UserID) != userID.String() || file.DeletedAt.Valid {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		base := "/v1/files/" + fileIDStr + "/dash"
+		streaming.ServeDASH(w, r, cfg.Queries, cfg.Storage, pgFileID, r.PathValue("segment"), base)
+	}
+}
+
+// storyboardStreamHandler serves a video's WebVTT thumbnail track and sprite
+// sheet, rewriting cue image URLs so players load the sheet through this
+// authenticated endpoint.
+func storyboardStreamHandler(cfg *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		fileIDStr := r.PathValue("id")
+		fileID, err := uuid.Parse(fileIDStr)
+		if err != nil {
+			http.Error(w, "invalid file ID", http.StatusBadRequest)
+			return
+		}
+
+		if cfg.Queries == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+
+		file, err := cfg.Queries.GetFile(r.Context(), pgFileID)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		if uuidFromPgtype(file.UserID) != userID.String() || file.DeletedAt.Valid {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		base := "/v1/files/" + fileIDStr + "/storyboard"
+		streaming.ServeStoryboard(w, r, cfg.Queries, cfg.Storage, pgFileID, r.PathValue("name"), base)
 	}
 }
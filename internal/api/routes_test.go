@@ -94,7 +94,7 @@ func TestUploadHandler(t *testing.T) {
 		{
 			name: "successful upload - PNG image",
 			setupRequest: func(t *testing.T) *http.Request {
-				body, contentType := createMultipartFormWithData(t, "file", "test.png", []byte("PNG data"), "image/png")
+				body, contentType := createMultipartFormWithData(t, "file", "test.png", []byte("\x89PNG\r\n\x1a\nPNG data"), "image/png")
 				req := httptest.NewRequest("POST", "/v1/upload", body)
 				req.Header.Set("Content-Type", contentType)
 				req.Header.Set("Authorization", "Bearer "+generateTestToken(t, testUserID, 1*time.Hour))
@@ -103,6 +103,28 @@ func TestUploadHandler(t *testing.T) {
 			wantStatus:   http.StatusAccepted,
 			wantBodyKeys: []string{"id", "filename", "status"},
 		},
+		{
+			name: "content mismatch - HTML labelled as PNG",
+			setupRequest: func(t *testing.T) *http.Request {
+				body, contentType := createMultipartFormWithData(t, "file", "test.png", []byte("<html><script>alert(1)</script></html>"), "image/png")
+				req := httptest.NewRequest("POST", "/v1/upload", body)
+				req.Header.Set("Content-Type", contentType)
+				req.Header.Set("Authorization", "Bearer "+generateTestToken(t, testUserID, 1*time.Hour))
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "content mismatch - PDF labelled as JPEG",
+			setupRequest: func(t *testing.T) *http.Request {
+				body, contentType := createMultipartFormWithData(t, "file", "test.jpg", []byte("%PDF-1.7\n"), "image/jpeg")
+				req := httptest.NewRequest("POST", "/v1/upload", body)
+				req.Header.Set("Content-Type", contentType)
+				req.Header.Set("Authorization", "Bearer "+generateTestToken(t, testUserID, 1*time.Hour))
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "missing file field",
 			setupRequest: func(t *testing.T) *http.Request {
@@ -599,6 +621,75 @@ func TestDownloadHandler(t *testing.T) {
 	}
 }
 
+// TestDownloadHandler_Range tests partial content responses for ranged downloads.
+func TestDownloadHandler_Range(t *testing.T) {
+	testUserID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	fileID := uuid.MustParse("770e8400-e29b-41d4-a716-446655440000")
+	content := "0123456789abcdefghij"
+
+	file := createTestFileWithID(fileID, testUserID, "video.mp4")
+	file.ContentType = "video/mp4"
+	file.SizeBytes = int64(len(content))
+	etag := objectETag(file.StorageKey, file.SizeBytes, file.UpdatedAt.Time)
+
+	tests := []struct {
+		name             string
+		rangeHeader      string
+		ifRange          string
+		wantStatus       int
+		wantBody         string
+		wantContentRange string
+	}{
+		{"partial range", "bytes=5-9", "", http.StatusPartialContent, "56789", "bytes 5-9/20"},
+		{"suffix range", "bytes=-3", "", http.StatusPartialContent, "hij", "bytes 17-19/20"},
+		{"matching if-range", "bytes=10-", etag, http.StatusPartialContent, "abcdefghij", "bytes 10-19/20"},
+		{"stale if-range falls back to redirect", "bytes=10-", `"stale"`, http.StatusTemporaryRedirect, "", ""},
+		{"unsatisfiable", "bytes=20-", "", http.StatusRequestedRangeNotSatisfiable, "", "bytes */20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, store, _, cfg := setupTestDeps(t)
+			queries.AddFile(file)
+			if err := store.Upload(context.Background(), file.StorageKey, strings.NewReader(content), file.ContentType, file.SizeBytes); err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			router := NewRouter(&Config{
+				Storage:       store,
+				Queries:       queries,
+				MaxUploadSize: cfg.MaxUploadSize,
+				JWTSecret:     cfg.JWTSecret,
+			})
+
+			req := httptest.NewRequest("GET", "/v1/files/"+fileID.String()+"/download", nil)
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, testUserID, 1*time.Hour))
+			req.Header.Set("Range", tt.rangeHeader)
+			if tt.ifRange != "" {
+				req.Header.Set("If-Range", tt.ifRange)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.wantContentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantContentRange)
+			}
+			if tt.wantStatus == http.StatusPartialContent {
+				if rec.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+				}
+				if got := rec.Header().Get("ETag"); got != etag {
+					t.Errorf("ETag = %q, want %q", got, etag)
+				}
+			}
+		})
+	}
+}
+
 // TestDeleteHandler tests the file delete endpoint.
 func TestDeleteHandler(t *testing.T) {
 	testUserID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
@@ -929,6 +1020,98 @@ func TestUploadVerifiesJobEnqueued(t *testing.T) {
 	_ = queries
 }
 
+func TestUploadHandler_Dedupe(t *testing.T) {
+	testUserID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	pngData := []byte("\x89PNG\r\n\x1a\nidentical bytes")
+
+	upload := func(t *testing.T, router http.Handler, query string) (int, map[string]any) {
+		t.Helper()
+		body, contentType := createMultipartFormWithData(t, "file", "test.png", pngData, "image/png")
+		req := httptest.NewRequest("POST", "/v1/upload"+query, body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, testUserID, 1*time.Hour))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var resp map[string]any
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	newRouter := func(t *testing.T) (http.Handler, *MockStorage) {
+		queries, storage, broker, cfg := setupTestDeps(t)
+		return NewRouter(&Config{
+			Storage:       storage,
+			Queries:       queries,
+			Broker:        broker,
+			MaxUploadSize: cfg.MaxUploadSize,
+			JWTSecret:     cfg.JWTSecret,
+		}), storage
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		router, storage := newRouter(t)
+		_, first := upload(t, router, "")
+		code, second := upload(t, router, "")
+
+		if code != http.StatusAccepted {
+			t.Fatalf("status = %d, want %d", code, http.StatusAccepted)
+		}
+		if first["id"] == second["id"] {
+			t.Error("expected a new file without dedupe")
+		}
+		if storage.Count() != 2 {
+			t.Errorf("stored objects = %d, want 2", storage.Count())
+		}
+	})
+
+	t.Run("existing returns original file", func(t *testing.T) {
+		router, storage := newRouter(t)
+		_, first := upload(t, router, "?dedupe=existing")
+		code, second := upload(t, router, "?dedupe=existing")
+
+		if code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+		if second["id"] != first["id"] {
+			t.Errorf("id = %v, want %v", second["id"], first["id"])
+		}
+		if second["duplicate"] != true {
+			t.Error("expected duplicate = true")
+		}
+		if storage.Count() != 1 {
+			t.Errorf("stored objects = %d, want 1", storage.Count())
+		}
+	})
+
+	t.Run("link shares stored object", func(t *testing.T) {
+		router, storage := newRouter(t)
+		_, first := upload(t, router, "?dedupe=link")
+		code, second := upload(t, router, "?dedupe=link")
+
+		if code != http.StatusAccepted {
+			t.Fatalf("status = %d, want %d", code, http.StatusAccepted)
+		}
+		if second["id"] == first["id"] {
+			t.Error("expected a new file row in link mode")
+		}
+		if second["duplicate_of"] != first["id"] {
+			t.Errorf("duplicate_of = %v, want %v", second["duplicate_of"], first["id"])
+		}
+		if storage.Count() != 1 {
+			t.Errorf("stored objects = %d, want 1", storage.Count())
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		router, _ := newRouter(t)
+		code, _ := upload(t, router, "?dedupe=sometimes")
+		if code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", code, http.StatusBadRequest)
+		}
+	})
+}
+
 func TestTransformHandler(t *testing.T) {
 	testUserID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
 	existingFileID := uuid.MustParse("770e8400-e29b-41d4-a716-446655440000")
@@ -976,6 +1159,52 @@ func TestTransformHandler(t *testing.T) {
 			},
 			wantStatus: http.StatusAccepted,
 		},
+		{
+			name:   "transform with avif preset",
+			fileID: existingFileID.String(),
+			body:   `{"presets": ["avif"]}`,
+			setupMocks: func(q *MockQuerier) {
+				q.AddFile(createTestFileWithID(existingFileID, testUserID, "test.jpg"))
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:   "transform with pipeline",
+			fileID: existingFileID.String(),
+			body:   `{"pipelines": ["w_800,wm_foo,f_webp"]}`,
+			setupMocks: func(q *MockQuerier) {
+				q.AddFile(createTestFileWithID(existingFileID, testUserID, "test.jpg"))
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:   "transform with user preset",
+			fileID: existingFileID.String(),
+			body:   `{"presets": ["hero"]}`,
+			setupMocks: func(q *MockQuerier) {
+				q.AddFile(createTestFileWithID(existingFileID, testUserID, "test.jpg"))
+				q.AddPreset(createTestPreset(testUserID, "hero"))
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:   "transform with auto format pipeline",
+			fileID: existingFileID.String(),
+			body:   `{"pipelines": ["w_800,f_auto"]}`,
+			setupMocks: func(q *MockQuerier) {
+				q.AddFile(createTestFileWithID(existingFileID, testUserID, "test.jpg"))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "transform with invalid pipeline",
+			fileID: existingFileID.String(),
+			body:   `{"pipelines": ["w_abc"]}`,
+			setupMocks: func(q *MockQuerier) {
+				q.AddFile(createTestFileWithID(existingFileID, testUserID, "test.jpg"))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "transform non-existent file",
 			fileID:     "00000000-0000-0000-0000-000000000000",
@@ -1024,6 +1253,7 @@ func TestTransformHandler(t *testing.T) {
 				Broker:        broker,
 				MaxUploadSize: cfg.MaxUploadSize,
 				JWTSecret:     cfg.JWTSecret,
+				AVIFEnabled:   true,
 			})
 
 			req := httptest.NewRequest("POST", "/v1/files/"+tt.fileID+"/transform", strings.NewReader(tt.body))
@@ -1053,6 +1283,41 @@ func TestTransformHandler(t *testing.T) {
 	}
 }
 
+func TestTransformHandlers_AVIFUnavailable(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"avif preset", "/v1/files/" + fileID.String() + "/transform", `{"presets": ["avif"]}`},
+		{"avif pipeline", "/v1/files/" + fileID.String() + "/transform", `{"pipelines": ["w_800,f_avif"]}`},
+		{"batch avif preset", "/v1/batch/transform", `{"file_ids": ["` + fileID.String() + `"], "presets": ["thumbnail", "avif"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, storage, broker, cfg := setupTestDeps(t)
+			queries.AddFile(createTestFileWithID(fileID, userID, "test.jpg"))
+			router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "format_unavailable") {
+				t.Errorf("status = %d, want 400 format_unavailable; body = %s", rec.Code, rec.Body.String())
+			}
+			if len(broker.jobs) != 0 {
+				t.Errorf("enqueued %d jobs, want none", len(broker.jobs))
+			}
+		})
+	}
+}
+
 func TestBatchTransformHandler(t *testing.T) {
 	testUserID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
 	fileID1 := uuid.MustParse("770e8400-e29b-41d4-a716-446655440001")
@@ -1091,6 +1356,15 @@ func TestBatchTransformHandler(t *testing.T) {
 			},
 			wantStatus: http.StatusAccepted,
 		},
+		{
+			name: "batch transform with user preset",
+			body: `{"file_ids": ["` + fileID1.String() + `"], "presets": ["hero"]}`,
+			setupMocks: func(q *MockQuerier) {
+				q.AddFile(createTestFileWithID(fileID1, testUserID, "test1.jpg"))
+				q.AddPreset(createTestPreset(testUserID, "hero"))
+			},
+			wantStatus: http.StatusAccepted,
+		},
 		{
 			name:       "batch transform with no files",
 			body:       `{"file_ids": [], "presets": ["thumbnail"]}`,
@@ -1701,6 +1975,7 @@ func TestHLSStreamHandler_ManifestDelivery(t *testing.T) {
 
 	queries, storage, broker, cfg := setupTestDeps(t)
 	queries.AddFile(createTestVideoFileWithID(fileID, testUserID, "test-video.mp4"))
+	addTestHLSPackage(t, queries, storage, fileID)
 
 	router := NewRouter(&Config{
 		Storage:       storage,
@@ -1716,14 +1991,18 @@ func TestHLSStreamHandler_ManifestDelivery(t *testing.T) {
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
 
-	// Should redirect to presigned URL
-	if rec.Code != http.StatusTemporaryRedirect {
-		t.Errorf("status = %d, want %d; body = %s", rec.Code, http.StatusTemporaryRedirect, rec.Body.String())
+	// The master playlist is served with rendition URIs pointing back here
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body.String())
 	}
 
-	location := rec.Header().Get("Location")
-	if location == "" {
-		t.Error("expected Location header for redirect")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.apple.mpegurl" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	want := "/v1/files/" + fileID.String() + "/hls/720p/playlist.m3u8"
+	if !strings.Contains(rec.Body.String(), "\n"+want+"\n") {
+		t.Errorf("master playlist does not reference %s:\n%s", want, rec.Body.String())
 	}
 }
 
@@ -1733,6 +2012,7 @@ func TestHLSStreamHandler_SegmentDelivery(t *testing.T) {
 
 	queries, storage, broker, cfg := setupTestDeps(t)
 	queries.AddFile(createTestVideoFileWithID(fileID, testUserID, "test-video.mp4"))
+	addTestHLSPackage(t, queries, storage, fileID)
 
 	router := NewRouter(&Config{
 		Storage:       storage,
@@ -1742,8 +2022,7 @@ func TestHLSStreamHandler_SegmentDelivery(t *testing.T) {
 		JWTSecret:     cfg.JWTSecret,
 	})
 
-	// Route pattern is /v1/files/{id}/hls/{segment} - segment is a single path component
-	req := httptest.NewRequest("GET", "/v1/files/"+fileID.String()+"/hls/segment001.ts", nil)
+	req := httptest.NewRequest("GET", "/v1/files/"+fileID.String()+"/hls/720p/segment_001.ts", nil)
 	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, testUserID, 1*time.Hour))
 
 	rec := httptest.NewRecorder()
@@ -1755,8 +2034,8 @@ func TestHLSStreamHandler_SegmentDelivery(t *testing.T) {
 	}
 
 	location := rec.Header().Get("Location")
-	if location == "" {
-		t.Error("expected Location header for redirect")
+	if !strings.Contains(location, "processed/"+fileID.String()+"/hls_720p/segment_001.ts") {
+		t.Errorf("Location = %q, want the rendition's segment", location)
 	}
 }
 
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"errors"
+	"net/http"
 	"path/filepath"
 	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/filetype"
 )
 
 // allowedMIMETypes defines the MIME types permitted for upload.
@@ -95,6 +100,16 @@ func IsBlockedExtension(filename string) bool {
 	return blockedExtensions[ext]
 }
 
+// contentMismatchError converts a filetype.Resolve failure into an API error.
+func contentMismatchError(err error) *apperror.Error {
+	if errors.Is(err, filetype.ErrUnrecognized) {
+		return apperror.WrapWithMessage(err, "unrecognized_file_content",
+			"The file content is not a recognized format for its declared type", http.StatusBadRequest)
+	}
+	return apperror.WrapWithMessage(err, "content_type_mismatch",
+		"The file content does not match its declared type", http.StatusBadRequest)
+}
+
 // SanitizeFilename removes potentially dangerous characters and path components
 // from a filename to prevent path traversal attacks.
 func SanitizeFilename(filename string) string {
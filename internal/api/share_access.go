@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
+)
+
+// shareAccessWindow is how long repeat requests from one client for one
+// share count as a single access.
+const shareAccessWindow = 30 * time.Minute
+
+// shareAccessTracker remembers which clients recently accessed which shares,
+// so the many requests behind one page view (variants, revalidations, cache
+// hits) produce one share.accessed event rather than one per request. It is
+// process-local, so each API replica announces a client at most once per
+// window.
+type shareAccessTracker struct {
+	window    time.Duration
+	seen      map[string]time.Time
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+func newShareAccessTracker(window time.Duration) *shareAccessTracker {
+	return &shareAccessTracker{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// defaultShareAccesses backs CDNConfig values without a tracker.
+var defaultShareAccesses = newShareAccessTracker(shareAccessWindow)
+
+// first reports whether this is the first access of shareID by client in
+// the current window, and records it.
+func (t *shareAccessTracker) first(shareID, client string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if now.Sub(t.lastSweep) > t.window {
+		for key, at := range t.seen {
+			if now.Sub(at) > t.window {
+				delete(t.seen, key)
+			}
+		}
+		t.lastSweep = now
+	}
+
+	key := shareID + "|" + client
+	if at, ok := t.seen[key]; ok && now.Sub(at) <= t.window {
+		return false
+	}
+	t.seen[key] = now
+	return true
+}
+
+// shareClient identifies the client behind r for access de-duplication.
+func shareClient(r *http.Request) string {
+	return audit.ClientIP(r) + "|" + r.UserAgent()
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/google/uuid"
+)
+
+func TestShareAccessTracker(t *testing.T) {
+	tracker := newShareAccessTracker(time.Minute)
+	now := time.Now()
+
+	if !tracker.first("s1", "client-a", now) {
+		t.Error("first access not reported")
+	}
+	if tracker.first("s1", "client-a", now.Add(30*time.Second)) {
+		t.Error("repeat access within the window reported")
+	}
+	if !tracker.first("s1", "client-b", now.Add(30*time.Second)) {
+		t.Error("access from another client not reported")
+	}
+	if !tracker.first("s2", "client-a", now.Add(30*time.Second)) {
+		t.Error("access to another share not reported")
+	}
+	if !tracker.first("s1", "client-a", now.Add(2*time.Minute)) {
+		t.Error("access after the window not reported")
+	}
+	if len(tracker.seen) != 1 {
+		t.Errorf("tracker keeps %d entries, want expired ones swept", len(tracker.seen))
+	}
+}
+
+func TestCDNHandler_ShareAccessedOncePerClient(t *testing.T) {
+	q, s, registry := setupCDNTestDeps(t)
+	userID := uuid.New()
+	fileID := uuid.New()
+	q.AddFile(createTestFileWithID(fileID, userID, "photo.jpg"))
+	q.AddShareByToken("tok", createTestShareByToken(fileID, userID, "tok", "uploads/photo.jpg", "image/jpeg", "photo.jpg", nil, nil))
+	s.PresignedURLFn = func(key string, expiry int) (string, error) { return "https://cdn.example.com/" + key, nil }
+	dispatcher, deliveries := newRecordingDispatcher()
+	cfg := &CDNConfig{Storage: s, Queries: q, Registry: registry, WebhookDispatcher: dispatcher, accesses: newShareAccessTracker(time.Minute)}
+
+	get := func(transforms, client string) int {
+		req := httptest.NewRequest(http.MethodGet, "/cdn/tok/"+transforms+"/photo.jpg", nil)
+		req.SetPathValue("token", "tok")
+		req.SetPathValue("transforms", transforms)
+		req.SetPathValue("filename", "photo.jpg")
+		req.Header.Set("X-Forwarded-For", client)
+		rec := httptest.NewRecorder()
+		CDNHandler(cfg).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A rejected request is not an access
+	if code := get("w_abc", "203.0.113.1"); code != http.StatusBadRequest {
+		t.Fatalf("invalid transform status = %d, want %d", code, http.StatusBadRequest)
+	}
+	for range 3 {
+		if code := get("_", "203.0.113.1"); code != http.StatusTemporaryRedirect {
+			t.Fatalf("status = %d, want %d", code, http.StatusTemporaryRedirect)
+		}
+	}
+	get("_", "203.0.113.2")
+
+	var got []db.CreateWebhookDeliveryParams
+	timeout := time.After(200 * time.Millisecond)
+	for collecting := true; collecting; {
+		select {
+		case d := <-deliveries:
+			got = append(got, d)
+		case <-timeout:
+			collecting = false
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d share.accessed deliveries, want one per client", len(got))
+	}
+	for _, d := range got {
+		if d.EventType != webhook.EventShareAccessed {
+			t.Errorf("delivered %s, want %s", d.EventType, webhook.EventShareAccessed)
+		}
+	}
+}
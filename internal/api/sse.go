@@ -3,11 +3,13 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/events"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -16,6 +18,8 @@ import (
 // SSEConfig holds configuration for SSE endpoints
 type SSEConfig struct {
 	Queries Querier
+	// Events replays job events for clients reconnecting with Last-Event-ID.
+	Events events.Bus
 }
 
 // SSEClient represents a connected SSE client
@@ -27,6 +31,7 @@ type SSEClient struct {
 
 // SSEMessage represents a message to send to SSE clients
 type SSEMessage struct {
+	ID    string      `json:"id,omitempty"`
 	Event string      `json:"event"`
 	Data  interface{} `json:"data"`
 }
@@ -81,21 +86,13 @@ func (h *SSEHub) run() {
 	}
 }
 
-// BroadcastFileStatus sends a file status update to relevant clients
-func BroadcastFileStatus(fileID string, userID uuid.UUID, status string, progress int) {
-	message := SSEMessage{
-		Event: "file:status",
-		Data: map[string]interface{}{
-			"file_id":  fileID,
-			"status":   status,
-			"progress": progress,
-		},
-	}
-
-	sseHub.mu.RLock()
-	defer sseHub.mu.RUnlock()
+// sendToUser delivers message to the clients of userID watching fileID or
+// all of their files.
+func (h *SSEHub) sendToUser(userID uuid.UUID, fileID string, message SSEMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	for client := range sseHub.clients {
+	for client := range h.clients {
 		if client.UserID == userID && (client.FileID == "" || client.FileID == fileID) {
 			select {
 			case client.Channel <- message:
@@ -106,26 +103,66 @@ func BroadcastFileStatus(fileID string, userID uuid.UUID, status string, progres
 	}
 }
 
+// BroadcastFileStatus sends a file status update to relevant clients
+func BroadcastFileStatus(fileID string, userID uuid.UUID, status string, progress int) {
+	sseHub.sendToUser(userID, fileID, SSEMessage{
+		Event: "file:status",
+		Data: map[string]interface{}{
+			"file_id":  fileID,
+			"status":   status,
+			"progress": progress,
+		},
+	})
+}
+
 // BroadcastJobComplete sends a job completion event
 func BroadcastJobComplete(fileID string, userID uuid.UUID, jobType string, variantID string) {
-	message := SSEMessage{
+	sseHub.sendToUser(userID, fileID, SSEMessage{
 		Event: "job:complete",
 		Data: map[string]interface{}{
 			"file_id":    fileID,
 			"job_type":   jobType,
 			"variant_id": variantID,
 		},
+	})
+}
+
+// BroadcastEvent forwards a job event from the event bus to relevant clients.
+func BroadcastEvent(event events.Event) {
+	sseHub.sendToUser(event.UserID, event.FileID, eventMessage(event))
+}
+
+func eventMessage(event events.Event) SSEMessage {
+	return SSEMessage{
+		ID:    event.ID,
+		Event: event.Type,
+		Data: map[string]interface{}{
+			"file_id":  event.FileID,
+			"job_id":   event.JobID,
+			"job_type": event.JobType,
+			"progress": event.Progress,
+			"error":    event.Error,
+			"time":     event.Time,
+		},
 	}
+}
 
-	sseHub.mu.RLock()
-	defer sseHub.mu.RUnlock()
+// RunSSEEventBridge subscribes to bus and broadcasts every event to the SSE
+// clients connected to this replica, resubscribing after failures until ctx
+// is cancelled.
+func RunSSEEventBridge(ctx context.Context, bus events.Bus) {
+	log := logger.FromContext(ctx)
+	for {
+		err := bus.Subscribe(ctx, BroadcastEvent)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("event subscription ended, resubscribing", "error", err)
 
-	for client := range sseHub.clients {
-		if client.UserID == userID && (client.FileID == "" || client.FileID == fileID) {
-			select {
-			case client.Channel <- message:
-			default:
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
 		}
 	}
 }
@@ -141,7 +178,10 @@ func FileStatusSSEHandler(cfg *SSEConfig) http.HandlerFunc {
 			return
 		}
 
-		fileID := r.URL.Query().Get("file_id")
+		fileID := r.PathValue("id")
+		if fileID == "" {
+			fileID = r.URL.Query().Get("file_id")
+		}
 
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
@@ -170,7 +210,24 @@ func FileStatusSSEHandler(cfg *SSEConfig) http.HandlerFunc {
 		})
 
 		if fileID != "" && cfg.Queries != nil {
-			go sendInitialFileStatus(r.Context(), w, flusher, cfg.Queries, userID, fileID)
+			sendInitialFileStatus(r.Context(), w, flusher, cfg.Queries, userID, fileID)
+		}
+
+		// Events published while replaying are also queued on the client
+		// channel; lastID drops those already sent.
+		var lastID string
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" && cfg.Events != nil {
+			lastID = lastEventID
+			missed, err := cfg.Events.Since(r.Context(), userID, lastEventID)
+			if err != nil {
+				log.Warn("failed to replay events", "last_event_id", lastEventID, "error", err)
+			}
+			for _, event := range missed {
+				if fileID == "" || event.FileID == fileID {
+					sendSSEMessage(w, flusher, eventMessage(event))
+				}
+				lastID = event.ID
+			}
 		}
 
 		ctx := r.Context()
@@ -184,6 +241,9 @@ func FileStatusSSEHandler(cfg *SSEConfig) http.HandlerFunc {
 				log.Info("SSE client disconnected", "user_id", userID.String())
 				return
 			case msg := <-client.Channel:
+				if msg.ID != "" && lastID != "" && events.CompareIDs(msg.ID, lastID) <= 0 {
+					continue
+				}
 				sendSSEMessage(w, flusher, msg)
 			case <-ticker.C:
 				sendSSEMessage(w, flusher, SSEMessage{
@@ -201,6 +261,9 @@ func sendSSEMessage(w http.ResponseWriter, flusher http.Flusher, msg SSEMessage)
 		return
 	}
 
+	if msg.ID != "" {
+		_, _ = fmt.Fprintf(w, "id: %s\n", msg.ID)
+	}
 	_, _ = fmt.Fprintf(w, "event: %s\n", msg.Event)
 	_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
 	flusher.Flush()
@@ -332,8 +395,12 @@ func FileStatusHandler(cfg *SSEConfig) http.HandlerFunc {
 	}
 }
 
-// UploadProgressSSEHandler handles SSE for upload progress
-func UploadProgressSSEHandler() http.HandlerFunc {
+// UploadProgressSSEHandler handles SSE for upload progress. A nil store uses
+// the process-local session store.
+func UploadProgressSSEHandler(sessions UploadSessionStore) http.HandlerFunc {
+	if sessions == nil {
+		sessions = defaultSessionStore
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := GetUserID(r.Context())
 		if !ok {
@@ -357,8 +424,8 @@ func UploadProgressSSEHandler() http.HandlerFunc {
 			return
 		}
 
-		session, ok := sessionStore.Get(uploadID)
-		if !ok {
+		session, err := sessions.Get(r.Context(), uploadID)
+		if err != nil {
 			sendSSEMessage(w, flusher, SSEMessage{
 				Event: "error",
 				Data:  map[string]string{"message": "upload session not found"},
@@ -392,14 +459,17 @@ func UploadProgressSSEHandler() http.HandlerFunc {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				session, ok := sessionStore.Get(uploadID)
-				if !ok {
+				session, err := sessions.Get(ctx, uploadID)
+				if errors.Is(err, errUploadSessionNotFound) {
 					sendSSEMessage(w, flusher, SSEMessage{
 						Event: "complete",
 						Data:  map[string]string{"status": "completed"},
 					})
 					return
 				}
+				if err != nil {
+					continue
+				}
 
 				session.mu.Lock()
 				chunksLoaded := len(session.ChunksLoaded)
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/events"
 	"github.com/google/uuid"
 )
 
@@ -404,7 +406,7 @@ func TestFileStatusHandler_NoQueries(t *testing.T) {
 }
 
 func TestUploadProgressSSEHandler_Unauthorized(t *testing.T) {
-	handler := UploadProgressSSEHandler()
+	handler := UploadProgressSSEHandler(nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/upload/progress?upload_id=123", nil)
 
@@ -417,7 +419,7 @@ func TestUploadProgressSSEHandler_Unauthorized(t *testing.T) {
 }
 
 func TestUploadProgressSSEHandler_MissingUploadID(t *testing.T) {
-	handler := UploadProgressSSEHandler()
+	handler := UploadProgressSSEHandler(nil)
 
 	userID := uuid.New()
 	ctx := context.WithValue(context.Background(), UserIDKey, userID)
@@ -591,3 +593,91 @@ func TestSSEHub_Broadcast(t *testing.T) {
 	hub.unregister <- client1
 	hub.unregister <- client2
 }
+
+func TestRunSSEEventBridge(t *testing.T) {
+	originalHub := sseHub
+	defer func() { sseHub = originalHub }()
+
+	testHub := &SSEHub{
+		clients:    make(map[*SSEClient]bool),
+		register:   make(chan *SSEClient),
+		unregister: make(chan *SSEClient),
+		broadcast:  make(chan SSEMessage),
+	}
+	sseHub = testHub
+	go testHub.run()
+
+	userID := uuid.New()
+	client := &SSEClient{UserID: userID, Channel: make(chan SSEMessage, 10)}
+	testHub.register <- client
+	defer func() { testHub.unregister <- client }()
+
+	bus := events.NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RunSSEEventBridge(ctx, bus)
+	time.Sleep(10 * time.Millisecond)
+
+	event := &events.Event{Type: events.TypeJobProgress, UserID: userID, FileID: "file-123", Progress: 40}
+	if err := bus.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case msg := <-client.Channel:
+		if msg.Event != events.TypeJobProgress {
+			t.Errorf("Event = %q, want %q", msg.Event, events.TypeJobProgress)
+		}
+		if msg.ID != event.ID {
+			t.Errorf("ID = %q, want %q", msg.ID, event.ID)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Error("Did not receive bridged event")
+	}
+}
+
+func TestFileStatusSSEHandler_Replay(t *testing.T) {
+	originalHub := sseHub
+	defer func() { sseHub = originalHub }()
+
+	testHub := &SSEHub{
+		clients:    make(map[*SSEClient]bool),
+		register:   make(chan *SSEClient),
+		unregister: make(chan *SSEClient),
+		broadcast:  make(chan SSEMessage),
+	}
+	sseHub = testHub
+	go testHub.run()
+
+	userID := uuid.New()
+	bus := events.NewMemoryBus()
+	publish := func(eventType, fileID string) *events.Event {
+		e := &events.Event{Type: eventType, UserID: userID, FileID: fileID}
+		_ = bus.Publish(context.Background(), e)
+		return e
+	}
+	seen := publish(events.TypeJobRunning, "file-a")
+	missed := publish(events.TypeJobComplete, "file-a")
+	other := publish(events.TypeJobComplete, "file-b")
+
+	handler := FileStatusSSEHandler(&SSEConfig{Events: bus})
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), UserIDKey, userID), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/v1/files/file-a/events", nil).WithContext(ctx)
+	req.SetPathValue("id", "file-a")
+	req.Header.Set("Last-Event-ID", seen.ID)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "id: "+missed.ID+"\nevent: job:complete") {
+		t.Errorf("body does not replay missed event %s:\n%s", missed.ID, body)
+	}
+	if strings.Contains(body, "id: "+seen.ID+"\n") {
+		t.Errorf("body replays already seen event %s", seen.ID)
+	}
+	if strings.Contains(body, "id: "+other.ID+"\n") {
+		t.Errorf("body replays event %s for another file", other.ID)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+)
+
+func TestVideoStoryboardHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		want       worker.VideoStoryboardPayload
+	}{
+		{"defaults", `{}`, http.StatusAccepted, worker.VideoStoryboardPayload{Columns: 10, Rows: 10, Width: 160, Height: 90}},
+		{"custom grid", `{"columns": 5, "rows": 4, "width": 240, "height": 135}`, http.StatusAccepted, worker.VideoStoryboardPayload{Columns: 5, Rows: 4, Width: 240, Height: 135}},
+		{"grid too large", `{"columns": 50}`, http.StatusBadRequest, worker.VideoStoryboardPayload{}},
+		{"thumbnails too large", `{"width": 1920}`, http.StatusBadRequest, worker.VideoStoryboardPayload{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			fileID := uuid.New()
+			queries, storage, broker, cfg := setupTestDeps(t)
+			queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+			router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+			req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/storyboard", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				if broker.HasJob("video_storyboard") {
+					t.Error("no job should be enqueued")
+				}
+				return
+			}
+			var payload *worker.VideoStoryboardPayload
+			for _, j := range broker.jobs {
+				if p, ok := j.Payload.(*worker.VideoStoryboardPayload); ok {
+					payload = p
+				}
+			}
+			if payload == nil {
+				t.Fatal("expected a video_storyboard job")
+			}
+			if payload.Columns != tt.want.Columns || payload.Rows != tt.want.Rows || payload.Width != tt.want.Width || payload.Height != tt.want.Height {
+				t.Errorf("payload = %+v, want %+v", *payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestVideoStoryboardHandler_TransformationLimit(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, broker, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+	queries.TransformationsUsed = 10000
+	router := NewRouter(&Config{Storage: storage, Queries: queries, Broker: broker, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	req := httptest.NewRequest("POST", "/v1/files/"+fileID.String()+"/video/storyboard", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "transformation_limit_reached") {
+		t.Fatalf("status = %d, body = %s; want 403 transformation_limit_reached", rec.Code, rec.Body.String())
+	}
+	if broker.HasJob("video_storyboard") {
+		t.Error("no job should be enqueued")
+	}
+}
+
+func TestStoryboardStreamHandler(t *testing.T) {
+	userID := uuid.New()
+	fileID := uuid.New()
+	queries, storage, _, cfg := setupTestDeps(t)
+	queries.AddFile(createTestVideoFileWithID(fileID, userID, "clip.mp4"))
+
+	prefix := "processed/" + fileID.String() + "/"
+	vtt := "WEBVTT\n\n00:00:00.000 --> 00:00:06.000\nsprite.jpg#xywh=0,0,160,90\n"
+	for key, body := range map[string]string{
+		prefix + "storyboard_vtt/storyboard.vtt": vtt,
+		prefix + "video_sprite/sprite.jpg":       "jpeg",
+	} {
+		if err := storage.Upload(context.Background(), key, strings.NewReader(body), "", int64(len(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	track := createTestVariant(fileID, "storyboard_vtt")
+	track.StorageKey = prefix + "storyboard_vtt/storyboard.vtt"
+	queries.AddVariant(track)
+	sprite := createTestVariant(fileID, "video_sprite")
+	sprite.StorageKey = prefix + "video_sprite/sprite.jpg"
+	queries.AddVariant(sprite)
+	router := NewRouter(&Config{Storage: storage, Queries: queries, MaxUploadSize: cfg.MaxUploadSize, JWTSecret: cfg.JWTSecret})
+
+	base := "/v1/files/" + fileID.String() + "/storyboard/"
+	rec := serveStream(t, router, userID, base+"storyboard.vtt")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("track: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "\n"+base+"sprite.jpg#xywh=0,0,160,90\n") {
+		t.Errorf("cue image not rewritten:\n%s", rec.Body.String())
+	}
+
+	rec = serveStream(t, router, userID, base+"sprite.jpg")
+	if rec.Code != http.StatusTemporaryRedirect || !strings.Contains(rec.Header().Get("Location"), "video_sprite/sprite.jpg") {
+		t.Errorf("sprite: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if rec := serveStream(t, router, uuid.New(), base+"storyboard.vtt"); rec.Code != http.StatusNotFound {
+		t.Errorf("other user: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
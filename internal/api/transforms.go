@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -62,117 +63,123 @@ func (t *TransformOptions) ProcessorName() string {
 	return ""
 }
 
-func (t *TransformOptions) ProcessorNameForContentType(contentType string) string {
-	if contentType == "application/pdf" {
-		return "pdf_thumbnail"
-	}
-	return t.ProcessorName()
-}
-
+// ParseTransforms merges every parameter of a transform string into one
+// TransformOptions. Use ParsePipeline to keep the order of operations.
 func ParseTransforms(s string) (*TransformOptions, error) {
 	if s == "" || s == "_" || s == "original" {
 		return &TransformOptions{}, nil
 	}
 
 	opts := &TransformOptions{}
-	parts := strings.Split(s, ",")
-
-	for _, part := range parts {
+	for _, part := range strings.Split(s, ",") {
 		part = strings.TrimSpace(part)
 		if part == "" {
 			continue
 		}
 
-		kv := strings.SplitN(part, "_", 2)
-		if len(kv) != 2 {
-			return nil, fmt.Errorf("invalid transform format: %s (expected key_value)", part)
+		key, value, err := splitTransform(part)
+		if err != nil {
+			return nil, err
 		}
+		if err := opts.set(key, value); err != nil {
+			return nil, err
+		}
+	}
 
-		key, value := kv[0], kv[1]
+	return opts, nil
+}
 
-		switch key {
-		case "w":
-			w, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid width value: %s", value)
-			}
-			if w < 1 || w > 10000 {
-				return nil, fmt.Errorf("width must be between 1 and 10000, got %d", w)
-			}
-			opts.Width = w
+func splitTransform(part string) (string, string, error) {
+	kv := strings.SplitN(part, "_", 2)
+	if len(kv) != 2 {
+		return "", "", fmt.Errorf("invalid transform format: %s (expected key_value)", part)
+	}
+	return kv[0], kv[1], nil
+}
 
-		case "h":
-			h, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid height value: %s", value)
-			}
-			if h < 1 || h > 10000 {
-				return nil, fmt.Errorf("height must be between 1 and 10000, got %d", h)
-			}
-			opts.Height = h
+// set parses and applies a single key_value transform parameter.
+func (t *TransformOptions) set(key, value string) error {
+	switch key {
+	case "w":
+		w, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid width value: %s", value)
+		}
+		if w < 1 || w > 10000 {
+			return fmt.Errorf("width must be between 1 and 10000, got %d", w)
+		}
+		t.Width = w
 
-		case "q":
-			q, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid quality value: %s", value)
-			}
-			if q < 1 || q > 100 {
-				return nil, fmt.Errorf("quality must be between 1 and 100, got %d", q)
-			}
-			opts.Quality = q
-
-		case "f":
-			value = strings.ToLower(value)
-			switch value {
-			case "webp", "jpg", "jpeg", "png", "gif":
-				opts.Format = value
-			default:
-				return nil, fmt.Errorf("unsupported format: %s (supported: webp, jpg, png, gif)", value)
-			}
+	case "h":
+		h, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid height value: %s", value)
+		}
+		if h < 1 || h > 10000 {
+			return fmt.Errorf("height must be between 1 and 10000, got %d", h)
+		}
+		t.Height = h
 
-		case "c":
-			value = strings.ToLower(value)
-			switch value {
-			case "thumb", "fit", "fill", "cover", "contain":
-				opts.Crop = value
-			default:
-				return nil, fmt.Errorf("unsupported crop mode: %s (supported: thumb, fit, fill, cover, contain)", value)
-			}
+	case "q":
+		q, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid quality value: %s", value)
+		}
+		if q < 1 || q > 100 {
+			return fmt.Errorf("quality must be between 1 and 100, got %d", q)
+		}
+		t.Quality = q
 
-		case "wm":
-			if len(value) > 100 {
-				return nil, fmt.Errorf("watermark text too long (max 100 characters)")
-			}
-			opts.Watermark = value
+	case "f":
+		value = strings.ToLower(value)
+		switch value {
+		case "webp", "avif", "jpg", "jpeg", "png", "gif", formatAuto:
+			t.Format = value
+		default:
+			return fmt.Errorf("unsupported format: %s (supported: webp, avif, jpg, png, gif, auto)", value)
+		}
 
-		case "p":
-			p, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid page value: %s", value)
-			}
-			if p < 1 || p > 9999 {
-				return nil, fmt.Errorf("page must be between 1 and 9999, got %d", p)
-			}
-			opts.Page = p
-
-		case "pos":
-			value = strings.ToLower(value)
-			switch value {
-			case "center", "north", "south", "east", "west",
-				"north-west", "north-east", "south-west", "south-east",
-				"top", "bottom", "left", "right",
-				"top-left", "top-right", "bottom-left", "bottom-right":
-				opts.Position = value
-			default:
-				return nil, fmt.Errorf("unsupported position: %s (supported: center, north, south, east, west, north-west, north-east, south-west, south-east)", value)
-			}
+	case "c":
+		value = strings.ToLower(value)
+		switch value {
+		case "thumb", "fit", "fill", "cover", "contain":
+			t.Crop = value
+		default:
+			return fmt.Errorf("unsupported crop mode: %s (supported: thumb, fit, fill, cover, contain)", value)
+		}
+
+	case "wm":
+		if len(value) > 100 {
+			return fmt.Errorf("watermark text too long (max 100 characters)")
+		}
+		t.Watermark = value
+
+	case "p":
+		p, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid page value: %s", value)
+		}
+		if p < 1 || p > 9999 {
+			return fmt.Errorf("page must be between 1 and 9999, got %d", p)
+		}
+		t.Page = p
 
+	case "pos":
+		value = strings.ToLower(value)
+		switch value {
+		case "center", "north", "south", "east", "west",
+			"north-west", "north-east", "south-west", "south-east",
+			"top", "bottom", "left", "right",
+			"top-left", "top-right", "bottom-left", "bottom-right":
+			t.Position = value
 		default:
-			return nil, fmt.Errorf("unknown transform key: %s", key)
+			return fmt.Errorf("unsupported position: %s (supported: center, north, south, east, west, north-west, north-east, south-west, south-east)", value)
 		}
-	}
 
-	return opts, nil
+	default:
+		return fmt.Errorf("unknown transform key: %s", key)
+	}
+	return nil
 }
 
 func ValidateTransforms(opts *TransformOptions) error {
@@ -188,3 +195,328 @@ func ValidateTransforms(opts *TransformOptions) error {
 
 	return nil
 }
+
+// maxPipelineSteps bounds the work a single transform string can request.
+const maxPipelineSteps = 8
+
+// stepKind groups the transform keys that configure the same operation.
+type stepKind int
+
+const (
+	stepModifier stepKind = iota
+	stepGeometry
+	stepFormat
+	stepWatermark
+)
+
+func transformKind(key string) stepKind {
+	switch key {
+	case "w", "h", "c":
+		return stepGeometry
+	case "f":
+		return stepFormat
+	case "wm":
+		return stepWatermark
+	}
+	return stepModifier
+}
+
+func (t *TransformOptions) has(key string) bool {
+	switch key {
+	case "w":
+		return t.Width > 0
+	case "h":
+		return t.Height > 0
+	case "c":
+		return t.Crop != ""
+	case "f":
+		return t.Format != ""
+	case "wm":
+		return t.Watermark != ""
+	}
+	return false
+}
+
+// TransformStep is one operation of a TransformPipeline, run by the named
+// processor.
+type TransformStep struct {
+	Processor string
+	Options   TransformOptions
+}
+
+// TransformPipeline is an ordered chain of transforms in which each step
+// processes the output of the previous one.
+type TransformPipeline struct {
+	Steps []TransformStep
+}
+
+// ParsePipeline parses a transform string into an ordered pipeline.
+// Consecutive geometry (w, h, c), format (f) and watermark (wm) parameters
+// form one step, and repeating a parameter starts a new step, so
+// "w_800,wm_foo,f_webp" resizes, watermarks and then converts. A format
+// directly after a geometry step is folded into it, so "w_800,f_webp" is
+// encoded once. q sets the quality of the final encode wherever it appears,
+// pos applies to the step it follows, or to the first step when it leads,
+// and p selects the PDF page for the whole pipeline.
+func ParsePipeline(s string) (*TransformPipeline, error) {
+	if s == "" || s == "_" || s == "original" {
+		return &TransformPipeline{}, nil
+	}
+
+	var (
+		steps   []TransformOptions
+		kinds   []stepKind
+		leading TransformOptions // modifiers seen before the first step
+		page    int
+		quality int
+	)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, err := splitTransform(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "p" || key == "q" {
+			var opts TransformOptions
+			if err := opts.set(key, value); err != nil {
+				return nil, err
+			}
+			if key == "p" {
+				page = opts.Page
+			} else {
+				quality = opts.Quality
+			}
+			continue
+		}
+
+		target := &leading
+		if kind := transformKind(key); kind != stepModifier {
+			n := len(steps)
+			if n == 0 || kinds[n-1] != kind || steps[n-1].has(key) {
+				if n >= maxPipelineSteps {
+					return nil, fmt.Errorf("too many transform steps (max %d)", maxPipelineSteps)
+				}
+				step := TransformOptions{}
+				if n == 0 {
+					step.Position = leading.Position
+				}
+				steps = append(steps, step)
+				kinds = append(kinds, kind)
+			}
+			target = &steps[len(steps)-1]
+		} else if len(steps) > 0 {
+			target = &steps[len(steps)-1]
+		}
+
+		if err := target.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(steps) == 0 {
+		if !leading.RequiresProcessing() && quality == 0 && page == 0 {
+			return &TransformPipeline{}, nil
+		}
+		steps = append(steps, leading)
+		kinds = append(kinds, stepGeometry)
+	}
+
+	// Resize encodes every output format itself, so a following format
+	// step would only decode and re-encode its output.
+	folded, foldedKinds := steps[:0], kinds[:0]
+	for i, opts := range steps {
+		if n := len(folded); n > 0 && kinds[i] == stepFormat && foldedKinds[n-1] == stepGeometry && folded[n-1].Format == "" {
+			folded[n-1].Format = opts.Format
+			continue
+		}
+		folded = append(folded, opts)
+		foldedKinds = append(foldedKinds, kinds[i])
+	}
+	steps, kinds = folded, foldedKinds
+	steps[len(steps)-1].Quality = quality
+
+	pipeline := &TransformPipeline{Steps: make([]TransformStep, len(steps))}
+	for i, opts := range steps {
+		opts.Page = page
+		pipeline.Steps[i] = TransformStep{Processor: stepProcessor(kinds[i], &opts), Options: opts}
+	}
+	return pipeline, nil
+}
+
+func stepProcessor(kind stepKind, opts *TransformOptions) string {
+	switch kind {
+	case stepFormat:
+		if opts.Format == "webp" {
+			return "webp"
+		}
+		return "convert"
+	case stepWatermark:
+		return "watermark"
+	}
+	if opts.Format != "" {
+		// Only resize writes formats other than JPEG
+		return "resize"
+	}
+	if name := opts.ProcessorName(); name != "" {
+		return name
+	}
+	return "resize"
+}
+
+// hasGeometry reports whether t resizes or crops.
+func (t *TransformOptions) hasGeometry() bool {
+	return t.Width > 0 || t.Height > 0 || t.Crop != ""
+}
+
+func (p *TransformPipeline) RequiresProcessing() bool {
+	return len(p.Steps) > 0
+}
+
+// CacheKey identifies the output of the whole chain. A single step keeps the
+// key of its TransformOptions so existing cache entries stay valid.
+func (p *TransformPipeline) CacheKey() string {
+	if len(p.Steps) == 1 {
+		return p.Steps[0].Options.CacheKey()
+	}
+	h := sha256.New()
+	for _, step := range p.Steps {
+		_, _ = fmt.Fprintf(h, "%s:%s|", step.Processor, step.Options.CacheKey())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ProcessorSteps resolves the pipeline for a source of contentType. PDFs are
+// rasterized first: a leading resize becomes the pdf_thumbnail step,
+// otherwise one is prepended.
+func (p *TransformPipeline) ProcessorSteps(contentType string) []processor.Step {
+	steps := make([]processor.Step, 0, len(p.Steps)+1)
+	for _, step := range p.Steps {
+		steps = append(steps, processor.Step{Processor: step.Processor, Options: *step.Options.ToProcessorOptions()})
+	}
+
+	if contentType == "application/pdf" {
+		if len(steps) > 0 && (steps[0].Processor == "resize" || steps[0].Processor == "thumbnail") {
+			// pdf_thumbnail rasterizes at the requested size but has a fixed
+			// output format, so a folded format becomes its own step again.
+			if format := steps[0].Options.Format; format != "" {
+				convert := TransformOptions{Format: format, Quality: steps[0].Options.Quality}
+				steps = slices.Insert(steps, 1, processor.Step{
+					Processor: stepProcessor(stepFormat, &convert),
+					Options:   *convert.ToProcessorOptions(),
+				})
+				steps[0].Options.Format, steps[0].Options.Quality = "", 0
+			}
+			steps[0].Processor = "pdf_thumbnail"
+		} else {
+			var page int
+			if len(p.Steps) > 0 {
+				page = p.Steps[0].Options.Page
+			}
+			steps = append([]processor.Step{{Processor: "pdf_thumbnail", Options: processor.Options{Page: page}}}, steps...)
+		}
+	}
+	return steps
+}
+
+// ValidatePipeline applies ValidateTransforms to every step.
+func ValidatePipeline(p *TransformPipeline) error {
+	for i := range p.Steps {
+		if err := ValidateTransforms(&p.Steps[i].Options); err != nil {
+			if len(p.Steps) > 1 {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// formatAuto is the f_ value that defers the output format to the client's
+// Accept header.
+const formatAuto = "auto"
+
+// HasAutoFormat reports whether any step uses f_auto.
+func (p *TransformPipeline) HasAutoFormat() bool {
+	return p.HasFormat(formatAuto)
+}
+
+// HasFormat reports whether any step converts to format.
+func (p *TransformPipeline) HasFormat(format string) bool {
+	for _, step := range p.Steps {
+		if step.Options.Format == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveAutoFormat replaces f_auto with format in every step and picks the
+// processor for the concrete format. It must run before CacheKey so the
+// negotiated format is part of the key.
+func (p *TransformPipeline) ResolveAutoFormat(format string) {
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Options.Format != formatAuto {
+			continue
+		}
+		step.Options.Format = format
+		kind := stepFormat
+		if step.Options.hasGeometry() {
+			kind = stepGeometry
+		}
+		step.Processor = stepProcessor(kind, &step.Options)
+	}
+}
+
+// NegotiateFormat picks the f_auto output format from an Accept header:
+// AVIF when the client accepts it and avif is enabled, then WebP, and
+// otherwise PNG for sources that may be transparent and JPEG for the rest.
+func NegotiateFormat(accept, sourceType string, avif bool) string {
+	accepted := acceptedImageTypes(accept)
+	switch {
+	case avif && accepted["image/avif"]:
+		return "avif"
+	case accepted["image/webp"]:
+		return "webp"
+	}
+
+	switch sourceType {
+	case "image/png", "image/gif", "image/webp", "image/avif":
+		return "png"
+	}
+	return "jpeg"
+}
+
+// acceptedImageTypes returns the media types listed in an Accept header,
+// leaving out those with q=0. Wildcards are kept verbatim and never imply
+// support for a specific image format.
+func acceptedImageTypes(accept string) map[string]bool {
+	types := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		rejected := false
+		for _, param := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(k) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q <= 0 {
+				rejected = true
+			}
+		}
+		if !rejected {
+			types[mediaType] = true
+		}
+	}
+	return types
+}
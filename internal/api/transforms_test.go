@@ -1,6 +1,7 @@
 package api
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -62,6 +63,12 @@ func TestParseTransforms(t *testing.T) {
 			wantFormat: "webp",
 			wantErr:    false,
 		},
+		{
+			name:       "format avif",
+			input:      "f_avif",
+			wantFormat: "avif",
+			wantErr:    false,
+		},
 		{
 			name:       "format jpg",
 			input:      "f_jpg",
@@ -327,3 +334,197 @@ func TestValidateTransforms(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantSteps []string
+		wantErr   bool
+	}{
+		{name: "original", input: "_", wantSteps: nil},
+		{name: "single resize", input: "w_800,h_600", wantSteps: []string{"resize"}},
+		{name: "small thumbnail", input: "w_100,h_100", wantSteps: []string{"thumbnail"}},
+		{name: "resize then webp is one encode", input: "w_800,f_webp", wantSteps: []string{"resize"}},
+		{name: "small thumbnail with format", input: "w_100,h_100,f_png", wantSteps: []string{"resize"}},
+		{name: "resize watermark webp", input: "w_800,wm_foo,f_webp", wantSteps: []string{"resize", "watermark", "webp"}},
+		{name: "resize then avif is one encode", input: "w_800,f_avif", wantSteps: []string{"resize"}},
+		{name: "second format is kept", input: "w_800,f_png,f_webp", wantSteps: []string{"resize", "webp"}},
+		{name: "order is kept", input: "f_png,w_800", wantSteps: []string{"convert", "resize"}},
+		{name: "repeated key starts a step", input: "w_800,w_200", wantSteps: []string{"resize", "resize"}},
+		{name: "quality only", input: "q_80", wantSteps: []string{"resize"}},
+		{name: "invalid value", input: "w_800,f_bmp", wantErr: true},
+		{name: "too many steps", input: "w_1,w_2,w_3,w_4,w_5,w_6,w_7,w_8,w_9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := ParsePipeline(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, step := range pipeline.Steps {
+				got = append(got, step.Processor)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantSteps, ",") {
+				t.Errorf("steps = %v, want %v", got, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestParsePipeline_Modifiers(t *testing.T) {
+	pipeline, err := ParsePipeline("pos_north,w_800,wm_foo,f_webp,q_90,p_2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pipeline.Steps) != 3 {
+		t.Fatalf("got %d steps, want 3", len(pipeline.Steps))
+	}
+	if pos := pipeline.Steps[0].Options.Position; pos != "north" {
+		t.Errorf("leading position applied to first step = %q, want north", pos)
+	}
+	for i, want := range []int{0, 0, 90} {
+		if q := pipeline.Steps[i].Options.Quality; q != want {
+			t.Errorf("step %d quality = %d, want %d", i, q, want)
+		}
+	}
+	for i, step := range pipeline.Steps {
+		if step.Options.Page != 2 {
+			t.Errorf("step %d page = %d, want 2", i, step.Options.Page)
+		}
+	}
+}
+
+func TestParsePipeline_QualityAppliesToOutput(t *testing.T) {
+	want := TransformStep{Processor: "resize", Options: TransformOptions{Width: 800, Quality: 80, Format: "webp"}}
+
+	for _, input := range []string{"w_800,q_80,f_webp", "q_80,w_800,f_webp", "w_800,f_webp,q_80"} {
+		pipeline, err := ParsePipeline(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if len(pipeline.Steps) != 1 || pipeline.Steps[0] != want {
+			t.Errorf("%s: steps = %+v, want a single encode %+v", input, pipeline.Steps, want)
+			continue
+		}
+		if got := pipeline.CacheKey(); got != want.Options.CacheKey() {
+			t.Errorf("%s: cache key depends on where q_ appears", input)
+		}
+	}
+}
+
+func TestTransformPipeline_CacheKey(t *testing.T) {
+	single, _ := ParsePipeline("w_800,q_85")
+	if got, want := single.CacheKey(), (&TransformOptions{Width: 800, Quality: 85}).CacheKey(); got != want {
+		t.Errorf("single step cache key = %q, want flat key %q", got, want)
+	}
+
+	a, _ := ParsePipeline("w_800,f_webp")
+	b, _ := ParsePipeline("f_webp,w_800")
+	c, _ := ParsePipeline("w_800,f_webp")
+	if a.CacheKey() == b.CacheKey() {
+		t.Error("reordered pipelines should have different cache keys")
+	}
+	if a.CacheKey() != c.CacheKey() {
+		t.Error("identical pipelines should have the same cache key")
+	}
+	if a.CacheKey() == single.CacheKey() {
+		t.Error("chain cache key should differ from its first step")
+	}
+}
+
+func TestTransformPipeline_ProcessorSteps(t *testing.T) {
+	resize, _ := ParsePipeline("w_400,f_webp,q_70")
+	steps := resize.ProcessorSteps("application/pdf")
+	if len(steps) != 2 || steps[0].Processor != "pdf_thumbnail" || steps[0].Options.Width != 400 || steps[0].Options.Format != "" ||
+		steps[1].Processor != "webp" || steps[1].Options.Quality != 70 {
+		t.Errorf("pdf with leading resize = %+v, want pdf_thumbnail(w=400), webp(q=70)", steps)
+	}
+	if steps := resize.ProcessorSteps("image/jpeg"); len(steps) != 1 || steps[0].Processor != "resize" || steps[0].Options.Format != "webp" {
+		t.Errorf("image steps = %+v, want resize to webp", steps)
+	}
+
+	watermark, _ := ParsePipeline("wm_foo,p_3")
+	steps = watermark.ProcessorSteps("application/pdf")
+	if len(steps) != 2 || steps[0].Processor != "pdf_thumbnail" || steps[0].Options.Page != 3 {
+		t.Errorf("pdf with leading watermark = %+v, want pdf_thumbnail(p=3), watermark", steps)
+	}
+
+	if steps := watermark.ProcessorSteps("image/jpeg"); len(steps) != 1 || steps[0].Processor != "watermark" {
+		t.Errorf("image steps = %+v, want watermark only", steps)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		sourceType string
+		avif       bool
+		want       string
+	}{
+		{name: "avif preferred", accept: "image/avif,image/webp,image/*,*/*;q=0.8", sourceType: "image/jpeg", avif: true, want: "avif"},
+		{name: "avif disabled falls back to webp", accept: "image/avif,image/webp,*/*", sourceType: "image/jpeg", avif: false, want: "webp"},
+		{name: "webp only", accept: "image/webp,*/*", sourceType: "image/jpeg", avif: true, want: "webp"},
+		{name: "avif rejected with q=0", accept: "image/avif;q=0,image/webp", sourceType: "image/jpeg", avif: true, want: "webp"},
+		{name: "wildcard jpeg source", accept: "*/*", sourceType: "image/jpeg", avif: true, want: "jpeg"},
+		{name: "wildcard png source", accept: "image/*", sourceType: "image/png", avif: true, want: "png"},
+		{name: "no accept header", accept: "", sourceType: "image/gif", avif: true, want: "png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NegotiateFormat(tt.accept, tt.sourceType, tt.avif); got != tt.want {
+				t.Errorf("NegotiateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformPipeline_ResolveAutoFormat(t *testing.T) {
+	pipeline, err := ParsePipeline("w_800,f_auto,q_70")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pipeline.HasAutoFormat() {
+		t.Fatal("HasAutoFormat() = false, want true")
+	}
+
+	webp := *pipeline
+	webp.Steps = append([]TransformStep(nil), pipeline.Steps...)
+	webp.ResolveAutoFormat("webp")
+	if webp.HasAutoFormat() {
+		t.Error("HasAutoFormat() after resolve = true, want false")
+	}
+	if got := webp.Steps[0]; got.Processor != "resize" || got.Options.Format != "webp" {
+		t.Errorf("webp step = %+v, want resize to webp", got)
+	}
+
+	pipeline.ResolveAutoFormat("avif")
+	if got := pipeline.Steps[0]; got.Processor != "resize" || got.Options.Format != "avif" {
+		t.Errorf("avif step = %+v, want resize to avif", got)
+	}
+
+	formatOnly, _ := ParsePipeline("f_auto")
+	formatOnly.ResolveAutoFormat("webp")
+	if got := formatOnly.Steps[0].Processor; got != "webp" {
+		t.Errorf("format-only step processor = %q, want webp", got)
+	}
+	if webp.CacheKey() == pipeline.CacheKey() {
+		t.Error("negotiated formats should have different cache keys")
+	}
+
+	explicit, _ := ParsePipeline("w_800,f_avif,q_70")
+	if explicit.CacheKey() != pipeline.CacheKey() {
+		t.Error("f_auto resolved to avif should share the f_avif cache key")
+	}
+}
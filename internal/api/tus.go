@@ -0,0 +1,423 @@
+package api
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+)
+
+// tus 1.0 protocol constants. See https://tus.io/protocols/resumable-upload.
+const (
+	tusVersion          = "1.0.0"
+	tusExtensions       = "creation,termination,checksum,expiration"
+	tusChecksumAlgos    = "sha1,sha256,md5"
+	tusOffsetOctet      = "application/offset+octet-stream"
+	tusStatusChecksum   = 460
+	tusBasePath         = "/v1/tus"
+	tusAllowHeaders     = "Accept, Authorization, Content-Type, Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset, Upload-Checksum, X-HTTP-Method-Override"
+	tusExposeHeaders    = "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm, Upload-Offset, Upload-Length, Upload-Expires, X-File-ID"
+	tusAllowMethods     = "POST, HEAD, PATCH, DELETE, OPTIONS"
+	tusDefaultMaxUpload = 100 * 1024 * 1024
+	// tusMaxChunkSize caps a single PATCH body; clients split larger
+	// uploads across several PATCH requests.
+	tusMaxChunkSize = 64 * 1024 * 1024
+	// tusLockTTL bounds how long a PATCH holds its session lock, so a
+	// crashed replica cannot block an upload forever.
+	tusLockTTL = 5 * time.Minute
+)
+
+// TusMiddleware handles CORS, OPTIONS capability discovery and protocol
+// version negotiation for the tus endpoints. OPTIONS requests are answered
+// before authentication, as the protocol requires.
+func TusMiddleware(maxSize int64) func(http.Handler) http.Handler {
+	if maxSize <= 0 {
+		maxSize = tusDefaultMaxUpload
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && allowedOrigins[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Expose-Headers", tusExposeHeaders)
+			}
+			w.Header().Set("Tus-Resumable", tusVersion)
+
+			if r.Method == http.MethodOptions {
+				if r.Header.Get("Access-Control-Request-Method") != "" {
+					w.Header().Set("Access-Control-Allow-Methods", tusAllowMethods)
+					w.Header().Set("Access-Control-Allow-Headers", tusAllowHeaders)
+					w.Header().Set("Access-Control-Max-Age", "3600")
+				}
+				w.Header().Set("Tus-Version", tusVersion)
+				w.Header().Set("Tus-Extension", tusExtensions)
+				w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+				w.Header().Set("Tus-Checksum-Algorithm", tusChecksumAlgos)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && r.Method == http.MethodPost {
+				r.Method = strings.ToUpper(override)
+			}
+
+			if r.Header.Get("Tus-Resumable") != tusVersion {
+				w.Header().Set("Tus-Version", tusVersion)
+				http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma separated pairs
+// of a key and an optional base64 encoded value.
+func parseTusMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("metadata %q: %w", key, err)
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// parseTusChecksum returns a hash and the expected digest for an
+// Upload-Checksum header such as "sha1 Kq5sNclPz7QV2+lfQIuc6R7oRu0=".
+func parseTusChecksum(header string) (hash.Hash, []byte, error) {
+	algo, encoded, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, nil, errors.New("malformed Upload-Checksum")
+	}
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("malformed Upload-Checksum: %w", err)
+	}
+	switch algo {
+	case "sha1":
+		return sha1.New(), digest, nil
+	case "sha256":
+		return sha256.New(), digest, nil
+	case "md5":
+		return md5.New(), digest, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported checksum algorithm %q", algo)
+}
+
+func tusExpiry(session *uploadSession) time.Time {
+	return session.CreatedAt.Add(uploadSessionTTL)
+}
+
+// loadTusSession fetches the session named in the path and checks ownership
+// and expiry, writing the error response when it returns false.
+func loadTusSession(w http.ResponseWriter, r *http.Request, cfg *ChunkedUploadConfig) (*uploadSession, bool) {
+	userID, ok := GetUserID(r.Context())
+	if !ok {
+		apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+		return nil, false
+	}
+
+	session, err := cfg.sessions().Get(r.Context(), r.PathValue("uploadId"))
+	if err != nil {
+		writeSessionLookupError(w, r, err)
+		return nil, false
+	}
+	if session.UserID != userID {
+		apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "session_not_found", "Upload session not found", http.StatusNotFound))
+		return nil, false
+	}
+	if time.Now().After(tusExpiry(session)) {
+		apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "upload_expired", "Upload has expired", http.StatusGone))
+		return nil, false
+	}
+	return session, true
+}
+
+// TusCreateHandler implements the creation extension
+func TusCreateHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length <= 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_upload_length", "A positive Upload-Length is required", http.StatusBadRequest))
+			return
+		}
+		if cfg.MaxUploadSize > 0 && length > cfg.MaxUploadSize {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "file_too_large", "Upload-Length exceeds Tus-Max-Size", http.StatusRequestEntityTooLarge))
+			return
+		}
+
+		meta, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_upload_metadata", "Invalid Upload-Metadata", http.StatusBadRequest))
+			return
+		}
+
+		filename := meta["filename"]
+		if filename == "" {
+			filename = meta["name"]
+		}
+		if filename == "" {
+			filename = "upload"
+		}
+		if IsBlockedExtension(filename) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "blocked_file_type",
+				"This file type is not allowed for security reasons", http.StatusBadRequest))
+			return
+		}
+		filename = SanitizeFilename(filename)
+
+		contentType := meta["filetype"]
+		if contentType == "" {
+			contentType = meta["type"]
+		}
+
+		if appErr := checkUploadQuota(r.Context(), cfg, userID, contentType, length); appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
+
+		placement, appErr := cfg.parsePlacement(r.Context(), userID, meta["folder_id"], splitTags(meta["tags"]))
+		if appErr != nil {
+			apperror.WriteJSON(w, r, appErr)
+			return
+		}
+
+		uploadID := uuid.New().String()
+		session := &uploadSession{
+			ID:           uploadID,
+			UserID:       userID,
+			Filename:     filename,
+			ContentType:  contentType,
+			TotalSize:    length,
+			ChunksLoaded: make(map[int]bool),
+			StorageKey:   fmt.Sprintf("uploads/%s/%s/%s", userID.String(), uploadID, filename),
+			CreatedAt:    time.Now(),
+			FolderID:     meta["folder_id"],
+			Tags:         placement.Tags,
+		}
+		if err := cfg.sessions().Set(r.Context(), session); err != nil {
+			log.Error("failed to save upload session", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		log.Info("tus upload created", "upload_id", uploadID, "filename", filename, "total_size", length)
+
+		w.Header().Set("Location", tusBasePath+"/"+uploadID)
+		w.Header().Set("Upload-Expires", tusExpiry(session).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// TusHeadHandler reports the current offset of an upload
+func TusHeadHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, ok := loadTusSession(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(session.TotalSize, 10))
+		w.Header().Set("Upload-Expires", tusExpiry(session).UTC().Format(http.TimeFormat))
+		if session.FileID != "" {
+			w.Header().Set("X-File-ID", session.FileID)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// TusPatchHandler appends data to an upload. Each PATCH body is streamed to
+// storage as the next chunk object, and the final one assembles the file
+// through the same path as the chunked upload API. PATCHes to one session are
+// serialized with a session lock; a concurrent PATCH is rejected with 423.
+func TusPatchHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		if r.Header.Get("Content-Type") != tusOffsetOctet {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_content_type", "Content-Type must be "+tusOffsetOctet, http.StatusUnsupportedMediaType))
+			return
+		}
+
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_upload_offset", "Invalid Upload-Offset", http.StatusBadRequest))
+			return
+		}
+
+		if r.ContentLength < 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "length_required", "Content-Length is required", http.StatusLengthRequired))
+			return
+		}
+		if r.ContentLength > tusMaxChunkSize {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "chunk_too_large",
+				fmt.Sprintf("PATCH body may not exceed %d bytes", tusMaxChunkSize), http.StatusRequestEntityTooLarge))
+			return
+		}
+
+		var checksum hash.Hash
+		var expected []byte
+		if header := r.Header.Get("Upload-Checksum"); header != "" {
+			checksum, expected, err = parseTusChecksum(header)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_checksum", err.Error(), http.StatusBadRequest))
+				return
+			}
+		}
+
+		session, ok := loadTusSession(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		unlock, err := cfg.sessions().Lock(r.Context(), session.ID, tusLockTTL)
+		if err != nil {
+			if errors.Is(err, errUploadSessionLocked) {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "upload_locked", "Another request is writing to this upload", http.StatusLocked))
+				return
+			}
+			log.Error("failed to lock upload session", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+		defer unlock()
+
+		// Reload under the lock so the offset reflects any PATCH that
+		// finished while this one was waiting.
+		session, err = cfg.sessions().Get(r.Context(), session.ID)
+		if err != nil {
+			writeSessionLookupError(w, r, err)
+			return
+		}
+
+		if offset != session.Offset {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "offset_mismatch",
+				fmt.Sprintf("Upload-Offset %d does not match current offset %d", offset, session.Offset), http.StatusConflict))
+			return
+		}
+
+		remaining := session.TotalSize - session.Offset
+		if remaining <= 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "upload_complete", "Upload is already complete", http.StatusForbidden))
+			return
+		}
+		if r.ContentLength > remaining {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "upload_length_exceeded", "Request body exceeds Upload-Length", http.StatusRequestEntityTooLarge))
+			return
+		}
+
+		if r.ContentLength > 0 {
+			var body io.Reader = io.LimitReader(r.Body, r.ContentLength)
+			if checksum != nil {
+				body = io.TeeReader(body, checksum)
+			}
+			counted := storage.NewHashingReader(body)
+
+			chunkKey := fmt.Sprintf("%s.chunk.%d", session.StorageKey, session.ChunksTotal)
+			if err := cfg.Storage.Upload(r.Context(), chunkKey, counted, "application/octet-stream", r.ContentLength); err != nil {
+				log.Error("failed to store tus chunk", "error", err)
+				_ = cfg.Storage.Delete(r.Context(), chunkKey)
+				apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+				return
+			}
+			if counted.BytesRead() != r.ContentLength {
+				_ = cfg.Storage.Delete(r.Context(), chunkKey)
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "read_chunk_failed", "Request body is shorter than Content-Length", http.StatusBadRequest))
+				return
+			}
+			if checksum != nil && !bytes.Equal(checksum.Sum(nil), expected) {
+				_ = cfg.Storage.Delete(r.Context(), chunkKey)
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "checksum_mismatch", "Checksum mismatch", tusStatusChecksum))
+				return
+			}
+			session.ChunksTotal++
+			session.Offset += r.ContentLength
+		}
+
+		if session.Offset == session.TotalSize {
+			fileID, err := assembleChunks(r.Context(), cfg, session, log)
+			if err != nil {
+				var appErr *apperror.Error
+				if errors.As(err, &appErr) {
+					_ = cfg.sessions().Delete(r.Context(), session.ID)
+					apperror.WriteJSON(w, r, appErr)
+					return
+				}
+				log.Error("failed to assemble tus upload", "error", err)
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "assemble_failed", "Failed to assemble file", http.StatusInternalServerError))
+				return
+			}
+			session.FileID = fileID
+			w.Header().Set("X-File-ID", fileID)
+			log.Info("tus upload complete", "upload_id", session.ID, "file_id", fileID)
+			recordUpload(r, session.UserID, fileID, session.Filename, session.ContentType, session.TotalSize, "tus")
+		}
+
+		// Completed sessions are kept until they expire so clients that
+		// resume a finished upload see the final offset.
+		if err := cfg.sessions().Set(r.Context(), session); err != nil {
+			log.Error("failed to save upload session", "error", err)
+			apperror.WriteJSON(w, r, apperror.Wrap(err, apperror.ErrInternal))
+			return
+		}
+
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		w.Header().Set("Upload-Expires", tusExpiry(session).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TusDeleteHandler implements the termination extension
+func TusDeleteHandler(cfg *ChunkedUploadConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, ok := loadTusSession(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		if session.FileID == "" {
+			deleteChunks(r.Context(), cfg.Storage, session)
+		}
+		if err := cfg.sessions().Delete(r.Context(), session.ID); err != nil {
+			logger.FromContext(r.Context()).Error("failed to delete upload session", "error", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTusTestRouter(t *testing.T) (http.Handler, *MockStorage, *MockQuerier) {
+	t.Helper()
+	queries, store, broker, cfg := setupTestDeps(t)
+	router := NewRouter(&Config{
+		Storage:        store,
+		Queries:        queries,
+		Broker:         broker,
+		MaxUploadSize:  cfg.MaxUploadSize,
+		JWTSecret:      cfg.JWTSecret,
+		UploadSessions: newMemoryUploadSessionStore(),
+	})
+	return router, store, queries
+}
+
+func tusRequest(t *testing.T, method, target string, body string, headers map[string]string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"), time.Hour))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func TestTusOptions(t *testing.T) {
+	router, _, _ := newTusTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/tus", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	for header, want := range map[string]string{
+		"Tus-Version":            tusVersion,
+		"Tus-Extension":          tusExtensions,
+		"Tus-Checksum-Algorithm": tusChecksumAlgos,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if rec.Header().Get("Tus-Max-Size") == "" {
+		t.Error("Tus-Max-Size header missing")
+	}
+}
+
+func TestTusVersionMismatch(t *testing.T) {
+	router, _, _ := newTusTestRouter(t)
+
+	req := tusRequest(t, http.MethodPost, "/v1/tus", "", map[string]string{"Upload-Length": "10"})
+	req.Header.Set("Tus-Resumable", "0.2.2")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestTusUploadFlow(t *testing.T) {
+	router, store, _ := newTusTestRouter(t)
+	content := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 24)
+	first, second := content[:12], content[12:]
+
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("photo.png")) +
+		",filetype " + base64.StdEncoding.EncodeToString([]byte("image/png"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPost, "/v1/tus", "", map[string]string{
+		"Upload-Length":   "32",
+		"Upload-Metadata": metadata,
+	}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/v1/tus/") {
+		t.Fatalf("Location = %q", location)
+	}
+	if rec.Header().Get("Upload-Expires") == "" {
+		t.Error("Upload-Expires header missing")
+	}
+
+	patch := func(offset, body string, extra map[string]string) *httptest.ResponseRecorder {
+		headers := map[string]string{
+			"Content-Type":  tusOffsetOctet,
+			"Upload-Offset": offset,
+		}
+		for k, v := range extra {
+			headers[k] = v
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, tusRequest(t, http.MethodPatch, location, body, headers))
+		return rec
+	}
+
+	if rec := patch("0", first, map[string]string{"Upload-Checksum": "sha1 " + base64.StdEncoding.EncodeToString([]byte("wrong digest"))}); rec.Code != tusStatusChecksum {
+		t.Fatalf("bad checksum status = %d, want %d", rec.Code, tusStatusChecksum)
+	}
+
+	sum := sha1.Sum([]byte(first))
+	rec = patch("0", first, map[string]string{"Upload-Checksum": "sha1 " + base64.StdEncoding.EncodeToString(sum[:])})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("first patch status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Upload-Offset"); got != "12" {
+		t.Errorf("Upload-Offset = %q, want 12", got)
+	}
+
+	if rec := patch("0", second, nil); rec.Code != http.StatusConflict {
+		t.Errorf("stale offset status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodHead, location, "", nil))
+	if got := rec.Header().Get("Upload-Offset"); rec.Code != http.StatusOK || got != "12" {
+		t.Errorf("HEAD status = %d, Upload-Offset = %q, want 200 and 12", rec.Code, got)
+	}
+
+	rec = patch("12", second, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("final patch status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-File-ID") == "" {
+		t.Error("X-File-ID header missing after final patch")
+	}
+	if store.Count() != 1 {
+		t.Errorf("storage has %d objects, want only the assembled file", store.Count())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodHead, location, "", nil))
+	if got := rec.Header().Get("Upload-Offset"); got != "32" {
+		t.Errorf("HEAD after completion Upload-Offset = %q, want 32", got)
+	}
+}
+
+func TestTusCreate_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"missing length", map[string]string{}, http.StatusBadRequest},
+		{"too large", map[string]string{"Upload-Length": "999999999999"}, http.StatusRequestEntityTooLarge},
+		{"bad metadata", map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename !!!"}, http.StatusBadRequest},
+		{"blocked extension", map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("run.exe"))}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, _ := newTusTestRouter(t)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, tusRequest(t, http.MethodPost, "/v1/tus", "", tt.headers))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTusTermination(t *testing.T) {
+	router, store, _ := newTusTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPost, "/v1/tus", "", map[string]string{"Upload-Length": "20"}))
+	location := rec.Header().Get("Location")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPatch, location, "0123456789", map[string]string{
+		"Content-Type":  tusOffsetOctet,
+		"Upload-Offset": "0",
+	}))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("patch status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodDelete, location, "", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if store.Count() != 0 {
+		t.Errorf("storage has %d chunks after termination, want 0", store.Count())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodHead, location, "", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("HEAD after delete status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestTusPatch_ConcurrentRequests(t *testing.T) {
+	router, _, _ := newTusTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPost, "/v1/tus", "", map[string]string{"Upload-Length": "20"}))
+	location := rec.Header().Get("Location")
+	headers := map[string]string{"Content-Type": tusOffsetOctet, "Upload-Offset": "0"}
+
+	// The first PATCH blocks while streaming its body, holding the lock
+	pr, pw := io.Pipe()
+	first := tusRequest(t, http.MethodPatch, location, "", headers)
+	first.Body, first.ContentLength = pr, 10
+	firstRec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(firstRec, first)
+	}()
+	if _, err := pw.Write([]byte("01234")); err != nil {
+		t.Fatalf("write first body: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPatch, location, "abcdefghij", headers))
+	if rec.Code != http.StatusLocked {
+		t.Errorf("concurrent patch status = %d, want %d; body = %s", rec.Code, http.StatusLocked, rec.Body.String())
+	}
+
+	_, _ = pw.Write([]byte("56789"))
+	_ = pw.Close()
+	<-done
+	if firstRec.Code != http.StatusNoContent {
+		t.Fatalf("first patch status = %d; body = %s", firstRec.Code, firstRec.Body.String())
+	}
+
+	// Once the lock is released, a retry at the old offset is stale
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPatch, location, "abcdefghij", headers))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("retried patch status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodHead, location, "", nil))
+	if got := rec.Header().Get("Upload-Offset"); got != "10" {
+		t.Errorf("Upload-Offset = %q, want 10", got)
+	}
+}
+
+func TestTusPatch_ChunkTooLarge(t *testing.T) {
+	router, _, _ := newTusTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, tusRequest(t, http.MethodPost, "/v1/tus", "", map[string]string{"Upload-Length": "20"}))
+	location := rec.Header().Get("Location")
+
+	req := tusRequest(t, http.MethodPatch, location, "0123456789", map[string]string{"Content-Type": tusOffsetOctet, "Upload-Offset": "0"})
+	req.ContentLength = tusMaxChunkSize + 1
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,460 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/uploadrules"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type UploadRuleQuerier interface {
+	CreateUploadRule(ctx context.Context, arg db.CreateUploadRuleParams) (db.UploadRule, error)
+	GetUploadRule(ctx context.Context, arg db.GetUploadRuleParams) (db.UploadRule, error)
+	ListUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error)
+	ListEnabledUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error)
+	UpdateUploadRule(ctx context.Context, arg db.UpdateUploadRuleParams) (db.UploadRule, error)
+	DeleteUploadRule(ctx context.Context, arg db.DeleteUploadRuleParams) error
+	GetFolder(ctx context.Context, arg db.GetFolderParams) (db.Folder, error)
+	GetFile(ctx context.Context, id pgtype.UUID) (db.File, error)
+	ListTagsByFile(ctx context.Context, fileID pgtype.UUID) ([]db.FileTag, error)
+}
+
+type UploadRuleConfig struct {
+	Queries UploadRuleQuerier
+}
+
+// UploadRuleRequest defines an upload rule. Conditions left empty match any
+// upload.
+type UploadRuleRequest struct {
+	Name            string   `json:"name"`
+	ContentType     string   `json:"content_type,omitempty"`
+	FolderID        string   `json:"folder_id,omitempty"`
+	Tag             string   `json:"tag,omitempty"`
+	FilenamePattern string   `json:"filename_pattern,omitempty"`
+	Jobs            []string `json:"jobs"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+}
+
+type UploadRuleResponse struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	ContentType     *string  `json:"content_type,omitempty"`
+	FolderID        *string  `json:"folder_id,omitempty"`
+	Tag             *string  `json:"tag,omitempty"`
+	FilenamePattern *string  `json:"filename_pattern,omitempty"`
+	Jobs            []string `json:"jobs"`
+	Enabled         bool     `json:"enabled"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
+}
+
+// TestUploadRulesRequest describes the upload to evaluate: either an existing
+// file or the attributes of a hypothetical one.
+type TestUploadRulesRequest struct {
+	FileID      string   `json:"file_id,omitempty"`
+	Filename    string   `json:"filename,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	FolderID    string   `json:"folder_id,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// uploadRuleParams validates a rule request and converts it to its stored
+// form.
+func uploadRuleParams(ctx context.Context, q UploadRuleQuerier, userID uuid.UUID, req *UploadRuleRequest) (db.CreateUploadRuleParams, error) {
+	params := db.CreateUploadRuleParams{
+		UserID:  pgtype.UUID{Bytes: userID, Valid: true},
+		Enabled: true,
+	}
+
+	params.Name = strings.TrimSpace(req.Name)
+	if params.Name == "" || len(params.Name) > 100 {
+		return params, errors.New("name must be 1-100 characters")
+	}
+	if req.Enabled != nil {
+		params.Enabled = *req.Enabled
+	}
+
+	if ct := strings.ToLower(strings.TrimSpace(req.ContentType)); ct != "" {
+		if major, minor, ok := strings.Cut(ct, "/"); !ok || major == "" || minor == "" {
+			return params, errors.New("content_type must look like image/png or image/*")
+		}
+		params.ContentType = &ct
+	}
+
+	if req.FolderID != "" {
+		folderID, err := uuid.Parse(req.FolderID)
+		if err != nil {
+			return params, errors.New("invalid folder_id")
+		}
+		params.FolderID = pgtype.UUID{Bytes: folderID, Valid: true}
+		if _, err := q.GetFolder(ctx, db.GetFolderParams{ID: params.FolderID, UserID: params.UserID}); err != nil {
+			return params, errors.New("folder not found")
+		}
+	}
+
+	if tag := strings.TrimSpace(req.Tag); tag != "" {
+		if len(tag) > 100 {
+			return params, errors.New("tag must be at most 100 characters")
+		}
+		params.Tag = &tag
+	}
+
+	if pattern := strings.TrimSpace(req.FilenamePattern); pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return params, errors.New("invalid filename_pattern")
+		}
+		params.FilenamePattern = &pattern
+	}
+
+	if len(req.Jobs) == 0 {
+		return params, errors.New("at least one job is required")
+	}
+	for _, job := range req.Jobs {
+		job = strings.ToLower(strings.TrimSpace(job))
+		if !uploadrules.ValidJob(job) {
+			return params, errors.New("unknown job: " + job + " (valid: " + strings.Join(uploadrules.Jobs, ", ") + ")")
+		}
+		params.Jobs = append(params.Jobs, job)
+	}
+	return params, nil
+}
+
+func ListUploadRulesHandler(cfg *UploadRuleConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		rules, err := cfg.Queries.ListUploadRulesByUser(r.Context(), pgtype.UUID{Bytes: userID, Valid: true})
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		results := make([]UploadRuleResponse, len(rules))
+		for i, rule := range rules {
+			results[i] = uploadRuleToResponse(rule)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"rules": results,
+		})
+	}
+}
+
+func CreateUploadRuleHandler(cfg *UploadRuleConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		var req UploadRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid JSON request body", http.StatusBadRequest))
+			return
+		}
+
+		params, err := uploadRuleParams(r.Context(), cfg.Queries, userID, &req)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_rule", err.Error(), http.StatusBadRequest))
+			return
+		}
+
+		rule, err := cfg.Queries.CreateUploadRule(r.Context(), params)
+		if err != nil {
+			log.Error("failed to create upload rule", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(uploadRuleToResponse(rule))
+	}
+}
+
+func GetUploadRuleHandler(cfg *UploadRuleConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		ruleID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_rule_id", "Invalid rule ID format", http.StatusBadRequest))
+			return
+		}
+
+		rule, err := cfg.Queries.GetUploadRule(r.Context(), db.GetUploadRuleParams{
+			ID:     pgtype.UUID{Bytes: ruleID, Valid: true},
+			UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		})
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(uploadRuleToResponse(rule))
+	}
+}
+
+func UpdateUploadRuleHandler(cfg *UploadRuleConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		ruleID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_rule_id", "Invalid rule ID format", http.StatusBadRequest))
+			return
+		}
+
+		var req UploadRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid JSON request body", http.StatusBadRequest))
+			return
+		}
+
+		params, err := uploadRuleParams(r.Context(), cfg.Queries, userID, &req)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_rule", err.Error(), http.StatusBadRequest))
+			return
+		}
+
+		rule, err := cfg.Queries.UpdateUploadRule(r.Context(), db.UpdateUploadRuleParams{
+			ID:              pgtype.UUID{Bytes: ruleID, Valid: true},
+			UserID:          params.UserID,
+			Name:            params.Name,
+			ContentType:     params.ContentType,
+			FolderID:        params.FolderID,
+			Tag:             params.Tag,
+			FilenamePattern: params.FilenamePattern,
+			Jobs:            params.Jobs,
+			Enabled:         params.Enabled,
+		})
+		if err != nil {
+			log.Debug("failed to update upload rule", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(uploadRuleToResponse(rule))
+	}
+}
+
+func DeleteUploadRuleHandler(cfg *UploadRuleConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		ruleID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_rule_id", "Invalid rule ID format", http.StatusBadRequest))
+			return
+		}
+
+		if err := cfg.Queries.DeleteUploadRule(r.Context(), db.DeleteUploadRuleParams{
+			ID:     pgtype.UUID{Bytes: ruleID, Valid: true},
+			UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		}); err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestUploadRulesHandler reports which rules match an upload and the jobs it
+// would queue, without queueing anything.
+func TestUploadRulesHandler(cfg *UploadRuleConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+
+		var req TestUploadRulesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid JSON request body", http.StatusBadRequest))
+			return
+		}
+
+		var file uploadrules.File
+		if req.FileID != "" {
+			fileID, err := uuid.Parse(req.FileID)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_file_id", "Invalid file ID format", http.StatusBadRequest))
+				return
+			}
+			dbFile, err := cfg.Queries.GetFile(r.Context(), pgtype.UUID{Bytes: fileID, Valid: true})
+			if err != nil || dbFile.UserID != pgUserID || dbFile.DeletedAt.Valid {
+				apperror.WriteJSON(w, r, apperror.ErrNotFound)
+				return
+			}
+			tags, err := cfg.Queries.ListTagsByFile(r.Context(), dbFile.ID)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.ErrInternal)
+				return
+			}
+			file = uploadrules.File{ID: fileID, Filename: dbFile.Filename, ContentType: dbFile.ContentType, FolderID: dbFile.FolderID}
+			for _, tag := range tags {
+				file.Tags = append(file.Tags, tag.TagName)
+			}
+		} else {
+			if req.Filename == "" || req.ContentType == "" {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "missing_required_fields",
+					"Either file_id or filename and content_type are required", http.StatusBadRequest))
+				return
+			}
+			file = uploadrules.File{Filename: req.Filename, ContentType: req.ContentType, Tags: req.Tags}
+			if req.FolderID != "" {
+				folderID, err := uuid.Parse(req.FolderID)
+				if err != nil {
+					apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_folder_id", "Invalid folder ID", http.StatusBadRequest))
+					return
+				}
+				file.FolderID = pgtype.UUID{Bytes: folderID, Valid: true}
+			}
+		}
+
+		rules, err := cfg.Queries.ListEnabledUploadRulesByUser(r.Context(), pgUserID)
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		var tier db.SubscriptionTier
+		if billingInfo := GetBilling(r.Context()); billingInfo != nil {
+			tier = billingInfo.Tier
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(uploadrules.BuildPlan(rules, file, tier))
+	}
+}
+
+func uploadRuleToResponse(rule db.UploadRule) UploadRuleResponse {
+	resp := UploadRuleResponse{
+		ID:              uuidFromPgtype(rule.ID),
+		Name:            rule.Name,
+		ContentType:     rule.ContentType,
+		Tag:             rule.Tag,
+		FilenamePattern: rule.FilenamePattern,
+		Jobs:            rule.Jobs,
+		Enabled:         rule.Enabled,
+		CreatedAt:       rule.CreatedAt.Time.Format(time.RFC3339),
+		UpdatedAt:       rule.UpdatedAt.Time.Format(time.RFC3339),
+	}
+	if rule.FolderID.Valid {
+		folderID := uuidFromPgtype(rule.FolderID)
+		resp.FolderID = &folderID
+	}
+	return resp
+}
+
+// uploadPlacement is the folder and tags an upload asks to be filed under,
+// set before the upload rules are evaluated so rules can match on them.
+type uploadPlacement struct {
+	FolderID pgtype.UUID
+	Tags     []string
+}
+
+// parseUploadPlacement validates the folder_id and tags sent with an upload.
+// The folder must belong to the user.
+func parseUploadPlacement(ctx context.Context, q Querier, userID uuid.UUID, folderID string, tags []string) (uploadPlacement, *apperror.Error) {
+	var p uploadPlacement
+
+	if folderID != "" {
+		id, err := uuid.Parse(folderID)
+		if err != nil {
+			return p, apperror.WrapWithMessage(err, "invalid_folder_id", "Invalid folder ID", http.StatusBadRequest)
+		}
+		p.FolderID = pgtype.UUID{Bytes: id, Valid: true}
+		if _, err := q.GetFolder(ctx, db.GetFolderParams{ID: p.FolderID, UserID: pgtype.UUID{Bytes: userID, Valid: true}}); err != nil {
+			return p, apperror.WrapWithMessage(err, "folder_not_found", "Folder not found", http.StatusNotFound)
+		}
+	}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > 100 {
+			return p, apperror.WrapWithMessage(nil, "invalid_tag", "Tags must be at most 100 characters", http.StatusBadRequest)
+		}
+		p.Tags = append(p.Tags, tag)
+	}
+	if len(p.Tags) > 20 {
+		return p, apperror.WrapWithMessage(nil, "too_many_tags", "Maximum 20 tags per upload", http.StatusBadRequest)
+	}
+	return p, nil
+}
+
+// splitTags splits a comma separated tags form value.
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// apply files a new upload under its folder and tags. Failures are logged so
+// the upload itself still succeeds.
+func (p uploadPlacement) apply(ctx context.Context, q Querier, userID uuid.UUID, fileID pgtype.UUID, log *slog.Logger) {
+	pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+	if p.FolderID.Valid {
+		if err := q.MoveFileToFolder(ctx, db.MoveFileToFolderParams{ID: fileID, UserID: pgUserID, FolderID: p.FolderID}); err != nil {
+			log.Error("failed to move upload to folder", "error", err)
+		}
+	}
+	for _, tag := range p.Tags {
+		if _, err := q.CreateFileTag(ctx, db.CreateFileTagParams{FileID: fileID, UserID: pgUserID, TagName: tag}); err != nil {
+			log.Debug("failed to tag upload", "tag", tag, "error", err)
+		}
+	}
+}
+
+// ruleFile describes a new upload for upload rule matching.
+func (p uploadPlacement) ruleFile(fileID uuid.UUID, filename, contentType string) uploadrules.File {
+	return uploadrules.File{
+		ID:          fileID,
+		Filename:    filename,
+		ContentType: contentType,
+		FolderID:    p.FolderID,
+		Tags:        p.Tags,
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/uploadrules"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func createTestUploadRule(userID uuid.UUID, name, contentType string, jobs ...string) db.UploadRule {
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return db.UploadRule{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:      pgtype.UUID{Bytes: userID, Valid: true},
+		Name:        name,
+		ContentType: &contentType,
+		Jobs:        jobs,
+		Enabled:     true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+func TestCreateUploadRuleHandler(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "valid rule",
+			body:       `{"name": "Images", "content_type": "image/*", "filename_pattern": "*.jpg", "jobs": ["responsive", "WebP"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing name",
+			body:       `{"jobs": ["webp"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_rule",
+		},
+		{
+			name:       "no jobs",
+			body:       `{"name": "empty"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_rule",
+		},
+		{
+			name:       "unknown job",
+			body:       `{"name": "bad", "jobs": ["sepia"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_rule",
+		},
+		{
+			name:       "invalid content type",
+			body:       `{"name": "bad", "content_type": "image", "jobs": ["webp"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_rule",
+		},
+		{
+			name:       "invalid filename pattern",
+			body:       `{"name": "bad", "filename_pattern": "[", "jobs": ["webp"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_rule",
+		},
+		{
+			name:       "invalid folder",
+			body:       `{"name": "bad", "folder_id": "nope", "jobs": ["webp"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_rule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CreateUploadRuleHandler(&UploadRuleConfig{Queries: NewMockQuerier()})
+
+			req := httptest.NewRequest("POST", "/v1/upload-rules", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("body = %s, want code %s", rec.Body.String(), tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusCreated {
+				var resp UploadRuleResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if !resp.Enabled {
+					t.Error("new rules should be enabled by default")
+				}
+				if want := []string{"responsive", "webp"}; !slices.Equal(resp.Jobs, want) {
+					t.Errorf("jobs = %v, want %v", resp.Jobs, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadRuleHandlers_OwnerScoped(t *testing.T) {
+	ownerID := uuid.New()
+	otherID := uuid.New()
+	queries := NewMockQuerier()
+	rule := createTestUploadRule(ownerID, "images", "image/*", "webp")
+	queries.AddUploadRule(rule)
+	cfg := &UploadRuleConfig{Queries: queries}
+
+	req := httptest.NewRequest("GET", "/v1/upload-rules/"+uuidFromPgtype(rule.ID), nil)
+	req.SetPathValue("id", uuidFromPgtype(rule.ID))
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, otherID))
+	rec := httptest.NewRecorder()
+	GetUploadRuleHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get as other user: status = %d, want 404", rec.Code)
+	}
+
+	req = httptest.NewRequest("PUT", "/v1/upload-rules/"+uuidFromPgtype(rule.ID), strings.NewReader(`{"name": "mine", "jobs": ["xl"]}`))
+	req.SetPathValue("id", uuidFromPgtype(rule.ID))
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, otherID))
+	rec = httptest.NewRecorder()
+	UpdateUploadRuleHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("update as other user: status = %d, want 404", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/upload-rules", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, ownerID))
+	rec = httptest.NewRecorder()
+	ListUploadRulesHandler(cfg).ServeHTTP(rec, req)
+
+	var resp struct {
+		Rules []UploadRuleResponse `json:"rules"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Rules) != 1 || resp.Rules[0].Name != "images" {
+		t.Errorf("rules = %+v, want the owner's rule", resp.Rules)
+	}
+}
+
+func TestTestUploadRulesHandler(t *testing.T) {
+	userID := uuid.New()
+	queries := NewMockQuerier()
+	queries.AddUploadRule(createTestUploadRule(userID, "images", "image/*", "webp", "metadata"))
+	queries.AddUploadRule(createTestUploadRule(userID, "videos", "video/*", "hls"))
+	file := createTestFile(userID, "photo.jpg")
+	queries.AddFile(file)
+	cfg := &UploadRuleConfig{Queries: queries}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantMatched []string
+		wantJobs    []string
+	}{
+		{
+			name:        "hypothetical image",
+			body:        `{"filename": "banner.png", "content_type": "image/png"}`,
+			wantStatus:  http.StatusOK,
+			wantMatched: []string{"images"},
+			wantJobs:    []string{"thumbnail", "metadata", "webp"},
+		},
+		{
+			name:        "existing file",
+			body:        `{"file_id": "` + uuidFromPgtype(file.ID) + `"}`,
+			wantStatus:  http.StatusOK,
+			wantMatched: []string{"images"},
+			wantJobs:    []string{"thumbnail", "metadata", "webp"},
+		},
+		{
+			name:        "no rules match",
+			body:        `{"filename": "doc.pdf", "content_type": "application/pdf"}`,
+			wantStatus:  http.StatusOK,
+			wantMatched: []string{},
+			wantJobs:    []string{"pdf_thumbnail"},
+		},
+		{
+			name:       "missing fields",
+			body:       `{"filename": "banner.png"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown file",
+			body:       `{"file_id": "` + uuid.New().String() + `"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/upload-rules/test", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+			rec := httptest.NewRecorder()
+			TestUploadRulesHandler(cfg).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var plan uploadrules.Plan
+			if err := json.NewDecoder(rec.Body).Decode(&plan); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !slices.Equal(plan.MatchedRules, tt.wantMatched) {
+				t.Errorf("matched = %v, want %v", plan.MatchedRules, tt.wantMatched)
+			}
+			var jobs []string
+			for _, j := range plan.Jobs {
+				jobs = append(jobs, string(j.JobType))
+			}
+			if !slices.Equal(jobs, tt.wantJobs) {
+				t.Errorf("jobs = %v, want %v", jobs, tt.wantJobs)
+			}
+		})
+	}
+}
+
+func TestUploadHandler_AppliesUploadRules(t *testing.T) {
+	userID := uuid.New()
+	queries, storage, broker, cfg := setupTestDeps(t)
+	rule := createTestUploadRule(userID, "tagged", "image/*", "webp")
+	tag := "marketing"
+	rule.Tag = &tag
+	queries.AddUploadRule(rule)
+
+	router := NewRouter(&Config{
+		Storage:       storage,
+		Queries:       queries,
+		Broker:        broker,
+		MaxUploadSize: cfg.MaxUploadSize,
+		JWTSecret:     cfg.JWTSecret,
+	})
+
+	upload := func(tags string) {
+		t.Helper()
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, err := writer.CreateFormFile("file", "photo.png")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		if err := png.Encode(part, image.NewRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+			t.Fatalf("failed to encode PNG: %v", err)
+		}
+		_ = writer.WriteField("tags", tags)
+		_ = writer.Close()
+
+		req := httptest.NewRequest("POST", "/v1/upload", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, userID, time.Hour))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("upload status = %d; body = %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	upload("holiday")
+	if !broker.HasJob("thumbnail") || broker.HasJob("webp") {
+		t.Error("untagged upload should only queue the default jobs")
+	}
+
+	upload("holiday, marketing")
+	if !broker.HasJob("webp") {
+		t.Error("tagged upload should queue the rule's webp job")
+	}
+}
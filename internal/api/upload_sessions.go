@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// uploadSessionTTL is how long a chunked upload may stay incomplete before
+// its session and chunks are cleaned up.
+const uploadSessionTTL = time.Hour
+
+var (
+	errUploadSessionNotFound = errors.New("upload session not found")
+	errUploadSessionLocked   = errors.New("upload session is locked")
+)
+
+// UploadSessionStore persists chunked upload sessions. Implementations shared
+// between API replicas let any instance accept the chunks of an upload.
+type UploadSessionStore interface {
+	Get(ctx context.Context, id string) (*uploadSession, error)
+	Set(ctx context.Context, session *uploadSession) error
+	// MarkChunkLoaded records a stored chunk and returns how many distinct
+	// chunks the session now has.
+	MarkChunkLoaded(ctx context.Context, id string, index int) (int, error)
+	Delete(ctx context.Context, id string) error
+	// Lock takes an exclusive lock on a session for at most ttl, so only
+	// one request at a time can append to it. It returns
+	// errUploadSessionLocked when another request holds the lock.
+	Lock(ctx context.Context, id string, ttl time.Duration) (unlock func(), err error)
+	// ClaimExpired removes and returns sessions created before cutoff. Each
+	// session is returned to exactly one caller.
+	ClaimExpired(ctx context.Context, cutoff time.Time) ([]*uploadSession, error)
+}
+
+// RunUploadSessionCleanup periodically removes expired sessions and their
+// orphaned chunks until ctx is cancelled.
+func RunUploadSessionCleanup(ctx context.Context, sessions UploadSessionStore, storageClient storage.Storage) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cleanupExpiredSessions(ctx, sessions, storageClient)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func cleanupExpiredSessions(ctx context.Context, sessions UploadSessionStore, storageClient storage.Storage) {
+	expired, err := sessions.ClaimExpired(ctx, time.Now().Add(-uploadSessionTTL))
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to claim expired upload sessions", "error", err)
+		return
+	}
+	for _, session := range expired {
+		deleteChunks(ctx, storageClient, session)
+		if session.Direct {
+			_ = storageClient.Delete(ctx, session.StorageKey)
+		}
+	}
+}
+
+// memoryUploadSessionStore keeps sessions in process memory. It is only
+// suitable for a single API instance and for tests.
+type memoryUploadSessionStore struct {
+	sessions map[string]*uploadSession
+	locks    map[string]time.Time
+	mu       sync.RWMutex
+}
+
+var _ UploadSessionStore = (*memoryUploadSessionStore)(nil)
+
+func newMemoryUploadSessionStore() *memoryUploadSessionStore {
+	return &memoryUploadSessionStore{
+		sessions: make(map[string]*uploadSession),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+// defaultSessionStore backs ChunkedUploadConfig values without a store.
+var defaultSessionStore = newMemoryUploadSessionStore()
+
+func (s *memoryUploadSessionStore) Get(ctx context.Context, id string) (*uploadSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, errUploadSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *memoryUploadSessionStore) Set(ctx context.Context, session *uploadSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session.ChunksLoaded == nil {
+		session.ChunksLoaded = make(map[int]bool)
+	}
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *memoryUploadSessionStore) MarkChunkLoaded(ctx context.Context, id string, index int) (int, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.ChunksLoaded[index] = true
+	return len(session.ChunksLoaded), nil
+}
+
+func (s *memoryUploadSessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memoryUploadSessionStore) Lock(ctx context.Context, id string, ttl time.Duration) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expires, held := s.locks[id]; held && time.Now().Before(expires) {
+		return nil, errUploadSessionLocked
+	}
+	expires := time.Now().Add(ttl)
+	s.locks[id] = expires
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.locks[id].Equal(expires) {
+			delete(s.locks, id)
+		}
+	}, nil
+}
+
+func (s *memoryUploadSessionStore) ClaimExpired(ctx context.Context, cutoff time.Time) ([]*uploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*uploadSession
+	for id, session := range s.sessions {
+		if session.CreatedAt.Before(cutoff) {
+			expired = append(expired, session)
+			delete(s.sessions, id)
+		}
+	}
+	return expired, nil
+}
+
+// RedisUploadSessionStore keeps sessions in Redis so chunk uploads can be
+// served by any API replica. Each session is a JSON document plus a set of
+// loaded chunk indexes; a sorted set indexed by creation time drives cleanup.
+type RedisUploadSessionStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+var _ UploadSessionStore = (*RedisUploadSessionStore)(nil)
+
+// NewRedisUploadSessionStore creates a Redis-backed session store. Keys
+// outlive uploadSessionTTL so the cleanup loop can still find the chunks of
+// abandoned sessions.
+func NewRedisUploadSessionStore(client *redis.Client) *RedisUploadSessionStore {
+	return &RedisUploadSessionStore{
+		client: client,
+		prefix: "upload_session:",
+		ttl:    2 * uploadSessionTTL,
+	}
+}
+
+func (s *RedisUploadSessionStore) sessionKey(id string) string {
+	return s.prefix + id
+}
+
+func (s *RedisUploadSessionStore) chunksKey(id string) string {
+	return s.prefix + id + ":chunks"
+}
+
+func (s *RedisUploadSessionStore) lockKey(id string) string {
+	return s.prefix + id + ":lock"
+}
+
+func (s *RedisUploadSessionStore) indexKey() string {
+	return s.prefix + "index"
+}
+
+func (s *RedisUploadSessionStore) Get(ctx context.Context, id string) (*uploadSession, error) {
+	pipe := s.client.Pipeline()
+	dataCmd := pipe.Get(ctx, s.sessionKey(id))
+	chunksCmd := pipe.SMembers(ctx, s.chunksKey(id))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("get upload session %s: %w", id, err)
+	}
+
+	data, err := dataCmd.Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errUploadSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get upload session %s: %w", id, err)
+	}
+
+	var session uploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("decode upload session %s: %w", id, err)
+	}
+
+	session.ChunksLoaded = make(map[int]bool)
+	for _, member := range chunksCmd.Val() {
+		if index, err := strconv.Atoi(member); err == nil {
+			session.ChunksLoaded[index] = true
+		}
+	}
+	return &session, nil
+}
+
+func (s *RedisUploadSessionStore) Set(ctx context.Context, session *uploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("encode upload session %s: %w", session.ID, err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.sessionKey(session.ID), data, s.ttl)
+	pipe.ZAdd(ctx, s.indexKey(), redis.Z{Score: float64(session.CreatedAt.Unix()), Member: session.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("save upload session %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+func (s *RedisUploadSessionStore) MarkChunkLoaded(ctx context.Context, id string, index int) (int, error) {
+	key := s.chunksKey(id)
+
+	pipe := s.client.TxPipeline()
+	pipe.SAdd(ctx, key, index)
+	countCmd := pipe.SCard(ctx, key)
+	pipe.Expire(ctx, key, s.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("mark chunk %d of upload session %s: %w", index, id, err)
+	}
+	return int(countCmd.Val()), nil
+}
+
+func (s *RedisUploadSessionStore) Delete(ctx context.Context, id string) error {
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.sessionKey(id), s.chunksKey(id))
+	pipe.ZRem(ctx, s.indexKey(), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("delete upload session %s: %w", id, err)
+	}
+	return nil
+}
+
+// unlockScript deletes a lock only while it still holds the caller's token,
+// so a request whose lock expired cannot release its successor's.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (s *RedisUploadSessionStore) Lock(ctx context.Context, id string, ttl time.Duration) (func(), error) {
+	key := s.lockKey(id)
+	token := uuid.NewString()
+	acquired, err := s.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("lock upload session %s: %w", id, err)
+	}
+	if !acquired {
+		return nil, errUploadSessionLocked
+	}
+	return func() {
+		// The request context may already be cancelled; release regardless.
+		_ = unlockScript.Run(context.WithoutCancel(ctx), s.client, []string{key}, token).Err()
+	}, nil
+}
+
+func (s *RedisUploadSessionStore) ClaimExpired(ctx context.Context, cutoff time.Time) ([]*uploadSession, error) {
+	ids, err := s.client.ZRangeByScore(ctx, s.indexKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(cutoff.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list expired upload sessions: %w", err)
+	}
+
+	var expired []*uploadSession
+	for _, id := range ids {
+		// Only the replica that removes the index entry cleans up the session.
+		removed, err := s.client.ZRem(ctx, s.indexKey(), id).Result()
+		if err != nil {
+			return expired, fmt.Errorf("claim upload session %s: %w", id, err)
+		}
+		if removed == 0 {
+			continue
+		}
+
+		session, err := s.Get(ctx, id)
+		if err == nil {
+			expired = append(expired, session)
+		}
+		_ = s.client.Del(ctx, s.sessionKey(id), s.chunksKey(id)).Err()
+	}
+	return expired, nil
+}
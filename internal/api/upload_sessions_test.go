@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemoryUploadSessionStore_MarkChunkLoaded(t *testing.T) {
+	store := newMemoryUploadSessionStore()
+	ctx := context.Background()
+
+	_ = store.Set(ctx, &uploadSession{ID: "s1", UserID: uuid.New(), ChunksTotal: 3})
+
+	for _, index := range []int{0, 2, 2} {
+		if _, err := store.MarkChunkLoaded(ctx, "s1", index); err != nil {
+			t.Fatalf("MarkChunkLoaded(%d) error = %v", index, err)
+		}
+	}
+
+	got, err := store.MarkChunkLoaded(ctx, "s1", 1)
+	if err != nil {
+		t.Fatalf("MarkChunkLoaded(1) error = %v", err)
+	}
+	if got != 3 {
+		t.Errorf("MarkChunkLoaded() = %d, want 3 distinct chunks", got)
+	}
+
+	if _, err := store.MarkChunkLoaded(ctx, "missing", 0); !errors.Is(err, errUploadSessionNotFound) {
+		t.Errorf("MarkChunkLoaded() on missing session error = %v, want errUploadSessionNotFound", err)
+	}
+}
+
+func TestCleanupExpiredSessions(t *testing.T) {
+	sessions := newMemoryUploadSessionStore()
+	store := NewMockStorage()
+	ctx := context.Background()
+
+	expired := &uploadSession{
+		ID:          "expired",
+		ChunksTotal: 2,
+		StorageKey:  "uploads/u/expired/file.bin",
+		CreatedAt:   time.Now().Add(-2 * uploadSessionTTL),
+	}
+	active := &uploadSession{
+		ID:          "active",
+		ChunksTotal: 1,
+		StorageKey:  "uploads/u/active/file.bin",
+		CreatedAt:   time.Now(),
+	}
+	for _, session := range []*uploadSession{expired, active} {
+		_ = sessions.Set(ctx, session)
+		for i := 0; i < session.ChunksTotal; i++ {
+			key := fmt.Sprintf("%s.chunk.%d", session.StorageKey, i)
+			_ = store.Upload(ctx, key, strings.NewReader("chunk"), "application/octet-stream", 5)
+		}
+	}
+
+	cleanupExpiredSessions(ctx, sessions, store)
+
+	if _, err := sessions.Get(ctx, "expired"); !errors.Is(err, errUploadSessionNotFound) {
+		t.Errorf("expired session still present, error = %v", err)
+	}
+	if _, err := sessions.Get(ctx, "active"); err != nil {
+		t.Errorf("active session removed, error = %v", err)
+	}
+	if store.Count() != 1 {
+		t.Errorf("storage has %d chunks, want 1", store.Count())
+	}
+
+	claimed, _ := sessions.ClaimExpired(ctx, time.Now().Add(-uploadSessionTTL))
+	if len(claimed) != 0 {
+		t.Errorf("ClaimExpired() returned %d sessions on second run, want 0", len(claimed))
+	}
+}
+
+func newTestRedisSessionStore(t *testing.T) (*RedisUploadSessionStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisUploadSessionStore(client), mr
+}
+
+func TestRedisUploadSessionStore(t *testing.T) {
+	store, mr := newTestRedisSessionStore(t)
+	ctx := context.Background()
+	folderID := uuid.NewString()
+
+	session := &uploadSession{
+		ID:          "redis-session",
+		UserID:      uuid.New(),
+		Filename:    "clip.mp4",
+		ContentType: "video/mp4",
+		TotalSize:   3 * 1024,
+		ChunksTotal: 3,
+		StorageKey:  "uploads/u/redis-session/clip.mp4",
+		CreatedAt:   time.Now().Truncate(time.Second),
+		FolderID:    folderID,
+		Tags:        []string{"raw"},
+	}
+
+	t.Run("Set and Get", func(t *testing.T) {
+		if err := store.Set(ctx, session); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		got, err := store.Get(ctx, session.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got.UserID != session.UserID || got.Filename != session.Filename || got.TotalSize != session.TotalSize ||
+			got.StorageKey != session.StorageKey || !got.CreatedAt.Equal(session.CreatedAt) ||
+			got.FolderID != folderID || len(got.Tags) != 1 || got.Tags[0] != "raw" {
+			t.Errorf("Get() = %+v, want %+v", got, session)
+		}
+		if got.ChunksLoaded == nil || len(got.ChunksLoaded) != 0 {
+			t.Errorf("ChunksLoaded = %v, want an empty map", got.ChunksLoaded)
+		}
+		if ttl := mr.TTL(store.sessionKey(session.ID)); ttl != 2*uploadSessionTTL {
+			t.Errorf("session TTL = %v, want %v", ttl, 2*uploadSessionTTL)
+		}
+	})
+
+	t.Run("Get non-existent", func(t *testing.T) {
+		if _, err := store.Get(ctx, "missing"); !errors.Is(err, errUploadSessionNotFound) {
+			t.Errorf("Get() error = %v, want errUploadSessionNotFound", err)
+		}
+	})
+
+	t.Run("MarkChunkLoaded", func(t *testing.T) {
+		for _, index := range []int{0, 2, 2} {
+			if _, err := store.MarkChunkLoaded(ctx, session.ID, index); err != nil {
+				t.Fatalf("MarkChunkLoaded(%d) error = %v", index, err)
+			}
+		}
+		if n, _ := store.MarkChunkLoaded(ctx, session.ID, 1); n != 3 {
+			t.Errorf("MarkChunkLoaded() = %d, want 3 distinct chunks", n)
+		}
+		got, _ := store.Get(ctx, session.ID)
+		if !got.ChunksLoaded[0] || !got.ChunksLoaded[1] || !got.ChunksLoaded[2] {
+			t.Errorf("ChunksLoaded = %v, want chunks 0-2", got.ChunksLoaded)
+		}
+	})
+
+	t.Run("Offset update", func(t *testing.T) {
+		got, _ := store.Get(ctx, session.ID)
+		got.Offset = 2048
+		if err := store.Set(ctx, got); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		got, _ = store.Get(ctx, session.ID)
+		if got.Offset != 2048 {
+			t.Errorf("Offset = %d, want 2048", got.Offset)
+		}
+		if len(got.ChunksLoaded) != 3 {
+			t.Errorf("updating the session dropped loaded chunks: %v", got.ChunksLoaded)
+		}
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		unlock, err := store.Lock(ctx, session.ID, time.Minute)
+		if err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+		if _, err := store.Lock(ctx, session.ID, time.Minute); !errors.Is(err, errUploadSessionLocked) {
+			t.Errorf("second Lock() error = %v, want errUploadSessionLocked", err)
+		}
+		unlock()
+		unlock, err = store.Lock(ctx, session.ID, time.Minute)
+		if err != nil {
+			t.Fatalf("Lock() after unlock error = %v", err)
+		}
+
+		// An expired lock can be taken over, and the stale holder must not
+		// release its successor's lock.
+		mr.FastForward(time.Minute)
+		unlockNext, err := store.Lock(ctx, session.ID, time.Minute)
+		if err != nil {
+			t.Fatalf("Lock() after expiry error = %v", err)
+		}
+		unlock()
+		if _, err := store.Lock(ctx, session.ID, time.Minute); !errors.Is(err, errUploadSessionLocked) {
+			t.Errorf("stale unlock released the new lock, Lock() error = %v", err)
+		}
+		unlockNext()
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := store.Delete(ctx, session.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := store.Get(ctx, session.ID); !errors.Is(err, errUploadSessionNotFound) {
+			t.Errorf("Get() after Delete() error = %v, want errUploadSessionNotFound", err)
+		}
+		if mr.Exists(store.chunksKey(session.ID)) {
+			t.Error("chunk set not deleted")
+		}
+		if claimed, _ := store.ClaimExpired(ctx, time.Now().Add(time.Hour)); len(claimed) != 0 {
+			t.Errorf("deleted session still indexed: %d claimed", len(claimed))
+		}
+	})
+}
+
+func TestRedisUploadSessionStore_Expiry(t *testing.T) {
+	store, mr := newTestRedisSessionStore(t)
+	ctx := context.Background()
+
+	old := &uploadSession{ID: "old", UserID: uuid.New(), CreatedAt: time.Now().Add(-2 * uploadSessionTTL)}
+	fresh := &uploadSession{ID: "fresh", UserID: uuid.New(), CreatedAt: time.Now()}
+	for _, session := range []*uploadSession{old, fresh} {
+		_ = store.Set(ctx, session)
+		_, _ = store.MarkChunkLoaded(ctx, session.ID, 0)
+	}
+
+	claimed, err := store.ClaimExpired(ctx, time.Now().Add(-uploadSessionTTL))
+	if err != nil {
+		t.Fatalf("ClaimExpired() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != "old" || !claimed[0].ChunksLoaded[0] {
+		t.Fatalf("ClaimExpired() = %+v, want the old session with its chunks", claimed)
+	}
+	if _, err := store.Get(ctx, "old"); !errors.Is(err, errUploadSessionNotFound) {
+		t.Errorf("claimed session still present, error = %v", err)
+	}
+	if again, _ := store.ClaimExpired(ctx, time.Now().Add(-uploadSessionTTL)); len(again) != 0 {
+		t.Errorf("ClaimExpired() returned %d sessions on second run, want 0", len(again))
+	}
+
+	// Redis drops sessions that outlive their key TTL on its own.
+	mr.FastForward(2*uploadSessionTTL + time.Second)
+	if _, err := store.Get(ctx, "fresh"); !errors.Is(err, errUploadSessionNotFound) {
+		t.Errorf("Get() after TTL error = %v, want errUploadSessionNotFound", err)
+	}
+}
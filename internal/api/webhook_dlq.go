@@ -2,12 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -219,3 +223,221 @@ func DeleteWebhookDLQEntryHandler(cfg *WebhookConfig) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+const (
+	defaultDLQReplayRate = 10
+	maxDLQReplayRate     = 50
+)
+
+// WebhookDLQReplayRequest selects the dead letter entries to replay. All
+// filters are optional; created_after and created_before are RFC 3339
+// timestamps bounding when the entry was dead-lettered.
+type WebhookDLQReplayRequest struct {
+	WebhookID     string `json:"webhook_id,omitempty"`
+	EventType     string `json:"event_type,omitempty"`
+	CreatedAfter  string `json:"created_after,omitempty"`
+	CreatedBefore string `json:"created_before,omitempty"`
+	RatePerSecond int    `json:"rate_per_second,omitempty"`
+}
+
+type WebhookDLQReplayResponse struct {
+	ID            string  `json:"id"`
+	Status        string  `json:"status"`
+	WebhookID     *string `json:"webhook_id,omitempty"`
+	EventType     *string `json:"event_type,omitempty"`
+	CreatedAfter  *string `json:"created_after,omitempty"`
+	CreatedBefore string  `json:"created_before"`
+	RatePerSecond int     `json:"rate_per_second"`
+	Total         int     `json:"total"`
+	Replayed      int     `json:"replayed"`
+	Failed        int     `json:"failed"`
+	ErrorMessage  *string `json:"error_message,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	StartedAt     *string `json:"started_at,omitempty"`
+	CompletedAt   *string `json:"completed_at,omitempty"`
+}
+
+// ReplayWebhookDLQHandler starts a throttled bulk replay of the retryable DLQ
+// entries matching the request filters and returns the replay for polling.
+func ReplayWebhookDLQHandler(cfg *WebhookConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		var req WebhookDLQReplayRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_request", "Invalid JSON request body", http.StatusBadRequest))
+			return
+		}
+
+		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+		params := db.CreateWebhookDLQReplayParams{
+			UserID:        pgUserID,
+			RatePerSecond: defaultDLQReplayRate,
+		}
+
+		if req.WebhookID != "" {
+			webhookID, err := uuid.Parse(req.WebhookID)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_webhook_id", "Invalid webhook ID format", http.StatusBadRequest))
+				return
+			}
+			params.WebhookID = pgtype.UUID{Bytes: webhookID, Valid: true}
+			if _, err := cfg.Queries.GetWebhook(r.Context(), db.GetWebhookParams{ID: params.WebhookID, UserID: pgUserID}); err != nil {
+				apperror.WriteJSON(w, r, apperror.ErrNotFound)
+				return
+			}
+		}
+
+		if req.EventType != "" {
+			if !webhook.ValidEventTypes[req.EventType] {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_event_type", "Unknown event type: "+req.EventType, http.StatusBadRequest))
+				return
+			}
+			params.EventType = &req.EventType
+		}
+
+		now := time.Now()
+		createdBefore := now
+		if req.CreatedAfter != "" {
+			t, err := time.Parse(time.RFC3339, req.CreatedAfter)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_created_after", "created_after must be an RFC 3339 timestamp", http.StatusBadRequest))
+				return
+			}
+			params.CreatedAfter = pgtype.Timestamptz{Time: t, Valid: true}
+		}
+		if req.CreatedBefore != "" {
+			t, err := time.Parse(time.RFC3339, req.CreatedBefore)
+			if err != nil {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_created_before", "created_before must be an RFC 3339 timestamp", http.StatusBadRequest))
+				return
+			}
+			// Entries dead-lettered after the replay starts, including
+			// replayed deliveries that fail again, are never picked up.
+			if t.Before(now) {
+				createdBefore = t
+			}
+		}
+		params.CreatedBefore = pgtype.Timestamptz{Time: createdBefore, Valid: true}
+		if params.CreatedAfter.Valid && !params.CreatedAfter.Time.Before(createdBefore) {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_time_range", "created_after must be before created_before", http.StatusBadRequest))
+			return
+		}
+
+		if req.RatePerSecond != 0 {
+			if req.RatePerSecond < 1 || req.RatePerSecond > maxDLQReplayRate {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_rate",
+					fmt.Sprintf("rate_per_second must be between 1 and %d", maxDLQReplayRate), http.StatusBadRequest))
+				return
+			}
+			params.RatePerSecond = int32(req.RatePerSecond)
+		}
+
+		total, err := cfg.Queries.CountReplayableWebhookDLQ(r.Context(), db.CountReplayableWebhookDLQParams{
+			UserID:        pgUserID,
+			WebhookID:     params.WebhookID,
+			EventType:     params.EventType,
+			CreatedAfter:  params.CreatedAfter,
+			CreatedBefore: params.CreatedBefore,
+		})
+		if err != nil {
+			log.Error("failed to count replayable DLQ entries", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		params.TotalEntries = int32(total)
+		params.Status = "pending"
+		if total == 0 {
+			params.Status = "completed"
+		}
+
+		replay, err := cfg.Queries.CreateWebhookDLQReplay(r.Context(), params)
+		if err != nil {
+			log.Error("failed to create DLQ replay", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		replayID := uuid.UUID(replay.ID.Bytes)
+		log = log.With("replay_id", replayID.String())
+
+		if total > 0 {
+			jobID, err := cfg.Broker.Enqueue("webhook_dlq_replay", worker.NewWebhookDLQReplayPayload(replayID))
+			if err != nil {
+				log.Error("failed to enqueue DLQ replay job", "error", err)
+				errMsg := "failed to enqueue replay"
+				_ = cfg.Queries.CompleteWebhookDLQReplay(r.Context(), db.CompleteWebhookDLQReplayParams{
+					ID:           replay.ID,
+					Status:       "failed",
+					ErrorMessage: &errMsg,
+				})
+				apperror.WriteJSON(w, r, apperror.ErrInternal)
+				return
+			}
+			log.Info("webhook DLQ replay enqueued", "job_id", jobID, "total", total)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(dlqReplayToResponse(replay))
+	}
+}
+
+// GetWebhookDLQReplayHandler returns the progress of a DLQ replay
+func GetWebhookDLQReplayHandler(cfg *WebhookConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserID(r.Context())
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		replayID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_replay_id", "Invalid replay ID format", http.StatusBadRequest))
+			return
+		}
+
+		replay, err := cfg.Queries.GetWebhookDLQReplay(r.Context(), db.GetWebhookDLQReplayParams{
+			ID:     pgtype.UUID{Bytes: replayID, Valid: true},
+			UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		})
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(dlqReplayToResponse(replay))
+	}
+}
+
+func dlqReplayToResponse(r db.WebhookDlqReplay) WebhookDLQReplayResponse {
+	resp := WebhookDLQReplayResponse{
+		ID:            uuidFromPgtype(r.ID),
+		Status:        r.Status,
+		EventType:     r.EventType,
+		CreatedAfter:  optionalTimestamp(r.CreatedAfter),
+		CreatedBefore: r.CreatedBefore.Time.Format(time.RFC3339),
+		RatePerSecond: int(r.RatePerSecond),
+		Total:         int(r.TotalEntries),
+		Replayed:      int(r.ReplayedEntries),
+		Failed:        int(r.FailedEntries),
+		ErrorMessage:  r.ErrorMessage,
+		CreatedAt:     r.CreatedAt.Time.Format(time.RFC3339),
+		StartedAt:     optionalTimestamp(r.StartedAt),
+		CompletedAt:   optionalTimestamp(r.CompletedAt),
+	}
+	if r.WebhookID.Valid {
+		webhookID := uuidFromPgtype(r.WebhookID)
+		resp.WebhookID = &webhookID
+	}
+	return resp
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// quotaWarningPercent is the share of a quota at which quota.warning fires.
+const quotaWarningPercent = 80
+
+// dispatchWebhook delivers event to the user's webhooks in the background so
+// the request never waits on, or fails because of, webhook bookkeeping.
+func dispatchWebhook(ctx context.Context, dispatcher *webhook.Dispatcher, userID uuid.UUID, event *webhook.Event) {
+	if dispatcher == nil || event == nil {
+		return
+	}
+	log := logger.FromContext(ctx)
+	go func() {
+		if err := dispatcher.Dispatch(context.Background(), userID, event); err != nil {
+			log.Debug("webhook dispatch failed", "event", event.Type, "error", err)
+		}
+	}()
+}
+
+// crossedQuotaWarning reports whether usage moved from below the warning
+// threshold to at or above it. Unlimited quotas (limit <= 0) never warn.
+func crossedQuotaWarning(before, after, limit int64) bool {
+	if limit <= 0 {
+		return false
+	}
+	threshold := limit * quotaWarningPercent
+	return before*100 < threshold && after*100 >= threshold
+}
+
+// checkTransformQuota fires quota.warning when the added transformations
+// pushed the user over the warning threshold. It runs after the counter has
+// been incremented, so each billing period warns once.
+func checkTransformQuota(ctx context.Context, cfg *Config, userID uuid.UUID, added int) {
+	if cfg.WebhookDispatcher == nil || cfg.Queries == nil || added <= 0 {
+		return
+	}
+	usage, err := cfg.Queries.GetUserTransformationUsage(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		logger.FromContext(ctx).Debug("failed to read transformation usage", "error", err)
+		return
+	}
+	after := int64(usage.TransformationsCount)
+	limit := int64(usage.TransformationsLimit)
+	if !crossedQuotaWarning(after-int64(added), after, limit) {
+		return
+	}
+	event, err := webhook.NewQuotaWarningEvent("transformations", after, limit)
+	if err != nil {
+		return
+	}
+	dispatchWebhook(ctx, cfg.WebhookDispatcher, userID, event)
+}
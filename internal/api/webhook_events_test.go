@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// recordingDispatcherQuerier subscribes every user to every event and hands
+// each created delivery to the test.
+type recordingDispatcherQuerier struct {
+	deliveries chan db.CreateWebhookDeliveryParams
+}
+
+func newRecordingDispatcher() (*webhook.Dispatcher, chan db.CreateWebhookDeliveryParams) {
+	q := &recordingDispatcherQuerier{deliveries: make(chan db.CreateWebhookDeliveryParams, 10)}
+	return webhook.NewDispatcher(q, NewMockBroker()), q.deliveries
+}
+
+func (q *recordingDispatcherQuerier) ListActiveWebhooksByUserAndEvent(ctx context.Context, arg db.ListActiveWebhooksByUserAndEventParams) ([]db.Webhook, error) {
+	return []db.Webhook{{
+		ID:     pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID: arg.UserID,
+		Active: true,
+	}}, nil
+}
+
+func (q *recordingDispatcherQuerier) CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	q.deliveries <- arg
+	return db.WebhookDelivery{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}, nil
+}
+
+func waitForDelivery(t *testing.T, deliveries chan db.CreateWebhookDeliveryParams, eventType string) webhook.Event {
+	t.Helper()
+	select {
+	case d := <-deliveries:
+		if d.EventType != eventType {
+			t.Fatalf("delivered %s, want %s", d.EventType, eventType)
+		}
+		var event webhook.Event
+		if err := json.Unmarshal(d.Payload, &event); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no %s delivery", eventType)
+	}
+	return webhook.Event{}
+}
+
+func TestCrossedQuotaWarning(t *testing.T) {
+	tests := []struct {
+		name                 string
+		before, after, limit int64
+		want                 bool
+	}{
+		{"below threshold", 10, 50, 100, false},
+		{"crosses threshold", 79, 80, 100, true},
+		{"jumps past threshold", 50, 95, 100, true},
+		{"already above", 80, 81, 100, false},
+		{"unlimited", 0, 1000, -1, false},
+		{"no quota", 0, 10, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := crossedQuotaWarning(tt.before, tt.after, tt.limit); got != tt.want {
+				t.Errorf("crossedQuotaWarning(%d, %d, %d) = %v, want %v", tt.before, tt.after, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookEvents_FileDeleted(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	fileID := uuid.New()
+	q.AddFile(createTestFileWithID(fileID, userID, "photo.jpg"))
+	dispatcher, deliveries := newRecordingDispatcher()
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/files/"+fileID.String(), nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+	req.SetPathValue("id", fileID.String())
+	rec := httptest.NewRecorder()
+	deleteHandler(&Config{Queries: q, WebhookDispatcher: dispatcher}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	event := waitForDelivery(t, deliveries, webhook.EventFileDeleted)
+	var data webhook.FileDeletedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data.FileID != fileID.String() || data.Filename != "photo.jpg" {
+		t.Errorf("data = %+v", data)
+	}
+}
+
+func TestWebhookEvents_Shares(t *testing.T) {
+	q, s, registry := setupCDNTestDeps(t)
+	userID := uuid.New()
+	fileID := uuid.New()
+	q.AddFile(createTestFileWithID(fileID, userID, "photo.jpg"))
+	dispatcher, deliveries := newRecordingDispatcher()
+	cfg := &CDNConfig{Storage: s, Queries: q, Registry: registry, WebhookDispatcher: dispatcher}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files/"+fileID.String()+"/share?expires=1h", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+	req.SetPathValue("id", fileID.String())
+	rec := httptest.NewRecorder()
+	CreateShareHandler(cfg, "https://file.cheap").ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create share status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	event := waitForDelivery(t, deliveries, webhook.EventShareCreated)
+	var created webhook.ShareCreatedData
+	if err := json.Unmarshal(event.Data, &created); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if created.FileID != fileID.String() || created.ShareID == "" || created.ExpiresAt == nil {
+		t.Errorf("share.created data = %+v", created)
+	}
+
+	q.AddShareByToken("tok", createTestShareByToken(fileID, userID, "tok", "uploads/photo.jpg", "image/jpeg", "photo.jpg", nil, nil))
+	s.PresignedURLFn = func(key string, expiry int) (string, error) { return "https://cdn.example.com/" + key, nil }
+	req = httptest.NewRequest(http.MethodGet, "/cdn/tok/_/photo.jpg", nil)
+	req.SetPathValue("token", "tok")
+	req.SetPathValue("transforms", "_")
+	req.SetPathValue("filename", "photo.jpg")
+	rec = httptest.NewRecorder()
+	CDNHandler(cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("cdn status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+
+	event = waitForDelivery(t, deliveries, webhook.EventShareAccessed)
+	var accessed webhook.ShareAccessedData
+	if err := json.Unmarshal(event.Data, &accessed); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if accessed.FileID != fileID.String() || accessed.Transforms != "_" {
+		t.Errorf("share.accessed data = %+v", accessed)
+	}
+}
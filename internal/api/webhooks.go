@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
@@ -22,6 +23,7 @@ type WebhookQuerier interface {
 	ListWebhooksByUser(ctx context.Context, arg db.ListWebhooksByUserParams) ([]db.Webhook, error)
 	CountWebhooksByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
 	UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error)
+	RotateWebhookSecret(ctx context.Context, arg db.RotateWebhookSecretParams) (db.Webhook, error)
 	DeleteWebhook(ctx context.Context, arg db.DeleteWebhookParams) error
 	ListDeliveriesByWebhook(ctx context.Context, arg db.ListDeliveriesByWebhookParams) ([]db.WebhookDelivery, error)
 	CountDeliveriesByWebhook(ctx context.Context, webhookID pgtype.UUID) (int64, error)
@@ -32,8 +34,17 @@ type WebhookQuerier interface {
 	CountWebhookDLQByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
 	MarkWebhookDLQRetried(ctx context.Context, id pgtype.UUID) error
 	DeleteWebhookDLQEntry(ctx context.Context, id pgtype.UUID) error
+	CountReplayableWebhookDLQ(ctx context.Context, arg db.CountReplayableWebhookDLQParams) (int64, error)
+	CreateWebhookDLQReplay(ctx context.Context, arg db.CreateWebhookDLQReplayParams) (db.WebhookDlqReplay, error)
+	GetWebhookDLQReplay(ctx context.Context, arg db.GetWebhookDLQReplayParams) (db.WebhookDlqReplay, error)
+	CompleteWebhookDLQReplay(ctx context.Context, arg db.CompleteWebhookDLQReplayParams) error
 }
 
+const (
+	defaultSecretGracePeriod = 24 * time.Hour
+	maxSecretGracePeriod     = 7 * 24 * time.Hour
+)
+
 type WebhookConfig struct {
 	Queries WebhookQuerier
 	Broker  Broker
@@ -42,22 +53,41 @@ type WebhookConfig struct {
 type CreateWebhookRequest struct {
 	URL    string   `json:"url"`
 	Events []string `json:"events"`
+	Format string   `json:"format,omitempty"`
 }
 
 type UpdateWebhookRequest struct {
 	URL    string   `json:"url"`
 	Events []string `json:"events"`
 	Active bool     `json:"active"`
+	// Format is left unchanged when omitted.
+	Format *string `json:"format,omitempty"`
 }
 
 type WebhookResponse struct {
-	ID        string   `json:"id"`
-	URL       string   `json:"url"`
-	Secret    string   `json:"secret,omitempty"`
-	Events    []string `json:"events"`
-	Active    bool     `json:"active"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+	Format string   `json:"format"`
+	// PreviousSecretExpiresAt is set while a rotated-out secret still signs
+	// deliveries.
+	PreviousSecretExpiresAt *string `json:"previous_secret_expires_at,omitempty"`
+	// DisabledAt is set when the circuit breaker disabled the webhook after
+	// sustained failure.
+	DisabledAt *string                `json:"disabled_at,omitempty"`
+	Circuit    WebhookCircuitResponse `json:"circuit"`
+	CreatedAt  string                 `json:"created_at"`
+	UpdatedAt  string                 `json:"updated_at"`
+}
+
+// WebhookCircuitResponse is the shared circuit breaker state of a webhook.
+type WebhookCircuitResponse struct {
+	State               string  `json:"state"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	LastFailureAt       *string `json:"last_failure_at,omitempty"`
+	FailingSince        *string `json:"failing_since,omitempty"`
 }
 
 type DeliveryResponse struct {
@@ -125,6 +155,14 @@ func CreateWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 			}
 		}
 
+		if req.Format == "" {
+			req.Format = webhook.FormatNative
+		}
+		if !webhook.ValidFormats[req.Format] {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_format", "invalid format: "+req.Format, http.StatusBadRequest))
+			return
+		}
+
 		secret, err := webhook.GenerateSecret()
 		if err != nil {
 			log.Error("failed to generate webhook secret", "error", err)
@@ -138,6 +176,7 @@ func CreateWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 			Url:    req.URL,
 			Secret: secret,
 			Events: req.Events,
+			Format: req.Format,
 		})
 		if err != nil {
 			log.Error("failed to create webhook", "error", err)
@@ -145,6 +184,14 @@ func CreateWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 			return
 		}
 
+		audit.Record(r, audit.Entry{
+			UserID:       userID,
+			Action:       audit.ActionWebhookCreate,
+			ResourceType: audit.ResourceWebhook,
+			ResourceID:   uuid.UUID(wh.ID.Bytes),
+			Metadata:     map[string]any{"url": wh.Url, "events": wh.Events, "format": wh.Format},
+		})
+
 		resp := webhookToResponse(wh)
 		resp.Secret = secret
 
@@ -285,6 +332,11 @@ func UpdateWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 			}
 		}
 
+		if req.Format != nil && !webhook.ValidFormats[*req.Format] {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "invalid_format", "invalid format: "+*req.Format, http.StatusBadRequest))
+			return
+		}
+
 		pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
 		pgWebhookID := pgtype.UUID{Bytes: webhookID, Valid: true}
 
@@ -294,6 +346,7 @@ func UpdateWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 			Url:    req.URL,
 			Events: req.Events,
 			Active: req.Active,
+			Format: req.Format,
 		})
 		if err != nil {
 			log.Error("failed to update webhook", "error", err)
@@ -306,6 +359,71 @@ func UpdateWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 	}
 }
 
+// RotateWebhookSecretHandler replaces a webhook's signing secret. The old
+// secret keeps signing deliveries alongside the new one for grace_period
+// (default 24h, at most 7 days) so receivers can switch without dropping
+// events.
+func RotateWebhookSecretHandler(cfg *WebhookConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx)
+
+		userID, ok := GetUserID(ctx)
+		if !ok {
+			apperror.WriteJSON(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		webhookID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			apperror.WriteJSON(w, r, apperror.ErrBadRequest)
+			return
+		}
+
+		grace := defaultSecretGracePeriod
+		if v := r.URL.Query().Get("grace_period"); v != "" {
+			grace, err = time.ParseDuration(v)
+			if err != nil || grace < 0 || grace > maxSecretGracePeriod {
+				apperror.WriteJSON(w, r, apperror.WrapWithMessage(err, "invalid_grace_period", "grace_period must be a duration between 0s and 168h", http.StatusBadRequest))
+				return
+			}
+		}
+
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			log.Error("failed to generate webhook secret", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrInternal)
+			return
+		}
+
+		wh, err := cfg.Queries.RotateWebhookSecret(ctx, db.RotateWebhookSecretParams{
+			ID:                      pgtype.UUID{Bytes: webhookID, Valid: true},
+			UserID:                  pgtype.UUID{Bytes: userID, Valid: true},
+			Secret:                  secret,
+			PreviousSecretExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(grace), Valid: true},
+		})
+		if err != nil {
+			log.Error("failed to rotate webhook secret", "error", err)
+			apperror.WriteJSON(w, r, apperror.ErrNotFound)
+			return
+		}
+
+		audit.Record(r, audit.Entry{
+			UserID:       userID,
+			Action:       audit.ActionWebhookRotateSecret,
+			ResourceType: audit.ResourceWebhook,
+			ResourceID:   webhookID,
+			Metadata:     map[string]any{"grace_period": grace.String()},
+		})
+
+		resp := webhookToResponse(wh)
+		resp.Secret = secret
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
 func DeleteWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -336,6 +454,12 @@ func DeleteWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 			return
 		}
 
+		audit.Record(r, audit.Entry{
+			UserID:       userID,
+			Action:       audit.ActionWebhookDelete,
+			ResourceType: audit.ResourceWebhook,
+			ResourceID:   webhookID,
+		})
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -482,14 +606,39 @@ func TestWebhookHandler(cfg *WebhookConfig) http.HandlerFunc {
 }
 
 func webhookToResponse(wh db.Webhook) WebhookResponse {
-	return WebhookResponse{
+	resp := WebhookResponse{
 		ID:        webhookUUIDToString(wh.ID),
 		URL:       wh.Url,
 		Events:    wh.Events,
 		Active:    wh.Active,
+		Format:    wh.Format,
 		CreatedAt: wh.CreatedAt.Time.Format(time.RFC3339),
 		UpdatedAt: wh.UpdatedAt.Time.Format(time.RFC3339),
 	}
+
+	if wh.PreviousSecret != nil && wh.PreviousSecretExpiresAt.Valid && wh.PreviousSecretExpiresAt.Time.After(time.Now()) {
+		resp.PreviousSecretExpiresAt = optionalTimestamp(wh.PreviousSecretExpiresAt)
+	}
+	resp.DisabledAt = optionalTimestamp(wh.DisabledAt)
+
+	resp.Circuit = WebhookCircuitResponse{
+		State:         webhook.CircuitState(wh),
+		LastFailureAt: optionalTimestamp(wh.LastFailureAt),
+		FailingSince:  optionalTimestamp(wh.FailingSince),
+	}
+	if wh.ConsecutiveFailures != nil {
+		resp.Circuit.ConsecutiveFailures = int(*wh.ConsecutiveFailures)
+	}
+
+	return resp
+}
+
+func optionalTimestamp(ts pgtype.Timestamptz) *string {
+	if !ts.Valid {
+		return nil
+	}
+	s := ts.Time.Format(time.RFC3339)
+	return &s
 }
 
 func deliveryToResponse(d db.WebhookDelivery) DeliveryResponse {
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCreateWebhookHandler_Format(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFormat string
+	}{
+		{"defaults to native", `{"url":"https://example.com/hook","events":["file.uploaded"]}`, http.StatusCreated, "native"},
+		{"cloudevents structured", `{"url":"https://example.com/hook","events":["file.uploaded"],"format":"cloudevents-structured"}`, http.StatusCreated, "cloudevents-structured"},
+		{"cloudevents binary", `{"url":"https://example.com/hook","events":["file.uploaded"],"format":"cloudevents-binary"}`, http.StatusCreated, "cloudevents-binary"},
+		{"unknown format", `{"url":"https://example.com/hook","events":["file.uploaded"],"format":"xml"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &WebhookConfig{Queries: NewMockQuerier()}
+			req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, uuid.New()))
+			rec := httptest.NewRecorder()
+			CreateWebhookHandler(cfg).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var resp WebhookResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", resp.Format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestUpdateWebhookHandler_Format(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFormat string
+	}{
+		{"omitted keeps current", `{"url":"https://example.com/hook","events":["file.uploaded"],"active":true}`, http.StatusOK, "native"},
+		{"switch to binary", `{"url":"https://example.com/hook","events":["file.uploaded"],"active":true,"format":"cloudevents-binary"}`, http.StatusOK, "cloudevents-binary"},
+		{"unknown format", `{"url":"https://example.com/hook","events":["file.uploaded"],"active":true,"format":"xml"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &WebhookConfig{Queries: NewMockQuerier()}
+			webhookID := uuid.New()
+			req := httptest.NewRequest(http.MethodPut, "/v1/webhooks/"+webhookID.String(), strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, uuid.New()))
+			req.SetPathValue("id", webhookID.String())
+			rec := httptest.NewRecorder()
+			UpdateWebhookHandler(cfg).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp WebhookResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", resp.Format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestRotateWebhookSecretHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantExpires time.Duration
+	}{
+		{"default grace period", "", http.StatusOK, 24 * time.Hour},
+		{"custom grace period", "?grace_period=2h", http.StatusOK, 2 * time.Hour},
+		{"invalid duration", "?grace_period=soon", http.StatusBadRequest, 0},
+		{"too long", "?grace_period=200h", http.StatusBadRequest, 0},
+		{"negative", "?grace_period=-1h", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &WebhookConfig{Queries: NewMockQuerier()}
+			webhookID := uuid.New()
+			req := httptest.NewRequest(http.MethodPost, "/v1/webhooks/"+webhookID.String()+"/rotate-secret"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, uuid.New()))
+			req.SetPathValue("id", webhookID.String())
+			rec := httptest.NewRecorder()
+			RotateWebhookSecretHandler(cfg).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp WebhookResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if !strings.HasPrefix(resp.Secret, "whsec_") {
+				t.Errorf("secret = %q, want a new whsec_ secret", resp.Secret)
+			}
+			if resp.PreviousSecretExpiresAt == nil {
+				t.Fatal("previous_secret_expires_at missing")
+			}
+			expiresAt, err := time.Parse(time.RFC3339, *resp.PreviousSecretExpiresAt)
+			if err != nil {
+				t.Fatalf("parse previous_secret_expires_at: %v", err)
+			}
+			if d := time.Until(expiresAt); d < tt.wantExpires-time.Minute || d > tt.wantExpires+time.Minute {
+				t.Errorf("previous secret expires in %v, want about %v", d, tt.wantExpires)
+			}
+		})
+	}
+}
+
+func TestGetWebhookHandler_CircuitState(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	webhookID := uuid.New()
+	open := "open"
+	failures := int32(7)
+	failingSince := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q.AddWebhook(db.Webhook{
+		ID:                  pgtype.UUID{Bytes: webhookID, Valid: true},
+		UserID:              pgtype.UUID{Bytes: userID, Valid: true},
+		Url:                 "https://example.com/hook",
+		Format:              "native",
+		CircuitState:        &open,
+		ConsecutiveFailures: &failures,
+		LastFailureAt:       pgtype.Timestamptz{Time: failingSince.Add(time.Hour), Valid: true},
+		FailingSince:        pgtype.Timestamptz{Time: failingSince, Valid: true},
+		DisabledAt:          pgtype.Timestamptz{Time: failingSince.Add(72 * time.Hour), Valid: true},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/webhooks/"+webhookID.String(), nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+	req.SetPathValue("id", webhookID.String())
+	rec := httptest.NewRecorder()
+	GetWebhookHandler(&WebhookConfig{Queries: q}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp WebhookResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Circuit.State != "open" || resp.Circuit.ConsecutiveFailures != 7 {
+		t.Errorf("circuit = %+v", resp.Circuit)
+	}
+	if resp.Circuit.FailingSince == nil || *resp.Circuit.FailingSince != "2026-03-01T12:00:00Z" {
+		t.Errorf("failing_since = %v", resp.Circuit.FailingSince)
+	}
+	if resp.DisabledAt == nil || *resp.DisabledAt != "2026-03-04T12:00:00Z" {
+		t.Errorf("disabled_at = %v", resp.DisabledAt)
+	}
+}
+
+func TestReplayWebhookDLQHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		replayable int64
+		wantStatus int
+		wantState  string
+		wantRate   int
+		wantJob    bool
+	}{
+		{"defaults", `{}`, 3, http.StatusAccepted, "pending", 10, true},
+		{"filters", `{"webhook_id":"` + uuid.NewString() + `","event_type":"file.uploaded","created_after":"2026-01-01T00:00:00Z","created_before":"2026-01-02T00:00:00Z","rate_per_second":5}`, 2, http.StatusAccepted, "pending", 5, true},
+		{"nothing to replay", `{}`, 0, http.StatusAccepted, "completed", 10, false},
+		{"invalid webhook id", `{"webhook_id":"nope"}`, 0, http.StatusBadRequest, "", 0, false},
+		{"unknown event type", `{"event_type":"file.exploded"}`, 0, http.StatusBadRequest, "", 0, false},
+		{"invalid timestamp", `{"created_after":"yesterday"}`, 0, http.StatusBadRequest, "", 0, false},
+		{"inverted range", `{"created_after":"2026-01-02T00:00:00Z","created_before":"2026-01-01T00:00:00Z"}`, 0, http.StatusBadRequest, "", 0, false},
+		{"rate too high", `{"rate_per_second":500}`, 0, http.StatusBadRequest, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewMockQuerier()
+			q.ReplayableDLQCount = tt.replayable
+			broker := NewMockBroker()
+			req := httptest.NewRequest(http.MethodPost, "/v1/webhooks/dlq/replay", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, uuid.New()))
+			rec := httptest.NewRecorder()
+			ReplayWebhookDLQHandler(&WebhookConfig{Queries: q, Broker: broker}).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				return
+			}
+			var resp WebhookDLQReplayResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Status != tt.wantState || resp.Total != int(tt.replayable) || resp.RatePerSecond != tt.wantRate {
+				t.Errorf("replay = %+v", resp)
+			}
+			if broker.HasJob("webhook_dlq_replay") != tt.wantJob {
+				t.Errorf("replay job enqueued = %v, want %v", !tt.wantJob, tt.wantJob)
+			}
+		})
+	}
+}
+
+func TestGetWebhookDLQReplayHandler(t *testing.T) {
+	q := NewMockQuerier()
+	userID := uuid.New()
+	replay, err := q.CreateWebhookDLQReplay(context.Background(), db.CreateWebhookDLQReplayParams{
+		UserID:        pgtype.UUID{Bytes: userID, Valid: true},
+		CreatedBefore: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		RatePerSecond: 10,
+		Status:        "running",
+		TotalEntries:  4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayID := uuidFromPgtype(replay.ID)
+
+	for _, tc := range []struct {
+		name       string
+		user       uuid.UUID
+		wantStatus int
+	}{
+		{"owner", userID, http.StatusOK},
+		{"other user", uuid.New(), http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/webhooks/dlq/replay/"+replayID, nil)
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, tc.user))
+			req.SetPathValue("id", replayID)
+			rec := httptest.NewRecorder()
+			GetWebhookDLQReplayHandler(&WebhookConfig{Queries: q}).ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var resp WebhookDLQReplayResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.ID != replayID || resp.Status != "running" || resp.Total != 4 {
+				t.Errorf("replay = %+v", resp)
+			}
+		})
+	}
+}
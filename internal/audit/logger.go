@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/http"
 	"net/netip"
+	"strings"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/google/uuid"
@@ -15,22 +16,62 @@ import (
 type Action string
 
 const (
-	ActionFileUpload         Action = "file.upload"
-	ActionFileDownload       Action = "file.download"
-	ActionFileDelete         Action = "file.delete"
-	ActionFileShare          Action = "file.share"
-	ActionShareAccess        Action = "share.access"
-	ActionShareDelete        Action = "share.delete"
-	ActionUserLogin          Action = "user.login"
-	ActionUserLogout         Action = "user.logout"
-	ActionUserPasswordChange Action = "user.password_change"
-	ActionSettingsUpdate     Action = "settings.update"
-	ActionAPITokenCreate     Action = "api_token.create"
-	ActionAPITokenDelete     Action = "api_token.delete"
-	ActionWebhookCreate      Action = "webhook.create"
-	ActionWebhookDelete      Action = "webhook.delete"
+	ActionFileUpload          Action = "file.upload"
+	ActionFileDownload        Action = "file.download"
+	ActionFileDelete          Action = "file.delete"
+	ActionFileShare           Action = "file.share"
+	ActionShareAccess         Action = "share.access"
+	ActionShareDelete         Action = "share.delete"
+	ActionUserLogin           Action = "user.login"
+	ActionUserLogout          Action = "user.logout"
+	ActionUserPasswordChange  Action = "user.password_change"
+	ActionSettingsUpdate      Action = "settings.update"
+	ActionAPITokenCreate      Action = "api_token.create"
+	ActionAPITokenDelete      Action = "api_token.delete"
+	ActionWebhookCreate       Action = "webhook.create"
+	ActionWebhookDelete       Action = "webhook.delete"
+	ActionWebhookRotateSecret Action = "webhook.rotate_secret"
 )
 
+// Resource types recorded alongside actions.
+const (
+	ResourceFile     = "file"
+	ResourceShare    = "share"
+	ResourceUser     = "user"
+	ResourceSettings = "settings"
+	ResourceAPIToken = "api_token"
+	ResourceWebhook  = "webhook"
+)
+
+// Actions lists every Action in the order they are declared.
+var Actions = []Action{
+	ActionFileUpload,
+	ActionFileDownload,
+	ActionFileDelete,
+	ActionFileShare,
+	ActionShareAccess,
+	ActionShareDelete,
+	ActionUserLogin,
+	ActionUserLogout,
+	ActionUserPasswordChange,
+	ActionSettingsUpdate,
+	ActionAPITokenCreate,
+	ActionAPITokenDelete,
+	ActionWebhookCreate,
+	ActionWebhookDelete,
+	ActionWebhookRotateSecret,
+}
+
+// Valid reports whether a is a known Action.
+func (a Action) Valid() bool {
+	for _, action := range Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
 type Entry struct {
 	UserID       uuid.UUID
 	Action       Action
@@ -94,7 +135,7 @@ func (l *Logger) Log(ctx context.Context, entry Entry) error {
 
 func (l *Logger) LogFromRequest(ctx context.Context, r *http.Request, entry Entry) error {
 	if entry.IPAddress == "" {
-		entry.IPAddress = getClientIP(r)
+		entry.IPAddress = ClientIP(r)
 	}
 	if entry.UserAgent == "" {
 		entry.UserAgent = r.UserAgent()
@@ -102,9 +143,12 @@ func (l *Logger) LogFromRequest(ctx context.Context, r *http.Request, entry Entr
 	return l.Log(ctx, entry)
 }
 
-func getClientIP(r *http.Request) string {
+// ClientIP returns the address of the client that sent r, preferring the
+// first X-Forwarded-For hop and X-Real-IP over the connection address.
+func ClientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
+		client, _, _ := strings.Cut(xff, ",")
+		return strings.TrimSpace(client)
 	}
 	if xri := r.Header.Get("X-Real-IP"); xri != "" {
 		return xri
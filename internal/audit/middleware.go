@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+)
+
+type contextKey struct{}
+
+// recordTimeout bounds how long a request waits on its audit write.
+const recordTimeout = 5 * time.Second
+
+// WithLogger returns a copy of ctx carrying l for Record.
+func WithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger attached to ctx, or nil.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(contextKey{}).(*Logger)
+	return l
+}
+
+// Middleware attaches l to every request so handlers can call Record without
+// threading the logger through their configs. A nil l disables auditing.
+func Middleware(l *Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), l)))
+		})
+	}
+}
+
+// RecordAsync is Record for hot paths such as CDN requests: the client IP
+// and user agent are taken from r straight away, and the entry is written in
+// the background so the request never waits on the database.
+func RecordAsync(r *http.Request, entry Entry) {
+	l := FromContext(r.Context())
+	if l == nil {
+		return
+	}
+	if entry.IPAddress == "" {
+		entry.IPAddress = ClientIP(r)
+	}
+	if entry.UserAgent == "" {
+		entry.UserAgent = r.UserAgent()
+	}
+
+	log := logger.FromContext(r.Context())
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, recordTimeout)
+		defer cancel()
+		if err := l.Log(ctx, entry); err != nil {
+			log.Warn("failed to write audit log",
+				"action", string(entry.Action),
+				"resource_type", entry.ResourceType,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// Record writes entry using the Logger attached to r, filling in the client
+// IP and user agent. It is a no-op when no Logger is attached. Failures are
+// logged rather than returned so auditing never fails the request it
+// describes.
+func Record(r *http.Request, entry Entry) {
+	l := FromContext(r.Context())
+	if l == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), recordTimeout)
+	defer cancel()
+
+	if err := l.LogFromRequest(ctx, r, entry); err != nil {
+		logger.FromContext(r.Context()).Warn("failed to write audit log",
+			"action", string(entry.Action),
+			"resource_type", entry.ResourceType,
+			"error", err,
+		)
+	}
+}
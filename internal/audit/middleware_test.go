@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+)
+
+type fakeQuerier struct {
+	entries []db.CreateAuditLogParams
+	err     error
+}
+
+func (f *fakeQuerier) CreateAuditLog(ctx context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+	if f.err != nil {
+		return db.AuditLog{}, f.err
+	}
+	f.entries = append(f.entries, arg)
+	return db.AuditLog{}, nil
+}
+
+func TestMiddleware_Record(t *testing.T) {
+	q := &fakeQuerier{}
+	userID := uuid.New()
+
+	handler := Middleware(NewLogger(q))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Record(r, Entry{
+			UserID:       userID,
+			Action:       ActionUserLogin,
+			ResourceType: ResourceUser,
+			ResourceID:   userID,
+			Metadata:     map[string]any{"method": "password"},
+		})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "192.0.2.10:51234"
+	req.Header.Set("User-Agent", "audit-test")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(q.entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(q.entries))
+	}
+	got := q.entries[0]
+	if got.Action != db.AuditAction(ActionUserLogin) || got.ResourceType != ResourceUser {
+		t.Errorf("entry = %s %s", got.Action, got.ResourceType)
+	}
+	if uuid.UUID(got.UserID.Bytes) != userID || !got.UserID.Valid {
+		t.Errorf("user_id = %v, want %s", got.UserID, userID)
+	}
+	if got.IpAddress == nil || got.IpAddress.String() != "192.0.2.10" {
+		t.Errorf("ip = %v, want 192.0.2.10", got.IpAddress)
+	}
+	if got.UserAgent == nil || *got.UserAgent != "audit-test" {
+		t.Errorf("user agent = %v", got.UserAgent)
+	}
+	if string(got.Metadata) != `{"method":"password"}` {
+		t.Errorf("metadata = %s", got.Metadata)
+	}
+}
+
+type chanQuerier chan db.CreateAuditLogParams
+
+func (c chanQuerier) CreateAuditLog(ctx context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+	if err := ctx.Err(); err != nil {
+		return db.AuditLog{}, err
+	}
+	c <- arg
+	return db.AuditLog{}, nil
+}
+
+func TestRecordAsync(t *testing.T) {
+	q := make(chanQuerier, 1)
+	ctx, cancel := context.WithCancel(WithLogger(context.Background(), NewLogger(q)))
+	req := httptest.NewRequest(http.MethodGet, "/cdn/tok/_/photo.jpg", nil).WithContext(ctx)
+	req.RemoteAddr = "192.0.2.20:40000"
+	req.Header.Set("User-Agent", "audit-test")
+
+	RecordAsync(req, Entry{Action: ActionShareAccess, ResourceType: ResourceShare})
+	// The write must outlive the request
+	cancel()
+
+	select {
+	case got := <-q:
+		if got.Action != db.AuditAction(ActionShareAccess) {
+			t.Errorf("action = %s", got.Action)
+		}
+		if got.IpAddress == nil || got.IpAddress.String() != "192.0.2.20" {
+			t.Errorf("ip = %v, want 192.0.2.20", got.IpAddress)
+		}
+		if got.UserAgent == nil || *got.UserAgent != "audit-test" {
+			t.Errorf("user agent = %v", got.UserAgent)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("audit entry was not written")
+	}
+}
+
+func TestRecord_WithoutLogger(t *testing.T) {
+	// Without the middleware Record must be a harmless no-op.
+	Record(httptest.NewRequest(http.MethodGet, "/", nil), Entry{Action: ActionFileDownload})
+}
+
+func TestRecord_ErrorDoesNotPanic(t *testing.T) {
+	q := &fakeQuerier{err: errors.New("db down")}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(WithLogger(req.Context(), NewLogger(q)))
+	Record(req, Entry{Action: ActionFileDownload, ResourceType: ResourceFile})
+}
+
+func TestMiddleware_NilLogger(t *testing.T) {
+	called := false
+	handler := Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if FromContext(r.Context()) != nil {
+			t.Error("nil logger attached to context")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Error("handler not called")
+	}
+}
+
+func TestGetClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   string
+	}{
+		{"forwarded chain", "X-Forwarded-For", "203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		{"single forwarded", "X-Forwarded-For", "203.0.113.8", "203.0.113.8"},
+		{"real ip", "X-Real-IP", "203.0.113.9", "203.0.113.9"},
+		{"remote addr", "", "", "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			if got := ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionValid(t *testing.T) {
+	for _, a := range Actions {
+		if !a.Valid() {
+			t.Errorf("%s not valid", a)
+		}
+	}
+	if Action("file.explode").Valid() {
+		t.Error("unknown action reported valid")
+	}
+}
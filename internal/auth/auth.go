@@ -30,6 +30,7 @@ const (
 	PermSharesWrite   = "shares:write"
 	PermWebhooksRead  = "webhooks:read"
 	PermWebhooksWrite = "webhooks:write"
+	PermAuditRead     = "audit:read"
 )
 
 // AllPermissions contains all available permissions
@@ -42,6 +43,7 @@ var AllPermissions = []string{
 	PermSharesWrite,
 	PermWebhooksRead,
 	PermWebhooksWrite,
+	PermAuditRead,
 }
 
 // PermissionPresets defines common permission combinations
@@ -245,13 +247,14 @@ func (s *Service) RequestPasswordReset(ctx context.Context, email string) (*Requ
 	}, nil
 }
 
-// ResetPassword resets a user's password using the token.
-func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+// ResetPassword resets a user's password using the token and returns the
+// ID of the user whose password changed.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) (uuid.UUID, error) {
 	log := logger.FromContext(ctx)
 
 	if err := ValidatePassword(newPassword); err != nil {
 		metrics.RecordAuthOperation("reset_password", "error")
-		return apperror.Wrap(err, apperror.ErrWeakPassword)
+		return uuid.Nil, apperror.Wrap(err, apperror.ErrWeakPassword)
 	}
 
 	tokenHash := HashToken(token)
@@ -260,13 +263,13 @@ func (s *Service) ResetPassword(ctx context.Context, token, newPassword string)
 	if err != nil {
 		log.Debug("password reset failed: invalid token")
 		metrics.RecordAuthOperation("reset_password", "error")
-		return apperror.ErrInvalidToken
+		return uuid.Nil, apperror.ErrInvalidToken
 	}
 
 	passwordHash, err := HashPassword(newPassword)
 	if err != nil {
 		metrics.RecordAuthOperation("reset_password", "error")
-		return apperror.Wrap(err, apperror.ErrInternal)
+		return uuid.Nil, apperror.Wrap(err, apperror.ErrInternal)
 	}
 
 	if err := s.queries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
@@ -274,17 +277,17 @@ func (s *Service) ResetPassword(ctx context.Context, token, newPassword string)
 		PasswordHash: &passwordHash,
 	}); err != nil {
 		metrics.RecordAuthOperation("reset_password", "error")
-		return apperror.Wrap(err, apperror.ErrInternal)
+		return uuid.Nil, apperror.Wrap(err, apperror.ErrInternal)
 	}
 
 	if err := s.queries.MarkPasswordResetUsed(ctx, reset.ID); err != nil {
 		metrics.RecordAuthOperation("reset_password", "error")
-		return apperror.Wrap(err, apperror.ErrInternal)
+		return uuid.Nil, apperror.Wrap(err, apperror.ErrInternal)
 	}
 
 	log.Info("password reset completed")
 	metrics.RecordAuthOperation("reset_password", "success")
-	return nil
+	return uuid.UUID(reset.UserID.Bytes), nil
 }
 
 // ValidatePasswordResetToken checks if a password reset token is valid.
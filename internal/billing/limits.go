@@ -90,9 +90,9 @@ func GetTierLimits(tier db.SubscriptionTier) TierLimits {
 				"thumbnail",
 				"sm", "md", "lg", "xl",
 				"og", "twitter", "instagram_square", "instagram_portrait", "instagram_story",
-				"webp", "watermark",
+				"webp", "avif", "watermark",
 				// Video processing
-				"video_thumbnail", "video_transcode", "video_watermark",
+				"video_thumbnail", "video_transcode", "video_watermark", "video_storyboard",
 			},
 			APIAccess:       APIAccessFull,
 			PriorityQueue:   true,
@@ -117,9 +117,9 @@ func GetTierLimits(tier db.SubscriptionTier) TierLimits {
 				"thumbnail",
 				"sm", "md", "lg", "xl",
 				"og", "twitter", "instagram_square", "instagram_portrait", "instagram_story",
-				"webp", "watermark",
+				"webp", "avif", "watermark",
 				// Video processing
-				"video_thumbnail", "video_transcode", "video_watermark",
+				"video_thumbnail", "video_transcode", "video_watermark", "video_storyboard",
 			},
 			APIAccess:       APIAccessFull,
 			PriorityQueue:   true,
@@ -70,6 +70,7 @@ func TestCanUseFeature(t *testing.T) {
 		{"free cannot use twitter", db.SubscriptionTierFree, "twitter", false},
 		{"free cannot use instagram_square", db.SubscriptionTierFree, "instagram_square", false},
 		{"free cannot use webp", db.SubscriptionTierFree, "webp", false},
+		{"free cannot use avif", db.SubscriptionTierFree, "avif", false},
 		{"free cannot use watermark", db.SubscriptionTierFree, "watermark", false},
 		{"pro can use thumbnail", db.SubscriptionTierPro, "thumbnail", true},
 		{"pro can use sm", db.SubscriptionTierPro, "sm", true},
@@ -82,6 +83,7 @@ func TestCanUseFeature(t *testing.T) {
 		{"pro can use instagram_portrait", db.SubscriptionTierPro, "instagram_portrait", true},
 		{"pro can use instagram_story", db.SubscriptionTierPro, "instagram_story", true},
 		{"pro can use webp", db.SubscriptionTierPro, "webp", true},
+		{"pro can use avif", db.SubscriptionTierPro, "avif", true},
 		{"pro can use watermark", db.SubscriptionTierPro, "watermark", true},
 	}
 
@@ -19,6 +19,11 @@ type Config struct {
 	DatabaseURL string
 	RedisURL    string
 
+	// StorageBackend selects the object store: "minio" or "filesystem".
+	StorageBackend    string
+	StoragePath       string
+	StorageSigningKey string
+
 	MinIOEndpoint  string
 	MinIOAccessKey string
 	MinIOSecretKey string
@@ -84,19 +89,27 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("REDIS_URL is required")
 	}
 
-	cfg.MinIOEndpoint = os.Getenv("MINIO_ENDPOINT")
-	if cfg.MinIOEndpoint == "" {
-		return nil, fmt.Errorf("MINIO_ENDPOINT is required")
-	}
+	cfg.StorageBackend = getEnvString("STORAGE_BACKEND", "minio")
+	switch cfg.StorageBackend {
+	case "minio":
+		cfg.MinIOEndpoint = os.Getenv("MINIO_ENDPOINT")
+		if cfg.MinIOEndpoint == "" {
+			return nil, fmt.Errorf("MINIO_ENDPOINT is required")
+		}
 
-	cfg.MinIOAccessKey = os.Getenv("MINIO_ACCESS_KEY")
-	if cfg.MinIOAccessKey == "" {
-		return nil, fmt.Errorf("MINIO_ACCESS_KEY is required")
-	}
+		cfg.MinIOAccessKey = os.Getenv("MINIO_ACCESS_KEY")
+		if cfg.MinIOAccessKey == "" {
+			return nil, fmt.Errorf("MINIO_ACCESS_KEY is required")
+		}
 
-	cfg.MinIOSecretKey = os.Getenv("MINIO_SECRET_KEY")
-	if cfg.MinIOSecretKey == "" {
-		return nil, fmt.Errorf("MINIO_SECRET_KEY is required")
+		cfg.MinIOSecretKey = os.Getenv("MINIO_SECRET_KEY")
+		if cfg.MinIOSecretKey == "" {
+			return nil, fmt.Errorf("MINIO_SECRET_KEY is required")
+		}
+	case "filesystem":
+		cfg.StoragePath = getEnvString("STORAGE_PATH", "./data/storage")
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND: %q", cfg.StorageBackend)
 	}
 
 	cfg.MinIOBucket = getEnvString("MINIO_BUCKET", "files")
@@ -116,6 +129,7 @@ func Load() (*Config, error) {
 	cfg.MaxVideoUploadSize = getEnvInt64("MAX_VIDEO_UPLOAD_SIZE", 500*1024*1024) // 500 MB default
 
 	cfg.JWTSecret = getEnvString("JWT_SECRET", "change-me-in-production")
+	cfg.StorageSigningKey = getEnvString("STORAGE_SIGNING_KEY", cfg.JWTSecret)
 
 	// Base URL and security
 	cfg.BaseURL = getEnvString("BASE_URL", "http://localhost:8080")
@@ -75,6 +75,72 @@ func (q *Queries) DeleteOldAuditLogs(ctx context.Context) error {
 	return err
 }
 
+const listAuditLogs = `-- name: ListAuditLogs :many
+SELECT id, user_id, action, resource_type, resource_id, ip_address, user_agent, metadata, created_at FROM audit_logs
+WHERE ($1::uuid IS NULL OR user_id = $1)
+  AND ($2::text IS NULL OR action::text = $2)
+  AND ($3::text IS NULL OR resource_type = $3)
+  AND ($4::uuid IS NULL OR resource_id = $4)
+  AND ($5::timestamptz IS NULL OR created_at >= $5)
+  AND ($6::timestamptz IS NULL OR created_at < $6)
+  AND ($7::timestamptz IS NULL
+       OR (created_at, id) < ($7, $8::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $9
+`
+
+type ListAuditLogsParams struct {
+	UserID       pgtype.UUID        `json:"user_id"`
+	Action       *string            `json:"action"`
+	ResourceType *string            `json:"resource_type"`
+	ResourceID   pgtype.UUID        `json:"resource_id"`
+	Since        pgtype.Timestamptz `json:"since"`
+	Until        pgtype.Timestamptz `json:"until"`
+	CursorTime   pgtype.Timestamptz `json:"cursor_time"`
+	CursorID     pgtype.UUID        `json:"cursor_id"`
+	Limit        int32              `json:"limit"`
+}
+
+func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, listAuditLogs,
+		arg.UserID,
+		arg.Action,
+		arg.ResourceType,
+		arg.ResourceID,
+		arg.Since,
+		arg.Until,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Action,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAuditLogsByAction = `-- name: ListAuditLogsByAction :many
 SELECT id, user_id, action, resource_type, resource_id, ip_address, user_agent, metadata, created_at FROM audit_logs
 WHERE action = $1
@@ -11,6 +11,40 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const completeBatchOperation = `-- name: CompleteBatchOperation :one
+UPDATE batch_operations
+SET status = $2, completed_at = NOW()
+WHERE id = $1 AND completed_at IS NULL
+RETURNING id, user_id, status, total_files, completed_files, failed_files, presets, webp, quality, watermark, error_message, created_at, started_at, completed_at
+`
+
+type CompleteBatchOperationParams struct {
+	ID     pgtype.UUID `json:"id"`
+	Status BatchStatus `json:"status"`
+}
+
+func (q *Queries) CompleteBatchOperation(ctx context.Context, arg CompleteBatchOperationParams) (BatchOperation, error) {
+	row := q.db.QueryRow(ctx, completeBatchOperation, arg.ID, arg.Status)
+	var i BatchOperation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.TotalFiles,
+		&i.CompletedFiles,
+		&i.FailedFiles,
+		&i.Presets,
+		&i.Webp,
+		&i.Quality,
+		&i.Watermark,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
 const countBatchItemsByStatus = `-- name: CountBatchItemsByStatus :one
 SELECT 
     COUNT(*) FILTER (WHERE status = 'pending') AS pending,
@@ -42,7 +76,7 @@ func (q *Queries) CountBatchItemsByStatus(ctx context.Context, batchID pgtype.UU
 const createBatchItem = `-- name: CreateBatchItem :one
 INSERT INTO batch_items (batch_id, file_id, job_ids)
 VALUES ($1, $2, $3)
-RETURNING id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at
+RETURNING id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at, finished_job_ids
 `
 
 type CreateBatchItemParams struct {
@@ -63,6 +97,7 @@ func (q *Queries) CreateBatchItem(ctx context.Context, arg CreateBatchItemParams
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.CompletedAt,
+		&i.FinishedJobIds,
 	)
 	return i, err
 }
@@ -112,7 +147,7 @@ func (q *Queries) CreateBatchOperation(ctx context.Context, arg CreateBatchOpera
 }
 
 const getBatchItem = `-- name: GetBatchItem :one
-SELECT id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at FROM batch_items WHERE id = $1
+SELECT id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at, finished_job_ids FROM batch_items WHERE id = $1
 `
 
 func (q *Queries) GetBatchItem(ctx context.Context, id pgtype.UUID) (BatchItem, error) {
@@ -127,6 +162,7 @@ func (q *Queries) GetBatchItem(ctx context.Context, id pgtype.UUID) (BatchItem,
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.CompletedAt,
+		&i.FinishedJobIds,
 	)
 	return i, err
 }
@@ -211,7 +247,7 @@ func (q *Queries) IncrementBatchFailedFiles(ctx context.Context, id pgtype.UUID)
 }
 
 const listBatchItems = `-- name: ListBatchItems :many
-SELECT id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at FROM batch_items WHERE batch_id = $1 ORDER BY created_at
+SELECT id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at, finished_job_ids FROM batch_items WHERE batch_id = $1 ORDER BY created_at
 `
 
 func (q *Queries) ListBatchItems(ctx context.Context, batchID pgtype.UUID) ([]BatchItem, error) {
@@ -232,6 +268,7 @@ func (q *Queries) ListBatchItems(ctx context.Context, batchID pgtype.UUID) ([]Ba
 			&i.ErrorMessage,
 			&i.CreatedAt,
 			&i.CompletedAt,
+			&i.FinishedJobIds,
 		); err != nil {
 			return nil, err
 		}
@@ -288,6 +325,38 @@ func (q *Queries) ListBatchOperationsByUser(ctx context.Context, arg ListBatchOp
 	return items, nil
 }
 
+const recordBatchJobFinished = `-- name: RecordBatchJobFinished :one
+UPDATE batch_items
+SET finished_job_ids = array_append(finished_job_ids, $1::text),
+    error_message = COALESCE(error_message, $2),
+    status = 'processing'
+WHERE job_ids @> ARRAY[$1::text]
+  AND NOT finished_job_ids @> ARRAY[$1::text]
+RETURNING id, batch_id, file_id, status, job_ids, error_message, created_at, completed_at, finished_job_ids
+`
+
+type RecordBatchJobFinishedParams struct {
+	JobID        string  `json:"job_id"`
+	ErrorMessage *string `json:"error_message"`
+}
+
+func (q *Queries) RecordBatchJobFinished(ctx context.Context, arg RecordBatchJobFinishedParams) (BatchItem, error) {
+	row := q.db.QueryRow(ctx, recordBatchJobFinished, arg.JobID, arg.ErrorMessage)
+	var i BatchItem
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.FileID,
+		&i.Status,
+		&i.JobIds,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.CompletedAt,
+		&i.FinishedJobIds,
+	)
+	return i, err
+}
+
 const updateBatchItemStatus = `-- name: UpdateBatchItemStatus :exec
 UPDATE batch_items 
 SET status = $2, error_message = $3, completed_at = CASE WHEN $2 IN ('completed', 'failed') THEN NOW() ELSE completed_at END
@@ -137,7 +137,7 @@ func (q *Queries) DeleteOldTransformCache(ctx context.Context) error {
 }
 
 const getFileShareByToken = `-- name: GetFileShareByToken :one
-SELECT s.id, s.file_id, s.token, s.expires_at, s.allowed_transforms, s.access_count, s.password_hash, s.max_downloads, s.download_count, s.created_at, f.storage_key, f.content_type, f.user_id, f.filename
+SELECT s.id, s.file_id, s.token, s.expires_at, s.allowed_transforms, s.access_count, s.password_hash, s.max_downloads, s.download_count, s.created_at, f.storage_key, f.content_type, f.user_id, f.filename, f.size_bytes, f.updated_at
 FROM file_shares s
 JOIN files f ON f.id = s.file_id
 WHERE s.token = $1
@@ -160,6 +160,8 @@ type GetFileShareByTokenRow struct {
 	ContentType       string             `json:"content_type"`
 	UserID            pgtype.UUID        `json:"user_id"`
 	Filename          string             `json:"filename"`
+	SizeBytes         int64              `json:"size_bytes"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
 }
 
 func (q *Queries) GetFileShareByToken(ctx context.Context, token string) (GetFileShareByTokenRow, error) {
@@ -180,6 +182,8 @@ func (q *Queries) GetFileShareByToken(ctx context.Context, token string) (GetFil
 		&i.ContentType,
 		&i.UserID,
 		&i.Filename,
+		&i.SizeBytes,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -23,6 +23,23 @@ func (q *Queries) CountFilesByUser(ctx context.Context, userID pgtype.UUID) (int
 	return count, err
 }
 
+const countStorageKeyReferences = `-- name: CountStorageKeyReferences :one
+SELECT COUNT(*) FROM files
+WHERE storage_key = $1 AND id <> $2
+`
+
+type CountStorageKeyReferencesParams struct {
+	StorageKey string      `json:"storage_key"`
+	ID         pgtype.UUID `json:"id"`
+}
+
+func (q *Queries) CountStorageKeyReferences(ctx context.Context, arg CountStorageKeyReferencesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countStorageKeyReferences, arg.StorageKey, arg.ID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFile = `-- name: CreateFile :one
 INSERT INTO files (
     user_id,
@@ -30,11 +47,12 @@ INSERT INTO files (
     content_type,
     size_bytes,
     storage_key,
-    status
+    status,
+    content_hash
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
+    $1, $2, $3, $4, $5, $6, $7
 )
-RETURNING id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at
+RETURNING id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash
 `
 
 type CreateFileParams struct {
@@ -44,6 +62,7 @@ type CreateFileParams struct {
 	SizeBytes   int64       `json:"size_bytes"`
 	StorageKey  string      `json:"storage_key"`
 	Status      FileStatus  `json:"status"`
+	ContentHash *string     `json:"content_hash"`
 }
 
 func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, error) {
@@ -54,6 +73,7 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		arg.SizeBytes,
 		arg.StorageKey,
 		arg.Status,
+		arg.ContentHash,
 	)
 	var i File
 	err := row.Scan(
@@ -68,12 +88,13 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ContentHash,
 	)
 	return i, err
 }
 
 const getFile = `-- name: GetFile :one
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at FROM files 
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash FROM files 
 WHERE id = $1 AND deleted_at IS NULL
 `
 
@@ -92,12 +113,45 @@ func (q *Queries) GetFile(ctx context.Context, id pgtype.UUID) (File, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ContentHash,
+	)
+	return i, err
+}
+
+const getFileByContentHash = `-- name: GetFileByContentHash :one
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash FROM files
+WHERE user_id = $1 AND content_hash = $2 AND storage_key <> '' AND deleted_at IS NULL
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+type GetFileByContentHashParams struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	ContentHash *string     `json:"content_hash"`
+}
+
+func (q *Queries) GetFileByContentHash(ctx context.Context, arg GetFileByContentHashParams) (File, error) {
+	row := q.db.QueryRow(ctx, getFileByContentHash, arg.UserID, arg.ContentHash)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FolderID,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.StorageKey,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ContentHash,
 	)
 	return i, err
 }
 
 const getFilesByIDs = `-- name: GetFilesByIDs :many
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at FROM files
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash FROM files
 WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
 `
 
@@ -122,6 +176,7 @@ func (q *Queries) GetFilesByIDs(ctx context.Context, dollar_1 []pgtype.UUID) ([]
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 		); err != nil {
 			return nil, err
 		}
@@ -135,8 +190,11 @@ func (q *Queries) GetFilesByIDs(ctx context.Context, dollar_1 []pgtype.UUID) ([]
 
 const getUserStorageUsage = `-- name: GetUserStorageUsage :one
 SELECT COALESCE(SUM(size_bytes), 0)::bigint as total_bytes
-FROM files
-WHERE user_id = $1 AND deleted_at IS NULL
+FROM (
+    SELECT DISTINCT ON (storage_key) size_bytes
+    FROM files
+    WHERE user_id = $1 AND deleted_at IS NULL AND storage_key <> ''
+) AS blobs
 `
 
 func (q *Queries) GetUserStorageUsage(ctx context.Context, userID pgtype.UUID) (int64, error) {
@@ -205,7 +263,7 @@ func (q *Queries) ListExpiredSoftDeletedFiles(ctx context.Context, limit int32)
 }
 
 const listFilesByUser = `-- name: ListFilesByUser :many
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at FROM files 
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash FROM files 
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -238,6 +296,7 @@ func (q *Queries) ListFilesByUser(ctx context.Context, arg ListFilesByUserParams
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 		); err != nil {
 			return nil, err
 		}
@@ -250,7 +309,7 @@ func (q *Queries) ListFilesByUser(ctx context.Context, arg ListFilesByUserParams
 }
 
 const listFilesByUserWithCount = `-- name: ListFilesByUserWithCount :many
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, COUNT(*) OVER() AS total_count FROM files
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash, COUNT(*) OVER() AS total_count FROM files
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -274,6 +333,7 @@ type ListFilesByUserWithCountRow struct {
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	ContentHash *string            `json:"content_hash"`
 	TotalCount  int64              `json:"total_count"`
 }
 
@@ -298,6 +358,7 @@ func (q *Queries) ListFilesByUserWithCount(ctx context.Context, arg ListFilesByU
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 			&i.TotalCount,
 		); err != nil {
 			return nil, err
@@ -356,8 +417,47 @@ func (q *Queries) MarkOriginalDeleted(ctx context.Context, id pgtype.UUID) error
 	return err
 }
 
+const replaceFileContent = `-- name: ReplaceFileContent :one
+UPDATE files
+SET storage_key = $2, size_bytes = $3, content_hash = $4, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash
+`
+
+type ReplaceFileContentParams struct {
+	ID          pgtype.UUID `json:"id"`
+	StorageKey  string      `json:"storage_key"`
+	SizeBytes   int64       `json:"size_bytes"`
+	ContentHash *string     `json:"content_hash"`
+}
+
+func (q *Queries) ReplaceFileContent(ctx context.Context, arg ReplaceFileContentParams) (File, error) {
+	row := q.db.QueryRow(ctx, replaceFileContent,
+		arg.ID,
+		arg.StorageKey,
+		arg.SizeBytes,
+		arg.ContentHash,
+	)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FolderID,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.StorageKey,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ContentHash,
+	)
+	return i, err
+}
+
 const searchFilesByUser = `-- name: SearchFilesByUser :many
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, COUNT(*) OVER() AS total_count FROM files
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash, COUNT(*) OVER() AS total_count FROM files
 WHERE user_id = $1
   AND deleted_at IS NULL
   AND ($2::text = '' OR filename ILIKE '%' || $2 || '%')
@@ -392,6 +492,7 @@ type SearchFilesByUserRow struct {
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	ContentHash *string            `json:"content_hash"`
 	TotalCount  int64              `json:"total_count"`
 }
 
@@ -425,6 +526,7 @@ func (q *Queries) SearchFilesByUser(ctx context.Context, arg SearchFilesByUserPa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 			&i.TotalCount,
 		); err != nil {
 			return nil, err
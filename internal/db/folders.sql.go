@@ -193,7 +193,7 @@ func (q *Queries) GetFolderPath(ctx context.Context, arg GetFolderPathParams) ([
 }
 
 const listFilesInFolder = `-- name: ListFilesInFolder :many
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at FROM files
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash FROM files
 WHERE user_id = $1 AND folder_id = $2 AND deleted_at IS NULL
 ORDER BY filename ASC
 `
@@ -224,6 +224,7 @@ func (q *Queries) ListFilesInFolder(ctx context.Context, arg ListFilesInFolderPa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 		); err != nil {
 			return nil, err
 		}
@@ -236,7 +237,7 @@ func (q *Queries) ListFilesInFolder(ctx context.Context, arg ListFilesInFolderPa
 }
 
 const listFilesInRoot = `-- name: ListFilesInRoot :many
-SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at FROM files
+SELECT id, user_id, folder_id, filename, content_type, size_bytes, storage_key, status, created_at, updated_at, deleted_at, content_hash FROM files
 WHERE user_id = $1 AND folder_id IS NULL AND deleted_at IS NULL
 ORDER BY filename ASC
 `
@@ -262,6 +263,7 @@ func (q *Queries) ListFilesInRoot(ctx context.Context, userID pgtype.UUID) ([]Fi
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 		); err != nil {
 			return nil, err
 		}
@@ -180,6 +180,26 @@ func (q *Queries) GetJobByUser(ctx context.Context, arg GetJobByUserParams) (Get
 	return i, err
 }
 
+const getJobEventTarget = `-- name: GetJobEventTarget :one
+SELECT pj.file_id, pj.job_type, f.user_id
+FROM processing_jobs pj
+JOIN files f ON f.id = pj.file_id
+WHERE pj.id = $1
+`
+
+type GetJobEventTargetRow struct {
+	FileID  pgtype.UUID `json:"file_id"`
+	JobType JobType     `json:"job_type"`
+	UserID  pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetJobEventTarget(ctx context.Context, id pgtype.UUID) (GetJobEventTargetRow, error) {
+	row := q.db.QueryRow(ctx, getJobEventTarget, id)
+	var i GetJobEventTargetRow
+	err := row.Scan(&i.FileID, &i.JobType, &i.UserID)
+	return i, err
+}
+
 const listJobsByFileID = `-- name: ListJobsByFileID :many
 SELECT id, file_id, job_type, status, priority, attempts, error_message, created_at, started_at, completed_at FROM processing_jobs
 WHERE file_id = $1
@@ -15,20 +15,21 @@ import (
 type AuditAction string
 
 const (
-	AuditActionFileupload         AuditAction = "file.upload"
-	AuditActionFiledownload       AuditAction = "file.download"
-	AuditActionFiledelete         AuditAction = "file.delete"
-	AuditActionFileshare          AuditAction = "file.share"
-	AuditActionShareaccess        AuditAction = "share.access"
-	AuditActionSharedelete        AuditAction = "share.delete"
-	AuditActionUserlogin          AuditAction = "user.login"
-	AuditActionUserlogout         AuditAction = "user.logout"
-	AuditActionUserpasswordChange AuditAction = "user.password_change"
-	AuditActionSettingsupdate     AuditAction = "settings.update"
-	AuditActionApiTokencreate     AuditAction = "api_token.create"
-	AuditActionApiTokendelete     AuditAction = "api_token.delete"
-	AuditActionWebhookcreate      AuditAction = "webhook.create"
-	AuditActionWebhookdelete      AuditAction = "webhook.delete"
+	AuditActionFileupload          AuditAction = "file.upload"
+	AuditActionFiledownload        AuditAction = "file.download"
+	AuditActionFiledelete          AuditAction = "file.delete"
+	AuditActionFileshare           AuditAction = "file.share"
+	AuditActionShareaccess         AuditAction = "share.access"
+	AuditActionSharedelete         AuditAction = "share.delete"
+	AuditActionUserlogin           AuditAction = "user.login"
+	AuditActionUserlogout          AuditAction = "user.logout"
+	AuditActionUserpasswordChange  AuditAction = "user.password_change"
+	AuditActionSettingsupdate      AuditAction = "settings.update"
+	AuditActionApiTokencreate      AuditAction = "api_token.create"
+	AuditActionApiTokendelete      AuditAction = "api_token.delete"
+	AuditActionWebhookcreate       AuditAction = "webhook.create"
+	AuditActionWebhookdelete       AuditAction = "webhook.delete"
+	AuditActionWebhookrotateSecret AuditAction = "webhook.rotate_secret"
 )
 
 func (e *AuditAction) Scan(src interface{}) error {
@@ -202,18 +203,23 @@ func (ns NullJobStatus) Value() (driver.Value, error) {
 type JobType string
 
 const (
-	JobTypeThumbnail      JobType = "thumbnail"
-	JobTypeResize         JobType = "resize"
-	JobTypeWebp           JobType = "webp"
-	JobTypeWatermark      JobType = "watermark"
-	JobTypePdfThumbnail   JobType = "pdf_thumbnail"
-	JobTypeMetadata       JobType = "metadata"
-	JobTypeOptimize       JobType = "optimize"
-	JobTypeVideoThumbnail JobType = "video_thumbnail"
-	JobTypeVideoTranscode JobType = "video_transcode"
-	JobTypeVideoHls       JobType = "video_hls"
-	JobTypeVideoWatermark JobType = "video_watermark"
-	JobTypeZipDownload    JobType = "zip_download"
+	JobTypeThumbnail       JobType = "thumbnail"
+	JobTypeResize          JobType = "resize"
+	JobTypeWebp            JobType = "webp"
+	JobTypeWatermark       JobType = "watermark"
+	JobTypePdfThumbnail    JobType = "pdf_thumbnail"
+	JobTypeMetadata        JobType = "metadata"
+	JobTypeOptimize        JobType = "optimize"
+	JobTypeVideoThumbnail  JobType = "video_thumbnail"
+	JobTypeVideoTranscode  JobType = "video_transcode"
+	JobTypeVideoHls        JobType = "video_hls"
+	JobTypeVideoWatermark  JobType = "video_watermark"
+	JobTypeZipDownload     JobType = "zip_download"
+	JobTypeTransform       JobType = "transform"
+	JobTypeConvert         JobType = "convert"
+	JobTypeVideoDash       JobType = "video_dash"
+	JobTypeVideoStoryboard JobType = "video_storyboard"
+	JobTypeVideoPreview    JobType = "video_preview"
 )
 
 func (e *JobType) Scan(src interface{}) error {
@@ -456,6 +462,12 @@ const (
 	VariantTypeHls720p           VariantType = "hls_720p"
 	VariantTypeHls1080p          VariantType = "hls_1080p"
 	VariantTypeVideoWatermarked  VariantType = "video_watermarked"
+	VariantTypeAvif              VariantType = "avif"
+	VariantTypeDashManifest      VariantType = "dash_manifest"
+	VariantTypeStoryboardVtt     VariantType = "storyboard_vtt"
+	VariantTypeVideoPreviewMp4   VariantType = "video_preview_mp4"
+	VariantTypeVideoPreviewWebp  VariantType = "video_preview_webp"
+	VariantTypeVideoPreviewGif   VariantType = "video_preview_gif"
 )
 
 func (e *VariantType) Scan(src interface{}) error {
@@ -571,14 +583,15 @@ type AuditLog struct {
 }
 
 type BatchItem struct {
-	ID           pgtype.UUID        `json:"id"`
-	BatchID      pgtype.UUID        `json:"batch_id"`
-	FileID       pgtype.UUID        `json:"file_id"`
-	Status       BatchStatus        `json:"status"`
-	JobIds       []string           `json:"job_ids"`
-	ErrorMessage *string            `json:"error_message"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	CompletedAt  pgtype.Timestamptz `json:"completed_at"`
+	ID             pgtype.UUID        `json:"id"`
+	BatchID        pgtype.UUID        `json:"batch_id"`
+	FileID         pgtype.UUID        `json:"file_id"`
+	Status         BatchStatus        `json:"status"`
+	JobIds         []string           `json:"job_ids"`
+	ErrorMessage   *string            `json:"error_message"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	CompletedAt    pgtype.Timestamptz `json:"completed_at"`
+	FinishedJobIds []string           `json:"finished_job_ids"`
 }
 
 type BatchOperation struct {
@@ -636,6 +649,29 @@ type File struct {
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	ContentHash *string            `json:"content_hash"`
+}
+
+type FileMetadatum struct {
+	FileID       pgtype.UUID        `json:"file_id"`
+	Width        int32              `json:"width"`
+	Height       int32              `json:"height"`
+	Format       string             `json:"format"`
+	CameraMake   *string            `json:"camera_make"`
+	CameraModel  *string            `json:"camera_model"`
+	LensModel    *string            `json:"lens_model"`
+	CapturedAt   pgtype.Timestamptz `json:"captured_at"`
+	GpsLatitude  *float64           `json:"gps_latitude"`
+	GpsLongitude *float64           `json:"gps_longitude"`
+	GpsAltitude  *float64           `json:"gps_altitude"`
+	Orientation  int32              `json:"orientation"`
+	IccProfile   *string            `json:"icc_profile"`
+	Caption      *string            `json:"caption"`
+	Keywords     []string           `json:"keywords"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	Blurhash     *string            `json:"blurhash"`
+	Lqip         *string            `json:"lqip"`
 }
 
 type FileShare struct {
@@ -768,6 +804,35 @@ type TransformCache struct {
 	LastAccessedAt  pgtype.Timestamptz `json:"last_accessed_at"`
 }
 
+type TransformPreset struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Name      string             `json:"name"`
+	Width     *int32             `json:"width"`
+	Height    *int32             `json:"height"`
+	Fit       *string            `json:"fit"`
+	Position  *string            `json:"position"`
+	Format    *string            `json:"format"`
+	Quality   *int32             `json:"quality"`
+	Watermark *string            `json:"watermark"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UploadRule struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Name            string             `json:"name"`
+	ContentType     *string            `json:"content_type"`
+	FolderID        pgtype.UUID        `json:"folder_id"`
+	Tag             *string            `json:"tag"`
+	FilenamePattern *string            `json:"filename_pattern"`
+	Jobs            []string           `json:"jobs"`
+	Enabled         bool               `json:"enabled"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
 type User struct {
 	ID                     pgtype.UUID        `json:"id"`
 	Email                  string             `json:"email"`
@@ -812,17 +877,22 @@ type UserSetting struct {
 }
 
 type Webhook struct {
-	ID                  pgtype.UUID        `json:"id"`
-	UserID              pgtype.UUID        `json:"user_id"`
-	Url                 string             `json:"url"`
-	Secret              string             `json:"secret"`
-	Events              []string           `json:"events"`
-	Active              bool               `json:"active"`
-	ConsecutiveFailures *int32             `json:"consecutive_failures"`
-	LastFailureAt       pgtype.Timestamptz `json:"last_failure_at"`
-	CircuitState        *string            `json:"circuit_state"`
-	CreatedAt           pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	ID                      pgtype.UUID        `json:"id"`
+	UserID                  pgtype.UUID        `json:"user_id"`
+	Url                     string             `json:"url"`
+	Secret                  string             `json:"secret"`
+	Events                  []string           `json:"events"`
+	Active                  bool               `json:"active"`
+	ConsecutiveFailures     *int32             `json:"consecutive_failures"`
+	LastFailureAt           pgtype.Timestamptz `json:"last_failure_at"`
+	CircuitState            *string            `json:"circuit_state"`
+	Format                  string             `json:"format"`
+	PreviousSecret          *string            `json:"previous_secret"`
+	PreviousSecretExpiresAt pgtype.Timestamptz `json:"previous_secret_expires_at"`
+	FailingSince            pgtype.Timestamptz `json:"failing_since"`
+	DisabledAt              pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt               pgtype.Timestamptz `json:"updated_at"`
 }
 
 type WebhookDelivery struct {
@@ -854,6 +924,24 @@ type WebhookDlq struct {
 	CreatedAt        pgtype.Timestamptz `json:"created_at"`
 }
 
+type WebhookDlqReplay struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	WebhookID       pgtype.UUID        `json:"webhook_id"`
+	EventType       *string            `json:"event_type"`
+	CreatedAfter    pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore   pgtype.Timestamptz `json:"created_before"`
+	RatePerSecond   int32              `json:"rate_per_second"`
+	Status          string             `json:"status"`
+	TotalEntries    int32              `json:"total_entries"`
+	ReplayedEntries int32              `json:"replayed_entries"`
+	FailedEntries   int32              `json:"failed_entries"`
+	ErrorMessage    *string            `json:"error_message"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	CompletedAt     pgtype.Timestamptz `json:"completed_at"`
+}
+
 type ZipDownload struct {
 	ID           pgtype.UUID        `json:"id"`
 	UserID       pgtype.UUID        `json:"user_id"`
@@ -108,7 +108,7 @@ func (q *Queries) DeleteTagByName(ctx context.Context, arg DeleteTagByNameParams
 }
 
 const listFilesByTag = `-- name: ListFilesByTag :many
-SELECT f.id, f.user_id, f.folder_id, f.filename, f.content_type, f.size_bytes, f.storage_key, f.status, f.created_at, f.updated_at, f.deleted_at, COUNT(*) OVER() AS total_count
+SELECT f.id, f.user_id, f.folder_id, f.filename, f.content_type, f.size_bytes, f.storage_key, f.status, f.created_at, f.updated_at, f.deleted_at, f.content_hash, COUNT(*) OVER() AS total_count
 FROM files f
 JOIN file_tags ft ON ft.file_id = f.id
 WHERE ft.user_id = $1
@@ -137,6 +137,7 @@ type ListFilesByTagRow struct {
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	ContentHash *string            `json:"content_hash"`
 	TotalCount  int64              `json:"total_count"`
 }
 
@@ -166,6 +167,7 @@ func (q *Queries) ListFilesByTag(ctx context.Context, arg ListFilesByTagParams)
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ContentHash,
 			&i.TotalCount,
 		); err != nil {
 			return nil, err
@@ -407,8 +407,11 @@ func (q *Queries) GetUserSubscriptionTier(ctx context.Context, id pgtype.UUID) (
 
 const getUserTotalStorageUsage = `-- name: GetUserTotalStorageUsage :one
 SELECT COALESCE(SUM(size_bytes), 0)::bigint AS total_bytes
-FROM files
-WHERE user_id = $1 AND deleted_at IS NULL
+FROM (
+    SELECT DISTINCT ON (storage_key) size_bytes
+    FROM files
+    WHERE user_id = $1 AND deleted_at IS NULL AND storage_key <> ''
+) AS blobs
 `
 
 func (q *Queries) GetUserTotalStorageUsage(ctx context.Context, userID pgtype.UUID) (int64, error) {
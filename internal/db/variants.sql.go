@@ -150,6 +150,21 @@ func (q *Queries) DeleteVariantsByFile(ctx context.Context, fileID pgtype.UUID)
 	return err
 }
 
+const deleteVariantsByType = `-- name: DeleteVariantsByType :exec
+DELETE FROM file_variants
+WHERE file_id = $1 AND variant_type = $2
+`
+
+type DeleteVariantsByTypeParams struct {
+	FileID      pgtype.UUID `json:"file_id"`
+	VariantType VariantType `json:"variant_type"`
+}
+
+func (q *Queries) DeleteVariantsByType(ctx context.Context, arg DeleteVariantsByTypeParams) error {
+	_, err := q.db.Exec(ctx, deleteVariantsByType, arg.FileID, arg.VariantType)
+	return err
+}
+
 const getThumbnailsForFiles = `-- name: GetThumbnailsForFiles :many
 SELECT file_id, storage_key, content_type, size_bytes
 FROM file_variants
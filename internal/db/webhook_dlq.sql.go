@@ -11,6 +11,117 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimWebhookDLQForReplay = `-- name: ClaimWebhookDLQForReplay :many
+UPDATE webhook_dlq
+SET can_retry = false, retried_at = NOW()
+WHERE webhook_dlq.id IN (
+    SELECT dlq.id
+    FROM webhook_dlq dlq
+    JOIN webhooks w ON w.id = dlq.webhook_id
+    JOIN webhook_dlq_replays r ON r.id = $1
+    WHERE w.user_id = r.user_id
+      AND dlq.can_retry = true
+      AND (r.webhook_id IS NULL OR dlq.webhook_id = r.webhook_id)
+      AND (r.event_type IS NULL OR dlq.event_type = r.event_type)
+      AND (r.created_after IS NULL OR dlq.created_at >= r.created_after)
+      AND dlq.created_at < r.created_before
+    ORDER BY dlq.created_at
+    LIMIT $2
+    FOR UPDATE OF dlq SKIP LOCKED
+)
+RETURNING id, webhook_id, delivery_id, event_type, payload, final_error, attempts, last_response_code, last_response_body, can_retry, retried_at, created_at
+`
+
+type ClaimWebhookDLQForReplayParams struct {
+	ReplayID  pgtype.UUID `json:"replay_id"`
+	BatchSize int32       `json:"batch_size"`
+}
+
+// Claims up to batch_size retryable entries matching the replay's filters,
+// marking them retried so concurrent replays never pick the same row.
+func (q *Queries) ClaimWebhookDLQForReplay(ctx context.Context, arg ClaimWebhookDLQForReplayParams) ([]WebhookDlq, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDLQForReplay, arg.ReplayID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDlq
+	for rows.Next() {
+		var i WebhookDlq
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.DeliveryID,
+			&i.EventType,
+			&i.Payload,
+			&i.FinalError,
+			&i.Attempts,
+			&i.LastResponseCode,
+			&i.LastResponseBody,
+			&i.CanRetry,
+			&i.RetriedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeWebhookDLQReplay = `-- name: CompleteWebhookDLQReplay :exec
+UPDATE webhook_dlq_replays
+SET status = $2, error_message = $3, completed_at = NOW()
+WHERE id = $1
+`
+
+type CompleteWebhookDLQReplayParams struct {
+	ID           pgtype.UUID `json:"id"`
+	Status       string      `json:"status"`
+	ErrorMessage *string     `json:"error_message"`
+}
+
+func (q *Queries) CompleteWebhookDLQReplay(ctx context.Context, arg CompleteWebhookDLQReplayParams) error {
+	_, err := q.db.Exec(ctx, completeWebhookDLQReplay, arg.ID, arg.Status, arg.ErrorMessage)
+	return err
+}
+
+const countReplayableWebhookDLQ = `-- name: CountReplayableWebhookDLQ :one
+SELECT COUNT(*)
+FROM webhook_dlq dlq
+JOIN webhooks w ON w.id = dlq.webhook_id
+WHERE w.user_id = $1
+  AND dlq.can_retry = true
+  AND ($2::uuid IS NULL OR dlq.webhook_id = $2)
+  AND ($3::text IS NULL OR dlq.event_type = $3)
+  AND ($4::timestamptz IS NULL OR dlq.created_at >= $4)
+  AND dlq.created_at < $5
+`
+
+type CountReplayableWebhookDLQParams struct {
+	UserID        pgtype.UUID        `json:"user_id"`
+	WebhookID     pgtype.UUID        `json:"webhook_id"`
+	EventType     *string            `json:"event_type"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+}
+
+func (q *Queries) CountReplayableWebhookDLQ(ctx context.Context, arg CountReplayableWebhookDLQParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countReplayableWebhookDLQ,
+		arg.UserID,
+		arg.WebhookID,
+		arg.EventType,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countWebhookDLQByUser = `-- name: CountWebhookDLQByUser :one
 SELECT COUNT(*)
 FROM webhook_dlq dlq
@@ -80,6 +191,64 @@ func (q *Queries) CreateWebhookDLQEntry(ctx context.Context, arg CreateWebhookDL
 	return i, err
 }
 
+const createWebhookDLQReplay = `-- name: CreateWebhookDLQReplay :one
+INSERT INTO webhook_dlq_replays (
+    user_id,
+    webhook_id,
+    event_type,
+    created_after,
+    created_before,
+    rate_per_second,
+    status,
+    total_entries
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, user_id, webhook_id, event_type, created_after, created_before, rate_per_second, status, total_entries, replayed_entries, failed_entries, error_message, created_at, started_at, completed_at
+`
+
+type CreateWebhookDLQReplayParams struct {
+	UserID        pgtype.UUID        `json:"user_id"`
+	WebhookID     pgtype.UUID        `json:"webhook_id"`
+	EventType     *string            `json:"event_type"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+	RatePerSecond int32              `json:"rate_per_second"`
+	Status        string             `json:"status"`
+	TotalEntries  int32              `json:"total_entries"`
+}
+
+func (q *Queries) CreateWebhookDLQReplay(ctx context.Context, arg CreateWebhookDLQReplayParams) (WebhookDlqReplay, error) {
+	row := q.db.QueryRow(ctx, createWebhookDLQReplay,
+		arg.UserID,
+		arg.WebhookID,
+		arg.EventType,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.RatePerSecond,
+		arg.Status,
+		arg.TotalEntries,
+	)
+	var i WebhookDlqReplay
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.CreatedAfter,
+		&i.CreatedBefore,
+		&i.RatePerSecond,
+		&i.Status,
+		&i.TotalEntries,
+		&i.ReplayedEntries,
+		&i.FailedEntries,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
 const deleteOldDLQEntries = `-- name: DeleteOldDLQEntries :exec
 DELETE FROM webhook_dlq
 WHERE created_at < NOW() - INTERVAL '30 days'
@@ -125,6 +294,67 @@ func (q *Queries) GetWebhookDLQEntry(ctx context.Context, id pgtype.UUID) (Webho
 	return i, err
 }
 
+const getWebhookDLQReplay = `-- name: GetWebhookDLQReplay :one
+SELECT id, user_id, webhook_id, event_type, created_after, created_before, rate_per_second, status, total_entries, replayed_entries, failed_entries, error_message, created_at, started_at, completed_at FROM webhook_dlq_replays
+WHERE id = $1 AND user_id = $2
+`
+
+type GetWebhookDLQReplayParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetWebhookDLQReplay(ctx context.Context, arg GetWebhookDLQReplayParams) (WebhookDlqReplay, error) {
+	row := q.db.QueryRow(ctx, getWebhookDLQReplay, arg.ID, arg.UserID)
+	var i WebhookDlqReplay
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.CreatedAfter,
+		&i.CreatedBefore,
+		&i.RatePerSecond,
+		&i.Status,
+		&i.TotalEntries,
+		&i.ReplayedEntries,
+		&i.FailedEntries,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getWebhookDLQReplayByID = `-- name: GetWebhookDLQReplayByID :one
+SELECT id, user_id, webhook_id, event_type, created_after, created_before, rate_per_second, status, total_entries, replayed_entries, failed_entries, error_message, created_at, started_at, completed_at FROM webhook_dlq_replays
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookDLQReplayByID(ctx context.Context, id pgtype.UUID) (WebhookDlqReplay, error) {
+	row := q.db.QueryRow(ctx, getWebhookDLQReplayByID, id)
+	var i WebhookDlqReplay
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.CreatedAfter,
+		&i.CreatedBefore,
+		&i.RatePerSecond,
+		&i.Status,
+		&i.TotalEntries,
+		&i.ReplayedEntries,
+		&i.FailedEntries,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
 const listRetryableWebhookDLQ = `-- name: ListRetryableWebhookDLQ :many
 SELECT dlq.id, dlq.webhook_id, dlq.delivery_id, dlq.event_type, dlq.payload, dlq.final_error, dlq.attempts, dlq.last_response_code, dlq.last_response_body, dlq.can_retry, dlq.retried_at, dlq.created_at
 FROM webhook_dlq dlq
@@ -276,3 +506,43 @@ func (q *Queries) MarkWebhookDLQRetried(ctx context.Context, id pgtype.UUID) err
 	_, err := q.db.Exec(ctx, markWebhookDLQRetried, id)
 	return err
 }
+
+const releaseWebhookDLQEntry = `-- name: ReleaseWebhookDLQEntry :exec
+UPDATE webhook_dlq
+SET can_retry = true, retried_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ReleaseWebhookDLQEntry(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, releaseWebhookDLQEntry, id)
+	return err
+}
+
+const startWebhookDLQReplay = `-- name: StartWebhookDLQReplay :exec
+UPDATE webhook_dlq_replays
+SET status = 'running', started_at = COALESCE(started_at, NOW())
+WHERE id = $1
+`
+
+func (q *Queries) StartWebhookDLQReplay(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, startWebhookDLQReplay, id)
+	return err
+}
+
+const updateWebhookDLQReplayProgress = `-- name: UpdateWebhookDLQReplayProgress :exec
+UPDATE webhook_dlq_replays
+SET replayed_entries = replayed_entries + $1::int,
+    failed_entries = failed_entries + $2::int
+WHERE id = $3
+`
+
+type UpdateWebhookDLQReplayProgressParams struct {
+	Replayed int32       `json:"replayed"`
+	Failed   int32       `json:"failed"`
+	ID       pgtype.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateWebhookDLQReplayProgress(ctx context.Context, arg UpdateWebhookDLQReplayProgressParams) error {
+	_, err := q.db.Exec(ctx, updateWebhookDLQReplayProgress, arg.Replayed, arg.Failed, arg.ID)
+	return err
+}
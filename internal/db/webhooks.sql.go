@@ -36,9 +36,9 @@ func (q *Queries) CountWebhooksByUser(ctx context.Context, userID pgtype.UUID) (
 }
 
 const createWebhook = `-- name: CreateWebhook :one
-INSERT INTO webhooks (user_id, url, secret, events)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, created_at, updated_at
+INSERT INTO webhooks (user_id, url, secret, events, format)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at
 `
 
 type CreateWebhookParams struct {
@@ -46,6 +46,7 @@ type CreateWebhookParams struct {
 	Url    string      `json:"url"`
 	Secret string      `json:"secret"`
 	Events []string    `json:"events"`
+	Format string      `json:"format"`
 }
 
 func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
@@ -54,6 +55,7 @@ func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (W
 		arg.Url,
 		arg.Secret,
 		arg.Events,
+		arg.Format,
 	)
 	var i Webhook
 	err := row.Scan(
@@ -66,6 +68,11 @@ func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (W
 		&i.ConsecutiveFailures,
 		&i.LastFailureAt,
 		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -118,8 +125,54 @@ func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) er
 	return err
 }
 
+const deleteWebhookDelivery = `-- name: DeleteWebhookDelivery :exec
+DELETE FROM webhook_deliveries
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookDelivery(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteWebhookDelivery, id)
+	return err
+}
+
+const disableFailingWebhook = `-- name: DisableFailingWebhook :one
+UPDATE webhooks
+SET active = false, disabled_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND active = true AND failing_since < $2
+RETURNING id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at
+`
+
+type DisableFailingWebhookParams struct {
+	ID           pgtype.UUID        `json:"id"`
+	FailingSince pgtype.Timestamptz `json:"failing_since"`
+}
+
+func (q *Queries) DisableFailingWebhook(ctx context.Context, arg DisableFailingWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, disableFailingWebhook, arg.ID, arg.FailingSince)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.Active,
+		&i.ConsecutiveFailures,
+		&i.LastFailureAt,
+		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getWebhook = `-- name: GetWebhook :one
-SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, created_at, updated_at FROM webhooks
+SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at FROM webhooks
 WHERE id = $1 AND user_id = $2
 `
 
@@ -141,6 +194,11 @@ func (q *Queries) GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook
 		&i.ConsecutiveFailures,
 		&i.LastFailureAt,
 		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -148,7 +206,7 @@ func (q *Queries) GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook
 }
 
 const getWebhookByDeliveryID = `-- name: GetWebhookByDeliveryID :one
-SELECT w.id, w.user_id, w.url, w.secret, w.events, w.active, w.consecutive_failures, w.last_failure_at, w.circuit_state, w.created_at, w.updated_at FROM webhooks w
+SELECT w.id, w.user_id, w.url, w.secret, w.events, w.active, w.consecutive_failures, w.last_failure_at, w.circuit_state, w.format, w.previous_secret, w.previous_secret_expires_at, w.failing_since, w.disabled_at, w.created_at, w.updated_at FROM webhooks w
 JOIN webhook_deliveries wd ON wd.webhook_id = w.id
 WHERE wd.id = $1
 `
@@ -166,6 +224,11 @@ func (q *Queries) GetWebhookByDeliveryID(ctx context.Context, id pgtype.UUID) (W
 		&i.ConsecutiveFailures,
 		&i.LastFailureAt,
 		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -173,7 +236,7 @@ func (q *Queries) GetWebhookByDeliveryID(ctx context.Context, id pgtype.UUID) (W
 }
 
 const getWebhookByID = `-- name: GetWebhookByID :one
-SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, created_at, updated_at FROM webhooks
+SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at FROM webhooks
 WHERE id = $1
 `
 
@@ -190,6 +253,11 @@ func (q *Queries) GetWebhookByID(ctx context.Context, id pgtype.UUID) (Webhook,
 		&i.ConsecutiveFailures,
 		&i.LastFailureAt,
 		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -247,21 +315,55 @@ func (q *Queries) GetWebhookDelivery(ctx context.Context, id pgtype.UUID) (Webho
 	return i, err
 }
 
-const incrementWebhookFailures = `-- name: IncrementWebhookFailures :exec
+const halfOpenWebhookCircuit = `-- name: HalfOpenWebhookCircuit :exec
 UPDATE webhooks
-SET consecutive_failures = consecutive_failures + 1,
-    last_failure_at = NOW(),
-    circuit_state = CASE WHEN consecutive_failures >= 9 THEN 'open' ELSE circuit_state END
-WHERE id = $1
+SET circuit_state = 'half_open'
+WHERE id = $1 AND circuit_state = 'open' AND last_failure_at < $2
 `
 
-func (q *Queries) IncrementWebhookFailures(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, incrementWebhookFailures, id)
+type HalfOpenWebhookCircuitParams struct {
+	ID            pgtype.UUID        `json:"id"`
+	LastFailureAt pgtype.Timestamptz `json:"last_failure_at"`
+}
+
+func (q *Queries) HalfOpenWebhookCircuit(ctx context.Context, arg HalfOpenWebhookCircuitParams) error {
+	_, err := q.db.Exec(ctx, halfOpenWebhookCircuit, arg.ID, arg.LastFailureAt)
 	return err
 }
 
+const incrementWebhookFailures = `-- name: IncrementWebhookFailures :one
+UPDATE webhooks
+SET consecutive_failures = COALESCE(consecutive_failures, 0) + 1,
+    last_failure_at = NOW(),
+    failing_since = COALESCE(failing_since, NOW()),
+    circuit_state = CASE
+        WHEN circuit_state = 'half_open' OR COALESCE(consecutive_failures, 0) + 1 >= $1::int THEN 'open'
+        ELSE circuit_state
+    END
+WHERE id = $2
+RETURNING consecutive_failures, circuit_state, failing_since
+`
+
+type IncrementWebhookFailuresParams struct {
+	FailureThreshold int32       `json:"failure_threshold"`
+	ID               pgtype.UUID `json:"id"`
+}
+
+type IncrementWebhookFailuresRow struct {
+	ConsecutiveFailures *int32             `json:"consecutive_failures"`
+	CircuitState        *string            `json:"circuit_state"`
+	FailingSince        pgtype.Timestamptz `json:"failing_since"`
+}
+
+func (q *Queries) IncrementWebhookFailures(ctx context.Context, arg IncrementWebhookFailuresParams) (IncrementWebhookFailuresRow, error) {
+	row := q.db.QueryRow(ctx, incrementWebhookFailures, arg.FailureThreshold, arg.ID)
+	var i IncrementWebhookFailuresRow
+	err := row.Scan(&i.ConsecutiveFailures, &i.CircuitState, &i.FailingSince)
+	return i, err
+}
+
 const listActiveWebhooksByUserAndEvent = `-- name: ListActiveWebhooksByUserAndEvent :many
-SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, created_at, updated_at FROM webhooks
+SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at FROM webhooks
 WHERE user_id = $1 AND active = true AND $2::text = ANY(events)
 `
 
@@ -289,6 +391,11 @@ func (q *Queries) ListActiveWebhooksByUserAndEvent(ctx context.Context, arg List
 			&i.ConsecutiveFailures,
 			&i.LastFailureAt,
 			&i.CircuitState,
+			&i.Format,
+			&i.PreviousSecret,
+			&i.PreviousSecretExpiresAt,
+			&i.FailingSince,
+			&i.DisabledAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -429,7 +536,7 @@ func (q *Queries) ListPendingDeliveries(ctx context.Context, limit int32) ([]Web
 }
 
 const listWebhooksByUser = `-- name: ListWebhooksByUser :many
-SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, created_at, updated_at FROM webhooks
+SELECT id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at FROM webhooks
 WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -460,6 +567,11 @@ func (q *Queries) ListWebhooksByUser(ctx context.Context, arg ListWebhooksByUser
 			&i.ConsecutiveFailures,
 			&i.LastFailureAt,
 			&i.CircuitState,
+			&i.Format,
+			&i.PreviousSecret,
+			&i.PreviousSecretExpiresAt,
+			&i.FailingSince,
+			&i.DisabledAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -511,7 +623,7 @@ func (q *Queries) MarkDeliverySuccess(ctx context.Context, arg MarkDeliverySucce
 
 const resetWebhookFailures = `-- name: ResetWebhookFailures :exec
 UPDATE webhooks
-SET consecutive_failures = 0, circuit_state = 'closed'
+SET consecutive_failures = 0, circuit_state = 'closed', failing_since = NULL
 WHERE id = $1
 `
 
@@ -520,6 +632,49 @@ func (q *Queries) ResetWebhookFailures(ctx context.Context, id pgtype.UUID) erro
 	return err
 }
 
+const rotateWebhookSecret = `-- name: RotateWebhookSecret :one
+UPDATE webhooks
+SET secret = $3, previous_secret = secret, previous_secret_expires_at = $4, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at
+`
+
+type RotateWebhookSecretParams struct {
+	ID                      pgtype.UUID        `json:"id"`
+	UserID                  pgtype.UUID        `json:"user_id"`
+	Secret                  string             `json:"secret"`
+	PreviousSecretExpiresAt pgtype.Timestamptz `json:"previous_secret_expires_at"`
+}
+
+func (q *Queries) RotateWebhookSecret(ctx context.Context, arg RotateWebhookSecretParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, rotateWebhookSecret,
+		arg.ID,
+		arg.UserID,
+		arg.Secret,
+		arg.PreviousSecretExpiresAt,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.Active,
+		&i.ConsecutiveFailures,
+		&i.LastFailureAt,
+		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const setWebhookCircuitState = `-- name: SetWebhookCircuitState :exec
 UPDATE webhooks
 SET circuit_state = $2
@@ -562,26 +717,35 @@ func (q *Queries) UpdateDeliveryRetry(ctx context.Context, arg UpdateDeliveryRet
 
 const updateWebhook = `-- name: UpdateWebhook :one
 UPDATE webhooks
-SET url = $3, events = $4, active = $5, updated_at = NOW()
-WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, created_at, updated_at
+SET url = $1, events = $2, active = $3,
+    format = COALESCE($4, format),
+    consecutive_failures = CASE WHEN $3 AND NOT active THEN 0 ELSE consecutive_failures END,
+    circuit_state = CASE WHEN $3 AND NOT active THEN 'closed' ELSE circuit_state END,
+    failing_since = CASE WHEN $3 AND NOT active THEN NULL ELSE failing_since END,
+    disabled_at = CASE WHEN $3 THEN NULL ELSE disabled_at END,
+    updated_at = NOW()
+WHERE id = $5 AND user_id = $6
+RETURNING id, user_id, url, secret, events, active, consecutive_failures, last_failure_at, circuit_state, format, previous_secret, previous_secret_expires_at, failing_since, disabled_at, created_at, updated_at
 `
 
 type UpdateWebhookParams struct {
-	ID     pgtype.UUID `json:"id"`
-	UserID pgtype.UUID `json:"user_id"`
 	Url    string      `json:"url"`
 	Events []string    `json:"events"`
 	Active bool        `json:"active"`
+	Format *string     `json:"format"`
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
 }
 
+// Re-activating a disabled webhook also closes its circuit.
 func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
 	row := q.db.QueryRow(ctx, updateWebhook,
-		arg.ID,
-		arg.UserID,
 		arg.Url,
 		arg.Events,
 		arg.Active,
+		arg.Format,
+		arg.ID,
+		arg.UserID,
 	)
 	var i Webhook
 	err := row.Scan(
@@ -594,6 +758,11 @@ func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (W
 		&i.ConsecutiveFailures,
 		&i.LastFailureAt,
 		&i.CircuitState,
+		&i.Format,
+		&i.PreviousSecret,
+		&i.PreviousSecretExpiresAt,
+		&i.FailingSince,
+		&i.DisabledAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -413,6 +413,36 @@ func (s *Service) SendEnterpriseEnabledEmail(to, name string) error {
 	return s.Send(to, "Your Enterprise plan is now active!", html)
 }
 
+// WebhookDisabledEmailData contains data for the webhook disabled notification email.
+type WebhookDisabledEmailData struct {
+	EmailData
+	WebhookURL   string
+	FailingSince string
+	DocsURL      string
+}
+
+// SendWebhookDisabledEmail tells a user that one of their webhooks was
+// disabled after failing continuously since failingSince.
+func (s *Service) SendWebhookDisabledEmail(to, name, webhookURL string, failingSince time.Time) error {
+	data := WebhookDisabledEmailData{
+		EmailData: EmailData{
+			RecipientName: name,
+			BaseURL:       s.cfg.BaseURL,
+			Year:          time.Now().Year(),
+		},
+		WebhookURL:   webhookURL,
+		FailingSince: failingSince.UTC().Format("January 2, 2006 15:04 MST"),
+		DocsURL:      fmt.Sprintf("%s/docs?section=webhooks", s.cfg.BaseURL),
+	}
+
+	html, err := s.renderTemplate(webhookDisabledEmailTemplate, data)
+	if err != nil {
+		return err
+	}
+
+	return s.Send(to, "Your webhook has been disabled", html)
+}
+
 const enterpriseInquiryEmailTemplate = `
 <!DOCTYPE html>
 <html>
@@ -546,6 +576,64 @@ const enterpriseEnabledEmailTemplate = `
 </html>
 `
 
+const webhookDisabledEmailTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+</head>
+<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #2E3440;">
+    <table role="presentation" style="width: 100%; border-collapse: collapse;">
+        <tr>
+            <td style="padding: 40px 20px;">
+                <table role="presentation" style="max-width: 600px; margin: 0 auto; background-color: #3B4252; border-radius: 8px; overflow: hidden;">
+                    <tr>
+                        <td style="padding: 40px; text-align: center; background-color: #434C5E;">
+                            <h1 style="margin: 0; color: #88C0D0; font-size: 24px;">file.cheap</h1>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 40px;">
+                            <h2 style="margin: 0 0 20px; color: #ECEFF4; font-size: 20px;">Webhook disabled</h2>
+                            <p style="margin: 0 0 20px; color: #D8DEE9; line-height: 1.6;">
+                                Hi {{.RecipientName}},
+                            </p>
+                            <p style="margin: 0 0 20px; color: #D8DEE9; line-height: 1.6;">
+                                Deliveries to your webhook have been failing since {{.FailingSince}}, so we have disabled it:
+                            </p>
+                            <p style="margin: 0 0 20px; padding: 15px; background-color: #434C5E; border-radius: 4px; color: #EBCB8B; word-break: break-all;">
+                                {{.WebhookURL}}
+                            </p>
+                            <p style="margin: 0 0 30px; color: #D8DEE9; line-height: 1.6;">
+                                Events that could not be delivered are kept in the webhook dead letter queue. Once the endpoint is healthy, re-enable the webhook with <code>PUT /v1/webhooks/{id}</code> and <code>"active": true</code>, then retry the failed deliveries.
+                            </p>
+                            <table role="presentation" style="margin: 0 auto;">
+                                <tr>
+                                    <td style="border-radius: 4px; background-color: #88C0D0;">
+                                        <a href="{{.DocsURL}}" style="display: inline-block; padding: 14px 28px; color: #2E3440; text-decoration: none; font-weight: 600;">
+                                            Webhook Docs
+                                        </a>
+                                    </td>
+                                </tr>
+                            </table>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 20px 40px; background-color: #434C5E; text-align: center;">
+                            <p style="margin: 0; color: #4C566A; font-size: 12px;">
+                                &copy; {{.Year}} file.cheap. All rights reserved.
+                            </p>
+                        </td>
+                    </tr>
+                </table>
+            </td>
+        </tr>
+    </table>
+</body>
+</html>
+`
+
 const oauthLinkedEmailTemplate = `
 <!DOCTYPE html>
 <html>
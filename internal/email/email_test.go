@@ -125,6 +125,37 @@ func TestEmailTemplates(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("webhook disabled email template renders", func(t *testing.T) {
+		data := WebhookDisabledEmailData{
+			EmailData: EmailData{
+				RecipientName: "Hook Owner",
+				BaseURL:       cfg.BaseURL,
+				Year:          2024,
+			},
+			WebhookURL:   "https://example.com/hooks/file-cheap",
+			FailingSince: "March 1, 2024 12:00 UTC",
+			DocsURL:      "http://localhost:8080/docs?section=webhooks",
+		}
+
+		html, err := svc.renderTemplate(webhookDisabledEmailTemplate, data)
+		if err != nil {
+			t.Fatalf("renderTemplate() error = %v", err)
+		}
+
+		checks := []string{
+			"Hook Owner",
+			"Webhook disabled",
+			"https://example.com/hooks/file-cheap",
+			"March 1, 2024 12:00 UTC",
+			"http://localhost:8080/docs?section=webhooks",
+		}
+		for _, check := range checks {
+			if !strings.Contains(html, check) {
+				t.Errorf("template missing %q", check)
+			}
+		}
+	})
 }
 
 func TestEmailDataStructures(t *testing.T) {
@@ -200,6 +231,15 @@ func TestTemplateValidHTML(t *testing.T) {
 				DashboardURL: "http://test",
 			},
 		},
+		{
+			"webhook_disabled",
+			webhookDisabledEmailTemplate,
+			WebhookDisabledEmailData{
+				EmailData:  EmailData{RecipientName: "Test", Year: 2024},
+				WebhookURL: "http://test",
+				DocsURL:    "http://test",
+			},
+		},
 	}
 
 	for _, tt := range templates {
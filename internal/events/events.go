@@ -0,0 +1,76 @@
+// Package events carries processing job events from the workers to every API
+// replica, where they are fanned out to connected SSE clients.
+package events
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published for processing jobs. They double as SSE event names.
+const (
+	TypeJobRunning  = "job:running"
+	TypeJobProgress = "job:progress"
+	TypeJobComplete = "job:complete"
+	TypeJobFailed   = "job:failed"
+)
+
+// Event is a job status change for a file owned by UserID.
+type Event struct {
+	// ID orders the events of one user. It is assigned on publish and sent
+	// to SSE clients so they can resume with Last-Event-ID.
+	ID       string    `json:"id,omitempty"`
+	Type     string    `json:"type"`
+	UserID   uuid.UUID `json:"user_id"`
+	FileID   string    `json:"file_id"`
+	JobID    string    `json:"job_id,omitempty"`
+	JobType  string    `json:"job_type,omitempty"`
+	Progress int       `json:"progress,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Publisher sends events to every subscriber.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// Bus is a Publisher that can also be subscribed to and replayed from.
+type Bus interface {
+	Publisher
+	// Subscribe calls handle for every published event until ctx is
+	// cancelled or the subscription fails.
+	Subscribe(ctx context.Context, handle func(Event)) error
+	// Since returns the retained events of userID published after lastID,
+	// oldest first.
+	Since(ctx context.Context, userID uuid.UUID, lastID string) ([]Event, error)
+}
+
+// CompareIDs orders two event IDs of the form "<ms>-<seq>", returning -1, 0
+// or 1. Malformed IDs sort before well-formed ones.
+func CompareIDs(a, b string) int {
+	am, as := parseID(a)
+	bm, bs := parseID(b)
+	switch {
+	case am < bm:
+		return -1
+	case am > bm:
+		return 1
+	case as < bs:
+		return -1
+	case as > bs:
+		return 1
+	}
+	return 0
+}
+
+func parseID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryBus delivers events within a single process. It is suitable for a
+// single API instance and for tests.
+type MemoryBus struct {
+	mu          sync.Mutex
+	seq         uint64
+	history     map[uuid.UUID][]Event
+	subscribers map[int]func(Event)
+	nextSub     int
+}
+
+var _ Bus = (*MemoryBus)(nil)
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		history:     make(map[uuid.UUID][]Event),
+		subscribers: make(map[int]func(Event)),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event *Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	b.seq++
+	event.ID = "0-" + strconv.FormatUint(b.seq, 10)
+	history := append(b.history[event.UserID], *event)
+	if len(history) > replayLength {
+		history = history[len(history)-replayLength:]
+	}
+	b.history[event.UserID] = history
+	handlers := make([]func(Event), 0, len(b.subscribers))
+	for _, handle := range b.subscribers {
+		handlers = append(handlers, handle)
+	}
+	b.mu.Unlock()
+
+	for _, handle := range handlers {
+		handle(*event)
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, handle func(Event)) error {
+	b.mu.Lock()
+	id := b.nextSub
+	b.nextSub++
+	b.subscribers[id] = handle
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.subscribers, id)
+	b.mu.Unlock()
+	return ctx.Err()
+}
+
+func (b *MemoryBus) Since(ctx context.Context, userID uuid.UUID, lastID string) ([]Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []Event
+	for _, event := range b.history[userID] {
+		if CompareIDs(event.ID, lastID) > 0 {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCompareIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0", "1-0", 0},
+		{"1-0", "2-0", -1},
+		{"1700000000000-5", "1700000000000-12", -1},
+		{"1700000000001-0", "1700000000000-99", 1},
+		{"garbage", "0-1", -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareIDs(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareIDs(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryBus_PublishSubscribe(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan Event, 1)
+	go func() { _ = bus.Subscribe(ctx, func(e Event) { received <- e }) }()
+	time.Sleep(10 * time.Millisecond)
+
+	event := &Event{Type: TypeJobComplete, UserID: uuid.New(), FileID: "file-1"}
+	if err := bus.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if event.ID == "" {
+		t.Error("Publish() should assign an ID")
+	}
+
+	select {
+	case got := <-received:
+		if got.ID != event.ID || got.Type != TypeJobComplete {
+			t.Errorf("received %+v, want %+v", got, *event)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("subscriber did not receive event")
+	}
+}
+
+func TestMemoryBus_Since(t *testing.T) {
+	bus := NewMemoryBus()
+	userID := uuid.New()
+
+	var published []*Event
+	for _, eventType := range []string{TypeJobRunning, TypeJobProgress, TypeJobComplete} {
+		e := &Event{Type: eventType, UserID: userID, FileID: "file-1"}
+		_ = bus.Publish(context.Background(), e)
+		published = append(published, e)
+	}
+	_ = bus.Publish(context.Background(), &Event{Type: TypeJobRunning, UserID: uuid.New()})
+
+	got, err := bus.Since(context.Background(), userID, published[0].ID)
+	if err != nil {
+		t.Fatalf("Since() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Since() returned %d events, want 2", len(got))
+	}
+	if got[0].ID != published[1].ID || got[1].ID != published[2].ID {
+		t.Errorf("Since() = %v, %v; want %v, %v", got[0].ID, got[1].ID, published[1].ID, published[2].ID)
+	}
+}
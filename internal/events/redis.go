@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// replayLength caps how many events are kept per user for replay.
+	replayLength = 500
+	// replayTTL is how long an idle user's replay stream is kept.
+	replayTTL = time.Hour
+)
+
+// RedisBus publishes events over Redis pub/sub. Each event is also appended
+// to a capped per-user stream whose entry ID becomes the event ID, so
+// reconnecting clients can replay what they missed.
+type RedisBus struct {
+	client  *redis.Client
+	channel string
+	prefix  string
+}
+
+var _ Bus = (*RedisBus)(nil)
+
+func NewRedisBus(client *redis.Client) *RedisBus {
+	return &RedisBus{
+		client:  client,
+		channel: "events",
+		prefix:  "events:user:",
+	}
+}
+
+func (b *RedisBus) streamKey(userID uuid.UUID) string {
+	return b.prefix + userID.String()
+}
+
+func (b *RedisBus) Publish(ctx context.Context, event *Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.ID = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	key := b.streamKey(event.UserID)
+	id, err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: replayLength,
+		Approx: true,
+		Values: map[string]interface{}{"event": data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("append event: %w", err)
+	}
+	_ = b.client.Expire(ctx, key, replayTTL).Err()
+
+	event.ID = id
+	data, err = json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, handle func(Event)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer func() { _ = sub.Close() }()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe to events: %w", err)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("event subscription closed")
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			handle(event)
+		}
+	}
+}
+
+func (b *RedisBus) Since(ctx context.Context, userID uuid.UUID, lastID string) ([]Event, error) {
+	msgs, err := b.client.XRange(ctx, b.streamKey(userID), "("+lastID, "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("replay events: %w", err)
+	}
+
+	events := make([]Event, 0, len(msgs))
+	for _, msg := range msgs {
+		data, ok := msg.Values["event"].(string)
+		if !ok {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		event.ID = msg.ID
+		events = append(events, event)
+	}
+	return events, nil
+}
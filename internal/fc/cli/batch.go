@@ -40,8 +40,13 @@ Config file format (YAML):
     - pattern: "hero-*.jpg"
       position: "north"
       transforms: [thumbnail, webp, resize:lg]
+    - pattern: "banner-*.jpg"
+      preset: hero
     - path: "specific-file.png"
-      position: "south-east"`,
+      position: "south-east"
+
+Presets can be local (fc config presets) or saved to your account
+(/v1/presets); account presets are resolved by the server.`,
 	RunE: runBatch,
 }
 
@@ -128,11 +133,7 @@ func batchProcessIDs(fileIDs []string) error {
 
 	presets := batchTransform
 	if batchPreset != "" {
-		preset, ok := cfg.GetPreset(batchPreset)
-		if !ok {
-			return fmt.Errorf("unknown preset: %s", batchPreset)
-		}
-		presets = append(presets, preset.Transforms...)
+		presets = append(presets, presetTransforms(batchPreset)...)
 	}
 
 	if len(presets) == 0 && batchWatermark == "" {
@@ -167,6 +168,15 @@ func batchProcessIDs(fileIDs []string) error {
 	return nil
 }
 
+// presetTransforms expands a local preset. Any other name is passed through
+// for the server to resolve against the account's presets.
+func presetTransforms(name string) []string {
+	if preset, ok := cfg.GetPreset(name); ok {
+		return preset.Transforms
+	}
+	return []string{name}
+}
+
 func waitForBatch(ctx context.Context, batchID string) error {
 	printer.Printf("Waiting for batch to complete...\n")
 
@@ -309,11 +319,10 @@ func uploadAndBatchProcessWithConfig(ctx context.Context, files []string, batchC
 				transforms = batchTransform
 			}
 
-			if batchPreset != "" {
-				preset, ok := cfg.GetPreset(batchPreset)
-				if ok {
-					transforms = append(transforms, preset.Transforms...)
-				}
+			if name := batchCfg.GetPreset(f); name != "" {
+				transforms = append(transforms, presetTransforms(name)...)
+			} else if batchPreset != "" {
+				transforms = append(transforms, presetTransforms(batchPreset)...)
 			}
 
 			if quality := batchCfg.GetQuality(f); quality > 0 {
@@ -2,9 +2,17 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/fc/client"
 	"github.com/abdul-hamid-achik/file.cheap/internal/fc/config"
 )
 
@@ -278,6 +286,68 @@ func TestSafePath(t *testing.T) {
 	}
 }
 
+func TestPartialDownloadPath(t *testing.T) {
+	tests := []struct {
+		etag string
+		want string
+	}{
+		{`"0a1b2c"`, "out/video.mp4.0a1b2c.part"},
+		{`"../x"`, "out/video.mp4.x.part"},
+		{`W/"0a1b2c"`, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := partialDownloadPath("out/video.mp4", tt.etag); got != tt.want {
+			t.Errorf("partialDownloadPath(%q) = %q, want %q", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestSaveDownload_Resume(t *testing.T) {
+	const content = "0123456789"
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		var start int
+		_, _ = fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, content[start:])
+	}))
+	defer server.Close()
+
+	oldClient, oldResume := apiClient, downloadResume
+	defer func() { apiClient, downloadResume = oldClient, oldResume }()
+	apiClient = client.New(server.URL, "fp_test123")
+	downloadResume = true
+
+	outputPath := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(partialDownloadPath(outputPath, `"v1"`), []byte("0123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := apiClient.DownloadRange(context.Background(), "f1", "", 0, "")
+	if err != nil {
+		t.Fatalf("DownloadRange() error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := saveDownload(context.Background(), "f1", "", resp, outputPath); err != nil {
+		t.Fatalf("saveDownload() error = %v", err)
+	}
+
+	got, err := os.ReadFile(outputPath)
+	if err != nil || string(got) != content {
+		t.Errorf("output = %q, %v; want %q", got, err, content)
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=4-" {
+		t.Errorf("requested ranges = %v, want a resume from byte 4", ranges)
+	}
+	if _, err := os.Stat(partialDownloadPath(outputPath, `"v1"`)); !os.IsNotExist(err) {
+		t.Error("partial file should be moved into place")
+	}
+}
+
 func TestRequireAuth(t *testing.T) {
 	// Save original cfg
 	originalCfg := cfg
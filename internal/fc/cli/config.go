@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/fc/client"
 	"github.com/abdul-hamid-achik/file.cheap/internal/fc/config"
 	"github.com/spf13/cobra"
 )
@@ -135,11 +136,24 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 }
 
 func runConfigPresets(cmd *cobra.Command, args []string) error {
+	var accountPresets []client.Preset
+	if cfg.IsAuthenticated() {
+		resp, err := apiClient.ListPresets(GetContext())
+		if err != nil {
+			printer.Warn("Could not load account presets: %v", err)
+		} else {
+			accountPresets = resp.Presets
+		}
+	}
+
 	if jsonOutput {
-		allPresets := make(map[string]config.Preset)
+		allPresets := make(map[string]interface{})
 		for name, preset := range config.BuiltinPresets {
 			allPresets[name] = preset
 		}
+		for _, preset := range accountPresets {
+			allPresets[preset.Name] = preset
+		}
 		for name, preset := range cfg.Presets {
 			allPresets[name] = preset
 		}
@@ -169,5 +183,14 @@ func runConfigPresets(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if len(accountPresets) > 0 {
+		printer.Section("Account Presets")
+		for _, preset := range accountPresets {
+			printer.Printf("  %s\n", preset.Name)
+			printer.Printf("    Transforms: %s\n", preset.Transforms)
+			printer.Printf("    CDN: preset_%s\n", preset.Name)
+		}
+	}
+
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/abdul-hamid-achik/file.cheap/internal/fc/client"
 	"github.com/spf13/cobra"
 )
 
@@ -73,7 +74,10 @@ Examples:
   fc download abc123 --variant=thumbnail  # Download variant
   fc download abc123 -o ./downloads/  # To specific path
   fc download abc123 def456 -o ./backup/  # Multiple files
-  fc download abc123 --all-variants   # Download all variants`,
+  fc download abc123 --all-variants   # Download all variants
+
+Interrupted downloads leave a <file>.<etag>.part file behind and resume from
+where they stopped on the next run, unless --resume=false is given.`,
 	RunE: runDownload,
 }
 
@@ -81,12 +85,14 @@ var (
 	downloadOutput      string
 	downloadVariant     string
 	downloadAllVariants bool
+	downloadResume      bool
 )
 
 func init() {
 	downloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", ".", "Output directory or file path")
 	downloadCmd.Flags().StringVar(&downloadVariant, "variant", "", "Download specific variant")
 	downloadCmd.Flags().BoolVar(&downloadAllVariants, "all-variants", false, "Download all variants")
+	downloadCmd.Flags().BoolVar(&downloadResume, "resume", true, "Resume interrupted downloads from their partial file")
 }
 
 func runDownload(cmd *cobra.Command, args []string) error {
@@ -129,13 +135,87 @@ func runDownload(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// partialDownloadPath returns where an unfinished download of outputPath is
+// kept. The name includes the ETag so a partial file is only ever resumed
+// against the same object; it is empty when the server sent no usable ETag.
+func partialDownloadPath(outputPath, etag string) string {
+	if strings.HasPrefix(etag, "W/") {
+		return ""
+	}
+	tag := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, etag)
+	if tag == "" {
+		return ""
+	}
+	return outputPath + "." + tag + ".part"
+}
+
+// saveDownload writes resp to outputPath. Resumable downloads go through a
+// partial file first; when one is left over from an interrupted run, the
+// rest of the object is requested with If-Range and appended to it. The
+// caller closes resp.Body.
+func saveDownload(ctx context.Context, fileID, variant string, resp *client.DownloadResponse, outputPath string) error {
+	partPath := partialDownloadPath(outputPath, resp.ETag)
+	if partPath == "" {
+		return writeDownloadFile(outputPath, resp.Body, os.O_TRUNC)
+	}
+
+	if info, err := os.Stat(partPath); err == nil && downloadResume && info.Size() > 0 && info.Size() < resp.Size {
+		resumed, err := apiClient.DownloadRange(ctx, fileID, variant, info.Size(), resp.ETag)
+		if err == nil {
+			_ = resp.Body.Close()
+			defer func() { _ = resumed.Body.Close() }()
+			resp = resumed
+		}
+	}
+
+	// A full response means the object changed or the server declined to
+	// resume, so the partial file starts over.
+	flag := os.O_TRUNC
+	if resp.Offset > 0 {
+		info, err := os.Stat(partPath)
+		if err != nil || info.Size() != resp.Offset {
+			return fmt.Errorf("server resumed at byte %d, which does not match the partial file", resp.Offset)
+		}
+		flag = os.O_APPEND
+	}
+	if err := writeDownloadFile(partPath, resp.Body, flag); err != nil {
+		return err
+	}
+	if err := os.Rename(partPath, outputPath); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+	return nil
+}
+
+func writeDownloadFile(path string, body io.Reader, flag int) error {
+	outFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|flag, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if _, err := io.Copy(outFile, body); err != nil {
+		_ = outFile.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
 func downloadSingleFile(ctx context.Context, fileID, variant string) error {
-	body, filename, err := apiClient.Download(ctx, fileID, variant)
+	resp, err := apiClient.DownloadRange(ctx, fileID, variant, 0, "")
 	if err != nil {
 		return err
 	}
-	defer func() { _ = body.Close() }()
+	defer func() { _ = resp.Body.Close() }()
 
+	filename := resp.Filename
 	if filename == "" {
 		file, err := apiClient.GetFile(ctx, fileID)
 		if err != nil {
@@ -178,15 +258,8 @@ func downloadSingleFile(ctx context.Context, fileID, variant string) error {
 		outputPath = downloadOutput
 	}
 
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer func() { _ = outFile.Close() }()
-
-	_, err = io.Copy(outFile, body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := saveDownload(ctx, fileID, variant, resp, outputPath); err != nil {
+		return err
 	}
 
 	printer.Success("Downloaded %s to %s", filename, outputPath)
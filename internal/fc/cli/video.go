@@ -24,6 +24,8 @@ Examples:
   fc video upload movie.mp4                    # Upload a video
   fc video upload movie.mp4 --transcode        # Upload and transcode
   fc video transcode <file-id> -r 720,1080     # Transcode to specific resolutions
+  fc video storyboard <file-id>                # Generate seek-bar preview thumbnails
+  fc video preview <file-id> -f mp4,webp       # Generate autoplay previews for listings
   fc video status <file-id>                    # Check processing status`,
 }
 
@@ -71,6 +73,42 @@ Examples:
 	RunE: runVideoTranscode,
 }
 
+var videoStoryboardCmd = &cobra.Command{
+	Use:   "storyboard <file-id>",
+	Short: "Generate seek-bar preview thumbnails",
+	Long: `Generate a storyboard for an uploaded video: a sprite sheet of thumbnails
+taken at equal intervals and a WebVTT track that maps playback time to each
+thumbnail, for seek-bar previews in players such as Plyr or Video.js.
+
+The grid can be at most 20x20 and thumbnails at most 480x480.
+
+Examples:
+  fc video storyboard abc123
+  fc video storyboard abc123 --columns 5 --rows 5
+  fc video storyboard abc123 --width 240 --height 135 --wait`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVideoStoryboard,
+}
+
+var videoPreviewCmd = &cobra.Command{
+	Use:   "preview <file-id>",
+	Short: "Generate short autoplay previews",
+	Long: `Generate a short, muted preview of an uploaded video for listing pages.
+Several short segments are cut from across the video, or from the range
+given by --start and --end, and joined into a low-bitrate MP4 and/or an
+animated WebP or GIF.
+
+Available formats: mp4, webp, gif
+Preview height never exceeds your plan's maximum video resolution.
+
+Examples:
+  fc video preview abc123
+  fc video preview abc123 -f mp4,webp --width 480
+  fc video preview abc123 -f gif --segments 1 --start 10s --end 14s --wait`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVideoPreview,
+}
+
 var videoStatusCmd = &cobra.Command{
 	Use:   "status <file-id>",
 	Short: "Check video processing status",
@@ -90,6 +128,18 @@ var (
 	videoThumbnail   bool
 	videoThumbnailAt string
 	videoWait        bool
+
+	storyboardColumns int
+	storyboardRows    int
+	storyboardWidth   int
+	storyboardHeight  int
+
+	previewFormats         []string
+	previewWidth           int
+	previewSegments        int
+	previewSegmentDuration float64
+	previewStart           time.Duration
+	previewEnd             time.Duration
 )
 
 func init() {
@@ -108,8 +158,24 @@ func init() {
 	videoTranscodeCmd.Flags().StringVar(&videoThumbnailAt, "thumbnail-at", "", "Thumbnail timestamp (e.g., 30s, 1m30s, 50%)")
 	videoTranscodeCmd.Flags().BoolVarP(&videoWait, "wait", "w", false, "Wait for processing")
 
+	videoStoryboardCmd.Flags().IntVar(&storyboardColumns, "columns", 10, "Thumbnails per row")
+	videoStoryboardCmd.Flags().IntVar(&storyboardRows, "rows", 10, "Rows of thumbnails")
+	videoStoryboardCmd.Flags().IntVar(&storyboardWidth, "width", 160, "Thumbnail width")
+	videoStoryboardCmd.Flags().IntVar(&storyboardHeight, "height", 90, "Thumbnail height")
+	videoStoryboardCmd.Flags().BoolVarP(&videoWait, "wait", "w", false, "Wait for processing")
+
+	videoPreviewCmd.Flags().StringSliceVarP(&previewFormats, "format", "f", []string{"mp4"}, "Output formats (mp4, webp, gif)")
+	videoPreviewCmd.Flags().IntVar(&previewWidth, "width", 320, "Preview width")
+	videoPreviewCmd.Flags().IntVar(&previewSegments, "segments", 3, "Number of segments to cut")
+	videoPreviewCmd.Flags().Float64Var(&previewSegmentDuration, "segment-duration", 2, "Seconds per segment")
+	videoPreviewCmd.Flags().DurationVar(&previewStart, "start", 0, "Start of the range to preview (e.g., 10s)")
+	videoPreviewCmd.Flags().DurationVar(&previewEnd, "end", 0, "End of the range to preview (default end of video)")
+	videoPreviewCmd.Flags().BoolVarP(&videoWait, "wait", "w", false, "Wait for processing")
+
 	videoCmd.AddCommand(videoUploadCmd)
 	videoCmd.AddCommand(videoTranscodeCmd)
+	videoCmd.AddCommand(videoStoryboardCmd)
+	videoCmd.AddCommand(videoPreviewCmd)
 	videoCmd.AddCommand(videoStatusCmd)
 }
 
@@ -321,6 +387,155 @@ func runVideoTranscode(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runVideoStoryboard(cmd *cobra.Command, args []string) error {
+	if err := requireAuth(); err != nil {
+		return err
+	}
+
+	fileID := args[0]
+	ctx := GetContext()
+
+	if storyboardColumns < 1 || storyboardColumns > 20 || storyboardRows < 1 || storyboardRows > 20 {
+		return fmt.Errorf("invalid grid: %dx%d (columns and rows must be 1-20)", storyboardColumns, storyboardRows)
+	}
+	if storyboardWidth < 32 || storyboardWidth > 480 || storyboardHeight < 18 || storyboardHeight > 480 {
+		return fmt.Errorf("invalid thumbnail size: %dx%d (width 32-480, height 18-480)", storyboardWidth, storyboardHeight)
+	}
+
+	req := &client.VideoStoryboardRequest{
+		Columns: storyboardColumns,
+		Rows:    storyboardRows,
+		Width:   storyboardWidth,
+		Height:  storyboardHeight,
+	}
+
+	resp, err := apiClient.VideoStoryboard(ctx, fileID, req)
+	if err != nil {
+		if !jsonOutput {
+			printer.FileFailed(fileID, err)
+		}
+		return err
+	}
+
+	if !jsonOutput {
+		printer.Success("%s: storyboard job queued (%dx%d thumbnails of %dx%d)", fileID, storyboardColumns, storyboardRows, storyboardWidth, storyboardHeight)
+	}
+
+	if videoWait {
+		if !jsonOutput && !quietMode {
+			spinner := output.NewSpinner(fmt.Sprintf("Processing %s...", fileID), quietMode)
+			file, err := apiClient.WaitForFile(ctx, fileID, 5*time.Second, cfg.GetTimeout("upload"))
+			spinner.Finish()
+			if err != nil {
+				printer.Warn("Timeout waiting for %s", fileID)
+			} else {
+				if file.Status == "completed" {
+					printer.Success("%s storyboard completed", fileID)
+				} else {
+					printer.Warn("%s: %s", fileID, file.Status)
+				}
+			}
+		}
+	}
+
+	if jsonOutput {
+		return printer.JSON(map[string]interface{}{
+			"file_id": fileID,
+			"jobs":    resp.Jobs,
+			"columns": storyboardColumns,
+			"rows":    storyboardRows,
+			"width":   storyboardWidth,
+			"height":  storyboardHeight,
+		})
+	}
+
+	if !videoWait {
+		printer.Println()
+		printer.Printf("Use 'fc video status %s' to check progress.\n", fileID)
+	}
+
+	return nil
+}
+
+func runVideoPreview(cmd *cobra.Command, args []string) error {
+	if err := requireAuth(); err != nil {
+		return err
+	}
+
+	fileID := args[0]
+	ctx := GetContext()
+
+	for _, f := range previewFormats {
+		if f != "mp4" && f != "webp" && f != "gif" {
+			return fmt.Errorf("invalid format: %s (supported: mp4, webp, gif)", f)
+		}
+	}
+	if previewWidth < 64 || previewWidth > 1280 {
+		return fmt.Errorf("invalid width: %d (must be 64-1280)", previewWidth)
+	}
+	if previewSegments < 1 || previewSegments > 10 || previewSegmentDuration < 0.5 || previewSegmentDuration > 10 {
+		return fmt.Errorf("invalid segments: %d x %.1fs (1-10 segments of 0.5-10s)", previewSegments, previewSegmentDuration)
+	}
+	if previewStart < 0 || previewEnd < 0 || (previewEnd > 0 && previewEnd <= previewStart) {
+		return fmt.Errorf("invalid range: %s-%s (end must be after start)", previewStart, previewEnd)
+	}
+
+	req := &client.VideoPreviewRequest{
+		Formats:         previewFormats,
+		Width:           previewWidth,
+		Segments:        previewSegments,
+		SegmentDuration: previewSegmentDuration,
+		Start:           previewStart.Seconds(),
+		End:             previewEnd.Seconds(),
+	}
+
+	resp, err := apiClient.VideoPreview(ctx, fileID, req)
+	if err != nil {
+		if !jsonOutput {
+			printer.FileFailed(fileID, err)
+		}
+		return err
+	}
+
+	if !jsonOutput {
+		printer.Success("%s: preview job queued (%s, %d x %.1fs at %dpx)", fileID, strings.Join(previewFormats, ", "), previewSegments, previewSegmentDuration, previewWidth)
+	}
+
+	if videoWait {
+		if !jsonOutput && !quietMode {
+			spinner := output.NewSpinner(fmt.Sprintf("Processing %s...", fileID), quietMode)
+			file, err := apiClient.WaitForFile(ctx, fileID, 5*time.Second, cfg.GetTimeout("upload"))
+			spinner.Finish()
+			if err != nil {
+				printer.Warn("Timeout waiting for %s", fileID)
+			} else {
+				if file.Status == "completed" {
+					printer.Success("%s preview completed", fileID)
+				} else {
+					printer.Warn("%s: %s", fileID, file.Status)
+				}
+			}
+		}
+	}
+
+	if jsonOutput {
+		return printer.JSON(map[string]interface{}{
+			"file_id":  fileID,
+			"jobs":     resp.Jobs,
+			"formats":  previewFormats,
+			"width":    previewWidth,
+			"segments": previewSegments,
+		})
+	}
+
+	if !videoWait {
+		printer.Println()
+		printer.Printf("Use 'fc video status %s' to check progress.\n", fileID)
+	}
+
+	return nil
+}
+
 func runVideoStatus(cmd *cobra.Command, args []string) error {
 	if err := requireAuth(); err != nil {
 		return err
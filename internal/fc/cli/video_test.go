@@ -83,9 +83,11 @@ func TestVideoCmd_HasSubcommands(t *testing.T) {
 	}
 
 	expectedSubcommands := map[string]bool{
-		"upload":    false,
-		"transcode": false,
-		"status":    false,
+		"upload":     false,
+		"transcode":  false,
+		"storyboard": false,
+		"preview":    false,
+		"status":     false,
 	}
 
 	for _, cmd := range subcommands {
@@ -158,6 +160,62 @@ func TestVideoTranscodeCmd_Flags(t *testing.T) {
 	}
 }
 
+func TestVideoStoryboardCmd_Flags(t *testing.T) {
+	tests := []struct {
+		flag string
+		def  string
+	}{
+		{"columns", "10"},
+		{"rows", "10"},
+		{"width", "160"},
+		{"height", "90"},
+		{"wait", "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			f := videoStoryboardCmd.Flags().Lookup(tt.flag)
+			if f == nil {
+				t.Errorf("Flag %q not found", tt.flag)
+				return
+			}
+
+			if f.DefValue != tt.def {
+				t.Errorf("Flag %q default = %q, want %q", tt.flag, f.DefValue, tt.def)
+			}
+		})
+	}
+}
+
+func TestVideoPreviewCmd_Flags(t *testing.T) {
+	tests := []struct {
+		flag string
+		def  string
+	}{
+		{"format", "[mp4]"},
+		{"width", "320"},
+		{"segments", "3"},
+		{"segment-duration", "2"},
+		{"start", "0s"},
+		{"end", "0s"},
+		{"wait", "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			f := videoPreviewCmd.Flags().Lookup(tt.flag)
+			if f == nil {
+				t.Errorf("Flag %q not found", tt.flag)
+				return
+			}
+
+			if f.DefValue != tt.def {
+				t.Errorf("Flag %q default = %q, want %q", tt.flag, f.DefValue, tt.def)
+			}
+		})
+	}
+}
+
 func TestVideoUploadCmd_RequiresArgs(t *testing.T) {
 	cmd := &cobra.Command{}
 	cmd.SetArgs([]string{})
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -38,7 +39,7 @@ func (c *Client) SetAPIKey(apiKey string) {
 	c.apiKey = apiKey
 }
 
-func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return nil, err
@@ -51,7 +52,14 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body io.Rea
 		req.Header.Set("Content-Type", contentType)
 	}
 	req.Header.Set("User-Agent", "fc-cli/"+version.Short())
+	return req, nil
+}
 
+func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, body, contentType)
+	if err != nil {
+		return nil, err
+	}
 	return c.httpClient.Do(req)
 }
 
@@ -253,13 +261,25 @@ func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
 	return c.doJSON(ctx, http.MethodDelete, "/api/v1/files/"+fileID, nil, nil)
 }
 
-func (c *Client) Download(ctx context.Context, fileID, variant string) (io.ReadCloser, string, error) {
+func downloadPath(fileID, variant string) string {
 	path := "/api/v1/files/" + fileID + "/download"
 	if variant != "" {
 		path += "?variant=" + url.QueryEscape(variant)
 	}
+	return path
+}
+
+func contentDispositionFilename(resp *http.Response) string {
+	cd := resp.Header.Get("Content-Disposition")
+	if !strings.Contains(cd, "filename=") {
+		return ""
+	}
+	parts := strings.Split(cd, "filename=")
+	return strings.Trim(parts[1], `"`)
+}
 
-	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, "")
+func (c *Client) Download(ctx context.Context, fileID, variant string) (io.ReadCloser, string, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, downloadPath(fileID, variant), nil, "")
 	if err != nil {
 		return nil, "", err
 	}
@@ -269,17 +289,69 @@ func (c *Client) Download(ctx context.Context, fileID, variant string) (io.ReadC
 		return nil, "", c.parseError(resp)
 	}
 
-	filename := ""
-	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
-		if strings.Contains(cd, "filename=") {
-			parts := strings.Split(cd, "filename=")
-			if len(parts) > 1 {
-				filename = strings.Trim(parts[1], `"`)
-			}
+	return resp.Body, contentDispositionFilename(resp), nil
+}
+
+// DownloadRange downloads a file or variant from offset onwards. The request
+// always carries a Range header so the API serves the bytes itself along with
+// an ETag; pass that ETag as ifRange when resuming. If the object changed in
+// the meantime the whole object comes back and the response's Offset is 0.
+func (c *Client) DownloadRange(ctx context.Context, fileID, variant string, offset int64, ifRange string) (*DownloadResponse, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, downloadPath(fileID, variant), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	if ifRange != "" {
+		req.Header.Set("If-Range", ifRange)
+	}
+
+	// A redirect means the API declined the range and points at storage,
+	// which would apply the range to whatever it now holds. Fetch the whole
+	// object there instead.
+	httpClient := *c.httpClient
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
 		}
+		req.Header.Del("Range")
+		req.Header.Del("If-Range")
+		return nil
 	}
 
-	return resp.Body, filename, nil
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset == 0 {
+		// Empty objects have no byte 0 to start from.
+		_ = resp.Body.Close()
+		body, filename, err := c.Download(ctx, fileID, variant)
+		if err != nil {
+			return nil, err
+		}
+		return &DownloadResponse{Body: body, Filename: filename, Size: -1}, nil
+	}
+	if resp.StatusCode >= 400 {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, c.parseError(resp)
+	}
+
+	result := &DownloadResponse{
+		Body:     resp.Body,
+		Filename: contentDispositionFilename(resp),
+		Size:     resp.ContentLength,
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		var end int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &result.Offset, &end, &result.Size); err != nil {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("invalid Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		result.ETag = resp.Header.Get("ETag")
+	}
+	return result, nil
 }
 
 func (c *Client) Transform(ctx context.Context, fileID string, req *TransformRequest) (*TransformResponse, error) {
@@ -311,6 +383,14 @@ func (c *Client) GetBatchStatus(ctx context.Context, batchID string, includeItem
 	return &result, nil
 }
 
+func (c *Client) ListPresets(ctx context.Context) (*ListPresetsResponse, error) {
+	var result ListPresetsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/presets", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (c *Client) GetJobStatus(ctx context.Context, jobID string) (*JobStatus, error) {
 	var result JobStatus
 	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/jobs/"+jobID, nil, &result); err != nil {
@@ -405,6 +485,22 @@ func (c *Client) VideoTranscode(ctx context.Context, fileID string, req *VideoTr
 	return &result, nil
 }
 
+func (c *Client) VideoStoryboard(ctx context.Context, fileID string, req *VideoStoryboardRequest) (*VideoTranscodeResponse, error) {
+	var result VideoTranscodeResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/files/"+fileID+"/video/storyboard", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) VideoPreview(ctx context.Context, fileID string, req *VideoPreviewRequest) (*VideoTranscodeResponse, error) {
+	var result VideoTranscodeResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/files/"+fileID+"/video/preview", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // Chunked upload methods for large files
 
 func (c *Client) InitChunkedUpload(ctx context.Context, req *ChunkedUploadInitRequest) (*ChunkedUploadInitResponse, error) {
@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -180,6 +182,31 @@ func TestClient_BatchTransform(t *testing.T) {
 	}
 }
 
+func TestClient_ListPresets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/presets" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		_ = json.NewEncoder(w).Encode(ListPresetsResponse{
+			Presets: []Preset{
+				{ID: "p1", Name: "hero", Width: 1600, Format: "webp", Transforms: "w_1600,f_webp"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "fp_test123")
+	resp, err := c.ListPresets(context.Background())
+	if err != nil {
+		t.Fatalf("ListPresets error = %v", err)
+	}
+
+	if len(resp.Presets) != 1 || resp.Presets[0].Name != "hero" {
+		t.Errorf("Presets = %+v, want one preset named hero", resp.Presets)
+	}
+}
+
 func TestClient_Upload(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -284,6 +311,62 @@ func TestClient_WaitForFile(t *testing.T) {
 	}
 }
 
+func TestClient_DownloadRange(t *testing.T) {
+	const content = "abcdefgh"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/storage/f1" {
+			if r.Header.Get("Range") != "" {
+				t.Error("Range should not be forwarded to storage")
+			}
+			_, _ = io.WriteString(w, content)
+			return
+		}
+		if ifRange := r.Header.Get("If-Range"); ifRange != "" && ifRange != `"v1"` {
+			http.Redirect(w, r, "/storage/f1", http.StatusTemporaryRedirect)
+			return
+		}
+		var start int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err != nil {
+			t.Errorf("Range = %q", r.Header.Get("Range"))
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, content[start:])
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "fp_test123")
+	tests := []struct {
+		name       string
+		offset     int64
+		ifRange    string
+		wantBody   string
+		wantOffset int64
+		wantETag   string
+	}{
+		{"from the start", 0, "", content, 0, `"v1"`},
+		{"resume", 4, `"v1"`, "efgh", 4, `"v1"`},
+		{"stale validator", 4, `"v0"`, content, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.DownloadRange(context.Background(), "f1", "", tt.offset, tt.ifRange)
+			if err != nil {
+				t.Fatalf("DownloadRange() error = %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.wantBody || resp.Offset != tt.wantOffset || resp.ETag != tt.wantETag {
+				t.Errorf("got body %q offset %d etag %q, want %q %d %q", body, resp.Offset, resp.ETag, tt.wantBody, tt.wantOffset, tt.wantETag)
+			}
+			if resp.Size != int64(len(content)) {
+				t.Errorf("Size = %d, want %d", resp.Size, len(content))
+			}
+		})
+	}
+}
+
 func TestClient_ErrorParsing(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
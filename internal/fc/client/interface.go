@@ -19,12 +19,14 @@ type ClientInterface interface {
 	GetFile(ctx context.Context, fileID string) (*File, error)
 	DeleteFile(ctx context.Context, fileID string) error
 	Download(ctx context.Context, fileID, variant string) (io.ReadCloser, string, error)
+	DownloadRange(ctx context.Context, fileID, variant string, offset int64, ifRange string) (*DownloadResponse, error)
 
 	// Transform operations
 	Transform(ctx context.Context, fileID string, req *TransformRequest) (*TransformResponse, error)
 	BatchTransform(ctx context.Context, req *BatchTransformRequest) (*BatchTransformResponse, error)
 	GetBatchStatus(ctx context.Context, batchID string, includeItems bool) (*BatchStatusResponse, error)
 	GetJobStatus(ctx context.Context, jobID string) (*JobStatus, error)
+	ListPresets(ctx context.Context) (*ListPresetsResponse, error)
 
 	// Sharing
 	CreateShare(ctx context.Context, fileID string, expires string) (*ShareResponse, error)
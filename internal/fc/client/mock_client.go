@@ -62,6 +62,14 @@ func (m *MockClient) Download(ctx context.Context, fileID, variant string) (io.R
 	return args.Get(0).(io.ReadCloser), args.String(1), args.Error(2)
 }
 
+func (m *MockClient) DownloadRange(ctx context.Context, fileID, variant string, offset int64, ifRange string) (*DownloadResponse, error) {
+	args := m.Called(ctx, fileID, variant, offset, ifRange)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*DownloadResponse), args.Error(1)
+}
+
 func (m *MockClient) Transform(ctx context.Context, fileID string, req *TransformRequest) (*TransformResponse, error) {
 	args := m.Called(ctx, fileID, req)
 	if args.Get(0) == nil {
@@ -94,6 +102,14 @@ func (m *MockClient) GetJobStatus(ctx context.Context, jobID string) (*JobStatus
 	return args.Get(0).(*JobStatus), args.Error(1)
 }
 
+func (m *MockClient) ListPresets(ctx context.Context) (*ListPresetsResponse, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ListPresetsResponse), args.Error(1)
+}
+
 func (m *MockClient) CreateShare(ctx context.Context, fileID string, expires string) (*ShareResponse, error) {
 	args := m.Called(ctx, fileID, expires)
 	if args.Get(0) == nil {
@@ -1,6 +1,9 @@
 package client
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 type File struct {
 	ID          string    `json:"id"`
@@ -21,6 +24,17 @@ type Variant struct {
 	Height      int    `json:"height,omitempty"`
 }
 
+// DownloadResponse is an open download. Offset is the byte the body starts
+// at and Size the length of the whole object, or -1 when unknown. ETag is set
+// only when the API served the bytes itself and the download can be resumed.
+type DownloadResponse struct {
+	Body     io.ReadCloser
+	Filename string
+	ETag     string
+	Offset   int64
+	Size     int64
+}
+
 type UploadResponse struct {
 	ID       string            `json:"id"`
 	Filename string            `json:"filename"`
@@ -97,6 +111,24 @@ type JobStatus struct {
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
 }
 
+// Preset is a transform preset saved to the account.
+type Preset struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	Fit        string `json:"fit,omitempty"`
+	Position   string `json:"position,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Quality    int    `json:"quality,omitempty"`
+	Watermark  string `json:"watermark,omitempty"`
+	Transforms string `json:"transforms"`
+}
+
+type ListPresetsResponse struct {
+	Presets []Preset `json:"presets"`
+}
+
 type ShareResponse struct {
 	ID        string     `json:"id"`
 	Token     string     `json:"token"`
@@ -158,6 +190,22 @@ type VideoTranscodeResponse struct {
 	Jobs   []string `json:"jobs"`
 }
 
+type VideoStoryboardRequest struct {
+	Columns int `json:"columns,omitempty"` // thumbnails per row
+	Rows    int `json:"rows,omitempty"`    // rows per sprite sheet
+	Width   int `json:"width,omitempty"`   // thumbnail width
+	Height  int `json:"height,omitempty"`  // thumbnail height
+}
+
+type VideoPreviewRequest struct {
+	Formats         []string `json:"formats,omitempty"`          // mp4, webp, gif
+	Width           int      `json:"width,omitempty"`            // preview width
+	Segments        int      `json:"segments,omitempty"`         // number of clips
+	SegmentDuration float64  `json:"segment_duration,omitempty"` // seconds per clip
+	Start           float64  `json:"start,omitempty"`            // range start in seconds
+	End             float64  `json:"end,omitempty"`              // range end in seconds
+}
+
 type ChunkedUploadInitRequest struct {
 	Filename    string `json:"filename"`
 	ContentType string `json:"content_type"`
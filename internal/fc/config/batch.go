@@ -14,6 +14,7 @@ type BatchConfig struct {
 }
 
 type BatchDefaults struct {
+	Preset     string   `yaml:"preset,omitempty"`
 	Transforms []string `yaml:"transforms,omitempty"`
 	Quality    int      `yaml:"quality,omitempty"`
 	Position   string   `yaml:"position,omitempty"`
@@ -22,6 +23,7 @@ type BatchDefaults struct {
 type BatchFileConfig struct {
 	Pattern     string   `yaml:"pattern,omitempty"`
 	Path        string   `yaml:"path,omitempty"`
+	Preset      string   `yaml:"preset,omitempty"`
 	Transforms  []string `yaml:"transforms,omitempty"`
 	Quality     int      `yaml:"quality,omitempty"`
 	Position    string   `yaml:"position,omitempty"`
@@ -70,6 +72,16 @@ func (bc *BatchConfig) GetTransforms(filename string) []string {
 	return bc.Defaults.Transforms
 }
 
+// GetPreset returns the preset named for a file. The name may be a local
+// preset or one saved to the account.
+func (bc *BatchConfig) GetPreset(filename string) string {
+	fc := bc.GetFileConfig(filename)
+	if fc != nil && fc.Preset != "" {
+		return fc.Preset
+	}
+	return bc.Defaults.Preset
+}
+
 func (bc *BatchConfig) GetQuality(filename string) int {
 	fc := bc.GetFileConfig(filename)
 	if fc != nil && fc.Quality > 0 {
@@ -110,3 +110,34 @@ func TestBuiltinPresets(t *testing.T) {
 		}
 	}
 }
+
+func TestBatchConfig_GetPreset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.yaml")
+	data := []byte(`defaults:
+  preset: ecommerce
+files:
+  - pattern: "hero-*.jpg"
+    preset: hero
+  - path: "logo.png"
+    quality: 90
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	bc, err := LoadBatchConfig(path)
+	if err != nil {
+		t.Fatalf("LoadBatchConfig() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"hero-1.jpg": "hero",
+		"logo.png":   "ecommerce",
+		"other.jpg":  "ecommerce",
+	}
+	for file, want := range tests {
+		if got := bc.GetPreset(file); got != want {
+			t.Errorf("GetPreset(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package filetype
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+// SniffLen is the number of leading bytes inspected when detecting a format.
+const SniffLen = 512
+
+var (
+	ErrMismatch     = errors.New("filetype: content does not match declared type")
+	ErrUnrecognized = errors.New("filetype: content format not recognized")
+)
+
+// aliases maps non-canonical MIME types sent by clients to the canonical form.
+var aliases = map[string]string{
+	"image/jpg":         "image/jpeg",
+	"image/pjpeg":       "image/jpeg",
+	"image/x-png":       "image/png",
+	"image/x-ms-bmp":    "image/bmp",
+	"image/x-bmp":       "image/bmp",
+	"video/x-m4v":       "video/mp4",
+	"video/avi":         "video/x-msvideo",
+	"video/msvideo":     "video/x-msvideo",
+	"application/x-pdf": "application/pdf",
+}
+
+// families groups types that share a container format and cannot always be
+// told apart from the leading bytes alone.
+var families = map[string]string{
+	"image/heic":       "heif",
+	"image/heif":       "heif",
+	"video/mp4":        "isobmff",
+	"video/quicktime":  "isobmff",
+	"video/webm":       "matroska",
+	"video/x-matroska": "matroska",
+	"video/ogg":        "ogg",
+	"audio/ogg":        "ogg",
+}
+
+// Normalize lowercases a MIME type, strips parameters and resolves aliases.
+func Normalize(mimeType string) string {
+	if idx := strings.Index(mimeType, ";"); idx != -1 {
+		mimeType = mimeType[:idx]
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if canonical, ok := aliases[mimeType]; ok {
+		return canonical
+	}
+	return mimeType
+}
+
+// Detect identifies the format of a file from its leading bytes. It returns an
+// empty string when the format is not one of the recognized media types.
+func Detect(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(head, []byte("BM")) && len(head) >= 14 && bytes.Equal(head[6:10], []byte{0, 0, 0, 0}):
+		return "image/bmp"
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return "application/pdf"
+	case bytes.HasPrefix(head, []byte("RIFF")) && len(head) >= 12:
+		return detectRIFF(head[8:12])
+	case len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")):
+		return detectISOBMFF(head)
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		if bytes.Contains(head, []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		if bytes.Contains(head, []byte("theora")) {
+			return "video/ogg"
+		}
+		return "audio/ogg"
+	case bytes.HasPrefix(head, []byte{0x00, 0x00, 0x01, 0xBA}), bytes.HasPrefix(head, []byte{0x00, 0x00, 0x01, 0xB3}):
+		return "video/mpeg"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return "audio/mpeg"
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0:
+		return "audio/aac"
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return "audio/mpeg"
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("moov")),
+		len(head) >= 8 && bytes.Equal(head[4:8], []byte("mdat")),
+		len(head) >= 8 && bytes.Equal(head[4:8], []byte("wide")):
+		return "video/quicktime"
+	case isSVG(head):
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+func detectRIFF(form []byte) string {
+	switch string(form) {
+	case "WEBP":
+		return "image/webp"
+	case "AVI ":
+		return "video/x-msvideo"
+	case "WAVE":
+		return "audio/wav"
+	}
+	return ""
+}
+
+// detectISOBMFF inspects the major brand of an ISO base media file.
+func detectISOBMFF(head []byte) string {
+	brand := string(head[8:12])
+	switch brand {
+	case "avif", "avis":
+		return "image/avif"
+	case "heic", "heix", "heim", "heis", "hevc", "hevx":
+		return "image/heic"
+	case "mif1", "msf1":
+		return "image/heif"
+	case "qt  ":
+		return "video/quicktime"
+	case "M4A ", "M4B ":
+		return "audio/x-m4a"
+	}
+	return "video/mp4"
+}
+
+// isSVG reports whether the head of a text file looks like an SVG document.
+func isSVG(head []byte) bool {
+	text := bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF"))
+	text = bytes.ToLower(bytes.TrimSpace(text))
+	if !bytes.HasPrefix(text, []byte("<")) {
+		return false
+	}
+	if bytes.Contains(text, []byte("<html")) || bytes.Contains(text, []byte("<!doctype html")) {
+		return false
+	}
+	return bytes.Contains(text, []byte("<svg"))
+}
+
+// Sniff reads up to SniffLen bytes from r and detects the format. The returned
+// reader replays the consumed bytes followed by the remainder of r.
+func Sniff(r io.Reader) (string, io.Reader, error) {
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", nil, err
+	}
+	head = head[:n]
+	return Detect(head), io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// requiresMatch reports whether a declared type must be confirmed by content
+// detection before it is accepted.
+func requiresMatch(declared string) bool {
+	return strings.HasPrefix(declared, "image/") ||
+		strings.HasPrefix(declared, "video/") ||
+		declared == "application/pdf"
+}
+
+// Resolve checks the content type declared by a client against the detected
+// one and returns the type that should be stored for the file. Images, PDFs
+// and videos must be confirmed by their content; other declared types fall
+// back to the detected type when one was found.
+func Resolve(declared, detected string) (string, error) {
+	declared = Normalize(declared)
+	if declared == "" {
+		declared = "application/octet-stream"
+	}
+
+	if detected == "" {
+		if requiresMatch(declared) {
+			return "", ErrUnrecognized
+		}
+		return declared, nil
+	}
+
+	if declared == "application/octet-stream" || declared == detected {
+		return detected, nil
+	}
+
+	if fam, ok := families[declared]; ok && families[detected] == fam {
+		return detected, nil
+	}
+
+	if requiresMatch(declared) || requiresMatch(detected) {
+		return "", ErrMismatch
+	}
+	return detected, nil
+}
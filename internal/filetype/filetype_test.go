@@ -0,0 +1,118 @@
+package filetype
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"jpeg", "\xFF\xD8\xFF\xE0\x00\x10JFIF", "image/jpeg"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"gif89a", "GIF89a\x01\x00", "image/gif"},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"bmp", "BM\x36\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00", "image/bmp"},
+		{"tiff little endian", "II*\x00\x08\x00\x00\x00", "image/tiff"},
+		{"avif", "\x00\x00\x00\x1cftypavif\x00\x00\x00\x00", "image/avif"},
+		{"heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", "image/heic"},
+		{"svg", "<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>", "image/svg+xml"},
+		{"pdf", "%PDF-1.7\n", "application/pdf"},
+		{"mp4", "\x00\x00\x00\x20ftypisom\x00\x00\x02\x00", "video/mp4"},
+		{"quicktime", "\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00", "video/quicktime"},
+		{"webm", "\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm", "video/webm"},
+		{"matroska", "\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x88matroska", "video/x-matroska"},
+		{"avi", "RIFF\x24\x00\x00\x00AVI LIST", "video/x-msvideo"},
+		{"mpeg", "\x00\x00\x01\xBA\x44\x00", "video/mpeg"},
+		{"wav", "RIFF\x24\x00\x00\x00WAVEfmt ", "audio/wav"},
+		{"mp3 id3", "ID3\x04\x00\x00", "audio/mpeg"},
+		{"html", "<!DOCTYPE html><html><body></body></html>", ""},
+		{"html with svg", "<html><body><svg></svg></body></html>", ""},
+		{"plain text", "hello world", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.head)); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	content := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 2*SniffLen)
+
+	detected, body, err := Sniff(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Sniff() error = %v", err)
+	}
+	if detected != "image/png" {
+		t.Errorf("Sniff() detected = %q, want image/png", detected)
+	}
+
+	replayed, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(replayed) != content {
+		t.Errorf("Sniff() reader returned %d bytes, want %d", len(replayed), len(content))
+	}
+}
+
+func TestSniff_ShortInput(t *testing.T) {
+	detected, body, err := Sniff(strings.NewReader("%PDF-"))
+	if err != nil {
+		t.Fatalf("Sniff() error = %v", err)
+	}
+	if detected != "application/pdf" {
+		t.Errorf("Sniff() detected = %q, want application/pdf", detected)
+	}
+	replayed, _ := io.ReadAll(body)
+	if string(replayed) != "%PDF-" {
+		t.Errorf("Sniff() replayed %q, want %%PDF-", replayed)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared string
+		detected string
+		want     string
+		wantErr  error
+	}{
+		{"exact match", "image/png", "image/png", "image/png", nil},
+		{"alias", "image/jpg", "image/jpeg", "image/jpeg", nil},
+		{"parameters stripped", "IMAGE/PNG; charset=binary", "image/png", "image/png", nil},
+		{"empty declared uses detected", "", "application/pdf", "application/pdf", nil},
+		{"octet-stream uses detected", "application/octet-stream", "video/mp4", "video/mp4", nil},
+		{"octet-stream unknown content", "application/octet-stream", "", "application/octet-stream", nil},
+		{"same container family", "video/quicktime", "video/mp4", "video/mp4", nil},
+		{"webm as matroska", "video/x-matroska", "video/webm", "video/webm", nil},
+		{"audio unknown content", "audio/aac", "", "audio/aac", nil},
+		{"image with unknown content", "image/png", "", "", ErrUnrecognized},
+		{"pdf with unknown content", "application/pdf", "", "", ErrUnrecognized},
+		{"png declared as jpeg", "image/jpeg", "image/png", "", ErrMismatch},
+		{"pdf declared as image", "image/png", "application/pdf", "", ErrMismatch},
+		{"video declared as audio", "audio/mpeg", "video/mp4", "", ErrMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.declared, tt.detected)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
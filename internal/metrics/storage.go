@@ -57,6 +57,27 @@ func (s *InstrumentedStorage) Download(ctx context.Context, key string) (io.Read
 	return &instrumentedReadCloser{ReadCloser: reader}, nil
 }
 
+func (s *InstrumentedStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	start := time.Now()
+
+	reader, err := s.Storage.DownloadRange(ctx, key, offset, length)
+
+	duration := time.Since(start).Seconds()
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	StorageOperationsTotal.WithLabelValues("download_range", status).Inc()
+	StorageOperationDuration.WithLabelValues("download_range").Observe(duration)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedReadCloser{ReadCloser: reader}, nil
+}
+
 func (s *InstrumentedStorage) Delete(ctx context.Context, key string) error {
 	start := time.Now()
 
@@ -91,6 +112,23 @@ func (s *InstrumentedStorage) Exists(ctx context.Context, key string) (bool, err
 	return exists, err
 }
 
+func (s *InstrumentedStorage) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	start := time.Now()
+
+	info, err := s.Storage.Stat(ctx, key)
+
+	duration := time.Since(start).Seconds()
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	StorageOperationsTotal.WithLabelValues("stat", status).Inc()
+	StorageOperationDuration.WithLabelValues("stat").Observe(duration)
+
+	return info, err
+}
+
 type instrumentedReadCloser struct {
 	io.ReadCloser
 	bytesRead int64
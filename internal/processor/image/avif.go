@@ -0,0 +1,87 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// ErrAVIFEncoderNotFound is returned when AVIF output is requested but the
+// avifenc binary is not installed.
+var ErrAVIFEncoderNotFound = errors.New("avifenc binary not found")
+
+const avifencBinary = "avifenc"
+
+// AVIFAvailable reports whether AVIF encoding is supported on this host.
+// Callers check it at startup so AVIF requests are rejected up front
+// instead of failing in the worker.
+func AVIFAvailable() bool {
+	_, err := exec.LookPath(avifencBinary)
+	return err == nil
+}
+
+// encodeAVIF writes img to w as AVIF using avifenc. The image is handed to
+// the encoder as a lossless PNG so quality is only applied once.
+func encodeAVIF(ctx context.Context, w io.Writer, img image.Image, quality int, tempDir string) error {
+	if !AVIFAvailable() {
+		return ErrAVIFEncoderNotFound
+	}
+
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	inputFile, err := os.CreateTemp(tempDir, "avif-input-*.png")
+	if err != nil {
+		return fmt.Errorf("failed to create input temp file: %w", err)
+	}
+	inputPath := inputFile.Name()
+	defer func() { _ = os.Remove(inputPath) }()
+
+	if err := png.Encode(inputFile, img); err != nil {
+		_ = inputFile.Close()
+		return fmt.Errorf("failed to write input data: %w", err)
+	}
+	_ = inputFile.Close()
+
+	outputFile, err := os.CreateTemp(tempDir, "avif-output-*.avif")
+	if err != nil {
+		return fmt.Errorf("failed to create output temp file: %w", err)
+	}
+	outputPath := outputFile.Name()
+	_ = outputFile.Close()
+	defer func() { _ = os.Remove(outputPath) }()
+
+	args := []string{
+		"-q", strconv.Itoa(quality),
+		"--speed", "6",
+		inputPath,
+		outputPath,
+	}
+
+	cmd := exec.CommandContext(ctx, avifencBinary, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("avifenc failed: %w, stderr: %s", err, stderr.String())
+	}
+
+	outputData, err := os.ReadFile(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read output file: %w", err)
+	}
+
+	_, err = w.Write(outputData)
+	return err
+}
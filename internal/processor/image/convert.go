@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"image"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
@@ -41,9 +40,9 @@ func (p *ConvertProcessor) SupportedTypes() []string {
 }
 
 func (p *ConvertProcessor) Process(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
-	img, _, err := image.Decode(input)
+	img, _, err := decodeImage(input)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", processor.ErrCorruptedFile, err)
+		return nil, err
 	}
 
 	bounds := img.Bounds()
@@ -83,6 +82,12 @@ func (p *ConvertProcessor) Process(ctx context.Context, opts *processor.Options,
 		}
 		contentType = "image/gif"
 		filename = "converted.gif"
+	case "avif":
+		if err := encodeAVIF(ctx, &buf, img, quality, p.config.TempDir); err != nil {
+			return nil, fmt.Errorf("failed to encode avif: %w", err)
+		}
+		contentType = "image/avif"
+		filename = "converted.avif"
 	default:
 		return nil, fmt.Errorf("%w: unsupported target format %q", processor.ErrInvalidConfig, targetFormat)
 	}
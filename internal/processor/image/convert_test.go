@@ -3,6 +3,7 @@ package image
 import (
 	"bytes"
 	"context"
+	"errors"
 	"image"
 	"io"
 	"testing"
@@ -205,3 +206,28 @@ func TestConvertProcessor_Dimensions(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertProcessor_AVIF(t *testing.T) {
+	p := NewConvertProcessor(processor.DefaultConfig())
+
+	result, err := p.Process(context.Background(), &processor.Options{Format: "avif", Quality: 60}, createTestJPEG(100, 100))
+	if !AVIFAvailable() {
+		if !errors.Is(err, ErrAVIFEncoderNotFound) {
+			t.Errorf("error = %v, want ErrAVIFEncoderNotFound", err)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ContentType != "image/avif" {
+		t.Errorf("ContentType = %v, want image/avif", result.ContentType)
+	}
+	if result.Filename != "converted.avif" {
+		t.Errorf("Filename = %v, want converted.avif", result.Filename)
+	}
+	if result.Size <= 0 {
+		t.Error("Size should be greater than 0")
+	}
+}
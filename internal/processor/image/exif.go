@@ -0,0 +1,629 @@
+package image
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// metadataBlocks holds the raw metadata payloads embedded in an image file.
+type metadataBlocks struct {
+	exif []byte // TIFF structure, without the "Exif\0\0" prefix
+	xmp  []byte
+	iptc []byte // IPTC-IIM records
+	icc  []byte
+}
+
+var (
+	jpegExifPrefix = []byte("Exif\x00\x00")
+	jpegXMPPrefix  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	jpegICCPrefix  = []byte("ICC_PROFILE\x00")
+	jpegPSPrefix   = []byte("Photoshop 3.0\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+const (
+	jpegMarkerSOS   = 0xDA
+	jpegMarkerEOI   = 0xD9
+	jpegMarkerAPP0  = 0xE0
+	jpegMarkerAPP1  = 0xE1
+	jpegMarkerAPP2  = 0xE2
+	jpegMarkerAPP13 = 0xED
+	jpegMarkerAPP14 = 0xEE
+	jpegMarkerCOM   = 0xFE
+)
+
+// jpegSegment is a marker segment before the start of scan. data is the
+// payload after the length field; raw is the whole segment.
+type jpegSegment struct {
+	marker byte
+	data   []byte
+	raw    []byte
+}
+
+// jpegSegments returns the marker segments of a JPEG up to the start of scan,
+// and the offset the scan starts at.
+func jpegSegments(data []byte) ([]jpegSegment, int, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, false
+	}
+	var segments []jpegSegment
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return segments, pos, false
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		if marker == jpegMarkerSOS || marker == jpegMarkerEOI {
+			return segments, pos, true
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return segments, pos, false
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: data[pos+4 : end], raw: data[pos:end]})
+		pos = end
+	}
+	return segments, pos, false
+}
+
+type pngChunk struct {
+	typ  string
+	data []byte
+	raw  []byte
+}
+
+func pngChunks(data []byte) ([]pngChunk, bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, false
+	}
+	var chunks []pngChunk
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return chunks, false
+		}
+		chunks = append(chunks, pngChunk{typ: string(data[pos+4 : pos+8]), data: data[pos+8 : pos+8+length], raw: data[pos:end]})
+		pos = end
+	}
+	return chunks, true
+}
+
+type riffChunk struct {
+	fourCC string
+	data   []byte
+	raw    []byte // including the padding byte
+}
+
+func webpChunks(data []byte) ([]riffChunk, bool) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false
+	}
+	var chunks []riffChunk
+	pos := 12
+	for pos+8 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size
+		if size < 0 || end > len(data) {
+			return chunks, false
+		}
+		padded := end + size%2
+		if padded > len(data) {
+			padded = len(data)
+		}
+		chunks = append(chunks, riffChunk{fourCC: string(data[pos : pos+4]), data: data[pos+8 : end], raw: data[pos:padded]})
+		pos = padded
+	}
+	return chunks, true
+}
+
+// findMetadataBlocks locates EXIF, XMP, IPTC and ICC payloads in JPEG, PNG
+// and WebP files. Other formats return no blocks.
+func findMetadataBlocks(data []byte) metadataBlocks {
+	var b metadataBlocks
+
+	if segments, _, ok := jpegSegments(data); ok || len(segments) > 0 {
+		for _, s := range segments {
+			switch {
+			case s.marker == jpegMarkerAPP1 && bytes.HasPrefix(s.data, jpegExifPrefix) && b.exif == nil:
+				b.exif = s.data[len(jpegExifPrefix):]
+			case s.marker == jpegMarkerAPP1 && bytes.HasPrefix(s.data, jpegXMPPrefix) && b.xmp == nil:
+				b.xmp = s.data[len(jpegXMPPrefix):]
+			case s.marker == jpegMarkerAPP2 && bytes.HasPrefix(s.data, jpegICCPrefix) && len(s.data) > len(jpegICCPrefix)+2:
+				// Large profiles are split across segments in sequence order.
+				b.icc = append(b.icc, s.data[len(jpegICCPrefix)+2:]...)
+			case s.marker == jpegMarkerAPP13 && bytes.HasPrefix(s.data, jpegPSPrefix):
+				b.iptc = photoshopIPTC(s.data[len(jpegPSPrefix):])
+			}
+		}
+		return b
+	}
+
+	if chunks, ok := pngChunks(data); ok || len(chunks) > 0 {
+		for _, c := range chunks {
+			switch c.typ {
+			case "eXIf":
+				b.exif = c.data
+			case "iTXt":
+				if keyword, text, ok := pngInternationalText(c.data); ok && keyword == "XML:com.adobe.xmp" {
+					b.xmp = text
+				}
+			case "iCCP":
+				if i := bytes.IndexByte(c.data, 0); i >= 0 && i+2 <= len(c.data) {
+					b.icc, _ = inflate(c.data[i+2:])
+				}
+			}
+		}
+		return b
+	}
+
+	if chunks, ok := webpChunks(data); ok || len(chunks) > 0 {
+		for _, c := range chunks {
+			switch c.fourCC {
+			case "EXIF":
+				b.exif = bytes.TrimPrefix(c.data, jpegExifPrefix)
+			case "XMP ":
+				b.xmp = c.data
+			case "ICCP":
+				b.icc = c.data
+			}
+		}
+	}
+	return b
+}
+
+// pngInternationalText splits an iTXt chunk into its keyword and text.
+func pngInternationalText(data []byte) (string, []byte, bool) {
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(rest) < 2 {
+		return "", nil, false
+	}
+	compressed := rest[0] == 1
+	rest = rest[2:]
+	// Skip the language tag and translated keyword.
+	for range 2 {
+		if _, rest, ok = bytes.Cut(rest, []byte{0}); !ok {
+			return "", nil, false
+		}
+	}
+	if compressed {
+		text, err := inflate(rest)
+		return string(keyword), text, err == nil
+	}
+	return string(keyword), rest, true
+}
+
+// maxInflatedSize bounds decompressed PNG metadata chunks.
+const maxInflatedSize = 4 << 20
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(io.LimitReader(r, maxInflatedSize))
+}
+
+// photoshopIPTC returns the IPTC-IIM resource from Photoshop image resource
+// blocks.
+func photoshopIPTC(data []byte) []byte {
+	for len(data) >= 12 && string(data[0:4]) == "8BIM" {
+		id := binary.BigEndian.Uint16(data[4:])
+		// The resource name is a Pascal string padded to an even length.
+		nameLen := int(data[6]) + 1
+		nameLen += nameLen % 2
+		pos := 6 + nameLen
+		if pos+4 > len(data) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		pos += 4
+		if size < 0 || pos+size > len(data) {
+			return nil
+		}
+		if id == 0x0404 {
+			return data[pos : pos+size]
+		}
+		pos += size + size%2
+		if pos > len(data) {
+			return nil
+		}
+		data = data[pos:]
+	}
+	return nil
+}
+
+// parseIPTC reads the caption and keywords from IPTC-IIM application
+// records.
+func parseIPTC(data []byte, meta *ImageMetadata) {
+	for len(data) >= 5 && data[0] == 0x1C {
+		record, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:]))
+		// Extended datasets set the high bit and are never used for text.
+		if size&0x8000 != 0 || 5+size > len(data) {
+			return
+		}
+		value := cleanString(data[5 : 5+size])
+		if record == 2 && value != "" {
+			switch dataset {
+			case 120:
+				meta.Caption = value
+			case 25:
+				meta.Keywords = append(meta.Keywords, value)
+			}
+		}
+		data = data[5+size:]
+	}
+}
+
+const (
+	xmpNamespaceDC  = "http://purl.org/dc/elements/1.1/"
+	xmpNamespaceRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// parseXMP fills in the caption and keywords from dc:description and
+// dc:subject when IPTC did not provide them.
+func parseXMP(data []byte, meta *ImageMetadata) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		field       string
+		inItem      bool
+		item        strings.Builder
+		description string
+		subjects    []string
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == xmpNamespaceDC && (t.Name.Local == "description" || t.Name.Local == "subject"):
+				field = t.Name.Local
+			case t.Name.Space == xmpNamespaceRDF && t.Name.Local == "li" && field != "":
+				inItem = true
+				item.Reset()
+			}
+		case xml.CharData:
+			if inItem {
+				item.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Space == xmpNamespaceRDF && t.Name.Local == "li" && inItem:
+				inItem = false
+				value := strings.TrimSpace(item.String())
+				if value == "" {
+					continue
+				}
+				if field == "description" && description == "" {
+					description = value
+				} else if field == "subject" {
+					subjects = append(subjects, value)
+				}
+			case t.Name.Space == xmpNamespaceDC && t.Name.Local == field:
+				field = ""
+			}
+		}
+	}
+
+	if meta.Caption == "" {
+		meta.Caption = description
+	}
+	if len(meta.Keywords) == 0 {
+		meta.Keywords = subjects
+	}
+}
+
+// iccProfileName returns the description tag of an ICC profile.
+func iccProfileName(data []byte) string {
+	if len(data) < 132 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			return ""
+		}
+		if string(data[entry:entry+4]) != "desc" {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+4:]))
+		size := int(binary.BigEndian.Uint32(data[entry+8:]))
+		if offset < 0 || size < 12 || offset+size > len(data) {
+			return ""
+		}
+		return iccText(data[offset : offset+size])
+	}
+	return ""
+}
+
+// iccText decodes a textDescriptionType (ICC v2) or
+// multiLocalizedUnicodeType (ICC v4) tag, using the first localisation.
+func iccText(tag []byte) string {
+	switch string(tag[0:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if n <= 0 || 12+n > len(tag) {
+			return ""
+		}
+		return cleanString(tag[12 : 12+n])
+	case "mluc":
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:]) == 0 {
+			return ""
+		}
+		n := int(binary.BigEndian.Uint32(tag[20:]))
+		offset := int(binary.BigEndian.Uint32(tag[24:]))
+		if n <= 0 || offset < 0 || offset+n > len(tag) {
+			return ""
+		}
+		units := make([]uint16, n/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+i*2:])
+		}
+		return cleanString([]byte(string(utf16.Decode(units))))
+	}
+	return ""
+}
+
+// TIFF tags read from EXIF.
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetTime       = 0x9011
+	tagLensModel        = 0xA434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// tiffTypeSizes maps TIFF field types to their size in bytes.
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+type tiffField struct {
+	typ   uint16
+	count int
+	value []byte
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func newTIFFReader(data []byte) (*tiffReader, uint32, bool) {
+	if len(data) < 8 {
+		return nil, 0, false
+	}
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, false
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil, 0, false
+	}
+	return &tiffReader{data: data, order: order}, order.Uint32(data[4:]), true
+}
+
+// ifd reads the directory at offset. Malformed entries are skipped.
+func (t *tiffReader) ifd(offset uint32) map[uint16]tiffField {
+	if offset == 0 || int64(offset)+2 > int64(len(t.data)) {
+		return nil
+	}
+	pos := int(offset)
+	n := int(t.order.Uint16(t.data[pos:]))
+	fields := make(map[uint16]tiffField, n)
+	for i := 0; i < n; i++ {
+		entry := pos + 2 + i*12
+		if entry+12 > len(t.data) {
+			break
+		}
+		tag := t.order.Uint16(t.data[entry:])
+		typ := t.order.Uint16(t.data[entry+2:])
+		count := int64(t.order.Uint32(t.data[entry+4:]))
+		size, ok := tiffTypeSizes[typ]
+		if !ok {
+			continue
+		}
+		total := count * int64(size)
+		var value []byte
+		if total <= 4 {
+			value = t.data[entry+8 : entry+8+int(total)]
+		} else {
+			start := int64(t.order.Uint32(t.data[entry+8:]))
+			if start+total > int64(len(t.data)) {
+				continue
+			}
+			value = t.data[start : start+total]
+		}
+		fields[tag] = tiffField{typ: typ, count: int(count), value: value}
+	}
+	return fields
+}
+
+func (t *tiffReader) uint(f tiffField) (uint32, bool) {
+	switch {
+	case f.typ == 3 && len(f.value) >= 2:
+		return uint32(t.order.Uint16(f.value)), true
+	case f.typ == 4 && len(f.value) >= 4:
+		return t.order.Uint32(f.value), true
+	case (f.typ == 1 || f.typ == 7) && len(f.value) >= 1:
+		return uint32(f.value[0]), true
+	}
+	return 0, false
+}
+
+func (t *tiffReader) rationals(f tiffField) []float64 {
+	if f.typ != 5 && f.typ != 10 {
+		return nil
+	}
+	out := make([]float64, 0, f.count)
+	for i := 0; i+8 <= len(f.value); i += 8 {
+		num, den := t.order.Uint32(f.value[i:]), t.order.Uint32(f.value[i+4:])
+		if den == 0 {
+			return nil
+		}
+		if f.typ == 10 {
+			out = append(out, float64(int32(num))/float64(int32(den)))
+		} else {
+			out = append(out, float64(num)/float64(den))
+		}
+	}
+	return out
+}
+
+func (t *tiffReader) string(fields map[uint16]tiffField, tag uint16) string {
+	f, ok := fields[tag]
+	if !ok || f.typ != 2 {
+		return ""
+	}
+	return cleanString(f.value)
+}
+
+// exifOrientation returns the EXIF orientation of an image file, or 1 when
+// it has none.
+func exifOrientation(data []byte) int {
+	return tiffOrientation(findMetadataBlocks(data).exif)
+}
+
+func tiffOrientation(exif []byte) int {
+	t, offset, ok := newTIFFReader(exif)
+	if !ok {
+		return orientationNormal
+	}
+	if f, ok := t.ifd(offset)[tagOrientation]; ok {
+		if v, ok := t.uint(f); ok && v >= 1 && v <= 8 {
+			return int(v)
+		}
+	}
+	return orientationNormal
+}
+
+// parseEXIF reads camera, capture time, GPS and orientation fields.
+func parseEXIF(data []byte, meta *ImageMetadata) {
+	t, offset, ok := newTIFFReader(data)
+	if !ok {
+		return
+	}
+	ifd0 := t.ifd(offset)
+	meta.CameraMake = t.string(ifd0, tagMake)
+	meta.CameraModel = t.string(ifd0, tagModel)
+	meta.Orientation = tiffOrientation(data)
+
+	captured := t.string(ifd0, tagDateTime)
+	if f, ok := ifd0[tagExifIFD]; ok {
+		if sub, ok := t.uint(f); ok {
+			exif := t.ifd(sub)
+			meta.LensModel = t.string(exif, tagLensModel)
+			if original := t.string(exif, tagDateTimeOriginal); original != "" {
+				captured = original
+			}
+			if at, ok := parseEXIFTime(captured, t.string(exif, tagOffsetTime)); ok {
+				meta.CapturedAt = &at
+			}
+		}
+	} else if at, ok := parseEXIFTime(captured, ""); ok {
+		meta.CapturedAt = &at
+	}
+
+	if f, ok := ifd0[tagGPSIFD]; ok {
+		if sub, ok := t.uint(f); ok {
+			meta.GPS = parseGPS(t, t.ifd(sub))
+		}
+	}
+}
+
+// parseEXIFTime parses an EXIF date. Without an offset the camera's time
+// zone is unknown and the time is treated as UTC.
+func parseEXIFTime(value, offset string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t, true
+		}
+	}
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil || t.Year() < 1900 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func parseGPS(t *tiffReader, fields map[uint16]tiffField) *GPSInfo {
+	lat := gpsCoordinate(t.rationals(fields[tagGPSLatitude]), t.string(fields, tagGPSLatitudeRef), "S")
+	lon := gpsCoordinate(t.rationals(fields[tagGPSLongitude]), t.string(fields, tagGPSLongitudeRef), "W")
+	if lat == nil || lon == nil || math.Abs(*lat) > 90 || math.Abs(*lon) > 180 {
+		return nil
+	}
+
+	gps := &GPSInfo{Latitude: *lat, Longitude: *lon}
+	if alt := t.rationals(fields[tagGPSAltitude]); len(alt) == 1 {
+		altitude := alt[0]
+		if f, ok := fields[tagGPSAltitudeRef]; ok {
+			if ref, ok := t.uint(f); ok && ref == 1 {
+				altitude = -altitude
+			}
+		}
+		gps.Altitude = &altitude
+	}
+	return gps
+}
+
+// gpsCoordinate converts degrees, minutes and seconds to decimal degrees.
+func gpsCoordinate(dms []float64, ref, negative string) *float64 {
+	if len(dms) != 3 {
+		return nil
+	}
+	v := dms[0] + dms[1]/60 + dms[2]/3600
+	if strings.EqualFold(ref, negative) {
+		v = -v
+	}
+	return &v
+}
+
+// cleanString trims NUL padding and whitespace and drops invalid UTF-8.
+func cleanString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(b), ""))
+}
@@ -9,6 +9,7 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
 	_ "golang.org/x/image/webp"
@@ -39,28 +40,78 @@ func (p *MetadataProcessor) SupportedTypes() []string {
 	}
 }
 
+// ImageMetadata describes an image and the EXIF, IPTC, XMP and ICC metadata
+// embedded in it. Width and Height are the displayed dimensions, after the
+// EXIF orientation is applied.
 type ImageMetadata struct {
-	Width     int    `json:"width"`
-	Height    int    `json:"height"`
-	Format    string `json:"format"`
-	ColorMode string `json:"color_mode,omitempty"`
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Format      string     `json:"format"`
+	ColorMode   string     `json:"color_mode,omitempty"`
+	CameraMake  string     `json:"camera_make,omitempty"`
+	CameraModel string     `json:"camera_model,omitempty"`
+	LensModel   string     `json:"lens_model,omitempty"`
+	CapturedAt  *time.Time `json:"captured_at,omitempty"`
+	GPS         *GPSInfo   `json:"gps,omitempty"`
+	Orientation int        `json:"orientation,omitempty"`
+	ICCProfile  string     `json:"icc_profile,omitempty"`
+	Caption     string     `json:"caption,omitempty"`
+	Keywords    []string   `json:"keywords,omitempty"`
 }
 
-func (p *MetadataProcessor) Process(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
-	data, err := io.ReadAll(input)
+// GPSInfo is a capture location in decimal degrees. Altitude is in metres
+// and negative below sea level.
+type GPSInfo struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
+// ExtractMetadata reads the dimensions and embedded metadata of a JPEG, PNG,
+// GIF, WebP or BMP image. IPTC captions and keywords take precedence over
+// their XMP equivalents.
+func ExtractMetadata(data []byte) (*ImageMetadata, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
 		return nil, processor.ErrCorruptedFile
 	}
 
-	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	meta := &ImageMetadata{
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Format:      format,
+		Orientation: orientationNormal,
+	}
+
+	blocks := findMetadataBlocks(data)
+	if blocks.exif != nil {
+		parseEXIF(blocks.exif, meta)
+	}
+	if blocks.iptc != nil {
+		parseIPTC(blocks.iptc, meta)
+	}
+	if blocks.xmp != nil {
+		parseXMP(blocks.xmp, meta)
+	}
+	if blocks.icc != nil {
+		meta.ICCProfile = iccProfileName(blocks.icc)
+	}
+
+	if swapsDimensions(meta.Orientation) {
+		meta.Width, meta.Height = meta.Height, meta.Width
+	}
+	return meta, nil
+}
+
+func (p *MetadataProcessor) Process(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
+	data, err := io.ReadAll(input)
 	if err != nil {
 		return nil, processor.ErrCorruptedFile
 	}
 
-	meta := ImageMetadata{
-		Width:  cfg.Width,
-		Height: cfg.Height,
-		Format: format,
+	meta, err := ExtractMetadata(data)
+	if err != nil {
+		return nil, err
 	}
 
 	jsonData, err := json.Marshal(meta)
@@ -73,9 +124,9 @@ func (p *MetadataProcessor) Process(ctx context.Context, opts *processor.Options
 		ContentType: "application/json",
 		Size:        int64(len(jsonData)),
 		Metadata: processor.ResultMetadata{
-			Width:  cfg.Width,
-			Height: cfg.Height,
-			Format: format,
+			Width:  meta.Width,
+			Height: meta.Height,
+			Format: meta.Format,
 		},
 	}, nil
 }
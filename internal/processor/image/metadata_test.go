@@ -0,0 +1,395 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+)
+
+// testTag is a TIFF directory entry used to build EXIF blocks.
+type testTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiTag(tag uint16, s string) testTag {
+	v := append([]byte(s), 0)
+	return testTag{tag: tag, typ: 2, count: uint32(len(v)), value: v}
+}
+
+func shortTag(tag, v uint16) testTag {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return testTag{tag: tag, typ: 3, count: 1, value: b}
+}
+
+func byteTag(tag uint16, v byte) testTag {
+	return testTag{tag: tag, typ: 1, count: 1, value: []byte{v}}
+}
+
+func rationalTag(tag uint16, values ...[2]uint32) testTag {
+	b := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, v[0])
+		b = binary.LittleEndian.AppendUint32(b, v[1])
+	}
+	return testTag{tag: tag, typ: 5, count: uint32(len(values)), value: b}
+}
+
+// buildEXIF lays out a little-endian TIFF structure with IFD0 and optional
+// EXIF and GPS sub-directories.
+func buildEXIF(ifd0, exif, gps []testTag) []byte {
+	ifdSize := func(n int) uint32 { return uint32(2 + 12*n + 4) }
+	le := binary.LittleEndian
+
+	ifd0 = slices.Clone(ifd0)
+	pointers := 0
+	if len(exif) > 0 {
+		pointers++
+	}
+	if len(gps) > 0 {
+		pointers++
+	}
+	next := 8 + ifdSize(len(ifd0)+pointers)
+	if len(exif) > 0 {
+		ifd0 = append(ifd0, testTag{tag: tagExifIFD, typ: 4, count: 1, value: le.AppendUint32(nil, next)})
+		next += ifdSize(len(exif))
+	}
+	if len(gps) > 0 {
+		ifd0 = append(ifd0, testTag{tag: tagGPSIFD, typ: 4, count: 1, value: le.AppendUint32(nil, next)})
+		next += ifdSize(len(gps))
+	}
+
+	out := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	var extra []byte
+	for _, ifd := range [][]testTag{ifd0, exif, gps} {
+		if len(ifd) == 0 {
+			continue
+		}
+		out = le.AppendUint16(out, uint16(len(ifd)))
+		for _, tag := range ifd {
+			out = le.AppendUint16(out, tag.tag)
+			out = le.AppendUint16(out, tag.typ)
+			out = le.AppendUint32(out, tag.count)
+			if len(tag.value) <= 4 {
+				out = append(out, tag.value...)
+				out = append(out, make([]byte, 4-len(tag.value))...)
+				continue
+			}
+			out = le.AppendUint32(out, next+uint32(len(extra)))
+			extra = append(extra, tag.value...)
+		}
+		out = le.AppendUint32(out, 0)
+	}
+	return append(out, extra...)
+}
+
+func jpegSegmentBytes(marker byte, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	seg := []byte{0xFF, marker}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(data)+2))
+	return append(seg, data...)
+}
+
+// withJPEGSegments inserts marker segments after the SOI marker.
+func withJPEGSegments(jpegData []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, jpegData[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, jpegData[2:]...)
+}
+
+func pngChunkBytes(typ string, data []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	out = append(out, typ...)
+	out = append(out, data...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+}
+
+// withPNGChunks inserts chunks after the IHDR chunk.
+func withPNGChunks(pngData []byte, chunks ...[]byte) []byte {
+	const afterIHDR = 8 + 25
+	out := append([]byte{}, pngData[:afterIHDR]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, pngData[afterIHDR:]...)
+}
+
+func testIPTC(caption string, keywords ...string) []byte {
+	dataset := func(id byte, value string) []byte {
+		out := []byte{0x1C, 2, id}
+		out = binary.BigEndian.AppendUint16(out, uint16(len(value)))
+		return append(out, value...)
+	}
+	iim := dataset(120, caption)
+	for _, k := range keywords {
+		iim = append(iim, dataset(25, k)...)
+	}
+
+	out := append([]byte{}, jpegPSPrefix...)
+	out = append(out, "8BIM"...)
+	out = binary.BigEndian.AppendUint16(out, 0x0404)
+	out = append(out, 0, 0) // empty name, padded
+	out = binary.BigEndian.AppendUint32(out, uint32(len(iim)))
+	out = append(out, iim...)
+	if len(iim)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func testXMP(description string, subjects ...string) []byte {
+	var items string
+	for _, s := range subjects {
+		items += "<rdf:li>" + s + "</rdf:li>"
+	}
+	return []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<dc:description><rdf:Alt><rdf:li xml:lang="x-default">` + description + `</rdf:li></rdf:Alt></dc:description>` +
+		`<dc:subject><rdf:Bag>` + items + `</rdf:Bag></dc:subject>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`)
+}
+
+// testICC builds a minimal ICC profile with a v2 description tag.
+func testICC(name string) []byte {
+	profile := make([]byte, 128)
+	profile = binary.BigEndian.AppendUint32(profile, 1)
+	profile = append(profile, "desc"...)
+	tag := append([]byte("desc\x00\x00\x00\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(name)+1))...)
+	tag = append(append(tag, name...), 0)
+	profile = binary.BigEndian.AppendUint32(profile, 144)
+	profile = binary.BigEndian.AppendUint32(profile, uint32(len(tag)))
+	return append(profile, tag...)
+}
+
+func testJPEGBytes(img image.Image) []byte {
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	return buf.Bytes()
+}
+
+// createCameraJPEG returns a 40x20 JPEG carrying the metadata a phone camera
+// writes, with orientation 6 (rotate 90 degrees clockwise to display).
+func createCameraJPEG() []byte {
+	exif := buildEXIF(
+		[]testTag{
+			asciiTag(tagMake, "Canon"),
+			asciiTag(tagModel, "EOS R5"),
+			shortTag(tagOrientation, 6),
+			asciiTag(tagDateTime, "2024:05:07 00:00:00"),
+		},
+		[]testTag{
+			asciiTag(tagDateTimeOriginal, "2024:05:06 07:08:09"),
+			asciiTag(tagOffsetTime, "+02:00"),
+			asciiTag(tagLensModel, "RF24-70mm F2.8 L IS USM"),
+		},
+		[]testTag{
+			asciiTag(tagGPSLatitudeRef, "N"),
+			rationalTag(tagGPSLatitude, [2]uint32{40, 1}, [2]uint32{26, 1}, [2]uint32{4630, 100}),
+			asciiTag(tagGPSLongitudeRef, "W"),
+			rationalTag(tagGPSLongitude, [2]uint32{79, 1}, [2]uint32{58, 1}, [2]uint32{56, 1}),
+			byteTag(tagGPSAltitudeRef, 1),
+			rationalTag(tagGPSAltitude, [2]uint32{100, 1}),
+		},
+	)
+
+	return withJPEGSegments(testJPEGBytes(createTestImage(40, 20)),
+		jpegSegmentBytes(jpegMarkerAPP1, jpegExifPrefix, exif),
+		jpegSegmentBytes(jpegMarkerAPP1, jpegXMPPrefix, testXMP("XMP caption", "xmp")),
+		jpegSegmentBytes(jpegMarkerAPP2, jpegICCPrefix, []byte{1, 1}, testICC("Display P3")),
+		jpegSegmentBytes(jpegMarkerAPP13, testIPTC("Sunset over the bay", "sunset", "bay")),
+		jpegSegmentBytes(jpegMarkerCOM, []byte("taken at home")),
+	)
+}
+
+func TestExtractMetadata_CameraJPEG(t *testing.T) {
+	meta, err := ExtractMetadata(createCameraJPEG())
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error = %v", err)
+	}
+
+	if meta.Width != 20 || meta.Height != 40 {
+		t.Errorf("dimensions = %dx%d, want 20x40 after orientation", meta.Width, meta.Height)
+	}
+	if meta.Format != "jpeg" || meta.Orientation != 6 {
+		t.Errorf("format = %q, orientation = %d", meta.Format, meta.Orientation)
+	}
+	if meta.CameraMake != "Canon" || meta.CameraModel != "EOS R5" || meta.LensModel != "RF24-70mm F2.8 L IS USM" {
+		t.Errorf("camera = %q %q, lens %q", meta.CameraMake, meta.CameraModel, meta.LensModel)
+	}
+
+	wantTime := time.Date(2024, 5, 6, 5, 8, 9, 0, time.UTC)
+	if meta.CapturedAt == nil || !meta.CapturedAt.Equal(wantTime) {
+		t.Errorf("captured_at = %v, want %v", meta.CapturedAt, wantTime)
+	}
+
+	if meta.GPS == nil {
+		t.Fatal("expected GPS coordinates")
+	}
+	if math.Abs(meta.GPS.Latitude-40.446195) > 1e-6 || math.Abs(meta.GPS.Longitude+79.982222) > 1e-6 {
+		t.Errorf("gps = %v, %v", meta.GPS.Latitude, meta.GPS.Longitude)
+	}
+	if meta.GPS.Altitude == nil || *meta.GPS.Altitude != -100 {
+		t.Errorf("altitude = %v, want -100", meta.GPS.Altitude)
+	}
+
+	if meta.ICCProfile != "Display P3" {
+		t.Errorf("icc_profile = %q", meta.ICCProfile)
+	}
+	// IPTC takes precedence over XMP.
+	if meta.Caption != "Sunset over the bay" || !slices.Equal(meta.Keywords, []string{"sunset", "bay"}) {
+		t.Errorf("caption = %q, keywords = %v", meta.Caption, meta.Keywords)
+	}
+}
+
+func TestExtractMetadata_PNGWithXMP(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, createTestImage(30, 10))
+
+	itxt := append([]byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"), testXMP("Team photo", "team", "offsite")...)
+	data := withPNGChunks(buf.Bytes(), pngChunkBytes("iTXt", itxt))
+
+	meta, err := ExtractMetadata(data)
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error = %v", err)
+	}
+	if meta.Width != 30 || meta.Height != 10 || meta.Format != "png" {
+		t.Errorf("got %dx%d %s", meta.Width, meta.Height, meta.Format)
+	}
+	if meta.Caption != "Team photo" || !slices.Equal(meta.Keywords, []string{"team", "offsite"}) {
+		t.Errorf("caption = %q, keywords = %v", meta.Caption, meta.Keywords)
+	}
+}
+
+func TestExtractMetadata_NoMetadata(t *testing.T) {
+	data, _ := io.ReadAll(createTestJPEG(64, 32))
+	meta, err := ExtractMetadata(data)
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error = %v", err)
+	}
+	if meta.Orientation != orientationNormal || meta.GPS != nil || meta.CapturedAt != nil || meta.CameraMake != "" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if meta.Width != 64 || meta.Height != 32 {
+		t.Errorf("dimensions = %dx%d, want 64x32", meta.Width, meta.Height)
+	}
+}
+
+func TestExtractMetadata_Corrupted(t *testing.T) {
+	if _, err := ExtractMetadata([]byte("not an image")); err == nil {
+		t.Error("expected error for invalid image")
+	}
+	// Truncated EXIF must not panic.
+	data := withJPEGSegments(testJPEGBytes(createTestImage(8, 8)),
+		jpegSegmentBytes(jpegMarkerAPP1, jpegExifPrefix, []byte("II*\x00\x08\x00\x00\x00\xff\x00")))
+	if _, err := ExtractMetadata(data); err != nil {
+		t.Errorf("ExtractMetadata() error = %v", err)
+	}
+}
+
+func TestMetadataProcessor_Process(t *testing.T) {
+	proc := NewMetadataProcessor(nil)
+	result, err := proc.Process(context.Background(), nil, bytes.NewReader(createCameraJPEG()))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var meta ImageMetadata
+	if err := json.NewDecoder(result.Data).Decode(&meta); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if meta.CameraModel != "EOS R5" || meta.GPS == nil {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if result.Metadata.Width != 20 || result.Metadata.Height != 40 {
+		t.Errorf("result dimensions = %dx%d, want 20x40", result.Metadata.Width, result.Metadata.Height)
+	}
+}
+
+// createOrientedJPEG returns a 40x20 JPEG, red on the left and blue on the
+// right, tagged with the given orientation.
+func createOrientedJPEG(orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	exif := buildEXIF([]testTag{shortTag(tagOrientation, orientation)}, nil, nil)
+	return withJPEGSegments(testJPEGBytes(img), jpegSegmentBytes(jpegMarkerAPP1, jpegExifPrefix, exif))
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xC000 && b < 0x4000
+}
+
+func TestDecodeImage_AutoOrients(t *testing.T) {
+	tests := []struct {
+		orientation   uint16
+		width, height int
+		redX, redY    int
+		blueX, blueY  int
+	}{
+		{1, 40, 20, 5, 10, 35, 10},
+		{3, 40, 20, 35, 10, 5, 10},
+		{6, 20, 40, 10, 5, 10, 35},
+		{8, 20, 40, 10, 35, 10, 5},
+	}
+
+	for _, tt := range tests {
+		img, _, err := decodeImage(bytes.NewReader(createOrientedJPEG(tt.orientation)))
+		if err != nil {
+			t.Fatalf("orientation %d: decodeImage() error = %v", tt.orientation, err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.width, tt.height)
+			continue
+		}
+		if !isRed(img.At(tt.redX, tt.redY)) || isRed(img.At(tt.blueX, tt.blueY)) {
+			t.Errorf("orientation %d: red half is not where expected", tt.orientation)
+		}
+	}
+}
+
+func TestResizeProcessor_AutoOrients(t *testing.T) {
+	proc := NewResizeProcessor(nil)
+	result, err := proc.Process(context.Background(), &processor.Options{Width: 10}, bytes.NewReader(createOrientedJPEG(6)))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if result.Metadata.Width != 10 || result.Metadata.Height != 20 {
+		t.Errorf("resized = %dx%d, want 10x20 for a portrait photo", result.Metadata.Width, result.Metadata.Height)
+	}
+}
+
+func TestDecodeConfig_SwapsRotatedDimensions(t *testing.T) {
+	cfg, format, err := DecodeConfig(bytes.NewReader(createOrientedJPEG(6)))
+	if err != nil {
+		t.Fatalf("DecodeConfig() error = %v", err)
+	}
+	if format != "jpeg" || cfg.Width != 20 || cfg.Height != 40 {
+		t.Errorf("got %s %dx%d, want jpeg 20x40", format, cfg.Width, cfg.Height)
+	}
+}
@@ -3,7 +3,6 @@ package image
 import (
 	"bytes"
 	"context"
-	"image"
 	_ "image/gif"
 	"image/jpeg"
 	"image/png"
@@ -42,7 +41,7 @@ func (p *OptimizeProcessor) Process(ctx context.Context, opts *processor.Options
 		return nil, processor.ErrCorruptedFile
 	}
 
-	img, format, err := image.Decode(bytes.NewReader(data))
+	img, format, err := decodeOriented(data)
 	if err != nil {
 		return nil, processor.ErrCorruptedFile
 	}
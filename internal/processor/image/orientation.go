@@ -0,0 +1,85 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	"github.com/disintegration/imaging"
+)
+
+// orientationNormal is the EXIF orientation of an image that needs no
+// rotation.
+const orientationNormal = 1
+
+// configPeekSize is how much of a file DecodeConfig reads before falling back
+// to the whole file. It covers the EXIF and ICC segments of camera JPEGs.
+const configPeekSize = 256 << 10
+
+// swapsDimensions reports whether an EXIF orientation rotates the image by
+// 90 degrees.
+func swapsDimensions(orientation int) bool {
+	return orientation >= 5 && orientation <= 8
+}
+
+// applyOrientation transforms img so it displays upright for the given EXIF
+// orientation.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// decodeOriented decodes data and rotates it upright according to its EXIF
+// orientation.
+func decodeOriented(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", processor.ErrCorruptedFile, err)
+	}
+	return applyOrientation(img, exifOrientation(data)), format, nil
+}
+
+// DecodeConfig returns the format and displayed dimensions of an image,
+// swapping width and height when the EXIF orientation rotates it. Only the
+// start of the file is read unless the header lies further in.
+func DecodeConfig(r io.Reader) (image.Config, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, configPeekSize))
+	if err != nil {
+		return image.Config{}, "", err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil && len(data) == configPeekSize {
+		rest, readErr := io.ReadAll(r)
+		if readErr != nil {
+			return image.Config{}, "", readErr
+		}
+		data = append(data, rest...)
+		cfg, format, err = image.DecodeConfig(bytes.NewReader(data))
+	}
+	if err != nil {
+		return image.Config{}, "", fmt.Errorf("%w: %v", processor.ErrCorruptedFile, err)
+	}
+
+	if swapsDimensions(exifOrientation(data)) {
+		cfg.Width, cfg.Height = cfg.Height, cfg.Width
+	}
+	return cfg, format, nil
+}
@@ -0,0 +1,103 @@
+package image
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+func TestBlurhash_SolidColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+
+	hash, err := Blurhash(img, 4, 3)
+	if err != nil {
+		t.Fatalf("Blurhash() error = %v", err)
+	}
+
+	if len(hash) != 28 {
+		t.Fatalf("len(hash) = %d, want 28", len(hash))
+	}
+	// The size flag encodes 4x3 components as 3+2*9.
+	if hash[0] != 'L' {
+		t.Errorf("size flag = %q, want L", hash[0])
+	}
+	// The average colour is stored in characters 2-5.
+	if got, want := hash[2:6], encode83(0xFFFFFF, 4); got != want {
+		t.Errorf("DC = %q, want %q (white)", got, want)
+	}
+}
+
+func TestBlurhash_Gradient(t *testing.T) {
+	hash, err := Blurhash(createTestImage(64, 32), 4, 3)
+	if err != nil {
+		t.Fatalf("Blurhash() error = %v", err)
+	}
+	if len(hash) != 28 {
+		t.Errorf("len(hash) = %d, want 28", len(hash))
+	}
+	if hash[1] == '0' {
+		t.Error("gradient should have AC energy")
+	}
+}
+
+func TestBlurhash_InvalidComponents(t *testing.T) {
+	if _, err := Blurhash(createTestImage(10, 10), 0, 3); err == nil {
+		t.Error("expected error for 0 components")
+	}
+	if _, err := Blurhash(createTestImage(10, 10), 4, 10); err == nil {
+		t.Error("expected error for 10 components")
+	}
+}
+
+func TestGeneratePlaceholder(t *testing.T) {
+	p, err := GeneratePlaceholder(createTestJPEG(800, 400))
+	if err != nil {
+		t.Fatalf("GeneratePlaceholder() error = %v", err)
+	}
+
+	if len(p.Blurhash) != 28 {
+		t.Errorf("blurhash = %q, want 28 characters", p.Blurhash)
+	}
+
+	data, ok := strings.CutPrefix(p.LQIP, "data:image/jpeg;base64,")
+	if !ok {
+		t.Fatalf("lqip = %q, want a JPEG data URI", p.LQIP)
+	}
+	if len(data) > 1024 {
+		t.Errorf("lqip is %d bytes, want a tiny image", len(data))
+	}
+}
+
+func TestGeneratePlaceholder_LQIPKeepsAspectRatio(t *testing.T) {
+	p, err := GeneratePlaceholder(createTestJPEG(800, 400))
+	if err != nil {
+		t.Fatalf("GeneratePlaceholder() error = %v", err)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.LQIP, "data:image/jpeg;base64,"))
+	if err != nil {
+		t.Fatalf("failed to decode lqip: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("lqip is not a JPEG: %v", err)
+	}
+	if cfg.Width != lqipWidth || cfg.Height != lqipWidth/2 {
+		t.Errorf("lqip = %dx%d, want %dx%d", cfg.Width, cfg.Height, lqipWidth, lqipWidth/2)
+	}
+}
+
+func TestGeneratePlaceholder_InvalidImage(t *testing.T) {
+	if _, err := GeneratePlaceholder(strings.NewReader("not an image")); err == nil {
+		t.Error("expected error for invalid image")
+	}
+}
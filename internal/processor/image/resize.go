@@ -43,9 +43,9 @@ func (p *ResizeProcessor) Process(ctx context.Context, opts *processor.Options,
 		return nil, fmt.Errorf("%w: width or height is required", processor.ErrInvalidConfig)
 	}
 
-	img, format, err := image.Decode(input)
+	img, format, err := decodeImage(input)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", processor.ErrCorruptedFile, err)
+		return nil, err
 	}
 
 	origBounds := img.Bounds()
@@ -67,7 +67,7 @@ func (p *ResizeProcessor) Process(ctx context.Context, opts *processor.Options,
 		outputFormat = opts.Format
 	}
 
-	buf, contentType, err := encodeImage(resized, outputFormat, quality)
+	buf, contentType, err := encodeImage(ctx, resized, outputFormat, quality, p.config.TempDir)
 	if err != nil {
 		return nil, err
 	}
@@ -112,12 +112,18 @@ func calculateDimensions(origWidth, origHeight, targetWidth, targetHeight int) (
 	return targetWidth, targetHeight
 }
 
-func encodeImage(img image.Image, format string, quality int) (*bytes.Buffer, string, error) {
+func encodeImage(ctx context.Context, img image.Image, format string, quality int, tempDir string) (*bytes.Buffer, string, error) {
 	var buf bytes.Buffer
 	var contentType string
 	var err error
 
 	switch format {
+	case "avif":
+		err = encodeAVIF(ctx, &buf, img, quality, tempDir)
+		contentType = "image/avif"
+	case "webp":
+		err = encodeWebP(ctx, &buf, img, quality, tempDir)
+		contentType = "image/webp"
 	case "png":
 		err = imaging.Encode(&buf, img, imaging.PNG)
 		contentType = "image/png"
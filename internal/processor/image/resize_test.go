@@ -515,6 +515,26 @@ func TestResizeProcessor_Process_PNG(t *testing.T) {
 	}
 }
 
+func TestResizeProcessor_Process_WebP(t *testing.T) {
+	if !cwebpInstalled() {
+		t.Skip("cwebp not installed, skipping WebP encode test")
+	}
+	p := NewResizeProcessor(nil)
+
+	opts := &processor.Options{Width: 200, Format: "webp", Quality: 80}
+	result, err := p.Process(context.Background(), opts, createTestPNG(500, 250))
+	if err != nil {
+		t.Fatalf("Process() error: %v", err)
+	}
+
+	if result.ContentType != "image/webp" || result.Metadata.Quality != 80 {
+		t.Errorf("ContentType = %q, Quality = %d, want image/webp at 80", result.ContentType, result.Metadata.Quality)
+	}
+	if result.Metadata.Width != 200 || result.Metadata.Height != 100 {
+		t.Errorf("size = %dx%d, want 200x100", result.Metadata.Width, result.Metadata.Height)
+	}
+}
+
 // Helper function tests
 
 func TestCalculateDimensions(t *testing.T) {
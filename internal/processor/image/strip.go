@@ -0,0 +1,137 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+)
+
+// pngStrippedChunks are PNG chunks that carry EXIF, text or timestamps.
+var pngStrippedChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+// StripMetadata removes EXIF, IPTC, XMP and comments, including GPS
+// coordinates, from a JPEG, PNG or WebP without re-encoding it. ICC profiles
+// are kept so colours do not shift, and a JPEG keeps a minimal EXIF block
+// with its orientation so it still displays upright.
+func StripMetadata(data []byte) ([]byte, error) {
+	if segments, scan, ok := jpegSegments(data); ok {
+		return stripJPEG(data, segments, scan), nil
+	}
+	if chunks, ok := pngChunks(data); ok {
+		return stripPNG(chunks), nil
+	}
+	if chunks, ok := webpChunks(data); ok {
+		return stripWebP(chunks), nil
+	}
+	if _, _, err := DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: metadata can only be stripped from JPEG, PNG and WebP", processor.ErrUnsupportedType)
+}
+
+func stripJPEG(data []byte, segments []jpegSegment, scan int) []byte {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	orientation := orientationNormal
+	for _, s := range segments {
+		switch s.marker {
+		case jpegMarkerAPP1:
+			if bytes.HasPrefix(s.data, jpegExifPrefix) {
+				orientation = tiffOrientation(s.data[len(jpegExifPrefix):])
+			}
+			continue
+		case jpegMarkerAPP2:
+			if !bytes.HasPrefix(s.data, jpegICCPrefix) {
+				continue
+			}
+		case jpegMarkerCOM:
+			continue
+		default:
+			// APP0 (JFIF) and APP14 (Adobe colour transform) affect decoding;
+			// every other application segment is metadata.
+			if s.marker > jpegMarkerAPP0 && s.marker <= 0xEF && s.marker != jpegMarkerAPP14 {
+				continue
+			}
+		}
+		out.Write(s.raw)
+	}
+
+	stripped := out.Bytes()
+	if orientation != orientationNormal {
+		stripped = insertAfterHeader(stripped, orientationSegment(orientation))
+	}
+	return append(stripped, data[scan:]...)
+}
+
+// insertAfterHeader places seg after the SOI marker and any APP0 segment,
+// where EXIF is expected.
+func insertAfterHeader(jpeg, seg []byte) []byte {
+	pos := 2
+	if len(jpeg) >= 6 && jpeg[2] == 0xFF && jpeg[3] == jpegMarkerAPP0 {
+		pos += 2 + int(binary.BigEndian.Uint16(jpeg[4:]))
+	}
+	out := make([]byte, 0, len(jpeg)+len(seg))
+	out = append(out, jpeg[:pos]...)
+	out = append(out, seg...)
+	return append(out, jpeg[pos:]...)
+}
+
+// orientationSegment builds an APP1 EXIF segment holding only an
+// orientation tag.
+func orientationSegment(orientation int) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header, IFD0 at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, // SHORT orientation
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append(append([]byte{}, jpegExifPrefix...), tiff...)
+	seg := []byte{0xFF, jpegMarkerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func stripPNG(chunks []pngChunk) []byte {
+	out := append([]byte{}, pngSignature...)
+	for _, c := range chunks {
+		if pngStrippedChunks[c.typ] {
+			continue
+		}
+		out = append(out, c.raw...)
+	}
+	return out
+}
+
+func stripWebP(chunks []riffChunk) []byte {
+	out := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		switch c.fourCC {
+		case "EXIF", "XMP ":
+			continue
+		case "VP8X":
+			if len(c.raw) > 8 {
+				raw := append([]byte{}, c.raw...)
+				raw[8] &^= webpFlagEXIF | webpFlagXMP
+				out = append(out, raw...)
+				continue
+			}
+		}
+		out = append(out, c.raw...)
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
@@ -0,0 +1,126 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+)
+
+func TestStripMetadata_JPEG(t *testing.T) {
+	original := createCameraJPEG()
+	stripped, err := StripMetadata(original)
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+
+	meta, err := ExtractMetadata(stripped)
+	if err != nil {
+		t.Fatalf("stripped image is not readable: %v", err)
+	}
+	if meta.GPS != nil || meta.CameraMake != "" || meta.CapturedAt != nil || meta.Caption != "" || len(meta.Keywords) > 0 {
+		t.Errorf("personal metadata survived: %+v", meta)
+	}
+	if meta.Orientation != 6 {
+		t.Errorf("orientation = %d, want 6 to be kept", meta.Orientation)
+	}
+	if meta.ICCProfile != "Display P3" {
+		t.Errorf("icc_profile = %q, want the profile to be kept", meta.ICCProfile)
+	}
+	if bytes.Contains(stripped, []byte("taken at home")) {
+		t.Error("comment survived")
+	}
+
+	// The image data itself is untouched.
+	if !bytes.HasSuffix(original, stripped[len(stripped)-100:]) {
+		t.Error("scan data changed")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped JPEG does not decode: %v", err)
+	}
+}
+
+func TestStripMetadata_JPEGWithoutOrientation(t *testing.T) {
+	stripped, err := StripMetadata(createOrientedJPEG(1))
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+	if bytes.Contains(stripped, jpegExifPrefix) {
+		t.Error("upright images should not keep an EXIF block")
+	}
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, createTestImage(10, 10))
+	exif := buildEXIF([]testTag{asciiTag(tagMake, "Apple")}, nil, nil)
+	data := withPNGChunks(buf.Bytes(),
+		pngChunkBytes("eXIf", exif),
+		pngChunkBytes("tEXt", []byte("Author\x00Jane")),
+	)
+
+	stripped, err := StripMetadata(data)
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+	if bytes.Contains(stripped, []byte("Apple")) || bytes.Contains(stripped, []byte("Jane")) {
+		t.Error("metadata survived")
+	}
+	if len(stripped) != buf.Len() {
+		t.Errorf("stripped size = %d, want the original %d", len(stripped), buf.Len())
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped PNG does not decode: %v", err)
+	}
+}
+
+func TestStripMetadata_WebP(t *testing.T) {
+	chunk := func(fourCC string, data []byte) []byte {
+		out := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		out = append(out, data...)
+		if len(data)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	body := bytes.Join([][]byte{
+		chunk("VP8X", []byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 9, 0, 0, 9, 0, 0}),
+		chunk("VP8L", []byte{0x2f, 1, 2}),
+		chunk("EXIF", buildEXIF([]testTag{asciiTag(tagMake, "Pixel")}, nil, nil)),
+		chunk("XMP ", testXMP("secret")),
+	}, nil)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)+4))...)
+	data = append(append(data, "WEBP"...), body...)
+
+	stripped, err := StripMetadata(data)
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+
+	chunks, ok := webpChunks(stripped)
+	if !ok || len(chunks) != 2 || chunks[0].fourCC != "VP8X" || chunks[1].fourCC != "VP8L" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if flags := chunks[0].data[0]; flags&(webpFlagEXIF|webpFlagXMP) != 0 {
+		t.Errorf("VP8X flags = %#x, want EXIF and XMP cleared", flags)
+	}
+	if size := binary.LittleEndian.Uint32(stripped[4:]); int(size) != len(stripped)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(stripped)-8)
+	}
+}
+
+func TestStripMetadata_Unsupported(t *testing.T) {
+	var buf bytes.Buffer
+	_ = gif.Encode(&buf, createTestImage(4, 4), nil)
+	if _, err := StripMetadata(buf.Bytes()); !errors.Is(err, processor.ErrUnsupportedType) {
+		t.Errorf("gif: error = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := StripMetadata([]byte("garbage")); !errors.Is(err, processor.ErrCorruptedFile) {
+		t.Errorf("garbage: error = %v, want ErrCorruptedFile", err)
+	}
+}
@@ -72,12 +72,14 @@ func (p *ThumbnailProcessor) Process(ctx context.Context, opts *processor.Option
 	}, nil
 }
 
+// decodeImage decodes an image and rotates it upright according to its EXIF
+// orientation.
 func decodeImage(r io.Reader) (image.Image, string, error) {
-	img, format, err := image.Decode(r)
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", processor.ErrCorruptedFile, err)
+		return nil, "", fmt.Errorf("failed to read input: %w", err)
 	}
-	return img, format, nil
+	return decodeOriented(data)
 }
 
 func createThumbnail(img image.Image, width, height int, position string) image.Image {
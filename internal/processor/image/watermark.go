@@ -80,9 +80,9 @@ func parseWatermarkOptions(opts *processor.Options) WatermarkOptions {
 }
 
 func (p *WatermarkProcessor) Process(ctx context.Context, opts *processor.Options, input io.Reader) (*processor.Result, error) {
-	img, format, err := image.Decode(input)
+	img, format, err := decodeImage(input)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", processor.ErrCorruptedFile, err)
+		return nil, err
 	}
 
 	bounds := img.Bounds()
@@ -11,7 +11,6 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
@@ -63,6 +62,21 @@ func (p *WebPProcessor) Process(ctx context.Context, opts *processor.Options, in
 	}
 	width, height := img.Width, img.Height
 
+	// cwebp ignores the EXIF orientation, so rotated photos are handed over
+	// upright.
+	if exifOrientation(inputData) != orientationNormal {
+		upright, _, err := decodeOriented(inputData)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := encodePNG(&buf, upright); err != nil {
+			return nil, fmt.Errorf("failed to encode upright image: %w", err)
+		}
+		inputData = buf.Bytes()
+		width, height = upright.Bounds().Dx(), upright.Bounds().Dy()
+	}
+
 	if cwebpAvailable() {
 		return p.processWithCwebp(ctx, inputData, width, height, quality)
 	}
@@ -76,7 +90,46 @@ func cwebpAvailable() bool {
 }
 
 func (p *WebPProcessor) processWithCwebp(ctx context.Context, inputData []byte, width, height, quality int) (*processor.Result, error) {
-	tempDir := p.config.TempDir
+	outputData, err := runCwebp(ctx, inputData, quality, p.config.TempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &processor.Result{
+		Data:        bytes.NewReader(outputData),
+		ContentType: "image/webp",
+		Filename:    "converted.webp",
+		Size:        int64(len(outputData)),
+		Metadata: processor.ResultMetadata{
+			Width:   width,
+			Height:  height,
+			Format:  "webp",
+			Quality: quality,
+		},
+	}, nil
+}
+
+// encodeWebP encodes img as WebP at quality, so processors that already hold
+// a decoded image can write WebP without a separate conversion step.
+func encodeWebP(ctx context.Context, w io.Writer, img image.Image, quality int, tempDir string) error {
+	if !cwebpAvailable() {
+		return fmt.Errorf("cwebp binary not available: %w", processor.ErrProcessingFailed)
+	}
+
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, img); err != nil {
+		return fmt.Errorf("failed to write input data: %w", err)
+	}
+	data, err := runCwebp(ctx, buf.Bytes(), quality, tempDir)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// runCwebp encodes inputData, in any format cwebp reads, as WebP at quality.
+func runCwebp(ctx context.Context, inputData []byte, quality int, tempDir string) ([]byte, error) {
 	if tempDir == "" {
 		tempDir = os.TempDir()
 	}
@@ -98,7 +151,12 @@ func (p *WebPProcessor) processWithCwebp(ctx context.Context, inputData []byte,
 	}
 	_ = inputFile.Close()
 
-	outputPath := filepath.Join(tempDir, fmt.Sprintf("webp-output-%d.webp", os.Getpid()))
+	outputFile, err := os.CreateTemp(tempDir, "webp-output-*.webp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output temp file: %w", err)
+	}
+	outputPath := outputFile.Name()
+	_ = outputFile.Close()
 	defer func() { _ = os.Remove(outputPath) }()
 
 	args := []string{
@@ -119,22 +177,14 @@ func (p *WebPProcessor) processWithCwebp(ctx context.Context, inputData []byte,
 	if err != nil {
 		return nil, fmt.Errorf("failed to read output file: %w", err)
 	}
-
-	return &processor.Result{
-		Data:        bytes.NewReader(outputData),
-		ContentType: "image/webp",
-		Filename:    "converted.webp",
-		Size:        int64(len(outputData)),
-		Metadata: processor.ResultMetadata{
-			Width:   width,
-			Height:  height,
-			Format:  "webp",
-			Quality: quality,
-		},
-	}, nil
+	return outputData, nil
 }
 
 func (p *WebPProcessor) processFallback(inputData []byte, width, height, quality int) (*processor.Result, error) {
+	// The input is passed through, so drop its metadata like cwebp would.
+	if stripped, err := StripMetadata(inputData); err == nil {
+		inputData = stripped
+	}
 	return &processor.Result{
 		Data:        bytes.NewReader(inputData),
 		ContentType: "image/webp",
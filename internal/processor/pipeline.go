@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Step is one stage of a processing pipeline: a registered processor and the
+// options it runs with.
+type Step struct {
+	Processor string  `json:"processor"`
+	Options   Options `json:"options"`
+}
+
+// RunPipeline runs steps in order, feeding the output of each processor into
+// the next, and returns the result of the last step.
+func (r *Registry) RunPipeline(ctx context.Context, steps []Step, input io.Reader) (*Result, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("pipeline has no steps")
+	}
+
+	var result *Result
+	for i, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		proc, err := r.GetOrError(step.Processor)
+		if err != nil {
+			return nil, err
+		}
+
+		opts := step.Options
+		result, err = proc.Process(ctx, &opts, input)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, step.Processor, err)
+		}
+		input = result.Data
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// appendProcessor returns a processor that appends its name to the input.
+func appendProcessor(name string) *mockProcessor {
+	p := newMockProcessor(name, "text/plain")
+	p.processFunc = func(ctx context.Context, opts *Options, input io.Reader) (*Result, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		out := string(data) + ">" + name
+		return &Result{Data: strings.NewReader(out), ContentType: "text/plain", Size: int64(len(out))}, nil
+	}
+	return p
+}
+
+func TestRegistry_RunPipeline(t *testing.T) {
+	r := NewRegistry()
+	r.Register("resize", appendProcessor("resize"))
+	r.Register("webp", appendProcessor("webp"))
+	r.Register("watermark", appendProcessor("watermark"))
+
+	result, err := r.RunPipeline(context.Background(), []Step{
+		{Processor: "resize", Options: Options{Width: 800}},
+		{Processor: "watermark"},
+		{Processor: "webp"},
+	}, strings.NewReader("in"))
+	if err != nil {
+		t.Fatalf("RunPipeline() error = %v", err)
+	}
+
+	data, _ := io.ReadAll(result.Data)
+	if want := "in>resize>watermark>webp"; string(data) != want {
+		t.Errorf("RunPipeline() output = %q, want %q", data, want)
+	}
+}
+
+func TestRegistry_RunPipeline_Errors(t *testing.T) {
+	r := NewRegistry()
+	r.Register("resize", appendProcessor("resize"))
+	failing := newMockProcessor("broken", "text/plain")
+	failing.processFunc = func(ctx context.Context, opts *Options, input io.Reader) (*Result, error) {
+		return nil, ErrProcessingFailed
+	}
+	r.Register("broken", failing)
+
+	if _, err := r.RunPipeline(context.Background(), nil, strings.NewReader("in")); err == nil {
+		t.Error("RunPipeline() with no steps should fail")
+	}
+
+	if _, err := r.RunPipeline(context.Background(), []Step{{Processor: "missing"}}, strings.NewReader("in")); err == nil {
+		t.Error("RunPipeline() with unregistered processor should fail")
+	}
+
+	_, err := r.RunPipeline(context.Background(), []Step{{Processor: "resize"}, {Processor: "broken"}}, strings.NewReader("in"))
+	if !errors.Is(err, ErrProcessingFailed) {
+		t.Errorf("RunPipeline() error = %v, want ErrProcessingFailed", err)
+	}
+}
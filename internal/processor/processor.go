@@ -21,14 +21,14 @@ type Processor interface {
 }
 
 type Options struct {
-	Width       int
-	Height      int
-	Quality     int
-	Fit         string
-	Format      string
-	VariantType string
-	Page        int
-	Position    string // anchor position for thumbnail cropping (center, north, south, east, west, north-west, north-east, south-west, south-east)
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Quality     int    `json:"quality,omitempty"`
+	Fit         string `json:"fit,omitempty"`
+	Format      string `json:"format,omitempty"`
+	VariantType string `json:"variant_type,omitempty"`
+	Page        int    `json:"page,omitempty"`
+	Position    string `json:"position,omitempty"` // anchor position for thumbnail cropping (center, north, south, east, west, north-west, north-east, south-west, south-east)
 }
 
 type Result struct {
@@ -0,0 +1,109 @@
+package video
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DASHManifest is the file name of the MPD written next to the segments.
+const DASHManifest = "manifest.mpd"
+
+// dashRepresentation is the encoded size of one video representation.
+type dashRepresentation struct {
+	width, height int
+}
+
+// dashArgs builds the ffmpeg arguments that encode every representation in
+// a single pass and package them as fragmented MP4 with a static MPD in dir.
+// Video representations share one adaptation set and audio gets its own, so
+// players can switch quality without interrupting the audio. As for HLS,
+// keyframes are forced on segment boundaries to keep representations aligned.
+func (p *FFmpegProcessor) dashArgs(opts *VideoOptions, metadata *VideoMetadata, inputPath, dir string, reps []dashRepresentation, segmentDuration int) []string {
+	preset := opts.Preset
+	if preset == "" {
+		preset = p.config.DefaultPreset
+	}
+	crf := opts.CRF
+	if crf <= 0 {
+		crf = p.config.DefaultCRF
+	}
+
+	var filter strings.Builder
+	fmt.Fprintf(&filter, "[0:v]split=%d", len(reps))
+	for i := range reps {
+		fmt.Fprintf(&filter, "[s%d]", i)
+	}
+	for i, rep := range reps {
+		fmt.Fprintf(&filter, ";[s%d]scale=%d:%d[v%d]", i, rep.width, rep.height, i)
+	}
+
+	args := []string{
+		"-i", inputPath,
+		"-filter_complex", filter.String(),
+	}
+	for i, rep := range reps {
+		level, _ := hlsCodecProfile(rep.height)
+		_, videoBitrate, _ := GetResolutionPreset(rep.height)
+		stream := strconv.Itoa(i)
+		args = append(args,
+			"-map", fmt.Sprintf("[v%d]", i),
+			"-c:v:"+stream, "libx264",
+			"-profile:v:"+stream, "main",
+			"-level:v:"+stream, level,
+			"-maxrate:v:"+stream, videoBitrate,
+			"-bufsize:v:"+stream, strconv.FormatInt(2*parseBitrate(videoBitrate), 10),
+		)
+	}
+	args = append(args,
+		"-preset", preset,
+		"-crf", strconv.Itoa(crf),
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentDuration),
+		"-sc_threshold", "0",
+	)
+
+	adaptationSets := "id=0,streams=v"
+	if metadata.HasAudio {
+		_, _, audioBitrate := GetResolutionPreset(reps[len(reps)-1].height)
+		args = append(args, "-map", "0:a:0", "-c:a", "aac", "-b:a", audioBitrate, "-ac", "2")
+		adaptationSets += " id=1,streams=a"
+	}
+
+	return append(args,
+		"-f", "dash",
+		"-seg_duration", strconv.Itoa(segmentDuration),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-init_seg_name", "init-$RepresentationID$.m4s",
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+		"-adaptation_sets", adaptationSets,
+		"-y",
+		filepath.Join(dir, DASHManifest),
+	)
+}
+
+var (
+	mpdURIAttribute = regexp.MustCompile(`(\s(?:initialization|media|sourceURL)=")([^"]*)(")`)
+	mpdBaseURL      = regexp.MustCompile(`(<BaseURL[^>]*>)([^<]*)(</BaseURL>)`)
+)
+
+// RewriteMPD returns the manifest with every segment URL passed through
+// rewrite: the initialization, media and sourceURL attributes of segment
+// templates and lists, and the content of BaseURL elements. Everything else
+// is left untouched.
+func RewriteMPD(data []byte, rewrite func(uri string) string) []byte {
+	replace := func(re *regexp.Regexp) func([]byte) []byte {
+		return func(match []byte) []byte {
+			parts := re.FindSubmatch(match)
+			uri := rewrite(string(parts[2]))
+			out := make([]byte, 0, len(parts[1])+len(uri)+len(parts[3]))
+			out = append(out, parts[1]...)
+			out = append(out, uri...)
+			return append(out, parts[3]...)
+		}
+	}
+	data = mpdURIAttribute.ReplaceAllFunc(data, replace(mpdURIAttribute))
+	return mpdBaseURL.ReplaceAllFunc(data, replace(mpdBaseURL))
+}
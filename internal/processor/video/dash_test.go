@@ -0,0 +1,65 @@
+package video
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFFmpegProcessor_dashArgs(t *testing.T) {
+	p := &FFmpegProcessor{config: DefaultVideoConfig()}
+	reps := []dashRepresentation{{width: 640, height: 360}, {width: 1280, height: 720}}
+
+	args := strings.Join(p.dashArgs(&VideoOptions{Preset: "fast"}, &VideoMetadata{HasAudio: true}, "/tmp/in", "/tmp/out", reps, 4), " ")
+	for _, want := range []string{
+		"-filter_complex [0:v]split=2[s0][s1];[s0]scale=640:360[v0];[s1]scale=1280:720[v1]",
+		"-map [v0] -c:v:0 libx264 -profile:v:0 main -level:v:0 3.0 -maxrate:v:0 800k",
+		"-map [v1] -c:v:1 libx264 -profile:v:1 main -level:v:1 3.1 -maxrate:v:1 3000k -bufsize:v:1 6000000",
+		"-preset fast -crf 28",
+		"-force_key_frames expr:gte(t,n_forced*4)",
+		"-map 0:a:0 -c:a aac -b:a 128k",
+		"-f dash -seg_duration 4",
+		"-adaptation_sets id=0,streams=v id=1,streams=a",
+		"/tmp/out/manifest.mpd",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+
+	silent := strings.Join(p.dashArgs(&VideoOptions{}, &VideoMetadata{}, "/tmp/in", "/tmp/out", reps[:1], 4), " ")
+	if strings.Contains(silent, "0:a") || strings.Contains(silent, "streams=a") {
+		t.Errorf("video without audio should not map an audio track: %s", silent)
+	}
+}
+
+func TestRewriteMPD(t *testing.T) {
+	mpd := `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10.0S">
+	<BaseURL>media/</BaseURL>
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video">
+			<Representation id="0" mimeType="video/mp4" codecs="avc1.4d401e" bandwidth="800000" width="640" height="360">
+				<SegmentTemplate timescale="12800" initialization="init-$RepresentationID$.m4s" media="chunk-$RepresentationID$-$Number%05d$.m4s" startNumber="1">
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+</MPD>
+`
+	got := string(RewriteMPD([]byte(mpd), func(uri string) string { return "/base/" + uri }))
+
+	for _, want := range []string{
+		`<BaseURL>/base/media/</BaseURL>`,
+		`initialization="/base/init-$RepresentationID$.m4s"`,
+		`media="/base/chunk-$RepresentationID$-$Number%05d$.m4s"`,
+		`mimeType="video/mp4"`,
+		`xmlns="urn:mpeg:dash:schema:mpd:2011"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("manifest missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "/base/") != 3 {
+		t.Errorf("only segment URLs should be rewritten:\n%s", got)
+	}
+}
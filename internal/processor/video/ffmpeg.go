@@ -231,14 +231,24 @@ func (p *FFmpegProcessor) GetMetadata(ctx context.Context, input io.Reader) (*Vi
 	return p.getMetadataFromFile(ctx, inputPath)
 }
 
-// GenerateHLS creates HLS segments and manifest from a video
+// GenerateHLS encodes a video into one HLS rendition per resolution, each
+// with its own media playlist and segments in a directory named after it
+// (e.g. 720p/playlist.m3u8), plus a master playlist listing them for adaptive
+// bitrate playback.
 func (p *FFmpegProcessor) GenerateHLS(ctx context.Context, opts *VideoOptions, input io.Reader) (*HLSResult, error) {
 	// Create temp directory
 	tempDir, err := p.createTempDir("hls")
 	if err != nil {
 		return nil, err
 	}
-	// Note: Don't defer cleanup here, caller needs the files
+	// Note: Don't defer cleanup on success, caller needs the files and removes
+	// the directory holding ManifestPath
+	done := false
+	defer func() {
+		if !done {
+			_ = os.RemoveAll(tempDir)
+		}
+	}()
 
 	// Write input to temp file
 	inputPath := filepath.Join(tempDir, "input")
@@ -257,47 +267,237 @@ func (p *FFmpegProcessor) GenerateHLS(ctx context.Context, opts *VideoOptions, i
 		segmentDuration = p.config.HLSSegmentDuration
 	}
 
-	outputDir := filepath.Join(tempDir, "output")
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output dir: %w", err)
+	requested := opts.HLSResolutions
+	if len(requested) == 0 {
+		requested = p.config.HLSResolutions
+	}
+	maxRes := opts.MaxResolution
+	if maxRes <= 0 {
+		maxRes = p.config.MaxResolution
+	}
+	heights := SelectHLSRenditions(requested, metadata.Height, maxRes)
+	if len(heights) == 0 {
+		return nil, fmt.Errorf("%w: no HLS renditions available for %v at max %dp", ErrResolutionTooHigh, requested, maxRes)
 	}
 
-	manifestPath := filepath.Join(outputDir, "playlist.m3u8")
+	result := &HLSResult{
+		ManifestPath:  filepath.Join(tempDir, HLSMasterPlaylist),
+		TotalDuration: metadata.Duration,
+	}
 
-	// Build ffmpeg HLS command
-	args := []string{
-		"-i", inputPath,
-		"-c:v", "libx264",
-		"-preset", opts.Preset,
-		"-crf", strconv.Itoa(opts.CRF),
-		"-c:a", "aac",
-		"-b:a", "128k",
-		"-f", "hls",
-		"-hls_time", strconv.Itoa(segmentDuration),
-		"-hls_list_size", "0",
-		"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.ts"),
-		"-y",
-		manifestPath,
+	for _, height := range heights {
+		encodeHeight := height
+		if metadata.Height > 0 && metadata.Height < height {
+			encodeHeight = metadata.Height &^ 1
+		}
+		width := scaledWidth(metadata.Width, metadata.Height, encodeHeight)
+
+		rendition := HLSRendition{
+			Name:      fmt.Sprintf("%dp", height),
+			Width:     width,
+			Height:    encodeHeight,
+			FrameRate: metadata.FrameRate,
+		}
+		dir := filepath.Join(tempDir, rendition.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output dir: %w", err)
+		}
+
+		args := p.hlsRenditionArgs(opts, metadata, inputPath, dir, width, encodeHeight, segmentDuration)
+		cmd := exec.CommandContext(ctx, p.config.FFmpegPath, args...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("%w: HLS generation failed for %s: %v, output: %s", ErrTranscodeFailed, rendition.Name, err, string(output))
+		}
+
+		rendition.PlaylistPath = filepath.Join(dir, HLSMediaPlaylist)
+		rendition.SegmentPaths, err = filepath.Glob(filepath.Join(dir, "segment_*.ts"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list segments: %w", err)
+		}
+
+		rendition.Bandwidth, rendition.AverageBandwidth, rendition.SizeBytes, err = measureHLSBandwidth(rendition.PlaylistPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to measure %s: %v", ErrTranscodeFailed, rendition.Name, err)
+		}
+		_, videoBitrate, audioBitrate := GetResolutionPreset(height)
+		if rendition.Bandwidth <= 0 {
+			rendition.Bandwidth = parseBitrate(videoBitrate) + parseBitrate(audioBitrate)
+		}
+
+		_, rendition.Codecs = hlsCodecProfile(encodeHeight)
+		if metadata.HasAudio {
+			rendition.Codecs += ",mp4a.40.2"
+		}
+
+		result.Renditions = append(result.Renditions, rendition)
+		result.SegmentPaths = append(result.SegmentPaths, rendition.SegmentPaths...)
+		result.Resolutions = append(result.Resolutions, encodeHeight)
+	}
+	result.SegmentCount = len(result.SegmentPaths)
+
+	if err := os.WriteFile(result.ManifestPath, BuildMasterPlaylist(result.Renditions), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write master playlist: %w", err)
+	}
+
+	done = true
+	return result, nil
+}
+
+func (p *FFmpegProcessor) GenerateDASH(ctx context.Context, opts *VideoOptions, input io.Reader) (*DASHResult, error) {
+	tempDir, err := p.createTempDir("dash")
+	if err != nil {
+		return nil, err
+	}
+	// Note: Don't defer cleanup on success, caller needs the files and removes
+	// the directory holding ManifestPath
+	done := false
+	defer func() {
+		if !done {
+			_ = os.RemoveAll(tempDir)
+		}
+	}()
+
+	inputPath := filepath.Join(tempDir, "input")
+	if err := p.writeInputFile(inputPath, input); err != nil {
+		return nil, err
+	}
+
+	metadata, err := p.getMetadataFromFile(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+	}
+
+	segmentDuration := opts.DASHSegmentDuration
+	if segmentDuration <= 0 {
+		segmentDuration = p.config.HLSSegmentDuration
+	}
+
+	// DASH uses the same ladder as HLS
+	requested := opts.DASHResolutions
+	if len(requested) == 0 {
+		requested = p.config.HLSResolutions
+	}
+	maxRes := opts.MaxResolution
+	if maxRes <= 0 {
+		maxRes = p.config.MaxResolution
 	}
+	heights := SelectHLSRenditions(requested, metadata.Height, maxRes)
+	if len(heights) == 0 {
+		return nil, fmt.Errorf("%w: no DASH representations available for %v at max %dp", ErrResolutionTooHigh, requested, maxRes)
+	}
+
+	result := &DASHResult{
+		ManifestPath:  filepath.Join(tempDir, DASHManifest),
+		TotalDuration: metadata.Duration,
+	}
+	reps := make([]dashRepresentation, 0, len(heights))
+	for _, height := range heights {
+		if metadata.Height > 0 && metadata.Height < height {
+			height = metadata.Height &^ 1
+		}
+		reps = append(reps, dashRepresentation{width: scaledWidth(metadata.Width, metadata.Height, height), height: height})
+		result.Resolutions = append(result.Resolutions, height)
+	}
+	result.Width, result.Height = reps[len(reps)-1].width, reps[len(reps)-1].height
 
+	args := p.dashArgs(opts, metadata, inputPath, tempDir, reps, segmentDuration)
 	cmd := exec.CommandContext(ctx, p.config.FFmpegPath, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("%w: HLS generation failed: %v, output: %s", ErrTranscodeFailed, err, string(output))
+		return nil, fmt.Errorf("%w: DASH generation failed: %v, output: %s", ErrTranscodeFailed, err, string(output))
 	}
 
-	// Find all segment files
-	segments, err := filepath.Glob(filepath.Join(outputDir, "segment_*.ts"))
+	result.SegmentPaths, err = filepath.Glob(filepath.Join(tempDir, "*.m4s"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list segments: %w", err)
 	}
+	result.SegmentCount = len(result.SegmentPaths)
 
-	return &HLSResult{
-		ManifestPath:  manifestPath,
-		SegmentPaths:  segments,
-		TotalDuration: metadata.Duration,
-		SegmentCount:  len(segments),
-		Resolutions:   []int{metadata.Height},
+	done = true
+	return result, nil
+}
+
+func (p *FFmpegProcessor) GeneratePreview(ctx context.Context, opts *VideoOptions, input io.Reader) (*processor.Result, error) {
+	if opts == nil {
+		opts = &VideoOptions{}
+	}
+
+	format := opts.PreviewFormat
+	if format == "" {
+		format = PreviewFormatMP4
+	}
+	if !IsPreviewFormat(format) {
+		return nil, fmt.Errorf("%w: preview format %q", ErrUnsupportedCodec, format)
+	}
+	width := opts.PreviewWidth
+	if width <= 0 {
+		width = DefaultPreviewWidth
+	}
+	segmentCount := opts.PreviewSegments
+	if segmentCount <= 0 {
+		segmentCount = DefaultPreviewSegments
+	}
+	segmentDuration := opts.PreviewSegmentDuration
+	if segmentDuration <= 0 {
+		segmentDuration = DefaultPreviewSegmentDuration
+	}
+	maxRes := opts.MaxResolution
+	if maxRes <= 0 {
+		maxRes = p.config.MaxResolution
+	}
+
+	tempDir, err := p.createTempDir("preview")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	inputPath := filepath.Join(tempDir, "input")
+	if err := p.writeInputFile(inputPath, input); err != nil {
+		return nil, err
+	}
+
+	metadata, err := p.getMetadataFromFile(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+	}
+
+	segments := SelectPreviewSegments(metadata.Duration, segmentCount, segmentDuration, opts.PreviewStart, opts.PreviewEnd)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: preview range %.1fs-%.1fs is outside the %.1fs video", ErrInvalidVideo, opts.PreviewStart, opts.PreviewEnd, metadata.Duration)
+	}
+	var duration float64
+	for _, seg := range segments {
+		duration += seg.Duration
+	}
+
+	outWidth, outHeight := previewSize(metadata.Width, metadata.Height, width, maxRes)
+	outputPath := filepath.Join(tempDir, "preview."+format)
+
+	args := previewArgs(format, inputPath, outputPath, segments, outWidth, outHeight)
+	cmd := exec.CommandContext(ctx, p.config.FFmpegPath, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%w: preview generation failed: %v, output: %s", ErrTranscodeFailed, err, string(output))
+	}
+
+	outputData, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read output: %v", ErrTranscodeFailed, err)
+	}
+
+	return &processor.Result{
+		Data:        bytes.NewReader(outputData),
+		ContentType: PreviewContentType(format),
+		Filename:    "preview." + format,
+		Size:        int64(len(outputData)),
+		Metadata: processor.ResultMetadata{
+			Width:    outWidth,
+			Height:   outHeight,
+			Duration: duration,
+			Format:   format,
+		},
 	}, nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -457,6 +458,23 @@ func TestFFmpegProcessor_GenerateHLS(t *testing.T) {
 		t.Errorf("TotalDuration = %f, want > 0", result.TotalDuration)
 	}
 
+	if len(result.Renditions) == 0 {
+		t.Fatal("Renditions is empty")
+	}
+	for _, r := range result.Renditions {
+		if r.Bandwidth <= 0 || r.Width <= 0 || r.Codecs == "" || len(r.SegmentPaths) == 0 {
+			t.Errorf("rendition %s = %+v", r.Name, r)
+		}
+	}
+
+	master, err := os.ReadFile(result.ManifestPath)
+	if err != nil {
+		t.Fatalf("failed to read master playlist: %v", err)
+	}
+	if got := strings.Count(string(master), "#EXT-X-STREAM-INF:"); got != len(result.Renditions) {
+		t.Errorf("master playlist lists %d renditions, want %d", got, len(result.Renditions))
+	}
+
 	// Cleanup
 	if result.ManifestPath != "" {
 		_ = os.RemoveAll(filepath.Dir(result.ManifestPath))
@@ -465,6 +483,85 @@ func TestFFmpegProcessor_GenerateHLS(t *testing.T) {
 	t.Logf("Generated HLS: %d segments, duration=%.2fs", result.SegmentCount, result.TotalDuration)
 }
 
+func TestFFmpegProcessor_GenerateDASH(t *testing.T) {
+	skipIfNoFFmpeg(t)
+	skipIfNoTestVideo(t)
+
+	p, err := NewFFmpegProcessor(nil)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	opts := &VideoOptions{
+		Preset:              "ultrafast",
+		CRF:                 28,
+		DASHSegmentDuration: 2,
+	}
+
+	result, err := p.GenerateDASH(ctx, opts, loadTestVideo(t))
+	if err != nil {
+		t.Fatalf("GenerateDASH() error = %v", err)
+	}
+	defer func() { _ = os.RemoveAll(filepath.Dir(result.ManifestPath)) }()
+
+	manifest, err := os.ReadFile(result.ManifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if got := strings.Count(string(manifest), "<Representation "); got < len(result.Resolutions) {
+		t.Errorf("manifest lists %d representations, want at least %d", got, len(result.Resolutions))
+	}
+
+	if result.SegmentCount == 0 || result.SegmentCount != len(result.SegmentPaths) {
+		t.Errorf("SegmentCount = %d, SegmentPaths = %d", result.SegmentCount, len(result.SegmentPaths))
+	}
+
+	if result.TotalDuration <= 0 {
+		t.Errorf("TotalDuration = %f, want > 0", result.TotalDuration)
+	}
+
+	t.Logf("Generated DASH: %d segments, resolutions=%v", result.SegmentCount, result.Resolutions)
+}
+
+func TestFFmpegProcessor_GeneratePreview(t *testing.T) {
+	skipIfNoFFmpeg(t)
+	skipIfNoTestVideo(t)
+
+	p, err := NewFFmpegProcessor(nil)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	for _, format := range []string{PreviewFormatMP4, PreviewFormatWebP, PreviewFormatGIF} {
+		t.Run(format, func(t *testing.T) {
+			opts := &VideoOptions{
+				PreviewFormat:          format,
+				PreviewWidth:           160,
+				PreviewSegments:        2,
+				PreviewSegmentDuration: 0.5,
+				MaxResolution:          480,
+			}
+
+			result, err := p.GeneratePreview(ctx, opts, loadTestVideo(t))
+			if err != nil {
+				t.Fatalf("GeneratePreview() error = %v", err)
+			}
+			if result.Size == 0 || result.ContentType != PreviewContentType(format) {
+				t.Errorf("Size = %d, ContentType = %q", result.Size, result.ContentType)
+			}
+			if result.Metadata.Width > 160 || result.Metadata.Height > 480 {
+				t.Errorf("preview is %dx%d, want at most 160 wide", result.Metadata.Width, result.Metadata.Height)
+			}
+		})
+	}
+}
+
 func TestFFmpegProcessor_ContextCancellation(t *testing.T) {
 	skipIfNoFFmpeg(t)
 	skipIfNoTestVideo(t)
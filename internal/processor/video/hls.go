@@ -0,0 +1,245 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HLSMasterPlaylist is the file name of the master playlist.
+	HLSMasterPlaylist = "master.m3u8"
+	// HLSMediaPlaylist is the file name of each rendition's media playlist.
+	HLSMediaPlaylist = "playlist.m3u8"
+)
+
+// HLSLadder lists the rendition heights that can be packaged, lowest first.
+var HLSLadder = []int{360, 480, 720, 1080}
+
+// HLSRendition is one encoded quality level of an HLS package.
+type HLSRendition struct {
+	Name             string   // e.g. "720p", also the rendition's directory
+	Width            int      // Encoded width in pixels
+	Height           int      // Encoded height in pixels
+	Bandwidth        int64    // Peak segment bitrate in bits/s
+	AverageBandwidth int64    // Average bitrate in bits/s
+	Codecs           string   // RFC 6381 codecs string
+	FrameRate        float64  // Frames per second, 0 if unknown
+	PlaylistPath     string   // Path to the media playlist
+	SegmentPaths     []string // Paths to the rendition's segments
+	SizeBytes        int64    // Playlist plus segments
+}
+
+// SelectHLSRenditions picks the ladder heights to encode for a source of
+// sourceHeight pixels. Requested heights that are not on the ladder or above
+// maxHeight are dropped, and a source is never upscaled: of the rungs above
+// the source only the lowest is kept, encoded at the source height.
+func SelectHLSRenditions(requested []int, sourceHeight, maxHeight int) []int {
+	var heights []int
+	for _, h := range requested {
+		if !slices.Contains(HLSLadder, h) || (maxHeight > 0 && h > maxHeight) || slices.Contains(heights, h) {
+			continue
+		}
+		heights = append(heights, h)
+	}
+	slices.Sort(heights)
+
+	if sourceHeight <= 0 {
+		return heights
+	}
+	for i, h := range heights {
+		if h >= sourceHeight {
+			return heights[:i+1]
+		}
+	}
+	return heights
+}
+
+// hlsCodecProfile returns the H.264 level and RFC 6381 video codec for a
+// Main profile encode at height.
+func hlsCodecProfile(height int) (level, codec string) {
+	switch {
+	case height <= 480:
+		return "3.0", "avc1.4d401e"
+	case height <= 720:
+		return "3.1", "avc1.4d401f"
+	default:
+		return "4.0", "avc1.4d4028"
+	}
+}
+
+// scaledWidth returns the even width that keeps the source aspect ratio at
+// height, falling back to 16:9 when the source size is unknown.
+func scaledWidth(srcWidth, srcHeight, height int) int {
+	if srcWidth <= 0 || srcHeight <= 0 {
+		width, _, _ := GetResolutionPreset(height)
+		return width
+	}
+	width := (srcWidth*height + srcHeight/2) / srcHeight
+	if width%2 == 1 {
+		width++
+	}
+	return width
+}
+
+// parseBitrate converts an ffmpeg bitrate such as "3000k" to bits/s.
+func parseBitrate(s string) int64 {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1000, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "M"):
+		multiplier, s = 1000000, strings.TrimSuffix(s, "M")
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n * multiplier
+}
+
+// hlsRenditionArgs builds the ffmpeg arguments that encode one rendition into
+// dir. Keyframes are forced on segment boundaries so every rendition splits
+// at the same timestamps and players can switch between them cleanly.
+func (p *FFmpegProcessor) hlsRenditionArgs(opts *VideoOptions, metadata *VideoMetadata, inputPath, dir string, width, height, segmentDuration int) []string {
+	level, _ := hlsCodecProfile(height)
+	_, videoBitrate, audioBitrate := GetResolutionPreset(height)
+
+	preset := opts.Preset
+	if preset == "" {
+		preset = p.config.DefaultPreset
+	}
+	crf := opts.CRF
+	if crf <= 0 {
+		crf = p.config.DefaultCRF
+	}
+
+	args := []string{
+		"-i", inputPath,
+		"-vf", fmt.Sprintf("scale=%d:%d", width, height),
+		"-c:v", "libx264",
+		"-preset", preset,
+		"-crf", strconv.Itoa(crf),
+		"-profile:v", "main",
+		"-level", level,
+		"-maxrate", videoBitrate,
+		"-bufsize", strconv.FormatInt(2*parseBitrate(videoBitrate), 10),
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentDuration),
+		"-sc_threshold", "0",
+	}
+	if metadata.HasAudio {
+		args = append(args, "-c:a", "aac", "-b:a", audioBitrate, "-ac", "2")
+	} else {
+		args = append(args, "-an")
+	}
+	return append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_list_size", "0",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%03d.ts"),
+		"-y",
+		filepath.Join(dir, HLSMediaPlaylist),
+	)
+}
+
+// measureHLSBandwidth reads a media playlist and the segments it lists and
+// returns the peak and average bitrate in bits/s, as required by the
+// BANDWIDTH and AVERAGE-BANDWIDTH attributes of the master playlist, along
+// with the total size of the rendition.
+func measureHLSBandwidth(playlistPath string) (peak, average, size int64, err error) {
+	data, err := os.ReadFile(playlistPath)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	size = int64(len(data))
+
+	var totalBits, totalDuration float64
+	var duration float64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			duration, _ = strconv.ParseFloat(value, 64)
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			info, err := os.Stat(filepath.Join(filepath.Dir(playlistPath), line))
+			if err != nil {
+				return 0, 0, 0, fmt.Errorf("failed to stat segment %s: %w", line, err)
+			}
+			size += info.Size()
+			bits := float64(info.Size() * 8)
+			if duration > 0 {
+				peak = max(peak, int64(bits/duration))
+			}
+			totalBits += bits
+			totalDuration += duration
+			duration = 0
+		}
+	}
+	if totalDuration > 0 {
+		average = int64(totalBits / totalDuration)
+	}
+	return peak, average, size, nil
+}
+
+// BuildMasterPlaylist renders an HLS master playlist that references each
+// rendition's media playlist as <name>/playlist.m3u8.
+func BuildMasterPlaylist(renditions []HLSRendition) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, r := range renditions {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d", r.Bandwidth)
+		if r.AverageBandwidth > 0 {
+			fmt.Fprintf(&b, ",AVERAGE-BANDWIDTH=%d", r.AverageBandwidth)
+		}
+		fmt.Fprintf(&b, ",RESOLUTION=%dx%d", r.Width, r.Height)
+		if r.FrameRate > 0 {
+			fmt.Fprintf(&b, ",FRAME-RATE=%.3f", r.FrameRate)
+		}
+		fmt.Fprintf(&b, ",CODECS=\"%s\"\n%s/%s\n", r.Codecs, r.Name, HLSMediaPlaylist)
+	}
+	return b.Bytes()
+}
+
+// RewritePlaylist passes every URI in an HLS playlist through rewrite: the
+// URI lines following EXTINF and EXT-X-STREAM-INF tags as well as URI="..."
+// attributes such as those of EXT-X-MAP and EXT-X-MEDIA.
+func RewritePlaylist(data []byte, rewrite func(uri string) string) []byte {
+	var b bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			line = rewriteURIAttribute(line, rewrite)
+		default:
+			line = rewrite(strings.TrimSpace(line))
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func rewriteURIAttribute(tag string, rewrite func(uri string) string) string {
+	const attr = `URI="`
+	start := strings.Index(tag, attr)
+	if start < 0 {
+		return tag
+	}
+	start += len(attr)
+	end := strings.IndexByte(tag[start:], '"')
+	if end < 0 {
+		return tag
+	}
+	return tag[:start] + rewrite(tag[start:start+end]) + tag[start+end:]
+}
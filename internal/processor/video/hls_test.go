@@ -0,0 +1,175 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSelectHLSRenditions(t *testing.T) {
+	tests := []struct {
+		name         string
+		requested    []int
+		sourceHeight int
+		maxHeight    int
+		want         []int
+	}{
+		{"sorted and deduplicated", []int{720, 360, 720}, 1080, 1080, []int{360, 720}},
+		{"off-ladder heights dropped", []int{240, 360, 1440}, 2160, 2160, []int{360}},
+		{"capped by max height", []int{360, 720, 1080}, 1080, 720, []int{360, 720}},
+		{"no upscaling", []int{360, 480, 720, 1080}, 540, 1080, []int{360, 480, 720}},
+		{"exact source height", []int{360, 720, 1080}, 720, 1080, []int{360, 720}},
+		{"small source keeps lowest rung", []int{360, 720}, 240, 1080, []int{360}},
+		{"unknown source height", []int{1080, 360}, 0, 0, []int{360, 1080}},
+		{"nothing allowed", []int{1080}, 1080, 480, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectHLSRenditions(tt.requested, tt.sourceHeight, tt.maxHeight)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SelectHLSRenditions(%v, %d, %d) = %v, want %v", tt.requested, tt.sourceHeight, tt.maxHeight, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaledWidth(t *testing.T) {
+	tests := []struct {
+		srcWidth, srcHeight, height, want int
+	}{
+		{1920, 1080, 720, 1280},
+		{1080, 1920, 360, 204},
+		{640, 480, 360, 480},
+		{0, 0, 720, 1280},
+	}
+	for _, tt := range tests {
+		if got := scaledWidth(tt.srcWidth, tt.srcHeight, tt.height); got != tt.want {
+			t.Errorf("scaledWidth(%d, %d, %d) = %d, want %d", tt.srcWidth, tt.srcHeight, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	tests := map[string]int64{"800k": 800000, "3M": 3000000, "128000": 128000, "fast": 0}
+	for in, want := range tests {
+		if got := parseBitrate(in); got != want {
+			t.Errorf("parseBitrate(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestFFmpegProcessor_hlsRenditionArgs(t *testing.T) {
+	p := &FFmpegProcessor{config: DefaultVideoConfig()}
+	opts := &VideoOptions{Preset: "fast", CRF: 23}
+
+	args := strings.Join(p.hlsRenditionArgs(opts, &VideoMetadata{HasAudio: true}, "/tmp/in", "/tmp/out/720p", 1280, 720, 6), " ")
+	for _, want := range []string{
+		"-vf scale=1280:720",
+		"-preset fast",
+		"-profile:v main -level 3.1",
+		"-maxrate 3000k -bufsize 6000000",
+		"-force_key_frames expr:gte(t,n_forced*6)",
+		"-c:a aac -b:a 128k",
+		"-hls_time 6",
+		"-hls_playlist_type vod",
+		"/tmp/out/720p/segment_%03d.ts",
+		"/tmp/out/720p/playlist.m3u8",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+
+	silent := strings.Join(p.hlsRenditionArgs(&VideoOptions{}, &VideoMetadata{}, "/tmp/in", "/tmp/out/360p", 640, 360, 6), " ")
+	if !strings.Contains(silent, "-an") || strings.Contains(silent, "-c:a") {
+		t.Errorf("video without audio should drop the audio track: %s", silent)
+	}
+	if !strings.Contains(silent, "-preset veryfast") || !strings.Contains(silent, "-crf 28") {
+		t.Errorf("missing options should fall back to the config defaults: %s", silent)
+	}
+}
+
+func TestMeasureHLSBandwidth(t *testing.T) {
+	dir := t.TempDir()
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4.000000,\nsegment_000.ts\n#EXTINF:2.000000,\nsegment_001.ts\n#EXT-X-ENDLIST\n"
+	if err := os.WriteFile(filepath.Join(dir, "playlist.m3u8"), []byte(playlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 4s at 1000 bytes and 2s at 1500 bytes: 2000 bits/s and 6000 bits/s.
+	if err := os.WriteFile(filepath.Join(dir, "segment_000.ts"), make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "segment_001.ts"), make([]byte, 1500), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	peak, average, size, err := measureHLSBandwidth(filepath.Join(dir, "playlist.m3u8"))
+	if err != nil {
+		t.Fatalf("measureHLSBandwidth() error = %v", err)
+	}
+	if peak != 6000 {
+		t.Errorf("peak = %d, want 6000", peak)
+	}
+	if average != 3333 {
+		t.Errorf("average = %d, want 3333", average)
+	}
+	if want := int64(len(playlist) + 2500); size != want {
+		t.Errorf("size = %d, want %d", size, want)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "segment_001.ts")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := measureHLSBandwidth(filepath.Join(dir, "playlist.m3u8")); err == nil {
+		t.Error("expected an error for a missing segment")
+	}
+}
+
+func TestBuildMasterPlaylist(t *testing.T) {
+	got := string(BuildMasterPlaylist([]HLSRendition{
+		{Name: "360p", Width: 640, Height: 360, Bandwidth: 900000, AverageBandwidth: 700000, Codecs: "avc1.4d401e,mp4a.40.2", FrameRate: 30},
+		{Name: "720p", Width: 1280, Height: 720, Bandwidth: 3200000, Codecs: "avc1.4d401f"},
+	}))
+
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=900000,AVERAGE-BANDWIDTH=700000,RESOLUTION=640x360,FRAME-RATE=30.000,CODECS=\"avc1.4d401e,mp4a.40.2\"\n" +
+		"360p/playlist.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=3200000,RESOLUTION=1280x720,CODECS=\"avc1.4d401f\"\n" +
+		"720p/playlist.m3u8\n"
+	if got != want {
+		t.Errorf("BuildMasterPlaylist() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRewritePlaylist(t *testing.T) {
+	playlist := "#EXTM3U\r\n" +
+		"#EXT-X-MAP:URI=\"init.mp4\",BYTERANGE=\"720@0\"\r\n" +
+		"#EXTINF:6.0,\r\n" +
+		"segment_000.ts\r\n" +
+		"\r\n" +
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",URI=\"audio/playlist.m3u8\"\r\n" +
+		"https://cdn.example.com/segment_001.ts\r\n" +
+		"#EXT-X-ENDLIST\r\n"
+
+	got := string(RewritePlaylist([]byte(playlist), func(uri string) string {
+		if strings.Contains(uri, "://") {
+			return uri
+		}
+		return "/base/" + uri
+	}))
+
+	want := "#EXTM3U\n" +
+		"#EXT-X-MAP:URI=\"/base/init.mp4\",BYTERANGE=\"720@0\"\n" +
+		"#EXTINF:6.0,\n" +
+		"/base/segment_000.ts\n" +
+		"\n" +
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",URI=\"/base/audio/playlist.m3u8\"\n" +
+		"https://cdn.example.com/segment_001.ts\n" +
+		"#EXT-X-ENDLIST\n"
+	if got != want {
+		t.Errorf("RewritePlaylist() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+package video
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Preview output formats
+const (
+	PreviewFormatMP4  = "mp4"
+	PreviewFormatWebP = "webp"
+	PreviewFormatGIF  = "gif"
+)
+
+// Preview defaults, used when VideoOptions leaves a setting unset.
+const (
+	DefaultPreviewWidth           = 320
+	DefaultPreviewSegments        = 3
+	DefaultPreviewSegmentDuration = 2.0
+)
+
+// previewFrameRates keeps previews small: animated images are far larger per
+// frame than H.264, so they get fewer frames.
+var previewFrameRates = map[string]int{
+	PreviewFormatMP4:  24,
+	PreviewFormatWebP: 12,
+	PreviewFormatGIF:  12,
+}
+
+// IsPreviewFormat reports whether format is a supported preview format.
+func IsPreviewFormat(format string) bool {
+	_, ok := previewFrameRates[format]
+	return ok
+}
+
+// PreviewContentType returns the MIME type of a preview in format.
+func PreviewContentType(format string) string {
+	switch format {
+	case PreviewFormatWebP:
+		return "image/webp"
+	case PreviewFormatGIF:
+		return "image/gif"
+	default:
+		return "video/mp4"
+	}
+}
+
+// PreviewSegment is a part of the source that is cut into a preview.
+type PreviewSegment struct {
+	Start    float64 // Offset into the source in seconds
+	Duration float64 // Length in seconds
+}
+
+// SelectPreviewSegments spreads count segments of length seconds evenly over
+// the range [start, end) of a video lasting duration seconds, taking each
+// from the middle of its share of the range. An end of zero means the end of
+// the video. When the segments would cover the whole range, the range is
+// returned as a single segment instead. It returns nil for an empty range.
+func SelectPreviewSegments(duration float64, count int, length, start, end float64) []PreviewSegment {
+	if count < 1 {
+		count = 1
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end <= 0 || (duration > 0 && end > duration) {
+		end = duration
+	}
+	if end <= 0 {
+		// Unknown duration: take one clip from the start of the range
+		return []PreviewSegment{{Start: start, Duration: length * float64(count)}}
+	}
+	if start >= end {
+		return nil
+	}
+
+	window := end - start
+	if length <= 0 || length*float64(count) >= window {
+		return []PreviewSegment{{Start: start, Duration: window}}
+	}
+
+	share := window / float64(count)
+	segments := make([]PreviewSegment, count)
+	for i := range segments {
+		segments[i] = PreviewSegment{
+			Start:    start + share*float64(i) + (share-length)/2,
+			Duration: length,
+		}
+	}
+	return segments
+}
+
+// previewSize returns the even output size of a preview of a srcWidth x
+// srcHeight video scaled to width, never upscaling and keeping the height
+// within maxHeight when it is set.
+func previewSize(srcWidth, srcHeight, width, maxHeight int) (int, int) {
+	if srcWidth <= 0 || srcHeight <= 0 {
+		srcWidth, srcHeight = 16, 9
+	} else if width > srcWidth {
+		width = srcWidth
+	}
+	height := (srcHeight*width + srcWidth/2) / srcWidth
+	if maxHeight > 0 && height > maxHeight {
+		height = maxHeight
+		width = scaledWidth(srcWidth, srcHeight, height)
+	}
+	return max(width&^1, 2), max(height&^1, 2)
+}
+
+// previewArgs builds the ffmpeg arguments that cut segments out of the input,
+// scale them to width x height and join them into a muted preview. Each
+// segment is read as its own input so ffmpeg seeks straight to it instead of
+// decoding the whole video.
+func previewArgs(format, inputPath, outputPath string, segments []PreviewSegment, width, height int) []string {
+	fps := previewFrameRates[format]
+
+	var args []string
+	var filter strings.Builder
+	for i, seg := range segments {
+		args = append(args,
+			"-ss", strconv.FormatFloat(seg.Start, 'f', 3, 64),
+			"-t", strconv.FormatFloat(seg.Duration, 'f', 3, 64),
+			"-i", inputPath,
+		)
+		fmt.Fprintf(&filter, "[%d:v]fps=%d,scale=%d:%d:flags=lanczos,setsar=1,setpts=PTS-STARTPTS[v%d];", i, fps, width, height, i)
+	}
+	for i := range segments {
+		fmt.Fprintf(&filter, "[v%d]", i)
+	}
+	fmt.Fprintf(&filter, "concat=n=%d:v=1:a=0[out]", len(segments))
+
+	output := "[out]"
+	if format == PreviewFormatGIF {
+		// A palette built from the clip itself keeps GIF colours faithful
+		filter.WriteString(";[out]split[p0][p1];[p0]palettegen=stats_mode=diff[pal];[p1][pal]paletteuse=dither=bayer:bayer_scale=5[gif]")
+		output = "[gif]"
+	}
+
+	args = append(args, "-filter_complex", filter.String(), "-map", output, "-an")
+	switch format {
+	case PreviewFormatWebP:
+		args = append(args, "-c:v", "libwebp", "-quality", "60", "-compression_level", "4", "-loop", "0")
+	case PreviewFormatGIF:
+		args = append(args, "-loop", "0")
+	default:
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-profile:v", "main",
+			"-crf", "30",
+			"-maxrate", "500k",
+			"-bufsize", "1000k",
+			"-pix_fmt", "yuv420p",
+			"-movflags", "+faststart",
+		)
+	}
+	return append(args, "-y", outputPath)
+}
@@ -0,0 +1,102 @@
+package video
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectPreviewSegments(t *testing.T) {
+	tests := []struct {
+		name               string
+		duration           float64
+		count              int
+		length, start, end float64
+		want               []PreviewSegment
+	}{
+		{"spread over the video", 60, 3, 2, 0, 0, []PreviewSegment{{9, 2}, {29, 2}, {49, 2}}},
+		{"within a range", 60, 2, 1, 10, 20, []PreviewSegment{{12, 1}, {17, 1}}},
+		{"end past the video", 30, 1, 2, 20, 90, []PreviewSegment{{24, 2}}},
+		{"segments cover the range", 5, 3, 2, 0, 0, []PreviewSegment{{0, 5}}},
+		{"unknown duration", 0, 2, 2, 3, 0, []PreviewSegment{{3, 4}}},
+		{"range after the video", 10, 3, 2, 15, 0, nil},
+		{"empty range", 60, 3, 2, 20, 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectPreviewSegments(tt.duration, tt.count, tt.length, tt.start, tt.end)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectPreviewSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewSize(t *testing.T) {
+	tests := []struct {
+		name                  string
+		srcW, srcH, w, maxH   int
+		wantWidth, wantHeight int
+	}{
+		{"landscape", 1920, 1080, 320, 0, 320, 180},
+		{"no upscaling", 426, 240, 640, 0, 426, 240},
+		{"portrait capped by the plan", 1080, 1920, 480, 480, 270, 480},
+		{"odd sizes are evened", 1280, 534, 321, 0, 320, 134},
+		{"unknown source", 0, 0, 320, 0, 320, 180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := previewSize(tt.srcW, tt.srcH, tt.w, tt.maxH)
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("previewSize() = %dx%d, want %dx%d", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestPreviewArgs(t *testing.T) {
+	segments := []PreviewSegment{{Start: 1.5, Duration: 2}, {Start: 10, Duration: 2}}
+
+	mp4 := strings.Join(previewArgs(PreviewFormatMP4, "/tmp/in", "/tmp/preview.mp4", segments, 320, 180), " ")
+	for _, want := range []string{
+		"-ss 1.500 -t 2.000 -i /tmp/in -ss 10.000 -t 2.000 -i /tmp/in",
+		"[0:v]fps=24,scale=320:180:flags=lanczos,setsar=1,setpts=PTS-STARTPTS[v0];",
+		"[v0][v1]concat=n=2:v=1:a=0[out]",
+		"-map [out] -an -c:v libx264",
+		"-movflags +faststart -y /tmp/preview.mp4",
+	} {
+		if !strings.Contains(mp4, want) {
+			t.Errorf("mp4 args missing %q: %s", want, mp4)
+		}
+	}
+
+	webp := strings.Join(previewArgs(PreviewFormatWebP, "/tmp/in", "/tmp/preview.webp", segments[:1], 320, 180), " ")
+	if !strings.Contains(webp, "fps=12") || !strings.Contains(webp, "-c:v libwebp") || !strings.Contains(webp, "-loop 0") {
+		t.Errorf("webp args = %s", webp)
+	}
+
+	gif := strings.Join(previewArgs(PreviewFormatGIF, "/tmp/in", "/tmp/preview.gif", segments, 320, 180), " ")
+	if !strings.Contains(gif, "palettegen") || !strings.Contains(gif, "-map [gif]") {
+		t.Errorf("gif args = %s", gif)
+	}
+}
+
+func TestPreviewContentType(t *testing.T) {
+	for format, want := range map[string]string{
+		PreviewFormatMP4:  "video/mp4",
+		PreviewFormatWebP: "image/webp",
+		PreviewFormatGIF:  "image/gif",
+	} {
+		if !IsPreviewFormat(format) {
+			t.Errorf("IsPreviewFormat(%q) = false", format)
+		}
+		if got := PreviewContentType(format); got != want {
+			t.Errorf("PreviewContentType(%q) = %q, want %q", format, got, want)
+		}
+	}
+	if IsPreviewFormat("webm") {
+		t.Error("IsPreviewFormat(webm) = true")
+	}
+}
@@ -38,7 +38,20 @@ type VideoOptions struct {
 	ThumbnailAt float64 // Extract thumbnail at this percentage of duration (0.0-1.0)
 
 	// HLS specific
-	HLSSegmentDuration int // Segment duration in seconds (default 10)
+	HLSSegmentDuration int   // Segment duration in seconds (default 10)
+	HLSResolutions     []int // Rendition heights (default VideoConfig.HLSResolutions)
+
+	// DASH specific, defaults shared with HLS
+	DASHSegmentDuration int   // Segment duration in seconds (default VideoConfig.HLSSegmentDuration)
+	DASHResolutions     []int // Representation heights (default VideoConfig.HLSResolutions)
+
+	// Preview specific, bounded by MaxResolution
+	PreviewFormat          string  // mp4, webp or gif (default mp4)
+	PreviewWidth           int     // Output width in pixels (default 320)
+	PreviewSegments        int     // Number of segments cut across the range (default 3)
+	PreviewSegmentDuration float64 // Segment length in seconds (default 2)
+	PreviewStart           float64 // Start of the range in seconds
+	PreviewEnd             float64 // End of the range in seconds, 0 for the end of the video
 }
 
 // VideoMetadata contains detailed video information
@@ -70,15 +83,33 @@ type VideoProcessor interface {
 
 	// GenerateHLS creates HLS segments and manifest from a video
 	GenerateHLS(ctx context.Context, opts *VideoOptions, input io.Reader) (*HLSResult, error)
+
+	// GenerateDASH creates fragmented MP4 segments and an MPD from a video
+	GenerateDASH(ctx context.Context, opts *VideoOptions, input io.Reader) (*DASHResult, error)
+
+	// GeneratePreview cuts short segments of a video into a muted clip or animation
+	GeneratePreview(ctx context.Context, opts *VideoOptions, input io.Reader) (*processor.Result, error)
 }
 
 // HLSResult contains the output of HLS generation
 type HLSResult struct {
-	ManifestPath  string   // Path to the m3u8 manifest
-	SegmentPaths  []string // Paths to all segment files
+	ManifestPath  string         // Path to the master m3u8 playlist
+	SegmentPaths  []string       // Paths to all segment files
+	TotalDuration float64        // Total duration in seconds
+	SegmentCount  int            // Number of segments
+	Resolutions   []int          // Available resolutions (heights)
+	Renditions    []HLSRendition // One entry per encoded resolution, lowest first
+}
+
+// DASHResult contains the output of DASH generation
+type DASHResult struct {
+	ManifestPath  string   // Path to the MPD
+	SegmentPaths  []string // Paths to all initialization and media segments
 	TotalDuration float64  // Total duration in seconds
-	SegmentCount  int      // Number of segments
-	Resolutions   []int    // Available resolutions (heights)
+	SegmentCount  int      // Number of segment files
+	Resolutions   []int    // Encoded heights, lowest first
+	Width         int      // Width of the highest representation
+	Height        int      // Height of the highest representation
 }
 
 // VideoConfig holds configuration for video processors
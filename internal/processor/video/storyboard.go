@@ -0,0 +1,64 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// StoryboardSprite is the file name of the storyboard sprite sheet.
+	StoryboardSprite = "sprite.jpg"
+	// StoryboardVTT is the file name of the WebVTT thumbnail track.
+	StoryboardVTT = "storyboard.vtt"
+)
+
+// BuildStoryboardVTT returns a WebVTT thumbnail track for a sprite sheet made
+// by GenerateThumbnailSprite. The sheet holds cols*rows tiles of thumbWidth x
+// thumbHeight pixels, taken left to right and top to bottom at equal
+// intervals over duration, and each tile becomes a cue pointing at its
+// region of sprite with a #xywh= media fragment.
+func BuildStoryboardVTT(sprite string, duration float64, cols, rows, thumbWidth, thumbHeight int) []byte {
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n")
+
+	tiles := cols * rows
+	if tiles <= 0 || duration <= 0 {
+		return b.Bytes()
+	}
+	interval := duration / float64(tiles)
+	for i := range tiles {
+		start := float64(i) * interval
+		end := start + interval
+		if i == tiles-1 {
+			end = duration
+		}
+		x, y := (i%cols)*thumbWidth, (i/cols)*thumbHeight
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), sprite, x, y, thumbWidth, thumbHeight)
+	}
+	return b.Bytes()
+}
+
+// vttTimestamp formats seconds as a WebVTT timestamp, e.g. 00:01:02.500.
+func vttTimestamp(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// RewriteStoryboardVTT returns the track with the image URI of every
+// #xywh= cue passed through rewrite, keeping the fragment.
+func RewriteStoryboardVTT(data []byte, rewrite func(uri string) string) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if uri, fragment, ok := strings.Cut(line, "#xywh="); ok && !strings.Contains(line, "-->") {
+			line = rewrite(uri) + "#xywh=" + fragment
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
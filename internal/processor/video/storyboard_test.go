@@ -0,0 +1,56 @@
+package video
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildStoryboardVTT(t *testing.T) {
+	got := string(BuildStoryboardVTT("sprite.jpg", 25, 2, 2, 160, 90))
+	want := `WEBVTT
+
+00:00:00.000 --> 00:00:06.250
+sprite.jpg#xywh=0,0,160,90
+
+00:00:06.250 --> 00:00:12.500
+sprite.jpg#xywh=160,0,160,90
+
+00:00:12.500 --> 00:00:18.750
+sprite.jpg#xywh=0,90,160,90
+
+00:00:18.750 --> 00:00:25.000
+sprite.jpg#xywh=160,90,160,90
+`
+	if got != want {
+		t.Errorf("BuildStoryboardVTT() =\n%s\nwant\n%s", got, want)
+	}
+
+	if empty := string(BuildStoryboardVTT("sprite.jpg", 0, 10, 10, 160, 90)); empty != "WEBVTT\n" {
+		t.Errorf("zero duration = %q, want only the header", empty)
+	}
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	tests := map[float64]string{
+		0:       "00:00:00.000",
+		62.5:    "00:01:02.500",
+		3723.04: "01:02:03.040",
+	}
+	for seconds, want := range tests {
+		if got := vttTimestamp(seconds); got != want {
+			t.Errorf("vttTimestamp(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestRewriteStoryboardVTT(t *testing.T) {
+	vtt := "WEBVTT\r\n\r\n00:00:00.000 --> 00:00:06.000\r\nsprite.jpg#xywh=0,0,160,90\r\n"
+	got := string(RewriteStoryboardVTT([]byte(vtt), func(uri string) string { return "/base/" + uri }))
+
+	if !strings.Contains(got, "\n/base/sprite.jpg#xywh=0,0,160,90\n") {
+		t.Errorf("cue image not rewritten:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "WEBVTT\n\n00:00:00.000 --> 00:00:06.000\n") {
+		t.Errorf("header and timings should be kept:\n%s", got)
+	}
+}
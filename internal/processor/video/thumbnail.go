@@ -279,9 +279,10 @@ func (p *ThumbnailProcessor) GenerateThumbnailSprite(ctx context.Context, input
 		Filename:    "sprite.jpg",
 		Size:        int64(len(spriteData)),
 		Metadata: processor.ResultMetadata{
-			Width:  thumbWidth * cols,
-			Height: thumbHeight * rows,
-			Format: "jpeg",
+			Width:    thumbWidth * cols,
+			Height:   thumbHeight * rows,
+			Duration: duration,
+			Format:   "jpeg",
 		},
 	}, nil
 }
@@ -0,0 +1,386 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+)
+
+var _ Storage = (*FilesystemStorage)(nil)
+
+// FilesystemDownloadPath is the route prefix served by FilesystemStorage for
+// presigned downloads and uploads.
+const FilesystemDownloadPath = "/storage/"
+
+// FilesystemStorage stores objects on local disk. Object data lives under
+// objects/, a JSON sidecar with the content type under meta/, and partially
+// written files under tmp/ until they are renamed into place.
+type FilesystemStorage struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+type fileMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+func NewFilesystemStorage(cfg *Config) (*FilesystemStorage, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("filesystem storage path is required")
+	}
+	if cfg.SigningKey == "" {
+		return nil, errors.New("filesystem storage signing key is required")
+	}
+
+	root, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve storage path: %w", err)
+	}
+	for _, dir := range []string{"objects", "meta", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	return &FilesystemStorage{
+		root:       root,
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		signingKey: []byte(cfg.SigningKey),
+	}, nil
+}
+
+// paths returns the object and sidecar paths for key, rejecting keys that
+// would escape the storage root.
+func (s *FilesystemStorage) paths(key string) (string, string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", "", ErrInvalidKey
+		}
+	}
+	rel := filepath.FromSlash(key)
+	return filepath.Join(s.root, "objects", rel), filepath.Join(s.root, "meta", rel+".json"), nil
+}
+
+func (s *FilesystemStorage) Upload(ctx context.Context, key string, reader io.Reader, contentType string, size int64) error {
+	log := logger.FromContext(ctx)
+	start := time.Now()
+
+	objPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(fileMeta{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("encode metadata for %s: %w", key, err)
+	}
+
+	written, err := s.writeAtomic(objPath, reader)
+	if err != nil {
+		log.Error("storage upload failed", "key", key, "size", size, "error", err)
+		return fmt.Errorf("upload to %s: %w", key, err)
+	}
+	if _, err := s.writeAtomic(metaPath, strings.NewReader(string(meta))); err != nil {
+		log.Error("storage metadata write failed", "key", key, "error", err)
+		return fmt.Errorf("upload to %s: %w", key, err)
+	}
+
+	log.Debug("storage upload completed", "key", key, "size", written, "content_type", contentType, "duration_ms", time.Since(start).Milliseconds())
+	return nil
+}
+
+// writeAtomic writes r to a temporary file and renames it over dst so readers
+// never observe a partially written object.
+func (s *FilesystemStorage) writeAtomic(dst string, r io.Reader) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "upload-*")
+	if err != nil {
+		return 0, err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	n, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+
+	return n, os.Rename(tmpName, dst)
+}
+
+func (s *FilesystemStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	log := logger.FromContext(ctx)
+
+	objPath, _, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(objPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Warn("storage object not found", "key", key)
+			return nil, ErrNotFound
+		}
+		log.Error("storage download failed", "key", key, "error", err)
+		return nil, fmt.Errorf("download %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (s *FilesystemStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("download %s: invalid offset %d", key, offset)
+	}
+
+	rc, err := s.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	f := rc.(*os.File)
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("download %s: %w", key, err)
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return limitedReadCloser{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (s *FilesystemStorage) Delete(ctx context.Context, key string) error {
+	log := logger.FromContext(ctx)
+
+	objPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{objPath, metaPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Error("storage delete failed", "key", key, "error", err)
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+	}
+
+	log.Debug("storage object deleted", "key", key)
+	return nil
+}
+
+func (s *FilesystemStorage) Exists(ctx context.Context, key string) (bool, error) {
+	objPath, _, err := s.paths(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(objPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("check exists %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// GetPresignedURL returns a URL served by ServeHTTP. The signature covers the
+// method, key and expiry so none can be altered by the holder of the URL.
+func (s *FilesystemStorage) GetPresignedURL(ctx context.Context, key string, expirySeconds int) (string, error) {
+	rawURL, err := s.presign(http.MethodGet, key, expirySeconds)
+	if err != nil {
+		return "", err
+	}
+	logger.FromContext(ctx).Debug("storage presigned url generated", "key", key, "expiry_seconds", expirySeconds)
+	return rawURL, nil
+}
+
+// GetPresignedUploadURL returns a URL that ServeHTTP accepts a PUT of the
+// object body on. The content type is taken from the PUT request.
+func (s *FilesystemStorage) GetPresignedUploadURL(ctx context.Context, key, contentType string, expirySeconds int) (string, error) {
+	rawURL, err := s.presign(http.MethodPut, key, expirySeconds)
+	if err != nil {
+		return "", err
+	}
+	logger.FromContext(ctx).Debug("storage presigned upload url generated", "key", key, "content_type", contentType, "expiry_seconds", expirySeconds)
+	return rawURL, nil
+}
+
+func (s *FilesystemStorage) presign(method, key string, expirySeconds int) (string, error) {
+	if _, _, err := s.paths(key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(presignDuration(expirySeconds)).Unix()
+
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.sign(method, key, expires))
+
+	return s.baseURL + FilesystemDownloadPath + strings.Join(segments, "/") + "?" + q.Encode(), nil
+}
+
+func (s *FilesystemStorage) sign(method, key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(method + "\n" + key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature was produced for method and key
+// by GetPresignedURL or GetPresignedUploadURL and has not expired.
+func (s *FilesystemStorage) VerifySignature(method, key, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(s.sign(method, key, exp)), []byte(signature))
+}
+
+// ServeHTTP serves presigned downloads and uploads under
+// FilesystemDownloadPath. HEAD requests are accepted with GET signatures.
+func (s *FilesystemStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, FilesystemDownloadPath)
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	q := r.URL.Query()
+	if !s.VerifySignature(method, key, q.Get("expires"), q.Get("signature")) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+
+	switch method {
+	case http.MethodGet:
+		s.serveDownload(w, r, key)
+	case http.MethodPut:
+		s.serveUpload(w, r, key)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *FilesystemStorage) serveDownload(w http.ResponseWriter, r *http.Request, key string) {
+	objPath, metaPath, err := s.paths(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(objPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "failed to read object", http.StatusInternalServerError)
+		return
+	}
+
+	if meta, err := s.readMeta(metaPath); err == nil && meta.ContentType != "" {
+		w.Header().Set("Content-Type", meta.ContentType)
+	}
+
+	http.ServeContent(w, r, filepath.Base(objPath), info.ModTime(), f)
+}
+
+func (s *FilesystemStorage) serveUpload(w http.ResponseWriter, r *http.Request, key string) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if err := s.Upload(r.Context(), key, r.Body, contentType, r.ContentLength); err != nil {
+		if errors.Is(err, ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "failed to store object", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FilesystemStorage) readMeta(metaPath string) (fileMeta, error) {
+	var meta fileMeta
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+// Stat returns the size of an object and the content type recorded when it
+// was written.
+func (s *FilesystemStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	objPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(objPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("stat %s: %w", key, err)
+	}
+
+	obj := &ObjectInfo{Size: info.Size()}
+	if meta, err := s.readMeta(metaPath); err == nil {
+		obj.ContentType = meta.ContentType
+	}
+	return obj, nil
+}
+
+func (s *FilesystemStorage) HealthCheck(ctx context.Context) error {
+	f, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "health-*")
+	if err != nil {
+		return fmt.Errorf("storage health check failed: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
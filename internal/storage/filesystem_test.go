@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestFilesystemStorage(t *testing.T) *FilesystemStorage {
+	t.Helper()
+	store, err := NewFilesystemStorage(&Config{
+		Path:       t.TempDir(),
+		BaseURL:    "http://localhost:8080",
+		SigningKey: "test-secret",
+	})
+	if err != nil {
+		t.Fatalf("NewFilesystemStorage() error = %v", err)
+	}
+	return store
+}
+
+func TestFilesystemStorage_RoundTrip(t *testing.T) {
+	store := newTestFilesystemStorage(t)
+	ctx := context.Background()
+	key := "uploads/user/file name.txt"
+
+	if err := store.Upload(ctx, key, strings.NewReader("hello world"), "text/plain", 11); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	exists, err := store.Exists(ctx, key)
+	if err != nil || !exists {
+		t.Fatalf("Exists() = %v, %v, want true", exists, err)
+	}
+
+	rc, err := store.Download(ctx, key)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(data) != "hello world" {
+		t.Errorf("Download() = %q, want %q", data, "hello world")
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(store.root, "tmp"))
+	if len(entries) != 0 {
+		t.Errorf("tmp directory has %d leftover files", len(entries))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Download(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Download() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of missing key error = %v, want nil", err)
+	}
+}
+
+func TestFilesystemStorage_DownloadRange(t *testing.T) {
+	store := newTestFilesystemStorage(t)
+	ctx := context.Background()
+
+	if err := store.Upload(ctx, "range.bin", strings.NewReader("0123456789"), "application/octet-stream", 10); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           string
+	}{
+		{"middle", 2, 3, "234"},
+		{"to end", 7, -1, "789"},
+		{"past end", 8, 10, "89"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := store.DownloadRange(ctx, "range.bin", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("DownloadRange() error = %v", err)
+			}
+			defer func() { _ = rc.Close() }()
+			data, _ := io.ReadAll(rc)
+			if string(data) != tt.want {
+				t.Errorf("DownloadRange() = %q, want %q", data, tt.want)
+			}
+		})
+	}
+
+	if _, err := store.DownloadRange(ctx, "missing.bin", 0, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DownloadRange() missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFilesystemStorage_InvalidKey(t *testing.T) {
+	store := newTestFilesystemStorage(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../escape", "a/../../b", "a//b", `a\b`} {
+		t.Run(key, func(t *testing.T) {
+			err := store.Upload(ctx, key, strings.NewReader("x"), "text/plain", 1)
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Upload(%q) error = %v, want ErrInvalidKey", key, err)
+			}
+		})
+	}
+}
+
+func TestFilesystemStorage_PresignedURL(t *testing.T) {
+	store := newTestFilesystemStorage(t)
+	ctx := context.Background()
+	key := "processed/abc/thumb.png"
+
+	if err := store.Upload(ctx, key, strings.NewReader("png-bytes"), "image/png", 9); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	rawURL, err := store.GetPresignedURL(ctx, key, 60)
+	if err != nil {
+		t.Fatalf("GetPresignedURL() error = %v", err)
+	}
+	if !strings.HasPrefix(rawURL, "http://localhost:8080/storage/processed/abc/thumb.png?") {
+		t.Fatalf("GetPresignedURL() = %q", rawURL)
+	}
+	u, _ := url.Parse(rawURL)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"valid signature", u.RequestURI(), http.StatusOK},
+		{"tampered key", strings.Replace(u.RequestURI(), "thumb", "other", 1), http.StatusForbidden},
+		{"missing signature", u.Path, http.StatusForbidden},
+		{"expired", u.Path + "?expires=1&signature=" + store.sign(http.MethodGet, key, 1), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+				t.Errorf("Content-Type = %q, want image/png", ct)
+			}
+			if rec.Body.String() != "png-bytes" {
+				t.Errorf("body = %q, want png-bytes", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFilesystemStorage_PresignedUpload(t *testing.T) {
+	store := newTestFilesystemStorage(t)
+	ctx := context.Background()
+	key := "uploads/user/id/photo.png"
+
+	rawURL, err := store.GetPresignedUploadURL(ctx, key, "image/png", 60)
+	if err != nil {
+		t.Fatalf("GetPresignedUploadURL() error = %v", err)
+	}
+	u, _ := url.Parse(rawURL)
+
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET with upload signature status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, u.RequestURI(), strings.NewReader("png-bytes"))
+	req.Header.Set("Content-Type", "image/png")
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	info, err := store.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 9 || info.ContentType != "image/png" {
+		t.Errorf("Stat() = %+v, want size 9 and image/png", info)
+	}
+
+	if _, err := store.Stat(ctx, "uploads/missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() missing key error = %v, want ErrNotFound", err)
+	}
+}
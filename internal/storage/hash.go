@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// HashingReader computes a SHA-256 digest of the data read through it, so a
+// content hash can be taken while streaming an upload to storage.
+type HashingReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	n      int64
+}
+
+// NewHashingReader wraps r so that every byte read is fed into a SHA-256 hash.
+func NewHashingReader(r io.Reader) *HashingReader {
+	h := sha256.New()
+	return &HashingReader{
+		reader: io.TeeReader(r, h),
+		hash:   h,
+	}
+}
+
+func (h *HashingReader) Read(p []byte) (int, error) {
+	n, err := h.reader.Read(p)
+	h.n += int64(n)
+	return n, err
+}
+
+// Sum returns the hex-encoded SHA-256 digest of the bytes read so far.
+func (h *HashingReader) Sum() string {
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
+
+// BytesRead returns the number of bytes read so far.
+func (h *HashingReader) BytesRead() int64 {
+	return h.n
+}
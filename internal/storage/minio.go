@@ -99,6 +99,42 @@ func (s *MinIOStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 	return obj, nil
 }
 
+func (s *MinIOStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	log := logger.FromContext(ctx)
+
+	if offset < 0 {
+		return nil, fmt.Errorf("download %s: invalid offset %d", key, offset)
+	}
+
+	opts := minio.GetObjectOptions{}
+	end := int64(0)
+	if length >= 0 {
+		end = offset + length - 1
+	}
+	if err := opts.SetRange(offset, end); err != nil {
+		return nil, fmt.Errorf("download %s: %w", key, err)
+	}
+
+	obj, err := s.client.GetObject(ctx, s.bucket, key, opts)
+	if err != nil {
+		log.Error("storage range download failed", "key", key, "offset", offset, "length", length, "error", err)
+		return nil, fmt.Errorf("download %s: %w", key, err)
+	}
+
+	if _, err := obj.Stat(); err != nil {
+		_ = obj.Close()
+		if isNotFoundError(err) {
+			log.Warn("storage object not found", "key", key)
+			return nil, ErrNotFound
+		}
+		log.Error("storage stat failed", "key", key, "error", err)
+		return nil, fmt.Errorf("stat %s: %w", key, err)
+	}
+
+	log.Debug("storage range download started", "key", key, "offset", offset, "length", length)
+	return obj, nil
+}
+
 func (s *MinIOStorage) Delete(ctx context.Context, key string) error {
 	log := logger.FromContext(ctx)
 
@@ -136,6 +172,30 @@ func (s *MinIOStorage) GetPresignedURL(ctx context.Context, key string, expirySe
 	return url.String(), nil
 }
 
+func (s *MinIOStorage) GetPresignedUploadURL(ctx context.Context, key, contentType string, expirySeconds int) (string, error) {
+	log := logger.FromContext(ctx)
+
+	url, err := s.client.PresignedPutObject(ctx, s.bucket, key, presignDuration(expirySeconds))
+	if err != nil {
+		log.Error("storage upload presign failed", "key", key, "error", err)
+		return "", fmt.Errorf("presign upload %s: %w", key, err)
+	}
+
+	log.Debug("storage presigned upload url generated", "key", key, "content_type", contentType, "expiry_seconds", expirySeconds)
+	return url.String(), nil
+}
+
+func (s *MinIOStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("stat %s: %w", key, err)
+	}
+	return &ObjectInfo{Size: info.Size, ContentType: info.ContentType}, nil
+}
+
 func isNotFoundError(err error) bool {
 	if err == nil {
 		return false
@@ -73,6 +73,33 @@ func (s *MemoryStorage) Download(ctx context.Context, key string) (io.ReadCloser
 	return io.NopCloser(bytes.NewReader(file.data)), nil
 }
 
+// DownloadRange retrieves length bytes from offset. A negative length reads
+// to the end of the file.
+func (s *MemoryStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, exists := s.files[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	size := int64(len(file.data))
+	if offset < 0 || offset > size {
+		return nil, fmt.Errorf("invalid offset %d for %s", offset, key)
+	}
+	end := size
+	if length >= 0 && offset+length < size {
+		end = offset + length
+	}
+
+	return io.NopCloser(bytes.NewReader(file.data[offset:end])), nil
+}
+
 // Delete removes the file at the given key.
 func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
 	if err := ctx.Err(); err != nil {
@@ -115,6 +142,34 @@ func (s *MemoryStorage) GetPresignedURL(ctx context.Context, key string, expiryS
 	return fmt.Sprintf("http://test-storage/%s?expires=%d", key, expirySeconds), nil
 }
 
+// GetPresignedUploadURL returns a fake presigned upload URL for testing.
+func (s *MemoryStorage) GetPresignedUploadURL(ctx context.Context, key, contentType string, expirySeconds int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+
+	return fmt.Sprintf("http://test-storage/%s?upload=1&expires=%d", key, expirySeconds), nil
+}
+
+// Stat returns the size and content type of the file at the given key.
+func (s *MemoryStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, exists := s.files[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &ObjectInfo{Size: int64(len(file.data)), ContentType: file.contentType}, nil
+}
+
 // GetData returns the raw data for a key (test helper).
 func (s *MemoryStorage) GetData(key string) ([]byte, bool) {
 	s.mu.RLock()
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -16,17 +17,58 @@ var (
 type Storage interface {
 	Upload(ctx context.Context, key string, reader io.Reader, contentType string, size int64) error
 	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	// DownloadRange reads length bytes starting at offset. A negative length
+	// reads to the end of the object.
+	DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 	GetPresignedURL(ctx context.Context, key string, expirySeconds int) (string, error)
+	// GetPresignedUploadURL returns a URL that accepts a single PUT of the
+	// object body with the given content type.
+	GetPresignedUploadURL(ctx context.Context, key, contentType string, expirySeconds int) (string, error)
+	// Stat returns the size and content type of an object, or ErrNotFound.
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
 	HealthCheck(ctx context.Context) error
 }
 
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Backend names accepted by New.
+const (
+	BackendMinIO      = "minio"
+	BackendFilesystem = "filesystem"
+)
+
 type Config struct {
+	Backend string
+
 	Endpoint  string
 	AccessKey string
 	SecretKey string
 	Bucket    string
 	UseSSL    bool
 	Region    string
+
+	// Filesystem backend settings. BaseURL and SigningKey are used to build
+	// and verify the URLs returned by GetPresignedURL.
+	Path       string
+	BaseURL    string
+	SigningKey string
+}
+
+// New creates the storage backend selected by cfg.Backend. An empty backend
+// selects MinIO.
+func New(cfg *Config) (Storage, error) {
+	switch cfg.Backend {
+	case "", BackendMinIO:
+		return NewMinIOStorage(cfg)
+	case BackendFilesystem:
+		return NewFilesystemStorage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
 }
@@ -648,3 +648,26 @@ func TestMinIOStorage_GetPresignedURL(t *testing.T) {
 		}
 	})
 }
+
+func TestHashingReader(t *testing.T) {
+	content := "hello world"
+	hr := NewHashingReader(strings.NewReader(content))
+
+	store := NewMemoryStorage()
+	if err := store.Upload(context.Background(), "test/hash.txt", hr, "text/plain", int64(len(content))); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	const want = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if got := hr.Sum(); got != want {
+		t.Errorf("Sum() = %s, want %s", got, want)
+	}
+	if got := hr.BytesRead(); got != int64(len(content)) {
+		t.Errorf("BytesRead() = %d, want %d", got, len(content))
+	}
+
+	data, _ := store.GetData("test/hash.txt")
+	if string(data) != content {
+		t.Errorf("stored data = %q, want %q", data, content)
+	}
+}
@@ -0,0 +1,53 @@
+package streaming
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const contentTypeMPD = "application/dash+xml"
+
+// ServeDASH serves name from the DASH package of fileID. base is the URL path
+// the request was routed through, e.g. /v1/files/{id}/dash: the manifest is
+// returned with its relative segment URLs rewritten under it, and segments
+// redirect to a short-lived presigned URL.
+func ServeDASH(w http.ResponseWriter, r *http.Request, queries VariantQuerier, store storage.Storage, fileID pgtype.UUID, name, base string) {
+	if !validName(name) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	variant, err := queries.GetVariant(r.Context(), db.GetVariantParams{FileID: fileID, VariantType: db.VariantTypeDashManifest})
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if name != video.DASHManifest {
+		redirectToObject(w, r, store, path.Join(path.Dir(variant.StorageKey), name))
+		return
+	}
+
+	data, ok := readObject(w, r, store, variant.StorageKey)
+	if !ok {
+		return
+	}
+
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	manifest := video.RewriteMPD(data, func(uri string) string {
+		if isAbsoluteURI(uri) {
+			return uri
+		}
+		return prefix + uri
+	})
+
+	w.Header().Set("Content-Type", contentTypeMPD)
+	w.Header().Set("Cache-Control", cacheControlPlaylist)
+	_, _ = w.Write(manifest)
+}
@@ -0,0 +1,78 @@
+package streaming
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const testMPD = `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+	<Period id="0">
+		<AdaptationSet id="0" contentType="video">
+			<Representation id="0" bandwidth="800000" width="640" height="360">
+				<SegmentTemplate initialization="init-$RepresentationID$.m4s" media="chunk-$RepresentationID$-$Number%05d$.m4s" startNumber="1"/>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+</MPD>
+`
+
+func serveDASH(t *testing.T, name string) *httptest.ResponseRecorder {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	for key, body := range map[string]string{
+		"p/dash_manifest/manifest.mpd": testMPD,
+		"p/dash_manifest/init-0.m4s":   "init",
+	} {
+		if err := store.Upload(context.Background(), key, strings.NewReader(body), "", int64(len(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	variants := fakeVariants{db.VariantTypeDashManifest: "p/dash_manifest/manifest.mpd"}
+
+	rec := httptest.NewRecorder()
+	fileID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	ServeDASH(rec, httptest.NewRequest("GET", "/", nil), variants, store, fileID, name, "/stream")
+	return rec
+}
+
+func TestServeDASH_Manifest(t *testing.T) {
+	rec := serveDASH(t, "manifest.mpd")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `initialization="/stream/init-$RepresentationID$.m4s"`) ||
+		!strings.Contains(body, `media="/stream/chunk-$RepresentationID$-$Number%05d$.m4s"`) {
+		t.Errorf("segment URLs not rewritten:\n%s", body)
+	}
+	if rec.Header().Get("Content-Type") != contentTypeMPD || rec.Header().Get("Cache-Control") != cacheControlPlaylist {
+		t.Errorf("headers = %v", rec.Header())
+	}
+}
+
+func TestServeDASH_SegmentRedirect(t *testing.T) {
+	rec := serveDASH(t, "init-0.m4s")
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+	}
+	if loc := rec.Header().Get("Location"); !strings.Contains(loc, "p/dash_manifest/init-0.m4s") {
+		t.Errorf("Location = %q", loc)
+	}
+}
+
+func TestServeDASH_NotFound(t *testing.T) {
+	for _, name := range []string{"", "..", `..\manifest.mpd`} {
+		if rec := serveDASH(t, name); rec.Code != http.StatusNotFound {
+			t.Errorf("%q: status = %d, want %d", name, rec.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package streaming serves packaged adaptive video from storage. HLS
+// playlists and DASH manifests are rewritten so every URI in them resolves
+// through the endpoint that served them, which checks access before
+// redirecting to the stored object.
+package streaming
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// presignExpiry is how long segment redirects stay valid, in seconds.
+	presignExpiry = 3600
+
+	cacheControlPlaylist = "private, max-age=60"
+	contentTypePlaylist  = "application/vnd.apple.mpegurl"
+)
+
+type VariantQuerier interface {
+	GetVariant(ctx context.Context, arg db.GetVariantParams) (db.FileVariant, error)
+}
+
+// hlsRenditions maps rendition path names to their variant types.
+var hlsRenditions = map[string]db.VariantType{
+	"360p":  db.VariantTypeHls360p,
+	"480p":  db.VariantTypeHls480p,
+	"720p":  db.VariantTypeHls720p,
+	"1080p": db.VariantTypeHls1080p,
+}
+
+// ServeHLS serves name from the HLS package of fileID. rendition is empty for
+// the master playlist and anything stored next to it (the segments of older
+// single-rendition packages), or a rendition such as "720p". base is the URL
+// path the request was routed through, e.g. /v1/files/{id}/hls: playlists are
+// returned with their relative URIs rewritten under it, and everything else
+// redirects to a short-lived presigned URL.
+func ServeHLS(w http.ResponseWriter, r *http.Request, queries VariantQuerier, store storage.Storage, fileID pgtype.UUID, rendition, name, base string) {
+	variantType := db.VariantTypeHlsMaster
+	if rendition != "" {
+		var ok bool
+		if variantType, ok = hlsRenditions[rendition]; !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}
+	if !validName(name) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	variant, err := queries.GetVariant(r.Context(), db.GetVariantParams{FileID: fileID, VariantType: variantType})
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	// The variant row points at the rendition's own playlist; other files
+	// live alongside it.
+	key := path.Join(path.Dir(variant.StorageKey), name)
+	if (rendition == "" && name == video.HLSMasterPlaylist) || (rendition != "" && name == video.HLSMediaPlaylist) {
+		key = variant.StorageKey
+	}
+
+	if !strings.HasSuffix(name, ".m3u8") {
+		redirectToObject(w, r, store, key)
+		return
+	}
+
+	data, ok := readObject(w, r, store, key)
+	if !ok {
+		return
+	}
+
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	if rendition != "" {
+		prefix += rendition + "/"
+	}
+	playlist := video.RewritePlaylist(data, func(uri string) string {
+		if isAbsoluteURI(uri) {
+			return uri
+		}
+		return prefix + uri
+	})
+
+	w.Header().Set("Content-Type", contentTypePlaylist)
+	w.Header().Set("Cache-Control", cacheControlPlaylist)
+	_, _ = w.Write(playlist)
+}
+
+// validName reports whether name is a single path element that can be
+// looked up next to a variant's storage key.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// redirectToObject redirects to a presigned URL for key.
+func redirectToObject(w http.ResponseWriter, r *http.Request, store storage.Storage, key string) {
+	url, err := store.GetPresignedURL(r.Context(), key, presignExpiry)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+// readObject downloads a playlist or manifest. On failure it writes the
+// error response and returns false.
+func readObject(w http.ResponseWriter, r *http.Request, store storage.Storage, key string) ([]byte, bool) {
+	reader, err := store.Download(r.Context(), key)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil, false
+	}
+	data, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		http.Error(w, "failed to read manifest", http.StatusInternalServerError)
+		return nil, false
+	}
+	return data, true
+}
+
+// isAbsoluteURI reports whether uri already names a full URL or an absolute
+// path and must be left alone.
+func isAbsoluteURI(uri string) bool {
+	return strings.HasPrefix(uri, "/") || strings.Contains(uri, "://")
+}
@@ -0,0 +1,109 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeVariants map[db.VariantType]string
+
+func (f fakeVariants) GetVariant(ctx context.Context, arg db.GetVariantParams) (db.FileVariant, error) {
+	key, ok := f[arg.VariantType]
+	if !ok {
+		return db.FileVariant{}, errors.New("variant not found")
+	}
+	return db.FileVariant{FileID: arg.FileID, VariantType: arg.VariantType, StorageKey: key}, nil
+}
+
+func setupPackage(t *testing.T) (fakeVariants, *storage.MemoryStorage) {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	objects := map[string]string{
+		"p/hls_master/master.m3u8":  "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=900000,RESOLUTION=640x360\n360p/playlist.m3u8\n",
+		"p/hls_360p/playlist.m3u8":  "#EXTM3U\n#EXTINF:6.0,\nsegment_000.ts\n#EXTINF:6.0,\nhttps://other.example.com/segment_001.ts\n#EXT-X-ENDLIST\n",
+		"p/hls_360p/segment_000.ts": "ts",
+	}
+	for key, body := range objects {
+		if err := store.Upload(context.Background(), key, strings.NewReader(body), "", int64(len(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fakeVariants{
+		db.VariantTypeHlsMaster: "p/hls_master/master.m3u8",
+		db.VariantTypeHls360p:   "p/hls_360p/playlist.m3u8",
+	}, store
+}
+
+func serve(variants fakeVariants, store storage.Storage, rendition, name string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	fileID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	ServeHLS(rec, req, variants, store, fileID, rendition, name, "/stream/")
+	return rec
+}
+
+func TestServeHLS_Playlists(t *testing.T) {
+	variants, store := setupPackage(t)
+
+	rec := serve(variants, store, "", "master.m3u8")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("master: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "\n/stream/360p/playlist.m3u8\n") {
+		t.Errorf("master = %s", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != contentTypePlaylist || rec.Header().Get("Cache-Control") != cacheControlPlaylist {
+		t.Errorf("headers = %v", rec.Header())
+	}
+
+	rec = serve(variants, store, "360p", "playlist.m3u8")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rendition: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "\n/stream/360p/segment_000.ts\n") {
+		t.Errorf("relative segment not rewritten: %s", body)
+	}
+	if !strings.Contains(body, "\nhttps://other.example.com/segment_001.ts\n") {
+		t.Errorf("absolute segment should be left alone: %s", body)
+	}
+}
+
+func TestServeHLS_SegmentRedirect(t *testing.T) {
+	variants, store := setupPackage(t)
+
+	rec := serve(variants, store, "360p", "segment_000.ts")
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+	}
+	if loc := rec.Header().Get("Location"); !strings.Contains(loc, "p/hls_360p/segment_000.ts") {
+		t.Errorf("Location = %q", loc)
+	}
+}
+
+func TestServeHLS_NotFound(t *testing.T) {
+	variants, store := setupPackage(t)
+
+	tests := []struct{ rendition, name string }{
+		{"720p", "playlist.m3u8"}, // not packaged
+		{"2k", "playlist.m3u8"},   // not a rendition
+		{"360p", ".."},
+		{"360p", `..\master.m3u8`},
+		{"360p", ""},
+		{"", "other.m3u8"}, // not stored
+	}
+	for _, tt := range tests {
+		if rec := serve(variants, store, tt.rendition, tt.name); rec.Code != http.StatusNotFound {
+			t.Errorf("%q/%q: status = %d, want %d", tt.rendition, tt.name, rec.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package streaming
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const contentTypeVTT = "text/vtt; charset=utf-8"
+
+// ServeStoryboard serves name from the storyboard of fileID: the WebVTT
+// thumbnail track, with its cue images rewritten under base, or a sprite
+// sheet, which redirects to a short-lived presigned URL.
+func ServeStoryboard(w http.ResponseWriter, r *http.Request, queries VariantQuerier, store storage.Storage, fileID pgtype.UUID, name, base string) {
+	if !validName(name) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if name != video.StoryboardVTT {
+		sprite, err := queries.GetVariant(r.Context(), db.GetVariantParams{FileID: fileID, VariantType: db.VariantTypeVideoSprite})
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		redirectToObject(w, r, store, path.Join(path.Dir(sprite.StorageKey), name))
+		return
+	}
+
+	variant, err := queries.GetVariant(r.Context(), db.GetVariantParams{FileID: fileID, VariantType: db.VariantTypeStoryboardVtt})
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	data, ok := readObject(w, r, store, variant.StorageKey)
+	if !ok {
+		return
+	}
+
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	track := video.RewriteStoryboardVTT(data, func(uri string) string {
+		if isAbsoluteURI(uri) {
+			return uri
+		}
+		return prefix + uri
+	})
+
+	w.Header().Set("Content-Type", contentTypeVTT)
+	w.Header().Set("Cache-Control", cacheControlPlaylist)
+	_, _ = w.Write(track)
+}
@@ -0,0 +1,67 @@
+package streaming
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func serveStoryboard(t *testing.T, variants fakeVariants, name string) *httptest.ResponseRecorder {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	for key, body := range map[string]string{
+		"p/storyboard_vtt/storyboard.vtt": "WEBVTT\n\n00:00:00.000 --> 00:00:06.000\nsprite.jpg#xywh=0,0,160,90\n",
+		"p/video_sprite/sprite.jpg":       "jpeg",
+	} {
+		if err := store.Upload(context.Background(), key, strings.NewReader(body), "", int64(len(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	fileID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	ServeStoryboard(rec, httptest.NewRequest("GET", "/", nil), variants, store, fileID, name, "/stream")
+	return rec
+}
+
+func TestServeStoryboard(t *testing.T) {
+	variants := fakeVariants{
+		db.VariantTypeStoryboardVtt: "p/storyboard_vtt/storyboard.vtt",
+		db.VariantTypeVideoSprite:   "p/video_sprite/sprite.jpg",
+	}
+
+	rec := serveStoryboard(t, variants, "storyboard.vtt")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("track: status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "\n/stream/sprite.jpg#xywh=0,0,160,90\n") {
+		t.Errorf("cue image not rewritten:\n%s", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != contentTypeVTT {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+
+	rec = serveStoryboard(t, variants, "sprite.jpg")
+	if rec.Code != http.StatusTemporaryRedirect || !strings.Contains(rec.Header().Get("Location"), "p/video_sprite/sprite.jpg") {
+		t.Errorf("sprite: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestServeStoryboard_NotFound(t *testing.T) {
+	if rec := serveStoryboard(t, fakeVariants{}, "storyboard.vtt"); rec.Code != http.StatusNotFound {
+		t.Errorf("no storyboard: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := serveStoryboard(t, fakeVariants{}, "sprite.jpg"); rec.Code != http.StatusNotFound {
+		t.Errorf("no sprite: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := serveStoryboard(t, fakeVariants{db.VariantTypeVideoSprite: "p/video_sprite/sprite.jpg"}, ".."); rec.Code != http.StatusNotFound {
+		t.Errorf("invalid name: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
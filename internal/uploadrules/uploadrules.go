@@ -0,0 +1,287 @@
+// Package uploadrules decides which processing jobs run after an upload.
+// Every upload gets the default jobs for its content type; a user's upload
+// rules add more jobs for the files they match.
+package uploadrules
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/billing"
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Jobs lists the job names a rule can queue. "responsive" expands to the
+// sm, md, lg and xl sizes.
+var Jobs = []string{"thumbnail", "responsive", "sm", "md", "lg", "xl", "webp", "metadata", "hls"}
+
+var responsiveSizes = []string{"sm", "md", "lg", "xl"}
+
+// defaultJobs run for every upload they support: a thumbnail for images,
+// PDFs and videos, and metadata extraction for images.
+var defaultJobs = []string{"thumbnail", "metadata"}
+
+type Querier interface {
+	ListEnabledUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error)
+	CreateJob(ctx context.Context, arg db.CreateJobParams) (db.ProcessingJob, error)
+	IncrementTransformationCount(ctx context.Context, id pgtype.UUID) error
+	GetUserTransformationUsage(ctx context.Context, id pgtype.UUID) (db.GetUserTransformationUsageRow, error)
+}
+
+// File is an uploaded file as seen by rule matching.
+type File struct {
+	ID          uuid.UUID
+	Filename    string
+	ContentType string
+	FolderID    pgtype.UUID
+	Tags        []string
+}
+
+// PlannedJob is a job an upload will queue. Rule is empty for default jobs.
+type PlannedJob struct {
+	Name    string     `json:"name"`
+	JobType db.JobType `json:"job_type"`
+	Rule    string     `json:"rule,omitempty"`
+}
+
+// SkippedJob is a job a matching rule asked for that will not run.
+type SkippedJob struct {
+	Name   string `json:"name"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+type Plan struct {
+	MatchedRules []string     `json:"matched_rules"`
+	Jobs         []PlannedJob `json:"jobs"`
+	Skipped      []SkippedJob `json:"skipped,omitempty"`
+}
+
+// ValidJob reports whether name can be used in a rule.
+func ValidJob(name string) bool {
+	return slices.Contains(Jobs, name)
+}
+
+// Matches reports whether rule applies to f. Every condition the rule sets
+// must hold; a rule without conditions matches every upload. Content types
+// may end in /* to match a whole family, and filename patterns use
+// path.Match syntax. Both comparisons ignore case.
+func Matches(rule db.UploadRule, f File) bool {
+	if rule.ContentType != nil && !matchContentType(*rule.ContentType, f.ContentType) {
+		return false
+	}
+	if rule.FolderID.Valid && rule.FolderID != f.FolderID {
+		return false
+	}
+	if rule.Tag != nil && !slices.Contains(f.Tags, *rule.Tag) {
+		return false
+	}
+	if rule.FilenamePattern != nil {
+		ok, err := path.Match(strings.ToLower(*rule.FilenamePattern), strings.ToLower(f.Filename))
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matchContentType(pattern, contentType string) bool {
+	pattern = strings.ToLower(pattern)
+	contentType = strings.ToLower(contentType)
+	if family, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(contentType, family+"/")
+	}
+	return pattern == contentType
+}
+
+// BuildPlan works out the jobs for f from the default jobs and the rules that
+// match it. Jobs that do not apply to the file type or, when tier is set, are
+// not on the user's plan are reported as skipped. Each job runs once even if
+// several rules ask for it.
+func BuildPlan(rules []db.UploadRule, f File, tier db.SubscriptionTier) Plan {
+	plan := Plan{MatchedRules: []string{}, Jobs: []PlannedJob{}}
+	seen := make(map[string]bool)
+
+	for _, name := range defaultJobs {
+		if jobType, ok := jobTypeFor(name, f.ContentType); ok {
+			plan.Jobs = append(plan.Jobs, PlannedJob{Name: name, JobType: jobType})
+			seen[name] = true
+		}
+	}
+
+	for _, rule := range rules {
+		if !rule.Enabled || !Matches(rule, f) {
+			continue
+		}
+		plan.MatchedRules = append(plan.MatchedRules, rule.Name)
+
+		for _, name := range expandJobs(rule.Jobs) {
+			if seen[name] {
+				continue
+			}
+			jobType, ok := jobTypeFor(name, f.ContentType)
+			if !ok {
+				plan.Skipped = append(plan.Skipped, SkippedJob{Name: name, Rule: rule.Name, Reason: "not supported for " + f.ContentType})
+				continue
+			}
+			if tier != "" && !allowedOnTier(name, tier) {
+				plan.Skipped = append(plan.Skipped, SkippedJob{Name: name, Rule: rule.Name, Reason: "not available on your plan"})
+				continue
+			}
+			seen[name] = true
+			plan.Jobs = append(plan.Jobs, PlannedJob{Name: name, JobType: jobType, Rule: rule.Name})
+		}
+	}
+	return plan
+}
+
+func expandJobs(names []string) []string {
+	var out []string
+	for _, name := range names {
+		if name == "responsive" {
+			out = append(out, responsiveSizes...)
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// jobTypeFor maps a rule job name to the job queued for a content type.
+func jobTypeFor(name, contentType string) (db.JobType, bool) {
+	isImage := strings.HasPrefix(contentType, "image/")
+	isVideo := video.IsVideoType(contentType)
+
+	switch name {
+	case "thumbnail":
+		switch {
+		case isImage:
+			return db.JobTypeThumbnail, true
+		case contentType == "application/pdf":
+			return db.JobTypePdfThumbnail, true
+		case isVideo:
+			return db.JobTypeVideoThumbnail, true
+		}
+	case "sm", "md", "lg", "xl":
+		return db.JobTypeResize, isImage
+	case "webp":
+		return db.JobTypeWebp, isImage
+	case "metadata":
+		return db.JobTypeMetadata, isImage
+	case "hls":
+		return db.JobTypeVideoHls, isVideo
+	}
+	return "", false
+}
+
+func allowedOnTier(name string, tier db.SubscriptionTier) bool {
+	switch name {
+	case "metadata":
+		return true
+	case "hls":
+		return billing.GetTierLimits(tier).AdaptiveBitrate
+	}
+	return billing.CanUseFeature(tier, name)
+}
+
+func payloadFor(job PlannedJob, fileID uuid.UUID) worker.JobPayload {
+	switch job.JobType {
+	case db.JobTypeThumbnail:
+		p := worker.NewThumbnailPayload(fileID)
+		return &p
+	case db.JobTypePdfThumbnail:
+		p := worker.NewPDFThumbnailPayload(fileID)
+		return &p
+	case db.JobTypeVideoThumbnail:
+		p := worker.NewVideoThumbnailPayload(fileID)
+		return &p
+	case db.JobTypeResize:
+		p := worker.NewResponsivePayload(fileID, job.Name)
+		return &p
+	case db.JobTypeWebp:
+		p := worker.NewWebPPayload(fileID, 85)
+		return &p
+	case db.JobTypeMetadata:
+		p := worker.NewMetadataPayload(fileID)
+		return &p
+	case db.JobTypeVideoHls:
+		p := worker.NewVideoHLSPayload(fileID, nil)
+		return &p
+	}
+	return nil
+}
+
+// Enqueue queues the planned jobs for a new upload. A failure to load the
+// user's rules falls back to the default jobs, and failures are logged rather
+// than returned so an upload is never rejected because a job could not be
+// queued. Jobs queued by rules count against the transformation quota, and
+// those that would exceed it are skipped.
+func Enqueue(ctx context.Context, q Querier, broker worker.Broker, userID uuid.UUID, f File, tier db.SubscriptionTier, log *slog.Logger) []string {
+	pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+
+	rules, err := q.ListEnabledUploadRulesByUser(ctx, pgUserID)
+	if err != nil {
+		log.Warn("failed to load upload rules", "error", err)
+	}
+
+	plan := BuildPlan(rules, f, tier)
+	if len(plan.Jobs) == 0 {
+		log.Debug("no automatic processing for content type", "content_type", f.ContentType)
+		return nil
+	}
+
+	remaining := remainingTransformations(ctx, q, pgUserID, plan.Jobs, log)
+
+	var jobIDs []string
+	for _, job := range plan.Jobs {
+		if job.Rule != "" && remaining == 0 {
+			log.Warn("transformation limit reached, skipping upload rule job", "job", job.Name, "rule", job.Rule)
+			continue
+		}
+
+		jobID, err := worker.EnqueueWithTracking(ctx, q, broker, payloadFor(job, f.ID), job.JobType)
+		if err != nil {
+			log.Error("failed to enqueue upload job", "job", job.Name, "rule", job.Rule, "error", err)
+			continue
+		}
+		metrics.RecordJobEnqueued(string(job.JobType))
+		log.Info("upload job enqueued", "job", job.Name, "rule", job.Rule, "job_id", jobID)
+		jobIDs = append(jobIDs, jobID)
+
+		if job.Rule != "" {
+			if err := q.IncrementTransformationCount(ctx, pgUserID); err != nil {
+				log.Error("failed to increment transformation count", "error", err)
+			}
+			if remaining > 0 {
+				remaining--
+			}
+		}
+	}
+	return jobIDs
+}
+
+// remainingTransformations returns how many more rule jobs the user's quota
+// allows, or -1 when it is unlimited. Like the transform endpoint it lets
+// jobs through when the usage cannot be read.
+func remainingTransformations(ctx context.Context, q Querier, userID pgtype.UUID, jobs []PlannedJob, log *slog.Logger) int {
+	if !slices.ContainsFunc(jobs, func(j PlannedJob) bool { return j.Rule != "" }) {
+		return -1
+	}
+	usage, err := q.GetUserTransformationUsage(ctx, userID)
+	if err != nil {
+		log.Warn("failed to load transformation usage", "error", err)
+		return -1
+	}
+	if usage.TransformationsLimit == -1 {
+		return -1
+	}
+	return max(int(usage.TransformationsLimit)-int(usage.TransformationsCount), 0)
+}
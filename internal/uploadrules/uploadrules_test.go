@@ -0,0 +1,231 @@
+package uploadrules
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func strPtr(s string) *string { return &s }
+
+func jobNames(jobs []PlannedJob) []string {
+	names := make([]string, len(jobs))
+	for i, j := range jobs {
+		names[i] = j.Name
+	}
+	return names
+}
+
+func TestMatches(t *testing.T) {
+	folder := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	file := File{
+		Filename:    "Hero-Banner.JPG",
+		ContentType: "image/jpeg",
+		FolderID:    folder,
+		Tags:        []string{"marketing"},
+	}
+
+	tests := []struct {
+		name string
+		rule db.UploadRule
+		want bool
+	}{
+		{"no conditions", db.UploadRule{}, true},
+		{"exact content type", db.UploadRule{ContentType: strPtr("image/jpeg")}, true},
+		{"content type family", db.UploadRule{ContentType: strPtr("image/*")}, true},
+		{"other content type", db.UploadRule{ContentType: strPtr("video/*")}, false},
+		{"folder", db.UploadRule{FolderID: folder}, true},
+		{"other folder", db.UploadRule{FolderID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}, false},
+		{"tag", db.UploadRule{Tag: strPtr("marketing")}, true},
+		{"missing tag", db.UploadRule{Tag: strPtr("legal")}, false},
+		{"filename glob ignores case", db.UploadRule{FilenamePattern: strPtr("hero-*.jpg")}, true},
+		{"filename glob mismatch", db.UploadRule{FilenamePattern: strPtr("*.png")}, false},
+		{"all conditions", db.UploadRule{ContentType: strPtr("image/*"), FolderID: folder, Tag: strPtr("marketing"), FilenamePattern: strPtr("*.jpg")}, true},
+		{"one condition fails", db.UploadRule{ContentType: strPtr("image/*"), Tag: strPtr("legal")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.rule, file); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	rules := []db.UploadRule{
+		{Name: "images", ContentType: strPtr("image/*"), Jobs: []string{"responsive", "webp"}, Enabled: true},
+		{Name: "heroes", FilenamePattern: strPtr("hero*"), Jobs: []string{"lg", "metadata", "hls"}, Enabled: true},
+		{Name: "disabled", Jobs: []string{"xl"}, Enabled: false},
+		{Name: "videos", ContentType: strPtr("video/*"), Jobs: []string{"hls"}, Enabled: true},
+	}
+
+	t.Run("image", func(t *testing.T) {
+		plan := BuildPlan(rules, File{Filename: "hero.png", ContentType: "image/png"}, db.SubscriptionTierPro)
+
+		if want := []string{"images", "heroes"}; !slices.Equal(plan.MatchedRules, want) {
+			t.Errorf("matched = %v, want %v", plan.MatchedRules, want)
+		}
+		if want := []string{"thumbnail", "metadata", "sm", "md", "lg", "xl", "webp"}; !slices.Equal(jobNames(plan.Jobs), want) {
+			t.Errorf("jobs = %v, want %v", jobNames(plan.Jobs), want)
+		}
+		if plan.Jobs[0].Rule != "" || plan.Jobs[0].JobType != db.JobTypeThumbnail {
+			t.Errorf("default job = %+v, want thumbnail without rule", plan.Jobs[0])
+		}
+		if len(plan.Skipped) != 1 || plan.Skipped[0].Name != "hls" || plan.Skipped[0].Rule != "heroes" {
+			t.Errorf("skipped = %+v, want hls from heroes", plan.Skipped)
+		}
+	})
+
+	t.Run("video", func(t *testing.T) {
+		plan := BuildPlan(rules, File{Filename: "clip.mp4", ContentType: "video/mp4"}, db.SubscriptionTierPro)
+
+		if want := []string{"thumbnail", "hls"}; !slices.Equal(jobNames(plan.Jobs), want) {
+			t.Errorf("jobs = %v, want %v", jobNames(plan.Jobs), want)
+		}
+		if plan.Jobs[0].JobType != db.JobTypeVideoThumbnail || plan.Jobs[1].JobType != db.JobTypeVideoHls {
+			t.Errorf("job types = %s, %s", plan.Jobs[0].JobType, plan.Jobs[1].JobType)
+		}
+	})
+
+	t.Run("free tier", func(t *testing.T) {
+		plan := BuildPlan(rules, File{Filename: "clip.mp4", ContentType: "video/mp4"}, db.SubscriptionTierFree)
+
+		if want := []string{"thumbnail"}; !slices.Equal(jobNames(plan.Jobs), want) {
+			t.Errorf("jobs = %v, want %v", jobNames(plan.Jobs), want)
+		}
+		if len(plan.Skipped) != 1 || plan.Skipped[0].Reason != "not available on your plan" {
+			t.Errorf("skipped = %+v, want hls not on plan", plan.Skipped)
+		}
+	})
+
+	t.Run("unsupported type has no default job", func(t *testing.T) {
+		plan := BuildPlan(nil, File{Filename: "notes.txt", ContentType: "text/plain"}, "")
+		if len(plan.Jobs) != 0 || len(plan.MatchedRules) != 0 {
+			t.Errorf("plan = %+v, want empty", plan)
+		}
+	})
+}
+
+type mockQuerier struct {
+	rules      []db.UploadRule
+	rulesErr   error
+	usage      db.GetUserTransformationUsageRow
+	usageErr   error
+	jobs       []db.JobType
+	increments int
+}
+
+func (m *mockQuerier) ListEnabledUploadRulesByUser(ctx context.Context, userID pgtype.UUID) ([]db.UploadRule, error) {
+	return m.rules, m.rulesErr
+}
+
+func (m *mockQuerier) CreateJob(ctx context.Context, arg db.CreateJobParams) (db.ProcessingJob, error) {
+	m.jobs = append(m.jobs, arg.JobType)
+	return db.ProcessingJob{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, FileID: arg.FileID, JobType: arg.JobType}, nil
+}
+
+func (m *mockQuerier) IncrementTransformationCount(ctx context.Context, id pgtype.UUID) error {
+	m.increments++
+	m.usage.TransformationsCount++
+	return nil
+}
+
+func (m *mockQuerier) GetUserTransformationUsage(ctx context.Context, id pgtype.UUID) (db.GetUserTransformationUsageRow, error) {
+	return m.usage, m.usageErr
+}
+
+type mockBroker struct {
+	jobs []string
+}
+
+func (b *mockBroker) Enqueue(jobType string, payload interface{}) (string, error) {
+	b.jobs = append(b.jobs, jobType)
+	return uuid.New().String(), nil
+}
+
+func TestEnqueue(t *testing.T) {
+	q := &mockQuerier{
+		rules: []db.UploadRule{
+			{Name: "images", ContentType: strPtr("image/*"), Jobs: []string{"webp", "metadata", "sm"}, Enabled: true},
+		},
+		usage: db.GetUserTransformationUsageRow{TransformationsLimit: -1},
+	}
+	broker := &mockBroker{}
+	file := File{ID: uuid.New(), Filename: "photo.jpg", ContentType: "image/jpeg"}
+
+	ids := Enqueue(context.Background(), q, broker, uuid.New(), file, db.SubscriptionTierPro, slog.Default())
+
+	if len(ids) != 4 {
+		t.Fatalf("enqueued %d jobs, want 4", len(ids))
+	}
+	if want := []string{"thumbnail", "metadata", "webp", "resize"}; !slices.Equal(broker.jobs, want) {
+		t.Errorf("broker jobs = %v, want %v", broker.jobs, want)
+	}
+	if want := []db.JobType{db.JobTypeThumbnail, db.JobTypeMetadata, db.JobTypeWebp, db.JobTypeResize}; !slices.Equal(q.jobs, want) {
+		t.Errorf("tracked jobs = %v, want %v", q.jobs, want)
+	}
+	if q.increments != 2 {
+		t.Errorf("transformation count incremented %d times, want 2 (rule jobs only)", q.increments)
+	}
+}
+
+func TestEnqueue_TransformationLimit(t *testing.T) {
+	rules := []db.UploadRule{
+		{Name: "sizes", ContentType: strPtr("image/*"), Jobs: []string{"webp", "sm", "md"}, Enabled: true},
+	}
+	file := File{ID: uuid.New(), Filename: "photo.jpg", ContentType: "image/jpeg"}
+
+	tests := []struct {
+		name      string
+		usage     db.GetUserTransformationUsageRow
+		usageErr  error
+		wantJobs  []string
+		wantCount int
+	}{
+		{"partly over the limit", db.GetUserTransformationUsageRow{TransformationsCount: 9, TransformationsLimit: 10}, nil,
+			[]string{"thumbnail", "metadata", "webp"}, 1},
+		{"limit reached", db.GetUserTransformationUsageRow{TransformationsCount: 12, TransformationsLimit: 10}, nil,
+			[]string{"thumbnail", "metadata"}, 0},
+		{"usage unavailable", db.GetUserTransformationUsageRow{}, errors.New("db down"),
+			[]string{"thumbnail", "metadata", "webp", "resize", "resize"}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &mockQuerier{rules: rules, usage: tt.usage, usageErr: tt.usageErr}
+			broker := &mockBroker{}
+
+			Enqueue(context.Background(), q, broker, uuid.New(), file, db.SubscriptionTierPro, slog.Default())
+
+			if !slices.Equal(broker.jobs, tt.wantJobs) {
+				t.Errorf("broker jobs = %v, want %v", broker.jobs, tt.wantJobs)
+			}
+			if q.increments != tt.wantCount {
+				t.Errorf("transformation count incremented %d times, want %d", q.increments, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestEnqueue_FallsBackToDefaultWhenRulesFail(t *testing.T) {
+	q := &mockQuerier{rulesErr: errors.New("db down")}
+	broker := &mockBroker{}
+	file := File{ID: uuid.New(), Filename: "doc.pdf", ContentType: "application/pdf"}
+
+	Enqueue(context.Background(), q, broker, uuid.New(), file, "", slog.Default())
+
+	if want := []string{"pdf_thumbnail"}; !slices.Equal(broker.jobs, want) {
+		t.Errorf("broker jobs = %v, want %v", broker.jobs, want)
+	}
+	if q.increments != 0 {
+		t.Errorf("default jobs should not count as a transformation")
+	}
+}
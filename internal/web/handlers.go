@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
@@ -14,14 +15,21 @@ import (
 	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/auth"
 	"github.com/abdul-hamid-achik/file.cheap/internal/billing"
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/email"
+	"github.com/abdul-hamid-achik/file.cheap/internal/filetype"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/abdul-hamid-achik/file.cheap/internal/streaming"
+	"github.com/abdul-hamid-achik/file.cheap/internal/uploadrules"
 	"github.com/abdul-hamid-achik/file.cheap/internal/web/templates/components"
 	"github.com/abdul-hamid-achik/file.cheap/internal/web/templates/pages"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
 	"github.com/abdul-hamid-achik/file.cheap/internal/worker"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -99,6 +107,14 @@ func (h *Handlers) LoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       user.ID.Bytes,
+		Action:       audit.ActionUserLogin,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.Bytes,
+		Metadata:     map[string]any{"method": "password"},
+	})
+
 	if returnURL == "" {
 		returnURL = "/dashboard"
 	}
@@ -168,6 +184,14 @@ func (h *Handlers) RegisterPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	if user, _ := h.sessionManager.GetSession(r.Context(), r); user != nil {
+		audit.Record(r, audit.Entry{
+			UserID:       user.ID,
+			Action:       audit.ActionUserLogout,
+			ResourceType: audit.ResourceUser,
+			ResourceID:   user.ID,
+		})
+	}
 	_ = h.sessionManager.DeleteSession(r.Context(), w, r)
 	http.Redirect(w, r, "/", http.StatusFound)
 }
@@ -231,7 +255,7 @@ func (h *Handlers) ResetPasswordPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.authService.ResetPassword(r.Context(), token, password)
+	userID, err := h.authService.ResetPassword(r.Context(), token, password)
 	if err != nil {
 		errMsg := "Failed to reset password. The link may have expired."
 		if strings.Contains(err.Error(), "weak") || strings.Contains(err.Error(), "password") {
@@ -245,6 +269,14 @@ func (h *Handlers) ResetPasswordPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       userID,
+		Action:       audit.ActionUserPasswordChange,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   userID,
+		Metadata:     map[string]any{"method": "reset"},
+	})
+
 	http.Redirect(w, r, "/login?success=password_reset", http.StatusSeeOther)
 }
 
@@ -412,7 +444,14 @@ func (h *Handlers) UploadFileAPI(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	placement, appErr := h.parseUploadPlacement(r.Context(), user.ID, r.FormValue("folder_id"), r.FormValue("tags"))
+	if appErr != nil {
+		apperror.WriteJSON(w, r, appErr)
+		return
+	}
+
 	var results []map[string]any
+	var rejected []string
 
 	for _, fileHeader := range files {
 		file, err := fileHeader.Open()
@@ -421,13 +460,29 @@ func (h *Handlers) UploadFileAPI(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		detectedType, body, err := filetype.Sniff(file)
+		if err != nil {
+			_ = file.Close()
+			log.Error("failed to read uploaded file", "filename", fileHeader.Filename, "error", err)
+			continue
+		}
+		contentType, err := filetype.Resolve(fileHeader.Header.Get("Content-Type"), detectedType)
+		if err != nil {
+			_ = file.Close()
+			log.Warn("upload content rejected", "filename", fileHeader.Filename,
+				"declared_type", fileHeader.Header.Get("Content-Type"), "detected_type", detectedType)
+			rejected = append(rejected, fileHeader.Filename)
+			continue
+		}
+
 		fileID := uuid.New()
 		storageKey := fmt.Sprintf("uploads/%s/%s/%s", user.ID.String(), fileID.String(), fileHeader.Filename)
 
-		log.Info("uploading file", "filename", fileHeader.Filename, "size", fileHeader.Size)
+		log.Info("uploading file", "filename", fileHeader.Filename, "size", fileHeader.Size, "content_type", contentType)
 
+		hashingBody := storage.NewHashingReader(body)
 		uploadStart := time.Now()
-		if err := h.cfg.Storage.Upload(r.Context(), storageKey, file, fileHeader.Header.Get("Content-Type"), fileHeader.Size); err != nil {
+		if err := h.cfg.Storage.Upload(r.Context(), storageKey, hashingBody, contentType, fileHeader.Size); err != nil {
 			_ = file.Close()
 			metrics.RecordFileUpload("error", 0, 0)
 			log.Error("storage upload failed", "filename", fileHeader.Filename, "error", err)
@@ -435,13 +490,9 @@ func (h *Handlers) UploadFileAPI(w http.ResponseWriter, r *http.Request) {
 		}
 		_ = file.Close()
 		metrics.RecordFileUpload("success", fileHeader.Size, time.Since(uploadStart).Seconds())
+		contentHash := hashingBody.Sum()
 
 		if h.cfg.Queries != nil {
-			contentType := fileHeader.Header.Get("Content-Type")
-			if contentType == "" {
-				contentType = "application/octet-stream"
-			}
-
 			pgUserID := pgtype.UUID{
 				Bytes: user.ID,
 				Valid: true,
@@ -454,6 +505,7 @@ func (h *Handlers) UploadFileAPI(w http.ResponseWriter, r *http.Request) {
 				SizeBytes:   fileHeader.Size,
 				StorageKey:  storageKey,
 				Status:      db.FileStatusPending,
+				ContentHash: &contentHash,
 			})
 			if err != nil {
 				log.Error("database create file failed", "filename", fileHeader.Filename, "error", err)
@@ -470,30 +522,28 @@ func (h *Handlers) UploadFileAPI(w http.ResponseWriter, r *http.Request) {
 			})
 
 			log.Info("file created", "file_id", dbFileID.String())
+			h.applyUploadPlacement(r.Context(), placement, user.ID, dbFile.ID, log)
+			audit.Record(r, audit.Entry{
+				UserID:       user.ID,
+				Action:       audit.ActionFileUpload,
+				ResourceType: audit.ResourceFile,
+				ResourceID:   dbFileID,
+				Metadata: map[string]any{
+					"filename":     dbFile.Filename,
+					"content_type": dbFile.ContentType,
+					"size_bytes":   dbFile.SizeBytes,
+					"method":       "web",
+				},
+			})
 
 			if h.cfg.Broker != nil {
-				switch {
-				case strings.HasPrefix(contentType, "image/"):
-					payload := worker.NewThumbnailPayload(dbFileID)
-					jobID, err := worker.EnqueueWithTracking(r.Context(), h.cfg.Queries, h.cfg.Broker, &payload, db.JobTypeThumbnail)
-					if err != nil {
-						log.Error("failed to enqueue thumbnail job", "error", err)
-					} else {
-						metrics.RecordJobEnqueued("thumbnail")
-						log.Info("thumbnail job enqueued", "job_id", jobID, "file_id", dbFileID.String())
-					}
-				case contentType == "application/pdf":
-					payload := worker.NewPDFThumbnailPayload(dbFileID)
-					jobID, err := worker.EnqueueWithTracking(r.Context(), h.cfg.Queries, h.cfg.Broker, &payload, db.JobTypePdfThumbnail)
-					if err != nil {
-						log.Error("failed to enqueue pdf_thumbnail job", "error", err)
-					} else {
-						metrics.RecordJobEnqueued("pdf_thumbnail")
-						log.Info("pdf_thumbnail job enqueued", "job_id", jobID, "file_id", dbFileID.String())
-					}
-				default:
-					log.Debug("no automatic processing for content type", "content_type", contentType)
-				}
+				uploadrules.Enqueue(r.Context(), h.cfg.Queries, h.cfg.Broker, user.ID, uploadrules.File{
+					ID:          dbFileID,
+					Filename:    dbFile.Filename,
+					ContentType: contentType,
+					FolderID:    placement.FolderID,
+					Tags:        placement.Tags,
+				}, user.SubscriptionTier, log.With("file_id", dbFileID.String()))
 			}
 		} else {
 			results = append(results, map[string]any{
@@ -505,16 +555,84 @@ func (h *Handlers) UploadFileAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(results) == 0 {
+		if len(rejected) > 0 {
+			apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "content_type_mismatch",
+				fmt.Sprintf("File content does not match its declared type: %s", strings.Join(rejected, ", ")),
+				http.StatusBadRequest))
+			return
+		}
 		apperror.WriteJSON(w, r, apperror.WrapWithMessage(nil, "upload_failed", "Failed to upload files", http.StatusInternalServerError))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	_ = json.NewEncoder(w).Encode(map[string]any{
+	response := map[string]any{
 		"files":   results,
 		"message": fmt.Sprintf("Successfully uploaded %d file(s)", len(results)),
-	})
+	}
+	if len(rejected) > 0 {
+		response["rejected"] = rejected
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// uploadPlacement is the folder and tags a web upload is filed under.
+type uploadPlacement struct {
+	FolderID pgtype.UUID
+	Tags     []string
+}
+
+// parseUploadPlacement validates the folder_id and comma separated tags sent
+// with a web upload, with the same limits as the API. The folder must belong
+// to the user.
+func (h *Handlers) parseUploadPlacement(ctx context.Context, userID uuid.UUID, folderID, tags string) (uploadPlacement, *apperror.Error) {
+	var p uploadPlacement
+
+	if folderID != "" {
+		id, err := uuid.Parse(folderID)
+		if err != nil {
+			return p, apperror.WrapWithMessage(err, "invalid_folder_id", "Invalid folder ID", http.StatusBadRequest)
+		}
+		p.FolderID = pgtype.UUID{Bytes: id, Valid: true}
+		if h.cfg.Queries != nil {
+			if _, err := h.cfg.Queries.GetFolder(ctx, db.GetFolderParams{ID: p.FolderID, UserID: pgtype.UUID{Bytes: userID, Valid: true}}); err != nil {
+				return p, apperror.WrapWithMessage(err, "folder_not_found", "Folder not found", http.StatusNotFound)
+			}
+		}
+	}
+
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > 100 {
+			return p, apperror.WrapWithMessage(nil, "invalid_tag", "Tags must be at most 100 characters", http.StatusBadRequest)
+		}
+		p.Tags = append(p.Tags, tag)
+	}
+	if len(p.Tags) > 20 {
+		return p, apperror.WrapWithMessage(nil, "too_many_tags", "Maximum 20 tags per upload", http.StatusBadRequest)
+	}
+	return p, nil
+}
+
+// applyUploadPlacement files a new upload under its folder and tags.
+// Failures are logged so the upload itself still succeeds.
+func (h *Handlers) applyUploadPlacement(ctx context.Context, p uploadPlacement, userID uuid.UUID, fileID pgtype.UUID, log *slog.Logger) {
+	pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+	if p.FolderID.Valid {
+		if err := h.cfg.Queries.MoveFileToFolder(ctx, db.MoveFileToFolderParams{ID: fileID, UserID: pgUserID, FolderID: p.FolderID}); err != nil {
+			log.Error("failed to move upload to folder", "error", err)
+		}
+	}
+	for _, tag := range p.Tags {
+		if _, err := h.cfg.Queries.CreateFileTag(ctx, db.CreateFileTagParams{FileID: fileID, UserID: pgUserID, TagName: tag}); err != nil {
+			log.Debug("failed to tag upload", "tag", tag, "error", err)
+		}
+	}
 }
 
 func (h *Handlers) FileList(w http.ResponseWriter, r *http.Request) {
@@ -733,7 +851,7 @@ func (h *Handlers) FileDetail(w http.ResponseWriter, r *http.Request) {
 		streamURL := ""
 		for _, v := range variants {
 			if string(v.VariantType) == "hls_master" {
-				streamURL = "/files/" + fileIDStr + "/download?variant=hls_master"
+				streamURL = "/files/" + fileIDStr + "/hls/master.m3u8"
 				break
 			}
 		}
@@ -820,9 +938,35 @@ func (h *Handlers) DeleteFile(w http.ResponseWriter, r *http.Request) {
 
 	metrics.RecordFileDeletion("success")
 	log.Info("file deleted", "file_id", fileIDStr, "user_id", user.ID.String())
+	audit.Record(r, audit.Entry{
+		UserID:       user.ID,
+		Action:       audit.ActionFileDelete,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   fileID,
+		Metadata:     map[string]any{"filename": file.Filename},
+	})
+	h.dispatchFileDeleted(r, user.ID, fileIDStr, file.Filename)
 	http.Redirect(w, r, "/files?success=deleted", http.StatusFound)
 }
 
+// dispatchFileDeleted announces a deleted file to the user's webhooks in the
+// background.
+func (h *Handlers) dispatchFileDeleted(r *http.Request, userID uuid.UUID, fileID, filename string) {
+	if h.cfg.WebhookDispatcher == nil {
+		return
+	}
+	event, err := webhook.NewFileDeletedEvent(fileID, filename)
+	if err != nil {
+		return
+	}
+	log := logger.FromContext(r.Context())
+	go func() {
+		if err := h.cfg.WebhookDispatcher.Dispatch(context.Background(), userID, event); err != nil {
+			log.Debug("webhook dispatch failed", "event", event.Type, "error", err)
+		}
+	}()
+}
+
 func (h *Handlers) ProcessFile(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	user := auth.GetUserFromContext(r.Context())
@@ -1013,6 +1157,7 @@ func (h *Handlers) ProcessFile(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		p := worker.NewVideoHLSPayload(fileID, resolutions)
+		p.MaxResolution = billing.GetTierLimits(user.SubscriptionTier).MaxVideoResolution
 		payload = &p
 	default:
 		http.Error(w, "Invalid action", http.StatusBadRequest)
@@ -1253,6 +1398,18 @@ func (h *Handlers) DownloadFile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = reader.Close() }()
 
+	metadata := map[string]any{"filename": file.Filename}
+	if variantType != "" {
+		metadata["variant"] = variantType
+	}
+	audit.Record(r, audit.Entry{
+		UserID:       user.ID,
+		Action:       audit.ActionFileDownload,
+		ResourceType: audit.ResourceFile,
+		ResourceID:   fileID,
+		Metadata:     metadata,
+	})
+
 	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
 	w.Header().Set("Cache-Control", "public, max-age=31536000")
@@ -1407,6 +1564,7 @@ func (h *Handlers) ProfilePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordSettingsUpdate(r, user.ID, "profile")
 	http.Redirect(w, r, "/profile?success=profile_updated", http.StatusFound)
 }
 
@@ -1602,6 +1760,14 @@ func (h *Handlers) SettingsPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       user.ID,
+		Action:       audit.ActionUserPasswordChange,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID,
+		Metadata:     map[string]any{"method": "settings"},
+	})
+
 	http.Redirect(w, r, "/settings?password_success=1&tab=security", http.StatusFound)
 }
 
@@ -1626,6 +1792,7 @@ func (h *Handlers) SettingsNotifications(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	recordSettingsUpdate(r, user.ID, "notifications")
 	http.Redirect(w, r, "/settings?success=1&tab=notifications", http.StatusFound)
 }
 
@@ -1654,9 +1821,21 @@ func (h *Handlers) SettingsFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordSettingsUpdate(r, user.ID, "files")
 	http.Redirect(w, r, "/settings?success=1&tab=files", http.StatusFound)
 }
 
+// recordSettingsUpdate audits a change to one section of a user's settings.
+func recordSettingsUpdate(r *http.Request, userID uuid.UUID, section string) {
+	audit.Record(r, audit.Entry{
+		UserID:       userID,
+		Action:       audit.ActionSettingsUpdate,
+		ResourceType: audit.ResourceSettings,
+		ResourceID:   userID,
+		Metadata:     map[string]any{"section": section},
+	})
+}
+
 func (h *Handlers) SettingsCreateToken(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if user == nil {
@@ -1706,12 +1885,20 @@ func (h *Handlers) SettingsCreateToken(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt:   expiresAt,
 	}
 
-	rawToken, _, err := h.authService.CreateAPIToken(r.Context(), user.ID, input)
+	rawToken, token, err := h.authService.CreateAPIToken(r.Context(), user.ID, input)
 	if err != nil {
 		http.Redirect(w, r, "/settings?error=1", http.StatusFound)
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       user.ID,
+		Action:       audit.ActionAPITokenCreate,
+		ResourceType: audit.ResourceAPIToken,
+		ResourceID:   token.ID.Bytes,
+		Metadata:     map[string]any{"name": name, "permissions": permissions},
+	})
+
 	h.sessionManager.SetFlash(w, "new_token", rawToken)
 	http.Redirect(w, r, "/settings?token_created=1&tab=api", http.StatusFound)
 }
@@ -1735,6 +1922,13 @@ func (h *Handlers) SettingsDeleteToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       user.ID,
+		Action:       audit.ActionAPITokenDelete,
+		ResourceType: audit.ResourceAPIToken,
+		ResourceID:   tokenID,
+	})
+
 	http.Redirect(w, r, "/settings?token_deleted=1&tab=api", http.StatusFound)
 }
 
@@ -1992,9 +2186,9 @@ func (h *Handlers) BatchDeleteFiles(w http.ResponseWriter, r *http.Request) {
 		}
 		_ = h.cfg.Queries.DeleteVariantsByFile(r.Context(), pgFileID)
 
-		// Delete file from storage
+		// Delete file from storage unless a deduplicated copy still uses it
 		if h.cfg.Storage != nil {
-			_ = h.cfg.Storage.Delete(r.Context(), file.StorageKey)
+			_, _ = worker.ReleaseStorageObject(r.Context(), h.cfg.Queries, h.cfg.Storage, pgFileID, file.StorageKey)
 		}
 
 		// Soft delete file record
@@ -2005,6 +2199,14 @@ func (h *Handlers) BatchDeleteFiles(w http.ResponseWriter, r *http.Request) {
 		}
 
 		metrics.RecordFileDeletion("success")
+		audit.Record(r, audit.Entry{
+			UserID:       user.ID,
+			Action:       audit.ActionFileDelete,
+			ResourceType: audit.ResourceFile,
+			ResourceID:   fileID,
+			Metadata:     map[string]any{"filename": file.Filename, "batch": true},
+		})
+		h.dispatchFileDeleted(r, user.ID, fileIDStr, file.Filename)
 		deletedCount++
 	}
 
@@ -2208,11 +2410,14 @@ func (h *Handlers) VideoEmbed(w http.ResponseWriter, r *http.Request) {
 
 	for _, v := range variants {
 		if string(v.VariantType) == "hls_master" {
-			data.StreamURL = h.cfg.BaseURL + "/files/" + fileIDStr + "/download?variant=hls_master"
+			data.StreamURL = h.cfg.BaseURL + "/files/" + fileIDStr + "/hls/master.m3u8"
 		}
 		if v.VariantType == db.VariantTypeThumbnail || string(v.VariantType) == "video_thumbnail" {
 			data.PosterURL = h.cfg.BaseURL + "/files/" + fileIDStr + "/download?variant=" + string(v.VariantType)
 		}
+		if v.VariantType == db.VariantTypeStoryboardVtt {
+			data.StoryboardURL = h.cfg.BaseURL + "/files/" + fileIDStr + "/storyboard/" + video.StoryboardVTT
+		}
 	}
 
 	if data.StreamURL == "" {
@@ -2222,6 +2427,69 @@ func (h *Handlers) VideoEmbed(w http.ResponseWriter, r *http.Request) {
 	_ = pages.VideoEmbedPage(data).Render(r.Context(), w)
 }
 
+// StreamHLS serves a video's HLS playlists and segments to its owner,
+// rewriting playlist URIs so the player keeps fetching through this route.
+func (h *Handlers) StreamHLS(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fileIDStr := r.PathValue("id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	if h.cfg.Queries == nil || h.cfg.Storage == nil {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+	file, err := h.cfg.Queries.GetFile(r.Context(), pgFileID)
+	if err != nil || file.UserID.Bytes != user.ID || file.DeletedAt.Valid {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	streaming.ServeHLS(w, r, h.cfg.Queries, h.cfg.Storage, pgFileID, r.PathValue("rendition"), r.PathValue("segment"), "/files/"+fileIDStr+"/hls")
+}
+
+// StreamStoryboard serves a video's WebVTT thumbnail track and sprite sheet
+// to its owner, rewriting cue image URLs so the player loads the sheet
+// through this route.
+func (h *Handlers) StreamStoryboard(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fileIDStr := r.PathValue("id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	if h.cfg.Queries == nil || h.cfg.Storage == nil {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	pgFileID := pgtype.UUID{Bytes: fileID, Valid: true}
+	file, err := h.cfg.Queries.GetFile(r.Context(), pgFileID)
+	if err != nil || file.UserID.Bytes != user.ID || file.DeletedAt.Valid {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	streaming.ServeStoryboard(w, r, h.cfg.Queries, h.cfg.Storage, pgFileID, r.PathValue("name"), "/files/"+fileIDStr+"/storyboard")
+}
+
 // FileInfo returns metadata for a file (PDF page count, video duration, dimensions)
 func (h *Handlers) FileInfo(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
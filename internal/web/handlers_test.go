@@ -143,6 +143,22 @@ func (m *MockStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 	return io.NopCloser(bytes.NewReader(data)), nil
 }
 
+func (m *MockStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	rc, err := m.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := io.ReadAll(rc)
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
 func (m *MockStorage) Delete(ctx context.Context, key string) error {
 	if m.DeleteErr != nil {
 		return m.DeleteErr
@@ -164,6 +180,20 @@ func (m *MockStorage) GetPresignedURL(ctx context.Context, key string, expirySec
 	return "http://localhost:9000/" + key, nil
 }
 
+func (m *MockStorage) GetPresignedUploadURL(ctx context.Context, key, contentType string, expirySeconds int) (string, error) {
+	return "http://localhost:9000/" + key + "?upload=1", nil
+}
+
+func (m *MockStorage) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.files[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &storage.ObjectInfo{Size: int64(len(data))}, nil
+}
+
 func (m *MockStorage) HealthCheck(ctx context.Context) error {
 	return nil
 }
@@ -663,3 +693,43 @@ func TestVideoEmbedHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestParseUploadPlacement(t *testing.T) {
+	h, _, _ := createTestHandlers()
+	folderID := uuid.New()
+
+	tests := []struct {
+		name     string
+		folderID string
+		tags     string
+		wantCode string
+		wantTags []string
+	}{
+		{name: "folder and tags", folderID: folderID.String(), tags: " product, hero ,,", wantTags: []string{"product", "hero"}},
+		{name: "nothing", wantTags: nil},
+		{name: "invalid folder", folderID: "inbox", wantCode: "invalid_folder_id"},
+		{name: "tag too long", tags: strings.Repeat("a", 101), wantCode: "invalid_tag"},
+		{name: "too many tags", tags: strings.Repeat("t,", 21), wantCode: "too_many_tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, appErr := h.parseUploadPlacement(context.Background(), uuid.New(), tt.folderID, tt.tags)
+			if tt.wantCode != "" {
+				if appErr == nil || appErr.Code != tt.wantCode {
+					t.Fatalf("error = %v, want %s", appErr, tt.wantCode)
+				}
+				return
+			}
+			if appErr != nil {
+				t.Fatalf("unexpected error: %v", appErr)
+			}
+			if strings.Join(p.Tags, ",") != strings.Join(tt.wantTags, ",") {
+				t.Errorf("tags = %v, want %v", p.Tags, tt.wantTags)
+			}
+			if tt.folderID != "" && uuid.UUID(p.FolderID.Bytes) != folderID {
+				t.Errorf("folder = %v, want %s", p.FolderID, folderID)
+			}
+		})
+	}
+}
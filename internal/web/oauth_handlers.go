@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/apperror"
+	"github.com/abdul-hamid-achik/file.cheap/internal/audit"
 	"github.com/abdul-hamid-achik/file.cheap/internal/auth"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -135,6 +136,14 @@ func (h *Handlers) OAuthGoogleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       result.User.ID.Bytes,
+		Action:       audit.ActionUserLogin,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   result.User.ID.Bytes,
+		Metadata:     map[string]any{"method": "google"},
+	})
+
 	http.Redirect(w, r, "/dashboard", http.StatusFound)
 }
 
@@ -262,6 +271,14 @@ func (h *Handlers) OAuthGitHubCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:       result.User.ID.Bytes,
+		Action:       audit.ActionUserLogin,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   result.User.ID.Bytes,
+		Metadata:     map[string]any{"method": "github"},
+	})
+
 	http.Redirect(w, r, "/dashboard", http.StatusFound)
 }
 
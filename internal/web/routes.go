@@ -7,6 +7,7 @@ import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
 	"github.com/abdul-hamid-achik/file.cheap/internal/email"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
 )
 
 type Broker interface {
@@ -19,6 +20,8 @@ type Config struct {
 	Broker  Broker
 	BaseURL string
 	Secure  bool
+	// WebhookDispatcher, when set, announces file deletions to webhooks.
+	WebhookDispatcher *webhook.Dispatcher
 }
 
 func NewRouter(cfg *Config, sm *auth.SessionManager, authSvc *auth.Service, oauthSvc *auth.OAuthService, emailSvc *email.Service, billingHandlers *BillingHandlers, analyticsHandlers *AnalyticsHandlers, adminHandlers *AdminHandlers, enterpriseHandlers *EnterpriseHandlers) http.Handler {
@@ -57,6 +60,9 @@ func NewRouter(cfg *Config, sm *auth.SessionManager, authSvc *auth.Service, oaut
 		mux.Handle("GET /files", requireAuth(http.HandlerFunc(h.FileList)))
 		mux.Handle("GET /files/{id}", requireAuth(http.HandlerFunc(h.FileDetail)))
 		mux.Handle("GET /files/{id}/download", requireAuth(http.HandlerFunc(h.DownloadFile)))
+		mux.Handle("GET /files/{id}/hls/{segment}", requireAuth(http.HandlerFunc(h.StreamHLS)))
+		mux.Handle("GET /files/{id}/hls/{rendition}/{segment}", requireAuth(http.HandlerFunc(h.StreamHLS)))
+		mux.Handle("GET /files/{id}/storyboard/{name}", requireAuth(http.HandlerFunc(h.StreamStoryboard)))
 		mux.Handle("POST /files/{id}/delete", requireAuth(http.HandlerFunc(h.DeleteFile)))
 		mux.Handle("POST /files/{id}/process", requireAuth(http.HandlerFunc(h.ProcessFile)))
 		mux.Handle("POST /files/{id}/process-bundle", requireAuth(http.HandlerFunc(h.ProcessBundle)))
@@ -1,146 +1,124 @@
 package webhook
 
 import (
-	"sync"
+	"context"
+	"errors"
 	"time"
-)
 
-type circuitState int
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
+// Circuit states as stored in webhooks.circuit_state.
 const (
-	stateClosed circuitState = iota
-	stateOpen
-	stateHalfOpen
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
 )
 
-type endpointHealth struct {
-	failures    int
-	lastFailure time.Time
-	state       circuitState
+// CircuitQuerier is the subset of db.Queries the circuit breaker needs.
+type CircuitQuerier interface {
+	GetWebhookCircuitState(ctx context.Context, id pgtype.UUID) (db.GetWebhookCircuitStateRow, error)
+	HalfOpenWebhookCircuit(ctx context.Context, arg db.HalfOpenWebhookCircuitParams) error
+	IncrementWebhookFailures(ctx context.Context, arg db.IncrementWebhookFailuresParams) (db.IncrementWebhookFailuresRow, error)
+	ResetWebhookFailures(ctx context.Context, id pgtype.UUID) error
+	DisableFailingWebhook(ctx context.Context, arg db.DisableFailingWebhookParams) (db.Webhook, error)
 }
 
-// CircuitBreaker implements a circuit breaker pattern for webhook endpoints
+// CircuitBreaker implements a circuit breaker pattern for webhook endpoints.
+// State lives on the webhooks row, so every worker sees the same circuit and
+// it survives restarts.
 type CircuitBreaker struct {
-	mu               sync.RWMutex
-	endpoints        map[string]*endpointHealth
+	queries          CircuitQuerier
 	failureThreshold int
 	recoveryTime     time.Duration
+	disableAfter     time.Duration
 }
 
-// NewCircuitBreaker creates a new circuit breaker with default settings
-func NewCircuitBreaker() *CircuitBreaker {
-	return &CircuitBreaker{
-		endpoints:        make(map[string]*endpointHealth),
-		failureThreshold: 5,
-		recoveryTime:     30 * time.Minute,
-	}
+// NewCircuitBreaker creates a new circuit breaker with default settings: the
+// circuit opens after 5 consecutive failures, lets a probe through after 30
+// minutes, and the webhook is disabled once it has failed for 3 days.
+func NewCircuitBreaker(queries CircuitQuerier) *CircuitBreaker {
+	return NewCircuitBreakerWithConfig(queries, 5, 30*time.Minute, 72*time.Hour)
 }
 
 // NewCircuitBreakerWithConfig creates a circuit breaker with custom settings
-func NewCircuitBreakerWithConfig(failureThreshold int, recoveryTime time.Duration) *CircuitBreaker {
+func NewCircuitBreakerWithConfig(queries CircuitQuerier, failureThreshold int, recoveryTime, disableAfter time.Duration) *CircuitBreaker {
 	return &CircuitBreaker{
-		endpoints:        make(map[string]*endpointHealth),
+		queries:          queries,
 		failureThreshold: failureThreshold,
 		recoveryTime:     recoveryTime,
+		disableAfter:     disableAfter,
 	}
 }
 
-// Allow checks if a request to the endpoint should be allowed
-func (cb *CircuitBreaker) Allow(endpoint string) bool {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-
-	health, exists := cb.endpoints[endpoint]
-	if !exists {
-		return true
+// Allow checks if a delivery to the webhook should be attempted. An open
+// circuit moves to half-open once the recovery time has passed since the
+// last failure.
+func (cb *CircuitBreaker) Allow(ctx context.Context, webhookID pgtype.UUID) (bool, error) {
+	state, err := cb.queries.GetWebhookCircuitState(ctx, webhookID)
+	if err != nil {
+		return true, err
 	}
 
-	switch health.state {
-	case stateClosed:
-		return true
-	case stateOpen:
-		if time.Since(health.lastFailure) > cb.recoveryTime {
-			health.state = stateHalfOpen
-			return true
-		}
-		return false
-	case stateHalfOpen:
-		return true
+	if state.CircuitState == nil || *state.CircuitState != CircuitOpen {
+		return true, nil
 	}
-	return true
-}
-
-// RecordSuccess records a successful request and resets the circuit
-func (cb *CircuitBreaker) RecordSuccess(endpoint string) {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-
-	health, exists := cb.endpoints[endpoint]
-	if !exists {
-		return
+	if !state.LastFailureAt.Valid || time.Since(state.LastFailureAt.Time) <= cb.recoveryTime {
+		return false, nil
 	}
 
-	health.failures = 0
-	health.state = stateClosed
+	err = cb.queries.HalfOpenWebhookCircuit(ctx, db.HalfOpenWebhookCircuitParams{
+		ID:            webhookID,
+		LastFailureAt: pgtype.Timestamptz{Time: time.Now().Add(-cb.recoveryTime), Valid: true},
+	})
+	return true, err
 }
 
-// RecordFailure records a failed request and potentially opens the circuit
-func (cb *CircuitBreaker) RecordFailure(endpoint string) {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
+// RecordSuccess records a successful delivery and closes the circuit
+func (cb *CircuitBreaker) RecordSuccess(ctx context.Context, webhookID pgtype.UUID) error {
+	return cb.queries.ResetWebhookFailures(ctx, webhookID)
+}
 
-	health, exists := cb.endpoints[endpoint]
-	if !exists {
-		health = &endpointHealth{}
-		cb.endpoints[endpoint] = health
+// RecordFailure records a failed delivery, opening the circuit at the failure
+// threshold or when a half-open probe fails. When the webhook has been failing
+// for longer than the disable window it is deactivated and returned; only the
+// call that disables it gets a non-nil webhook.
+func (cb *CircuitBreaker) RecordFailure(ctx context.Context, webhookID pgtype.UUID) (*db.Webhook, error) {
+	row, err := cb.queries.IncrementWebhookFailures(ctx, db.IncrementWebhookFailuresParams{
+		FailureThreshold: int32(cb.failureThreshold),
+		ID:               webhookID,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	health.failures++
-	health.lastFailure = time.Now()
-
-	if health.state == stateHalfOpen {
-		health.state = stateOpen
-	} else if health.failures >= cb.failureThreshold {
-		health.state = stateOpen
+	if row.CircuitState == nil || *row.CircuitState != CircuitOpen {
+		return nil, nil
 	}
-}
-
-// IsOpen returns whether the circuit is open for the given endpoint
-func (cb *CircuitBreaker) IsOpen(endpoint string) bool {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
-
-	health, exists := cb.endpoints[endpoint]
-	if !exists {
-		return false
+	if !row.FailingSince.Valid || time.Since(row.FailingSince.Time) < cb.disableAfter {
+		return nil, nil
 	}
-	return health.state == stateOpen
-}
-
-// GetState returns the current state for the given endpoint
-func (cb *CircuitBreaker) GetState(endpoint string) string {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
 
-	health, exists := cb.endpoints[endpoint]
-	if !exists {
-		return "closed"
+	wh, err := cb.queries.DisableFailingWebhook(ctx, db.DisableFailingWebhookParams{
+		ID:           webhookID,
+		FailingSince: pgtype.Timestamptz{Time: time.Now().Add(-cb.disableAfter), Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-
-	switch health.state {
-	case stateClosed:
-		return "closed"
-	case stateOpen:
-		return "open"
-	case stateHalfOpen:
-		return "half_open"
+	if err != nil {
+		return nil, err
 	}
-	return "unknown"
+	return &wh, nil
 }
 
-// Reset resets the circuit breaker state for an endpoint
-func (cb *CircuitBreaker) Reset(endpoint string) {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-	delete(cb.endpoints, endpoint)
+// CircuitState returns the circuit state stored for a webhook, defaulting to
+// closed.
+func CircuitState(wh db.Webhook) string {
+	if wh.CircuitState == nil || *wh.CircuitState == "" {
+		return CircuitClosed
+	}
+	return *wh.CircuitState
 }
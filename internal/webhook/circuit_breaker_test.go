@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// circuitStore mirrors the webhooks row updates the breaker's queries make,
+// standing in for the table every worker shares.
+type circuitStore struct {
+	wh db.Webhook
+}
+
+func newCircuitStore() *circuitStore {
+	closed := CircuitClosed
+	zero := int32(0)
+	return &circuitStore{wh: db.Webhook{
+		ID:                  pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Active:              true,
+		CircuitState:        &closed,
+		ConsecutiveFailures: &zero,
+	}}
+}
+
+func (s *circuitStore) GetWebhookCircuitState(ctx context.Context, id pgtype.UUID) (db.GetWebhookCircuitStateRow, error) {
+	return db.GetWebhookCircuitStateRow{
+		ID:                  s.wh.ID,
+		ConsecutiveFailures: s.wh.ConsecutiveFailures,
+		LastFailureAt:       s.wh.LastFailureAt,
+		CircuitState:        s.wh.CircuitState,
+	}, nil
+}
+
+func (s *circuitStore) HalfOpenWebhookCircuit(ctx context.Context, arg db.HalfOpenWebhookCircuitParams) error {
+	if CircuitState(s.wh) == CircuitOpen && s.wh.LastFailureAt.Time.Before(arg.LastFailureAt.Time) {
+		halfOpen := CircuitHalfOpen
+		s.wh.CircuitState = &halfOpen
+	}
+	return nil
+}
+
+func (s *circuitStore) IncrementWebhookFailures(ctx context.Context, arg db.IncrementWebhookFailuresParams) (db.IncrementWebhookFailuresRow, error) {
+	failures := *s.wh.ConsecutiveFailures + 1
+	s.wh.ConsecutiveFailures = &failures
+	s.wh.LastFailureAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	if !s.wh.FailingSince.Valid {
+		s.wh.FailingSince = s.wh.LastFailureAt
+	}
+	if CircuitState(s.wh) == CircuitHalfOpen || failures >= arg.FailureThreshold {
+		open := CircuitOpen
+		s.wh.CircuitState = &open
+	}
+	return db.IncrementWebhookFailuresRow{
+		ConsecutiveFailures: s.wh.ConsecutiveFailures,
+		CircuitState:        s.wh.CircuitState,
+		FailingSince:        s.wh.FailingSince,
+	}, nil
+}
+
+func (s *circuitStore) ResetWebhookFailures(ctx context.Context, id pgtype.UUID) error {
+	closed := CircuitClosed
+	zero := int32(0)
+	s.wh.ConsecutiveFailures = &zero
+	s.wh.CircuitState = &closed
+	s.wh.FailingSince = pgtype.Timestamptz{}
+	return nil
+}
+
+func (s *circuitStore) DisableFailingWebhook(ctx context.Context, arg db.DisableFailingWebhookParams) (db.Webhook, error) {
+	if !s.wh.Active || !s.wh.FailingSince.Time.Before(arg.FailingSince.Time) {
+		return db.Webhook{}, pgx.ErrNoRows
+	}
+	s.wh.Active = false
+	s.wh.DisabledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return s.wh, nil
+}
+
+func TestCircuitBreaker_OpensAtThreshold(t *testing.T) {
+	ctx := context.Background()
+	store := newCircuitStore()
+	cb := NewCircuitBreakerWithConfig(store, 3, time.Hour, 24*time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cb.RecordFailure(ctx, store.wh.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if allowed, _ := cb.Allow(ctx, store.wh.ID); !allowed {
+		t.Fatal("circuit opened below the threshold")
+	}
+
+	if _, err := cb.RecordFailure(ctx, store.wh.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := CircuitState(store.wh); got != CircuitOpen {
+		t.Fatalf("state = %s, want open", got)
+	}
+
+	// A second breaker, as on another worker, sees the same open circuit.
+	other := NewCircuitBreakerWithConfig(store, 3, time.Hour, 24*time.Hour)
+	if allowed, _ := other.Allow(ctx, store.wh.ID); allowed {
+		t.Error("open circuit allowed a delivery")
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("probe succeeds", func(t *testing.T) {
+		store := newCircuitStore()
+		cb := NewCircuitBreakerWithConfig(store, 1, time.Minute, 24*time.Hour)
+		_, _ = cb.RecordFailure(ctx, store.wh.ID)
+		store.wh.LastFailureAt.Time = time.Now().Add(-2 * time.Minute)
+
+		if allowed, _ := cb.Allow(ctx, store.wh.ID); !allowed {
+			t.Fatal("recovered circuit did not allow a probe")
+		}
+		if got := CircuitState(store.wh); got != CircuitHalfOpen {
+			t.Fatalf("state = %s, want half_open", got)
+		}
+		if err := cb.RecordSuccess(ctx, store.wh.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := CircuitState(store.wh); got != CircuitClosed {
+			t.Errorf("state = %s, want closed", got)
+		}
+	})
+
+	t.Run("probe fails", func(t *testing.T) {
+		store := newCircuitStore()
+		cb := NewCircuitBreakerWithConfig(store, 5, time.Minute, 24*time.Hour)
+		half := CircuitHalfOpen
+		store.wh.CircuitState = &half
+
+		if _, err := cb.RecordFailure(ctx, store.wh.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := CircuitState(store.wh); got != CircuitOpen {
+			t.Errorf("state = %s, want open after a failed probe", got)
+		}
+	})
+}
+
+func TestCircuitBreaker_DisablesAfterSustainedFailure(t *testing.T) {
+	ctx := context.Background()
+	store := newCircuitStore()
+	cb := NewCircuitBreakerWithConfig(store, 1, time.Minute, time.Hour)
+
+	disabled, err := cb.RecordFailure(ctx, store.wh.ID)
+	if err != nil || disabled != nil {
+		t.Fatalf("first failure disabled = %v, err = %v", disabled, err)
+	}
+
+	store.wh.FailingSince.Time = time.Now().Add(-2 * time.Hour)
+	disabled, err = cb.RecordFailure(ctx, store.wh.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disabled == nil || disabled.Active || !disabled.DisabledAt.Valid {
+		t.Fatalf("webhook not disabled: %+v", disabled)
+	}
+
+	// Only the call that disabled the webhook reports it.
+	if again, _ := cb.RecordFailure(ctx, store.wh.ID); again != nil {
+		t.Error("webhook reported disabled twice")
+	}
+}
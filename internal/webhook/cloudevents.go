@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Delivery formats a webhook can be configured with.
+const (
+	FormatNative                = "native"
+	FormatCloudEventsStructured = "cloudevents-structured"
+	FormatCloudEventsBinary     = "cloudevents-binary"
+)
+
+var ValidFormats = map[string]bool{
+	FormatNative:                true,
+	FormatCloudEventsStructured: true,
+	FormatCloudEventsBinary:     true,
+}
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsSource is the ce-source attribute of every event we emit.
+	CloudEventsSource = "https://file.cheap"
+	// CloudEventsTypePrefix turns event types into reverse-DNS CloudEvents
+	// types, e.g. file.uploaded becomes cheap.file.file.uploaded.
+	CloudEventsTypePrefix = "cheap.file."
+
+	contentTypeJSON        = "application/json"
+	contentTypeCloudEvents = "application/cloudevents+json"
+)
+
+// CloudEvent is the structured-mode JSON representation of an Event.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// ToCloudEvent maps the native envelope onto CloudEvents attributes.
+func (e *Event) ToCloudEvent() CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              e.ID,
+		Source:          CloudEventsSource,
+		Type:            CloudEventsTypePrefix + e.Type,
+		Time:            e.CreatedAt.UTC(),
+		DataContentType: contentTypeJSON,
+		Data:            e.Data,
+	}
+}
+
+// EncodePayload converts a stored native event payload into the request body
+// and content headers for the given delivery format. The returned body is
+// what must be signed, since it is exactly what the receiver sees.
+func EncodePayload(payload []byte, format string) ([]byte, http.Header, error) {
+	header := http.Header{}
+
+	if format == "" || format == FormatNative {
+		header.Set("Content-Type", contentTypeJSON)
+		return payload, header, nil
+	}
+	if !ValidFormats[format] {
+		return nil, nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, nil, fmt.Errorf("decode event: %w", err)
+	}
+	ce := event.ToCloudEvent()
+
+	if format == FormatCloudEventsBinary {
+		header.Set("Content-Type", ce.DataContentType)
+		header.Set("Ce-Specversion", ce.SpecVersion)
+		header.Set("Ce-Id", ce.ID)
+		header.Set("Ce-Source", ce.Source)
+		header.Set("Ce-Type", ce.Type)
+		header.Set("Ce-Time", ce.Time.Format(time.RFC3339Nano))
+		return ce.Data, header, nil
+	}
+
+	body, err := json.Marshal(ce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode cloudevent: %w", err)
+	}
+	header.Set("Content-Type", contentTypeCloudEvents+"; charset=utf-8")
+	return body, header, nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testEventPayload(t *testing.T) []byte {
+	t.Helper()
+	payload, err := json.Marshal(Event{
+		ID:        "evt_1",
+		Type:      EventFileUploaded,
+		CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Data:      json.RawMessage(`{"file_id":"f1"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestEncodePayload_Native(t *testing.T) {
+	payload := testEventPayload(t)
+
+	for _, format := range []string{"", FormatNative} {
+		body, header, err := EncodePayload(payload, format)
+		if err != nil {
+			t.Fatalf("EncodePayload(%q) error = %v", format, err)
+		}
+		if string(body) != string(payload) {
+			t.Errorf("EncodePayload(%q) body = %s, want stored payload", format, body)
+		}
+		if got := header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+	}
+}
+
+func TestEncodePayload_CloudEventsStructured(t *testing.T) {
+	body, header, err := EncodePayload(testEventPayload(t), FormatCloudEventsStructured)
+	if err != nil {
+		t.Fatalf("EncodePayload() error = %v", err)
+	}
+	if got := header.Get("Content-Type"); got != "application/cloudevents+json; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	want := `{"specversion":"1.0","id":"evt_1","source":"https://file.cheap","type":"cheap.file.file.uploaded","time":"2026-03-01T12:00:00Z","datacontenttype":"application/json","data":{"file_id":"f1"}}`
+	if string(body) != want {
+		t.Errorf("body = %s\nwant %s", body, want)
+	}
+}
+
+func TestEncodePayload_CloudEventsBinary(t *testing.T) {
+	body, header, err := EncodePayload(testEventPayload(t), FormatCloudEventsBinary)
+	if err != nil {
+		t.Fatalf("EncodePayload() error = %v", err)
+	}
+	if string(body) != `{"file_id":"f1"}` {
+		t.Errorf("body = %s, want event data", body)
+	}
+
+	want := map[string]string{
+		"Content-Type":   "application/json",
+		"ce-specversion": "1.0",
+		"ce-id":          "evt_1",
+		"ce-source":      "https://file.cheap",
+		"ce-type":        "cheap.file.file.uploaded",
+		"ce-time":        "2026-03-01T12:00:00Z",
+	}
+	for key, value := range want {
+		if got := header.Get(key); got != value {
+			t.Errorf("header %s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestEncodePayload_Errors(t *testing.T) {
+	if _, _, err := EncodePayload(testEventPayload(t), "xml"); err == nil {
+		t.Error("unknown format should fail")
+	}
+	if _, _, err := EncodePayload([]byte("not json"), FormatCloudEventsStructured); err == nil {
+		t.Error("invalid payload should fail")
+	}
+}
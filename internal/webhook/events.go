@@ -12,6 +12,12 @@ const (
 	EventProcessingStarted   = "processing.started"
 	EventProcessingCompleted = "processing.completed"
 	EventProcessingFailed    = "processing.failed"
+	EventFileDeleted         = "file.deleted"
+	EventShareCreated        = "share.created"
+	EventShareAccessed       = "share.accessed"
+	EventBatchCompleted      = "batch.completed"
+	EventZipReady            = "zip.ready"
+	EventQuotaWarning        = "quota.warning"
 )
 
 var ValidEventTypes = map[string]bool{
@@ -19,6 +25,12 @@ var ValidEventTypes = map[string]bool{
 	EventProcessingStarted:   true,
 	EventProcessingCompleted: true,
 	EventProcessingFailed:    true,
+	EventFileDeleted:         true,
+	EventShareCreated:        true,
+	EventShareAccessed:       true,
+	EventBatchCompleted:      true,
+	EventZipReady:            true,
+	EventQuotaWarning:        true,
 }
 
 type Event struct {
@@ -58,6 +70,49 @@ type ProcessingFailedData struct {
 	ErrorMessage string `json:"error_message"`
 }
 
+type FileDeletedData struct {
+	FileID   string `json:"file_id"`
+	Filename string `json:"filename"`
+}
+
+type ShareCreatedData struct {
+	ShareID   string     `json:"share_id"`
+	FileID    string     `json:"file_id"`
+	ShareURL  string     `json:"share_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ShareAccessedData struct {
+	ShareID    string `json:"share_id"`
+	FileID     string `json:"file_id"`
+	Transforms string `json:"transforms,omitempty"`
+}
+
+type BatchCompletedData struct {
+	BatchID        string `json:"batch_id"`
+	Status         string `json:"status"`
+	TotalFiles     int    `json:"total_files"`
+	CompletedFiles int    `json:"completed_files"`
+	FailedFiles    int    `json:"failed_files"`
+}
+
+type ZipReadyData struct {
+	ZipDownloadID string    `json:"zip_download_id"`
+	FileCount     int       `json:"file_count"`
+	SizeBytes     int64     `json:"size_bytes"`
+	DownloadURL   string    `json:"download_url"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// QuotaWarningData reports a quota that has crossed its warning threshold.
+// Resource names the quota, e.g. "transformations".
+type QuotaWarningData struct {
+	Resource string `json:"resource"`
+	Used     int64  `json:"used"`
+	Limit    int64  `json:"limit"`
+	Percent  int    `json:"percent"`
+}
+
 func NewEvent(eventType string, data any) (*Event, error) {
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
@@ -109,6 +164,63 @@ func NewProcessingFailedEvent(fileID, jobID, jobType, errorMessage string) (*Eve
 	})
 }
 
+func NewFileDeletedEvent(fileID, filename string) (*Event, error) {
+	return NewEvent(EventFileDeleted, FileDeletedData{
+		FileID:   fileID,
+		Filename: filename,
+	})
+}
+
+func NewShareCreatedEvent(shareID, fileID, shareURL string, expiresAt *time.Time) (*Event, error) {
+	return NewEvent(EventShareCreated, ShareCreatedData{
+		ShareID:   shareID,
+		FileID:    fileID,
+		ShareURL:  shareURL,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func NewShareAccessedEvent(shareID, fileID, transforms string) (*Event, error) {
+	return NewEvent(EventShareAccessed, ShareAccessedData{
+		ShareID:    shareID,
+		FileID:     fileID,
+		Transforms: transforms,
+	})
+}
+
+func NewBatchCompletedEvent(batchID, status string, totalFiles, completedFiles, failedFiles int) (*Event, error) {
+	return NewEvent(EventBatchCompleted, BatchCompletedData{
+		BatchID:        batchID,
+		Status:         status,
+		TotalFiles:     totalFiles,
+		CompletedFiles: completedFiles,
+		FailedFiles:    failedFiles,
+	})
+}
+
+func NewZipReadyEvent(zipDownloadID string, fileCount int, sizeBytes int64, downloadURL string, expiresAt time.Time) (*Event, error) {
+	return NewEvent(EventZipReady, ZipReadyData{
+		ZipDownloadID: zipDownloadID,
+		FileCount:     fileCount,
+		SizeBytes:     sizeBytes,
+		DownloadURL:   downloadURL,
+		ExpiresAt:     expiresAt,
+	})
+}
+
+func NewQuotaWarningEvent(resource string, used, limit int64) (*Event, error) {
+	var percent int
+	if limit > 0 {
+		percent = int(used * 100 / limit)
+	}
+	return NewEvent(EventQuotaWarning, QuotaWarningData{
+		Resource: resource,
+		Used:     used,
+		Limit:    limit,
+		Percent:  percent,
+	})
+}
+
 func (e *Event) Marshal() ([]byte, error) {
 	return json.Marshal(e)
 }
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestValidEventTypes(t *testing.T) {
+	for _, eventType := range []string{
+		EventFileUploaded,
+		EventProcessingStarted,
+		EventProcessingCompleted,
+		EventProcessingFailed,
+		EventFileDeleted,
+		EventShareCreated,
+		EventShareAccessed,
+		EventBatchCompleted,
+		EventZipReady,
+		EventQuotaWarning,
+	} {
+		if !ValidEventTypes[eventType] {
+			t.Errorf("ValidEventTypes[%q] = false, want true", eventType)
+		}
+	}
+
+	if ValidEventTypes["file.exploded"] {
+		t.Error("unknown event type reported valid")
+	}
+}
+
+func TestNewEventConstructors(t *testing.T) {
+	expires := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		newEvent func() (*Event, error)
+		wantType string
+		wantData string
+	}{
+		{
+			name:     "file deleted",
+			newEvent: func() (*Event, error) { return NewFileDeletedEvent("f1", "photo.jpg") },
+			wantType: EventFileDeleted,
+			wantData: `{"file_id":"f1","filename":"photo.jpg"}`,
+		},
+		{
+			name: "share created",
+			newEvent: func() (*Event, error) {
+				return NewShareCreatedEvent("s1", "f1", "https://file.cheap/cdn/tok/_/photo.jpg", &expires)
+			},
+			wantType: EventShareCreated,
+			wantData: `{"share_id":"s1","file_id":"f1","share_url":"https://file.cheap/cdn/tok/_/photo.jpg","expires_at":"2026-03-01T00:00:00Z"}`,
+		},
+		{
+			name:     "share accessed",
+			newEvent: func() (*Event, error) { return NewShareAccessedEvent("s1", "f1", "w_200") },
+			wantType: EventShareAccessed,
+			wantData: `{"share_id":"s1","file_id":"f1","transforms":"w_200"}`,
+		},
+		{
+			name:     "batch completed",
+			newEvent: func() (*Event, error) { return NewBatchCompletedEvent("b1", "partial", 3, 2, 1) },
+			wantType: EventBatchCompleted,
+			wantData: `{"batch_id":"b1","status":"partial","total_files":3,"completed_files":2,"failed_files":1}`,
+		},
+		{
+			name: "zip ready",
+			newEvent: func() (*Event, error) {
+				return NewZipReadyEvent("z1", 2, 1024, "https://example.com/z1.zip", expires)
+			},
+			wantType: EventZipReady,
+			wantData: `{"zip_download_id":"z1","file_count":2,"size_bytes":1024,"download_url":"https://example.com/z1.zip","expires_at":"2026-03-01T00:00:00Z"}`,
+		},
+		{
+			name:     "quota warning",
+			newEvent: func() (*Event, error) { return NewQuotaWarningEvent("transformations", 85, 100) },
+			wantType: EventQuotaWarning,
+			wantData: `{"resource":"transformations","used":85,"limit":100,"percent":85}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := tt.newEvent()
+			if err != nil {
+				t.Fatalf("constructor error = %v", err)
+			}
+			if event.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", event.Type, tt.wantType)
+			}
+			if event.ID == "" || event.CreatedAt.IsZero() {
+				t.Errorf("event missing id or created_at: %+v", event)
+			}
+			if string(event.Data) != tt.wantData {
+				t.Errorf("Data = %s, want %s", event.Data, tt.wantData)
+			}
+			if !json.Valid(event.Data) {
+				t.Error("Data is not valid JSON")
+			}
+		})
+	}
+}
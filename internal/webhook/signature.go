@@ -26,16 +26,26 @@ func GenerateSignature(payload []byte, secret string, timestamp time.Time) strin
 	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
 }
 
-func VerifySignature(payload []byte, signature, secret string, timestamp time.Time, tolerance time.Duration) bool {
+// VerifySignature reports whether any of signatures was produced for payload
+// with secret. While a secret is being rotated the header carries one
+// signature per valid secret, so a receiver holding either one verifies.
+func VerifySignature(payload []byte, signatures []string, secret string, timestamp time.Time, tolerance time.Duration) bool {
 	if time.Since(timestamp) > tolerance {
 		return false
 	}
 
-	expected := GenerateSignature(payload, secret, timestamp)
-	return hmac.Equal([]byte(signature), []byte(expected))
+	expected := []byte(GenerateSignature(payload, secret, timestamp))
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), expected) {
+			return true
+		}
+	}
+	return false
 }
 
-func ParseSignatureHeader(header string) (signature string, timestamp time.Time, err error) {
+// ParseSignatureHeader extracts the timestamp and every v1 signature from a
+// signature header.
+func ParseSignatureHeader(header string) (signatures []string, timestamp time.Time, err error) {
 	parts := strings.Split(header, ",")
 	var ts int64
 
@@ -44,25 +54,32 @@ func ParseSignatureHeader(header string) (signature string, timestamp time.Time,
 		if val, ok := strings.CutPrefix(part, "t="); ok {
 			ts, err = strconv.ParseInt(val, 10, 64)
 			if err != nil {
-				return "", time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
+				return nil, time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
 			}
 			timestamp = time.Unix(ts, 0)
 		} else if val, ok := strings.CutPrefix(part, "v1="); ok {
-			signature = "sha256=" + val
+			signatures = append(signatures, "sha256="+val)
 		}
 	}
 
-	if signature == "" {
-		return "", time.Time{}, fmt.Errorf("signature not found")
+	if len(signatures) == 0 {
+		return nil, time.Time{}, fmt.Errorf("signature not found")
 	}
 	if ts == 0 {
-		return "", time.Time{}, fmt.Errorf("timestamp not found")
+		return nil, time.Time{}, fmt.Errorf("timestamp not found")
 	}
 
-	return signature, timestamp, nil
+	return signatures, timestamp, nil
 }
 
-func BuildSignatureHeader(signature string, timestamp time.Time) string {
-	sig := strings.TrimPrefix(signature, "sha256=")
-	return fmt.Sprintf("t=%d,v1=%s", timestamp.Unix(), sig)
+// BuildSignatureHeader formats a signature header with one v1 value per
+// signature, e.g. t=1700000000,v1=abc,v1=def.
+func BuildSignatureHeader(timestamp time.Time, signatures ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "t=%d", timestamp.Unix())
+	for _, signature := range signatures {
+		b.WriteString(",v1=")
+		b.WriteString(strings.TrimPrefix(signature, "sha256="))
+	}
+	return b.String()
 }
@@ -90,7 +90,7 @@ func TestVerifySignature(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := VerifySignature(tt.payload, tt.signature, tt.secret, tt.timestamp, tt.tolerance)
+			got := VerifySignature(tt.payload, []string{tt.signature}, tt.secret, tt.timestamp, tt.tolerance)
 			if got != tt.want {
 				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
 			}
@@ -101,15 +101,15 @@ func TestVerifySignature(t *testing.T) {
 func TestParseSignatureHeader(t *testing.T) {
 	timestamp := time.Unix(1234567890, 0)
 	sig := "sha256=abc123"
-	header := BuildSignatureHeader(sig, timestamp)
+	header := BuildSignatureHeader(timestamp, sig)
 
-	parsedSig, parsedTS, err := ParseSignatureHeader(header)
+	parsedSigs, parsedTS, err := ParseSignatureHeader(header)
 	if err != nil {
 		t.Fatalf("ParseSignatureHeader() error = %v", err)
 	}
 
-	if parsedSig != sig {
-		t.Errorf("ParseSignatureHeader() signature = %v, want %v", parsedSig, sig)
+	if len(parsedSigs) != 1 || parsedSigs[0] != sig {
+		t.Errorf("ParseSignatureHeader() signatures = %v, want [%v]", parsedSigs, sig)
 	}
 
 	if !parsedTS.Equal(timestamp) {
@@ -121,7 +121,7 @@ func TestBuildSignatureHeader(t *testing.T) {
 	sig := "sha256=abc123"
 	timestamp := time.Unix(1234567890, 0)
 
-	header := BuildSignatureHeader(sig, timestamp)
+	header := BuildSignatureHeader(timestamp, sig)
 
 	if !strings.Contains(header, "t=1234567890") {
 		t.Errorf("BuildSignatureHeader() = %v, should contain t=1234567890", header)
@@ -131,3 +131,40 @@ func TestBuildSignatureHeader(t *testing.T) {
 		t.Errorf("BuildSignatureHeader() = %v, should contain v1=abc123", header)
 	}
 }
+
+func TestSignatureHeader_MultipleSignatures(t *testing.T) {
+	payload := []byte(`{"type":"test","data":{}}`)
+	timestamp := time.Now()
+	oldSig := GenerateSignature(payload, "whsec_old", timestamp)
+	newSig := GenerateSignature(payload, "whsec_new", timestamp)
+
+	header := BuildSignatureHeader(timestamp, newSig, oldSig)
+	if strings.Count(header, "v1=") != 2 {
+		t.Fatalf("BuildSignatureHeader() = %v, want two v1 values", header)
+	}
+
+	sigs, ts, err := ParseSignatureHeader(header)
+	if err != nil {
+		t.Fatalf("ParseSignatureHeader() error = %v", err)
+	}
+	if len(sigs) != 2 || sigs[0] != newSig || sigs[1] != oldSig {
+		t.Errorf("ParseSignatureHeader() signatures = %v, want [%v %v]", sigs, newSig, oldSig)
+	}
+
+	for _, secret := range []string{"whsec_new", "whsec_old"} {
+		if !VerifySignature(payload, sigs, secret, ts, 5*time.Minute) {
+			t.Errorf("VerifySignature() with %s = false, want true", secret)
+		}
+	}
+	if VerifySignature(payload, sigs, "whsec_other", ts, 5*time.Minute) {
+		t.Error("VerifySignature() with unrelated secret = true, want false")
+	}
+}
+
+func TestParseSignatureHeader_Errors(t *testing.T) {
+	for _, header := range []string{"", "t=1234567890", "v1=abc", "t=abc,v1=abc"} {
+		if _, _, err := ParseSignatureHeader(header); err == nil {
+			t.Errorf("ParseSignatureHeader(%q) error = nil, want error", header)
+		}
+	}
+}
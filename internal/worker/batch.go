@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
+	jqworker "github.com/abdul-hamid-achik/job-queue/pkg/worker"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// batchJobTypes are the job types batch transforms enqueue.
+var batchJobTypes = map[string]bool{
+	string(db.JobTypeThumbnail): true,
+	string(db.JobTypeResize):    true,
+	string(db.JobTypeWebp):      true,
+	string(db.JobTypeWatermark): true,
+	string(db.JobTypeConvert):   true,
+	string(db.JobTypeTransform): true, // saved presets and pipelines
+}
+
+// batchQuerier is the subset of db.Queries that batch tracking uses.
+type batchQuerier interface {
+	RecordBatchJobFinished(ctx context.Context, arg db.RecordBatchJobFinishedParams) (db.BatchItem, error)
+	UpdateBatchItemStatus(ctx context.Context, arg db.UpdateBatchItemStatusParams) error
+	IncrementBatchCompletedFiles(ctx context.Context, id pgtype.UUID) error
+	IncrementBatchFailedFiles(ctx context.Context, id pgtype.UUID) error
+	CountBatchItemsByStatus(ctx context.Context, batchID pgtype.UUID) (db.CountBatchItemsByStatusRow, error)
+	CompleteBatchOperation(ctx context.Context, arg db.CompleteBatchOperationParams) (db.BatchOperation, error)
+}
+
+// BatchTrackingMiddleware records the outcome of jobs that belong to a batch
+// transform. A job counts as finished when it succeeds or fails for the last
+// time; once every job of every item has finished the batch is completed and
+// a batch.completed webhook is dispatched.
+func BatchTrackingMiddleware(deps *Dependencies) jqworker.Middleware {
+	return batchTrackingMiddleware(deps.Queries, deps.dispatchBatchCompleted)
+}
+
+func batchTrackingMiddleware(queries batchQuerier, completed func(context.Context, db.BatchOperation)) jqworker.Middleware {
+	return func(next jqworker.HandlerFunc) jqworker.HandlerFunc {
+		return func(ctx context.Context, j *job.Job) error {
+			err := next(ctx, j)
+			if !batchJobTypes[j.Type] {
+				return err
+			}
+
+			// The job's own context may have timed out; the bookkeeping
+			// should still land.
+			recordCtx := context.WithoutCancel(ctx)
+			switch {
+			case err == nil:
+				recordBatchJob(recordCtx, queries, completed, j.ID, nil)
+			case isFinalAttempt(j):
+				msg := err.Error()
+				recordBatchJob(recordCtx, queries, completed, j.ID, &msg)
+			}
+			return err
+		}
+	}
+}
+
+// isFinalAttempt reports whether a failed run of j will not be retried.
+func isFinalAttempt(j *job.Job) bool {
+	return j.RetryCount+1 >= j.MaxRetries
+}
+
+// batchStatus derives a finished batch's status from its item counts.
+func batchStatus(counts db.CountBatchItemsByStatusRow) db.BatchStatus {
+	switch {
+	case counts.Failed == 0:
+		return db.BatchStatusCompleted
+	case counts.Completed == 0:
+		return db.BatchStatusFailed
+	default:
+		return db.BatchStatusPartial
+	}
+}
+
+// recordBatchJob marks jobID finished on its batch item and, once the last
+// item of the batch finishes, completes the batch and calls completed.
+func recordBatchJob(ctx context.Context, queries batchQuerier, completed func(context.Context, db.BatchOperation), jobID string, errMsg *string) {
+	log := logger.FromContext(ctx).With("job_id", jobID)
+
+	item, err := queries.RecordBatchJobFinished(ctx, db.RecordBatchJobFinishedParams{
+		JobID:        jobID,
+		ErrorMessage: errMsg,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Warn("failed to record batch job", "error", err)
+		return
+	}
+	if len(item.FinishedJobIds) < len(item.JobIds) {
+		return
+	}
+
+	itemStatus := db.BatchStatusCompleted
+	if item.ErrorMessage != nil {
+		itemStatus = db.BatchStatusFailed
+	}
+	if err := queries.UpdateBatchItemStatus(ctx, db.UpdateBatchItemStatusParams{
+		ID:           item.ID,
+		Status:       itemStatus,
+		ErrorMessage: item.ErrorMessage,
+	}); err != nil {
+		log.Warn("failed to update batch item", "error", err)
+		return
+	}
+	if itemStatus == db.BatchStatusFailed {
+		err = queries.IncrementBatchFailedFiles(ctx, item.BatchID)
+	} else {
+		err = queries.IncrementBatchCompletedFiles(ctx, item.BatchID)
+	}
+	if err != nil {
+		log.Warn("failed to update batch counts", "error", err)
+	}
+
+	counts, err := queries.CountBatchItemsByStatus(ctx, item.BatchID)
+	if err != nil {
+		log.Warn("failed to count batch items", "error", err)
+		return
+	}
+	if counts.Pending+counts.Processing > 0 {
+		return
+	}
+
+	// Only one worker wins the update when the last items finish together.
+	batch, err := queries.CompleteBatchOperation(ctx, db.CompleteBatchOperationParams{
+		ID:     item.BatchID,
+		Status: batchStatus(counts),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Warn("failed to complete batch", "error", err)
+		return
+	}
+	log.Info("batch completed", "batch_id", uuid.UUID(batch.ID.Bytes).String(), "status", batch.Status)
+	completed(ctx, batch)
+}
+
+// dispatchBatchCompleted sends the batch.completed webhook for batch.
+func (d *Dependencies) dispatchBatchCompleted(ctx context.Context, batch db.BatchOperation) {
+	if d.WebhookDispatcher == nil {
+		return
+	}
+	event, err := webhook.NewBatchCompletedEvent(uuid.UUID(batch.ID.Bytes).String(), string(batch.Status),
+		int(batch.TotalFiles), int(batch.CompletedFiles), int(batch.FailedFiles))
+	if err != nil {
+		return
+	}
+	d.dispatchWebhook(ctx, batch.UserID, event)
+}
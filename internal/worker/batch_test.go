@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestIsFinalAttempt(t *testing.T) {
+	tests := []struct {
+		retryCount int
+		maxRetries int
+		want       bool
+	}{
+		{0, 3, false},
+		{1, 3, false},
+		{2, 3, true},
+		{0, 1, true},
+		{0, 0, true},
+	}
+
+	for _, tt := range tests {
+		j := &job.Job{RetryCount: tt.retryCount, MaxRetries: tt.maxRetries}
+		if got := isFinalAttempt(j); got != tt.want {
+			t.Errorf("isFinalAttempt(retry %d of %d) = %v, want %v", tt.retryCount, tt.maxRetries, got, tt.want)
+		}
+	}
+}
+
+func TestBatchStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts db.CountBatchItemsByStatusRow
+		want   db.BatchStatus
+	}{
+		{"all completed", db.CountBatchItemsByStatusRow{Completed: 3}, db.BatchStatusCompleted},
+		{"all failed", db.CountBatchItemsByStatusRow{Failed: 2}, db.BatchStatusFailed},
+		{"mixed", db.CountBatchItemsByStatusRow{Completed: 2, Failed: 1}, db.BatchStatusPartial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batchStatus(tt.counts); got != tt.want {
+				t.Errorf("batchStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchTrackingMiddleware_IgnoresOtherJobTypes(t *testing.T) {
+	// Queries is nil, so any bookkeeping for these jobs would panic.
+	deps := &Dependencies{}
+	wantErr := errors.New("boom")
+
+	for _, jobType := range []string{"webhook_delivery", "zip_download", "video_hls"} {
+		handler := BatchTrackingMiddleware(deps)(func(ctx context.Context, j *job.Job) error {
+			return wantErr
+		})
+		j := &job.Job{ID: "job-1", Type: jobType, MaxRetries: 1}
+		if err := handler(context.Background(), j); !errors.Is(err, wantErr) {
+			t.Errorf("%s: error = %v, want %v", jobType, err, wantErr)
+		}
+	}
+}
+
+// fakeBatchQuerier keeps the items of a single batch in memory.
+type fakeBatchQuerier struct {
+	batchID pgtype.UUID
+	items   []*db.BatchItem
+	done    bool
+}
+
+func (f *fakeBatchQuerier) RecordBatchJobFinished(ctx context.Context, arg db.RecordBatchJobFinishedParams) (db.BatchItem, error) {
+	for _, item := range f.items {
+		if slices.Contains(item.JobIds, arg.JobID) && !slices.Contains(item.FinishedJobIds, arg.JobID) {
+			item.FinishedJobIds = append(item.FinishedJobIds, arg.JobID)
+			if arg.ErrorMessage != nil {
+				item.ErrorMessage = arg.ErrorMessage
+			}
+			return *item, nil
+		}
+	}
+	return db.BatchItem{}, pgx.ErrNoRows
+}
+
+func (f *fakeBatchQuerier) UpdateBatchItemStatus(ctx context.Context, arg db.UpdateBatchItemStatusParams) error {
+	for _, item := range f.items {
+		if item.ID == arg.ID {
+			item.Status = arg.Status
+		}
+	}
+	return nil
+}
+
+func (f *fakeBatchQuerier) IncrementBatchCompletedFiles(ctx context.Context, id pgtype.UUID) error {
+	return nil
+}
+
+func (f *fakeBatchQuerier) IncrementBatchFailedFiles(ctx context.Context, id pgtype.UUID) error {
+	return nil
+}
+
+func (f *fakeBatchQuerier) CountBatchItemsByStatus(ctx context.Context, batchID pgtype.UUID) (db.CountBatchItemsByStatusRow, error) {
+	var counts db.CountBatchItemsByStatusRow
+	for _, item := range f.items {
+		switch item.Status {
+		case db.BatchStatusCompleted:
+			counts.Completed++
+		case db.BatchStatusFailed:
+			counts.Failed++
+		default:
+			counts.Pending++
+		}
+	}
+	return counts, nil
+}
+
+func (f *fakeBatchQuerier) CompleteBatchOperation(ctx context.Context, arg db.CompleteBatchOperationParams) (db.BatchOperation, error) {
+	if f.done {
+		return db.BatchOperation{}, pgx.ErrNoRows
+	}
+	f.done = true
+	return db.BatchOperation{ID: f.batchID, Status: arg.Status}, nil
+}
+
+func TestBatchTrackingMiddleware_CompletesPresetBatch(t *testing.T) {
+	batchID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	queries := &fakeBatchQuerier{
+		batchID: batchID,
+		items: []*db.BatchItem{
+			{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, BatchID: batchID, Status: db.BatchStatusPending, JobIds: []string{"thumb-1", "preset-1"}},
+			{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, BatchID: batchID, Status: db.BatchStatusPending, JobIds: []string{"preset-2"}},
+		},
+	}
+
+	var completed []db.BatchOperation
+	handler := batchTrackingMiddleware(queries, func(ctx context.Context, batch db.BatchOperation) {
+		completed = append(completed, batch)
+	})(func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+
+	jobs := []*job.Job{
+		{ID: "thumb-1", Type: string(db.JobTypeThumbnail)},
+		{ID: "preset-1", Type: string(db.JobTypeTransform)},
+		{ID: "preset-2", Type: string(db.JobTypeTransform)},
+	}
+	for _, j := range jobs {
+		if len(completed) != 0 {
+			t.Fatalf("batch completed before job %s ran", j.ID)
+		}
+		if err := handler(context.Background(), j); err != nil {
+			t.Fatalf("%s: unexpected error: %v", j.ID, err)
+		}
+	}
+
+	if len(completed) != 1 {
+		t.Fatalf("batch completed %d times, want once", len(completed))
+	}
+	if completed[0].ID != batchID || completed[0].Status != db.BatchStatusCompleted {
+		t.Errorf("completed batch = %+v, want %v completed", completed[0], batchID)
+	}
+}
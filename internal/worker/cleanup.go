@@ -9,6 +9,7 @@ import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/metrics"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type CleanupDependencies struct {
@@ -23,6 +24,40 @@ type CleanupStats struct {
 	DatabaseDeleteErrors int
 }
 
+// StorageReferenceCounter counts the files that share a storage key.
+type StorageReferenceCounter interface {
+	CountStorageKeyReferences(ctx context.Context, arg db.CountStorageKeyReferencesParams) (int64, error)
+}
+
+// ReleaseStorageObject deletes the stored object behind a file unless another
+// file row still references the same key, which happens when deduplicated
+// uploads share a blob. It reports whether the object was deleted.
+func ReleaseStorageObject(ctx context.Context, queries StorageReferenceCounter, store storage.Storage, fileID pgtype.UUID, storageKey string) (bool, error) {
+	if storageKey == "" {
+		return false, nil
+	}
+
+	refs, err := queries.CountStorageKeyReferences(ctx, db.CountStorageKeyReferencesParams{
+		StorageKey: storageKey,
+		ID:         fileID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to count storage references: %w", err)
+	}
+	if refs > 0 {
+		logger.FromContext(ctx).Debug("storage object still referenced, keeping it",
+			"storage_key", storageKey,
+			"references", refs,
+		)
+		return false, nil
+	}
+
+	if err := store.Delete(ctx, storageKey); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func RunCleanup(ctx context.Context, deps *CleanupDependencies) (*CleanupStats, error) {
 	log := logger.FromContext(ctx)
 	log.Info("starting cleanup job")
@@ -64,15 +99,13 @@ func cleanupSoftDeletedFiles(ctx context.Context, deps *CleanupDependencies, sta
 		}
 
 		for _, file := range files {
-			if file.StorageKey != "" {
-				if err := deps.Storage.Delete(ctx, file.StorageKey); err != nil {
-					log.Warn("failed to delete file from storage",
-						"file_id", file.ID.Bytes,
-						"storage_key", file.StorageKey,
-						"error", err,
-					)
-					stats.StorageDeleteErrors++
-				}
+			if _, err := ReleaseStorageObject(ctx, deps.Queries, deps.Storage, file.ID, file.StorageKey); err != nil {
+				log.Warn("failed to delete file from storage",
+					"file_id", file.ID.Bytes,
+					"storage_key", file.StorageKey,
+					"error", err,
+				)
+				stats.StorageDeleteErrors++
 			}
 
 			if err := deps.Queries.HardDeleteFile(ctx, file.ID); err != nil {
@@ -112,15 +145,13 @@ func cleanupRetentionExpiredFiles(ctx context.Context, deps *CleanupDependencies
 		}
 
 		for _, file := range files {
-			if file.StorageKey != "" {
-				if err := deps.Storage.Delete(ctx, file.StorageKey); err != nil {
-					log.Warn("failed to delete file from storage",
-						"file_id", file.ID.Bytes,
-						"storage_key", file.StorageKey,
-						"error", err,
-					)
-					stats.StorageDeleteErrors++
-				}
+			if _, err := ReleaseStorageObject(ctx, deps.Queries, deps.Storage, file.ID, file.StorageKey); err != nil {
+				log.Warn("failed to delete file from storage",
+					"file_id", file.ID.Bytes,
+					"storage_key", file.StorageKey,
+					"error", err,
+				)
+				stats.StorageDeleteErrors++
 			}
 
 			if err := deps.Queries.SoftDeleteFile(ctx, file.ID); err != nil {
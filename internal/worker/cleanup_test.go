@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type referenceCounter struct {
+	refs int64
+	err  error
+	got  db.CountStorageKeyReferencesParams
+}
+
+func (c *referenceCounter) CountStorageKeyReferences(ctx context.Context, arg db.CountStorageKeyReferencesParams) (int64, error) {
+	c.got = arg
+	return c.refs, c.err
+}
+
+func TestReleaseStorageObject(t *testing.T) {
+	const key = "uploads/user/file/photo.jpg"
+
+	tests := []struct {
+		name        string
+		refs        int64
+		countErr    error
+		wantDeleted bool
+		wantErr     bool
+	}{
+		{name: "shared blob is kept", refs: 2},
+		{name: "last reference is deleted", refs: 0, wantDeleted: true},
+		{name: "count error keeps the blob", countErr: errors.New("db down"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStorage()
+			_ = store.Upload(context.Background(), key, strings.NewReader("blob"), "image/jpeg", 4)
+			counter := &referenceCounter{refs: tt.refs, err: tt.countErr}
+			fileID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+			deleted, err := ReleaseStorageObject(context.Background(), counter, store, fileID, key)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReleaseStorageObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if counter.got.StorageKey != key || counter.got.ID != fileID {
+				t.Errorf("counted references with %+v, want the key and the released file", counter.got)
+			}
+			exists, _ := store.Exists(context.Background(), key)
+			if exists == tt.wantDeleted {
+				t.Errorf("object exists = %v after release", exists)
+			}
+		})
+	}
+}
+
+func TestReleaseStorageObject_EmptyKey(t *testing.T) {
+	counter := &referenceCounter{}
+	deleted, err := ReleaseStorageObject(context.Background(), counter, storage.NewMemoryStorage(), pgtype.UUID{}, "")
+	if deleted || err != nil {
+		t.Errorf("ReleaseStorageObject() = %v, %v, want false, nil", deleted, err)
+	}
+	if counter.got.StorageKey != "" {
+		t.Error("references should not be counted without a key")
+	}
+}
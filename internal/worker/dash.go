@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// uploadDASHFile uploads the manifest or one segment of a DASH package and
+// returns its size.
+func uploadDASHFile(ctx context.Context, store storage.Storage, key, path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	contentType := "video/iso.segment"
+	switch name := filepath.Base(path); {
+	case strings.HasSuffix(name, ".mpd"):
+		contentType = "application/dash+xml"
+	case strings.HasPrefix(name, "init-"):
+		contentType = "video/mp4"
+	}
+	if err := store.Upload(ctx, key, bytes.NewReader(data), contentType, int64(len(data))); err != nil {
+		return 0, fmt.Errorf("failed to upload %s: %w", filepath.Base(path), err)
+	}
+	return int64(len(data)), nil
+}
+
+// dashManifestVariantParams describes a DASH package as a single variant
+// whose storage key is the manifest. The size covers the manifest and all
+// segments, and the dimensions are those of the highest representation.
+func dashManifestVariantParams(fileID pgtype.UUID, result *video.DASHResult, key string, size int64) db.CreateVideoVariantParams {
+	width, height := int32(result.Width), int32(result.Height)
+	resolution := fmt.Sprintf("%dx%d", result.Width, result.Height)
+	videoCodec := "h264"
+	return db.CreateVideoVariantParams{
+		FileID:          fileID,
+		VariantType:     db.VariantTypeDashManifest,
+		ContentType:     "application/dash+xml",
+		SizeBytes:       size,
+		StorageKey:      key,
+		Width:           &width,
+		Height:          &height,
+		DurationSeconds: durationNumeric(result.TotalDuration),
+		VideoCodec:      &videoCodec,
+		Resolution:      &resolution,
+	}
+}
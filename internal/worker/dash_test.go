@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDASHManifestVariantParams(t *testing.T) {
+	params := dashManifestVariantParams(pgtype.UUID{Bytes: uuid.New(), Valid: true}, &video.DASHResult{
+		TotalDuration: 30,
+		Resolutions:   []int{360, 720},
+		Width:         1280,
+		Height:        720,
+	}, "processed/x/dash_manifest/manifest.mpd", 4096)
+
+	if params.VariantType != db.VariantTypeDashManifest || params.ContentType != "application/dash+xml" || params.SizeBytes != 4096 {
+		t.Errorf("params = %+v", params)
+	}
+	if *params.Width != 1280 || *params.Height != 720 || *params.Resolution != "1280x720" {
+		t.Errorf("dimensions = %dx%d (%s)", *params.Width, *params.Height, *params.Resolution)
+	}
+	if d, _ := params.DurationSeconds.Float64Value(); d.Float64 != 30 {
+		t.Errorf("duration = %v, want 30", d.Float64)
+	}
+}
+
+func TestUploadDASHFile(t *testing.T) {
+	dir := t.TempDir()
+	store := storage.NewMemoryStorage()
+	for name, want := range map[string]string{
+		"manifest.mpd":      "application/dash+xml",
+		"init-0.m4s":        "video/mp4",
+		"chunk-0-00001.m4s": "video/iso.segment",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		size, err := uploadDASHFile(context.Background(), store, "dash/"+name, path)
+		if err != nil {
+			t.Fatalf("uploadDASHFile(%s) error = %v", name, err)
+		}
+		if size != int64(len(name)) {
+			t.Errorf("%s size = %d, want %d", name, size, len(name))
+		}
+		if got, _ := store.GetContentType("dash/" + name); got != want {
+			t.Errorf("%s content type = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -71,6 +71,18 @@ func (p *VideoThumbnailPayload) GetFileID() pgtype.UUID {
 	return pgtype.UUID{Bytes: p.FileID, Valid: true}
 }
 
+func (p *VideoStoryboardPayload) SetJobID(id pgtype.UUID) { p.JobID = id }
+func (p *VideoStoryboardPayload) GetJobID() pgtype.UUID   { return p.JobID }
+func (p *VideoStoryboardPayload) GetFileID() pgtype.UUID {
+	return pgtype.UUID{Bytes: p.FileID, Valid: true}
+}
+
+func (p *VideoPreviewPayload) SetJobID(id pgtype.UUID) { p.JobID = id }
+func (p *VideoPreviewPayload) GetJobID() pgtype.UUID   { return p.JobID }
+func (p *VideoPreviewPayload) GetFileID() pgtype.UUID {
+	return pgtype.UUID{Bytes: p.FileID, Valid: true}
+}
+
 func (p *VideoTranscodePayload) SetJobID(id pgtype.UUID) { p.JobID = id }
 func (p *VideoTranscodePayload) GetJobID() pgtype.UUID   { return p.JobID }
 func (p *VideoTranscodePayload) GetFileID() pgtype.UUID {
@@ -83,12 +95,24 @@ func (p *VideoHLSPayload) GetFileID() pgtype.UUID {
 	return pgtype.UUID{Bytes: p.FileID, Valid: true}
 }
 
+func (p *VideoDASHPayload) SetJobID(id pgtype.UUID) { p.JobID = id }
+func (p *VideoDASHPayload) GetJobID() pgtype.UUID   { return p.JobID }
+func (p *VideoDASHPayload) GetFileID() pgtype.UUID {
+	return pgtype.UUID{Bytes: p.FileID, Valid: true}
+}
+
 func (p *VideoWatermarkPayload) SetJobID(id pgtype.UUID) { p.JobID = id }
 func (p *VideoWatermarkPayload) GetJobID() pgtype.UUID   { return p.JobID }
 func (p *VideoWatermarkPayload) GetFileID() pgtype.UUID {
 	return pgtype.UUID{Bytes: p.FileID, Valid: true}
 }
 
+func (p *TransformPayload) SetJobID(id pgtype.UUID) { p.JobID = id }
+func (p *TransformPayload) GetJobID() pgtype.UUID   { return p.JobID }
+func (p *TransformPayload) GetFileID() pgtype.UUID {
+	return pgtype.UUID{Bytes: p.FileID, Valid: true}
+}
+
 type JobCreator interface {
 	CreateJob(ctx context.Context, arg db.CreateJobParams) (db.ProcessingJob, error)
 }
@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
@@ -12,8 +13,10 @@ import (
 	"time"
 
 	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/events"
 	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	imgproc "github.com/abdul-hamid-achik/file.cheap/internal/processor/image"
 	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
 	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
 	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
@@ -28,6 +31,9 @@ type Dependencies struct {
 	Registry          *processor.Registry
 	Queries           *db.Queries
 	WebhookDispatcher *webhook.Dispatcher
+	// Events receives job status changes for realtime clients; nil disables
+	// publishing.
+	Events events.Publisher
 }
 
 func (d *Dependencies) markJobRunning(ctx context.Context, jobID pgtype.UUID) {
@@ -35,6 +41,8 @@ func (d *Dependencies) markJobRunning(ctx context.Context, jobID pgtype.UUID) {
 		if err := d.Queries.MarkJobRunning(ctx, jobID); err != nil {
 			logger.FromContext(ctx).Warn("failed to mark job running", "job_id", jobID, "error", err)
 		}
+		d.publishJobEvent(ctx, jobID, events.TypeJobRunning, 0, "")
+		d.dispatchProcessingStarted(ctx, jobID)
 	}
 }
 
@@ -43,6 +51,7 @@ func (d *Dependencies) markJobCompleted(ctx context.Context, jobID pgtype.UUID)
 		if err := d.Queries.MarkJobCompleted(ctx, jobID); err != nil {
 			logger.FromContext(ctx).Warn("failed to mark job completed", "job_id", jobID, "error", err)
 		}
+		d.publishJobEvent(ctx, jobID, events.TypeJobComplete, 100, "")
 	}
 }
 
@@ -54,39 +63,105 @@ func (d *Dependencies) markJobFailed(ctx context.Context, jobID pgtype.UUID, err
 		}); err != nil {
 			logger.FromContext(ctx).Warn("failed to mark job failed", "job_id", jobID, "error", err)
 		}
+		d.publishJobEvent(ctx, jobID, events.TypeJobFailed, 0, errMsg)
 	}
 }
 
-func (d *Dependencies) dispatchProcessingCompleted(ctx context.Context, userID pgtype.UUID, fileID, jobID, jobType, variantKey, contentType string, sizeBytes, durationMs int64) {
-	if d.WebhookDispatcher == nil || !userID.Valid {
+// reportProgress publishes how far, in percent, a long-running job has got.
+func (d *Dependencies) reportProgress(ctx context.Context, jobID pgtype.UUID, progress int) {
+	d.publishJobEvent(ctx, jobID, events.TypeJobProgress, progress, "")
+}
+
+// replaceVideoVariant records a video variant, first dropping earlier rows of
+// the same type. Reruns write to the same storage key, so keeping the old
+// rows would leave several records pointing at one object.
+func (d *Dependencies) replaceVideoVariant(ctx context.Context, arg db.CreateVideoVariantParams) error {
+	if err := d.Queries.DeleteVariantsByType(ctx, db.DeleteVariantsByTypeParams{
+		FileID:      arg.FileID,
+		VariantType: arg.VariantType,
+	}); err != nil {
+		return fmt.Errorf("failed to clear %s variants: %w", arg.VariantType, err)
+	}
+	_, err := d.Queries.CreateVideoVariant(ctx, arg)
+	return err
+}
+
+// publishJobEvent announces a job status change. Events are best effort and
+// never fail the job.
+func (d *Dependencies) publishJobEvent(ctx context.Context, jobID pgtype.UUID, eventType string, progress int, errMsg string) {
+	if d.Events == nil || !jobID.Valid {
 		return
 	}
-	event, err := webhook.NewProcessingCompletedEvent(fileID, jobID, jobType, variantKey, contentType, sizeBytes, durationMs)
+
+	target, err := d.Queries.GetJobEventTarget(ctx, jobID)
 	if err != nil {
+		logger.FromContext(ctx).Warn("failed to look up job for event", "job_id", jobID, "error", err)
+		return
+	}
+
+	if err := d.Events.Publish(ctx, &events.Event{
+		Type:     eventType,
+		UserID:   uuid.UUID(target.UserID.Bytes),
+		FileID:   uuid.UUID(target.FileID.Bytes).String(),
+		JobID:    uuid.UUID(jobID.Bytes).String(),
+		JobType:  string(target.JobType),
+		Progress: progress,
+		Error:    errMsg,
+	}); err != nil {
+		logger.FromContext(ctx).Warn("failed to publish job event", "job_id", jobID, "event", eventType, "error", err)
+	}
+}
+
+// dispatchWebhook delivers event to the user's webhooks in the background.
+// Like job events, webhooks are best effort and never fail the job.
+func (d *Dependencies) dispatchWebhook(ctx context.Context, userID pgtype.UUID, event *webhook.Event) {
+	if d.WebhookDispatcher == nil || !userID.Valid {
 		return
 	}
 	uid := uuid.UUID(userID.Bytes)
 	go func() {
 		if err := d.WebhookDispatcher.Dispatch(context.Background(), uid, event); err != nil {
-			logger.FromContext(ctx).Debug("webhook dispatch failed", "error", err)
+			logger.FromContext(ctx).Debug("webhook dispatch failed", "event", event.Type, "error", err)
 		}
 	}()
 }
 
+func (d *Dependencies) dispatchProcessingStarted(ctx context.Context, jobID pgtype.UUID) {
+	if d.WebhookDispatcher == nil {
+		return
+	}
+	target, err := d.Queries.GetJobEventTarget(ctx, jobID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to look up job for webhook", "job_id", jobID, "error", err)
+		return
+	}
+	event, err := webhook.NewProcessingStartedEvent(uuid.UUID(target.FileID.Bytes).String(), uuid.UUID(jobID.Bytes).String(), string(target.JobType))
+	if err != nil {
+		return
+	}
+	d.dispatchWebhook(ctx, target.UserID, event)
+}
+
+func (d *Dependencies) dispatchProcessingCompleted(ctx context.Context, userID pgtype.UUID, fileID, jobID, jobType, variantKey, contentType string, sizeBytes, durationMs int64) {
+	if d.WebhookDispatcher == nil {
+		return
+	}
+	event, err := webhook.NewProcessingCompletedEvent(fileID, jobID, jobType, variantKey, contentType, sizeBytes, durationMs)
+	if err != nil {
+		return
+	}
+	d.dispatchWebhook(ctx, userID, event)
+}
+
 func (d *Dependencies) dispatchProcessingFailed(ctx context.Context, userID pgtype.UUID, fileID, jobID, jobType, errMsg string) {
-	if d.WebhookDispatcher == nil || !userID.Valid {
+	if d.WebhookDispatcher == nil {
 		return
 	}
 	event, err := webhook.NewProcessingFailedEvent(fileID, jobID, jobType, errMsg)
 	if err != nil {
 		return
 	}
-	uid := uuid.UUID(userID.Bytes)
-	go func() {
-		if err := d.WebhookDispatcher.Dispatch(context.Background(), uid, event); err != nil {
-			logger.FromContext(ctx).Debug("webhook dispatch failed", "error", err)
-		}
-	}()
+	d.dispatchWebhook(ctx, userID, event)
 }
 
 func ThumbnailHandler(deps *Dependencies) func(context.Context, *job.Job) error {
@@ -648,8 +723,33 @@ func MetadataHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 			return middleware.Permanent(fmt.Errorf("failed to extract metadata: %w", err))
 		}
 
+		data, err := io.ReadAll(result.Data)
+		if err != nil {
+			log.Error("failed to read metadata", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+
+		var meta imgproc.ImageMetadata
+		if err := json.Unmarshal(data, &meta); err != nil {
+			log.Error("invalid metadata", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return middleware.Permanent(fmt.Errorf("invalid metadata: %w", err))
+		}
+
+		params := FileMetadataParams(payload.FileID, &meta)
+		if err := withPlaceholder(ctx, deps.Storage, file.StorageKey, &params); err != nil {
+			log.Warn("failed to generate placeholder", "error", err)
+		}
+
+		if _, err := deps.Queries.UpsertFileMetadata(ctx, params); err != nil {
+			log.Error("failed to save metadata", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to save metadata: %w", err)
+		}
+
 		variantKey := buildVariantKey(payload.FileID, "metadata", "metadata.json")
-		if err := deps.Storage.Upload(ctx, variantKey, result.Data, result.ContentType, result.Size); err != nil {
+		if err := deps.Storage.Upload(ctx, variantKey, bytes.NewReader(data), result.ContentType, int64(len(data))); err != nil {
 			log.Error("failed to upload metadata", "error", err)
 			deps.markJobFailed(ctx, payload.JobID, err.Error())
 			return fmt.Errorf("failed to upload metadata: %w", err)
@@ -796,8 +896,11 @@ func ConvertHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 		width := int32(result.Metadata.Width)
 		height := int32(result.Metadata.Height)
 		variantType := db.VariantType(payload.Format)
-		if payload.Format == "webp" {
+		switch payload.Format {
+		case "webp":
 			variantType = db.VariantTypeWebp
+		case "avif":
+			variantType = db.VariantTypeAvif
 		}
 		_, err = deps.Queries.CreateVariant(ctx, db.CreateVariantParams{
 			FileID:      file.ID,
@@ -871,6 +974,7 @@ func VideoThumbnailHandler(deps *Dependencies) func(context.Context, *job.Job) e
 		}
 		defer closeSafely(reader, "original video reader")
 		log.Debug("video downloaded", "duration_ms", time.Since(downloadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 10)
 
 		proc := deps.Registry.MustGet("video_thumbnail")
 
@@ -897,6 +1001,7 @@ func VideoThumbnailHandler(deps *Dependencies) func(context.Context, *job.Job) e
 			return middleware.Permanent(fmt.Errorf("failed to extract video thumbnail: %w", err))
 		}
 		log.Debug("video thumbnail extracted", "duration_ms", time.Since(processStart).Milliseconds(), "output_size", result.Size)
+		deps.reportProgress(ctx, payload.JobID, 60)
 
 		ext := "jpg"
 		if payload.Format == "png" {
@@ -911,6 +1016,7 @@ func VideoThumbnailHandler(deps *Dependencies) func(context.Context, *job.Job) e
 			return fmt.Errorf("failed to upload variant: %w", err)
 		}
 		log.Debug("variant uploaded", "duration_ms", time.Since(uploadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 90)
 
 		width := int32(result.Metadata.Width)
 		height := int32(result.Metadata.Height)
@@ -944,6 +1050,245 @@ func VideoThumbnailHandler(deps *Dependencies) func(context.Context, *job.Job) e
 	}
 }
 
+func VideoStoryboardHandler(deps *Dependencies) func(context.Context, *job.Job) error {
+	return func(ctx context.Context, j *job.Job) error {
+		log := logger.FromContext(ctx).With("job_id", j.ID, "job_type", "video_storyboard")
+		log.Info("job started")
+		start := time.Now()
+
+		var payload VideoStoryboardPayload
+		if err := j.UnmarshalPayload(&payload); err != nil {
+			log.Error("invalid payload", "error", err)
+			return middleware.Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+
+		deps.markJobRunning(ctx, payload.JobID)
+		log = log.With("file_id", payload.FileID.String())
+
+		fileID := pgtype.UUID{
+			Bytes: payload.FileID,
+			Valid: true,
+		}
+
+		file, err := deps.Queries.GetFile(ctx, fileID)
+		if err != nil {
+			log.Error("failed to retrieve file", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to retrieve file: %w", err)
+		}
+
+		log.Debug("downloading video from storage", "storage_key", file.StorageKey)
+		downloadStart := time.Now()
+		reader, err := deps.Storage.Download(ctx, file.StorageKey)
+		if err != nil {
+			log.Error("failed to download file", "storage_key", file.StorageKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to download file %s: %w", file.StorageKey, err)
+		}
+		defer closeSafely(reader, "original video reader")
+		log.Debug("video downloaded", "duration_ms", time.Since(downloadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 10)
+
+		proc := deps.Registry.MustGet("video_thumbnail")
+		thumbProc, ok := proc.(*video.ThumbnailProcessor)
+		if !ok {
+			log.Error("video_thumbnail processor is not ThumbnailProcessor")
+			deps.markJobFailed(ctx, payload.JobID, "invalid processor type")
+			return middleware.Permanent(fmt.Errorf("invalid processor type"))
+		}
+
+		log.Debug("generating storyboard", "columns", payload.Columns, "rows", payload.Rows, "width", payload.Width, "height", payload.Height)
+		processStart := time.Now()
+		result, err := thumbProc.GenerateThumbnailSprite(ctx, reader, payload.Columns, payload.Rows, payload.Width, payload.Height)
+		if err != nil {
+			log.Error("failed to generate storyboard", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return middleware.Permanent(fmt.Errorf("failed to generate storyboard: %w", err))
+		}
+		log.Debug("storyboard generated", "duration_ms", time.Since(processStart).Milliseconds(), "output_size", result.Size)
+		deps.reportProgress(ctx, payload.JobID, 60)
+
+		spriteKey := buildVariantKey(payload.FileID, "video_sprite", video.StoryboardSprite)
+		log.Debug("uploading sprite sheet", "storage_key", spriteKey)
+		if err := deps.Storage.Upload(ctx, spriteKey, result.Data, result.ContentType, result.Size); err != nil {
+			log.Error("failed to upload sprite sheet", "storage_key", spriteKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to upload sprite sheet: %w", err)
+		}
+
+		width := int32(result.Metadata.Width)
+		height := int32(result.Metadata.Height)
+		_, err = deps.Queries.CreateVariant(ctx, db.CreateVariantParams{
+			FileID:      file.ID,
+			VariantType: db.VariantTypeVideoSprite,
+			ContentType: result.ContentType,
+			SizeBytes:   result.Size,
+			StorageKey:  spriteKey,
+			Width:       &width,
+			Height:      &height,
+		})
+		if err != nil {
+			log.Error("failed to save variant record", "variant_type", "video_sprite", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to save variant record: %w", err)
+		}
+		deps.reportProgress(ctx, payload.JobID, 80)
+
+		// GenerateThumbnailSprite falls back to its own defaults for unset
+		// sizes, so derive the tile size from the sheet it produced.
+		columns, rows := payload.Columns, payload.Rows
+		if columns <= 0 {
+			columns = 10
+		}
+		if rows <= 0 {
+			rows = 10
+		}
+		vtt := video.BuildStoryboardVTT(video.StoryboardSprite, result.Metadata.Duration, columns, rows,
+			result.Metadata.Width/columns, result.Metadata.Height/rows)
+
+		vttKey := buildVariantKey(payload.FileID, "storyboard_vtt", video.StoryboardVTT)
+		log.Debug("uploading storyboard track", "storage_key", vttKey)
+		if err := deps.Storage.Upload(ctx, vttKey, bytes.NewReader(vtt), "text/vtt", int64(len(vtt))); err != nil {
+			log.Error("failed to upload storyboard track", "storage_key", vttKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to upload storyboard track: %w", err)
+		}
+
+		_, err = deps.Queries.CreateVariant(ctx, db.CreateVariantParams{
+			FileID:      file.ID,
+			VariantType: db.VariantTypeStoryboardVtt,
+			ContentType: "text/vtt",
+			SizeBytes:   int64(len(vtt)),
+			StorageKey:  vttKey,
+		})
+		if err != nil {
+			log.Error("failed to save variant record", "variant_type", "storyboard_vtt", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to save variant record: %w", err)
+		}
+
+		if err := deps.Queries.UpdateFileStatus(ctx, db.UpdateFileStatusParams{
+			ID:     file.ID,
+			Status: db.FileStatusCompleted,
+		}); err != nil {
+			log.Error("failed to update file status", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to update file status: %w", err)
+		}
+
+		deps.markJobCompleted(ctx, payload.JobID)
+		log.Info("job completed", "duration_ms", time.Since(start).Milliseconds(), "sprite_width", width, "sprite_height", height, "duration_seconds", result.Metadata.Duration)
+		return nil
+	}
+}
+
+func VideoPreviewHandler(deps *Dependencies) func(context.Context, *job.Job) error {
+	return func(ctx context.Context, j *job.Job) error {
+		log := logger.FromContext(ctx).With("job_id", j.ID, "job_type", "video_preview")
+		log.Info("job started")
+		start := time.Now()
+
+		var payload VideoPreviewPayload
+		if err := j.UnmarshalPayload(&payload); err != nil {
+			log.Error("invalid payload", "error", err)
+			return middleware.Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+
+		deps.markJobRunning(ctx, payload.JobID)
+		log = log.With("file_id", payload.FileID.String())
+
+		fileID := pgtype.UUID{
+			Bytes: payload.FileID,
+			Valid: true,
+		}
+
+		file, err := deps.Queries.GetFile(ctx, fileID)
+		if err != nil {
+			log.Error("failed to retrieve file", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to retrieve file: %w", err)
+		}
+
+		proc := deps.Registry.MustGet("video_transcode")
+		ffmpegProc, ok := proc.(*video.FFmpegProcessor)
+		if !ok {
+			log.Error("video_transcode processor is not FFmpegProcessor")
+			deps.markJobFailed(ctx, payload.JobID, "invalid processor type")
+			return middleware.Permanent(fmt.Errorf("invalid processor type"))
+		}
+
+		formats := payload.Formats
+		if len(formats) == 0 {
+			formats = []string{video.PreviewFormatMP4}
+		}
+		deps.reportProgress(ctx, payload.JobID, 10)
+
+		// Each format is cut from a fresh download so the source never has
+		// to be held in memory.
+		for i, format := range formats {
+			flog := log.With("format", format)
+
+			flog.Debug("downloading video from storage", "storage_key", file.StorageKey)
+			reader, err := deps.Storage.Download(ctx, file.StorageKey)
+			if err != nil {
+				flog.Error("failed to download file", "storage_key", file.StorageKey, "error", err)
+				deps.markJobFailed(ctx, payload.JobID, err.Error())
+				return fmt.Errorf("failed to download file %s: %w", file.StorageKey, err)
+			}
+
+			opts := &video.VideoOptions{
+				MaxResolution:          payload.MaxResolution,
+				PreviewFormat:          format,
+				PreviewWidth:           payload.Width,
+				PreviewSegments:        payload.Segments,
+				PreviewSegmentDuration: payload.SegmentDuration,
+				PreviewStart:           payload.Start,
+				PreviewEnd:             payload.End,
+			}
+
+			flog.Debug("generating preview", "width", payload.Width, "segments", payload.Segments, "segment_duration", payload.SegmentDuration, "max_resolution", payload.MaxResolution)
+			processStart := time.Now()
+			result, err := ffmpegProc.GeneratePreview(ctx, opts, reader)
+			closeSafely(reader, "original video reader")
+			if err != nil {
+				flog.Error("failed to generate preview", "error", err)
+				deps.markJobFailed(ctx, payload.JobID, err.Error())
+				return middleware.Permanent(fmt.Errorf("failed to generate %s preview: %w", format, err))
+			}
+			flog.Debug("preview generated", "duration_ms", time.Since(processStart).Milliseconds(), "output_size", result.Size)
+
+			variantKey := buildVariantKey(payload.FileID, string(previewVariantType(format)), result.Filename)
+			flog.Debug("uploading preview", "storage_key", variantKey)
+			if err := deps.Storage.Upload(ctx, variantKey, result.Data, result.ContentType, result.Size); err != nil {
+				flog.Error("failed to upload preview", "storage_key", variantKey, "error", err)
+				deps.markJobFailed(ctx, payload.JobID, err.Error())
+				return fmt.Errorf("failed to upload preview: %w", err)
+			}
+
+			if err := deps.replaceVideoVariant(ctx, previewVariantParams(file.ID, result, variantKey)); err != nil {
+				flog.Error("failed to save variant record", "error", err)
+				deps.markJobFailed(ctx, payload.JobID, err.Error())
+				return fmt.Errorf("failed to save variant record: %w", err)
+			}
+
+			deps.reportProgress(ctx, payload.JobID, 10+80*(i+1)/len(formats))
+		}
+
+		if err := deps.Queries.UpdateFileStatus(ctx, db.UpdateFileStatusParams{
+			ID:     file.ID,
+			Status: db.FileStatusCompleted,
+		}); err != nil {
+			log.Error("failed to update file status", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to update file status: %w", err)
+		}
+
+		deps.markJobCompleted(ctx, payload.JobID)
+		log.Info("job completed", "duration_ms", time.Since(start).Milliseconds(), "formats", formats)
+		return nil
+	}
+}
+
 func VideoTranscodeHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 	return func(ctx context.Context, j *job.Job) error {
 		log := logger.FromContext(ctx).With("job_id", j.ID, "job_type", "video_transcode")
@@ -981,6 +1326,7 @@ func VideoTranscodeHandler(deps *Dependencies) func(context.Context, *job.Job) e
 		}
 		defer closeSafely(reader, "original video reader")
 		log.Debug("video downloaded", "duration_ms", time.Since(downloadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 10)
 
 		proc := deps.Registry.MustGet("video_transcode")
 
@@ -1003,6 +1349,7 @@ func VideoTranscodeHandler(deps *Dependencies) func(context.Context, *job.Job) e
 			return middleware.Permanent(fmt.Errorf("failed to transcode video: %w", err))
 		}
 		log.Debug("video transcoded", "duration_ms", time.Since(processStart).Milliseconds(), "output_size", result.Size)
+		deps.reportProgress(ctx, payload.JobID, 60)
 
 		ext := payload.OutputFormat
 		if ext == "" {
@@ -1018,6 +1365,7 @@ func VideoTranscodeHandler(deps *Dependencies) func(context.Context, *job.Job) e
 			return fmt.Errorf("failed to upload variant: %w", err)
 		}
 		log.Debug("variant uploaded", "duration_ms", time.Since(uploadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 90)
 
 		width := int32(result.Metadata.Width)
 		height := int32(result.Metadata.Height)
@@ -1069,7 +1417,7 @@ func VideoTranscodeHandler(deps *Dependencies) func(context.Context, *job.Job) e
 		// Auto-delete original if user setting is enabled
 		userSettings, err := deps.Queries.GetUserSettings(ctx, file.UserID)
 		if err == nil && userSettings.AutoDeleteOriginals && file.StorageKey != "" {
-			if err := deps.Storage.Delete(ctx, file.StorageKey); err != nil {
+			if _, err := ReleaseStorageObject(ctx, deps.Queries, deps.Storage, file.ID, file.StorageKey); err != nil {
 				log.Warn("failed to delete original file", "error", err)
 			} else {
 				if err := deps.Queries.MarkOriginalDeleted(ctx, file.ID); err != nil {
@@ -1122,6 +1470,7 @@ func VideoHLSHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 		}
 		defer closeSafely(reader, "original video reader")
 		log.Debug("video downloaded", "duration_ms", time.Since(downloadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 10)
 
 		proc := deps.Registry.MustGet("video_transcode")
 		ffmpegProc, ok := proc.(*video.FFmpegProcessor)
@@ -1139,10 +1488,12 @@ func VideoHLSHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 		opts := &video.VideoOptions{
 			Preset:             "medium",
 			CRF:                23,
+			MaxResolution:      payload.MaxResolution,
 			HLSSegmentDuration: segmentDuration,
+			HLSResolutions:     payload.Resolutions,
 		}
 
-		log.Debug("generating HLS", "segment_duration", segmentDuration, "resolutions", payload.Resolutions)
+		log.Debug("generating HLS", "segment_duration", segmentDuration, "resolutions", payload.Resolutions, "max_resolution", payload.MaxResolution)
 		processStart := time.Now()
 		hlsResult, err := ffmpegProc.GenerateHLS(ctx, opts, reader)
 		if err != nil {
@@ -1151,52 +1502,177 @@ func VideoHLSHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 			return middleware.Permanent(fmt.Errorf("failed to generate HLS: %w", err))
 		}
 		defer func() { _ = os.RemoveAll(filepath.Dir(hlsResult.ManifestPath)) }()
-		log.Debug("HLS generated", "duration_ms", time.Since(processStart).Milliseconds(), "segments", hlsResult.SegmentCount)
+		log.Debug("HLS generated", "duration_ms", time.Since(processStart).Milliseconds(), "renditions", len(hlsResult.Renditions), "segments", hlsResult.SegmentCount)
+		deps.reportProgress(ctx, payload.JobID, 50)
+
+		// Renditions go first so the master playlist never references a
+		// rendition that is missing from storage.
+		uploaded, lastProgress := 0, 50
+		for _, rendition := range hlsResult.Renditions {
+			variantType := hlsVariantType(rendition)
+			files := append([]string{rendition.PlaylistPath}, rendition.SegmentPaths...)
+			for _, path := range files {
+				key := buildVariantKey(payload.FileID, variantType, filepath.Base(path))
+				if err := uploadHLSFile(ctx, deps.Storage, key, path); err != nil {
+					log.Error("failed to upload HLS file", "storage_key", key, "error", err)
+					deps.markJobFailed(ctx, payload.JobID, err.Error())
+					return err
+				}
+				uploaded++
+				if progress := 50 + 40*uploaded/(hlsResult.SegmentCount+len(hlsResult.Renditions)); progress != lastProgress {
+					deps.reportProgress(ctx, payload.JobID, progress)
+					lastProgress = progress
+				}
+			}
+
+			key := buildVariantKey(payload.FileID, variantType, video.HLSMediaPlaylist)
+			if err := deps.replaceVideoVariant(ctx, hlsRenditionVariantParams(file.ID, rendition, key, hlsResult.TotalDuration)); err != nil {
+				log.Error("failed to save variant record", "variant_type", variantType, "error", err)
+				deps.markJobFailed(ctx, payload.JobID, err.Error())
+				return fmt.Errorf("failed to save variant record: %w", err)
+			}
+		}
+
+		manifestKey := buildVariantKey(payload.FileID, "hls_master", video.HLSMasterPlaylist)
+		log.Debug("uploading master playlist", "storage_key", manifestKey)
+		if err := uploadHLSFile(ctx, deps.Storage, manifestKey, hlsResult.ManifestPath); err != nil {
+			log.Error("failed to upload master playlist", "storage_key", manifestKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return err
+		}
+
+		if err := deps.replaceVideoVariant(ctx, hlsMasterVariantParams(file.ID, hlsResult, manifestKey)); err != nil {
+			log.Error("failed to save variant record", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to save variant record: %w", err)
+		}
+
+		for _, variantType := range staleHLSVariantTypes(hlsResult) {
+			if err := deps.Queries.DeleteVariantsByType(ctx, db.DeleteVariantsByTypeParams{
+				FileID:      file.ID,
+				VariantType: variantType,
+			}); err != nil {
+				log.Warn("failed to remove stale HLS rendition", "variant_type", variantType, "error", err)
+			}
+		}
 
-		manifestData, err := os.ReadFile(hlsResult.ManifestPath)
+		if err := deps.Queries.UpdateFileStatus(ctx, db.UpdateFileStatusParams{
+			ID:     file.ID,
+			Status: db.FileStatusCompleted,
+		}); err != nil {
+			log.Error("failed to update file status", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to update file status: %w", err)
+		}
+
+		deps.markJobCompleted(ctx, payload.JobID)
+		log.Info("job completed", "duration_ms", time.Since(start).Milliseconds(), "renditions", len(hlsResult.Renditions), "segments", hlsResult.SegmentCount, "duration_seconds", hlsResult.TotalDuration)
+		return nil
+	}
+}
+
+func VideoDASHHandler(deps *Dependencies) func(context.Context, *job.Job) error {
+	return func(ctx context.Context, j *job.Job) error {
+		log := logger.FromContext(ctx).With("job_id", j.ID, "job_type", "video_dash")
+		log.Info("job started")
+		start := time.Now()
+
+		var payload VideoDASHPayload
+		if err := j.UnmarshalPayload(&payload); err != nil {
+			log.Error("invalid payload", "error", err)
+			return middleware.Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+
+		deps.markJobRunning(ctx, payload.JobID)
+		log = log.With("file_id", payload.FileID.String())
+
+		fileID := pgtype.UUID{
+			Bytes: payload.FileID,
+			Valid: true,
+		}
+
+		file, err := deps.Queries.GetFile(ctx, fileID)
 		if err != nil {
-			log.Error("failed to read manifest", "error", err)
+			log.Error("failed to retrieve file", "error", err)
 			deps.markJobFailed(ctx, payload.JobID, err.Error())
-			return fmt.Errorf("failed to read manifest: %w", err)
+			return fmt.Errorf("failed to retrieve file: %w", err)
 		}
 
-		manifestKey := buildVariantKey(payload.FileID, "hls_master", "playlist.m3u8")
-		log.Debug("uploading manifest", "storage_key", manifestKey)
-		if err := deps.Storage.Upload(ctx, manifestKey, bytes.NewReader(manifestData), "application/x-mpegURL", int64(len(manifestData))); err != nil {
-			log.Error("failed to upload manifest", "storage_key", manifestKey, "error", err)
+		log.Debug("downloading video from storage", "storage_key", file.StorageKey)
+		downloadStart := time.Now()
+		reader, err := deps.Storage.Download(ctx, file.StorageKey)
+		if err != nil {
+			log.Error("failed to download file", "storage_key", file.StorageKey, "error", err)
 			deps.markJobFailed(ctx, payload.JobID, err.Error())
-			return fmt.Errorf("failed to upload manifest: %w", err)
+			return fmt.Errorf("failed to download file %s: %w", file.StorageKey, err)
+		}
+		defer closeSafely(reader, "original video reader")
+		log.Debug("video downloaded", "duration_ms", time.Since(downloadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 10)
+
+		proc := deps.Registry.MustGet("video_transcode")
+		ffmpegProc, ok := proc.(*video.FFmpegProcessor)
+		if !ok {
+			log.Error("video_transcode processor is not FFmpegProcessor")
+			deps.markJobFailed(ctx, payload.JobID, "invalid processor type")
+			return middleware.Permanent(fmt.Errorf("invalid processor type"))
+		}
+
+		segmentDuration := payload.SegmentDuration
+		if segmentDuration <= 0 {
+			segmentDuration = 10
+		}
+
+		opts := &video.VideoOptions{
+			Preset:              "medium",
+			CRF:                 23,
+			MaxResolution:       payload.MaxResolution,
+			DASHSegmentDuration: segmentDuration,
+			DASHResolutions:     payload.Resolutions,
 		}
 
-		for _, segPath := range hlsResult.SegmentPaths {
-			segData, err := os.ReadFile(segPath)
+		log.Debug("generating DASH", "segment_duration", segmentDuration, "resolutions", payload.Resolutions, "max_resolution", payload.MaxResolution)
+		processStart := time.Now()
+		dashResult, err := ffmpegProc.GenerateDASH(ctx, opts, reader)
+		if err != nil {
+			log.Error("failed to generate DASH", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return middleware.Permanent(fmt.Errorf("failed to generate DASH: %w", err))
+		}
+		defer func() { _ = os.RemoveAll(filepath.Dir(dashResult.ManifestPath)) }()
+		log.Debug("DASH generated", "duration_ms", time.Since(processStart).Milliseconds(), "resolutions", dashResult.Resolutions, "segments", dashResult.SegmentCount)
+		deps.reportProgress(ctx, payload.JobID, 50)
+
+		// Segments go first so the manifest never references a segment that
+		// is missing from storage.
+		var size int64
+		lastProgress := 50
+		for i, path := range dashResult.SegmentPaths {
+			key := buildVariantKey(payload.FileID, "dash_manifest", filepath.Base(path))
+			n, err := uploadDASHFile(ctx, deps.Storage, key, path)
 			if err != nil {
-				log.Error("failed to read segment", "path", segPath, "error", err)
+				log.Error("failed to upload DASH segment", "storage_key", key, "error", err)
 				deps.markJobFailed(ctx, payload.JobID, err.Error())
-				return fmt.Errorf("failed to read segment: %w", err)
+				return err
 			}
-			segName := filepath.Base(segPath)
-			segKey := buildVariantKey(payload.FileID, "hls_master", segName)
-			if err := deps.Storage.Upload(ctx, segKey, bytes.NewReader(segData), "video/mp2t", int64(len(segData))); err != nil {
-				log.Error("failed to upload segment", "storage_key", segKey, "error", err)
-				deps.markJobFailed(ctx, payload.JobID, err.Error())
-				return fmt.Errorf("failed to upload segment: %w", err)
+			size += n
+			if progress := 50 + 40*(i+1)/(dashResult.SegmentCount+1); progress != lastProgress {
+				deps.reportProgress(ctx, payload.JobID, progress)
+				lastProgress = progress
 			}
 		}
 
-		_, err = deps.Queries.CreateVideoVariant(ctx, db.CreateVideoVariantParams{
-			FileID:      file.ID,
-			VariantType: db.VariantType("hls_master"),
-			ContentType: "application/x-mpegURL",
-			SizeBytes:   int64(len(manifestData)),
-			StorageKey:  manifestKey,
-			DurationSeconds: pgtype.Numeric{
-				Int:   big.NewInt(int64(hlsResult.TotalDuration * 100)),
-				Exp:   -2,
-				Valid: true,
-			},
-		})
+		manifestKey := buildVariantKey(payload.FileID, "dash_manifest", video.DASHManifest)
+		log.Debug("uploading DASH manifest", "storage_key", manifestKey)
+		n, err := uploadDASHFile(ctx, deps.Storage, manifestKey, dashResult.ManifestPath)
 		if err != nil {
+			log.Error("failed to upload DASH manifest", "storage_key", manifestKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return err
+		}
+		size += n
+
+		if err := deps.replaceVideoVariant(ctx, dashManifestVariantParams(file.ID, dashResult, manifestKey, size)); err != nil {
 			log.Error("failed to save variant record", "error", err)
 			deps.markJobFailed(ctx, payload.JobID, err.Error())
 			return fmt.Errorf("failed to save variant record: %w", err)
@@ -1212,7 +1688,7 @@ func VideoHLSHandler(deps *Dependencies) func(context.Context, *job.Job) error {
 		}
 
 		deps.markJobCompleted(ctx, payload.JobID)
-		log.Info("job completed", "duration_ms", time.Since(start).Milliseconds(), "segments", hlsResult.SegmentCount, "duration_seconds", hlsResult.TotalDuration)
+		log.Info("job completed", "duration_ms", time.Since(start).Milliseconds(), "resolutions", dashResult.Resolutions, "segments", dashResult.SegmentCount, "duration_seconds", dashResult.TotalDuration)
 		return nil
 	}
 }
@@ -1254,6 +1730,7 @@ func VideoWatermarkHandler(deps *Dependencies) func(context.Context, *job.Job) e
 		}
 		defer closeSafely(reader, "original video reader")
 		log.Debug("video downloaded", "duration_ms", time.Since(downloadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 10)
 
 		proc := deps.Registry.MustGet("video_transcode")
 		ffmpegProc, ok := proc.(*video.FFmpegProcessor)
@@ -1285,6 +1762,7 @@ func VideoWatermarkHandler(deps *Dependencies) func(context.Context, *job.Job) e
 			return middleware.Permanent(fmt.Errorf("failed to add watermark: %w", err))
 		}
 		log.Debug("watermark added", "duration_ms", time.Since(processStart).Milliseconds(), "output_size", result.Size)
+		deps.reportProgress(ctx, payload.JobID, 60)
 
 		variantKey := buildVariantKey(payload.FileID, "video_watermarked", "video.mp4")
 		log.Debug("uploading variant", "storage_key", variantKey)
@@ -1295,6 +1773,7 @@ func VideoWatermarkHandler(deps *Dependencies) func(context.Context, *job.Job) e
 			return fmt.Errorf("failed to upload variant: %w", err)
 		}
 		log.Debug("variant uploaded", "duration_ms", time.Since(uploadStart).Milliseconds())
+		deps.reportProgress(ctx, payload.JobID, 90)
 
 		width := int32(result.Metadata.Width)
 		height := int32(result.Metadata.Height)
@@ -1513,6 +1992,13 @@ func ZipDownloadHandler(deps *Dependencies) func(context.Context, *job.Job) erro
 			return fmt.Errorf("failed to update zip download record: %w", err)
 		}
 
+		if deps.WebhookDispatcher != nil {
+			event, err := webhook.NewZipReadyEvent(payload.ZipDownloadID.String(), len(validFiles), zipSize, downloadURL, expiresAt)
+			if err == nil {
+				deps.dispatchWebhook(ctx, pgtype.UUID{Bytes: payload.UserID, Valid: true}, event)
+			}
+		}
+
 		log.Info("job completed",
 			"duration_ms", time.Since(start).Milliseconds(),
 			"file_count", len(validFiles),
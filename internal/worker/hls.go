@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// hlsVariantType returns the variant type a rendition is stored under, e.g.
+// hls_720p.
+func hlsVariantType(r video.HLSRendition) string {
+	return "hls_" + r.Name
+}
+
+// hlsRenditionVariantTypes lists every rendition variant type on the ladder.
+var hlsRenditionVariantTypes = []db.VariantType{
+	db.VariantTypeHls360p,
+	db.VariantTypeHls480p,
+	db.VariantTypeHls720p,
+	db.VariantTypeHls1080p,
+}
+
+// staleHLSVariantTypes returns the rendition variant types that result did not
+// produce. Rows of these types are left over from an earlier run with a
+// different ladder and no longer appear in the master playlist.
+func staleHLSVariantTypes(result *video.HLSResult) []db.VariantType {
+	produced := make(map[db.VariantType]bool, len(result.Renditions))
+	for _, r := range result.Renditions {
+		produced[db.VariantType(hlsVariantType(r))] = true
+	}
+	var stale []db.VariantType
+	for _, t := range hlsRenditionVariantTypes {
+		if !produced[t] {
+			stale = append(stale, t)
+		}
+	}
+	return stale
+}
+
+func uploadHLSFile(ctx context.Context, store storage.Storage, key, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	contentType := "video/mp2t"
+	if strings.HasSuffix(path, ".m3u8") {
+		contentType = "application/x-mpegURL"
+	}
+	if err := store.Upload(ctx, key, bytes.NewReader(data), contentType, int64(len(data))); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func durationNumeric(seconds float64) pgtype.Numeric {
+	return pgtype.Numeric{
+		Int:   big.NewInt(int64(seconds * 100)),
+		Exp:   -2,
+		Valid: true,
+	}
+}
+
+// hlsRenditionVariantParams describes one rendition as a file variant whose
+// storage key is its media playlist. The size covers the playlist and all of
+// its segments.
+func hlsRenditionVariantParams(fileID pgtype.UUID, r video.HLSRendition, key string, duration float64) db.CreateVideoVariantParams {
+	width, height := int32(r.Width), int32(r.Height)
+	bitrate := r.AverageBandwidth
+	if bitrate <= 0 {
+		bitrate = r.Bandwidth
+	}
+	resolution := fmt.Sprintf("%dx%d", r.Width, r.Height)
+	videoCodec := "h264"
+	params := db.CreateVideoVariantParams{
+		FileID:          fileID,
+		VariantType:     db.VariantType(hlsVariantType(r)),
+		ContentType:     "application/x-mpegURL",
+		SizeBytes:       r.SizeBytes,
+		StorageKey:      key,
+		Width:           &width,
+		Height:          &height,
+		DurationSeconds: durationNumeric(duration),
+		BitrateBps:      &bitrate,
+		VideoCodec:      &videoCodec,
+		Resolution:      &resolution,
+	}
+	if strings.Contains(r.Codecs, "mp4a") {
+		audioCodec := "aac"
+		params.AudioCodec = &audioCodec
+	}
+	if r.FrameRate > 0 {
+		params.FrameRate = pgtype.Numeric{Int: big.NewInt(int64(r.FrameRate * 1000)), Exp: -3, Valid: true}
+	}
+	return params
+}
+
+// hlsMasterVariantParams describes the master playlist. Its dimensions are
+// those of the highest rendition it lists.
+func hlsMasterVariantParams(fileID pgtype.UUID, result *video.HLSResult, key string) db.CreateVideoVariantParams {
+	var size int64
+	if info, err := os.Stat(result.ManifestPath); err == nil {
+		size = info.Size()
+	}
+	params := db.CreateVideoVariantParams{
+		FileID:          fileID,
+		VariantType:     db.VariantTypeHlsMaster,
+		ContentType:     "application/x-mpegURL",
+		SizeBytes:       size,
+		StorageKey:      key,
+		DurationSeconds: durationNumeric(result.TotalDuration),
+	}
+	if n := len(result.Renditions); n > 0 {
+		top := result.Renditions[n-1]
+		width, height := int32(top.Width), int32(top.Height)
+		resolution := fmt.Sprintf("%dx%d", top.Width, top.Height)
+		params.Width, params.Height, params.Resolution = &width, &height, &resolution
+	}
+	return params
+}
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestHLSRenditionVariantParams(t *testing.T) {
+	fileID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	rendition := video.HLSRendition{
+		Name:             "720p",
+		Width:            1280,
+		Height:           720,
+		Bandwidth:        3400000,
+		AverageBandwidth: 2800000,
+		Codecs:           "avc1.4d401f,mp4a.40.2",
+		FrameRate:        29.97,
+		SizeBytes:        4096,
+	}
+
+	params := hlsRenditionVariantParams(fileID, rendition, "processed/x/hls_720p/playlist.m3u8", 12.5)
+	if params.VariantType != db.VariantTypeHls720p || params.StorageKey != "processed/x/hls_720p/playlist.m3u8" || params.SizeBytes != 4096 {
+		t.Errorf("params = %+v", params)
+	}
+	if *params.Width != 1280 || *params.Height != 720 || *params.Resolution != "1280x720" {
+		t.Errorf("dimensions = %dx%d (%s)", *params.Width, *params.Height, *params.Resolution)
+	}
+	if *params.BitrateBps != 2800000 {
+		t.Errorf("bitrate = %d, want the average bandwidth", *params.BitrateBps)
+	}
+	if *params.VideoCodec != "h264" || params.AudioCodec == nil || *params.AudioCodec != "aac" {
+		t.Errorf("codecs = %v / %v", params.VideoCodec, params.AudioCodec)
+	}
+	if d, _ := params.DurationSeconds.Float64Value(); d.Float64 != 12.5 {
+		t.Errorf("duration = %v, want 12.5", d.Float64)
+	}
+	if fps, _ := params.FrameRate.Float64Value(); fps.Float64 != 29.97 {
+		t.Errorf("frame rate = %v, want 29.97", fps.Float64)
+	}
+
+	silent := hlsRenditionVariantParams(fileID, video.HLSRendition{Name: "360p", Bandwidth: 900000, Codecs: "avc1.4d401e"}, "k", 1)
+	if silent.AudioCodec != nil || *silent.BitrateBps != 900000 || silent.FrameRate.Valid {
+		t.Errorf("silent params = %+v", silent)
+	}
+}
+
+func TestHLSMasterVariantParams(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, video.HLSMasterPlaylist)
+	if err := os.WriteFile(manifest, []byte("#EXTM3U\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	params := hlsMasterVariantParams(pgtype.UUID{Bytes: uuid.New(), Valid: true}, &video.HLSResult{
+		ManifestPath:  manifest,
+		TotalDuration: 30,
+		Renditions: []video.HLSRendition{
+			{Name: "360p", Width: 640, Height: 360},
+			{Name: "1080p", Width: 1920, Height: 1080},
+		},
+	}, "processed/x/hls_master/master.m3u8")
+
+	if params.VariantType != db.VariantTypeHlsMaster || params.SizeBytes != 8 {
+		t.Errorf("params = %+v", params)
+	}
+	if params.Resolution == nil || *params.Resolution != "1920x1080" {
+		t.Errorf("resolution = %v, want the highest rendition", params.Resolution)
+	}
+}
+
+func TestStaleHLSVariantTypes(t *testing.T) {
+	stale := staleHLSVariantTypes(&video.HLSResult{
+		Renditions: []video.HLSRendition{{Name: "360p"}, {Name: "720p"}},
+	})
+	want := []db.VariantType{db.VariantTypeHls480p, db.VariantTypeHls1080p}
+	if len(stale) != len(want) {
+		t.Fatalf("stale = %v, want %v", stale, want)
+	}
+	for i := range want {
+		if stale[i] != want[i] {
+			t.Errorf("stale = %v, want %v", stale, want)
+		}
+	}
+}
+
+func TestUploadHLSFile(t *testing.T) {
+	dir := t.TempDir()
+	store := storage.NewMemoryStorage()
+	for name, want := range map[string]string{
+		"playlist.m3u8":  "application/x-mpegURL",
+		"segment_000.ts": "video/mp2t",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := uploadHLSFile(context.Background(), store, "hls/"+name, path); err != nil {
+			t.Fatalf("uploadHLSFile(%s) error = %v", name, err)
+		}
+		if got, _ := store.GetContentType("hls/" + name); got != want {
+			t.Errorf("%s content type = %q, want %q", name, got, want)
+		}
+	}
+
+	if err := uploadHLSFile(context.Background(), store, "hls/missing.ts", filepath.Join(dir, "missing.ts")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	imgproc "github.com/abdul-hamid-achik/file.cheap/internal/processor/image"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FileMetadataParams converts extracted image metadata into the row stored
+// for a file. Empty strings are stored as NULL.
+func FileMetadataParams(fileID uuid.UUID, meta *imgproc.ImageMetadata) db.UpsertFileMetadataParams {
+	params := db.UpsertFileMetadataParams{
+		FileID:      pgtype.UUID{Bytes: fileID, Valid: true},
+		Width:       int32(meta.Width),
+		Height:      int32(meta.Height),
+		Format:      meta.Format,
+		CameraMake:  nullableString(meta.CameraMake),
+		CameraModel: nullableString(meta.CameraModel),
+		LensModel:   nullableString(meta.LensModel),
+		Orientation: int32(meta.Orientation),
+		IccProfile:  nullableString(meta.ICCProfile),
+		Caption:     nullableString(meta.Caption),
+		Keywords:    meta.Keywords,
+	}
+	if params.Orientation == 0 {
+		params.Orientation = 1
+	}
+	if params.Keywords == nil {
+		params.Keywords = []string{}
+	}
+	if meta.CapturedAt != nil {
+		params.CapturedAt = pgtype.Timestamptz{Time: *meta.CapturedAt, Valid: true}
+	}
+	if meta.GPS != nil {
+		params.GpsLatitude = &meta.GPS.Latitude
+		params.GpsLongitude = &meta.GPS.Longitude
+		params.GpsAltitude = meta.GPS.Altitude
+	}
+	return params
+}
+
+// withPlaceholder downloads the image at key and adds its blurhash and LQIP
+// to params, so responsive manifests never have to decode the original.
+func withPlaceholder(ctx context.Context, store storage.Storage, key string, params *db.UpsertFileMetadataParams) error {
+	reader, err := store.Download(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer closeSafely(reader, "placeholder reader")
+
+	placeholder, err := imgproc.GeneratePlaceholder(reader)
+	if err != nil {
+		return err
+	}
+	params.Blurhash = &placeholder.Blurhash
+	params.Lqip = &placeholder.LQIP
+	return nil
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	imgproc "github.com/abdul-hamid-achik/file.cheap/internal/processor/image"
+	"github.com/abdul-hamid-achik/file.cheap/internal/storage"
+	"github.com/google/uuid"
+)
+
+func TestFileMetadataParams(t *testing.T) {
+	fileID := uuid.New()
+	captured := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	altitude := 12.5
+
+	params := FileMetadataParams(fileID, &imgproc.ImageMetadata{
+		Width:       4000,
+		Height:      3000,
+		Format:      "jpeg",
+		CameraMake:  "FUJIFILM",
+		CapturedAt:  &captured,
+		GPS:         &imgproc.GPSInfo{Latitude: 35.6, Longitude: 139.7, Altitude: &altitude},
+		Orientation: 8,
+		Caption:     "Shibuya",
+	})
+
+	if params.FileID.Bytes != fileID || params.Width != 4000 || params.Orientation != 8 {
+		t.Errorf("params = %+v", params)
+	}
+	if params.CameraMake == nil || *params.CameraMake != "FUJIFILM" || params.CameraModel != nil {
+		t.Errorf("camera = %v %v, want empty strings stored as NULL", params.CameraMake, params.CameraModel)
+	}
+	if !params.CapturedAt.Valid || !params.CapturedAt.Time.Equal(captured) {
+		t.Errorf("captured_at = %v", params.CapturedAt)
+	}
+	if params.GpsLatitude == nil || *params.GpsLatitude != 35.6 || params.GpsAltitude == nil || *params.GpsAltitude != altitude {
+		t.Errorf("gps = %v, %v", params.GpsLatitude, params.GpsAltitude)
+	}
+	if params.Keywords == nil {
+		t.Error("keywords should be an empty array, not NULL")
+	}
+}
+
+func TestFileMetadataParams_Defaults(t *testing.T) {
+	params := FileMetadataParams(uuid.New(), &imgproc.ImageMetadata{Width: 1, Height: 1, Format: "gif"})
+	if params.Orientation != 1 || params.CapturedAt.Valid || params.GpsLatitude != nil {
+		t.Errorf("params = %+v, want orientation 1 and no capture data", params)
+	}
+}
+
+func TestWithPlaceholder(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 160, 90))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	_ = store.Upload(ctx, "uploads/photo.png", &buf, "image/png", int64(buf.Len()))
+
+	params := FileMetadataParams(uuid.New(), &imgproc.ImageMetadata{Width: 160, Height: 90, Format: "png"})
+	if err := withPlaceholder(ctx, store, "uploads/photo.png", &params); err != nil {
+		t.Fatalf("withPlaceholder() error = %v", err)
+	}
+	if params.Blurhash == nil || len(*params.Blurhash) != 28 {
+		t.Errorf("blurhash = %v, want a 4x3 hash", params.Blurhash)
+	}
+	if params.Lqip == nil || !strings.HasPrefix(*params.Lqip, "data:image/jpeg;base64,") {
+		t.Errorf("lqip = %v, want a JPEG data URI", params.Lqip)
+	}
+
+	params = FileMetadataParams(uuid.New(), &imgproc.ImageMetadata{})
+	if err := withPlaceholder(ctx, store, "uploads/missing.png", &params); err == nil || params.Blurhash != nil {
+		t.Errorf("withPlaceholder() on a missing object = %v, blurhash %v", err, params.Blurhash)
+	}
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"github.com/abdul-hamid-achik/file.cheap/internal/presets"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -247,6 +248,55 @@ func NewVideoThumbnailPayloadWithOptions(fileID uuid.UUID, width, height int, at
 	return p
 }
 
+// VideoStoryboardPayload builds a sprite sheet of Columns x Rows thumbnails
+// taken at equal intervals, and a WebVTT track that maps playback time to
+// each thumbnail for seek-bar previews.
+type VideoStoryboardPayload struct {
+	JobID   pgtype.UUID `json:"job_id,omitempty"`
+	FileID  uuid.UUID   `json:"file_id"`
+	Columns int         `json:"columns"`
+	Rows    int         `json:"rows"`
+	Width   int         `json:"width"`  // thumbnail width
+	Height  int         `json:"height"` // thumbnail height
+}
+
+func NewVideoStoryboardPayload(fileID uuid.UUID) VideoStoryboardPayload {
+	return VideoStoryboardPayload{
+		FileID:  fileID,
+		Columns: 10,
+		Rows:    10,
+		Width:   160,
+		Height:  90,
+	}
+}
+
+// VideoPreviewPayload cuts Segments clips of SegmentDuration seconds spread
+// across the video, or across Start to End when End is set, into a muted
+// autoplay preview in each of Formats. MaxResolution carries the plan's
+// height limit so previews never exceed what the user could transcode.
+type VideoPreviewPayload struct {
+	JobID           pgtype.UUID `json:"job_id,omitempty"`
+	FileID          uuid.UUID   `json:"file_id"`
+	Formats         []string    `json:"formats"`          // mp4, webp, gif
+	Width           int         `json:"width"`            // output width
+	Segments        int         `json:"segments"`         // number of clips
+	SegmentDuration float64     `json:"segment_duration"` // seconds per clip
+	Start           float64     `json:"start"`            // range start in seconds
+	End             float64     `json:"end"`              // range end in seconds, 0 for the end of the video
+	MaxResolution   int         `json:"max_resolution"`   // max height in pixels
+}
+
+func NewVideoPreviewPayload(fileID uuid.UUID, maxResolution int) VideoPreviewPayload {
+	return VideoPreviewPayload{
+		FileID:          fileID,
+		Formats:         []string{"mp4"},
+		Width:           320,
+		Segments:        3,
+		SegmentDuration: 2,
+		MaxResolution:   maxResolution,
+	}
+}
+
 type VideoTranscodePayload struct {
 	JobID         pgtype.UUID `json:"job_id,omitempty"`
 	FileID        uuid.UUID   `json:"file_id"`
@@ -273,6 +323,7 @@ type VideoHLSPayload struct {
 	FileID          uuid.UUID   `json:"file_id"`
 	SegmentDuration int         `json:"segment_duration"` // seconds per segment
 	Resolutions     []int       `json:"resolutions"`      // [360, 480, 720, 1080]
+	MaxResolution   int         `json:"max_resolution"`   // plan cap in pixels, 0 for the worker default
 }
 
 func NewVideoHLSPayload(fileID uuid.UUID, resolutions []int) VideoHLSPayload {
@@ -286,6 +337,25 @@ func NewVideoHLSPayload(fileID uuid.UUID, resolutions []int) VideoHLSPayload {
 	}
 }
 
+type VideoDASHPayload struct {
+	JobID           pgtype.UUID `json:"job_id,omitempty"`
+	FileID          uuid.UUID   `json:"file_id"`
+	SegmentDuration int         `json:"segment_duration"` // seconds per segment
+	Resolutions     []int       `json:"resolutions"`      // [360, 480, 720, 1080]
+	MaxResolution   int         `json:"max_resolution"`   // plan cap in pixels, 0 for the worker default
+}
+
+func NewVideoDASHPayload(fileID uuid.UUID, resolutions []int) VideoDASHPayload {
+	if len(resolutions) == 0 {
+		resolutions = []int{360, 720}
+	}
+	return VideoDASHPayload{
+		FileID:          fileID,
+		SegmentDuration: 10,
+		Resolutions:     resolutions,
+	}
+}
+
 type VideoWatermarkPayload struct {
 	JobID     pgtype.UUID `json:"job_id,omitempty"`
 	FileID    uuid.UUID   `json:"file_id"`
@@ -295,6 +365,26 @@ type VideoWatermarkPayload struct {
 	IsPremium bool        `json:"is_premium"`
 }
 
+// TransformPayload runs an ordered chain of processors over a file. The
+// result is stored in the transform cache under CacheKey, where CDN requests
+// for the same transform string find it.
+type TransformPayload struct {
+	JobID      pgtype.UUID      `json:"job_id,omitempty"`
+	FileID     uuid.UUID        `json:"file_id"`
+	Transforms string           `json:"transforms"`
+	CacheKey   string           `json:"cache_key"`
+	Steps      []processor.Step `json:"steps"`
+}
+
+func NewTransformPayload(fileID uuid.UUID, transforms, cacheKey string, steps []processor.Step) TransformPayload {
+	return TransformPayload{
+		FileID:     fileID,
+		Transforms: transforms,
+		CacheKey:   cacheKey,
+		Steps:      steps,
+	}
+}
+
 // ZIP download payloads
 
 type ZipDownloadPayload struct {
@@ -310,3 +400,13 @@ func NewZipDownloadPayload(zipDownloadID, userID uuid.UUID, fileIDs []uuid.UUID)
 		FileIDs:       fileIDs,
 	}
 }
+
+// Webhook DLQ replay payloads
+
+type WebhookDLQReplayPayload struct {
+	ReplayID uuid.UUID `json:"replay_id"`
+}
+
+func NewWebhookDLQReplayPayload(replayID uuid.UUID) WebhookDLQReplayPayload {
+	return WebhookDLQReplayPayload{ReplayID: replayID}
+}
@@ -352,6 +352,54 @@ func TestNewVideoHLSPayload(t *testing.T) {
 	}
 }
 
+func TestNewVideoDASHPayload(t *testing.T) {
+	fileID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	payload := NewVideoDASHPayload(fileID, nil)
+
+	if payload.FileID != fileID {
+		t.Errorf("FileID = %v, want %v", payload.FileID, fileID)
+	}
+	if payload.SegmentDuration != 10 {
+		t.Errorf("SegmentDuration = %d, want 10", payload.SegmentDuration)
+	}
+	if len(payload.Resolutions) != 2 || payload.Resolutions[0] != 360 || payload.Resolutions[1] != 720 {
+		t.Errorf("Resolutions = %v, want [360 720]", payload.Resolutions)
+	}
+}
+
+func TestNewVideoStoryboardPayload(t *testing.T) {
+	fileID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	payload := NewVideoStoryboardPayload(fileID)
+
+	if payload.FileID != fileID {
+		t.Errorf("FileID = %v, want %v", payload.FileID, fileID)
+	}
+	if payload.Columns != 10 || payload.Rows != 10 {
+		t.Errorf("grid = %dx%d, want 10x10", payload.Columns, payload.Rows)
+	}
+	if payload.Width != 160 || payload.Height != 90 {
+		t.Errorf("thumbnail = %dx%d, want 160x90", payload.Width, payload.Height)
+	}
+}
+
+func TestNewVideoPreviewPayload(t *testing.T) {
+	fileID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	payload := NewVideoPreviewPayload(fileID, 480)
+
+	if payload.FileID != fileID {
+		t.Errorf("FileID = %v, want %v", payload.FileID, fileID)
+	}
+	if len(payload.Formats) != 1 || payload.Formats[0] != "mp4" {
+		t.Errorf("Formats = %v, want [mp4]", payload.Formats)
+	}
+	if payload.Width != 320 || payload.Segments != 3 || payload.SegmentDuration != 2 {
+		t.Errorf("preview = %dpx, %d x %.1fs, want 320px, 3 x 2.0s", payload.Width, payload.Segments, payload.SegmentDuration)
+	}
+	if payload.MaxResolution != 480 {
+		t.Errorf("MaxResolution = %d, want 480", payload.MaxResolution)
+	}
+}
+
 func TestVideoWatermarkPayloadFields(t *testing.T) {
 	fileID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
 	payload := VideoWatermarkPayload{
@@ -414,6 +462,9 @@ func TestGetFileID(t *testing.T) {
 		{"video_thumbnail", &VideoThumbnailPayload{FileID: fileID}},
 		{"video_transcode", &VideoTranscodePayload{FileID: fileID}},
 		{"video_hls", &VideoHLSPayload{FileID: fileID}},
+		{"video_dash", &VideoDASHPayload{FileID: fileID}},
+		{"video_storyboard", &VideoStoryboardPayload{FileID: fileID}},
+		{"video_preview", &VideoPreviewPayload{FileID: fileID}},
 		{"video_watermark", &VideoWatermarkPayload{FileID: fileID}},
 	}
 
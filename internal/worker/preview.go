@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"fmt"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor/video"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// previewCodecs names the codec recorded for each preview format.
+var previewCodecs = map[string]string{
+	video.PreviewFormatMP4:  "h264",
+	video.PreviewFormatWebP: "webp",
+	video.PreviewFormatGIF:  "gif",
+}
+
+// previewVariantTypes gives each preview format its own variant type, so
+// every format can be fetched on its own.
+var previewVariantTypes = map[string]db.VariantType{
+	video.PreviewFormatMP4:  db.VariantTypeVideoPreviewMp4,
+	video.PreviewFormatWebP: db.VariantTypeVideoPreviewWebp,
+	video.PreviewFormatGIF:  db.VariantTypeVideoPreviewGif,
+}
+
+// previewVariantType returns the variant type of a preview in format.
+func previewVariantType(format string) db.VariantType {
+	if variantType, ok := previewVariantTypes[format]; ok {
+		return variantType
+	}
+	return db.VariantTypeVideoPreviewMp4
+}
+
+// previewVariantParams describes one preview as the variant of its format.
+func previewVariantParams(fileID pgtype.UUID, result *processor.Result, key string) db.CreateVideoVariantParams {
+	width, height := int32(result.Metadata.Width), int32(result.Metadata.Height)
+	resolution := fmt.Sprintf("%dx%d", result.Metadata.Width, result.Metadata.Height)
+	params := db.CreateVideoVariantParams{
+		FileID:          fileID,
+		VariantType:     previewVariantType(result.Metadata.Format),
+		ContentType:     result.ContentType,
+		SizeBytes:       result.Size,
+		StorageKey:      key,
+		Width:           &width,
+		Height:          &height,
+		DurationSeconds: durationNumeric(result.Metadata.Duration),
+		Resolution:      &resolution,
+	}
+	if codec, ok := previewCodecs[result.Metadata.Format]; ok {
+		params.VideoCodec = &codec
+	}
+	return params
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/processor"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestPreviewVariantParams(t *testing.T) {
+	fileID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	result := &processor.Result{
+		ContentType: "image/webp",
+		Size:        2048,
+		Metadata: processor.ResultMetadata{
+			Width:    320,
+			Height:   180,
+			Duration: 6,
+			Format:   "webp",
+		},
+	}
+
+	params := previewVariantParams(fileID, result, "processed/x/video_preview_webp/preview.webp")
+	if params.VariantType != db.VariantTypeVideoPreviewWebp || params.ContentType != "image/webp" || params.SizeBytes != 2048 {
+		t.Errorf("params = %+v", params)
+	}
+	if *params.Width != 320 || *params.Height != 180 || *params.Resolution != "320x180" {
+		t.Errorf("dimensions = %dx%d (%s)", *params.Width, *params.Height, *params.Resolution)
+	}
+	if params.VideoCodec == nil || *params.VideoCodec != "webp" {
+		t.Errorf("codec = %v, want webp", params.VideoCodec)
+	}
+	if d, _ := params.DurationSeconds.Float64Value(); d.Float64 != 6 {
+		t.Errorf("duration = %v, want 6", d.Float64)
+	}
+}
+
+func TestPreviewVariantType(t *testing.T) {
+	seen := make(map[db.VariantType]string)
+	for format, want := range map[string]db.VariantType{
+		"mp4":  db.VariantTypeVideoPreviewMp4,
+		"webp": db.VariantTypeVideoPreviewWebp,
+		"gif":  db.VariantTypeVideoPreviewGif,
+	} {
+		got := previewVariantType(format)
+		if got != want {
+			t.Errorf("previewVariantType(%q) = %q, want %q", format, got, want)
+		}
+		if other, dup := seen[got]; dup {
+			t.Errorf("formats %q and %q share variant type %q", format, other, got)
+		}
+		seen[got] = format
+	}
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
+	"github.com/abdul-hamid-achik/job-queue/pkg/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TransformCacheKey returns the storage key of a cached transform result. The
+// CDN reads and writes the same layout.
+func TransformCacheKey(fileID, cacheKey, filename string) string {
+	return fmt.Sprintf("cache/%s/%s/%s", fileID, cacheKey, filename)
+}
+
+// TransformHandler runs a transform pipeline and stores the output in the
+// transform cache.
+func TransformHandler(deps *Dependencies) func(context.Context, *job.Job) error {
+	return func(ctx context.Context, j *job.Job) error {
+		log := logger.FromContext(ctx).With("job_id", j.ID, "job_type", "transform")
+		log.Info("job started")
+		start := time.Now()
+
+		var payload TransformPayload
+		if err := j.UnmarshalPayload(&payload); err != nil {
+			log.Error("invalid payload", "error", err)
+			return middleware.Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+		if len(payload.Steps) == 0 || payload.CacheKey == "" {
+			return middleware.Permanent(fmt.Errorf("transform payload requires steps and a cache key"))
+		}
+
+		deps.markJobRunning(ctx, payload.JobID)
+		log = log.With("file_id", payload.FileID.String(), "transforms", payload.Transforms)
+
+		fileID := pgtype.UUID{Bytes: payload.FileID, Valid: true}
+		file, err := deps.Queries.GetFile(ctx, fileID)
+		if err != nil {
+			log.Error("failed to retrieve file", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to retrieve file: %w", err)
+		}
+
+		reader, err := deps.Storage.Download(ctx, file.StorageKey)
+		if err != nil {
+			log.Error("failed to download file", "storage_key", file.StorageKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			deps.dispatchProcessingFailed(ctx, file.UserID, payload.FileID.String(), j.ID, "transform", err.Error())
+			return fmt.Errorf("failed to download file %s: %w", file.StorageKey, err)
+		}
+		defer closeSafely(reader, "original file reader")
+
+		log.Debug("running transform pipeline", "steps", len(payload.Steps))
+		result, err := deps.Registry.RunPipeline(ctx, payload.Steps, reader)
+		if err != nil {
+			log.Error("failed to run transform pipeline", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			deps.dispatchProcessingFailed(ctx, file.UserID, payload.FileID.String(), j.ID, "transform", err.Error())
+			return middleware.Permanent(fmt.Errorf("failed to process transform: %w", err))
+		}
+
+		data, err := io.ReadAll(result.Data)
+		if err != nil {
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to read transform result: %w", err)
+		}
+
+		cacheKey := TransformCacheKey(payload.FileID.String(), payload.CacheKey, result.Filename)
+		if err := deps.Storage.Upload(ctx, cacheKey, bytes.NewReader(data), result.ContentType, int64(len(data))); err != nil {
+			log.Error("failed to upload transform result", "storage_key", cacheKey, "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to upload transform result: %w", err)
+		}
+
+		var width, height *int32
+		if result.Metadata.Width > 0 {
+			w := int32(result.Metadata.Width)
+			width = &w
+		}
+		if result.Metadata.Height > 0 {
+			h := int32(result.Metadata.Height)
+			height = &h
+		}
+
+		if _, err := deps.Queries.CreateTransformCache(ctx, db.CreateTransformCacheParams{
+			FileID:          file.ID,
+			CacheKey:        payload.CacheKey,
+			TransformParams: payload.Transforms,
+			StorageKey:      cacheKey,
+			ContentType:     result.ContentType,
+			SizeBytes:       int64(len(data)),
+			Width:           width,
+			Height:          height,
+		}); err != nil {
+			log.Error("failed to save transform cache record", "error", err)
+			deps.markJobFailed(ctx, payload.JobID, err.Error())
+			return fmt.Errorf("failed to save transform cache record: %w", err)
+		}
+
+		deps.markJobCompleted(ctx, payload.JobID)
+		durationMs := time.Since(start).Milliseconds()
+		deps.dispatchProcessingCompleted(ctx, file.UserID, payload.FileID.String(), j.ID, "transform", cacheKey, result.ContentType, int64(len(data)), durationMs)
+		log.Info("job completed", "duration_ms", durationMs, "steps", len(payload.Steps))
+		return nil
+	}
+}
@@ -45,6 +45,16 @@ func calculateBackoff(attempt int) time.Duration {
 type WebhookDependencies struct {
 	Queries    WebhookQuerier
 	HTTPClient *http.Client
+	// CircuitBreaker, when set, holds back deliveries to endpoints whose
+	// circuit is open and disables webhooks that keep failing.
+	CircuitBreaker *webhook.CircuitBreaker
+	// Notifier emails the owner when their webhook is disabled.
+	Notifier WebhookNotifier
+}
+
+// WebhookNotifier tells a user their webhook was disabled.
+type WebhookNotifier interface {
+	SendWebhookDisabledEmail(to, name, webhookURL string, failingSince time.Time) error
 }
 
 type WebhookQuerier interface {
@@ -55,6 +65,7 @@ type WebhookQuerier interface {
 	UpdateDeliveryRetry(ctx context.Context, arg db.UpdateDeliveryRetryParams) error
 	// DLQ support
 	CreateWebhookDLQEntry(ctx context.Context, arg db.CreateWebhookDLQEntryParams) (db.WebhookDlq, error)
+	GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error)
 }
 
 func WebhookDeliveryHandler(deps *WebhookDependencies) func(context.Context, *job.Job) error {
@@ -93,17 +104,49 @@ func WebhookDeliveryHandler(deps *WebhookDependencies) func(context.Context, *jo
 		webhookID := uuidToString(wh.ID)
 		log = log.With("webhook_id", webhookID, "webhook_url", wh.Url)
 
+		if !wh.Active {
+			log.Info("webhook disabled, moving delivery to dead letter queue")
+			deadLetterDelivery(ctx, deps, wh, delivery, int(delivery.Attempts), 0, "", "webhook disabled")
+			return middleware.Permanent(fmt.Errorf("webhook disabled"))
+		}
+		if deps.CircuitBreaker != nil {
+			allowed, err := deps.CircuitBreaker.Allow(ctx, wh.ID)
+			if err != nil {
+				log.Warn("failed to read circuit state", "error", err)
+			}
+			if !allowed {
+				log.Info("circuit open, moving delivery to dead letter queue")
+				deadLetterDelivery(ctx, deps, wh, delivery, int(delivery.Attempts), 0, "", "circuit open")
+				return middleware.Permanent(fmt.Errorf("circuit open for webhook %s", webhookID))
+			}
+		}
+
+		// The signature covers the body as sent, so receivers verify it the
+		// same way whatever the format.
+		body, header, err := webhook.EncodePayload(delivery.Payload, wh.Format)
+		if err != nil {
+			log.Error("failed to encode payload", "format", wh.Format, "error", err)
+			return middleware.Permanent(fmt.Errorf("failed to encode payload: %w", err))
+		}
+
 		timestamp := time.Now()
-		signature := webhook.GenerateSignature(delivery.Payload, wh.Secret, timestamp)
-		signatureHeader := webhook.BuildSignatureHeader(signature, timestamp)
+		signatures := []string{webhook.GenerateSignature(body, wh.Secret, timestamp)}
+		// During a secret rotation the previous secret signs as well, so
+		// receivers that have not switched yet keep verifying.
+		if wh.PreviousSecret != nil && wh.PreviousSecretExpiresAt.Valid && timestamp.Before(wh.PreviousSecretExpiresAt.Time) {
+			signatures = append(signatures, webhook.GenerateSignature(body, *wh.PreviousSecret, timestamp))
+		}
+		signatureHeader := webhook.BuildSignatureHeader(timestamp, signatures...)
 
-		req, err := http.NewRequestWithContext(ctx, "POST", wh.Url, bytes.NewReader(delivery.Payload))
+		req, err := http.NewRequestWithContext(ctx, "POST", wh.Url, bytes.NewReader(body))
 		if err != nil {
 			log.Error("failed to create request", "error", err)
 			return middleware.Permanent(fmt.Errorf("failed to create request: %w", err))
 		}
 
-		req.Header.Set("Content-Type", "application/json")
+		for key, values := range header {
+			req.Header[key] = values
+		}
 		req.Header.Set("X-Webhook-Signature", signatureHeader)
 		req.Header.Set("X-Webhook-ID", payload.DeliveryID)
 		req.Header.Set("User-Agent", "file.cheap-webhook/1.0")
@@ -137,6 +180,11 @@ func WebhookDeliveryHandler(deps *WebhookDependencies) func(context.Context, *jo
 			}); err != nil {
 				log.Error("failed to mark success", "error", err)
 			}
+			if deps.CircuitBreaker != nil {
+				if err := deps.CircuitBreaker.RecordSuccess(ctx, wh.ID); err != nil {
+					log.Warn("failed to reset circuit", "error", err)
+				}
+			}
 			metrics.RecordWebhookDelivery("success", deliveryDuration)
 			log.Info("webhook delivered successfully", "response_code", responseCode, "duration_seconds", deliveryDuration)
 			return nil
@@ -145,37 +193,23 @@ func WebhookDeliveryHandler(deps *WebhookDependencies) func(context.Context, *jo
 		newAttempts := int(delivery.Attempts) + 1
 		log.Warn("webhook delivery failed", "response_code", responseCode, "attempts", newAttempts, "max_retries", maxRetries)
 
-		if newAttempts >= maxRetries {
-			if err := deps.Queries.MarkDeliveryFailed(ctx, db.MarkDeliveryFailedParams{
-				ID:           pgDeliveryID,
-				ResponseCode: &responseCode,
-				ResponseBody: &responseBody,
-			}); err != nil {
-				log.Error("failed to mark failed", "error", err)
+		if deps.CircuitBreaker != nil {
+			disabled, err := deps.CircuitBreaker.RecordFailure(ctx, wh.ID)
+			if err != nil {
+				log.Warn("failed to record circuit failure", "error", err)
+			}
+			if disabled != nil {
+				log.Warn("webhook disabled after sustained failure", "failing_since", disabled.FailingSince.Time)
+				notifyWebhookDisabled(ctx, deps, *disabled)
 			}
+		}
 
-			// Add to Dead Letter Queue for visibility and manual retry
+		if newAttempts >= maxRetries {
 			finalError := fmt.Sprintf("max retries exceeded after %d attempts, last response: %d", newAttempts, responseCode)
 			if responseCode == 0 {
 				finalError = fmt.Sprintf("max retries exceeded after %d attempts, connection error: %s", newAttempts, responseBody)
 			}
-			dlqResponseCode := int32(responseCode)
-			_, dlqErr := deps.Queries.CreateWebhookDLQEntry(ctx, db.CreateWebhookDLQEntryParams{
-				WebhookID:        wh.ID,
-				DeliveryID:       pgDeliveryID,
-				EventType:        delivery.EventType,
-				Payload:          delivery.Payload,
-				FinalError:       finalError,
-				Attempts:         int32(newAttempts),
-				LastResponseCode: &dlqResponseCode,
-				LastResponseBody: &responseBody,
-			})
-			if dlqErr != nil {
-				log.Error("failed to add to DLQ", "error", dlqErr)
-			} else {
-				log.Warn("webhook delivery added to dead letter queue", "dlq_error", finalError)
-				metrics.RecordWebhookDLQ()
-			}
+			deadLetterDelivery(ctx, deps, wh, delivery, newAttempts, responseCode, responseBody, finalError)
 
 			metrics.RecordWebhookDelivery("failed", deliveryDuration)
 			log.Warn("webhook delivery permanently failed after max retries", "attempts", newAttempts)
@@ -201,6 +235,55 @@ func WebhookDeliveryHandler(deps *WebhookDependencies) func(context.Context, *jo
 	}
 }
 
+// deadLetterDelivery marks a delivery failed and adds it to the dead letter
+// queue for visibility and manual retry.
+func deadLetterDelivery(ctx context.Context, deps *WebhookDependencies, wh db.Webhook, delivery db.WebhookDelivery, attempts int, responseCode int32, responseBody, finalError string) {
+	log := logger.FromContext(ctx)
+
+	if err := deps.Queries.MarkDeliveryFailed(ctx, db.MarkDeliveryFailedParams{
+		ID:           delivery.ID,
+		ResponseCode: &responseCode,
+		ResponseBody: &responseBody,
+	}); err != nil {
+		log.Error("failed to mark failed", "error", err)
+	}
+
+	_, err := deps.Queries.CreateWebhookDLQEntry(ctx, db.CreateWebhookDLQEntryParams{
+		WebhookID:        wh.ID,
+		DeliveryID:       delivery.ID,
+		EventType:        delivery.EventType,
+		Payload:          delivery.Payload,
+		FinalError:       finalError,
+		Attempts:         int32(attempts),
+		LastResponseCode: &responseCode,
+		LastResponseBody: &responseBody,
+	})
+	if err != nil {
+		log.Error("failed to add to DLQ", "error", err)
+		return
+	}
+	log.Warn("webhook delivery added to dead letter queue", "dlq_error", finalError)
+	metrics.RecordWebhookDLQ()
+}
+
+// notifyWebhookDisabled emails the owner of a webhook the circuit breaker
+// just disabled.
+func notifyWebhookDisabled(ctx context.Context, deps *WebhookDependencies, wh db.Webhook) {
+	if deps.Notifier == nil {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	user, err := deps.Queries.GetUserByID(ctx, wh.UserID)
+	if err != nil {
+		log.Warn("failed to load webhook owner", "error", err)
+		return
+	}
+	if err := deps.Notifier.SendWebhookDisabledEmail(user.Email, user.Name, wh.Url, wh.FailingSince.Time); err != nil {
+		log.Warn("failed to send webhook disabled email", "error", err)
+	}
+}
+
 func uuidToString(id pgtype.UUID) string {
 	if !id.Valid {
 		return ""
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/logger"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
+	"github.com/abdul-hamid-achik/job-queue/pkg/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// replayHandoff is how close to its deadline a replay job hands the rest of
+// the work to a fresh job instead of claiming another batch.
+const replayHandoff = 30 * time.Second
+
+type WebhookDLQReplayQuerier interface {
+	GetWebhookDLQReplayByID(ctx context.Context, id pgtype.UUID) (db.WebhookDlqReplay, error)
+	StartWebhookDLQReplay(ctx context.Context, id pgtype.UUID) error
+	ClaimWebhookDLQForReplay(ctx context.Context, arg db.ClaimWebhookDLQForReplayParams) ([]db.WebhookDlq, error)
+	ReleaseWebhookDLQEntry(ctx context.Context, id pgtype.UUID) error
+	CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error)
+	DeleteWebhookDelivery(ctx context.Context, id pgtype.UUID) error
+	UpdateWebhookDLQReplayProgress(ctx context.Context, arg db.UpdateWebhookDLQReplayProgressParams) error
+	CompleteWebhookDLQReplay(ctx context.Context, arg db.CompleteWebhookDLQReplayParams) error
+}
+
+type WebhookDLQReplayDependencies struct {
+	Queries WebhookDLQReplayQuerier
+	Broker  Broker
+
+	// interval is the throttle window; each window replays at most
+	// rate_per_second entries. Zero means one second.
+	interval time.Duration
+}
+
+// WebhookDLQReplayHandler re-enqueues the dead letter entries matching a
+// replay's filters, claiming rate_per_second entries each second. Claimed
+// entries are marked retried; entries that cannot be re-enqueued are released
+// back to the DLQ.
+func WebhookDLQReplayHandler(deps *WebhookDLQReplayDependencies) func(context.Context, *job.Job) error {
+	return func(ctx context.Context, j *job.Job) error {
+		log := logger.FromContext(ctx).With("job_id", j.ID, "job_type", "webhook_dlq_replay")
+		log.Info("job started")
+
+		var payload WebhookDLQReplayPayload
+		if err := j.UnmarshalPayload(&payload); err != nil {
+			log.Error("invalid payload", "error", err)
+			return middleware.Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+
+		replayID := pgtype.UUID{Bytes: payload.ReplayID, Valid: true}
+		log = log.With("replay_id", payload.ReplayID.String())
+
+		replay, err := deps.Queries.GetWebhookDLQReplayByID(ctx, replayID)
+		if err != nil {
+			log.Error("failed to get replay", "error", err)
+			return fmt.Errorf("failed to get replay: %w", err)
+		}
+		if replay.Status == "completed" || replay.Status == "failed" {
+			log.Info("replay already finished", "status", replay.Status)
+			return nil
+		}
+
+		if err := deps.Queries.StartWebhookDLQReplay(ctx, replayID); err != nil {
+			log.Warn("failed to mark replay running", "error", err)
+		}
+
+		interval := deps.interval
+		if interval == 0 {
+			interval = time.Second
+		}
+
+		replayed := int(replay.ReplayedEntries)
+		for {
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < replayHandoff {
+				if _, err := deps.Broker.Enqueue("webhook_dlq_replay", payload); err != nil {
+					return fmt.Errorf("failed to enqueue replay continuation: %w", err)
+				}
+				log.Info("replay handed off to a new job", "replayed", replayed)
+				return nil
+			}
+
+			windowStart := time.Now()
+			entries, err := deps.Queries.ClaimWebhookDLQForReplay(ctx, db.ClaimWebhookDLQForReplayParams{
+				ReplayID:  replayID,
+				BatchSize: replay.RatePerSecond,
+			})
+			if err != nil {
+				log.Error("failed to claim DLQ entries", "error", err)
+				return fmt.Errorf("failed to claim DLQ entries: %w", err)
+			}
+
+			if len(entries) == 0 {
+				if err := deps.Queries.CompleteWebhookDLQReplay(ctx, db.CompleteWebhookDLQReplayParams{
+					ID:     replayID,
+					Status: "completed",
+				}); err != nil {
+					return fmt.Errorf("failed to complete replay: %w", err)
+				}
+				log.Info("replay completed", "replayed", replayed)
+				return nil
+			}
+
+			batchReplayed := 0
+			var replayErr error
+			for i, entry := range entries {
+				if err := replayDLQEntry(ctx, deps, entry); err != nil {
+					replayErr = err
+					for _, unsent := range entries[i:] {
+						if err := deps.Queries.ReleaseWebhookDLQEntry(ctx, unsent.ID); err != nil {
+							log.Error("failed to release DLQ entry", "entry_id", uuidToString(unsent.ID), "error", err)
+						}
+					}
+					break
+				}
+				batchReplayed++
+			}
+			replayed += batchReplayed
+
+			progress := db.UpdateWebhookDLQReplayProgressParams{
+				Replayed: int32(batchReplayed),
+				ID:       replayID,
+			}
+			if replayErr != nil && isFinalAttempt(j) {
+				progress.Failed = max(replay.TotalEntries-int32(replayed), 0)
+			}
+			if err := deps.Queries.UpdateWebhookDLQReplayProgress(ctx, progress); err != nil {
+				log.Warn("failed to update replay progress", "error", err)
+			}
+
+			if replayErr != nil {
+				log.Error("failed to replay DLQ entry", "error", replayErr)
+				if isFinalAttempt(j) {
+					errMsg := replayErr.Error()
+					_ = deps.Queries.CompleteWebhookDLQReplay(ctx, db.CompleteWebhookDLQReplayParams{
+						ID:           replayID,
+						Status:       "failed",
+						ErrorMessage: &errMsg,
+					})
+				}
+				return fmt.Errorf("failed to replay DLQ entry: %w", replayErr)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval - time.Since(windowStart)):
+			}
+		}
+	}
+}
+
+// replayDLQEntry creates a fresh delivery for a dead letter entry and
+// enqueues it, mirroring a single-entry retry. If the enqueue fails the
+// delivery is removed again, since nothing would ever pick it up and the
+// entry goes back to the DLQ.
+func replayDLQEntry(ctx context.Context, deps *WebhookDLQReplayDependencies, entry db.WebhookDlq) error {
+	delivery, err := deps.Queries.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		WebhookID: entry.WebhookID,
+		EventType: entry.EventType,
+		Payload:   entry.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("create delivery: %w", err)
+	}
+
+	if _, err := deps.Broker.Enqueue("webhook_delivery", webhook.DeliveryPayload{
+		DeliveryID: uuidToString(delivery.ID),
+	}); err != nil {
+		if delErr := deps.Queries.DeleteWebhookDelivery(ctx, delivery.ID); delErr != nil {
+			logger.FromContext(ctx).Warn("failed to remove unqueued delivery", "delivery_id", uuidToString(delivery.ID), "error", delErr)
+		}
+		return fmt.Errorf("enqueue delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// mockReplayQuerier holds one replay and the DLQ entries it matches.
+type mockReplayQuerier struct {
+	replay     db.WebhookDlqReplay
+	entries    []db.WebhookDlq
+	batchSizes []int32
+	released   []pgtype.UUID
+	deliveries int
+	deleted    []pgtype.UUID
+}
+
+func (m *mockReplayQuerier) GetWebhookDLQReplayByID(ctx context.Context, id pgtype.UUID) (db.WebhookDlqReplay, error) {
+	return m.replay, nil
+}
+
+func (m *mockReplayQuerier) StartWebhookDLQReplay(ctx context.Context, id pgtype.UUID) error {
+	m.replay.Status = "running"
+	return nil
+}
+
+func (m *mockReplayQuerier) ClaimWebhookDLQForReplay(ctx context.Context, arg db.ClaimWebhookDLQForReplayParams) ([]db.WebhookDlq, error) {
+	m.batchSizes = append(m.batchSizes, arg.BatchSize)
+	var claimed []db.WebhookDlq
+	for i := range m.entries {
+		if len(claimed) == int(arg.BatchSize) {
+			break
+		}
+		if m.entries[i].CanRetry {
+			m.entries[i].CanRetry = false
+			m.entries[i].RetriedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			claimed = append(claimed, m.entries[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (m *mockReplayQuerier) ReleaseWebhookDLQEntry(ctx context.Context, id pgtype.UUID) error {
+	m.released = append(m.released, id)
+	for i := range m.entries {
+		if m.entries[i].ID == id {
+			m.entries[i].CanRetry = true
+			m.entries[i].RetriedAt = pgtype.Timestamptz{}
+		}
+	}
+	return nil
+}
+
+func (m *mockReplayQuerier) CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	m.deliveries++
+	return db.WebhookDelivery{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, WebhookID: arg.WebhookID}, nil
+}
+
+func (m *mockReplayQuerier) DeleteWebhookDelivery(ctx context.Context, id pgtype.UUID) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (m *mockReplayQuerier) UpdateWebhookDLQReplayProgress(ctx context.Context, arg db.UpdateWebhookDLQReplayProgressParams) error {
+	m.replay.ReplayedEntries += arg.Replayed
+	m.replay.FailedEntries += arg.Failed
+	return nil
+}
+
+func (m *mockReplayQuerier) CompleteWebhookDLQReplay(ctx context.Context, arg db.CompleteWebhookDLQReplayParams) error {
+	m.replay.Status = arg.Status
+	m.replay.ErrorMessage = arg.ErrorMessage
+	return nil
+}
+
+type mockReplayBroker struct {
+	jobs    []string
+	failAt  int
+	enqueue int
+}
+
+func (b *mockReplayBroker) Enqueue(jobType string, payload interface{}) (string, error) {
+	b.enqueue++
+	if b.failAt > 0 && b.enqueue >= b.failAt {
+		return "", errors.New("broker unavailable")
+	}
+	b.jobs = append(b.jobs, jobType)
+	return uuid.New().String(), nil
+}
+
+func newReplayFixture(entries int, rate int32) (*mockReplayQuerier, *job.Job) {
+	replayID := uuid.New()
+	q := &mockReplayQuerier{
+		replay: db.WebhookDlqReplay{
+			ID:            pgtype.UUID{Bytes: replayID, Valid: true},
+			Status:        "pending",
+			RatePerSecond: rate,
+			TotalEntries:  int32(entries),
+		},
+	}
+	for i := 0; i < entries; i++ {
+		q.entries = append(q.entries, db.WebhookDlq{
+			ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			WebhookID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			EventType: "file.uploaded",
+			Payload:   []byte(`{}`),
+			CanRetry:  true,
+		})
+	}
+	j, _ := job.New("webhook_dlq_replay", NewWebhookDLQReplayPayload(replayID))
+	return q, j
+}
+
+func TestWebhookDLQReplayHandler_ReplaysInThrottledBatches(t *testing.T) {
+	q, j := newReplayFixture(5, 2)
+	broker := &mockReplayBroker{}
+	handler := WebhookDLQReplayHandler(&WebhookDLQReplayDependencies{Queries: q, Broker: broker, interval: time.Millisecond})
+
+	if err := handler(context.Background(), j); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+
+	if q.replay.Status != "completed" {
+		t.Errorf("status = %q, want completed", q.replay.Status)
+	}
+	if q.replay.ReplayedEntries != 5 || q.replay.FailedEntries != 0 {
+		t.Errorf("replayed = %d, failed = %d; want 5, 0", q.replay.ReplayedEntries, q.replay.FailedEntries)
+	}
+	if len(q.batchSizes) != 4 {
+		t.Errorf("claimed %d batches, want 4 (2+2+1+empty)", len(q.batchSizes))
+	}
+	for _, size := range q.batchSizes {
+		if size != 2 {
+			t.Errorf("batch size = %d, want rate_per_second 2", size)
+		}
+	}
+	if len(broker.jobs) != 5 {
+		t.Errorf("enqueued %d deliveries, want 5", len(broker.jobs))
+	}
+	for _, e := range q.entries {
+		if e.CanRetry || !e.RetriedAt.Valid {
+			t.Errorf("entry %v not marked retried", e.ID)
+		}
+	}
+}
+
+func TestWebhookDLQReplayHandler_ReleasesEntriesOnEnqueueFailure(t *testing.T) {
+	q, j := newReplayFixture(3, 3)
+	broker := &mockReplayBroker{failAt: 2}
+	handler := WebhookDLQReplayHandler(&WebhookDLQReplayDependencies{Queries: q, Broker: broker, interval: time.Millisecond})
+
+	if err := handler(context.Background(), j); err == nil {
+		t.Fatal("expected error when the broker fails")
+	}
+
+	if q.replay.ReplayedEntries != 1 {
+		t.Errorf("replayed = %d, want 1", q.replay.ReplayedEntries)
+	}
+	if len(q.released) != 2 {
+		t.Errorf("released %d entries, want 2", len(q.released))
+	}
+	if len(q.deleted) != 1 || q.deliveries-len(q.deleted) != 1 {
+		t.Errorf("created %d deliveries and deleted %d, want only the enqueued one kept", q.deliveries, len(q.deleted))
+	}
+	retryable := 0
+	for _, e := range q.entries {
+		if e.CanRetry {
+			retryable++
+		}
+	}
+	if retryable != 2 {
+		t.Errorf("%d entries retryable after failure, want 2", retryable)
+	}
+
+	j.RetryCount = j.MaxRetries - 1
+	if err := handler(context.Background(), j); err == nil {
+		t.Fatal("expected error on final attempt")
+	}
+	if q.replay.Status != "failed" || q.replay.ErrorMessage == nil {
+		t.Errorf("status = %q, error = %v; want failed with message", q.replay.Status, q.replay.ErrorMessage)
+	}
+	if q.replay.FailedEntries != 2 {
+		t.Errorf("failed = %d, want 2", q.replay.FailedEntries)
+	}
+}
+
+func TestWebhookDLQReplayHandler_HandsOffNearDeadline(t *testing.T) {
+	q, j := newReplayFixture(3, 1)
+	broker := &mockReplayBroker{}
+	handler := WebhookDLQReplayHandler(&WebhookDLQReplayDependencies{Queries: q, Broker: broker, interval: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), replayHandoff/2)
+	defer cancel()
+	if err := handler(ctx, j); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+
+	if len(broker.jobs) != 1 || broker.jobs[0] != "webhook_dlq_replay" {
+		t.Errorf("enqueued %v, want a single continuation job", broker.jobs)
+	}
+	if q.replay.Status != "running" {
+		t.Errorf("status = %q, want running", q.replay.Status)
+	}
+}
+
+func TestWebhookDLQReplayHandler_SkipsFinishedReplay(t *testing.T) {
+	q, j := newReplayFixture(2, 10)
+	q.replay.Status = "completed"
+	broker := &mockReplayBroker{}
+
+	if err := WebhookDLQReplayHandler(&WebhookDLQReplayDependencies{Queries: q, Broker: broker})(context.Background(), j); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+	if len(q.batchSizes) != 0 || len(broker.jobs) != 0 {
+		t.Error("finished replay should not claim or enqueue anything")
+	}
+}
@@ -0,0 +1,326 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/file.cheap/internal/db"
+	"github.com/abdul-hamid-achik/file.cheap/internal/webhook"
+	"github.com/abdul-hamid-achik/job-queue/pkg/job"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type mockWebhookQuerier struct {
+	delivery db.WebhookDelivery
+	webhook  db.Webhook
+	success  bool
+	dlq      []db.CreateWebhookDLQEntryParams
+}
+
+func (m *mockWebhookQuerier) GetWebhookDelivery(ctx context.Context, id pgtype.UUID) (db.WebhookDelivery, error) {
+	return m.delivery, nil
+}
+
+func (m *mockWebhookQuerier) GetWebhookByDeliveryID(ctx context.Context, id pgtype.UUID) (db.Webhook, error) {
+	return m.webhook, nil
+}
+
+func (m *mockWebhookQuerier) MarkDeliverySuccess(ctx context.Context, arg db.MarkDeliverySuccessParams) error {
+	m.success = true
+	return nil
+}
+
+func (m *mockWebhookQuerier) MarkDeliveryFailed(ctx context.Context, arg db.MarkDeliveryFailedParams) error {
+	return nil
+}
+
+func (m *mockWebhookQuerier) UpdateDeliveryRetry(ctx context.Context, arg db.UpdateDeliveryRetryParams) error {
+	return nil
+}
+
+func (m *mockWebhookQuerier) CreateWebhookDLQEntry(ctx context.Context, arg db.CreateWebhookDLQEntryParams) (db.WebhookDlq, error) {
+	m.dlq = append(m.dlq, arg)
+	return db.WebhookDlq{}, nil
+}
+
+func (m *mockWebhookQuerier) GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error) {
+	return db.User{ID: id, Email: "owner@example.com", Name: "Owner"}, nil
+}
+
+// Circuit breaker queries, applied to the single webhook.
+
+func (m *mockWebhookQuerier) GetWebhookCircuitState(ctx context.Context, id pgtype.UUID) (db.GetWebhookCircuitStateRow, error) {
+	return db.GetWebhookCircuitStateRow{ID: id, LastFailureAt: m.webhook.LastFailureAt, CircuitState: m.webhook.CircuitState}, nil
+}
+
+func (m *mockWebhookQuerier) HalfOpenWebhookCircuit(ctx context.Context, arg db.HalfOpenWebhookCircuitParams) error {
+	state := webhook.CircuitHalfOpen
+	m.webhook.CircuitState = &state
+	return nil
+}
+
+func (m *mockWebhookQuerier) IncrementWebhookFailures(ctx context.Context, arg db.IncrementWebhookFailuresParams) (db.IncrementWebhookFailuresRow, error) {
+	var failures int32 = 1
+	if m.webhook.ConsecutiveFailures != nil {
+		failures += *m.webhook.ConsecutiveFailures
+	}
+	m.webhook.ConsecutiveFailures = &failures
+	m.webhook.LastFailureAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	if !m.webhook.FailingSince.Valid {
+		m.webhook.FailingSince = m.webhook.LastFailureAt
+	}
+	if failures >= arg.FailureThreshold {
+		state := webhook.CircuitOpen
+		m.webhook.CircuitState = &state
+	}
+	return db.IncrementWebhookFailuresRow{
+		ConsecutiveFailures: m.webhook.ConsecutiveFailures,
+		CircuitState:        m.webhook.CircuitState,
+		FailingSince:        m.webhook.FailingSince,
+	}, nil
+}
+
+func (m *mockWebhookQuerier) ResetWebhookFailures(ctx context.Context, id pgtype.UUID) error {
+	state := webhook.CircuitClosed
+	m.webhook.CircuitState = &state
+	m.webhook.ConsecutiveFailures = nil
+	m.webhook.FailingSince = pgtype.Timestamptz{}
+	return nil
+}
+
+func (m *mockWebhookQuerier) DisableFailingWebhook(ctx context.Context, arg db.DisableFailingWebhookParams) (db.Webhook, error) {
+	m.webhook.Active = false
+	m.webhook.DisabledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return m.webhook, nil
+}
+
+type recordingNotifier struct {
+	to, webhookURL string
+}
+
+func (n *recordingNotifier) SendWebhookDisabledEmail(to, name, webhookURL string, failingSince time.Time) error {
+	n.to, n.webhookURL = to, webhookURL
+	return nil
+}
+
+func TestWebhookDeliveryHandler_Formats(t *testing.T) {
+	const secret = "whsec_test"
+	event, err := webhook.NewEvent(webhook.EventFileUploaded, map[string]string{"file_id": "f1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format          string
+		wantContentType string
+		wantCEHeader    bool
+		wantBody        string
+	}{
+		{webhook.FormatNative, "application/json", false, string(payload)},
+		{webhook.FormatCloudEventsStructured, "application/cloudevents+json; charset=utf-8", false, ""},
+		{webhook.FormatCloudEventsBinary, "application/json", true, `{"file_id":"f1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got *http.Request
+			var gotBody []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				gotBody, _ = io.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+
+			deliveryID := uuid.New()
+			q := &mockWebhookQuerier{
+				delivery: db.WebhookDelivery{ID: uuidToPgtype(deliveryID), EventType: event.Type, Payload: payload},
+				webhook:  db.Webhook{ID: uuidToPgtype(uuid.New()), Url: srv.URL, Secret: secret, Format: tt.format, Active: true},
+			}
+			j, err := job.New("webhook_delivery", webhook.DeliveryPayload{DeliveryID: deliveryID.String()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := WebhookDeliveryHandler(&WebhookDependencies{Queries: q})(context.Background(), j); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if !q.success {
+				t.Error("delivery not marked successful")
+			}
+
+			if ct := got.Header.Get("Content-Type"); ct != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.wantContentType)
+			}
+			if (got.Header.Get("ce-id") == event.ID) != tt.wantCEHeader {
+				t.Errorf("ce-id = %q, want present %v", got.Header.Get("ce-id"), tt.wantCEHeader)
+			}
+			if tt.wantBody != "" && string(gotBody) != tt.wantBody {
+				t.Errorf("body = %s, want %s", gotBody, tt.wantBody)
+			}
+			if got.Header.Get("X-Webhook-ID") != deliveryID.String() {
+				t.Errorf("X-Webhook-ID = %q", got.Header.Get("X-Webhook-ID"))
+			}
+
+			signatures, timestamp, err := webhook.ParseSignatureHeader(got.Header.Get("X-Webhook-Signature"))
+			if err != nil {
+				t.Fatalf("parse signature: %v", err)
+			}
+			if !webhook.VerifySignature(gotBody, signatures, secret, timestamp, time.Minute) {
+				t.Error("signature does not verify against the received body")
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryHandler_RotatedSecret(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"file.uploaded","created_at":"2026-03-01T12:00:00Z","data":{}}`)
+	previous := "whsec_old"
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		wantSigs  int
+	}{
+		{"within grace period", time.Now().Add(time.Hour), 2},
+		{"grace period over", time.Now().Add(-time.Hour), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header string
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Get("X-Webhook-Signature")
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+
+			deliveryID := uuid.New()
+			q := &mockWebhookQuerier{
+				delivery: db.WebhookDelivery{ID: uuidToPgtype(deliveryID), Payload: payload},
+				webhook: db.Webhook{
+					ID:                      uuidToPgtype(uuid.New()),
+					Url:                     srv.URL,
+					Secret:                  "whsec_new",
+					Format:                  webhook.FormatNative,
+					Active:                  true,
+					PreviousSecret:          &previous,
+					PreviousSecretExpiresAt: pgtype.Timestamptz{Time: tt.expiresAt, Valid: true},
+				},
+			}
+			j, err := job.New("webhook_delivery", webhook.DeliveryPayload{DeliveryID: deliveryID.String()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := WebhookDeliveryHandler(&WebhookDependencies{Queries: q})(context.Background(), j); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			signatures, timestamp, err := webhook.ParseSignatureHeader(header)
+			if err != nil {
+				t.Fatalf("parse signature: %v", err)
+			}
+			if len(signatures) != tt.wantSigs {
+				t.Fatalf("got %d signatures, want %d: %s", len(signatures), tt.wantSigs, header)
+			}
+			if !webhook.VerifySignature(body, signatures, "whsec_new", timestamp, time.Minute) {
+				t.Error("new secret does not verify")
+			}
+			oldVerifies := webhook.VerifySignature(body, signatures, previous, timestamp, time.Minute)
+			if oldVerifies != (tt.wantSigs == 2) {
+				t.Errorf("previous secret verifies = %v, want %v", oldVerifies, tt.wantSigs == 2)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryHandler_CircuitBreaker(t *testing.T) {
+	newDelivery := func(t *testing.T, url string) (*mockWebhookQuerier, *job.Job) {
+		t.Helper()
+		deliveryID := uuid.New()
+		q := &mockWebhookQuerier{
+			delivery: db.WebhookDelivery{ID: uuidToPgtype(deliveryID), EventType: webhook.EventFileUploaded, Payload: []byte(`{}`)},
+			webhook:  db.Webhook{ID: uuidToPgtype(uuid.New()), Url: url, Secret: "whsec_test", Format: webhook.FormatNative, Active: true},
+		}
+		j, err := job.New("webhook_delivery", webhook.DeliveryPayload{DeliveryID: deliveryID.String()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q, j
+	}
+
+	t.Run("open circuit skips the endpoint", func(t *testing.T) {
+		var hits int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+		defer srv.Close()
+
+		q, j := newDelivery(t, srv.URL)
+		open := webhook.CircuitOpen
+		q.webhook.CircuitState = &open
+		q.webhook.LastFailureAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		deps := &WebhookDependencies{Queries: q, CircuitBreaker: webhook.NewCircuitBreaker(q)}
+
+		if err := WebhookDeliveryHandler(deps)(context.Background(), j); err == nil {
+			t.Fatal("expected an error for an open circuit")
+		}
+		if hits != 0 {
+			t.Errorf("endpoint called %d times with the circuit open", hits)
+		}
+		if len(q.dlq) != 1 || q.dlq[0].FinalError != "circuit open" {
+			t.Errorf("dlq = %+v, want one circuit open entry", q.dlq)
+		}
+	})
+
+	t.Run("success closes the circuit", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		q, j := newDelivery(t, srv.URL)
+		half := webhook.CircuitHalfOpen
+		q.webhook.CircuitState = &half
+		deps := &WebhookDependencies{Queries: q, CircuitBreaker: webhook.NewCircuitBreaker(q)}
+
+		if err := WebhookDeliveryHandler(deps)(context.Background(), j); err != nil {
+			t.Fatalf("handler error = %v", err)
+		}
+		if got := webhook.CircuitState(q.webhook); got != webhook.CircuitClosed {
+			t.Errorf("circuit = %s, want closed", got)
+		}
+	})
+
+	t.Run("sustained failure disables and notifies", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		q, j := newDelivery(t, srv.URL)
+		q.webhook.FailingSince = pgtype.Timestamptz{Time: time.Now().Add(-4 * 24 * time.Hour), Valid: true}
+		notifier := &recordingNotifier{}
+		deps := &WebhookDependencies{
+			Queries:        q,
+			CircuitBreaker: webhook.NewCircuitBreakerWithConfig(q, 1, time.Minute, 72*time.Hour),
+			Notifier:       notifier,
+		}
+
+		if err := WebhookDeliveryHandler(deps)(context.Background(), j); err == nil {
+			t.Fatal("expected a retry error")
+		}
+		if q.webhook.Active {
+			t.Error("webhook still active")
+		}
+		if notifier.to != "owner@example.com" || notifier.webhookURL != srv.URL {
+			t.Errorf("notification = %+v", notifier)
+		}
+	})
+}
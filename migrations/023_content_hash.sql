@@ -0,0 +1,11 @@
+-- Content-addressed deduplication
+-- Adds a SHA-256 content hash to files so identical uploads can share one stored object
+
+ALTER TABLE files ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+-- Lookup of a user's existing file by hash on upload
+CREATE INDEX IF NOT EXISTS idx_files_user_content_hash ON files(user_id, content_hash)
+    WHERE content_hash IS NOT NULL AND deleted_at IS NULL;
+
+-- Reference counting of shared storage objects during cleanup
+CREATE INDEX IF NOT EXISTS idx_files_storage_key ON files(storage_key);
@@ -0,0 +1,4 @@
+-- Transform pipelines
+-- Jobs that run an ordered chain of processors and store the result in the transform cache
+
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'transform';
@@ -0,0 +1,5 @@
+-- AVIF output
+-- Variant type for AVIF conversions and a job type for format conversion jobs
+
+ALTER TYPE variant_type ADD VALUE IF NOT EXISTS 'avif';
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'convert';
@@ -0,0 +1,7 @@
+-- Batch progress tracking
+-- Records which of a batch item's jobs have finished so the worker can tell
+-- when an item, and then its batch, is done
+
+ALTER TABLE batch_items ADD COLUMN IF NOT EXISTS finished_job_ids TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_batch_items_job_ids ON batch_items USING GIN (job_ids);
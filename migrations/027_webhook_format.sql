@@ -0,0 +1,6 @@
+-- Webhook delivery format
+-- Lets each webhook receive events in the native envelope or as CloudEvents 1.0
+-- in structured or binary content mode
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT 'native'
+    CHECK (format IN ('native', 'cloudevents-structured', 'cloudevents-binary'));
@@ -0,0 +1,10 @@
+-- Webhook secret rotation
+-- Keeps the previous secret valid until previous_secret_expires_at so
+-- deliveries can be signed with both while receivers switch over. Secrets are
+-- "whsec_" plus 64 hex characters, which never fit the original VARCHAR(64).
+
+ALTER TABLE webhooks ALTER COLUMN secret TYPE TEXT;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS previous_secret TEXT;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS previous_secret_expires_at TIMESTAMPTZ;
+
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'webhook.rotate_secret';
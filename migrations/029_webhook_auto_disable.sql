@@ -0,0 +1,10 @@
+-- Webhook auto-disable
+-- failing_since marks the start of the current failure streak so the shared
+-- circuit breaker can disable endpoints that stay down; disabled_at records
+-- when that happened
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS failing_since TIMESTAMPTZ;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+UPDATE webhooks SET failing_since = last_failure_at
+WHERE consecutive_failures > 0 AND failing_since IS NULL;
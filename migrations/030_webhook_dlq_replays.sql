@@ -0,0 +1,23 @@
+-- Webhook DLQ bulk replay
+-- A replay re-enqueues the retryable dead letter entries matching its filters
+-- at a throttled rate and tracks progress so clients can poll it
+
+CREATE TABLE IF NOT EXISTS webhook_dlq_replays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    webhook_id UUID REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type TEXT,
+    created_after TIMESTAMPTZ,
+    created_before TIMESTAMPTZ NOT NULL,
+    rate_per_second INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    total_entries INTEGER NOT NULL DEFAULT 0,
+    replayed_entries INTEGER NOT NULL DEFAULT 0,
+    failed_entries INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_dlq_replays_user_id ON webhook_dlq_replays(user_id, created_at DESC);
@@ -0,0 +1,19 @@
+-- User-defined transform presets
+-- Named bundles of transform options that can be applied by name from the
+-- transform and batch APIs and from CDN URLs (preset_<name>)
+
+CREATE TABLE IF NOT EXISTS transform_presets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    fit TEXT,
+    position TEXT,
+    format TEXT,
+    quality INTEGER,
+    watermark TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
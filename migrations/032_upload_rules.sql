@@ -0,0 +1,19 @@
+-- Upload rules
+-- Per-user rules that queue extra processing jobs for new uploads matching a
+-- content type, folder, tag or filename pattern
+
+CREATE TABLE IF NOT EXISTS upload_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    content_type TEXT,
+    folder_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    tag TEXT,
+    filename_pattern TEXT,
+    jobs TEXT[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_rules_user_id ON upload_rules(user_id, created_at);
@@ -0,0 +1,23 @@
+-- File metadata
+-- Camera, capture time, GPS, orientation, ICC profile and IPTC/XMP caption
+-- and keywords extracted from images by the metadata job
+
+CREATE TABLE IF NOT EXISTS file_metadata (
+    file_id UUID PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    format TEXT NOT NULL,
+    camera_make TEXT,
+    camera_model TEXT,
+    lens_model TEXT,
+    captured_at TIMESTAMPTZ,
+    gps_latitude DOUBLE PRECISION,
+    gps_longitude DOUBLE PRECISION,
+    gps_altitude DOUBLE PRECISION,
+    orientation INTEGER NOT NULL DEFAULT 1,
+    icc_profile TEXT,
+    caption TEXT,
+    keywords TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
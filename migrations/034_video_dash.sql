@@ -0,0 +1,5 @@
+-- MPEG-DASH output
+-- Variant type for the DASH manifest and a job type for DASH packaging jobs
+
+ALTER TYPE variant_type ADD VALUE IF NOT EXISTS 'dash_manifest';
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'video_dash';
@@ -0,0 +1,6 @@
+-- Video storyboards
+-- Variant type for the WebVTT thumbnail track that indexes a video_sprite
+-- sheet, and a job type for storyboard jobs
+
+ALTER TYPE variant_type ADD VALUE IF NOT EXISTS 'storyboard_vtt';
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'video_storyboard';
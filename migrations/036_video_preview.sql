@@ -0,0 +1,8 @@
+-- Video previews
+-- Variant types for short muted MP4 and animated WebP/GIF previews cut from
+-- across a video, one per format, and a job type for preview jobs
+
+ALTER TYPE variant_type ADD VALUE IF NOT EXISTS 'video_preview_mp4';
+ALTER TYPE variant_type ADD VALUE IF NOT EXISTS 'video_preview_webp';
+ALTER TYPE variant_type ADD VALUE IF NOT EXISTS 'video_preview_gif';
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'video_preview';
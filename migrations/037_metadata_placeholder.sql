@@ -0,0 +1,6 @@
+-- Image placeholders
+-- Blurhash and LQIP computed by the metadata job, so responsive image
+-- manifests can be served without decoding the original
+
+ALTER TABLE file_metadata ADD COLUMN IF NOT EXISTS blurhash TEXT;
+ALTER TABLE file_metadata ADD COLUMN IF NOT EXISTS lqip TEXT;
@@ -55,3 +55,18 @@ SELECT
     COUNT(*) FILTER (WHERE status = 'completed') AS completed,
     COUNT(*) FILTER (WHERE status = 'failed') AS failed
 FROM batch_items WHERE batch_id = $1;
+
+-- name: RecordBatchJobFinished :one
+UPDATE batch_items
+SET finished_job_ids = array_append(finished_job_ids, sqlc.arg(job_id)::text),
+    error_message = COALESCE(error_message, sqlc.narg(error_message)),
+    status = 'processing'
+WHERE job_ids @> ARRAY[sqlc.arg(job_id)::text]
+  AND NOT finished_job_ids @> ARRAY[sqlc.arg(job_id)::text]
+RETURNING *;
+
+-- name: CompleteBatchOperation :one
+UPDATE batch_operations
+SET status = $2, completed_at = NOW()
+WHERE id = $1 AND completed_at IS NULL
+RETURNING *;
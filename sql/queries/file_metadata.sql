@@ -0,0 +1,31 @@
+-- name: GetFileMetadata :one
+SELECT * FROM file_metadata
+WHERE file_id = $1;
+
+-- name: UpsertFileMetadata :one
+INSERT INTO file_metadata (
+    file_id, width, height, format, camera_make, camera_model, lens_model,
+    captured_at, gps_latitude, gps_longitude, gps_altitude, orientation,
+    icc_profile, caption, keywords, blurhash, lqip
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+)
+ON CONFLICT (file_id) DO UPDATE SET
+    width = EXCLUDED.width,
+    height = EXCLUDED.height,
+    format = EXCLUDED.format,
+    camera_make = EXCLUDED.camera_make,
+    camera_model = EXCLUDED.camera_model,
+    lens_model = EXCLUDED.lens_model,
+    captured_at = EXCLUDED.captured_at,
+    gps_latitude = EXCLUDED.gps_latitude,
+    gps_longitude = EXCLUDED.gps_longitude,
+    gps_altitude = EXCLUDED.gps_altitude,
+    orientation = EXCLUDED.orientation,
+    icc_profile = EXCLUDED.icc_profile,
+    caption = EXCLUDED.caption,
+    keywords = EXCLUDED.keywords,
+    blurhash = COALESCE(EXCLUDED.blurhash, file_metadata.blurhash),
+    lqip = COALESCE(EXCLUDED.lqip, file_metadata.lqip),
+    updated_at = NOW()
+RETURNING *;
@@ -4,7 +4,7 @@ VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: GetFileShareByToken :one
-SELECT s.*, f.storage_key, f.content_type, f.user_id, f.filename
+SELECT s.*, f.storage_key, f.content_type, f.user_id, f.filename, f.size_bytes, f.updated_at
 FROM file_shares s
 JOIN files f ON f.id = s.file_id
 WHERE s.token = $1
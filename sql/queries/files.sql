@@ -19,9 +19,10 @@ INSERT INTO files (
     content_type,
     size_bytes,
     storage_key,
-    status
+    status,
+    content_hash
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
+    $1, $2, $3, $4, $5, $6, $7
 )
 RETURNING *;
 
@@ -47,8 +48,11 @@ LIMIT $2 OFFSET $3;
 
 -- name: GetUserStorageUsage :one
 SELECT COALESCE(SUM(size_bytes), 0)::bigint as total_bytes
-FROM files
-WHERE user_id = $1 AND deleted_at IS NULL;
+FROM (
+    SELECT DISTINCT ON (storage_key) size_bytes
+    FROM files
+    WHERE user_id = $1 AND deleted_at IS NULL AND storage_key <> ''
+) AS blobs;
 
 -- name: GetUserVideoStorageUsage :one
 SELECT COALESCE(SUM(size_bytes), 0)::bigint as total_bytes
@@ -80,6 +84,12 @@ UPDATE files
 SET storage_key = '', updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NULL;
 
+-- name: ReplaceFileContent :one
+UPDATE files
+SET storage_key = $2, size_bytes = $3, content_hash = $4, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
 -- name: SearchFilesByUser :many
 SELECT *, COUNT(*) OVER() AS total_count FROM files
 WHERE user_id = $1
@@ -91,3 +101,13 @@ WHERE user_id = $1
   AND ($6::text = '' OR status = $6::file_status)
 ORDER BY created_at DESC
 LIMIT $7 OFFSET $8;
+
+-- name: GetFileByContentHash :one
+SELECT * FROM files
+WHERE user_id = $1 AND content_hash = $2 AND storage_key <> '' AND deleted_at IS NULL
+ORDER BY created_at ASC
+LIMIT 1;
+
+-- name: CountStorageKeyReferences :one
+SELECT COUNT(*) FROM files
+WHERE storage_key = $1 AND id <> $2;
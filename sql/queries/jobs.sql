@@ -97,3 +97,9 @@ JOIN files f ON f.id = pj.file_id
 WHERE pj.id = $1
   AND f.user_id = $2
   AND f.deleted_at IS NULL;
+
+-- name: GetJobEventTarget :one
+SELECT pj.file_id, pj.job_type, f.user_id
+FROM processing_jobs pj
+JOIN files f ON f.id = pj.file_id
+WHERE pj.id = $1;
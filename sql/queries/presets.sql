@@ -0,0 +1,28 @@
+-- name: CreateTransformPreset :one
+INSERT INTO transform_presets (user_id, name, width, height, fit, position, format, quality, watermark)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetTransformPreset :one
+SELECT * FROM transform_presets
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetTransformPresetByName :one
+SELECT * FROM transform_presets
+WHERE user_id = $1 AND name = $2;
+
+-- name: ListTransformPresetsByUser :many
+SELECT * FROM transform_presets
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: UpdateTransformPreset :one
+UPDATE transform_presets
+SET name = $3, width = $4, height = $5, fit = $6, position = $7,
+    format = $8, quality = $9, watermark = $10, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteTransformPreset :exec
+DELETE FROM transform_presets
+WHERE id = $1 AND user_id = $2;
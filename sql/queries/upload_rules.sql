@@ -0,0 +1,29 @@
+-- name: CreateUploadRule :one
+INSERT INTO upload_rules (user_id, name, content_type, folder_id, tag, filename_pattern, jobs, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetUploadRule :one
+SELECT * FROM upload_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListUploadRulesByUser :many
+SELECT * FROM upload_rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ListEnabledUploadRulesByUser :many
+SELECT * FROM upload_rules
+WHERE user_id = $1 AND enabled = TRUE
+ORDER BY created_at;
+
+-- name: UpdateUploadRule :one
+UPDATE upload_rules
+SET name = $3, content_type = $4, folder_id = $5, tag = $6,
+    filename_pattern = $7, jobs = $8, enabled = $9, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteUploadRule :exec
+DELETE FROM upload_rules
+WHERE id = $1 AND user_id = $2;
@@ -142,8 +142,11 @@ WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: GetUserTotalStorageUsage :one
 SELECT COALESCE(SUM(size_bytes), 0)::bigint AS total_bytes
-FROM files
-WHERE user_id = $1 AND deleted_at IS NULL;
+FROM (
+    SELECT DISTINCT ON (storage_key) size_bytes
+    FROM files
+    WHERE user_id = $1 AND deleted_at IS NULL AND storage_key <> ''
+) AS blobs;
 
 -- name: UpdateUserStorageUsed :exec
 UPDATE users
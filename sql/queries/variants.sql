@@ -29,6 +29,10 @@ WHERE file_id = $1;
 DELETE FROM file_variants
 WHERE id = $1;
 
+-- name: DeleteVariantsByType :exec
+DELETE FROM file_variants
+WHERE file_id = $1 AND variant_type = $2;
+
 -- name: GetThumbnailsForFiles :many
 SELECT file_id, storage_key, content_type, size_bytes
 FROM file_variants
@@ -56,3 +56,79 @@ LIMIT $2;
 -- name: DeleteOldDLQEntries :exec
 DELETE FROM webhook_dlq
 WHERE created_at < NOW() - INTERVAL '30 days';
+
+-- name: CountReplayableWebhookDLQ :one
+SELECT COUNT(*)
+FROM webhook_dlq dlq
+JOIN webhooks w ON w.id = dlq.webhook_id
+WHERE w.user_id = @user_id
+  AND dlq.can_retry = true
+  AND (sqlc.narg(webhook_id)::uuid IS NULL OR dlq.webhook_id = sqlc.narg(webhook_id))
+  AND (sqlc.narg(event_type)::text IS NULL OR dlq.event_type = sqlc.narg(event_type))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR dlq.created_at >= sqlc.narg(created_after))
+  AND dlq.created_at < @created_before;
+
+-- name: ClaimWebhookDLQForReplay :many
+-- Claims up to batch_size retryable entries matching the replay's filters,
+-- marking them retried so concurrent replays never pick the same row.
+UPDATE webhook_dlq
+SET can_retry = false, retried_at = NOW()
+WHERE webhook_dlq.id IN (
+    SELECT dlq.id
+    FROM webhook_dlq dlq
+    JOIN webhooks w ON w.id = dlq.webhook_id
+    JOIN webhook_dlq_replays r ON r.id = @replay_id
+    WHERE w.user_id = r.user_id
+      AND dlq.can_retry = true
+      AND (r.webhook_id IS NULL OR dlq.webhook_id = r.webhook_id)
+      AND (r.event_type IS NULL OR dlq.event_type = r.event_type)
+      AND (r.created_after IS NULL OR dlq.created_at >= r.created_after)
+      AND dlq.created_at < r.created_before
+    ORDER BY dlq.created_at
+    LIMIT @batch_size
+    FOR UPDATE OF dlq SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseWebhookDLQEntry :exec
+UPDATE webhook_dlq
+SET can_retry = true, retried_at = NULL
+WHERE id = $1;
+
+-- name: CreateWebhookDLQReplay :one
+INSERT INTO webhook_dlq_replays (
+    user_id,
+    webhook_id,
+    event_type,
+    created_after,
+    created_before,
+    rate_per_second,
+    status,
+    total_entries
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetWebhookDLQReplay :one
+SELECT * FROM webhook_dlq_replays
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhookDLQReplayByID :one
+SELECT * FROM webhook_dlq_replays
+WHERE id = $1;
+
+-- name: StartWebhookDLQReplay :exec
+UPDATE webhook_dlq_replays
+SET status = 'running', started_at = COALESCE(started_at, NOW())
+WHERE id = $1;
+
+-- name: UpdateWebhookDLQReplayProgress :exec
+UPDATE webhook_dlq_replays
+SET replayed_entries = replayed_entries + @replayed::int,
+    failed_entries = failed_entries + @failed::int
+WHERE id = @id;
+
+-- name: CompleteWebhookDLQReplay :exec
+UPDATE webhook_dlq_replays
+SET status = $2, error_message = $3, completed_at = NOW()
+WHERE id = $1;
@@ -1,6 +1,6 @@
 -- name: CreateWebhook :one
-INSERT INTO webhooks (user_id, url, secret, events)
-VALUES ($1, $2, $3, $4)
+INSERT INTO webhooks (user_id, url, secret, events, format)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetWebhook :one
@@ -22,8 +22,21 @@ SELECT COUNT(*) FROM webhooks
 WHERE user_id = $1;
 
 -- name: UpdateWebhook :one
+-- Re-activating a disabled webhook also closes its circuit.
 UPDATE webhooks
-SET url = $3, events = $4, active = $5, updated_at = NOW()
+SET url = @url, events = @events, active = @active,
+    format = COALESCE(sqlc.narg(format), format),
+    consecutive_failures = CASE WHEN @active AND NOT active THEN 0 ELSE consecutive_failures END,
+    circuit_state = CASE WHEN @active AND NOT active THEN 'closed' ELSE circuit_state END,
+    failing_since = CASE WHEN @active AND NOT active THEN NULL ELSE failing_since END,
+    disabled_at = CASE WHEN @active THEN NULL ELSE disabled_at END,
+    updated_at = NOW()
+WHERE id = @id AND user_id = @user_id
+RETURNING *;
+
+-- name: RotateWebhookSecret :one
+UPDATE webhooks
+SET secret = $3, previous_secret = secret, previous_secret_expires_at = $4, updated_at = NOW()
 WHERE id = $1 AND user_id = $2
 RETURNING *;
 
@@ -40,6 +53,10 @@ INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
 VALUES ($1, $2, $3)
 RETURNING *;
 
+-- name: DeleteWebhookDelivery :exec
+DELETE FROM webhook_deliveries
+WHERE id = $1;
+
 -- name: GetWebhookDelivery :one
 SELECT * FROM webhook_deliveries
 WHERE id = $1;
@@ -84,18 +101,34 @@ WHERE status IN ('pending', 'retrying')
 ORDER BY created_at ASC
 LIMIT $1;
 
--- name: IncrementWebhookFailures :exec
+-- name: IncrementWebhookFailures :one
 UPDATE webhooks
-SET consecutive_failures = consecutive_failures + 1,
+SET consecutive_failures = COALESCE(consecutive_failures, 0) + 1,
     last_failure_at = NOW(),
-    circuit_state = CASE WHEN consecutive_failures >= 9 THEN 'open' ELSE circuit_state END
-WHERE id = $1;
+    failing_since = COALESCE(failing_since, NOW()),
+    circuit_state = CASE
+        WHEN circuit_state = 'half_open' OR COALESCE(consecutive_failures, 0) + 1 >= @failure_threshold::int THEN 'open'
+        ELSE circuit_state
+    END
+WHERE id = @id
+RETURNING consecutive_failures, circuit_state, failing_since;
 
 -- name: ResetWebhookFailures :exec
 UPDATE webhooks
-SET consecutive_failures = 0, circuit_state = 'closed'
+SET consecutive_failures = 0, circuit_state = 'closed', failing_since = NULL
 WHERE id = $1;
 
+-- name: HalfOpenWebhookCircuit :exec
+UPDATE webhooks
+SET circuit_state = 'half_open'
+WHERE id = $1 AND circuit_state = 'open' AND last_failure_at < $2;
+
+-- name: DisableFailingWebhook :one
+UPDATE webhooks
+SET active = false, disabled_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND active = true AND failing_since < $2
+RETURNING *;
+
 -- name: GetWebhookCircuitState :one
 SELECT id, url, consecutive_failures, last_failure_at, circuit_state
 FROM webhooks
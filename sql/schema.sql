@@ -9,7 +9,7 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 CREATE TYPE file_status AS ENUM ('pending', 'processing', 'completed', 'failed');
 
 -- Job type enum
-CREATE TYPE job_type AS ENUM ('thumbnail', 'resize', 'webp', 'watermark', 'pdf_thumbnail', 'metadata', 'optimize', 'video_thumbnail', 'video_transcode', 'video_hls', 'video_watermark', 'zip_download');
+CREATE TYPE job_type AS ENUM ('thumbnail', 'resize', 'webp', 'watermark', 'pdf_thumbnail', 'metadata', 'optimize', 'video_thumbnail', 'video_transcode', 'video_hls', 'video_watermark', 'zip_download', 'transform', 'convert', 'video_dash', 'video_storyboard', 'video_preview');
 
 -- Job status enum  
 CREATE TYPE job_status AS ENUM ('pending', 'running', 'completed', 'failed');
@@ -44,7 +44,13 @@ CREATE TYPE variant_type AS ENUM (
     'hls_480p',
     'hls_720p',
     'hls_1080p',
-    'video_watermarked'
+    'video_watermarked',
+    'avif',
+    'dash_manifest',
+    'storyboard_vtt',
+    'video_preview_mp4',
+    'video_preview_webp',
+    'video_preview_gif'
 );
 
 -- User roles
@@ -122,7 +128,8 @@ CREATE TABLE files (
     status file_status NOT NULL DEFAULT 'pending',
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    deleted_at TIMESTAMPTZ
+    deleted_at TIMESTAMPTZ,
+    content_hash VARCHAR(64)
 );
 
 CREATE INDEX idx_files_folder ON files(folder_id) WHERE folder_id IS NOT NULL;
@@ -259,6 +266,8 @@ CREATE INDEX idx_users_subscription_status ON users(subscription_status) WHERE d
 CREATE INDEX idx_files_user_id ON files(user_id) WHERE deleted_at IS NULL;
 CREATE INDEX idx_files_status ON files(status) WHERE deleted_at IS NULL;
 CREATE INDEX idx_files_created_at ON files(created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_files_user_content_hash ON files(user_id, content_hash) WHERE content_hash IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX idx_files_storage_key ON files(storage_key);
 
 -- Processing jobs indexes (for queue queries)
 CREATE INDEX idx_processing_jobs_file_id ON processing_jobs(file_id);
@@ -400,12 +409,14 @@ CREATE TABLE batch_items (
     job_ids TEXT[] NOT NULL DEFAULT '{}',
     error_message TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    completed_at TIMESTAMPTZ
+    completed_at TIMESTAMPTZ,
+    finished_job_ids TEXT[] NOT NULL DEFAULT '{}'
 );
 
 CREATE INDEX idx_batch_items_batch ON batch_items(batch_id);
 CREATE INDEX idx_batch_items_file ON batch_items(file_id);
 CREATE INDEX idx_batch_items_status ON batch_items(batch_id, status);
+CREATE INDEX idx_batch_items_job_ids ON batch_items USING GIN (job_ids);
 
 -- ============================================================================
 -- NOTIFICATIONS
@@ -451,12 +462,17 @@ CREATE TABLE webhooks (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     url TEXT NOT NULL,
-    secret VARCHAR(64) NOT NULL,
+    secret TEXT NOT NULL,
     events TEXT[] NOT NULL DEFAULT '{}',
     active BOOLEAN NOT NULL DEFAULT true,
     consecutive_failures INTEGER DEFAULT 0,
     last_failure_at TIMESTAMPTZ,
     circuit_state VARCHAR(20) DEFAULT 'closed',
+    format TEXT NOT NULL DEFAULT 'native' CHECK (format IN ('native', 'cloudevents-structured', 'cloudevents-binary')),
+    previous_secret TEXT,
+    previous_secret_expires_at TIMESTAMPTZ,
+    failing_since TIMESTAMPTZ,
+    disabled_at TIMESTAMPTZ,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -494,7 +510,7 @@ CREATE TYPE audit_action AS ENUM (
     'share.access', 'share.delete',
     'user.login', 'user.logout', 'user.password_change',
     'settings.update', 'api_token.create', 'api_token.delete',
-    'webhook.create', 'webhook.delete'
+    'webhook.create', 'webhook.delete', 'webhook.rotate_secret'
 );
 
 CREATE TABLE audit_logs (
@@ -603,3 +619,81 @@ CREATE TABLE webhook_dlq (
 CREATE INDEX idx_webhook_dlq_webhook_id ON webhook_dlq(webhook_id);
 CREATE INDEX idx_webhook_dlq_can_retry ON webhook_dlq(webhook_id) WHERE can_retry = true;
 CREATE INDEX idx_webhook_dlq_created_at ON webhook_dlq(created_at DESC);
+
+-- Bulk replays of dead letter entries
+CREATE TABLE webhook_dlq_replays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    webhook_id UUID REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type TEXT,
+    created_after TIMESTAMPTZ,
+    created_before TIMESTAMPTZ NOT NULL,
+    rate_per_second INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    total_entries INTEGER NOT NULL DEFAULT 0,
+    replayed_entries INTEGER NOT NULL DEFAULT 0,
+    failed_entries INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_dlq_replays_user_id ON webhook_dlq_replays(user_id, created_at DESC);
+
+-- User-defined transform presets
+CREATE TABLE transform_presets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    fit TEXT,
+    position TEXT,
+    format TEXT,
+    quality INTEGER,
+    watermark TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- Upload rules that queue processing jobs for matching uploads
+CREATE TABLE upload_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    content_type TEXT,
+    folder_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    tag TEXT,
+    filename_pattern TEXT,
+    jobs TEXT[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_upload_rules_user_id ON upload_rules(user_id, created_at);
+
+-- Metadata extracted from images by the metadata job
+CREATE TABLE file_metadata (
+    file_id UUID PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    format TEXT NOT NULL,
+    camera_make TEXT,
+    camera_model TEXT,
+    lens_model TEXT,
+    captured_at TIMESTAMPTZ,
+    gps_latitude DOUBLE PRECISION,
+    gps_longitude DOUBLE PRECISION,
+    gps_altitude DOUBLE PRECISION,
+    orientation INTEGER NOT NULL DEFAULT 1,
+    icc_profile TEXT,
+    caption TEXT,
+    keywords TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    blurhash TEXT,
+    lqip TEXT
+);